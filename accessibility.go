@@ -0,0 +1,81 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// accessibleFlag is the root flag that turns on accessible output.
+	accessibleFlag = "accessible"
+	// accessibleFlagUsage is the help text of --accessible.
+	accessibleFlagUsage = "Screen reader friendly output: no aligned tables or animations; also set with ACCESSIBLE=1"
+	// accessibleEnv is the environment variable that turns on accessible output.
+	accessibleEnv = "ACCESSIBLE"
+)
+
+// Accessible reports whether cmd should write screen reader friendly output:
+// no spinners or other animations, no tables aligned with padding or box-drawing
+// characters, and progress as explicit percentages. It is on when --accessible
+// (added with accessibility: true) is set or ACCESSIBLE is set to a value other
+// than 0 or false. The built-in commands respect it; handlers with their own
+// spinners or tables should too.
+func Accessible(cmd *cobra.Command) bool {
+	if on, err := cmd.Flags().GetBool(accessibleFlag); err == nil && on {
+		return true
+	}
+	value := os.Getenv(accessibleEnv)
+	return value != "" && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// addAccessibleFlag adds --accessible to the root command when accessibility is set.
+func (cb *CommandBuilder) addAccessibleFlag(rootCmd *cobra.Command) {
+	if !cb.config.Accessibility || rootCmd.PersistentFlags().Lookup(accessibleFlag) != nil {
+		return
+	}
+	rootCmd.PersistentFlags().Bool(accessibleFlag, false, accessibleFlagUsage)
+}
+
+// outputTable writes rows under a header. It aligns columns with padding, or in
+// accessible mode writes every row as "Header: value" lines followed by a blank line.
+type outputTable struct {
+	header     []string
+	accessible bool
+	w          *tabwriter.Writer
+	cmd        *cobra.Command
+}
+
+// newOutputTable starts a table with header on the output of cmd.
+func newOutputTable(cmd *cobra.Command, header ...string) *outputTable {
+	t := &outputTable{header: header, accessible: Accessible(cmd), cmd: cmd}
+	if !t.accessible {
+		t.w = tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(t.w, strings.Join(header, "\t"))
+	}
+	return t
+}
+
+// Row writes a row with one value per header column.
+func (t *outputTable) Row(values ...string) {
+	if !t.accessible {
+		fmt.Fprintln(t.w, strings.Join(values, "\t"))
+		return
+	}
+	out := t.cmd.OutOrStdout()
+	for i, value := range values {
+		fmt.Fprintf(out, "%s: %s\n", t.header[i], value)
+	}
+	fmt.Fprintln(out)
+}
+
+// Flush writes the aligned table.
+func (t *outputTable) Flush() error {
+	if t.accessible {
+		return nil
+	}
+	return t.w.Flush()
+}
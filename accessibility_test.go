@@ -0,0 +1,70 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const accessibilityToolYAML = `
+name: a11y-test
+accessibility: true
+history: true
+search_command: true
+root:
+  use: a11y-test
+  short: Accessibility test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy the application
+    run_func: runDeploy
+`
+
+func TestAccessible(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want bool
+	}{
+		{name: "off", args: []string{"deploy"}, want: false},
+		{name: "flag", args: []string{"deploy", "--accessible"}, want: true},
+		{name: "env", env: "1", args: []string{"deploy"}, want: true},
+		{name: "env false", env: "false", args: []string{"deploy"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			t.Setenv(accessibleEnv, tt.env)
+			var got bool
+			testTool{yaml: accessibilityToolYAML, funcs: map[string]any{
+				"runDeploy": func(cmd *cobra.Command, args []string) error {
+					got = Accessible(cmd)
+					return nil
+				},
+			}}.mustRun(t, tt.args...)
+			if got != tt.want {
+				t.Errorf("Accessible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_AccessibleOutput(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(accessibleEnv, "")
+	tool := testTool{yaml: accessibilityToolYAML, funcs: map[string]any{"runDeploy": noopRun}}
+	tool.mustRun(t, "deploy")
+
+	history := tool.mustRun(t, "history", "--accessible")
+	if !strings.HasPrefix(history, "ID: 1\nTIME: ") || !strings.HasSuffix(history, "COMMAND: a11y-test deploy\n\n") {
+		t.Errorf("history should list one labeled line per field, got %q", history)
+	}
+
+	search := tool.mustRun(t, "search", "deploy", "--accessible")
+	if search != "a11y-test deploy: Deploy the application\n" {
+		t.Errorf("search = %q, want an unpadded line", search)
+	}
+}
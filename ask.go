@@ -0,0 +1,253 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// MatcherKeywords is the built-in matcher of the ask command. It scores
+	// commands by the words of the request found in their names, descriptions
+	// and examples.
+	MatcherKeywords = "keywords"
+	// askLimit is the number of commands suggested by ask.
+	askLimit = 3
+)
+
+// CommandCandidate is a command the ask command can suggest.
+type CommandCandidate struct {
+	Path    string   // full command path, e.g. "mytool db backup"
+	Usage   string   // command path with the args of its use line, e.g. "mytool db restore <file>"
+	Aliases []string // alternative names of the command
+	Short   string   // short description
+	Long    string   // long description
+	Example string   // examples of the command's help
+}
+
+// CommandMatch is a command suggested for a request.
+type CommandMatch struct {
+	Path       string  // Path of the suggested candidate
+	Invocation string  // suggested command line; the candidate's Usage when empty
+	Score      float64 // relevance; higher is better
+}
+
+// CommandMatcher suggests the commands that fulfil a request written in natural
+// language, such as a local embedding index or a call to an external API.
+// Matches with a Path not among candidates are ignored.
+type CommandMatcher interface {
+	Match(ctx context.Context, request string, candidates []CommandCandidate) ([]CommandMatch, error)
+}
+
+// CommandMatcherFunc adapts a function to the CommandMatcher interface.
+type CommandMatcherFunc func(ctx context.Context, request string, candidates []CommandCandidate) ([]CommandMatch, error)
+
+// Match calls f(ctx, request, candidates).
+func (f CommandMatcherFunc) Match(ctx context.Context, request string, candidates []CommandCandidate) ([]CommandMatch, error) {
+	return f(ctx, request, candidates)
+}
+
+// RegisterCommandMatcher registers a matcher that ask_matcher can refer to by
+// name, replacing the built-in keywords matcher if name is keywords.
+func (cb *CommandBuilder) RegisterCommandMatcher(name string, matcher CommandMatcher) {
+	if cb.matchers == nil {
+		cb.matchers = make(map[string]CommandMatcher)
+	}
+	cb.matchers[name] = matcher
+}
+
+// commandMatcher returns the matcher registered as name or the built-in one.
+func (cb *CommandBuilder) commandMatcher(name string) (CommandMatcher, bool) {
+	if name == "" {
+		name = MatcherKeywords
+	}
+	if matcher, exists := cb.matchers[name]; exists {
+		return matcher, true
+	}
+	if name == MatcherKeywords {
+		return CommandMatcherFunc(matchKeywords), true
+	}
+	return nil, false
+}
+
+// addAskCommand adds the "ask" command when ask_command is set, unless the tool
+// already has a command of that name.
+func (cb *CommandBuilder) addAskCommand(rootCmd *cobra.Command) {
+	if !cb.config.AskCommand || findSubcommand(rootCmd, "ask") != nil {
+		return
+	}
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "ask <request>...",
+		Short: "Suggest the commands that do what you describe",
+		Long: "Describe what you want to do in your own words, and ask prints the commands " +
+			"that match best. The commands are not run.",
+		Example: fmt.Sprintf("  %s ask how do I back up the database", rootCmd.Name()),
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			matcher, exists := cb.commandMatcher(cb.config.AskMatcher)
+			if !exists {
+				return fmt.Errorf("matcher %q is not registered", cb.config.AskMatcher)
+			}
+			request := strings.Join(args, " ")
+			matches, err := suggestCommands(cmd, matcher, request)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if len(matches) == 0 {
+				fmt.Fprintf(out, "No commands match %q\n", request)
+				return nil
+			}
+			if Accessible(cmd) {
+				for _, m := range matches {
+					fmt.Fprintf(out, "%s: %s\n", m.invocation, m.short)
+				}
+				return nil
+			}
+			width := 0
+			for _, m := range matches {
+				width = max(width, len(m.invocation))
+			}
+			for _, m := range matches {
+				fmt.Fprintf(out, "%-*s  %s\n", width, m.invocation, m.short)
+			}
+			return nil
+		},
+	})
+}
+
+// suggestion is a command suggested by ask.
+type suggestion struct {
+	invocation string
+	short      string
+}
+
+// suggestCommands returns the best askLimit matches of matcher for request
+// among the available, runnable commands below the root of cmd.
+func suggestCommands(cmd *cobra.Command, matcher CommandMatcher, request string) ([]suggestion, error) {
+	byPath := make(map[string]CommandCandidate)
+	var candidates []CommandCandidate
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, sub := range c.Commands() {
+			if !sub.IsAvailableCommand() || sub == cmd {
+				continue
+			}
+			if sub.Runnable() {
+				usage := sub.CommandPath()
+				if _, args, found := strings.Cut(sub.Use, " "); found {
+					usage += " " + strings.TrimSpace(args)
+				}
+				candidate := CommandCandidate{
+					Path:    sub.CommandPath(),
+					Usage:   usage,
+					Aliases: sub.Aliases,
+					Short:   sub.Short,
+					Long:    sub.Long,
+					Example: sub.Example,
+				}
+				candidates = append(candidates, candidate)
+				byPath[candidate.Path] = candidate
+			}
+			walk(sub)
+		}
+	}
+	walk(cmd.Root())
+
+	matches, err := matcher.Match(cmd.Context(), request, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match commands: %w", err)
+	}
+	matches = slices.DeleteFunc(matches, func(m CommandMatch) bool {
+		_, exists := byPath[m.Path]
+		return !exists
+	})
+	slices.SortStableFunc(matches, func(a, b CommandMatch) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+
+	var suggestions []suggestion
+	for _, m := range matches[:min(len(matches), askLimit)] {
+		candidate := byPath[m.Path]
+		invocation := m.Invocation
+		if invocation == "" {
+			invocation = candidate.Usage
+		}
+		suggestions = append(suggestions, suggestion{invocation: invocation, short: candidate.Short})
+	}
+	return suggestions, nil
+}
+
+// askStopWords are words of a request that say nothing about the command.
+var askStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "can": true, "do": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "into": true, "is": true, "it": true,
+	"me": true, "my": true, "of": true, "on": true, "or": true, "please": true, "the": true,
+	"this": true, "to": true, "want": true, "what": true, "with": true, "you": true,
+}
+
+// askWords returns the lowercase words of s without stop words.
+func askWords(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return slices.DeleteFunc(words, func(w string) bool { return askStopWords[w] })
+}
+
+// matchKeywords is the built-in keywords matcher. Every word of the request
+// found in the names of a command counts 3, in its short description 2, and in
+// its long description or examples 1. Words match when one starts with the
+// other and the shorter has at least four letters, so "backups" finds "backup".
+func matchKeywords(_ context.Context, request string, candidates []CommandCandidate) ([]CommandMatch, error) {
+	words := askWords(request)
+	var matches []CommandMatch
+	for _, c := range candidates {
+		_, names, _ := strings.Cut(c.Path, " ") // without the tool name
+		fields := []struct {
+			words  []string
+			weight float64
+		}{
+			{askWords(strings.Join(append([]string{names}, c.Aliases...), " ")), 3},
+			{askWords(c.Short), 2},
+			{askWords(c.Long + " " + c.Example), 1},
+		}
+		score := 0.0
+		for _, word := range words {
+			best := 0.0
+			for _, field := range fields {
+				if field.weight > best && slices.ContainsFunc(field.words, func(w string) bool { return askWordsMatch(word, w) }) {
+					best = field.weight
+				}
+			}
+			score += best
+		}
+		if score > 0 {
+			matches = append(matches, CommandMatch{Path: c.Path, Score: score})
+		}
+	}
+	return matches, nil
+}
+
+// askWordsMatch reports whether a and b are the same word or one is a prefix of
+// the other of at least four letters.
+func askWordsMatch(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return len(a) >= 4 && strings.HasPrefix(b, a)
+}
@@ -0,0 +1,177 @@
+package cobrayaml
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const askToolYAML = `
+name: ask-test
+ask_command: true
+root:
+  use: ask-test
+  short: Ask test
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy the application
+    long: Rolls out the current build to an environment.
+    run_func: runNoop
+    aliases: [ship]
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runNoop
+      restore:
+        use: restore <file>
+        short: Restore the database from a backup file
+        run_func: runNoop
+  secret:
+    use: secret
+    short: Back up secrets
+    run_func: runNoop
+    hidden: true
+`
+
+func TestCommandBuilder_AskCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(accessibleEnv, "")
+	tool := testTool{yaml: askToolYAML, funcs: map[string]any{"runNoop": noopRun}}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "best match first",
+			args: []string{"ask", "how", "do", "I", "restore", "the", "database", "from", "backups?"},
+			want: "ask-test db restore <file>  Restore the database from a backup file\n" +
+				"ask-test db backup          Back up the database\n",
+		},
+		{
+			name: "alias and long description",
+			args: []string{"ask", "ship", "the", "build"},
+			want: "ask-test deploy <env>  Deploy the application\n",
+		},
+		{
+			name: "no match",
+			args: []string{"ask", "rotate", "certificates"},
+			want: "No commands match \"rotate certificates\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tool.run(t, tt.args...)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_AskCommand_Accessible(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(accessibleEnv, "1")
+	tool := testTool{
+		yaml:  strings.Replace(askToolYAML, "ask_command: true", "ask_command: true\naccessibility: true", 1),
+		funcs: map[string]any{"runNoop": noopRun},
+	}
+	got, err := tool.run(t, "ask", "--accessible", "deploy")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := "ask-test deploy <env>: Deploy the application\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestCommandBuilder_AskCommand_Matcher(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(accessibleEnv, "")
+	tool := testTool{
+		yaml:  strings.Replace(askToolYAML, "ask_command: true", "ask_command: true\nask_matcher: remote", 1),
+		funcs: map[string]any{"runNoop": noopRun},
+	}
+
+	var gotRequest string
+	var gotPaths []string
+	tool.setup = func(cb *CommandBuilder) {
+		cb.RegisterCommandMatcher("remote", CommandMatcherFunc(func(ctx context.Context, request string, candidates []CommandCandidate) ([]CommandMatch, error) {
+			gotRequest = request
+			for _, c := range candidates {
+				gotPaths = append(gotPaths, c.Path)
+			}
+			return []CommandMatch{
+				{Path: "ask-test db backup", Score: 0.4},
+				{Path: "ask-test deploy", Invocation: "ask-test deploy prod", Score: 0.9},
+				{Path: "ask-test secret", Score: 1},
+			}, nil
+		}))
+	}
+	got, err := tool.run(t, "ask", "ship", "to", "production")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if gotRequest != "ship to production" {
+		t.Errorf("request = %q", gotRequest)
+	}
+	if want := "ask-test db backup,ask-test db restore,ask-test deploy"; !strings.Contains(strings.Join(gotPaths, ","), want) {
+		t.Errorf("candidates = %v, want %s", gotPaths, want)
+	}
+	for _, path := range gotPaths {
+		if path == "ask-test secret" || path == "ask-test ask" || path == "ask-test db" {
+			t.Errorf("candidates should not contain %q", path)
+		}
+	}
+	want := "ask-test deploy prod  Deploy the application\n" +
+		"ask-test db backup    Back up the database\n"
+	if got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestCommandBuilder_AskCommand_Errors(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	tool := testTool{
+		yaml:  strings.Replace(askToolYAML, "ask_command: true", "ask_command: true\nask_matcher: remote", 1),
+		funcs: map[string]any{"runNoop": noopRun},
+	}
+
+	if _, err := tool.run(t, "ask", "deploy"); err == nil || err.Error() != `matcher "remote" is not registered` {
+		t.Errorf("Execute() error = %v, want the unregistered matcher", err)
+	}
+
+	tool.setup = func(cb *CommandBuilder) {
+		cb.RegisterCommandMatcher("remote", CommandMatcherFunc(func(context.Context, string, []CommandCandidate) ([]CommandMatch, error) {
+			return nil, errors.New("service unavailable")
+		}))
+	}
+	if _, err := tool.run(t, "ask", "deploy"); err == nil || err.Error() != "failed to match commands: service unavailable" {
+		t.Errorf("Execute() error = %v, want the matcher error", err)
+	}
+
+	config := &ToolConfig{
+		Name:       "test",
+		Root:       CommandConfig{Use: "test", Short: "Test"},
+		AskMatcher: "remote",
+	}
+	if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), "tool config: ask_matcher needs ask_command to be enabled") {
+		t.Errorf("ValidateConfig() error = %v", err)
+	}
+}
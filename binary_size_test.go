@@ -0,0 +1,92 @@
+package cobrayaml
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// binarySizeLimit is the size a minimal runtime-only CLI must stay under; the
+// README documents it in the Binary Size section.
+const binarySizeLimit = 10_000_000
+
+// TestBinarySize builds a minimal CLI against this checkout and checks that it
+// does not link the code generation, documentation, web UI or webhook code.
+func TestBinarySize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a binary")
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	goMod := "module tiny\n\ngo 1.22\n\nrequire github.com/S-mishina/cobrayaml v0.0.0\n\nreplace github.com/S-mishina/cobrayaml => " + root + "\n"
+	mainGo := `package main
+
+import (
+	"os"
+
+	"github.com/S-mishina/cobrayaml"
+)
+
+const config = ` + "`" + `
+name: tiny
+root:
+  use: tiny
+  short: Tiny
+  run_func: runRoot
+` + "`" + `
+
+func main() {
+	builder, err := cobrayaml.NewCommandBuilderFromString(config)
+	if err != nil {
+		os.Exit(1)
+	}
+	builder.RegisterFunction("runRoot", func() {})
+	if err := builder.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+`
+	goSum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"go.mod": goMod, "go.sum": string(goSum), "main.go": mainGo} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Build offline from the module cache this package was built from
+	binary := filepath.Join(dir, "tiny")
+	cmd := exec.Command("go", "build", "-o", binary, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\nOutput: %s", err, output)
+	}
+
+	info, err := os.Stat(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() >= binarySizeLimit {
+		t.Errorf("minimal CLI is %d bytes, want under %d", info.Size(), binarySizeLimit)
+	}
+
+	output, err := exec.Command("go", "tool", "nm", binary).Output()
+	if err != nil {
+		t.Fatalf("go tool nm failed: %v", err)
+	}
+	for _, prefix := range []string{"net/http.", "html/template.", "github.com/S-mishina/cobrayaml.(*Generator)", "github.com/S-mishina/cobrayaml.(*DocGenerator)"} {
+		if count := strings.Count(string(output), " "+prefix); count > 0 {
+			t.Errorf("minimal CLI links %d %s symbols", count, prefix)
+		}
+	}
+	t.Logf("minimal CLI is %d bytes", info.Size())
+}
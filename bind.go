@@ -0,0 +1,120 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Bind returns a T whose fields tagged with `flag:"<name>"` hold the values of
+// the flags of cmd, including inherited persistent flags, so handlers read all
+// their flags in one call:
+//
+//	type DeployOpts struct {
+//		Env      string        `flag:"env"`
+//		Replicas int           `flag:"replicas"`
+//		Timeout  time.Duration `flag:"timeout"`
+//	}
+//
+//	func runDeploy(cmd *cobra.Command, args []string) error {
+//		opts, err := cobrayaml.Bind[DeployOpts](cmd)
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// A field has the Go type of its flag's getter, e.g. *url.URL for a url flag,
+// int64 for a bytesize flag and time.Time for a time flag, or a type defined on
+// it such as `type Env string`. Flags of custom types bind to a field of their
+// pflag.Value type, or to a string field holding the value's String(). Untagged
+// fields and fields tagged `flag:"-"` are left alone. T must be a struct.
+func Bind[T any](cmd *cobra.Command) (T, error) {
+	var opts T
+	v := reflect.ValueOf(&opts).Elem()
+	if v.Kind() != reflect.Struct {
+		return opts, fmt.Errorf("cannot bind flags to %T: not a struct", opts)
+	}
+
+	fs := cmd.Flags()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("flag")
+		if name == "" || name == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			return opts, fmt.Errorf("cannot bind flag %s to unexported field %s", name, field.Name)
+		}
+		flag := fs.Lookup(name)
+		if flag == nil {
+			return opts, fmt.Errorf("field %s: flag accessed but not defined: %s", field.Name, name)
+		}
+		value, err := flagValue(fs, flag)
+		if err != nil {
+			return opts, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return opts, fmt.Errorf("field %s: flag %s: %w", field.Name, name, err)
+		}
+	}
+	return opts, nil
+}
+
+// flagValue returns the value of flag as the getter of its type returns it, or
+// the pflag.Value itself for custom types.
+func flagValue(fs *pflag.FlagSet, flag *pflag.Flag) (any, error) {
+	switch flag.Value.Type() {
+	case "string":
+		return fs.GetString(flag.Name)
+	case "bool":
+		return fs.GetBool(flag.Name)
+	case "int":
+		return fs.GetInt(flag.Name)
+	case "uint":
+		return fs.GetUint(flag.Name)
+	case "uint64":
+		return fs.GetUint64(flag.Name)
+	case "stringSlice":
+		return fs.GetStringSlice(flag.Name)
+	case "stringArray":
+		return fs.GetStringArray(flag.Name)
+	case "ip":
+		return fs.GetIP(flag.Name)
+	case "ipNet":
+		return fs.GetIPNet(flag.Name)
+	case "duration":
+		return fs.GetDuration(flag.Name)
+	case FlagTypeURL:
+		return GetURL(fs, flag.Name)
+	case FlagTypeByteSize:
+		return GetByteSize(fs, flag.Name)
+	case FlagTypeTime:
+		return GetTime(fs, flag.Name)
+	default:
+		return flag.Value, nil
+	}
+}
+
+// setField stores value in field, converting it to a type defined on the type
+// of value. A pflag.Value that does not fit is stored as its String().
+func setField(field reflect.Value, value any) error {
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case rv.Kind() == field.Kind() && rv.Type().ConvertibleTo(field.Type()):
+		field.Set(rv.Convert(field.Type()))
+	case field.Kind() == reflect.String:
+		if v, ok := value.(pflag.Value); ok {
+			field.SetString(v.String())
+			return nil
+		}
+		fallthrough
+	default:
+		return fmt.Errorf("cannot assign %s to field of type %s", rv.Type(), field.Type())
+	}
+	return nil
+}
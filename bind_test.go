@@ -0,0 +1,180 @@
+package cobrayaml
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const bindYAML = `
+name: bind
+root:
+  use: bind
+  short: Bind test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    flags:
+      - name: env
+        type: string
+        allowed_values: [dev, prod]
+        default: dev
+        usage: Environment
+      - name: replicas
+        type: int
+        default: "2"
+        usage: Replicas
+      - name: tags
+        type: stringSlice
+        usage: Tags
+      - name: timeout
+        type: duration
+        usage: Timeout
+      - name: endpoint
+        type: url
+        usage: Endpoint
+      - name: max-size
+        type: bytesize
+        usage: Max size
+      - name: bind
+        type: ip
+        usage: Bind address
+      - name: min-version
+        type: semver
+        usage: Minimum version
+`
+
+type environment string
+
+type bindOpts struct {
+	Verbose    bool          `flag:"verbose"`
+	Env        environment   `flag:"env"`
+	Replicas   int           `flag:"replicas"`
+	Tags       []string      `flag:"tags"`
+	Timeout    time.Duration `flag:"timeout"`
+	Endpoint   *url.URL      `flag:"endpoint"`
+	MaxSize    int64         `flag:"max-size"`
+	Bind       net.IP        `flag:"bind"`
+	MinVersion string        `flag:"min-version"`
+	Ignored    string
+	Skipped    string `flag:"-"`
+}
+
+// runBind runs deploy with args and a handler calling bind.
+func runBind(t *testing.T, args []string, bind func(cmd *cobra.Command) error) {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(bindYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFlagType("semver", func(FlagConfig) (pflag.Value, error) {
+		return &semverValue{}, nil
+	})
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		return bind(cmd)
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	var got bindOpts
+	runBind(t, []string{
+		"--verbose", "deploy", "--env", "prod", "--tags", "a,b", "--timeout", "1m",
+		"--endpoint", "https://example.com", "--max-size", "1KiB", "--bind", "127.0.0.1", "--min-version", "1.2.3",
+	}, func(cmd *cobra.Command) error {
+		var err error
+		got, err = Bind[bindOpts](cmd)
+		return err
+	})
+
+	want := bindOpts{
+		Verbose:    true,
+		Env:        "prod",
+		Replicas:   2,
+		Tags:       []string{"a", "b"},
+		Timeout:    time.Minute,
+		Endpoint:   &url.URL{Scheme: "https", Host: "example.com"},
+		MaxSize:    1024,
+		Bind:       net.ParseIP("127.0.0.1"),
+		MinVersion: "1.2.3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Bind() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBind_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		bind func(cmd *cobra.Command) error
+		want string
+	}{
+		{
+			name: "not a struct",
+			bind: func(cmd *cobra.Command) error { _, err := Bind[string](cmd); return err },
+			want: "cannot bind flags to string: not a struct",
+		},
+		{
+			name: "undefined flag",
+			bind: func(cmd *cobra.Command) error {
+				_, err := Bind[struct {
+					Region string `flag:"region"`
+				}](cmd)
+				return err
+			},
+			want: "field Region: flag accessed but not defined: region",
+		},
+		{
+			name: "wrong type",
+			bind: func(cmd *cobra.Command) error {
+				_, err := Bind[struct {
+					Replicas string `flag:"replicas"`
+				}](cmd)
+				return err
+			},
+			want: "field Replicas: flag replicas: cannot assign int to field of type string",
+		},
+		{
+			name: "unexported field",
+			bind: func(cmd *cobra.Command) error {
+				_, err := Bind[struct {
+					env string `flag:"env"`
+				}](cmd)
+				return err
+			},
+			want: "cannot bind flag env to unexported field env",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			runBind(t, []string{"deploy"}, func(cmd *cobra.Command) error {
+				err = tt.bind(cmd)
+				return nil
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Bind() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
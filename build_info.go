@@ -0,0 +1,92 @@
+package cobrayaml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// modulePath is the import path of this module, used to find its version in the build info.
+	modulePath = "github.com/S-mishina/cobrayaml"
+
+	// buildInfoCommand is the name of the hidden command added by SetBuildInfo.
+	buildInfoCommand = "build-info"
+)
+
+// BuildInfo identifies the inputs a CLI was generated from. The main.go written by
+// GenerateMain records them as constants and passes them to SetBuildInfo.
+type BuildInfo struct {
+	ConfigSHA256 string // SHA-256 of the commands.yaml the code was generated from
+	Version      string // cobrayaml version that generated the code
+}
+
+// ConfigHash returns the hex-encoded SHA-256 of a commands.yaml.
+func ConfigHash(yamlContent string) string {
+	sum := sha256.Sum256([]byte(yamlContent))
+	return hex.EncodeToString(sum[:])
+}
+
+// moduleVersion returns the version of this module linked into the running binary,
+// or "(devel)" when it is not known (e.g. a local build or replace directive).
+func moduleVersion() string {
+	return linkedVersion(modulePath)
+}
+
+// linkedVersion returns the version of the module at path linked into the running
+// binary, or "(devel)" when it is not known.
+func linkedVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	version := ""
+	if info.Main.Path == path {
+		version = info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			version = dep.Version
+		}
+	}
+	if version == "" {
+		return "(devel)"
+	}
+	return version
+}
+
+// SetBuildInfo records the inputs the CLI was generated from and adds a hidden
+// build-info command to the root command that prints them next to the hash of the
+// commands.yaml actually embedded, so operators can verify which commands.yaml a
+// deployed binary was built from.
+func (cb *CommandBuilder) SetBuildInfo(info BuildInfo) {
+	cb.buildInfo = &info
+}
+
+// addBuildInfoCommand adds the hidden build-info command unless one is declared in YAML.
+func (cb *CommandBuilder) addBuildInfoCommand(rootCmd *cobra.Command) {
+	if cb.buildInfo == nil || findSubcommand(rootCmd, buildInfoCommand) != nil {
+		return
+	}
+
+	info := *cb.buildInfo
+	rootCmd.AddCommand(&cobra.Command{
+		Use:    buildInfoCommand,
+		Short:  "Show the commands.yaml and cobrayaml version the CLI was generated from",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "commands.yaml sha256: %s\n", cb.configHash)
+			fmt.Fprintf(out, "generated from sha256: %s\n", info.ConfigSHA256)
+			fmt.Fprintf(out, "cobrayaml version: %s\n", info.Version)
+			if info.ConfigSHA256 != cb.configHash {
+				fmt.Fprintln(out, "warning: commands.yaml changed since the code was generated")
+			}
+			return nil
+		},
+	})
+}
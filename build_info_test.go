@@ -0,0 +1,115 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const buildInfoYAML = `
+name: build-info-test
+root:
+  use: build-info-test
+  short: Build info test
+`
+
+func TestConfigHash(t *testing.T) {
+	// SHA-256 of the empty string.
+	want := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if got := ConfigHash(""); got != want {
+		t.Errorf("ConfigHash() = %s, want %s", got, want)
+	}
+}
+
+func TestCommandBuilder_BuildInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        BuildInfo
+		wantWarning bool
+	}{
+		{
+			name: "matching hash",
+			info: BuildInfo{ConfigSHA256: ConfigHash(buildInfoYAML), Version: "v1.2.3"},
+		},
+		{
+			name:        "changed since generation",
+			info:        BuildInfo{ConfigSHA256: ConfigHash("name: old"), Version: "v1.2.3"},
+			wantWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(buildInfoYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			cb.SetBuildInfo(tt.info)
+
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			buildInfoCmd := findSubcommand(rootCmd, buildInfoCommand)
+			if buildInfoCmd == nil || !buildInfoCmd.Hidden {
+				t.Fatal("build-info should be added as a hidden command")
+			}
+
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetArgs([]string{buildInfoCommand})
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			for _, want := range []string{
+				"commands.yaml sha256: " + ConfigHash(buildInfoYAML),
+				"generated from sha256: " + tt.info.ConfigSHA256,
+				"cobrayaml version: v1.2.3",
+			} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("build-info output should contain %q, got:\n%s", want, out.String())
+				}
+			}
+			if got := strings.Contains(out.String(), "warning:"); got != tt.wantWarning {
+				t.Errorf("build-info warning = %v, want %v, got:\n%s", got, tt.wantWarning, out.String())
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_NoBuildInfo(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(buildInfoYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	if findSubcommand(rootCmd, buildInfoCommand) != nil {
+		t.Error("build-info should only be added when SetBuildInfo is called")
+	}
+}
+
+func TestGenerator_GenerateMain_BuildInfo(t *testing.T) {
+	gen, err := NewGeneratorFromString(buildInfoYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`commandsYAMLSHA256 = "` + ConfigHash(buildInfoYAML) + `"`,
+		`cobrayamlVersion   = "` + moduleVersion() + `"`,
+		"builder.SetBuildInfo(cobrayaml.BuildInfo{",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated main should contain %q, got:\n%s", want, code)
+		}
+	}
+}
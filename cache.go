@@ -0,0 +1,157 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// noCacheFlag is the flag added to commands with a result cache.
+const noCacheFlag = "no-cache"
+
+// resultCacheDir returns the directory the results of tool's commands are cached in.
+func resultCacheDir(tool string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tool, "results"), nil
+}
+
+// addCache wraps the RunE of cmd so its output is served from the result cache.
+// Only successful runs are cached; caching is skipped when the cache directory
+// cannot be determined or written.
+func (cb *CommandBuilder) addCache(cmd *cobra.Command, cache *CacheConfig) {
+	if cache == nil || cmd.RunE == nil {
+		return
+	}
+	ttl, _ := time.ParseDuration(cache.TTL) // checked by ValidateConfig
+	cmd.Flags().Bool(noCacheFlag, false, "Run without the cached result and refresh the cache")
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		dir, err := resultCacheDir(cmd.Root().Name())
+		if err != nil || undoing(cmd) {
+			return run(cmd, args)
+		}
+		path := filepath.Join(dir, cacheKey(cmd, cache.Key, args))
+
+		if noCache, _ := cmd.Flags().GetBool(noCacheFlag); !noCache {
+			if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < ttl {
+				if data, err := os.ReadFile(path); err == nil {
+					_, err = cmd.OutOrStdout().Write(data)
+					return err
+				}
+			}
+		}
+
+		var buf bytes.Buffer
+		out := cmd.OutOrStdout()
+		cmd.SetOut(io.MultiWriter(out, &buf))
+		defer cmd.SetOut(out)
+		if err := run(cmd, args); err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(dir, 0o755); err == nil {
+			_ = os.WriteFile(path, buf.Bytes(), 0o600)
+		}
+		return nil
+	}
+}
+
+// cacheKey returns the file name of the cached result of cmd for the given key
+// entries (flags.<name> or args.<index>). Without key entries, all args and flag
+// values make up the key. The caller identity of the run is always part of it,
+// so one caller is never served the result of another.
+func cacheKey(cmd *cobra.Command, key []string, args []string) string {
+	parts := []string{cmd.CommandPath()}
+	if id := contextIdentity(cmd.Context()); id != nil {
+		data, _ := json.Marshal(id)
+		parts = append(parts, "identity="+string(data))
+	}
+	if len(key) == 0 {
+		parts = append(parts, args...)
+		var flags []string
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Name != noCacheFlag {
+				flags = append(flags, flag.Name+"="+flag.Value.String())
+			}
+		})
+		sort.Strings(flags)
+		parts = append(parts, flags...)
+	}
+	for _, k := range key {
+		value := ""
+		if name, ok := strings.CutPrefix(k, "flags."); ok {
+			if flag := cmd.Flags().Lookup(name); flag != nil {
+				value = flag.Value.String()
+			}
+		} else if index, ok := strings.CutPrefix(k, "args."); ok {
+			if i, err := strconv.Atoi(index); err == nil && i < len(args) {
+				value = args[i]
+			}
+		}
+		parts = append(parts, k+"="+value)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// addCacheCommands adds the "cache clear" command when any of the built commands
+// caches its results.
+func (cb *CommandBuilder) addCacheCommands(rootCmd *cobra.Command, commands map[string]CommandConfig) {
+	if !hasCache(commands) {
+		return
+	}
+
+	cacheCmd := findSubcommand(rootCmd, "cache")
+	if cacheCmd == nil {
+		cacheCmd = &cobra.Command{
+			Use:   "cache",
+			Short: "Manage cached command results",
+		}
+		rootCmd.AddCommand(cacheCmd)
+	}
+	if findSubcommand(cacheCmd, "clear") == nil {
+		cacheCmd.AddCommand(&cobra.Command{
+			Use:   "clear",
+			Short: "Remove all cached command results",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				dir, err := resultCacheDir(cmd.Root().Name())
+				if err != nil {
+					return err
+				}
+				if err := os.RemoveAll(dir); err != nil {
+					return fmt.Errorf("failed to clear cache: %w", err)
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "Cache cleared")
+				return nil
+			},
+		})
+	}
+}
+
+// hasCache reports whether any of cmds or their subcommands has a result cache.
+func hasCache(cmds map[string]CommandConfig) bool {
+	for _, cmd := range cmds {
+		if cmd.Cache != nil || hasCache(cmd.Commands) {
+			return true
+		}
+	}
+	return false
+}
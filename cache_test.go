@@ -0,0 +1,184 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const cacheYAML = `
+name: cache-test
+root:
+  use: cache-test
+  short: Cache test
+commands:
+  pods:
+    use: pods [selector]
+    short: List pods
+    run_func: runPods
+    cache:
+      ttl: 5m
+      key: [flags.namespace, args.0]
+    flags:
+      - name: namespace
+        type: string
+        default: default
+        usage: Namespace
+      - name: verbose
+        type: bool
+        usage: Verbose output
+`
+
+func TestCommandBuilder_Cache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	runs := 0
+	tool := testTool{yaml: cacheYAML, funcs: map[string]any{
+		"runPods": func(cmd *cobra.Command, args []string) error {
+			runs++
+			ns, _ := cmd.Flags().GetString("namespace")
+			cmd.Printf("run %d in %s\n", runs, ns)
+			return nil
+		},
+	}}
+
+	steps := []struct {
+		desc string
+		args []string
+		want string
+	}{
+		{desc: "the first run runs the handler", args: []string{"pods"}, want: "run 1 in default\n"},
+		{desc: "a second run is served from the cache", args: []string{"pods"}, want: "run 1 in default\n"},
+		{desc: "flags outside the key keep the cache entry", args: []string{"pods", "--verbose"}, want: "run 1 in default\n"},
+		{desc: "a different key runs the handler", args: []string{"pods", "--namespace", "kube-system"}, want: "run 2 in kube-system\n"},
+		{desc: "a different arg runs the handler", args: []string{"pods", "app=web"}, want: "run 3 in default\n"},
+		{desc: "--no-cache runs the handler", args: []string{"pods", "--no-cache"}, want: "run 4 in default\n"},
+		{desc: "--no-cache refreshes the cache", args: []string{"pods"}, want: "run 4 in default\n"},
+	}
+	for _, step := range steps {
+		if got := tool.mustRun(t, step.args...); got != step.want {
+			t.Errorf("%s: output = %q, want %q", step.desc, got, step.want)
+		}
+	}
+
+	// Expire every entry
+	dir, err := resultCacheDir("cache-test")
+	if err != nil {
+		t.Fatalf("resultCacheDir() error = %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	old := time.Now().Add(-10 * time.Minute)
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().Perm() != 0o600 {
+			t.Errorf("cache entry mode = %v, want 0600", info.Mode().Perm())
+		}
+		if err := os.Chtimes(filepath.Join(dir, entry.Name()), old, old); err != nil {
+			t.Fatalf("Chtimes() error = %v", err)
+		}
+	}
+	if got := tool.mustRun(t, "pods"); got != "run 5 in default\n" {
+		t.Errorf("an expired entry should run the handler, got %q", got)
+	}
+
+	if got := tool.mustRun(t, "cache", "clear"); got != "Cache cleared\n" {
+		t.Errorf("cache clear output = %q", got)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("cache clear should remove %s, got error = %v", dir, err)
+	}
+	if got := tool.mustRun(t, "pods"); got != "run 6 in default\n" {
+		t.Errorf("run after cache clear should run the handler, got %q", got)
+	}
+}
+
+func TestCommandBuilder_CacheSkipsErrors(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	runs := 0
+	tool := testTool{yaml: cacheYAML, funcs: map[string]any{
+		"runPods": func(cmd *cobra.Command, args []string) error {
+			runs++
+			return os.ErrNotExist
+		},
+	}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := tool.run(t, "pods"); err == nil {
+			t.Fatal("Execute() expected handler error")
+		}
+	}
+	if runs != 2 {
+		t.Errorf("failed runs should not be cached, handler ran %d times", runs)
+	}
+}
+
+func TestValidateConfig_Cache(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			command: CommandConfig{RunFunc: "runPods", Cache: &CacheConfig{TTL: "30s", Key: []string{"flags.namespace", "args.1"}}},
+		},
+		{
+			name:    "missing ttl",
+			command: CommandConfig{RunFunc: "runPods", Cache: &CacheConfig{}},
+			wantErr: "cache ttl is required",
+		},
+		{
+			name:    "invalid ttl",
+			command: CommandConfig{RunFunc: "runPods", Cache: &CacheConfig{TTL: "-1m"}},
+			wantErr: `cache ttl "-1m" is not a positive duration`,
+		},
+		{
+			name:    "invalid key",
+			command: CommandConfig{RunFunc: "runPods", Cache: &CacheConfig{TTL: "1m", Key: []string{"args.first"}}},
+			wantErr: `cache key "args.first" must be flags.<name> or args.<index>`,
+		},
+		{
+			name:    "no run_func",
+			command: CommandConfig{Cache: &CacheConfig{TTL: "1m"}},
+			wantErr: "cache requires run_func",
+		},
+		{
+			name: "no-cache flag",
+			command: CommandConfig{
+				RunFunc: "runPods",
+				Cache:   &CacheConfig{TTL: "1m"},
+				Flags:   []FlagConfig{{Name: "no-cache", Type: FlagTypeBool, Usage: "No cache"}},
+			},
+			wantErr: "conflicts with the flag added by cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.command.Use = "pods"
+			tt.command.Short = "List pods"
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"pods": tt.command},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
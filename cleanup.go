@@ -0,0 +1,122 @@
+package cobrayaml
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// cleanupKey is the context key under which the cleanup registry is stored.
+type cleanupKey struct{}
+
+// exitProcess exits the process after the cleanups ran on a signal. It is a
+// variable so tests can replace it.
+var exitProcess = os.Exit
+
+// cleanupRegistry holds the teardown callbacks of a running handler.
+type cleanupRegistry struct {
+	mu      sync.Mutex
+	funcs   []func()
+	cancel  context.CancelFunc
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+// OnCleanup registers fn to run when the handler of cmd finishes: when it returns
+// successfully, returns an error or panics, or when the process receives an
+// interrupt or termination signal. Callbacks run once each, last registered first.
+//
+// On a signal, the command's context is canceled, the callbacks run and the
+// process exits with status 128 plus the signal number, as it would without a
+// handler for the signal. Signals are only caught once OnCleanup has been called.
+//
+// OnCleanup must be called with the command passed to a handler of a command
+// built by CommandBuilder; it panics otherwise.
+func OnCleanup(cmd *cobra.Command, fn func()) {
+	var r *cleanupRegistry
+	if ctx := cmd.Context(); ctx != nil {
+		r, _ = ctx.Value(cleanupKey{}).(*cleanupRegistry)
+	}
+	if r == nil {
+		panic("cobrayaml: OnCleanup called outside the handler of a command built by CommandBuilder")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs = append(r.funcs, fn)
+	if r.signals == nil {
+		r.signals = make(chan os.Signal, 1)
+		signal.Notify(r.signals, os.Interrupt, syscall.SIGTERM)
+		go r.watch()
+	}
+}
+
+// watch runs the callbacks and exits when a signal arrives before the handler returns.
+func (r *cleanupRegistry) watch() {
+	select {
+	case sig := <-r.signals:
+		r.cancel()
+		r.run()
+		code := 1
+		if s, ok := sig.(syscall.Signal); ok {
+			code = 128 + int(s)
+		}
+		exitProcess(code)
+	case <-r.done:
+	}
+}
+
+// run calls the registered callbacks in reverse order, each at most once.
+func (r *cleanupRegistry) run() {
+	for {
+		r.mu.Lock()
+		if len(r.funcs) == 0 {
+			r.mu.Unlock()
+			return
+		}
+		fn := r.funcs[len(r.funcs)-1]
+		r.funcs = r.funcs[:len(r.funcs)-1]
+		r.mu.Unlock()
+		fn()
+	}
+}
+
+// stop stops catching signals and runs the remaining callbacks.
+func (r *cleanupRegistry) stop() {
+	r.mu.Lock()
+	if r.signals != nil {
+		signal.Stop(r.signals)
+	}
+	close(r.done)
+	r.mu.Unlock()
+	r.run()
+}
+
+// addCleanup wraps the RunE of cmd so callbacks registered with OnCleanup run
+// after the handler returns.
+func addCleanup(cmd *cobra.Command) {
+	if cmd.RunE == nil {
+		return
+	}
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		parent := cmd.Context()
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithCancel(parent)
+		r := &cleanupRegistry{cancel: cancel, done: make(chan struct{})}
+		cmd.SetContext(context.WithValue(ctx, cleanupKey{}, r))
+		defer func() {
+			r.stop()
+			cancel()
+			cmd.SetContext(parent)
+		}()
+		return run(cmd, args)
+	}
+}
@@ -0,0 +1,143 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const cleanupYAML = `
+name: cleanup-test
+root:
+  use: cleanup-test
+  short: Cleanup test
+commands:
+  run:
+    use: run <result>
+    short: Run with cleanups
+    run_func: runWithCleanup
+`
+
+// newCleanupCLI builds the cleanup test CLI with handler as runWithCleanup.
+func newCleanupCLI(t *testing.T, handler func(cmd *cobra.Command, args []string) error) *cobra.Command {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(cleanupYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runWithCleanup", handler)
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&bytes.Buffer{})
+	return rootCmd
+}
+
+func TestOnCleanup(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  string
+		wantErr string
+	}{
+		{name: "success", result: "ok"},
+		{name: "error", result: "fail", wantErr: "handler failed"},
+		{name: "panic", result: "panic", wantErr: "handler panicked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			rootCmd := newCleanupCLI(t, func(cmd *cobra.Command, args []string) error {
+				OnCleanup(cmd, func() { calls = append(calls, "close db") })
+				OnCleanup(cmd, func() { calls = append(calls, "remove temp dir") })
+				switch args[0] {
+				case "fail":
+					return errors.New("handler failed")
+				case "panic":
+					panic("handler panicked")
+				}
+				return nil
+			})
+			rootCmd.SetArgs([]string{"run", tt.result})
+
+			err := func() (err error) {
+				defer func() {
+					if r := recover(); r != nil {
+						err = errors.New(r.(string))
+					}
+				}()
+				return rootCmd.Execute()
+			}()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Execute() error = %v, want containing %q", err, tt.wantErr)
+			}
+
+			want := []string{"remove temp dir", "close db"}
+			if !reflect.DeepEqual(calls, want) {
+				t.Errorf("cleanups = %q, want %q", calls, want)
+			}
+		})
+	}
+}
+
+func TestOnCleanup_Signal(t *testing.T) {
+	exited := make(chan int, 1)
+	orig := exitProcess
+	exitProcess = func(code int) { exited <- code }
+	defer func() { exitProcess = orig }()
+
+	var calls []string
+	canceled := false
+	rootCmd := newCleanupCLI(t, func(cmd *cobra.Command, args []string) error {
+		OnCleanup(cmd, func() { calls = append(calls, "first") })
+		OnCleanup(cmd, func() {
+			canceled = cmd.Context().Err() != nil
+			calls = append(calls, "second")
+		})
+
+		// Deliver the signal as signal.Notify would
+		r := cmd.Context().Value(cleanupKey{}).(*cleanupRegistry)
+		r.signals <- syscall.SIGTERM
+
+		select {
+		case code := <-exited:
+			if code != 128+int(syscall.SIGTERM) {
+				t.Errorf("exit code = %d, want %d", code, 128+int(syscall.SIGTERM))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the signal cleanup")
+		}
+		return nil
+	})
+	rootCmd.SetArgs([]string{"run", "ok"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if want := []string{"second", "first"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("cleanups = %q, want %q run once each", calls, want)
+	}
+	if !canceled {
+		t.Error("the command's context should be canceled before cleanups run on a signal")
+	}
+}
+
+func TestOnCleanup_OutsideHandler(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("OnCleanup() should panic outside a built command")
+		}
+	}()
+	OnCleanup(&cobra.Command{}, func() {})
+}
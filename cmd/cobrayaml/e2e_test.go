@@ -130,6 +130,78 @@ func TestE2E_Init_CustomName(t *testing.T) {
 	}
 }
 
+func TestE2E_Init_WithExamples(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "init", "example-cli", "--with-examples")
+	if err != nil {
+		t.Fatalf("init command failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "commands.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read commands.yaml: %v", err)
+	}
+
+	for _, want := range []string{"name: example-cli", "  # remove:", "  #   aliases:", "  #     persistent: true"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("commands.yaml should contain %q, got: %s", want, string(content))
+		}
+	}
+
+	// The commented examples must not change the generated config
+	stdout, stderr, err = runCobrayaml(t, tmpDir, "gen", "commands.yaml")
+	if err != nil {
+		t.Fatalf("gen command failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	handlers, err := os.ReadFile(filepath.Join(tmpDir, "handlers.go"))
+	if err != nil {
+		t.Fatalf("failed to read handlers.go: %v", err)
+	}
+	if strings.Contains(string(handlers), "runRemove") {
+		t.Error("commented examples should not generate handlers")
+	}
+}
+
+func TestE2E_Init_License(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "init", "example-cli", "--license", "Apache-2.0", "--copyright", "Example Corp.")
+	if err != nil {
+		t.Fatalf("init command failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	stdout, stderr, err = runCobrayaml(t, tmpDir, "gen", "commands.yaml")
+	if err != nil {
+		t.Fatalf("gen command failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	for _, file := range []string{"handlers.go", "main.go"} {
+		content, err := os.ReadFile(filepath.Join(tmpDir, file))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if !strings.Contains(string(content), "Example Corp.\n// SPDX-License-Identifier: Apache-2.0\n") {
+			t.Errorf("%s should start with the license header, got: %s", file, string(content))
+		}
+	}
+
+	noticePath := filepath.Join(tmpDir, "THIRD_PARTY_NOTICES.md")
+	logFileContent(t, noticePath)
+	notice, err := os.ReadFile(noticePath)
+	if err != nil {
+		t.Fatalf("THIRD_PARTY_NOTICES.md was not created: %v", err)
+	}
+	if !strings.Contains(string(notice), "github.com/spf13/cobra") {
+		t.Errorf("notice should list cobra, got: %s", string(notice))
+	}
+
+	// --copyright without --license has nowhere to go
+	_, _, err = runCobrayaml(t, t.TempDir(), "init", "--copyright", "Example Corp.")
+	if err == nil {
+		t.Error("init with --copyright but no --license should fail")
+	}
+}
+
 func TestE2E_Init_AlreadyExists(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -145,6 +217,30 @@ func TestE2E_Init_AlreadyExists(t *testing.T) {
 	}
 }
 
+func TestE2E_Reference(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "reference")
+	if err != nil {
+		t.Fatalf("reference command failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "### CommandConfig") {
+		t.Errorf("reference should print the markdown reference, got: %s", stdout)
+	}
+
+	stdout, stderr, err = runCobrayaml(t, tmpDir, "reference", "--format", "json")
+	if err != nil {
+		t.Fatalf("reference --format json failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, `"key": "run_func"`) {
+		t.Errorf("reference --format json should list fields, got: %s", stdout)
+	}
+
+	if _, _, err := runCobrayaml(t, tmpDir, "reference", "--format", "html"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
 // ============================================================================
 // gen command E2E tests
 // ============================================================================
@@ -232,6 +328,56 @@ commands:
 	}
 }
 
+func TestE2E_Gen_Enums(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlContent := `name: test-cli
+root:
+  use: test-cli
+  short: Test CLI application
+commands:
+  get:
+    use: get
+    short: Get items
+    run_func: handleGet
+    flags:
+      - name: output
+        type: string
+        usage: Output format
+        allowed_values: [json, yaml]
+`
+	yamlPath := filepath.Join(tmpDir, "commands.yaml")
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write commands.yaml: %v", err)
+	}
+
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "gen", "commands.yaml", "--enums")
+	if err != nil {
+		t.Fatalf("gen command failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+
+	enumsPath := filepath.Join(tmpDir, "enums.go")
+	logFileContent(t, enumsPath)
+
+	enumsContent, err := os.ReadFile(enumsPath)
+	if err != nil {
+		t.Fatalf("enums.go was not created: %v", err)
+	}
+	for _, expected := range []string{"type Output string", "OutputJSON", "OutputYAML", "func getOutput("} {
+		if !strings.Contains(string(enumsContent), expected) {
+			t.Errorf("enums.go should contain %q", expected)
+		}
+	}
+
+	handlersContent, err := os.ReadFile(filepath.Join(tmpDir, "handlers.go"))
+	if err != nil {
+		t.Fatalf("failed to read handlers.go: %v", err)
+	}
+	if !strings.Contains(string(handlersContent), "output := getOutput(cmd)") {
+		t.Error("handlers.go should use the typed enum getter")
+	}
+}
+
 func TestE2E_Gen_WithOutputPaths(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -367,6 +513,41 @@ func TestE2E_Gen_InvalidYAML(t *testing.T) {
 	}
 }
 
+func TestE2E_Gen_Strict(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlContent := `name: strict-cli
+root:
+  use: strict-cli
+  short: Strict CLI
+commands:
+  hello:
+    use: hello
+    short: Say hello
+    run_func: runHello
+`
+	yamlPath := filepath.Join(tmpDir, "commands.yaml")
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write commands.yaml: %v", err)
+	}
+
+	_, stderr, err := runCobrayaml(t, tmpDir, "gen", "commands.yaml", "--strict")
+	if err == nil {
+		t.Fatal("expected error for warnings with --strict")
+	}
+	if !strings.Contains(stderr, `Warning: command "hello": no long description`) {
+		t.Errorf("stderr should contain the warning, got: %s", stderr)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "handlers.go")); err == nil {
+		t.Error("handlers.go should not be generated with --strict and warnings")
+	}
+
+	// Without --strict, warnings are printed but do not fail
+	if _, stderr, err := runCobrayaml(t, tmpDir, "gen", "commands.yaml"); err != nil {
+		t.Fatalf("gen command failed: %v\nstderr: %s", err, stderr)
+	}
+}
+
 func TestE2E_Gen_MissingFile(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -376,6 +557,106 @@ func TestE2E_Gen_MissingFile(t *testing.T) {
 	}
 }
 
+// ============================================================================
+// schedule command E2E tests
+// ============================================================================
+
+func TestE2E_Schedule(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlContent := `name: test-cli
+root:
+  use: test-cli
+  short: Test CLI
+commands:
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "commands.yaml"), []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write commands.yaml: %v", err)
+	}
+
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "schedule", "commands.yaml", "db.backup", "--cron", "0 3 * * *", "--", "--target", "s3://backups")
+	if err != nil {
+		t.Fatalf("schedule failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	want := "# test-cli db backup: Back up the database\n0 3 * * * test-cli db backup --target s3://backups\n"
+	if stdout != want {
+		t.Errorf("schedule output = %q, want %q", stdout, want)
+	}
+
+	outDir := filepath.Join(tmpDir, "units")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, err := runCobrayaml(t, tmpDir, "schedule", "commands.yaml", "db.backup", "--cron", "@daily", "--format", "systemd", "--binary", "/usr/local/bin/test-cli", "-o", outDir); err != nil {
+		t.Fatalf("schedule --format systemd failed: %v\nstderr: %s", err, stderr)
+	}
+	timer, err := os.ReadFile(filepath.Join(outDir, "test-cli-db-backup.timer"))
+	if err != nil || !strings.Contains(string(timer), "OnCalendar=daily") {
+		t.Errorf("timer unit = %q, %v", timer, err)
+	}
+	service, err := os.ReadFile(filepath.Join(outDir, "test-cli-db-backup.service"))
+	if err != nil || !strings.Contains(string(service), "ExecStart=/usr/local/bin/test-cli db backup\n") {
+		t.Errorf("service unit = %q, %v", service, err)
+	}
+
+	if _, _, err := runCobrayaml(t, tmpDir, "schedule", "commands.yaml", "db", "--cron", "@daily"); err == nil {
+		t.Error("expected error for a command without run_func")
+	}
+	if _, _, err := runCobrayaml(t, tmpDir, "schedule", "commands.yaml", "db.backup"); err == nil {
+		t.Error("expected error without --cron")
+	}
+}
+
+func TestE2E_Manifest(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlContent := `name: test-cli
+root:
+  use: test-cli
+  short: Test CLI
+commands:
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "commands.yaml"), []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write commands.yaml: %v", err)
+	}
+
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "manifest", "commands.yaml")
+	if err != nil {
+		t.Fatalf("manifest failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, `"name": "test-cli_db_backup"`) || !strings.Contains(stdout, `"inputSchema"`) {
+		t.Errorf("manifest output = %s", stdout)
+	}
+
+	if _, stderr, err := runCobrayaml(t, tmpDir, "manifest", "commands.yaml", "--format", "openai", "-o", "tools.json"); err != nil {
+		t.Fatalf("manifest --format openai failed: %v\nstderr: %s", err, stderr)
+	}
+	tools, err := os.ReadFile(filepath.Join(tmpDir, "tools.json"))
+	if err != nil || !strings.Contains(string(tools), `"type": "function"`) {
+		t.Errorf("tools.json = %q, %v", tools, err)
+	}
+
+	if _, _, err := runCobrayaml(t, tmpDir, "manifest", "commands.yaml", "--format", "yaml"); err == nil {
+		t.Error("expected error for an unsupported format")
+	}
+}
+
 // ============================================================================
 // docs command E2E tests
 // ============================================================================
@@ -512,6 +793,69 @@ commands:
 	}
 }
 
+// ============================================================================
+// check-handlers command E2E tests
+// ============================================================================
+
+func TestE2E_CheckHandlers(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlContent := `name: test-cli
+root:
+  use: test-cli
+  short: Test CLI
+commands:
+  greet:
+    use: greet
+    short: Greet someone
+    run_func: handleGreet
+  wave:
+    use: wave
+    short: Wave
+    run_func: handleWave
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "commands.yaml"), []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write commands.yaml: %v", err)
+	}
+
+	// Generated main.go registers every function
+	_, _, err := runCobrayaml(t, tmpDir, "gen", "commands.yaml")
+	if err != nil {
+		t.Fatalf("gen failed: %v", err)
+	}
+	stdout, _, err := runCobrayaml(t, tmpDir, "check-handlers", "commands.yaml")
+	if err != nil {
+		t.Fatalf("check-handlers failed: %v", err)
+	}
+	if !strings.Contains(stdout, "All functions referenced in YAML are registered.") {
+		t.Errorf("unexpected output: %s", stdout)
+	}
+
+	// A hand-written main.go that misses one registration and keeps an old one
+	mainGo := `package main
+
+func main() {
+	builder.RegisterFunction("handleGreet", handleGreet)
+	builder.RegisterFunction("handleOld", handleOld)
+}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(mainGo), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+	stdout, _, err = runCobrayaml(t, tmpDir, "check-handlers", "commands.yaml", "./...")
+	if err == nil {
+		t.Fatal("expected error for mismatched registrations")
+	}
+	for _, want := range []string{
+		"Referenced in YAML but not registered:\n  handleWave (run, wave)",
+		"Registered but not referenced in YAML:\n  handleOld",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output should contain %q, got: %s", want, stdout)
+		}
+	}
+}
+
 // ============================================================================
 // Generated code compile and execute E2E tests
 // ============================================================================
@@ -1121,3 +1465,57 @@ func logFileContent(t *testing.T, filePath string) {
 
 	t.Logf("--- Content of %s ---\n%s\n--- End of %s ---", filepath.Base(filePath), string(content), filepath.Base(filePath))
 }
+
+func TestE2E_VerifyExamples(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlContent := `name: test-cli
+examples_dir: examples
+root:
+  use: test-cli
+  short: Test CLI
+commands:
+  greet:
+    use: greet <name>
+    short: Greet someone
+    run_func: runGreet
+    examples: [greet.sh]
+    args:
+      type: exact
+      count: 1
+    flags:
+      - name: times
+        type: int
+        usage: Number of greetings
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "commands.yaml"), []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write commands.yaml: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(tmpDir, "examples"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeExample := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tmpDir, "examples", "greet.sh"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeExample("test-cli greet alice --times 2\n")
+	stdout, stderr, err := runCobrayaml(t, tmpDir, "verify-examples", "commands.yaml")
+	if err != nil {
+		t.Fatalf("verify-examples failed: %v\nstdout: %s\nstderr: %s", err, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "All 1 example invocations parse.") {
+		t.Errorf("verify-examples output = %s", stdout)
+	}
+
+	writeExample("test-cli greet alice --count 2\n")
+	stdout, stderr, err = runCobrayaml(t, tmpDir, "verify-examples", "commands.yaml")
+	if err == nil {
+		t.Fatal("expected error for an unknown flag")
+	}
+	if !strings.Contains(stdout, "greet.sh:1: greet alice --count 2: unknown flag: --count") || !strings.Contains(stderr, "1 of 1 example invocation(s) fail") {
+		t.Errorf("verify-examples stdout = %s, stderr = %s", stdout, stderr)
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/S-mishina/cobrayaml"
+	"github.com/S-mishina/cobrayaml/webui"
 	"github.com/spf13/cobra"
 )
 
@@ -19,10 +23,17 @@ func main() {
 		Short:   "YAML-based command builder for cobra CLI applications",
 		Version: version,
 	}
+	rootCmd.SetVersionTemplate(fmt.Sprintf("cobrayaml version {{.Version}} (schema version %d)\n", cobrayaml.SchemaVersion()))
 
 	rootCmd.AddCommand(genCmd())
 	rootCmd.AddCommand(initCmd())
 	rootCmd.AddCommand(docsCmd())
+	rootCmd.AddCommand(checkHandlersCmd())
+	rootCmd.AddCommand(verifyExamplesCmd())
+	rootCmd.AddCommand(referenceCmd())
+	rootCmd.AddCommand(scheduleCmd())
+	rootCmd.AddCommand(uiCmd())
+	rootCmd.AddCommand(manifestCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -35,17 +46,28 @@ func genCmd() *cobra.Command {
 		outputPath     string
 		mainOutputPath string
 		force          bool
+		enums          bool
+		sortFuncs      bool
+		strict         bool
+		contractTest   bool
+		include        []string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "gen <commands.yaml>",
 		Short: "Generate handler function stubs and main.go from YAML",
 		Long: `Generate Go handler function stubs and main.go based on the run_func definitions in your YAML file.
+When the YAML declares a license, the files start with a license header and
+THIRD_PARTY_NOTICES.md is written next to main.go.
 
 Example:
   cobrayaml gen commands.yaml
   cobrayaml gen commands.yaml -p mypackage -o handlers.go -m main.go
-  cobrayaml gen commands.yaml --force`,
+  cobrayaml gen commands.yaml --force
+  cobrayaml gen commands.yaml --enums
+  cobrayaml gen commands.yaml --contract-test
+  cobrayaml gen commands.yaml --include 'db.*,user.list'
+  cobrayaml gen commands.yaml --strict`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			yamlPath := args[0]
@@ -54,6 +76,21 @@ Example:
 			if err != nil {
 				return fmt.Errorf("failed to load YAML: %w", err)
 			}
+			gen.SetEnumTypes(enums)
+			gen.SetSortRegistrations(sortFuncs)
+			if len(include) > 0 {
+				if err := gen.Include(include...); err != nil {
+					return err
+				}
+			}
+			warnings := append(gen.ConfigWarnings(), gen.Warnings()...)
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+			}
+			if strict && len(warnings) > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d warning(s) with --strict", len(warnings))
+			}
 
 			dir := filepath.Dir(yamlPath)
 			if outputPath == "" {
@@ -62,18 +99,34 @@ Example:
 			if mainOutputPath == "" {
 				mainOutputPath = filepath.Join(dir, "main.go")
 			}
+			enumsOutputPath := filepath.Join(filepath.Dir(outputPath), "enums.go")
+			contractOutputPath := filepath.Join(filepath.Dir(mainOutputPath), "contract_test.go")
+			notice := gen.License() != nil
+			noticeOutputPath := filepath.Join(filepath.Dir(mainOutputPath), cobrayaml.NoticeFile)
 
 			// Check if files already exist
 			handlersExist := false
 			mainExist := false
+			enumsExist := false
+			contractExist := false
+			noticeExist := false
 			if _, err := os.Stat(outputPath); err == nil {
 				handlersExist = true
 			}
 			if _, err := os.Stat(mainOutputPath); err == nil {
 				mainExist = true
 			}
+			if _, err := os.Stat(enumsOutputPath); err == nil && enums {
+				enumsExist = true
+			}
+			if _, err := os.Stat(contractOutputPath); err == nil && contractTest {
+				contractExist = true
+			}
+			if _, err := os.Stat(noticeOutputPath); err == nil && notice {
+				noticeExist = true
+			}
 
-			if (handlersExist || mainExist) && !force {
+			if (handlersExist || mainExist || enumsExist || contractExist || noticeExist) && !force {
 				var existingFiles []string
 				if handlersExist {
 					existingFiles = append(existingFiles, outputPath)
@@ -81,6 +134,15 @@ Example:
 				if mainExist {
 					existingFiles = append(existingFiles, mainOutputPath)
 				}
+				if enumsExist {
+					existingFiles = append(existingFiles, enumsOutputPath)
+				}
+				if contractExist {
+					existingFiles = append(existingFiles, contractOutputPath)
+				}
+				if noticeExist {
+					existingFiles = append(existingFiles, noticeOutputPath)
+				}
 				fmt.Printf("Warning: %v already exist(s). Use --force to overwrite.\n", existingFiles)
 				fmt.Println("Generated code preview:")
 				fmt.Println("------------------------")
@@ -96,6 +158,30 @@ Example:
 					return err
 				}
 				fmt.Println(mainCode)
+				if enums {
+					fmt.Println("// enums.go")
+					enumsCode, err := gen.GenerateEnums(packageName)
+					if err != nil {
+						return err
+					}
+					fmt.Println(enumsCode)
+				}
+				if contractTest {
+					fmt.Println("// contract_test.go")
+					contractCode, err := gen.GenerateContractTest(packageName)
+					if err != nil {
+						return err
+					}
+					fmt.Println(contractCode)
+				}
+				if notice {
+					fmt.Println("// " + cobrayaml.NoticeFile)
+					noticeText, err := gen.GenerateNotice()
+					if err != nil {
+						return err
+					}
+					fmt.Println(noticeText)
+				}
 				return nil
 			}
 
@@ -115,6 +201,30 @@ Example:
 				fmt.Printf("Generated main at: %s\n", mainOutputPath)
 			}
 
+			// Generate enums.go
+			if enums && (!enumsExist || force) {
+				if err := gen.GenerateEnumsToFile(packageName, enumsOutputPath); err != nil {
+					return fmt.Errorf("failed to generate enums: %w", err)
+				}
+				fmt.Printf("Generated enums at: %s\n", enumsOutputPath)
+			}
+
+			// Generate contract_test.go
+			if contractTest && (!contractExist || force) {
+				if err := gen.GenerateContractTestToFile(packageName, contractOutputPath); err != nil {
+					return fmt.Errorf("failed to generate contract test: %w", err)
+				}
+				fmt.Printf("Generated contract test at: %s\n", contractOutputPath)
+			}
+
+			// Generate the third-party notice
+			if notice && (!noticeExist || force) {
+				if err := gen.GenerateNoticeToFile(noticeOutputPath); err != nil {
+					return fmt.Errorf("failed to generate notice: %w", err)
+				}
+				fmt.Printf("Generated notice at: %s\n", noticeOutputPath)
+			}
+
 			return nil
 		},
 	}
@@ -123,11 +233,22 @@ Example:
 	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output file path for handlers (default: handlers.go)")
 	cmd.Flags().StringVarP(&mainOutputPath, "main", "m", "", "Output file path for main.go (default: main.go)")
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing files")
+	cmd.Flags().BoolVar(&enums, "enums", false, "Generate Go enum types for flags with allowed_values (enums.go)")
+	cmd.Flags().BoolVar(&contractTest, "contract-test", false, "Generate a test checking the built commands against the YAML (contract_test.go)")
+	cmd.Flags().BoolVar(&sortFuncs, "sort", false, "Sort the functions registered in main.go by function name")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail if the YAML has warnings (for CI)")
+	cmd.Flags().StringSliceVar(&include, "include", nil, "Generate only the commands matching these paths, e.g. db.*,user.list")
 
 	return cmd
 }
 
 func initCmd() *cobra.Command {
+	var (
+		withExamples bool
+		license      string
+		copyright    string
+	)
+
 	cmd := &cobra.Command{
 		Use:   "init [name]",
 		Short: "Create a new commands.yaml template",
@@ -139,7 +260,16 @@ func initCmd() *cobra.Command {
 			}
 
 			// Generate template from actual types
-			template := cobrayaml.GenerateInitTemplate(name)
+			opts := cobrayaml.InitOptions{Examples: withExamples}
+			if license != "" {
+				opts.License = &cobrayaml.LicenseConfig{SPDX: license, Holder: copyright, Year: time.Now().Year()}
+			} else if copyright != "" {
+				return fmt.Errorf("--copyright requires --license")
+			}
+			template, err := cobrayaml.GenerateInitTemplateWithOptions(name, opts)
+			if err != nil {
+				return err
+			}
 
 			outputPath := "commands.yaml"
 			if _, err := os.Stat(outputPath); err == nil {
@@ -160,6 +290,10 @@ func initCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&withExamples, "with-examples", false, "Include commented-out examples of advanced fields")
+	cmd.Flags().StringVar(&license, "license", "", "SPDX license identifier of the tool (e.g. Apache-2.0); gen then adds license headers and "+cobrayaml.NoticeFile)
+	cmd.Flags().StringVar(&copyright, "copyright", "", "Copyright holder written into the license headers (requires --license)")
+
 	return cmd
 }
 
@@ -207,3 +341,319 @@ Example:
 
 	return cmd
 }
+
+func checkHandlersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check-handlers <commands.yaml> [dir...]",
+		Short: "Check that YAML functions and registered functions match",
+		Long: `Parse the Go files in the given directories (default: the directory of commands.yaml)
+and report functions referenced in YAML that are never registered with RegisterFunction
+or RegisterFunctions, as well as registered functions that no YAML entry references.
+
+A directory ending in "/..." is searched recursively.
+
+Example:
+  cobrayaml check-handlers commands.yaml
+  cobrayaml check-handlers commands.yaml ./...`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yamlPath := args[0]
+
+			gen, err := cobrayaml.NewGenerator(yamlPath)
+			if err != nil {
+				return fmt.Errorf("failed to load YAML: %w", err)
+			}
+
+			dirs := args[1:]
+			if len(dirs) == 0 {
+				dirs = []string{filepath.Dir(yamlPath)}
+			}
+			check, err := gen.CheckHandlers(dirs...)
+			if err != nil {
+				return fmt.Errorf("failed to parse Go files: %w", err)
+			}
+
+			if check.OK() {
+				fmt.Println("All functions referenced in YAML are registered.")
+				return nil
+			}
+			if len(check.Unregistered) > 0 {
+				fmt.Println("Referenced in YAML but not registered:")
+				for _, fn := range check.Unregistered {
+					fmt.Printf("  %s (%s, %s)\n", fn.Name, fn.Kind, fn.CmdPath)
+				}
+			}
+			if len(check.Orphans) > 0 {
+				fmt.Println("Registered but not referenced in YAML:")
+				for _, name := range check.Orphans {
+					fmt.Printf("  %s\n", name)
+				}
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d unregistered and %d orphaned function(s)", len(check.Unregistered), len(check.Orphans))
+		},
+	}
+
+	return cmd
+}
+
+func verifyExamplesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-examples <commands.yaml>",
+		Short: "Check that the example scripts of the commands still parse",
+		Long: `Parse every invocation of the tool in the example scripts listed in the
+examples of the commands against the current commands, flags and args of
+commands.yaml, without running any handler. The scripts are read from
+examples_dir, relative to the directory of commands.yaml.
+
+Example:
+  cobrayaml verify-examples commands.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			yamlPath := args[0]
+
+			gen, err := cobrayaml.NewGenerator(yamlPath)
+			if err != nil {
+				return fmt.Errorf("failed to load YAML: %w", err)
+			}
+
+			invocations, err := gen.VerifyExamples(filepath.Dir(yamlPath))
+			if err != nil {
+				return err
+			}
+			if len(invocations) == 0 {
+				fmt.Println("No examples to verify.")
+				return nil
+			}
+
+			failed := 0
+			for _, invocation := range invocations {
+				if invocation.Err == nil {
+					continue
+				}
+				failed++
+				if invocation.Line == 0 {
+					fmt.Printf("%s: %v\n", invocation.File, invocation.Err)
+					continue
+				}
+				fmt.Printf("%s:%d: %s: %v\n", invocation.File, invocation.Line, invocation, invocation.Err)
+			}
+			if failed == 0 {
+				fmt.Printf("All %d example invocations parse.\n", len(invocations))
+				return nil
+			}
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d of %d example invocation(s) fail", failed, len(invocations))
+		},
+	}
+
+	return cmd
+}
+
+func referenceCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "reference",
+		Short: "Print the YAML configuration reference",
+		Long: `Print the reference of every commands.yaml field, flag type, args type and
+built-in transformer supported by this version of cobrayaml.
+
+Example:
+  cobrayaml reference
+  cobrayaml reference --format json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			docGen := cobrayaml.NewDocGenerator()
+
+			switch format {
+			case "md":
+				fmt.Print(docGen.GenerateYAMLReference())
+			case "json":
+				ref, err := docGen.GenerateYAMLReferenceJSON()
+				if err != nil {
+					return fmt.Errorf("failed to generate reference: %w", err)
+				}
+				fmt.Print(ref)
+			default:
+				return fmt.Errorf("unsupported format %q (supported: md, json)", format)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "md", "Output format (md or json)")
+
+	return cmd
+}
+
+func scheduleCmd() *cobra.Command {
+	var (
+		cron      string
+		format    string
+		binary    string
+		outputDir string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "schedule <commands.yaml> <command> [-- args...]",
+		Short: "Generate a crontab line or systemd units that run a command on a schedule",
+		Long: `Generate a crontab line or a systemd service and timer unit that run a command
+of the built binary on a cron schedule. The command is given by its path with dots
+(db.backup), and args and flags after -- are passed to it.
+
+Example:
+  cobrayaml schedule commands.yaml db.backup --cron "0 3 * * *"
+  cobrayaml schedule commands.yaml db.backup --cron @daily --format systemd -o /etc/systemd/system -- --compress`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var cmdArgs []string
+			if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+				if dash != 2 {
+					return fmt.Errorf("expected <commands.yaml> <command> before --")
+				}
+				cmdArgs = args[dash:]
+			} else if len(args) > 2 {
+				return fmt.Errorf("pass args for the command after --")
+			}
+
+			gen, err := cobrayaml.NewGenerator(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load YAML: %w", err)
+			}
+
+			files, err := gen.GenerateSchedule(args[1], cobrayaml.ScheduleOptions{
+				Cron:   cron,
+				Format: format,
+				Binary: binary,
+				Args:   cmdArgs,
+			})
+			if err != nil {
+				return err
+			}
+
+			if outputDir == "" {
+				for i, file := range files {
+					if len(files) > 1 {
+						if i > 0 {
+							fmt.Println()
+						}
+						fmt.Printf("# %s\n", file.Name)
+					}
+					fmt.Print(file.Content)
+				}
+				return nil
+			}
+
+			for _, file := range files {
+				path := filepath.Join(outputDir, file.Name)
+				if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+					return fmt.Errorf("failed to write file: %w", err)
+				}
+				fmt.Printf("Generated %s\n", path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cron, "cron", "", "Cron schedule, such as \"0 3 * * *\" or @daily")
+	cmd.Flags().StringVar(&format, "format", cobrayaml.ScheduleFormatCrontab, "Output format (crontab or systemd)")
+	cmd.Flags().StringVar(&binary, "binary", "", "Path of the built binary (default: the tool name)")
+	cmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Directory to write the files to (default: stdout)")
+	_ = cmd.MarkFlagRequired("cron")
+
+	return cmd
+}
+
+func uiCmd() *cobra.Command {
+	var (
+		addr   string
+		binary string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "ui <commands.yaml>",
+		Short: "Serve a web UI with a form per command that runs the built binary",
+		Long: `Serve a web page with a form per runnable command, with an input per flag and
+a field per argument. Submitting a form runs the command with the built binary
+on the server and streams its output to the page.
+
+The UI has no authentication; serve it on a trusted network. It only answers
+requests to an IP address, localhost or the host of --serve. Tools that register
+their handlers in-process can serve webui.Handler with webui.BuilderRunner.
+
+Example:
+  cobrayaml ui commands.yaml
+  cobrayaml ui commands.yaml --serve :8080 --binary ./bin/mytool`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			builder, err := cobrayaml.NewCommandBuilder(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load YAML: %w", err)
+			}
+			config := builder.GetConfig()
+			if binary == "" {
+				binary = config.Name
+			}
+
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				return fmt.Errorf("invalid --serve address: %w", err)
+			}
+
+			fmt.Printf("Serving the web UI at http://%s\n", addr)
+			return http.ListenAndServe(addr, webui.Handler(config, webui.BinaryRunner(binary), host))
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "serve", "localhost:8080", "Address to serve the web UI on")
+	cmd.Flags().StringVar(&binary, "binary", "", "Path of the built binary (default: the tool name)")
+
+	return cmd
+}
+
+func manifestCmd() *cobra.Command {
+	var (
+		format     string
+		outputPath string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "manifest <commands.yaml>",
+		Short: "Export the commands as a tool manifest for AI agents and MCP servers",
+		Long: `Export the runnable commands as tools for LLM agents: a name, a description and
+a JSON Schema of the parameters derived from the flags and args of each command.
+The mcp format is the result of an MCP tools/list request; the openai format is a
+list of function tools. Sensitive flags are left out.
+
+Example:
+  cobrayaml manifest commands.yaml
+  cobrayaml manifest commands.yaml --format openai -o tools.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gen, err := cobrayaml.NewGenerator(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to load YAML: %w", err)
+			}
+
+			manifest, err := gen.GenerateToolManifest(format)
+			if err != nil {
+				return err
+			}
+			if outputPath == "" {
+				fmt.Print(manifest)
+				return nil
+			}
+			if err := os.WriteFile(outputPath, []byte(manifest), 0644); err != nil {
+				return fmt.Errorf("failed to write file: %w", err)
+			}
+			fmt.Printf("Generated tool manifest at: %s\n", outputPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", cobrayaml.ManifestFormatMCP, "Output format (mcp or openai)")
+	cmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output file path (default: stdout)")
+
+	return cmd
+}
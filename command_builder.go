@@ -10,33 +10,61 @@
 // 1. Create a commands.yaml file defining your CLI structure
 // 2. Use NewCommandBuilder to load the configuration
 // 3. Register your handler functions with RegisterFunction
-// 4. Build and execute with BuildRootCommand
+// 4. Build and execute with Execute, or build with BuildRootCommand
 //
 // Example:
 //
 //	builder, _ := cobrayaml.NewCommandBuilder("commands.yaml")
 //	builder.RegisterFunction("runList", runList)
-//	rootCmd, _ := builder.BuildRootCommand()
-//	rootCmd.Execute()
+//	if err := builder.Execute(); err != nil {
+//		os.Exit(1)
+//	}
+//
+// # API Overview
+//
+// The core of the package is a single import, and its API falls into five areas:
+//
+//   - Configuration: ToolConfig, CommandConfig, FlagConfig, ValidateConfig and FromCobra
+//   - Building: NewCommandBuilder, RegisterFunction, Execute, BuildRootCommand, AttachTo,
+//     DisableFeatures and SetPolicy
+//   - Handler helpers: Bind, FlagValues, IdentityFromContext, GetEnv, GetDerived, GetSetting, Pool and OnCleanup
+//   - Code generation: NewGenerator, GenerateHandlers, GenerateEnums and GenerateMain
+//   - Documentation: GenerateDocs, NewDocGenerator and FieldCatalog
+//
+// A CLI that only builds commands at runtime does not link the code generation or
+// documentation code; the Go linker drops it as unreferenced. The code that needs
+// net/http lives in subpackages a CLI imports only when it uses them: webhook
+// posts webhook notifications and events, and webui serves the web UI.
 package cobrayaml
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"maps"
+	"net"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"gopkg.in/yaml.v2"
 )
 
-
 // ArgsConfig represents argument validation configuration in commands.yaml.
 //
 // Fields:
-//   - Type: Validation type (none, any, exact, min, max, range)
+//   - Type: Validation type (none, any, exact, min, max, range, only_valid_args)
 //   - Count: Required count for "exact" type
 //   - Min: Minimum count for "min" or "range" type
 //   - Max: Maximum count for "max" or "range" type
+//   - OnlyValid: Reject arguments not listed in the command's valid_args
 //
 // Example YAML:
 //
@@ -44,15 +72,26 @@ import (
 //	  type: exact
 //	  count: 2
 //
+//	valid_args: [pods, services]
+//	args:
+//	  type: only_valid_args
+//
+//	valid_args: [pods, services]
+//	args:
+//	  type: exact
+//	  count: 1
+//	  only_valid: true
+//
 //	args:
 //	  type: range
 //	  min: 1
 //	  max: 3
 type ArgsConfig struct {
-	Type  string `yaml:"type"`            // none, any, exact, min, max, range
-	Count int    `yaml:"count,omitempty"` // for exact
-	Min   int    `yaml:"min,omitempty"`   // for min, range
-	Max   int    `yaml:"max,omitempty"`   // for max, range
+	Type      string `yaml:"type"`                 // none, any, exact, min, max, range, only_valid_args
+	Count     int    `yaml:"count,omitempty"`      // for exact
+	Min       int    `yaml:"min,omitempty"`        // for min, range
+	Max       int    `yaml:"max,omitempty"`        // for max, range
+	OnlyValid bool   `yaml:"only_valid,omitempty"` // reject args not in valid_args
 }
 
 // Supported args types for commands.yaml.
@@ -63,6 +102,8 @@ const (
 	ArgsTypeMin   = "min"
 	ArgsTypeMax   = "max"
 	ArgsTypeRange = "range"
+	// ArgsTypeOnlyValidArgs accepts any number of arguments listed in valid_args.
+	ArgsTypeOnlyValidArgs = "only_valid_args"
 )
 
 // SupportedArgsTypes lists all supported argument validation types.
@@ -73,6 +114,7 @@ var SupportedArgsTypes = []string{
 	ArgsTypeMin,
 	ArgsTypeMax,
 	ArgsTypeRange,
+	ArgsTypeOnlyValidArgs,
 }
 
 // Supported flag types for commands.yaml.
@@ -94,10 +136,71 @@ const (
 	// Example: --timeout 30
 	FlagTypeInt = "int"
 
+	// FlagTypeUint represents a non-negative integer flag; negative values are rejected when parsed.
+	// Go type: uint
+	// Example: --replicas 3
+	FlagTypeUint = "uint"
+
+	// FlagTypeUint64 represents a non-negative 64-bit integer flag.
+	// Go type: uint64
+	// Example: --max-bytes 1048576
+	FlagTypeUint64 = "uint64"
+
 	// FlagTypeStringSlice represents a comma-separated string list flag.
 	// Go type: []string
 	// Example: --tags a,b,c
 	FlagTypeStringSlice = "stringSlice"
+
+	// FlagTypeStringArray represents a repeatable string flag whose values are kept
+	// verbatim (commas are not split, unlike stringSlice).
+	// Go type: []string
+	// Example: --header "Accept: a, b" --header "X-Id: 1"
+	FlagTypeStringArray = "stringArray"
+
+	// FlagTypeFile represents a file path flag validated when parsed.
+	// The file must exist unless must_exist is false.
+	// Go type: string
+	// Options: must_exist, extensions, create_missing
+	// Example: --input data.yaml
+	FlagTypeFile = "file"
+
+	// FlagTypeDir represents a directory path flag validated when parsed.
+	// The directory must exist unless must_exist is false.
+	// Go type: string
+	// Options: must_exist, create_missing
+	// Example: --output-dir ./out
+	FlagTypeDir = "dir"
+
+	// FlagTypeURL represents an absolute URL flag.
+	// Go type: *url.URL (read with cobrayaml.GetURL)
+	// Example: --endpoint https://api.example.com
+	FlagTypeURL = "url"
+
+	// FlagTypeIP represents an IPv4 or IPv6 address flag.
+	// Go type: net.IP
+	// Example: --bind 127.0.0.1
+	FlagTypeIP = "ip"
+
+	// FlagTypeCIDR represents an IP network flag in CIDR notation.
+	// Go type: net.IPNet
+	// Example: --subnet 10.0.0.0/16
+	FlagTypeCIDR = "cidr"
+
+	// FlagTypeDuration represents a duration flag.
+	// Go type: time.Duration
+	// Example: --timeout 1m30s
+	FlagTypeDuration = "duration"
+
+	// FlagTypeByteSize represents a size in bytes with an optional unit.
+	// Go type: int64 (read with cobrayaml.GetByteSize)
+	// Example: --max-size 10MiB
+	FlagTypeByteSize = "bytesize"
+
+	// FlagTypeTime represents a point in time parsed with a configurable layout.
+	// Go type: time.Time (read with cobrayaml.GetTime)
+	// Options: layout, relative
+	// Example: --since 2024-01-02T15:04:05Z
+	FlagTypeTime = "time"
 )
 
 // SupportedFlagTypes lists all supported flag types.
@@ -105,7 +208,18 @@ var SupportedFlagTypes = []string{
 	FlagTypeString,
 	FlagTypeBool,
 	FlagTypeInt,
+	FlagTypeUint,
+	FlagTypeUint64,
 	FlagTypeStringSlice,
+	FlagTypeStringArray,
+	FlagTypeFile,
+	FlagTypeDir,
+	FlagTypeURL,
+	FlagTypeIP,
+	FlagTypeCIDR,
+	FlagTypeDuration,
+	FlagTypeByteSize,
+	FlagTypeTime,
 }
 
 // CommandConfig represents a command configuration in commands.yaml.
@@ -116,20 +230,80 @@ var SupportedFlagTypes = []string{
 //   - Short: Brief description shown in help
 //   - Long: Detailed description
 //   - Args: Argument validation configuration (see ArgsConfig)
+//   - ValidArgs: Values shells complete for positional arguments
+//   - CompletionFunc: Name of a function registered with RegisterCompletionFunc that
+//     completes positional arguments at runtime (see CompletionFunc)
+//   - UndoFunc: Name of a function registered with RegisterFunction that reverts a run
+//     of the command; "undo" calls it with the args and flags of a run from the history
 //   - RunFunc: Name of the handler function registered with RegisterFunction
+//   - ValidateFunc: Name of a function registered with RegisterFunction that validates
+//     parsed flags and args together before RunFunc is called
 //   - Flags: List of flag definitions
 //   - Commands: Nested subcommands
 //   - Hidden: Hide command from help output
+//   - RenamedFrom: Former command names kept as hidden, deprecated shims
+//   - Derived: Values computed from other flags before the handler runs (see DerivedConfig)
+//   - Env: Environment variables the command reads (see EnvConfig)
+//   - RequiresConfig: Fail before the handler runs if the tool's config file does not exist
+//   - DynamicCommandsFunc: Name of a function registered with RegisterFunction that returns
+//     subcommands resolved at runtime (see DynamicCommandsFunc)
+//   - Cache: Serve the command's output from a cache within a TTL (see CacheConfig)
+//   - Background: Set to "supported" to allow running the command as a background
+//     job with --detach, managed with the jobs command
+//   - Touches: Sensitive paths the command accesses; the user is asked once to allow them
+//   - Annotations: Metadata passed through to the cobra command's Annotations for downstream tooling
+//   - Variants: Alternate short and long descriptions keyed by name, for help text
+//     experiments (see HelpVariantConfig)
+//   - Deprecated: Deprecation message (e.g., "use 'deploy' instead"); the command is
+//     hidden from help and running it prints the message
+//   - SilenceUsage: Do not print the usage when the command fails
+//   - SilenceErrors: Do not print the error when the command fails, e.g. because the
+//     handler reports errors itself
+//   - DisableFlagParsing: Pass all tokens, including flags and --help, to the handler
+//     as args, e.g. for wrappers that proxy to another binary
+//   - Examples: Example scripts in the tool's examples_dir whose invocations are
+//     checked by "cobrayaml verify-examples"
+//   - SideEffects: What running the command does: read-only, mutating or destructive
+//     (see SupportedSideEffects); destructive commands ask for confirmation unless --yes is set
+//   - Defaults: Flag defaults overridden for all descendant commands (see DefaultsConfig)
+//   - Quota: Name of the usage quota checked with the builder's QuotaChecker before the
+//     command runs (e.g., "deployments-per-day")
 type CommandConfig struct {
-	Use      string                   `yaml:"use"`
-	Aliases  []string                 `yaml:"aliases,omitempty"`
-	Short    string                   `yaml:"short"`
-	Long     string                   `yaml:"long,omitempty"`
-	Args     *ArgsConfig              `yaml:"args,omitempty"`
-	RunFunc  string                   `yaml:"run_func,omitempty"`
-	Flags    []FlagConfig             `yaml:"flags,omitempty"`
-	Commands map[string]CommandConfig `yaml:"commands,omitempty"`
-	Hidden   bool                     `yaml:"hidden,omitempty"`
+	Use                 string                       `yaml:"use"`
+	Aliases             []string                     `yaml:"aliases,omitempty"`
+	Short               string                       `yaml:"short"`
+	Long                string                       `yaml:"long,omitempty"`
+	Args                *ArgsConfig                  `yaml:"args,omitempty"`
+	ValidArgs           []string                     `yaml:"valid_args,omitempty"`
+	CompletionFunc      string                       `yaml:"completion_func,omitempty"`
+	RunFunc             string                       `yaml:"run_func,omitempty"`
+	ValidateFunc        string                       `yaml:"validate_func,omitempty"`
+	UndoFunc            string                       `yaml:"undo_func,omitempty"`
+	Flags               []FlagConfig                 `yaml:"flags,omitempty"`
+	Commands            map[string]CommandConfig     `yaml:"commands,omitempty"`
+	Hidden              bool                         `yaml:"hidden,omitempty"`
+	RenamedFrom         []string                     `yaml:"renamed_from,omitempty"`
+	Derived             []DerivedConfig              `yaml:"derived,omitempty"`
+	Env                 []EnvConfig                  `yaml:"env,omitempty"`
+	RequiresConfig      bool                         `yaml:"requires_config,omitempty"`
+	DynamicCommandsFunc string                       `yaml:"dynamic_commands_func,omitempty"`
+	Cache               *CacheConfig                 `yaml:"cache,omitempty"`
+	Background          string                       `yaml:"background,omitempty"`
+	SilenceUsage        bool                         `yaml:"silence_usage,omitempty"`
+	SilenceErrors       bool                         `yaml:"silence_errors,omitempty"`
+	DisableFlagParsing  bool                         `yaml:"disable_flag_parsing,omitempty"`
+	Notify              *NotifyConfig                `yaml:"notify,omitempty"`
+	RequiredTogether    [][]string                   `yaml:"required_together,omitempty"`
+	OneRequired         [][]string                   `yaml:"one_required,omitempty"`
+	Concurrency         *ConcurrencyConfig           `yaml:"concurrency,omitempty"`
+	Touches             []string                     `yaml:"touches,omitempty"`
+	Annotations         map[string]string            `yaml:"annotations,omitempty"`
+	Variants            map[string]HelpVariantConfig `yaml:"variants,omitempty"`
+	Deprecated          string                       `yaml:"deprecated,omitempty"`
+	Examples            []string                     `yaml:"examples,omitempty"`
+	SideEffects         string                       `yaml:"side_effects,omitempty"`
+	Defaults            *DefaultsConfig              `yaml:"defaults,omitempty"`
+	Quota               string                       `yaml:"quota,omitempty"`
 }
 
 // FlagConfig represents a flag configuration in commands.yaml.
@@ -143,24 +317,326 @@ type CommandConfig struct {
 //   - Required: Mark flag as required
 //   - Persistent: Inherit flag to all subcommands
 //   - Hidden: Hide flag from help output
+//   - TransformFunc: Name of a transformer applied to the parsed value before the handler runs
+//   - Schema: JSON Schema (inline or file path, relative to commands.yaml) that a JSON/YAML payload flag must match
+//   - MustExist: Set to false to accept the path of a file or dir flag that does not
+//     exist; by default it must exist
+//   - Extensions: Allowed file extensions for a file flag (e.g., [.yaml, .json])
+//   - CreateMissing: Create the file or directory, if it does not exist, before the command runs
+//   - Layout: Layout of a time flag, either a Go layout or a name such as RFC3339 or DateOnly
+//   - Relative: Also accept relative times such as "-24h" or "yesterday" for a time flag
+//   - AllowedValues: Values accepted by a string flag; anything else is rejected when parsed
+//   - Example: Illustrative value appended to the usage in help and docs
+//   - Deprecated: Deprecation message; the flag is hidden from help and using it prints the message
+//   - Sensitive: Redact the flag's value in the command history and background job list
+//   - Completion: Values shells complete for the flag
+//   - CompletionFunc: Name of a function registered with RegisterCompletionFunc that
+//     completes the flag's values at runtime (see CompletionFunc)
+//   - ShorthandDeprecated: Deprecation message of the shorthand; the long form keeps working silently
+//   - Annotations: Metadata passed through to the pflag annotations for downstream tooling;
+//     each value becomes a one-element annotation value
 type FlagConfig struct {
-	Name         string `yaml:"name"`
-	Shorthand    string `yaml:"shorthand,omitempty"`
-	Type         string `yaml:"type"`
-	DefaultValue string `yaml:"default,omitempty"`
-	Usage        string `yaml:"usage"`
-	Required     bool   `yaml:"required,omitempty"`
-	Persistent   bool   `yaml:"persistent,omitempty"`
-	Hidden       bool   `yaml:"hidden,omitempty"`
+	Name                string            `yaml:"name"`
+	Shorthand           string            `yaml:"shorthand,omitempty"`
+	Type                string            `yaml:"type"`
+	DefaultValue        string            `yaml:"default,omitempty"`
+	Usage               string            `yaml:"usage"`
+	Required            bool              `yaml:"required,omitempty"`
+	Persistent          bool              `yaml:"persistent,omitempty"`
+	Hidden              bool              `yaml:"hidden,omitempty"`
+	TransformFunc       string            `yaml:"transform_func,omitempty"`
+	Schema              string            `yaml:"schema,omitempty"`
+	MustExist           *bool             `yaml:"must_exist,omitempty"`
+	Extensions          []string          `yaml:"extensions,omitempty"`
+	CreateMissing       bool              `yaml:"create_missing,omitempty"`
+	Layout              string            `yaml:"layout,omitempty"`
+	Relative            bool              `yaml:"relative,omitempty"`
+	AllowedValues       []string          `yaml:"allowed_values,omitempty"`
+	Example             string            `yaml:"example,omitempty"`
+	Deprecated          string            `yaml:"deprecated,omitempty"`
+	ShorthandDeprecated string            `yaml:"shorthand_deprecated,omitempty"`
+	Sensitive           bool              `yaml:"sensitive,omitempty"`
+	Completion          []string          `yaml:"completion,omitempty"`
+	CompletionFunc      string            `yaml:"completion_func,omitempty"`
+	Annotations         map[string]string `yaml:"annotations,omitempty"`
+}
+
+// DerivedConfig represents a computed value in commands.yaml.
+// Derived values are not user-settable; they are computed from other flags
+// before the handler runs and read with GetDerived.
+//
+// Fields:
+//   - Name: Name used to look up the value with GetDerived
+//   - From: Names of the flags passed to the derive function
+//   - Func: Name of the derive function registered with RegisterFunction
+//
+// Example YAML:
+//
+//	derived:
+//	  - name: endpoint
+//	    from: [region, env]
+//	    func: computeEndpoint
+type DerivedConfig struct {
+	Name string   `yaml:"name"`
+	From []string `yaml:"from"`
+	Func string   `yaml:"func"`
+}
+
+// CacheConfig represents the result cache of a command in commands.yaml.
+// The output the handler writes to cmd.OutOrStdout() is stored in the user cache
+// directory and printed instead of running the handler until the TTL expires.
+// Commands with a cache get a --no-cache flag that runs the handler and refreshes
+// the cache, and the tool gets a "cache clear" command.
+//
+// Fields:
+//   - TTL: How long a cached result is served (e.g., "5m")
+//   - Key: Values the result depends on, as flags.<name> or args.<index>;
+//     when empty, all args and flag values are used
+//
+// Example YAML:
+//
+//	cache:
+//	  ttl: 5m
+//	  key: [flags.namespace, args.0]
+type CacheConfig struct {
+	TTL string   `yaml:"ttl"`
+	Key []string `yaml:"key,omitempty"`
+}
+
+// ConcurrencyConfig represents the parallelism control of a command in commands.yaml.
+// The command gets an int flag that sizes the worker pool returned by Pool, so
+// handlers processing many items share one way to tune parallelism.
+//
+// Fields:
+//   - Flag: Name of the flag (default: "concurrency")
+//   - Shorthand: Short flag (e.g., "j")
+//   - Default: Default number of parallel tasks (default: the number of CPUs)
+//
+// Example YAML:
+//
+//	concurrency:
+//	  flag: jobs
+//	  shorthand: j
+//	  default: 4
+type ConcurrencyConfig struct {
+	Flag      string `yaml:"flag,omitempty"`
+	Shorthand string `yaml:"shorthand,omitempty"`
+	Default   int    `yaml:"default,omitempty"`
+}
+
+// NotifyConfig represents the notification sent when a command finishes in
+// commands.yaml. The notifier subscribes to the command.succeeded and
+// command.failed events of the command (see Event), next to the sinks of
+// ToolConfig.Events. It is one of the built-in desktop and webhook notifiers
+// or a notifier registered with RegisterNotifier. A failing notifier prints a
+// warning and does not change the result of the command.
+//
+// Fields:
+//   - On: Events to notify on, success and/or failure (default: both)
+//   - Via: Name of the notifier (desktop, webhook or a registered notifier)
+//   - URL: URL the webhook notifier posts to; environment variables are expanded
+//   - Payload: text/template rendering the webhook body from the Notification;
+//     when empty, a JSON object describing the run is posted
+//
+// Example YAML:
+//
+//	notify:
+//	  on: [failure]
+//	  via: webhook
+//	  url: ${SLACK_WEBHOOK_URL}
+//	  payload: '{"text": {{json .Message}}}'
+type NotifyConfig struct {
+	On      []string `yaml:"on,omitempty"`
+	Via     string   `yaml:"via"`
+	URL     string   `yaml:"url,omitempty"`
+	Payload string   `yaml:"payload,omitempty"`
+}
+
+// EventSinkConfig represents a sink of command lifecycle events in commands.yaml.
+// Every runnable command emits command.started before its handler runs and
+// command.succeeded or command.failed after it returns; audit logs, notifications
+// and telemetry can all subscribe to these events. A failing sink prints a warning
+// and does not change the result of the command.
+//
+// Fields:
+//   - Type: stdout, file, webhook or the name of a sink registered with RegisterEventSink
+//   - Events: Events sent to the sink (default: all)
+//   - Path: File the file sink appends JSON lines to (a leading ~ is expanded)
+//   - URL: URL the webhook sink posts each event to; environment variables are expanded
+//
+// Example YAML:
+//
+//	events:
+//	  - type: file
+//	    path: ~/.local/state/mytool/audit.jsonl
+//	  - type: webhook
+//	    events: [command.failed]
+//	    url: ${ALERT_WEBHOOK_URL}
+type EventSinkConfig struct {
+	Type   string   `yaml:"type"`
+	Events []string `yaml:"events,omitempty"`
+	Path   string   `yaml:"path,omitempty"`
+	URL    string   `yaml:"url,omitempty"`
+}
+
+// LicenseConfig represents the license of the generated CLI in commands.yaml.
+// When set, the Go files written by the gen command start with a copyright and
+// SPDX license header, and gen also writes a notice listing the licenses of the
+// modules the CLI depends on.
+//
+// Fields:
+//   - SPDX: SPDX license identifier of the CLI (e.g., "Apache-2.0")
+//   - Holder: Copyright holder (e.g., "Example Corp.")
+//   - Year: Copyright year
+//
+// Example YAML:
+//
+//	license:
+//	  spdx: Apache-2.0
+//	  holder: Example Corp.
+//	  year: 2026
+type LicenseConfig struct {
+	SPDX   string `yaml:"spdx"`
+	Holder string `yaml:"holder,omitempty"`
+	Year   int    `yaml:"year,omitempty"`
+}
+
+// EnvConfig represents an environment variable read by a command in commands.yaml.
+// Required variables are checked before the handler runs, and declared values
+// are read with GetEnv.
+//
+// Fields:
+//   - Name: Environment variable name (e.g., "API_TOKEN")
+//   - Description: Description shown in generated docs
+//   - Required: Fail before the handler runs if the variable is not set
+//
+// Example YAML:
+//
+//	env:
+//	  - name: API_TOKEN
+//	    description: Token used to authenticate against the API
+//	    required: true
+type EnvConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+}
+
+// SettingConfig represents a runtime setting of the tool in the settings_schema
+// section of commands.yaml. Settings are stored in the tool's config file, which
+// the generated "config init" command creates and "config validate" checks.
+//
+// Fields:
+//   - Key: Setting key; dots nest the setting in the config file (e.g., "server.port")
+//   - Type: Setting type (see SupportedSettingTypes)
+//   - Default: Default value as string, written by "config init"
+//   - Description: Description written to the config file and generated docs
+//   - Required: Fail "config validate" if the setting is missing
+//   - Flag: Flag that overrides the setting when set on the command line
+//   - Env: Environment variable that overrides the config file
+//
+// The effective value is resolved with the precedence flag > env > config file > default
+// and read with GetSetting.
+//
+// Example YAML:
+//
+//	settings_schema:
+//	  - key: server.port
+//	    type: int
+//	    default: "8080"
+//	    description: Port the server listens on
+//	    flag: port
+//	    env: MYTOOL_PORT
+type SettingConfig struct {
+	Key         string `yaml:"key"`
+	Type        string `yaml:"type"`
+	Default     string `yaml:"default,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Flag        string `yaml:"flag,omitempty"`
+	Env         string `yaml:"env,omitempty"`
+}
+
+// BaseFlagsConfig configures the common flags added to the root command by
+// "base_flags". It is written either as a boolean or as a mapping that
+// customizes the individual flags, which enables base flags.
+//
+// Fields:
+//   - Config: The flag that overrides the config file (default name "config")
+//
+// Example YAML:
+//
+//	base_flags: true
+//
+//	base_flags:
+//	  config:
+//	    name: config-file
+//	    shorthand: c
+type BaseFlagsConfig struct {
+	Enabled bool           `yaml:"-"`
+	Config  BaseFlagConfig `yaml:"config,omitempty"`
+}
+
+// BaseFlagConfig customizes a single base flag.
+//
+// Fields:
+//   - Name: Flag name
+//   - Shorthand: Short flag
+//   - Usage: Description shown in help
+//   - Disabled: Do not add the flag
+type BaseFlagConfig struct {
+	Name      string `yaml:"name,omitempty"`
+	Shorthand string `yaml:"shorthand,omitempty"`
+	Usage     string `yaml:"usage,omitempty"`
+	Disabled  bool   `yaml:"disabled,omitempty"`
+}
+
+// UnmarshalYAML accepts "base_flags" as a boolean or as a mapping.
+func (b *BaseFlagsConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	var enabled bool
+	if err := unmarshal(&enabled); err == nil {
+		*b = BaseFlagsConfig{Enabled: enabled}
+		return nil
+	}
+
+	type plain BaseFlagsConfig
+	var config plain
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+	*b = BaseFlagsConfig(config)
+	b.Enabled = true
+	return nil
 }
 
 // ToolConfig represents the entire tool configuration in commands.yaml.
 //
 // Example YAML structure:
 //
+//	schema_version: 1
 //	name: "my-tool"
 //	description: "My CLI tool"
 //	version: "1.0.0"
+//	config_file: "~/.my-tool/config.yaml"
+//	base_flags: true # adds --config to override the config file (see BaseFlagsConfig)
+//	discover_plugins: true # runs my-tool-<sub> executables in $PATH as "my-tool <sub>"
+//	fuzzy_match: true # offers the closest commands to run for an unknown command
+//	search_command: true # adds "my-tool search <keyword>" to find commands
+//	ask_command: true # adds "my-tool ask <request>" to suggest commands for a request
+//	history: true # records runs for "my-tool history" and "my-tool rerun <id>"
+//	record: true # adds --record <file> to write a transcript of a run
+//	accessibility: true # adds --accessible for screen reader friendly output
+//	tui: true # adds "my-tool tui" to browse and run commands from a menu
+//	require_side_effects: true # warns about runnable commands without side_effects
+//	silence_usage: true # no usage after errors in any command
+//	examples_dir: "examples" # example scripts of commands, relative to commands.yaml
+//	errors: # catalog of coded errors returned with NewCatalogError (see ErrorConfig)
+//	  - code: "E1001"
+//	    message: "cannot reach %s"
+//	config_files:
+//	  - "/etc/my-tool/config.yaml"
+//	  - "~/.my-tool/config.yaml"
+//	  - "./.my-tool.yaml"
 //	root:
 //	  use: "my-tool"
 //	  short: "A CLI tool"
@@ -175,18 +651,74 @@ type FlagConfig struct {
 //	    args: "NoArgs"
 //	    run_func: "runList"
 type ToolConfig struct {
-	Name        string                    `yaml:"name"`
-	Description string                    `yaml:"description,omitempty"`
-	Version     string                    `yaml:"version,omitempty"`
-	Root        CommandConfig             `yaml:"root"`
-	Commands    map[string]CommandConfig  `yaml:"commands,omitempty"`
-	Functions   map[string]string         `yaml:"functions,omitempty"`
+	SchemaVersion      int                      `yaml:"schema_version,omitempty"`
+	Name               string                   `yaml:"name"`
+	Description        string                   `yaml:"description,omitempty"`
+	Version            string                   `yaml:"version,omitempty"`
+	Root               CommandConfig            `yaml:"root"`
+	Commands           map[string]CommandConfig `yaml:"commands,omitempty"`
+	Functions          map[string]string        `yaml:"functions,omitempty"`
+	ConfigFile         string                   `yaml:"config_file,omitempty"`
+	ConfigFiles        []string                 `yaml:"config_files,omitempty"`
+	SettingsSchema     []SettingConfig          `yaml:"settings_schema,omitempty"`
+	BaseFlags          BaseFlagsConfig          `yaml:"base_flags,omitempty"`
+	DiscoverPlugins    bool                     `yaml:"discover_plugins,omitempty"`
+	FuzzyMatch         bool                     `yaml:"fuzzy_match,omitempty"`
+	SearchCommand      bool                     `yaml:"search_command,omitempty"`
+	AskCommand         bool                     `yaml:"ask_command,omitempty"`
+	AskMatcher         string                   `yaml:"ask_matcher,omitempty"`
+	History            bool                     `yaml:"history,omitempty"`
+	Record             bool                     `yaml:"record,omitempty"`
+	Accessibility      bool                     `yaml:"accessibility,omitempty"`
+	TUI                bool                     `yaml:"tui,omitempty"`
+	RequireSideEffects bool                     `yaml:"require_side_effects,omitempty"`
+	SilenceUsage       bool                     `yaml:"silence_usage,omitempty"`
+	SilenceErrors      bool                     `yaml:"silence_errors,omitempty"`
+	Events             []EventSinkConfig        `yaml:"events,omitempty"`
+	Errors             []ErrorConfig            `yaml:"errors,omitempty"`
+	License            *LicenseConfig           `yaml:"license,omitempty"`
+	Surfaces           map[string]SurfaceConfig `yaml:"surfaces,omitempty"`
+	DefaultSurface     string                   `yaml:"default_surface,omitempty"`
+	SurfaceEnv         string                   `yaml:"surface_env,omitempty"`
+	ExamplesDir        string                   `yaml:"examples_dir,omitempty"`
+}
+
+// currentSchemaVersion is the commands.yaml schema version this package implements.
+// Bump it when the meaning of an existing key changes or a key is removed.
+const currentSchemaVersion = 1
+
+// SchemaVersion returns the commands.yaml schema version implemented by this package.
+// A commands.yaml may declare the version it was written for with schema_version;
+// files declaring a newer version than SchemaVersion are rejected by ValidateConfig.
+func SchemaVersion() int {
+	return currentSchemaVersion
 }
 
 // CommandBuilder builds cobra commands from YAML configuration
 type CommandBuilder struct {
-	config    *ToolConfig
-	funcMap   map[string]any
+	config           *ToolConfig
+	funcMap          map[string]any
+	flagTypes        map[string]FlagFactory
+	notifiers        map[string]Notifier
+	matchers         map[string]CommandMatcher
+	eventSinks       map[string]EventSink
+	schemas          map[string]*PayloadSchema
+	schemasMu        sync.Mutex
+	configOverride   string
+	configDir        string // directory of commands.yaml, when loaded from a file
+	configHash       string
+	buildInfo        *BuildInfo
+	globalFlags      []*pflag.FlagSet
+	decorators       []func(*cobra.Command)
+	middleware       []Middleware
+	quotaChecker     QuotaChecker
+	webhookPoster    WebhookPoster
+	frequentCommands int
+	completionFuncs  map[string]CompletionFunc
+	parseOnly        bool // flags are parse-only and overrides do not apply (see VerifyExamples)
+	in               io.Reader
+	out              io.Writer
+	errOut           io.Writer
 }
 
 // NewCommandBuilder creates a new command builder
@@ -204,10 +736,14 @@ func NewCommandBuilder(configPath string) (*CommandBuilder, error) {
 	if err := ValidateConfig(&config); err != nil {
 		return nil, err
 	}
+	resolveDefaults(&config)
 
 	return &CommandBuilder{
-		config:  &config,
-		funcMap: make(map[string]any),
+		config:     &config,
+		configDir:  filepath.Dir(configPath),
+		configHash: ConfigHash(string(data)),
+		funcMap:    make(map[string]any),
+		schemas:    make(map[string]*PayloadSchema),
 	}, nil
 }
 
@@ -221,100 +757,660 @@ func NewCommandBuilderFromString(yamlContent string) (*CommandBuilder, error) {
 	if err := ValidateConfig(&config); err != nil {
 		return nil, err
 	}
+	resolveDefaults(&config)
 
 	return &CommandBuilder{
-		config:  &config,
-		funcMap: make(map[string]any),
+		config:     &config,
+		configHash: ConfigHash(yamlContent),
+		funcMap:    make(map[string]any),
+		schemas:    make(map[string]*PayloadSchema),
 	}, nil
 }
 
-// RegisterFunction registers a function that can be called from YAML config
+// RegisterFunction registers a function that can be called from YAML config.
+// Run functions are func(*cobra.Command, []string) error, or
+// func(*cobra.Command, []string) for handlers that cannot fail.
 func (cb *CommandBuilder) RegisterFunction(name string, fn any) {
 	cb.funcMap[name] = fn
 }
 
-// BuildRootCommand builds the root command from configuration
+// RegisterFunctions registers several functions at once, keyed by the names used
+// in YAML config, as if RegisterFunction was called for each of them.
+func (cb *CommandBuilder) RegisterFunctions(funcs map[string]any) {
+	for name, fn := range funcs {
+		cb.RegisterFunction(name, fn)
+	}
+}
+
+// AddPersistentFlags registers flags owned by the host application that are added
+// as persistent flags to the root command built by BuildRootCommand. Flags marked
+// hidden in fs stay hidden from help. A flag whose name or shorthand is already
+// used by the root command makes BuildRootCommand fail.
+func (cb *CommandBuilder) AddPersistentFlags(fs *pflag.FlagSet) {
+	cb.globalFlags = append(cb.globalFlags, fs)
+}
+
+// DecorateRoot registers a function that is called with the root command at the end
+// of BuildRootCommand, after all YAML-defined commands and flags have been added.
+// Decorators run in registration order.
+func (cb *CommandBuilder) DecorateRoot(decorate func(*cobra.Command)) {
+	cb.decorators = append(cb.decorators, decorate)
+}
+
+// SetOut sets the writer the root command built by BuildRootCommand prints output
+// to (default: os.Stdout). Built-in commands and handlers that write to
+// cmd.OutOrStdout() print to it, so hosts and tests can capture the output.
+// AttachTo keeps the writers of the existing root command.
+func (cb *CommandBuilder) SetOut(w io.Writer) {
+	cb.out = w
+}
+
+// SetErr sets the writer the root command built by BuildRootCommand prints errors,
+// warnings, hints and prompts to (default: os.Stderr), like cmd.ErrOrStderr().
+func (cb *CommandBuilder) SetErr(w io.Writer) {
+	cb.errOut = w
+}
+
+// SetIn sets the reader that confirmations and other prompts of the root command
+// built by BuildRootCommand read answers from (default: os.Stdin).
+func (cb *CommandBuilder) SetIn(r io.Reader) {
+	cb.in = r
+}
+
+// Include restricts the commands built by BuildRootCommand and returned by
+// GetConfig to those selected by patterns, e.g. "db.*" and "user.list", to ship
+// a restricted edition of the CLI from the same commands.yaml. Handlers of the
+// other commands need not be registered. Patterns are command paths whose names
+// may be globs; a selected command keeps its subcommands. It fails for an
+// invalid pattern or one that selects no command.
+func (cb *CommandBuilder) Include(patterns ...string) error {
+	return includeCommands(cb.config, patterns)
+}
+
+// markFlagGroups marks the flag groups of config on cmd, whose flags must already
+// be added. Cobra checks the groups after parsing the flags.
+func markFlagGroups(cmd *cobra.Command, config CommandConfig) {
+	for _, group := range config.RequiredTogether {
+		cmd.MarkFlagsRequiredTogether(group...)
+	}
+	for _, group := range config.OneRequired {
+		cmd.MarkFlagsOneRequired(group...)
+	}
+}
+
+// BuildRootCommand builds the root command from configuration, with the
+// commands of the surface selected by the process arguments (see surfaces).
 func (cb *CommandBuilder) BuildRootCommand() (*cobra.Command, error) {
+	return cb.buildRootCommand(commandLineArgs())
+}
+
+// BuildRootCommandFor is like BuildRootCommand, with the commands of the
+// surface selected by args instead of the process arguments, for a server that
+// runs args in-process on a command tree of its own.
+func (cb *CommandBuilder) BuildRootCommandFor(args []string) (*cobra.Command, error) {
+	return cb.buildRootCommand(args)
+}
+
+// buildRootCommand builds the root command for running args.
+func (cb *CommandBuilder) buildRootCommand(args []string) (*cobra.Command, error) {
 	rootCmd := &cobra.Command{
-		Use:     cb.config.Root.Use,
-		Short:   cb.config.Root.Short,
-		Long:    cb.config.Root.Long,
-		Version: cb.config.Version,
+		Use:           cb.config.Root.Use,
+		Short:         cb.config.Root.Short,
+		Long:          cb.config.Root.Long,
+		Version:       cb.config.Version,
+		Annotations:   maps.Clone(cb.config.Root.Annotations),
+		SilenceUsage:  cb.config.SilenceUsage || cb.config.Root.SilenceUsage,
+		SilenceErrors: cb.config.SilenceErrors || cb.config.Root.SilenceErrors,
+	}
+	if cb.in != nil {
+		rootCmd.SetIn(cb.in)
+	}
+	if cb.out != nil {
+		rootCmd.SetOut(cb.out)
+	}
+	if cb.errOut != nil {
+		rootCmd.SetErr(cb.errOut)
+	}
+	cb.applyHelpVariant(rootCmd, cb.config.Root)
+	disableCompletionCommand(rootCmd)
+	if err := cb.setCompletion(rootCmd, cb.config.Root); err != nil {
+		return nil, err
 	}
 
 	// Set run function for root command
 	if cb.config.Root.RunFunc != "" {
-		if fn, exists := cb.funcMap[cb.config.Root.RunFunc]; exists {
-			if runE, ok := fn.(func(*cobra.Command, []string) error); ok {
-				rootCmd.RunE = runE
-			} else {
-				return nil, fmt.Errorf("function %s is not of type func(*cobra.Command, []string) error", cb.config.Root.RunFunc)
+		runE, err := cb.lookupRun(cb.config.Root.RunFunc)
+		if err != nil {
+			return nil, err
+		}
+		rootCmd.RunE = runE
+	}
+
+	addCleanup(rootCmd)
+	cb.addEvents(rootCmd)
+
+	// Set pre-run hook for root command
+	preRunE, err := cb.preRun(cb.config.Root)
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.PreRunE = preRunE
+	cb.addMiddleware(rootCmd)
+	cb.addErrorCatalog(rootCmd)
+
+	if err := cb.populateRoot(rootCmd, args); err != nil {
+		return nil, err
+	}
+
+	// Set the sensitive flags passed to a background job
+	if err := applyJobSecrets(rootCmd); err != nil {
+		return nil, err
+	}
+
+	return rootCmd, nil
+}
+
+// MustBuildRootCommand is like BuildRootCommand but panics if the root command
+// cannot be built, e.g. because a handler is not registered.
+func (cb *CommandBuilder) MustBuildRootCommand() *cobra.Command {
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		panic(err)
+	}
+	return rootCmd
+}
+
+// Execute builds the root command and runs it with the command line args, so
+// main only needs to set the exit status:
+//
+//	if err := builder.Execute(); err != nil {
+//		os.Exit(1)
+//	}
+//
+// An error building the root command is printed like an error of a command, to
+// the writer set with SetErr (default: os.Stderr), and returned.
+func (cb *CommandBuilder) Execute() error {
+	return cb.ExecuteContext(context.Background())
+}
+
+// ExecuteContext is like Execute, running the root command with ctx as the
+// context that handlers get from cmd.Context().
+func (cb *CommandBuilder) ExecuteContext(ctx context.Context) error {
+	return cb.ExecuteArgs(ctx, commandLineArgs())
+}
+
+// ExecuteArgs is like ExecuteContext, running the command given by args instead
+// of the process arguments, e.g. for a server running commands in-process for
+// its clients. Each call builds a fresh command tree, so concurrent calls do
+// not share flag values.
+func (cb *CommandBuilder) ExecuteArgs(ctx context.Context, args []string) error {
+	rootCmd, err := cb.buildRootCommand(args)
+	if err != nil {
+		errOut := cb.errOut
+		if errOut == nil {
+			errOut = os.Stderr
+		}
+		fmt.Fprintln(errOut, "Error:", err)
+		return err
+	}
+	rootCmd.SetArgs(args)
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// AttachTo builds the YAML-defined commands as children of an existing cobra root
+// command, so commands.yaml can be adopted incrementally in an established cobra
+// codebase. Root flags from commands.yaml are added to root as well; the root's
+// use, descriptions and run_func are ignored. It fails before modifying root if
+// a command name, alias or flag conflicts with one root already has.
+func (cb *CommandBuilder) AttachTo(root *cobra.Command) error {
+	args := commandLineArgs()
+	if err := cb.checkAttachConflicts(root, args); err != nil {
+		return err
+	}
+	return cb.populateRoot(root, args)
+}
+
+// checkAttachConflicts reports the YAML-defined commands and root flags that
+// conflict with those of an existing root command.
+func (cb *CommandBuilder) checkAttachConflicts(root *cobra.Command, args []string) error {
+	config, _, err := cb.surfaceConfig(args)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	for _, sub := range root.Commands() {
+		existing[sub.Name()] = true
+		for _, alias := range sub.Aliases {
+			existing[alias] = true
+		}
+	}
+
+	var conflicts []string
+	for _, name := range sortedCommandNames(config.Commands) {
+		cmdConfig := config.Commands[name]
+		cmdName := extractCommandName(cmdConfig.Use)
+		if cmdName == "" {
+			cmdName = name
+		}
+		for _, n := range slices.Concat([]string{cmdName}, cmdConfig.Aliases, cmdConfig.RenamedFrom) {
+			if existing[n] {
+				conflicts = append(conflicts, fmt.Sprintf("command %q already exists", n))
 			}
-		} else {
-			return nil, fmt.Errorf("function %s not registered", cb.config.Root.RunFunc)
 		}
 	}
 
+	for _, flag := range cb.config.Root.Flags {
+		if root.Flags().Lookup(flag.Name) != nil || root.PersistentFlags().Lookup(flag.Name) != nil {
+			conflicts = append(conflicts, fmt.Sprintf("flag --%s already exists", flag.Name))
+		}
+		if flag.Shorthand != "" && (root.Flags().ShorthandLookup(flag.Shorthand) != nil || root.PersistentFlags().ShorthandLookup(flag.Shorthand) != nil) {
+			conflicts = append(conflicts, fmt.Sprintf("flag shorthand -%s already exists", flag.Shorthand))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("cannot attach to %s: %s", root.Name(), strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// populateRoot adds the root flags, subcommands and injected behavior to a root
+// command, with the commands of the selected surface (see SurfaceConfig) and the
+// user's overrides file applied (see OverridesConfig).
+func (cb *CommandBuilder) populateRoot(rootCmd *cobra.Command, args []string) error {
+	if err := cb.checkEventSinks(); err != nil {
+		return err
+	}
+
+	// Add the commands of the surface selected for args to the shared commands
+	config, surface, err := cb.surfaceConfig(args)
+	if err != nil {
+		return err
+	}
+
+	// Apply the user's overrides file to the flags and commands built below
+	config, err = cb.loadOverrides(rootCmd.Name(), config)
+	if err != nil {
+		return err
+	}
+
 	// Add flags to root command
-	if err := cb.addFlags(rootCmd, cb.config.Root.Flags); err != nil {
-		return nil, err
+	if err := cb.addFlags(rootCmd, config.Root.Flags); err != nil {
+		return err
+	}
+	markFlagGroups(rootCmd, config.Root)
+	if err := cb.addSideEffects(rootCmd, config.Root.SideEffects); err != nil {
+		return err
+	}
+	cb.addBaseFlags(rootCmd)
+	cb.addSurfaceFlag(rootCmd, surface)
+	cb.addRecordFlag(rootCmd)
+	cb.addAccessibleFlag(rootCmd)
+	if len(cb.config.SettingsSchema) > 0 && rootCmd.PersistentFlags().Lookup(debugSettingsFlag) == nil {
+		rootCmd.PersistentFlags().Bool(debugSettingsFlag, false, "Print the effective settings and where each value came from")
 	}
 
 	// Build and add subcommands
-	for name, cmdConfig := range cb.config.Commands {
+	for _, name := range sortedCommandNames(config.Commands) {
+		cmdConfig := config.Commands[name]
 		subCmd, err := cb.buildCommand(name, cmdConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build command %s: %v", name, err)
+			return fmt.Errorf("failed to build command %s: %v", name, err)
 		}
 		rootCmd.AddCommand(subCmd)
+
+		shims, err := cb.buildRenameShims(name, cmdConfig)
+		if err != nil {
+			return fmt.Errorf("failed to build command %s: %v", name, err)
+		}
+		rootCmd.AddCommand(shims...)
+	}
+	if err := cb.addDynamicCommands(rootCmd, cb.config.Root); err != nil {
+		return err
 	}
 
-	return rootCmd, nil
+	// Add config init/validate when a settings schema is declared
+	cb.addConfigCommands(rootCmd)
+
+	// Add cache clear when a command caches its results
+	cb.addCacheCommands(rootCmd, config.Commands)
+
+	// Add jobs list/logs/kill when a command can run in the background
+	cb.addJobsCommands(rootCmd, config.Commands)
+
+	// Add the hidden build-info command when the generated main.go set the build info
+	cb.addBuildInfoCommand(rootCmd)
+
+	// Add plugins found in $PATH after all other commands so they never replace them
+	cb.addPluginCommands(rootCmd, args)
+
+	// Add history and rerun when runs are recorded
+	cb.addHistoryCommands(rootCmd)
+
+	// Add undo when a recorded command can be reverted
+	cb.addUndoCommand(rootCmd, config.Commands)
+
+	// Add tui to browse and run the commands from a menu
+	cb.addTUICommand(rootCmd)
+
+	// Add search after all other commands so it finds them all
+	cb.addSearchCommand(rootCmd)
+
+	// Add ask to suggest commands for a request in natural language
+	cb.addAskCommand(rootCmd)
+
+	// Offer the closest commands for an unknown command
+	cb.addFuzzyMatch(rootCmd)
+
+	// Report which help variants are shown
+	cb.addHelpEvents(rootCmd)
+
+	// List the most used commands at the top of the root help
+	cb.addFrequentHelp(rootCmd)
+
+	// Print help as JSON with --help-format json
+	cb.addHelpFormat(rootCmd)
+
+	// Add flags and behavior injected by the host application
+	if err := cb.addGlobalFlags(rootCmd); err != nil {
+		return err
+	}
+	for _, decorate := range cb.decorators {
+		decorate(rootCmd)
+	}
+
+	return nil
+}
+
+// addGlobalFlags adds the flags registered with AddPersistentFlags to the root command.
+func (cb *CommandBuilder) addGlobalFlags(rootCmd *cobra.Command) error {
+	for _, fs := range cb.globalFlags {
+		var err error
+		fs.VisitAll(func(flag *pflag.Flag) {
+			if err != nil {
+				return
+			}
+			if rootCmd.Flags().Lookup(flag.Name) != nil || rootCmd.PersistentFlags().Lookup(flag.Name) != nil {
+				err = fmt.Errorf("injected flag --%s conflicts with an existing root flag", flag.Name)
+				return
+			}
+			if flag.Shorthand != "" && (rootCmd.Flags().ShorthandLookup(flag.Shorthand) != nil || rootCmd.PersistentFlags().ShorthandLookup(flag.Shorthand) != nil) {
+				err = fmt.Errorf("injected flag --%s: shorthand -%s conflicts with an existing root flag", flag.Name, flag.Shorthand)
+				return
+			}
+			rootCmd.PersistentFlags().AddFlag(flag)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedCommandNames returns the keys of cmds in sorted order.
+func sortedCommandNames(cmds map[string]CommandConfig) []string {
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // buildCommand builds a single command from configuration
 func (cb *CommandBuilder) buildCommand(_ string, config CommandConfig) (*cobra.Command, error) {
 	cmd := &cobra.Command{
-		Use:     config.Use,
-		Aliases: config.Aliases,
-		Short:   config.Short,
-		Long:    config.Long,
-		Hidden:  config.Hidden,
+		Use:                config.Use,
+		Aliases:            config.Aliases,
+		Short:              config.Short,
+		Long:               config.Long,
+		ValidArgs:          config.ValidArgs,
+		Hidden:             config.Hidden,
+		Deprecated:         config.Deprecated,
+		Annotations:        maps.Clone(config.Annotations),
+		SilenceUsage:       cb.config.SilenceUsage || config.SilenceUsage,
+		SilenceErrors:      cb.config.SilenceErrors || config.SilenceErrors,
+		DisableFlagParsing: config.DisableFlagParsing,
 	}
 
+	// Show the help variant selected for the user
+	cb.applyHelpVariant(cmd, config)
+
 	// Set args validation
 	cb.setArgs(cmd, config.Args)
 
+	// Set dynamic completion of args
+	if err := cb.setCompletion(cmd, config); err != nil {
+		return nil, err
+	}
+
 	// Set run function
 	if config.RunFunc != "" {
-		if fn, exists := cb.funcMap[config.RunFunc]; exists {
-			if runE, ok := fn.(func(*cobra.Command, []string) error); ok {
-				cmd.RunE = runE
-			} else {
-				return nil, fmt.Errorf("function %s is not of type func(*cobra.Command, []string) error", config.RunFunc)
-			}
-		} else {
-			return nil, fmt.Errorf("function %s not registered", config.RunFunc)
+		runE, err := cb.lookupRun(config.RunFunc)
+		if err != nil {
+			return nil, err
 		}
+		cmd.RunE = runE
+	}
+
+	// Call the undo function instead of the handler for the undo command
+	if err := cb.setUndo(cmd, config); err != nil {
+		return nil, err
+	}
+
+	// Set pre-run hook
+	preRunE, err := cb.preRun(config)
+	if err != nil {
+		return nil, err
 	}
+	cmd.PreRunE = preRunE
 
 	// Add flags
 	if err := cb.addFlags(cmd, config.Flags); err != nil {
 		return nil, err
 	}
 
+	// Mark flag groups
+	markFlagGroups(cmd, config)
+
+	// Record the side effects and add --yes to confirm destructive commands
+	if err := cb.addSideEffects(cmd, config.SideEffects); err != nil {
+		return nil, err
+	}
+
+	// Run cleanups registered with OnCleanup
+	addCleanup(cmd)
+
+	// Add the concurrency flag read by Pool
+	cb.addConcurrency(cmd, config.Concurrency)
+
+	// Serve the output from the result cache
+	cb.addCache(cmd, config.Cache)
+
+	// Emit lifecycle events, also to the notifier of the command
+	notify, err := cb.notifySubscriber(config.Notify)
+	if err != nil {
+		return nil, err
+	}
+	cb.addEvents(cmd, notify)
+
+	// Count runs for the frequently used commands in help
+	cb.addUsage(cmd)
+
+	// Record runs for history and rerun
+	cb.addHistory(cmd)
+
+	// Write a transcript of the run with --record
+	cb.addRecord(cmd)
+
+	// Allow running as a background job
+	cb.addBackground(cmd, config.Background)
+
+	// Wrap the pre-run and run, including the wrappers above, in the middleware
+	cb.addMiddleware(cmd)
+
+	// Print catalog errors with their hint and doc link
+	cb.addErrorCatalog(cmd)
+
 	// Build and add subcommands
-	for subName, subConfig := range config.Commands {
+	for _, subName := range sortedCommandNames(config.Commands) {
+		subConfig := config.Commands[subName]
 		subCmd, err := cb.buildCommand(subName, subConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to build subcommand %s: %v", subName, err)
 		}
 		cmd.AddCommand(subCmd)
+
+		shims, err := cb.buildRenameShims(subName, subConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build subcommand %s: %v", subName, err)
+		}
+		cmd.AddCommand(shims...)
+	}
+
+	// Add subcommands resolved at runtime
+	if err := cb.addDynamicCommands(cmd, config); err != nil {
+		return nil, err
 	}
 
 	return cmd, nil
 }
 
+// lookupRun resolves a registered run function by name. A handler that cannot
+// fail, func(*cobra.Command, []string), runs like cobra's Run; it is adapted to
+// RunE so result caching, history and the other wrappers apply to it as well.
+func (cb *CommandBuilder) lookupRun(name string) (func(*cobra.Command, []string) error, error) {
+	fn, exists := cb.funcMap[name]
+	if !exists {
+		return nil, fmt.Errorf("function %s not registered", name)
+	}
+	switch run := fn.(type) {
+	case func(*cobra.Command, []string) error:
+		return run, nil
+	case func(*cobra.Command, []string):
+		return func(cmd *cobra.Command, args []string) error {
+			run(cmd, args)
+			return nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("function %s has type %T; run functions must be of type func(*cobra.Command, []string) error or func(*cobra.Command, []string)", name, fn)
+	}
+}
+
+// buildRenameShims builds one hidden command per renamed_from entry.
+// Each shim is a full copy of the renamed command (flags, args, run function
+// and subcommands), so existing scripts keep working, but cobra prints a
+// deprecation notice pointing at the new name before running it.
+func (cb *CommandBuilder) buildRenameShims(name string, config CommandConfig) ([]*cobra.Command, error) {
+	if len(config.RenamedFrom) == 0 {
+		return nil, nil
+	}
+
+	newName := extractCommandName(config.Use)
+	if newName == "" {
+		newName = name
+	}
+
+	var shims []*cobra.Command
+	for _, oldName := range config.RenamedFrom {
+		shim, err := cb.buildCommand(oldName, config)
+		if err != nil {
+			return nil, err
+		}
+		shim.Use = oldName + strings.TrimPrefix(config.Use, newName)
+		shim.Aliases = nil
+		shim.Hidden = true
+		shim.Deprecated = fmt.Sprintf("it has been renamed to %q", newName)
+		shims = append(shims, shim)
+	}
+
+	return shims, nil
+}
+
+// preRun builds the PreRunE hook for a command. The hook stops at the first
+// failing step:
+//
+//  1. checks that --api-version matches the surface the commands were built for
+//  2. checks that the config file exists, when the command requires it
+//  3. reads the declared environment variables, failing on an unset required one
+//  4. applies the transformers of the flags
+//  5. validates payload flags against their schemas
+//  6. resolves the settings from flags, environment, config files and defaults
+//  7. computes derived values
+//  8. calls the command's validate_func, if any
+//  9. asks for consent to the paths the command touches
+//  10. asks for confirmation of a destructive command
+//  11. checks the command's quota
+//  12. creates the missing paths of create_missing flags
+func (cb *CommandBuilder) preRun(config CommandConfig) (func(*cobra.Command, []string) error, error) {
+	var validate func(*cobra.Command, []string) error
+	if config.ValidateFunc != "" {
+		fn, exists := cb.funcMap[config.ValidateFunc]
+		if !exists {
+			return nil, fmt.Errorf("function %s not registered", config.ValidateFunc)
+		}
+		var ok bool
+		if validate, ok = fn.(func(*cobra.Command, []string) error); !ok {
+			return nil, fmt.Errorf("function %s is not of type func(*cobra.Command, []string) error", config.ValidateFunc)
+		}
+	}
+
+	checkQuota := cb.quotaCheck(config.Quota)
+
+	deriveFuncs := make([]DeriveFunc, len(config.Derived))
+	for i, d := range config.Derived {
+		derive, err := cb.lookupDerive(d.Func)
+		if err != nil {
+			return nil, err
+		}
+		deriveFuncs[i] = derive
+	}
+
+	return func(cmd *cobra.Command, args []string) error {
+		if err := checkSurface(cmd); err != nil {
+			return err
+		}
+		if config.RequiresConfig {
+			if err := cb.checkConfigFile(cmd); err != nil {
+				return err
+			}
+		}
+		if err := loadEnv(cmd, config.Env); err != nil {
+			return err
+		}
+		if err := cb.applyTransforms(cmd); err != nil {
+			return err
+		}
+		if err := cb.validatePayloads(cmd); err != nil {
+			return err
+		}
+		if err := cb.resolveSettings(cmd); err != nil {
+			return err
+		}
+		if err := computeDerived(cmd, config.Derived, deriveFuncs); err != nil {
+			return err
+		}
+		if validate != nil {
+			if err := validate(cmd, args); err != nil {
+				return err
+			}
+		}
+		if err := checkConsent(cmd, config.Touches); err != nil {
+			return err
+		}
+		if err := confirmDestructive(cmd, config.SideEffects); err != nil {
+			return err
+		}
+		if checkQuota != nil {
+			if err := checkQuota(cmd, args); err != nil {
+				return err
+			}
+		}
+		return createMissingPaths(cmd)
+	}, nil
+}
+
 // setArgs sets argument validation on a command based on ArgsConfig
 func (cb *CommandBuilder) setArgs(cmd *cobra.Command, args *ArgsConfig) {
 	if args == nil {
@@ -334,12 +1430,25 @@ func (cb *CommandBuilder) setArgs(cmd *cobra.Command, args *ArgsConfig) {
 		cmd.Args = cobra.MaximumNArgs(args.Max)
 	case ArgsTypeRange:
 		cmd.Args = cobra.RangeArgs(args.Min, args.Max)
+	case ArgsTypeOnlyValidArgs:
+		cmd.Args = cobra.OnlyValidArgs
+	}
+
+	if args.OnlyValid && args.Type != ArgsTypeOnlyValidArgs {
+		if cmd.Args == nil {
+			cmd.Args = cobra.OnlyValidArgs
+		} else {
+			cmd.Args = cobra.MatchAll(cmd.Args, cobra.OnlyValidArgs)
+		}
 	}
 }
 
 // addFlags adds flags to a command based on flag configuration
 func (cb *CommandBuilder) addFlags(cmd *cobra.Command, flags []FlagConfig) error {
 	for _, flag := range flags {
+		if cb.parseOnly {
+			flag = parseOnlyFlag(flag)
+		}
 		var flagSet *pflag.FlagSet
 		if flag.Persistent {
 			flagSet = cmd.PersistentFlags()
@@ -347,19 +1456,27 @@ func (cb *CommandBuilder) addFlags(cmd *cobra.Command, flags []FlagConfig) error
 			flagSet = cmd.Flags()
 		}
 
+		usage := flagUsage(flag)
+
 		switch flag.Type {
 		case "string":
-			if flag.Shorthand != "" {
-				flagSet.StringP(flag.Name, flag.Shorthand, flag.DefaultValue, flag.Usage)
+			if len(flag.AllowedValues) > 0 {
+				value := &enumValue{value: flag.DefaultValue, allowed: flag.AllowedValues}
+				flagSet.VarP(value, flag.Name, flag.Shorthand, usage)
+				if err := cmd.RegisterFlagCompletionFunc(flag.Name, cobra.FixedCompletions(flag.AllowedValues, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+					return fmt.Errorf("failed to set completion for flag %s: %w", flag.Name, err)
+				}
+			} else if flag.Shorthand != "" {
+				flagSet.StringP(flag.Name, flag.Shorthand, flag.DefaultValue, usage)
 			} else {
-				flagSet.String(flag.Name, flag.DefaultValue, flag.Usage)
+				flagSet.String(flag.Name, flag.DefaultValue, usage)
 			}
 		case "bool":
 			defaultBool := flag.DefaultValue == "true"
 			if flag.Shorthand != "" {
-				flagSet.BoolP(flag.Name, flag.Shorthand, defaultBool, flag.Usage)
+				flagSet.BoolP(flag.Name, flag.Shorthand, defaultBool, usage)
 			} else {
-				flagSet.Bool(flag.Name, defaultBool, flag.Usage)
+				flagSet.Bool(flag.Name, defaultBool, usage)
 			}
 		case "int":
 			defaultInt := 0
@@ -369,23 +1486,129 @@ func (cb *CommandBuilder) addFlags(cmd *cobra.Command, flags []FlagConfig) error
 				}
 			}
 			if flag.Shorthand != "" {
-				flagSet.IntP(flag.Name, flag.Shorthand, defaultInt, flag.Usage)
+				flagSet.IntP(flag.Name, flag.Shorthand, defaultInt, usage)
+			} else {
+				flagSet.Int(flag.Name, defaultInt, usage)
+			}
+		case "uint":
+			var defaultUint uint64
+			if flag.DefaultValue != "" {
+				var err error
+				if defaultUint, err = strconv.ParseUint(flag.DefaultValue, 10, strconv.IntSize); err != nil {
+					return fmt.Errorf("invalid uint default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+			}
+			if flag.Shorthand != "" {
+				flagSet.UintP(flag.Name, flag.Shorthand, uint(defaultUint), usage)
 			} else {
-				flagSet.Int(flag.Name, defaultInt, flag.Usage)
+				flagSet.Uint(flag.Name, uint(defaultUint), usage)
+			}
+		case "uint64":
+			var defaultUint64 uint64
+			if flag.DefaultValue != "" {
+				var err error
+				if defaultUint64, err = strconv.ParseUint(flag.DefaultValue, 10, 64); err != nil {
+					return fmt.Errorf("invalid uint64 default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+			}
+			if flag.Shorthand != "" {
+				flagSet.Uint64P(flag.Name, flag.Shorthand, defaultUint64, usage)
+			} else {
+				flagSet.Uint64(flag.Name, defaultUint64, usage)
 			}
 		case "stringSlice":
 			var defaultSlice []string
 			if flag.Shorthand != "" {
-				flagSet.StringSliceP(flag.Name, flag.Shorthand, defaultSlice, flag.Usage)
+				flagSet.StringSliceP(flag.Name, flag.Shorthand, defaultSlice, usage)
 			} else {
-				flagSet.StringSlice(flag.Name, defaultSlice, flag.Usage)
+				flagSet.StringSlice(flag.Name, defaultSlice, usage)
+			}
+		case "stringArray":
+			var defaultArray []string
+			if flag.Shorthand != "" {
+				flagSet.StringArrayP(flag.Name, flag.Shorthand, defaultArray, usage)
+			} else {
+				flagSet.StringArray(flag.Name, defaultArray, usage)
+			}
+		case "file":
+			flagSet.VarP(newPathValue(flag), flag.Name, flag.Shorthand, usage)
+			extensions := make([]string, 0, len(flag.Extensions))
+			for _, ext := range normalizeExtensions(flag.Extensions) {
+				extensions = append(extensions, strings.TrimPrefix(ext, "."))
+			}
+			if err := cobra.MarkFlagFilename(flagSet, flag.Name, extensions...); err != nil {
+				return fmt.Errorf("failed to set completion for flag %s: %w", flag.Name, err)
+			}
+		case "dir":
+			flagSet.VarP(newPathValue(flag), flag.Name, flag.Shorthand, usage)
+			if err := cobra.MarkFlagDirname(flagSet, flag.Name); err != nil {
+				return fmt.Errorf("failed to set completion for flag %s: %w", flag.Name, err)
 			}
+		case "url":
+			value := &urlValue{}
+			if flag.DefaultValue != "" {
+				if err := value.Set(flag.DefaultValue); err != nil {
+					return fmt.Errorf("invalid url default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+			}
+			flagSet.VarP(value, flag.Name, flag.Shorthand, usage)
+		case "ip":
+			var defaultIP net.IP
+			if flag.DefaultValue != "" {
+				if defaultIP = net.ParseIP(flag.DefaultValue); defaultIP == nil {
+					return fmt.Errorf("invalid ip default value %q for flag %s", flag.DefaultValue, flag.Name)
+				}
+			}
+			flagSet.IPP(flag.Name, flag.Shorthand, defaultIP, usage)
+		case "cidr":
+			var defaultNet net.IPNet
+			if flag.DefaultValue != "" {
+				_, n, err := net.ParseCIDR(flag.DefaultValue)
+				if err != nil {
+					return fmt.Errorf("invalid cidr default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+				defaultNet = *n
+			}
+			flagSet.IPNetP(flag.Name, flag.Shorthand, defaultNet, usage)
+		case "duration":
+			var defaultDuration time.Duration
+			if flag.DefaultValue != "" {
+				d, err := time.ParseDuration(flag.DefaultValue)
+				if err != nil {
+					return fmt.Errorf("invalid duration default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+				defaultDuration = d
+			}
+			flagSet.DurationP(flag.Name, flag.Shorthand, defaultDuration, usage)
+		case "bytesize":
+			value := new(byteSizeValue)
+			if flag.DefaultValue != "" {
+				if err := value.Set(flag.DefaultValue); err != nil {
+					return fmt.Errorf("invalid bytesize default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+			}
+			flagSet.VarP(value, flag.Name, flag.Shorthand, usage)
+		case "time":
+			value := newTimeValue(flag)
+			if flag.DefaultValue != "" {
+				if err := value.Set(flag.DefaultValue); err != nil {
+					return fmt.Errorf("invalid time default value %q for flag %s: %w", flag.DefaultValue, flag.Name, err)
+				}
+			}
+			flagSet.VarP(value, flag.Name, flag.Shorthand, usage)
 		default:
-			return fmt.Errorf("unsupported flag type: %s", flag.Type)
+			if err := cb.addCustomFlag(flagSet, flag, usage); err != nil {
+				return err
+			}
 		}
 
 		if flag.Required {
-			if err := cmd.MarkFlagRequired(flag.Name); err != nil {
+			// A required persistent flag is also required on every subcommand
+			markRequired := cmd.MarkFlagRequired
+			if flag.Persistent {
+				markRequired = cmd.MarkPersistentFlagRequired
+			}
+			if err := markRequired(flag.Name); err != nil {
 				return fmt.Errorf("failed to mark flag %s as required: %w", flag.Name, err)
 			}
 		}
@@ -395,11 +1618,72 @@ func (cb *CommandBuilder) addFlags(cmd *cobra.Command, flags []FlagConfig) error
 				return fmt.Errorf("failed to mark flag %s as hidden: %w", flag.Name, err)
 			}
 		}
+
+		if flag.Deprecated != "" {
+			if err := flagSet.MarkDeprecated(flag.Name, flag.Deprecated); err != nil {
+				return fmt.Errorf("failed to mark flag %s as deprecated: %w", flag.Name, err)
+			}
+		}
+
+		if flag.ShorthandDeprecated != "" {
+			if err := flagSet.MarkShorthandDeprecated(flag.Name, flag.ShorthandDeprecated); err != nil {
+				return fmt.Errorf("failed to mark shorthand of flag %s as deprecated: %w", flag.Name, err)
+			}
+		}
+
+		for key, value := range flag.Annotations {
+			if err := flagSet.SetAnnotation(flag.Name, key, []string{value}); err != nil {
+				return fmt.Errorf("failed to set annotation %s of flag %s: %w", key, flag.Name, err)
+			}
+		}
+
+		if flag.TransformFunc != "" {
+			if _, err := cb.lookupTransform(flag.TransformFunc); err != nil {
+				return err
+			}
+			if err := flagSet.SetAnnotation(flag.Name, transformAnnotation, []string{flag.TransformFunc}); err != nil {
+				return fmt.Errorf("failed to set transform for flag %s: %w", flag.Name, err)
+			}
+		}
+
+		if flag.Sensitive {
+			if err := flagSet.SetAnnotation(flag.Name, sensitiveAnnotation, []string{"true"}); err != nil {
+				return fmt.Errorf("failed to mark flag %s sensitive: %w", flag.Name, err)
+			}
+		}
+
+		if err := cb.setFlagCompletion(cmd, flag); err != nil {
+			return err
+		}
+
+		// Inline schemas are checked now; schema files when the flag is used
+		if flag.Schema != "" {
+			if isInline(flag.Schema) {
+				if _, err := cb.loadSchema(flag.Schema); err != nil {
+					return fmt.Errorf("invalid schema for flag %s: %w", flag.Name, err)
+				}
+			}
+			if err := flagSet.SetAnnotation(flag.Name, schemaAnnotation, []string{cb.schemaSource(flag.Schema)}); err != nil {
+				return fmt.Errorf("failed to set schema for flag %s: %w", flag.Name, err)
+			}
+		}
 	}
 
 	return nil
 }
 
+// flagUsage returns the help text of a flag, including its allowed values and example.
+func flagUsage(flag FlagConfig) string {
+	usage := flag.Usage
+	if len(flag.AllowedValues) > 0 {
+		usage += fmt.Sprintf(" (one of: %s)", strings.Join(flag.AllowedValues, ", "))
+	}
+	if flag.Example != "" {
+		usage += fmt.Sprintf(" (e.g. --%s %s)", flag.Name, flag.Example)
+	}
+	return usage
+}
+
 // GetConfig returns the tool configuration
 func (cb *CommandBuilder) GetConfig() *ToolConfig {
 	return cb.config
@@ -1,11 +1,17 @@
 package cobrayaml
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func TestNewCommandBuilderFromString(t *testing.T) {
@@ -151,6 +157,161 @@ commands:
 	}
 }
 
+func TestCommandBuilder_RegisterFunctions(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  start:
+    use: start
+    short: Start
+    run_func: runStart
+  stop:
+    use: stop
+    short: Stop
+    run_func: runStop
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var called []string
+	cb.RegisterFunctions(map[string]any{
+		"runStart": func(cmd *cobra.Command, args []string) error {
+			called = append(called, "start")
+			return nil
+		},
+		"runStop": func(cmd *cobra.Command, args []string) error {
+			called = append(called, "stop")
+			return nil
+		},
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	for _, name := range []string{"start", "stop"} {
+		rootCmd.SetArgs([]string{name})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute(%s) error = %v", name, err)
+		}
+	}
+	if !reflect.DeepEqual(called, []string{"start", "stop"}) {
+		t.Errorf("called = %v, want [start stop]", called)
+	}
+}
+
+func TestCommandBuilder_SetOutput(t *testing.T) {
+	yamlContent := `
+name: output-test
+search_command: true
+root:
+  use: output-test
+  short: Output test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy the app
+    run_func: runDeploy
+    touches: [~/.kube/config]
+`
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		fmt.Fprintln(cmd.OutOrStdout(), "deploying")
+		return fmt.Errorf("boom")
+	})
+	var in, out, errOut bytes.Buffer
+	cb.SetIn(&in)
+	cb.SetOut(&out)
+	cb.SetErr(&errOut)
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	in.WriteString("y\n")
+	rootCmd.SetArgs([]string{"deploy"})
+	if err := rootCmd.Execute(); err == nil {
+		t.Fatal("Execute() error = nil, want boom")
+	}
+	rootCmd.SetArgs([]string{"search", "deploy"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if !strings.HasPrefix(out.String(), "deploying\n") || !strings.Contains(out.String(), "Deploy the app") {
+		t.Errorf("out = %q, want the handler and search output", out.String())
+	}
+	for _, want := range []string{"~/.kube/config", "Error: boom"} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("errOut = %q, want %q", errOut.String(), want)
+		}
+	}
+}
+
+func TestCommandBuilder_Execute(t *testing.T) {
+	yamlContent := `
+name: execute-test
+root:
+  use: execute-test
+  short: Execute test
+commands:
+  greet:
+    use: greet <name>
+    short: Greet someone
+    run_func: runGreet
+    args:
+      type: exact
+      count: 1
+`
+	type ctxKey struct{}
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var errOut bytes.Buffer
+	cb.SetErr(&errOut)
+
+	// Without the handler registered, the build error is printed and returned.
+	if err := cb.Execute(); err == nil || !strings.Contains(err.Error(), "function runGreet not registered") {
+		t.Fatalf("Execute() error = %v, want the unregistered handler", err)
+	}
+	if got := errOut.String(); got != "Error: failed to build command greet: function runGreet not registered\n" {
+		t.Errorf("errOut = %q", got)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustBuildRootCommand() did not panic")
+			}
+		}()
+		cb.MustBuildRootCommand()
+	}()
+
+	var got string
+	cb.RegisterFunction("runGreet", func(cmd *cobra.Command, args []string) {
+		got = fmt.Sprintf("%s from %v", args[0], cmd.Context().Value(ctxKey{}))
+	})
+	setCommandLine(t, "greet", "gopher")
+	if err := cb.ExecuteContext(context.WithValue(context.Background(), ctxKey{}, "test")); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	if got != "gopher from test" {
+		t.Errorf("handler got %q, want the command line args and context", got)
+	}
+	if rootCmd := cb.MustBuildRootCommand(); rootCmd.Name() != "execute-test" {
+		t.Errorf("MustBuildRootCommand() = %s", rootCmd.Name())
+	}
+}
+
 func TestCommandBuilder_BuildRootCommand(t *testing.T) {
 	yamlContent := `
 name: build-test
@@ -608,6 +769,130 @@ commands:
 	}
 }
 
+func TestCommandBuilder_UintFlags(t *testing.T) {
+	yamlContent := `
+name: flag-test
+root:
+  use: flag-test
+  short: Flag test command
+commands:
+  scale:
+    use: scale
+    short: Scale the deployment
+    run_func: runScale
+    flags:
+      - name: replicas
+        shorthand: r
+        type: uint
+        default: "1"
+        usage: Number of replicas
+      - name: max-bytes
+        type: uint64
+        usage: Maximum size in bytes
+`
+	tests := []struct {
+		name         string
+		args         []string
+		wantReplicas uint
+		wantMaxBytes uint64
+		wantErr      bool
+	}{
+		{name: "defaults", args: []string{"scale"}, wantReplicas: 1},
+		{name: "set", args: []string{"scale", "-r", "3", "--max-bytes", "18446744073709551615"}, wantReplicas: 3, wantMaxBytes: 18446744073709551615},
+		{name: "negative replicas", args: []string{"scale", "--replicas", "-1"}, wantErr: true},
+		{name: "negative max-bytes", args: []string{"scale", "--max-bytes=-5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+
+			var replicas uint
+			var maxBytes uint64
+			cb.RegisterFunction("runScale", func(cmd *cobra.Command, args []string) error {
+				replicas, _ = cmd.Flags().GetUint("replicas")
+				maxBytes, _ = cmd.Flags().GetUint64("max-bytes")
+				return nil
+			})
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+			rootCmd.SetArgs(tt.args)
+
+			err = rootCmd.Execute()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Execute() expected error for a negative value")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if replicas != tt.wantReplicas || maxBytes != tt.wantMaxBytes {
+				t.Errorf("replicas, max-bytes = %d, %d, want %d, %d", replicas, maxBytes, tt.wantReplicas, tt.wantMaxBytes)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_StringArrayFlag(t *testing.T) {
+	yamlContent := `
+name: flag-test
+root:
+  use: flag-test
+  short: Flag test command
+commands:
+  request:
+    use: request
+    short: Send a request
+    run_func: runRequest
+    flags:
+      - name: header
+        shorthand: H
+        type: stringArray
+        usage: Header to send (repeatable)
+        transform_func: trimSpace
+      - name: tag
+        type: stringSlice
+        usage: Tags
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var headers, tags []string
+	cb.RegisterFunction("runRequest", func(cmd *cobra.Command, args []string) error {
+		headers, _ = cmd.Flags().GetStringArray("header")
+		tags, _ = cmd.Flags().GetStringSlice("tag")
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"request", "-H", "Accept: text/html, application/json ", "--header", "X-Id: 1", "--tag", "a,b"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	wantHeaders := []string{"Accept: text/html, application/json", "X-Id: 1"}
+	if !reflect.DeepEqual(headers, wantHeaders) {
+		t.Errorf("header = %q, want %q (commas kept, transform applied)", headers, wantHeaders)
+	}
+	if !reflect.DeepEqual(tags, []string{"a", "b"}) {
+		t.Errorf("tag = %q, want [a b]", tags)
+	}
+}
+
 func TestCommandBuilder_UnsupportedFlagType(t *testing.T) {
 	yamlContent := `
 name: unsupported-test
@@ -943,6 +1228,208 @@ root:
 	}
 }
 
+func TestCommandBuilder_DeprecatedFlag(t *testing.T) {
+	yamlContent := `
+name: deprecated-flag-test
+root:
+  use: deprecated-flag-test
+  short: Deprecated flag test
+commands:
+  get:
+    use: get
+    short: Get
+    run_func: runGet
+    flags:
+      - name: output
+        type: string
+        usage: Output format
+      - name: format
+        type: string
+        usage: Output format
+        deprecated: use --output instead
+`
+	var got string
+	tool := testTool{yaml: yamlContent, funcs: map[string]any{
+		"runGet": func(cmd *cobra.Command, args []string) error {
+			got, _ = cmd.Flags().GetString("format")
+			return nil
+		},
+	}}
+
+	if help := tool.mustRun(t, "get", "--help"); strings.Contains(help, "--format") {
+		t.Errorf("help should hide the deprecated flag, got:\n%s", help)
+	}
+
+	out := tool.mustRun(t, "get", "--format", "json")
+	if got != "json" {
+		t.Errorf("deprecated flag value = %q, want json", got)
+	}
+	if want := "Flag --format has been deprecated, use --output instead\n"; !strings.Contains(out, want) {
+		t.Errorf("using the deprecated flag should print %q, got %q", want, out)
+	}
+}
+
+func TestCommandBuilder_ShorthandDeprecated(t *testing.T) {
+	yamlContent := `
+name: shorthand-test
+root:
+  use: shorthand-test
+  short: Shorthand test
+commands:
+  get:
+    use: get
+    short: Get
+    run_func: runGet
+    flags:
+      - name: force
+        shorthand: f
+        type: bool
+        usage: Force the operation
+        shorthand_deprecated: use --force instead
+`
+	tests := []struct {
+		name        string
+		args        []string
+		wantWarning bool
+	}{
+		{name: "long form", args: []string{"get", "--force"}},
+		{name: "shorthand", args: []string{"get", "-f"}, wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			force := false
+			cb.RegisterFunction("runGet", func(cmd *cobra.Command, args []string) error {
+				force, _ = cmd.Flags().GetBool("force")
+				return nil
+			})
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(&out)
+			rootCmd.SetArgs(tt.args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+
+			if !force {
+				t.Error("force should be set")
+			}
+			warned := strings.Contains(out.String(), "Flag shorthand -f has been deprecated, use --force instead")
+			if warned != tt.wantWarning {
+				t.Errorf("deprecation warning printed = %v, want %v (output %q)", warned, tt.wantWarning, out.String())
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_Annotations(t *testing.T) {
+	yamlContent := `
+name: annotations-test
+root:
+  use: annotations-test
+  short: Annotations test
+  annotations:
+    docs.section: tools
+commands:
+  get:
+    use: get
+    short: Get
+    run_func: runGet
+    annotations:
+      completion.group: read
+    flags:
+      - name: namespace
+        type: string
+        usage: Namespace
+        annotations:
+          completion.resource: namespaces
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runGet", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	if got := rootCmd.Annotations["docs.section"]; got != "tools" {
+		t.Errorf("root annotation = %q, want tools", got)
+	}
+	getCmd, _, err := rootCmd.Find([]string{"get"})
+	if err != nil {
+		t.Fatalf("Find(get) error = %v", err)
+	}
+	if got := getCmd.Annotations["completion.group"]; got != "read" {
+		t.Errorf("command annotation = %q, want read", got)
+	}
+	flag := getCmd.Flags().Lookup("namespace")
+	if got := flag.Annotations["completion.resource"]; !reflect.DeepEqual(got, []string{"namespaces"}) {
+		t.Errorf("flag annotation = %v, want [namespaces]", got)
+	}
+
+	getCmd.Annotations["completion.group"] = "changed"
+	if cb.config.Commands["get"].Annotations["completion.group"] != "read" {
+		t.Error("changing the command's annotations should not change the config")
+	}
+}
+
+func TestCommandBuilder_DeprecatedCommand(t *testing.T) {
+	yamlContent := `
+name: deprecated-test
+root:
+  use: deprecated-test
+  short: Deprecated test
+commands:
+  release:
+    use: release
+    short: Release
+    run_func: runRelease
+    deprecated: use 'deploy' instead
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	ran := false
+	cb.RegisterFunction("runRelease", func(cmd *cobra.Command, args []string) error {
+		ran = true
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs([]string{"release"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !ran {
+		t.Error("deprecated command should still run")
+	}
+	if !strings.Contains(out.String(), `Command "release" is deprecated, use 'deploy' instead`) {
+		t.Errorf("output should contain the deprecation message, got %q", out.String())
+	}
+
+	releaseCmd, _, _ := rootCmd.Find([]string{"release"})
+	if releaseCmd.IsAvailableCommand() {
+		t.Error("deprecated command should not be listed in help")
+	}
+}
+
 func TestCommandBuilder_HiddenPersistentFlag(t *testing.T) {
 	yamlContent := `
 name: hidden-persistent-flag-test
@@ -1045,3 +1532,927 @@ func TestExampleCommandsYAML(t *testing.T) {
 		t.Errorf("delete command (via alias 'rm') execution failed: %v", err)
 	}
 }
+
+func TestCommandBuilder_RenamedFrom(t *testing.T) {
+	yamlContent := `
+name: rename-test
+root:
+  use: rename-test
+  short: Rename test
+commands:
+  remove:
+    use: remove <name>
+    short: Remove an item
+    renamed_from: [delete, del]
+    run_func: runRemove
+    args:
+      type: exact
+      count: 1
+    flags:
+      - name: force
+        type: bool
+        usage: Force removal
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var gotArgs []string
+	var gotForce bool
+	cb.RegisterFunction("runRemove", func(cmd *cobra.Command, args []string) error {
+		gotArgs = args
+		gotForce, _ = cmd.Flags().GetBool("force")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	shim, _, err := rootCmd.Find([]string{"delete"})
+	if err != nil {
+		t.Fatalf("shim command not found: %v", err)
+	}
+	if shim.Use != "delete <name>" {
+		t.Errorf("shim Use = %q, want %q", shim.Use, "delete <name>")
+	}
+	if !shim.Hidden {
+		t.Error("shim command should be hidden")
+	}
+	if !strings.Contains(shim.Deprecated, `"remove"`) {
+		t.Errorf("shim Deprecated = %q, should mention new name", shim.Deprecated)
+	}
+
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetOut(&stderr)
+	rootCmd.SetArgs([]string{"del", "item", "--force"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if len(gotArgs) != 1 || gotArgs[0] != "item" {
+		t.Errorf("args = %v, want [item]", gotArgs)
+	}
+	if !gotForce {
+		t.Error("force flag should be forwarded to the renamed command")
+	}
+	if !strings.Contains(stderr.String(), `renamed to "remove"`) {
+		t.Errorf("expected rename notice, got %q", stderr.String())
+	}
+}
+
+func TestCommandBuilder_ValidateFunc(t *testing.T) {
+	yamlContent := `
+name: validate-test
+root:
+  use: validate-test
+  short: Validate test
+commands:
+  report:
+    use: report
+    short: Build a report
+    validate_func: validateReport
+    run_func: runReport
+    flags:
+      - name: start
+        type: int
+        usage: Start
+      - name: end
+        type: int
+        usage: End
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	ran := false
+	cb.RegisterFunction("validateReport", func(cmd *cobra.Command, args []string) error {
+		start, _ := cmd.Flags().GetInt("start")
+		end, _ := cmd.Flags().GetInt("end")
+		if start > end {
+			return fmt.Errorf("--start must not be after --end")
+		}
+		return nil
+	})
+	cb.RegisterFunction("runReport", func(cmd *cobra.Command, args []string) error {
+		ran = true
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.SetArgs([]string{"report", "--start", "5", "--end", "1"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--start") {
+		t.Errorf("Execute() error = %v, want validation error", err)
+	}
+	if ran {
+		t.Error("handler should not run when validation fails")
+	}
+
+	rootCmd.SetArgs([]string{"report", "--start", "1", "--end", "5"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Execute() error = %v", err)
+	}
+	if !ran {
+		t.Error("handler should run when validation passes")
+	}
+}
+
+func TestCommandBuilder_ValidateFuncInvalid(t *testing.T) {
+	yamlContent := `
+name: validate-test
+root:
+  use: validate-test
+  short: Validate test
+  validate_func: validateRoot
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("BuildRootCommand() error = %v, want not registered error", err)
+	}
+
+	cb.RegisterFunction("validateRoot", func() error { return nil })
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "is not of type") {
+		t.Errorf("BuildRootCommand() error = %v, want type error", err)
+	}
+}
+
+func TestCommandBuilder_RunWithoutError(t *testing.T) {
+	yamlContent := `
+name: run-test
+history: true
+root:
+  use: run-test
+  short: Run test
+commands:
+  greet:
+    use: greet <name>
+    short: Greet
+    run_func: runGreet
+`
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	cb.RegisterFunction("runGreet", func(cmd *cobra.Command, args []string) {
+		cmd.Printf("hello %s\n", args[0])
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"greet", "gopher"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out.String() != "hello gopher\n" {
+		t.Errorf("output = %q, want %q", out.String(), "hello gopher\n")
+	}
+
+	cb.RegisterFunction("runGreet", func(args []string) error { return nil })
+	want := "function runGreet has type func([]string) error; run functions must be of type " +
+		"func(*cobra.Command, []string) error or func(*cobra.Command, []string)"
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("BuildRootCommand() error = %v, want %q", err, want)
+	}
+}
+
+func TestCommandBuilder_RequiredPersistentFlag(t *testing.T) {
+	yamlContent := `
+name: persistent-test
+root:
+  use: persistent-test
+  short: Persistent test
+  flags:
+    - name: token
+      type: string
+      usage: Access token
+      required: true
+      persistent: true
+commands:
+  db:
+    use: db
+    short: Database commands
+    flags:
+      - name: dsn
+        type: string
+        usage: Database DSN
+        required: true
+        persistent: true
+    commands:
+      migrate:
+        use: migrate
+        short: Run migrations
+        run_func: runMigrate
+`
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "all set", args: []string{"db", "migrate", "--token", "abc", "--dsn", "postgres://"}},
+		{name: "root flag missing", args: []string{"db", "migrate", "--dsn", "postgres://"}, wantErr: `required flag(s) "token" not set`},
+		{name: "parent flag missing", args: []string{"db", "migrate", "--token", "abc"}, wantErr: `required flag(s) "dsn" not set`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			ran := false
+			cb.RegisterFunction("runMigrate", func(cmd *cobra.Command, args []string) error {
+				ran = true
+				return nil
+			})
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SilenceErrors = true
+			rootCmd.SilenceUsage = true
+			rootCmd.SetArgs(tt.args)
+
+			err = rootCmd.Execute()
+			if tt.wantErr == "" {
+				if err != nil || !ran {
+					t.Errorf("Execute() error = %v, ran = %v, want the handler to run", err, ran)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if ran {
+				t.Error("handler should not run without a required persistent flag")
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_RequiredTogether(t *testing.T) {
+	yamlContent := `
+name: group-test
+root:
+  use: group-test
+  short: Group test
+commands:
+  login:
+    use: login
+    short: Log in
+    run_func: runLogin
+    required_together:
+      - [user, password]
+    flags:
+      - name: user
+        type: string
+        usage: User name
+      - name: password
+        type: string
+        usage: Password
+      - name: token
+        type: string
+        usage: Access token
+`
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "none", args: []string{"login", "--token", "abc"}},
+		{name: "both", args: []string{"login", "--user", "alice", "--password", "secret"}},
+		{
+			name:    "user only",
+			args:    []string{"login", "--user", "alice"},
+			wantErr: "if any flags in the group [user password] are set they must all be set; missing [password]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			ran := false
+			cb.RegisterFunction("runLogin", func(cmd *cobra.Command, args []string) error {
+				ran = true
+				return nil
+			})
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SilenceErrors = true
+			rootCmd.SilenceUsage = true
+			rootCmd.SetArgs(tt.args)
+
+			err = rootCmd.Execute()
+			if tt.wantErr == "" {
+				if err != nil || !ran {
+					t.Errorf("Execute() error = %v, ran = %v, want the handler to run", err, ran)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if ran {
+				t.Error("handler should not run when a flag group is incomplete")
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_OneRequired(t *testing.T) {
+	yamlContent := `
+name: group-test
+root:
+  use: group-test
+  short: Group test
+commands:
+  import:
+    use: import
+    short: Import data
+    run_func: runImport
+    one_required:
+      - [file, url]
+    flags:
+      - name: file
+        type: string
+        usage: File to import
+      - name: url
+        type: string
+        usage: URL to import
+`
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "file", args: []string{"import", "--file", "data.csv"}},
+		{name: "both", args: []string{"import", "--file", "data.csv", "--url", "https://example.com/data.csv"}},
+		{
+			name:    "none",
+			args:    []string{"import"},
+			wantErr: "at least one of the flags in the group [file url] is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			ran := false
+			cb.RegisterFunction("runImport", func(cmd *cobra.Command, args []string) error {
+				ran = true
+				return nil
+			})
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SilenceErrors = true
+			rootCmd.SilenceUsage = true
+			rootCmd.SetArgs(tt.args)
+
+			err = rootCmd.Execute()
+			if tt.wantErr == "" {
+				if err != nil || !ran {
+					t.Errorf("Execute() error = %v, ran = %v, want the handler to run", err, ran)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Execute() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if ran {
+				t.Error("handler should not run when no flag of the group is set")
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_FlagExample(t *testing.T) {
+	yamlContent := `
+name: example-test
+root:
+  use: example-test
+  short: Example test
+  flags:
+    - name: namespace
+      type: string
+      usage: Target namespace
+      example: kube-system
+    - name: format
+      type: string
+      usage: Output format
+      allowed_values: [json, yaml]
+      example: json
+    - name: verbose
+      type: bool
+      usage: Verbose output
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	tests := []struct {
+		flag  string
+		usage string
+	}{
+		{"namespace", "Target namespace (e.g. --namespace kube-system)"},
+		{"format", "Output format (one of: json, yaml) (e.g. --format json)"},
+		{"verbose", "Verbose output"},
+	}
+	for _, tt := range tests {
+		flag := rootCmd.Flags().Lookup(tt.flag)
+		if flag == nil {
+			t.Fatalf("flag %s not found", tt.flag)
+		}
+		if flag.Usage != tt.usage {
+			t.Errorf("flag %s usage = %q, want %q", tt.flag, flag.Usage, tt.usage)
+		}
+	}
+}
+
+func TestCommandBuilder_HostInjection(t *testing.T) {
+	yamlContent := `
+name: host-test
+root:
+  use: host-test
+  short: Host test
+  flags:
+    - name: output
+      shorthand: o
+      type: string
+      usage: Output format
+      persistent: true
+commands:
+  list:
+    use: list
+    short: List
+    run_func: runList
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var traceID string
+	fs := pflag.NewFlagSet("host", pflag.ContinueOnError)
+	fs.StringVar(&traceID, "trace-id", "", "Trace identifier")
+	_ = fs.MarkHidden("trace-id")
+	cb.AddPersistentFlags(fs)
+
+	var order []string
+	cb.DecorateRoot(func(root *cobra.Command) {
+		order = append(order, "first")
+		root.SilenceUsage = true
+	})
+	cb.DecorateRoot(func(root *cobra.Command) {
+		order = append(order, "second")
+		if root.Commands() == nil {
+			t.Error("decorators should run after subcommands are added")
+		}
+	})
+
+	var seen string
+	cb.RegisterFunction("runList", func(cmd *cobra.Command, args []string) error {
+		seen = traceID
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("decorator order = %v, want [first second]", order)
+	}
+	if !rootCmd.SilenceUsage {
+		t.Error("decorator changes should be kept on the root command")
+	}
+	flag := rootCmd.PersistentFlags().Lookup("trace-id")
+	if flag == nil || !flag.Hidden {
+		t.Fatal("injected flag should be a hidden persistent flag")
+	}
+
+	rootCmd.SetArgs([]string{"list", "--trace-id", "abc"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if seen != "abc" {
+		t.Errorf("trace-id = %q, want %q", seen, "abc")
+	}
+}
+
+func TestCommandBuilder_AddPersistentFlagsConflict(t *testing.T) {
+	yamlContent := `
+name: host-test
+root:
+  use: host-test
+  short: Host test
+  flags:
+    - name: output
+      shorthand: o
+      type: string
+      usage: Output format
+`
+	tests := []struct {
+		name    string
+		define  func(fs *pflag.FlagSet)
+		wantErr string
+	}{
+		{"name", func(fs *pflag.FlagSet) { fs.String("output", "", "") }, "injected flag --output conflicts"},
+		{"shorthand", func(fs *pflag.FlagSet) { fs.StringP("other", "o", "", "") }, "shorthand -o conflicts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			fs := pflag.NewFlagSet("host", pflag.ContinueOnError)
+			tt.define(fs)
+			cb.AddPersistentFlags(fs)
+
+			if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildRootCommand() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_AttachTo(t *testing.T) {
+	yamlContent := `
+name: attach-test
+root:
+  use: ignored
+  short: Ignored
+  flags:
+    - name: region
+      type: string
+      usage: Region
+      persistent: true
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var region string
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		region, _ = cmd.Flags().GetString("region")
+		return nil
+	})
+
+	root := &cobra.Command{Use: "legacy", Short: "Legacy CLI"}
+	root.AddCommand(&cobra.Command{Use: "status", Run: func(cmd *cobra.Command, args []string) {}})
+
+	if err := cb.AttachTo(root); err != nil {
+		t.Fatalf("AttachTo() error = %v", err)
+	}
+	if root.Use != "legacy" {
+		t.Errorf("root Use = %q, AttachTo should keep the existing root", root.Use)
+	}
+	if findSubcommand(root, "status") == nil || findSubcommand(root, "deploy") == nil {
+		t.Fatal("root should have both the existing and the attached commands")
+	}
+
+	root.SetArgs([]string{"deploy", "--region", "eu"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if region != "eu" {
+		t.Errorf("region = %q, want %q", region, "eu")
+	}
+}
+
+func TestCommandBuilder_AttachToConflicts(t *testing.T) {
+	yamlContent := `
+name: attach-test
+root:
+  use: ignored
+  short: Ignored
+  flags:
+    - name: verbose
+      shorthand: v
+      type: bool
+      usage: Verbose
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    aliases: [ship]
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	root := &cobra.Command{Use: "legacy"}
+	root.AddCommand(&cobra.Command{Use: "ship"})
+	root.PersistentFlags().BoolP("verbose", "v", false, "")
+
+	err = cb.AttachTo(root)
+	if err == nil {
+		t.Fatal("AttachTo() expected conflict error")
+	}
+	for _, want := range []string{
+		`command "ship" already exists`,
+		"flag --verbose already exists",
+		"flag shorthand -v already exists",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+	if findSubcommand(root, "deploy") != nil {
+		t.Error("AttachTo() should not modify root on conflict")
+	}
+}
+
+func TestCommandBuilder_ValidArgs(t *testing.T) {
+	yamlContent := `
+name: valid-args-test
+root:
+  use: valid-args-test
+  short: Valid args test
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+    valid_args: [dev, staging, prod]
+    args:
+      type: exact
+      count: 1
+      only_valid: true
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs([]string{cobra.ShellCompNoDescRequestCmd, "deploy", "st"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "staging\n") || strings.Contains(out.String(), "prod") {
+		t.Errorf("completion = %q, want staging", out.String())
+	}
+
+	deployCmd, _, _ := rootCmd.Find([]string{"deploy"})
+	if err := deployCmd.Args(deployCmd, []string{"prod"}); err != nil {
+		t.Errorf("Args(prod) error = %v", err)
+	}
+	if err := deployCmd.Args(deployCmd, []string{"qa"}); err == nil || !strings.Contains(err.Error(), `invalid argument "qa"`) {
+		t.Errorf("Args(qa) error = %v, want invalid argument", err)
+	}
+	if err := deployCmd.Args(deployCmd, []string{"dev", "prod"}); err == nil {
+		t.Error("Args(dev, prod) should still check the argument count")
+	}
+}
+
+func TestCommandBuilder_OnlyValidArgsType(t *testing.T) {
+	yamlContent := `
+name: valid-args-test
+root:
+  use: valid-args-test
+  short: Valid args test
+commands:
+  logs:
+    use: logs [component...]
+    short: Show logs
+    run_func: runLogs
+    valid_args: [api, worker, scheduler]
+    args:
+      type: only_valid_args
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runLogs", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	logsCmd, _, _ := rootCmd.Find([]string{"logs"})
+	for _, args := range [][]string{nil, {"api"}, {"api", "worker", "scheduler"}} {
+		if err := logsCmd.Args(logsCmd, args); err != nil {
+			t.Errorf("Args(%v) error = %v", args, err)
+		}
+	}
+	if err := logsCmd.Args(logsCmd, []string{"api", "db"}); err == nil || !strings.Contains(err.Error(), `invalid argument "db"`) {
+		t.Errorf("Args(api, db) error = %v, want invalid argument", err)
+	}
+
+	config, err := FromCobra(rootCmd)
+	if err != nil {
+		t.Fatalf("FromCobra() error = %v", err)
+	}
+	if got := config.Commands["logs"].Args; got == nil || got.Type != ArgsTypeOnlyValidArgs {
+		t.Errorf("round-tripped args = %+v, want type only_valid_args", got)
+	}
+}
+
+func TestCommandBuilder_Silence(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      string
+		command   string
+		wantUsage bool
+		wantError bool
+	}{
+		{name: "default", wantUsage: true, wantError: true},
+		{name: "command silence_usage", command: "silence_usage: true", wantError: true},
+		{name: "command silence_errors", command: "silence_errors: true", wantUsage: true},
+		{name: "tool silence_usage", tool: "silence_usage: true", wantError: true},
+		{name: "tool silence_errors", tool: "silence_errors: true", wantUsage: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlContent := `
+name: silence-test
+` + tt.tool + `
+root:
+  use: silence-test
+  short: Silence test
+commands:
+  fail:
+    use: fail
+    short: Fail
+    run_func: runFail
+    ` + tt.command + `
+`
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			cb.RegisterFunction("runFail", func(cmd *cobra.Command, args []string) error {
+				return fmt.Errorf("handler failed")
+			})
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(&out)
+			rootCmd.SetArgs([]string{"fail"})
+			if err := rootCmd.Execute(); err == nil {
+				t.Fatal("Execute() should fail")
+			}
+			if got := strings.Contains(out.String(), "Usage:"); got != tt.wantUsage {
+				t.Errorf("usage printed = %v, want %v\n%s", got, tt.wantUsage, out.String())
+			}
+			if got := strings.Contains(out.String(), "Error: handler failed"); got != tt.wantError {
+				t.Errorf("error printed = %v, want %v\n%s", got, tt.wantError, out.String())
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_DisableFlagParsing(t *testing.T) {
+	yamlContent := `
+name: proxy-test
+root:
+  use: proxy-test
+  short: Proxy test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  kubectl:
+    use: kubectl [args...]
+    short: Run kubectl
+    run_func: runKubectl
+    disable_flag_parsing: true
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var got []string
+	cb.RegisterFunction("runKubectl", func(cmd *cobra.Command, args []string) error {
+		got = args
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"kubectl", "get", "pods", "-n", "kube-system", "--verbose", "--", "--help"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []string{"get", "pods", "-n", "kube-system", "--verbose", "--", "--help"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %v, want %v", got, want)
+	}
+}
+
+// testTool is the fixture of a test CLI: its commands.yaml and the handlers
+// registered by name. setup, if set, configures the builder before the
+// commands are built, and in is the input of a run.
+type testTool struct {
+	yaml  string
+	funcs map[string]any
+	setup func(cb *CommandBuilder)
+	in    string
+}
+
+// noopRun is a handler that does nothing.
+func noopRun(cmd *cobra.Command, args []string) error { return nil }
+
+// build loads the tool and builds its root command, failing t on errors.
+func (tool testTool) build(t testing.TB) *cobra.Command {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(tool.yaml)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunctions(tool.funcs)
+	if tool.setup != nil {
+		tool.setup(cb)
+	}
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	return rootCmd
+}
+
+// run builds the tool, runs args and returns what the run wrote to its output
+// and error streams.
+func (tool testTool) run(t testing.TB, args ...string) (string, error) {
+	t.Helper()
+	rootCmd := tool.build(t)
+	var out bytes.Buffer
+	rootCmd.SetIn(strings.NewReader(tool.in))
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+// mustRun is run failing t when the run fails.
+func (tool testTool) mustRun(t testing.TB, args ...string) string {
+	t.Helper()
+	out, err := tool.run(t, args...)
+	if err != nil {
+		t.Fatalf("Execute(%v) error = %v", args, err)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnmarshalYAML decodes a ToolConfig and expands multi-word command keys such as
+// "db migrate up" into nested commands (see expandCommandPaths), including those
+// of each surface.
+func (c *ToolConfig) UnmarshalYAML(unmarshal func(any) error) error {
+	type plain ToolConfig
+	var config plain
+	if err := unmarshal(&config); err != nil {
+		return err
+	}
+
+	commands, err := expandCommandPaths(config.Commands, nil)
+	if err != nil {
+		return err
+	}
+	config.Commands = commands
+
+	for name, surface := range config.Surfaces {
+		if surface.Commands, err = expandCommandPaths(surface.Commands, nil); err != nil {
+			return fmt.Errorf("surface %q: %w", name, err)
+		}
+		config.Surfaces[name] = surface
+	}
+	*c = ToolConfig(config)
+	return nil
+}
+
+// expandCommandPaths returns cmds with every space-separated key ("db migrate up")
+// moved under its parent commands, creating grouping commands for parents that are
+// not defined. Keys are placed shortest first so explicitly defined parents are
+// reused. A command defined both by a multi-word key and under an explicit parent
+// is an error. parent holds the names of the commands above cmds.
+func expandCommandPaths(cmds map[string]CommandConfig, parent []string) (map[string]CommandConfig, error) {
+	if len(cmds) == 0 {
+		return cmds, nil
+	}
+
+	keys := make([]string, 0, len(cmds))
+	for key := range cmds {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ni, nj := len(strings.Fields(keys[i])), len(strings.Fields(keys[j]))
+		if ni != nj {
+			return ni < nj
+		}
+		return keys[i] < keys[j]
+	})
+
+	expanded := make(map[string]CommandConfig, len(cmds))
+	for _, key := range keys {
+		words := strings.Fields(key)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("command key %q must not be empty", key)
+		}
+
+		cmd := cmds[key]
+		subcommands, err := expandCommandPaths(cmd.Commands, append(append([]string{}, parent...), words...))
+		if err != nil {
+			return nil, err
+		}
+		cmd.Commands = subcommands
+
+		if err := insertCommand(expanded, parent, words, cmd); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// insertCommand adds cmd at the path words below cmds, creating missing parents.
+func insertCommand(cmds map[string]CommandConfig, parent, words []string, cmd CommandConfig) error {
+	name := words[0]
+	path := append(append([]string{}, parent...), name)
+
+	if len(words) == 1 {
+		if _, exists := cmds[name]; exists {
+			return fmt.Errorf("command %q is defined more than once", strings.Join(path, " "))
+		}
+		cmds[name] = cmd
+		return nil
+	}
+
+	group, exists := cmds[name]
+	if !exists {
+		group = CommandConfig{
+			Use:   name,
+			Short: strings.Join(path, " ") + " commands",
+		}
+	}
+	if group.Commands == nil {
+		group.Commands = make(map[string]CommandConfig)
+	}
+	if err := insertCommand(group.Commands, path, words[1:], cmd); err != nil {
+		return err
+	}
+	cmds[name] = group
+	return nil
+}
@@ -0,0 +1,132 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestExpandCommandPaths(t *testing.T) {
+	yamlContent := `
+name: paths-test
+root:
+  use: paths-test
+  short: Paths test
+commands:
+  db:
+    use: db
+    short: Database commands
+    commands:
+      "seed run":
+        use: run
+        short: Run seeds
+  "db migrate up":
+    use: up
+    short: Apply migrations
+    run_func: runUp
+  "db migrate down":
+    use: down
+    short: Roll back migrations
+  "cache clear":
+    use: clear
+    short: Clear the cache
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	db, ok := cb.config.Commands["db"]
+	if !ok || db.Short != "Database commands" {
+		t.Fatalf("explicit db command should be kept, got %+v", db)
+	}
+	migrate, ok := db.Commands["migrate"]
+	if !ok || migrate.Use != "migrate" || migrate.Short != "db migrate commands" {
+		t.Errorf("db migrate should be created as a grouping command, got %+v", migrate)
+	}
+	if _, ok := migrate.Commands["up"]; !ok {
+		t.Error("db migrate up should be nested under db migrate")
+	}
+	if _, ok := migrate.Commands["down"]; !ok {
+		t.Error("db migrate down should be nested under db migrate")
+	}
+	if _, ok := db.Commands["seed"].Commands["run"]; !ok {
+		t.Error("multi-word keys of subcommands should be expanded too")
+	}
+	if cache, ok := cb.config.Commands["cache"]; !ok || cache.Short != "cache commands" {
+		t.Errorf("cache should be created as a grouping command, got %+v", cache)
+	}
+	if len(cb.config.Commands) != 2 {
+		t.Errorf("top-level commands = %d, want 2 (db, cache)", len(cb.config.Commands))
+	}
+
+	var out bytes.Buffer
+	cb.RegisterFunction("runUp", func(cmd *cobra.Command, args []string) error {
+		out.WriteString(cmd.CommandPath())
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SetArgs([]string{"db", "migrate", "up"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out.String() != "paths-test db migrate up" {
+		t.Errorf("executed %q, want paths-test db migrate up", out.String())
+	}
+}
+
+func TestExpandCommandPaths_Conflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands string
+		wantErr  string
+	}{
+		{
+			name: "defined under explicit parent",
+			commands: `
+  db:
+    use: db
+    short: Database
+    commands:
+      migrate:
+        use: migrate
+        short: Migrate
+  "db migrate":
+    use: migrate
+    short: Migrate again
+`,
+			wantErr: `command "db migrate" is defined more than once`,
+		},
+		{
+			name: "defined in nested and flat form",
+			commands: `
+  "db migrate":
+    use: migrate
+    short: Migrate
+    commands:
+      up:
+        use: up
+        short: Up
+  "db migrate up":
+    use: up
+    short: Up again
+`,
+			wantErr: `command "db migrate up" is defined more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yamlContent := "name: test\nroot:\n  use: test\n  short: Test\ncommands:" + tt.commands
+			_, err := NewCommandBuilderFromString(yamlContent)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewCommandBuilderFromString() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package cobrayaml
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// CompletionFunc returns the shell completions for the next positional argument
+// of a command, like cobra's ValidArgsFunction, or for the value of a flag.
+// toComplete is the partial argument being completed; args holds the
+// positional arguments before it.
+// Register completion functions with RegisterCompletionFunc and reference them
+// from the "completion_func" field of a command or flag definition.
+type CompletionFunc = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// RegisterCompletionFunc registers a function that completes positional arguments
+// or flag values wherever completion_func is name, such as namespaces read from a
+// cluster. A function of the same type registered with RegisterFunction is found
+// as well; RegisterCompletionFunc takes precedence.
+func (cb *CommandBuilder) RegisterCompletionFunc(name string, fn CompletionFunc) {
+	if cb.completionFuncs == nil {
+		cb.completionFuncs = make(map[string]CompletionFunc)
+	}
+	cb.completionFuncs[name] = fn
+}
+
+// lookupCompletion resolves a completion function by name.
+func (cb *CommandBuilder) lookupCompletion(name string) (CompletionFunc, error) {
+	if fn, exists := cb.completionFuncs[name]; exists {
+		return fn, nil
+	}
+	fn, exists := cb.funcMap[name]
+	if !exists {
+		return nil, fmt.Errorf("function %s not registered", name)
+	}
+	complete, ok := fn.(func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective))
+	if !ok {
+		return nil, fmt.Errorf("function %s is not of type func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)", name)
+	}
+	return complete, nil
+}
+
+// setFlagCompletion registers the completion of a flag's values from its
+// completion list or completion_func.
+func (cb *CommandBuilder) setFlagCompletion(cmd *cobra.Command, flag FlagConfig) error {
+	var complete CompletionFunc
+	switch {
+	case len(flag.Completion) > 0:
+		complete = cobra.FixedCompletions(flag.Completion, cobra.ShellCompDirectiveNoFileComp)
+	case flag.CompletionFunc != "":
+		var err error
+		if complete, err = cb.lookupCompletion(flag.CompletionFunc); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	if err := cmd.RegisterFlagCompletionFunc(flag.Name, complete); err != nil {
+		return fmt.Errorf("failed to set completion for flag %s: %w", flag.Name, err)
+	}
+	return nil
+}
+
+// setCompletion sets the command's ValidArgsFunction from its completion_func.
+func (cb *CommandBuilder) setCompletion(cmd *cobra.Command, config CommandConfig) error {
+	if config.CompletionFunc == "" {
+		return nil
+	}
+	complete, err := cb.lookupCompletion(config.CompletionFunc)
+	if err != nil {
+		return err
+	}
+	cmd.ValidArgsFunction = complete
+	return nil
+}
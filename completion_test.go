@@ -0,0 +1,223 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const completionToolYAML = `
+name: completion-test
+root:
+  use: completion-test
+  short: Completion test
+commands:
+  logs:
+    use: logs <namespace>
+    short: Show logs
+    run_func: runNoop
+    completion_func: completeNamespaces
+`
+
+// completeNamespaces completes from a fixed list of namespaces.
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var matches []string
+	for _, ns := range []string{"default", "dev", "kube-system"} {
+		if strings.HasPrefix(ns, toComplete) {
+			matches = append(matches, ns)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeArgs runs shell completion of args on rootCmd and returns the output.
+func completeArgs(t *testing.T, rootCmd *cobra.Command, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs(append([]string{cobra.ShellCompNoDescRequestCmd}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	return out.String()
+}
+
+func TestCommandBuilder_CompletionFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(cb *CommandBuilder)
+	}{
+		{
+			name:     "RegisterCompletionFunc",
+			register: func(cb *CommandBuilder) { cb.RegisterCompletionFunc("completeNamespaces", completeNamespaces) },
+		},
+		{
+			name:     "RegisterFunction",
+			register: func(cb *CommandBuilder) { cb.RegisterFunction("completeNamespaces", completeNamespaces) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(completionToolYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			cb.RegisterFunction("runNoop", func(cmd *cobra.Command, args []string) error { return nil })
+			tt.register(cb)
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+
+			want := "default\ndev\n:4\n"
+			if got := completeArgs(t, rootCmd, "logs", "de"); !strings.HasPrefix(got, want) {
+				t.Errorf("completion = %q, want prefix %q", got, want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_CompletionFuncErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(cb *CommandBuilder)
+		wantErr  string
+	}{
+		{
+			name:     "not registered",
+			register: func(cb *CommandBuilder) {},
+			wantErr:  "function completeNamespaces not registered",
+		},
+		{
+			name: "wrong type",
+			register: func(cb *CommandBuilder) {
+				cb.RegisterFunction("completeNamespaces", func(cmd *cobra.Command, args []string) error { return nil })
+			},
+			wantErr: "function completeNamespaces is not of type func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(completionToolYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			cb.RegisterFunction("runNoop", func(cmd *cobra.Command, args []string) error { return nil })
+			tt.register(cb)
+			if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildRootCommand() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_CompletionFuncWithValidArgs(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		Commands: map[string]CommandConfig{
+			"logs": {Use: "logs", Short: "Logs", CompletionFunc: "completeNamespaces", ValidArgs: []string{"default"}},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), `command "logs": completion_func and valid_args cannot be combined`) {
+		t.Errorf("ValidateConfig() error = %v, want completion_func error", err)
+	}
+}
+
+func TestCommandBuilder_FlagCompletion(t *testing.T) {
+	yamlContent := `
+name: completion-test
+root:
+  use: completion-test
+  short: Completion test
+commands:
+  logs:
+    use: logs
+    short: Show logs
+    run_func: runNoop
+    flags:
+      - name: env
+        type: string
+        usage: Environment
+        completion: [dev, staging, prod]
+      - name: namespace
+        type: string
+        usage: Namespace
+        completion_func: completeNamespaces
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runNoop", func(cmd *cobra.Command, args []string) error { return nil })
+	cb.RegisterCompletionFunc("completeNamespaces", completeNamespaces)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "completion", args: []string{"logs", "--env", ""}, want: "dev\nstaging\nprod\n:4\n"},
+		{name: "completion_func", args: []string{"logs", "--namespace", "k"}, want: "kube-system\n:4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			if got := completeArgs(t, rootCmd, tt.args...); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("completion = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_FlagCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    FlagConfig
+		wantErr string
+	}{
+		{
+			name:    "completion with completion_func",
+			flag:    FlagConfig{Name: "env", Type: "string", Usage: "Env", Completion: []string{"dev"}, CompletionFunc: "completeEnv"},
+			wantErr: `flag "env": completion and completion_func cannot be combined`,
+		},
+		{
+			name:    "allowed_values",
+			flag:    FlagConfig{Name: "env", Type: "string", Usage: "Env", AllowedValues: []string{"dev"}, Completion: []string{"dev"}},
+			wantErr: `flag "env": allowed_values are completed already`,
+		},
+		{
+			name:    "bool flag",
+			flag:    FlagConfig{Name: "force", Type: "bool", Usage: "Force", CompletionFunc: "completeForce"},
+			wantErr: `flag "force": completion and completion_func are not supported for bool flags`,
+		},
+		{
+			name:    "duplicate value",
+			flag:    FlagConfig{Name: "env", Type: "string", Usage: "Env", Completion: []string{"dev", "dev"}},
+			wantErr: `flag "env": duplicate completion value "dev"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name: "test",
+				Root: CommandConfig{Use: "test", Short: "Test", RunFunc: "runTest", Flags: []FlagConfig{tt.flag}},
+			}
+			err := ValidateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
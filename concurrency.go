@@ -0,0 +1,149 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultConcurrencyFlag is the name of the flag added by concurrency when no
+// flag is configured.
+const defaultConcurrencyFlag = "concurrency"
+
+// concurrencyAnnotation is the pflag annotation key that marks the concurrency flag.
+const concurrencyAnnotation = "cobrayaml_concurrency"
+
+// addConcurrency adds the concurrency flag of a command and rejects values below 1
+// before the handler runs. The default is the number of CPUs unless configured.
+func (cb *CommandBuilder) addConcurrency(cmd *cobra.Command, concurrency *ConcurrencyConfig) {
+	if concurrency == nil {
+		return
+	}
+	name := concurrency.Flag
+	if name == "" {
+		name = defaultConcurrencyFlag
+	}
+	value := concurrency.Default
+	if value == 0 {
+		value = runtime.NumCPU()
+	}
+	cmd.Flags().IntP(name, concurrency.Shorthand, value, "Number of items to process in parallel")
+	_ = cmd.Flags().SetAnnotation(name, concurrencyAnnotation, []string{"true"})
+
+	if cmd.RunE == nil {
+		return
+	}
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if n, _ := cmd.Flags().GetInt(name); n < 1 {
+			return fmt.Errorf("--%s must be at least 1, got %d", name, n)
+		}
+		return run(cmd, args)
+	}
+}
+
+// concurrencyFlag returns the concurrency flag of cmd, or nil if it has none.
+func concurrencyFlag(cmd *cobra.Command) *pflag.Flag {
+	var found *pflag.Flag
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if found == nil && len(flag.Annotations[concurrencyAnnotation]) > 0 {
+			found = flag
+		}
+	})
+	return found
+}
+
+// WorkerPool runs tasks with bounded parallelism. Create one with Pool.
+type WorkerPool struct {
+	size   int
+	ctx    context.Context
+	cancel context.CancelFunc
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// Pool returns a worker pool sized by the command's concurrency flag, so every
+// handler processing many items honors the same --concurrency (or configured)
+// flag. Commands without a concurrency config get a pool that runs one task at a
+// time. The pool's context is derived from cmd.Context() and canceled when a
+// task fails.
+//
+// Example:
+//
+//	pool := cobrayaml.Pool(cmd)
+//	for _, item := range items {
+//		pool.Go(func(ctx context.Context) error {
+//			return process(ctx, item)
+//		})
+//	}
+//	return pool.Wait()
+func Pool(cmd *cobra.Command) *WorkerPool {
+	size := 1
+	if flag := concurrencyFlag(cmd); flag != nil {
+		if n, err := cmd.Flags().GetInt(flag.Name); err == nil && n > 1 {
+			size = n
+		}
+	}
+
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	return &WorkerPool{
+		size:   size,
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, size),
+	}
+}
+
+// Size returns the maximum number of tasks the pool runs at once.
+func (p *WorkerPool) Size() int {
+	return p.size
+}
+
+// Go runs task in a new goroutine, blocking while the pool is full. Once a task
+// has failed or the context is done, Go no longer starts tasks.
+func (p *WorkerPool) Go(task func(ctx context.Context) error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		return
+	}
+	if p.ctx.Err() != nil {
+		<-p.sem
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		if err := task(p.ctx); err != nil {
+			p.once.Do(func() {
+				p.err = err
+				p.cancel()
+			})
+		}
+	}()
+}
+
+// Wait waits for all started tasks and returns the first error, or the context's
+// error if it was canceled before any task failed.
+func (p *WorkerPool) Wait() error {
+	p.wg.Wait()
+	defer p.cancel()
+	if p.err != nil {
+		return p.err
+	}
+	return context.Cause(p.ctx)
+}
@@ -0,0 +1,210 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const concurrencyYAML = `
+name: pool-test
+root:
+  use: pool-test
+  short: Pool test
+commands:
+  sync:
+    use: sync
+    short: Sync items
+    run_func: runSync
+    concurrency:
+      flag: jobs
+      shorthand: j
+      default: 4
+  plain:
+    use: plain
+    short: Plain command
+    run_func: runPlain
+  auto:
+    use: auto
+    short: Auto-sized command
+    run_func: runPlain
+    concurrency: {}
+`
+
+func TestCommandBuilder_Concurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantSize int
+		wantErr  string
+	}{
+		{name: "default", args: []string{"sync"}, wantSize: 4},
+		{name: "flag", args: []string{"sync", "--jobs", "2"}, wantSize: 2},
+		{name: "shorthand", args: []string{"sync", "-j", "1"}, wantSize: 1},
+		{name: "zero", args: []string{"sync", "--jobs", "0"}, wantErr: "--jobs must be at least 1, got 0"},
+		{name: "no concurrency config", args: []string{"plain"}, wantSize: 1},
+		{name: "default flag name", args: []string{"auto"}, wantSize: runtime.NumCPU()},
+		{name: "default flag name set", args: []string{"auto", "--concurrency", "3"}, wantSize: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(concurrencyYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+
+			var size int
+			var running, maxRunning atomic.Int32
+			run := func(cmd *cobra.Command, args []string) error {
+				pool := Pool(cmd)
+				size = pool.Size()
+				for i := 0; i < 10; i++ {
+					pool.Go(func(ctx context.Context) error {
+						n := running.Add(1)
+						for {
+							max := maxRunning.Load()
+							if n <= max || maxRunning.CompareAndSwap(max, n) {
+								break
+							}
+						}
+						time.Sleep(5 * time.Millisecond)
+						running.Add(-1)
+						return nil
+					})
+				}
+				return pool.Wait()
+			}
+			cb.RegisterFunction("runSync", run)
+			cb.RegisterFunction("runPlain", run)
+
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+			rootCmd.SetArgs(tt.args)
+
+			err = rootCmd.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Execute() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if size != tt.wantSize {
+				t.Errorf("Pool().Size() = %d, want %d", size, tt.wantSize)
+			}
+			if max := int(maxRunning.Load()); max > tt.wantSize {
+				t.Errorf("pool ran %d tasks at once, want at most %d", max, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_StopsAfterError(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().Int("concurrency", 2, "")
+	_ = cmd.Flags().SetAnnotation("concurrency", concurrencyAnnotation, []string{"true"})
+
+	pool := Pool(cmd)
+	wantErr := errors.New("item 3 failed")
+	var mu sync.Mutex
+	var started []int
+	for i := 0; i < 20; i++ {
+		pool.Go(func(ctx context.Context) error {
+			mu.Lock()
+			started = append(started, i)
+			mu.Unlock()
+			if i == 3 {
+				return wantErr
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Millisecond):
+			}
+			return nil
+		})
+	}
+	if err := pool.Wait(); !errors.Is(err, wantErr) {
+		t.Errorf("Wait() error = %v, want %v", err, wantErr)
+	}
+	if len(started) == 20 {
+		t.Error("no tasks should start after a task failed")
+	}
+}
+
+func TestValidateConfig_Concurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			command: CommandConfig{Concurrency: &ConcurrencyConfig{Flag: "jobs", Shorthand: "j", Default: 4}},
+		},
+		{
+			name:    "negative default",
+			command: CommandConfig{Concurrency: &ConcurrencyConfig{Default: -1}},
+			wantErr: "concurrency default must not be negative, got -1",
+		},
+		{
+			name:    "long shorthand",
+			command: CommandConfig{Concurrency: &ConcurrencyConfig{Shorthand: "jj"}},
+			wantErr: `concurrency shorthand "jj" must be a single character`,
+		},
+		{
+			name: "flag conflict",
+			command: CommandConfig{
+				Concurrency: &ConcurrencyConfig{},
+				Flags:       []FlagConfig{{Name: "concurrency", Type: FlagTypeInt, Usage: "Concurrency"}},
+			},
+			wantErr: `flag "concurrency" conflicts with the flag added by concurrency`,
+		},
+		{
+			name: "shorthand conflict",
+			command: CommandConfig{
+				Concurrency: &ConcurrencyConfig{Flag: "jobs", Shorthand: "j"},
+				Flags:       []FlagConfig{{Name: "json", Shorthand: "j", Type: FlagTypeBool, Usage: "JSON output"}},
+			},
+			wantErr: `flag "json" shorthand "j" conflicts with the flag added by concurrency`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.command.Use = "sync"
+			tt.command.Short = "Sync"
+			tt.command.RunFunc = "runSync"
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"sync": tt.command},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
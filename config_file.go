@@ -0,0 +1,117 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// configFlag is the default name of the persistent flag added by "base_flags"
+// that overrides the config file.
+const configFlag = "config"
+
+// ConfigFilePath returns the path of the tool's config file declared with
+// "config_file", with a leading "~" expanded to the user's home directory.
+// The config flag added by "base_flags" takes precedence when set.
+// It returns an empty string if no config file is declared.
+func (cb *CommandBuilder) ConfigFilePath() (string, error) {
+	path := cb.config.ConfigFile
+	if cb.configOverride != "" {
+		path = cb.configOverride
+	}
+	if path == "" {
+		return "", nil
+	}
+	return expandHome(path)
+}
+
+// ConfigFilePaths returns the config file cascade with a leading "~" expanded.
+// Files are listed from lowest to highest precedence: settings in later files
+// override those in earlier ones. Without "config_files" the cascade is the
+// single "config_file". When the config flag added by "base_flags" is set,
+// the cascade is only that file.
+func (cb *CommandBuilder) ConfigFilePaths() ([]string, error) {
+	files := cb.config.ConfigFiles
+	if len(files) == 0 && cb.config.ConfigFile != "" {
+		files = []string{cb.config.ConfigFile}
+	}
+	if cb.configOverride != "" {
+		files = []string{cb.configOverride}
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		path, err := expandHome(file)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// checkConfigFile fails with guidance on how to create the tool's config file
+// if no file of the config cascade exists. It points to "config init" only when
+// the tool of cmd has that command.
+func (cb *CommandBuilder) checkConfigFile(cmd *cobra.Command) error {
+	paths, err := cb.ConfigFilePaths()
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file: %w", err)
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		switch {
+		case err == nil && info.IsDir():
+			return fmt.Errorf("config file %s is a directory", path)
+		case err == nil:
+			return nil
+		case !os.IsNotExist(err):
+			return fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+	}
+
+	path, err := cb.ConfigFilePath()
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file: %w", err)
+	}
+	if configCmd := findSubcommand(cmd.Root(), "config"); configCmd != nil && findSubcommand(configCmd, "init") != nil {
+		return fmt.Errorf("config file %s not found; run `%s config init` to create it", path, cmd.Root().Name())
+	}
+	return fmt.Errorf("config file %s not found; create it to run %s", path, cmd.CommandPath())
+}
+
+// addBaseFlags adds the common persistent flags enabled with "base_flags" to the
+// root command. Disabled flags and flags already declared in commands.yaml are skipped.
+func (cb *CommandBuilder) addBaseFlags(rootCmd *cobra.Command) {
+	base := cb.config.BaseFlags
+	if !base.Enabled || base.Config.Disabled {
+		return
+	}
+
+	name := configFlagName(base)
+	if rootCmd.PersistentFlags().Lookup(name) != nil {
+		return
+	}
+	rootCmd.PersistentFlags().StringVarP(&cb.configOverride, name, base.Config.Shorthand, "", configFlagUsage(cb.config))
+	_ = rootCmd.MarkPersistentFlagFilename(name, "yaml", "yml")
+}
+
+// configFlagName returns the name of the base flag that overrides the config file.
+func configFlagName(base BaseFlagsConfig) string {
+	if base.Config.Name != "" {
+		return base.Config.Name
+	}
+	return configFlag
+}
+
+// configFlagUsage returns the help text of the base flag that overrides the config file.
+func configFlagUsage(config *ToolConfig) string {
+	if config.BaseFlags.Config.Usage != "" {
+		return config.BaseFlags.Config.Usage
+	}
+	if config.ConfigFile != "" {
+		return fmt.Sprintf("Config file (default %s)", config.ConfigFile)
+	}
+	return "Config file"
+}
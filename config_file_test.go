@@ -0,0 +1,368 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandBuilder_RequiresConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yamlContent := `
+name: config-test
+config_file: ` + configPath + `
+root:
+  use: config-test
+  short: Config test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    requires_config: true
+  version:
+    use: version
+    short: Version
+    run_func: runVersion
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var ran []string
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		ran = append(ran, "deploy")
+		return nil
+	})
+	cb.RegisterFunction("runVersion", func(cmd *cobra.Command, args []string) error {
+		ran = append(ran, "version")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.SetArgs([]string{"deploy"})
+	err = rootCmd.Execute()
+	if want := "config file " + configPath + " not found; create it to run config-test deploy"; err == nil || err.Error() != want {
+		t.Errorf("Execute() error = %v, want %q", err, want)
+	}
+
+	rootCmd.SetArgs([]string{"version"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Execute() error = %v, commands without requires_config should run", err)
+	}
+
+	if err := os.WriteFile(configPath, []byte("key: value\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"deploy"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Execute() error = %v", err)
+	}
+
+	if strings.Join(ran, ",") != "version,deploy" {
+		t.Errorf("ran = %v, want [version deploy]", ran)
+	}
+}
+
+func TestCommandBuilder_RequiresConfigInit(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	cb, err := NewCommandBuilderFromString(`
+name: config-test
+config_file: ` + configPath + `
+settings_schema:
+  - key: region
+    type: string
+root:
+  use: config-test
+  short: Config test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    requires_config: true
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error { return nil })
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{"deploy"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "run `config-test config init`") {
+		t.Errorf("Execute() error = %v, want config init guidance", err)
+	}
+}
+
+func TestCommandBuilder_ConfigFilePath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	cb, err := NewCommandBuilderFromString(`
+name: config-test
+config_file: ~/.config-test/config.yaml
+root:
+  use: config-test
+  short: Config test
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	got, err := cb.ConfigFilePath()
+	if err != nil {
+		t.Fatalf("ConfigFilePath() error = %v", err)
+	}
+	want := filepath.Join(home, ".config-test", "config.yaml")
+	if got != want {
+		t.Errorf("ConfigFilePath() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateConfig_RequiresConfigWithoutConfigFile(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		Commands: map[string]CommandConfig{
+			"remote": {
+				Use:   "remote",
+				Short: "Remote",
+				Commands: map[string]CommandConfig{
+					"add": {Use: "add", Short: "Add", RequiresConfig: true},
+				},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), `command "remote/add": requires_config is set but the tool has no config_file`) {
+		t.Errorf("ValidateConfig() error = %v, want requires_config error", err)
+	}
+
+	config.ConfigFile = "config.yaml"
+	if err := ValidateConfig(config); err != nil {
+		t.Errorf("ValidateConfig() error = %v, want nil", err)
+	}
+}
+
+func TestCommandBuilder_RequiresConfigCascade(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.yaml")
+	user := filepath.Join(dir, "user.yaml")
+
+	cb, err := NewCommandBuilderFromString(`
+name: config-test
+config_file: ` + user + `
+config_files: [` + system + `, ` + user + `]
+root:
+  use: config-test
+  short: Config test
+  run_func: runRoot
+  requires_config: true
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.SetArgs([]string{})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "config file "+user+" not found") {
+		t.Errorf("Execute() error = %v, want config file not found", err)
+	}
+
+	if err := os.WriteFile(system, []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Execute() error = %v, any file of the cascade should satisfy requires_config", err)
+	}
+}
+
+func TestValidateConfig_ConfigFiles(t *testing.T) {
+	config := &ToolConfig{
+		Name:        "test",
+		Root:        CommandConfig{Use: "test", Short: "Test"},
+		ConfigFile:  "user.yaml",
+		ConfigFiles: []string{"system.yaml", " "},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected errors")
+	}
+	for _, want := range []string{
+		"config_files entries must not be empty",
+		`config_file "user.yaml" must be one of config_files`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+}
+
+func TestCommandBuilder_BaseFlags(t *testing.T) {
+	dir := t.TempDir()
+	defaultPath := filepath.Join(dir, "default.yaml")
+	overridePath := filepath.Join(dir, "override.yaml")
+	if err := os.WriteFile(overridePath, []byte("region: eu\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cb, err := NewCommandBuilderFromString(`
+name: base-test
+config_file: ` + defaultPath + `
+base_flags: true
+root:
+  use: base-test
+  short: Base test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    requires_config: true
+settings_schema:
+  - key: region
+    type: string
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var region any
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		region, _ = GetSetting(cmd, "region")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	flag := rootCmd.PersistentFlags().Lookup("config")
+	if flag == nil {
+		t.Fatal("base_flags should add a persistent --config flag")
+	}
+	if !strings.Contains(flag.Usage, defaultPath) {
+		t.Errorf("--config usage = %q, want default path", flag.Usage)
+	}
+
+	rootCmd.SetArgs([]string{"deploy"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), defaultPath) {
+		t.Errorf("Execute() error = %v, want default config file not found", err)
+	}
+
+	rootCmd.SetArgs([]string{"deploy", "--config", overridePath})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if region != "eu" {
+		t.Errorf("region = %v, want eu from the --config file", region)
+	}
+}
+
+func TestCommandBuilder_BaseFlagsKeepDeclaredConfigFlag(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(`
+name: base-test
+base_flags: true
+root:
+  use: base-test
+  short: Base test
+  flags:
+    - name: config
+      type: string
+      usage: Custom config flag
+      persistent: true
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	if usage := rootCmd.PersistentFlags().Lookup("config").Usage; usage != "Custom config flag" {
+		t.Errorf("--config usage = %q, want the declared flag", usage)
+	}
+}
+
+func TestCommandBuilder_BaseFlagsCustomized(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseFlags string
+		wantFlag  string
+		wantShort string
+		wantUsage string
+	}{
+		{"disabled by bool", "false", "", "", ""},
+		{"renamed", "{config: {name: config-file, shorthand: c, usage: Alternate config}}", "config-file", "c", "Alternate config"},
+		{"disabled flag", "{config: {disabled: true}}", "", "", ""},
+		{"mapping enables defaults", "{config: {}}", "config", "", "Config file (default config.yaml)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(`
+name: base-test
+config_file: config.yaml
+base_flags: ` + tt.baseFlags + `
+root:
+  use: base-test
+  short: Base test
+`)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+
+			if tt.wantFlag == "" {
+				if rootCmd.PersistentFlags().HasAvailableFlags() {
+					t.Error("no base flag should be added")
+				}
+				return
+			}
+			flag := rootCmd.PersistentFlags().Lookup(tt.wantFlag)
+			if flag == nil {
+				t.Fatalf("flag --%s not found", tt.wantFlag)
+			}
+			if flag.Shorthand != tt.wantShort {
+				t.Errorf("shorthand = %q, want %q", flag.Shorthand, tt.wantShort)
+			}
+			if flag.Usage != tt.wantUsage {
+				t.Errorf("usage = %q, want %q", flag.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// consentFile returns the file recording which sensitive paths the user allowed
+// tool's commands to touch.
+func consentFile(tool string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tool, "consent.json"), nil
+}
+
+// readConsent returns the recorded consent, keyed by command path.
+func readConsent(path string) (map[string][]string, error) {
+	consent := make(map[string][]string)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return consent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &consent); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return consent, nil
+}
+
+// checkConsent asks the user once whether cmd may touch the given paths and
+// records the answer. The user is asked again when paths are added to touches.
+func checkConsent(cmd *cobra.Command, touches []string) error {
+	if len(touches) == 0 {
+		return nil
+	}
+	path, err := consentFile(cmd.Root().Name())
+	if err != nil {
+		return fmt.Errorf("failed to locate consent file: %w", err)
+	}
+	consent, err := readConsent(path)
+	if err != nil {
+		return err
+	}
+
+	key := cmd.CommandPath()
+	var missing []string
+	for _, touched := range touches {
+		if !slices.Contains(consent[key], touched) {
+			missing = append(missing, touched)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	ok, err := confirm(cmd, fmt.Sprintf("%s accesses these paths:\n  %s\nAllow?", key, strings.Join(missing, "\n  ")))
+	switch {
+	case errors.Is(err, errNoAnswer):
+		return fmt.Errorf("%s needs consent to access %s; run it interactively to allow", key, strings.Join(missing, ", "))
+	case err != nil:
+		return err
+	case !ok:
+		return fmt.Errorf("access to %s not allowed", strings.Join(missing, ", "))
+	}
+
+	consent[key] = append(consent[key], missing...)
+	slices.Sort(consent[key])
+	data, err := json.MarshalIndent(consent, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to record consent: %w", err)
+	}
+	return nil
+}
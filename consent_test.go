@@ -0,0 +1,147 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const consentYAML = `
+name: consent-test
+root:
+  use: consent-test
+  short: Consent test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy to the cluster
+    run_func: runDeploy
+    touches:
+      - ~/.kube/config
+      - /etc/hosts
+`
+
+func TestCommandBuilder_Touches(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	runs := 0
+	tool := testTool{yaml: consentYAML, funcs: map[string]any{
+		"runDeploy": func(cmd *cobra.Command, args []string) { runs++ },
+	}}
+
+	tool.in = "n\n"
+	prompt, err := tool.run(t, "deploy")
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("denied consent error = %v, want not allowed", err)
+	}
+	if !strings.Contains(prompt, "~/.kube/config") || !strings.Contains(prompt, "Allow? [y/N]") {
+		t.Errorf("prompt = %q, want the touched paths and a question", prompt)
+	}
+	if runs != 0 {
+		t.Errorf("handler ran %d times without consent", runs)
+	}
+
+	tool.in = ""
+	if _, err := tool.run(t, "deploy"); err == nil || !strings.Contains(err.Error(), "run it interactively") {
+		t.Errorf("consent without input error = %v, want run it interactively", err)
+	}
+
+	tool.in = "y\n"
+	if _, err := tool.run(t, "deploy"); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if runs != 1 {
+		t.Errorf("handler ran %d times after consent, want 1", runs)
+	}
+
+	tool.in = ""
+	prompt, err = tool.run(t, "deploy")
+	if err != nil {
+		t.Fatalf("Execute() after consent error = %v", err)
+	}
+	if prompt != "" {
+		t.Errorf("consent should be remembered, got prompt %q", prompt)
+	}
+
+	added := strings.Replace(consentYAML, "      - /etc/hosts\n", "      - /etc/hosts\n      - ~/.ssh/id_ed25519\n", 1)
+	tool.yaml, tool.in = added, "y\n"
+	prompt, err = tool.run(t, "deploy")
+	if err != nil {
+		t.Fatalf("Execute() with an added path error = %v", err)
+	}
+	if !strings.Contains(prompt, "~/.ssh/id_ed25519") || strings.Contains(prompt, "/etc/hosts") {
+		t.Errorf("prompt = %q, want only the added path", prompt)
+	}
+	if runs != 3 {
+		t.Errorf("handler ran %d times, want 3", runs)
+	}
+}
+
+func TestCommandBuilder_TouchesAndDestructive(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// One answer per prompt, read from the same input
+	ran := false
+	out := testTool{
+		yaml: strings.Replace(consentYAML, "    run_func: runDeploy\n", "    run_func: runDeploy\n    side_effects: destructive\n", 1),
+		funcs: map[string]any{"runDeploy": func(cmd *cobra.Command, args []string) {
+			ran = true
+		}},
+		in: "y\ny\n",
+	}.mustRun(t, "deploy")
+	if !ran {
+		t.Error("handler did not run after both prompts were answered")
+	}
+	if !strings.Contains(out, "Allow? [y/N]: ") || !strings.Contains(out, "Continue? [y/N]: ") {
+		t.Errorf("output = %q, want both prompts", out)
+	}
+}
+
+func TestValidateConfig_Touches(t *testing.T) {
+	tests := []struct {
+		name    string
+		touches []string
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			touches: []string{"~/.kube/config", "/etc/hosts"},
+		},
+		{
+			name:    "empty path",
+			touches: []string{"~/.kube/config", " "},
+			wantErr: "touches[1] must not be empty",
+		},
+		{
+			name:    "duplicate path",
+			touches: []string{"/etc/hosts", "/etc/hosts"},
+			wantErr: `duplicate path "/etc/hosts" in touches`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name: "test",
+				Root: CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{
+					"deploy": {Use: "deploy", Short: "Deploy", RunFunc: "runDeploy", Touches: tt.touches},
+				},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// CheckContract compares rootCmd with the commands declared in config and
+// returns a violation per difference: commands that do not exist, flags that
+// are missing or differ in type, shorthand, default or required, and args
+// validators that accept or reject other args than configured. Flags of custom
+// types are only checked to exist. Commands and flags added by cobrayaml
+// features or DecorateRoot are ignored.
+//
+// Generated contract tests call it with the root command built by main.go, so
+// manual edits of generated code that drift from commands.yaml fail the tests.
+func CheckContract(rootCmd *cobra.Command, config *ToolConfig) []error {
+	cb := &CommandBuilder{config: config}
+	var violations []error
+	var check func(cmd *cobra.Command, cmdConfig CommandConfig)
+	check = func(cmd *cobra.Command, cmdConfig CommandConfig) {
+		violations = append(violations, cb.checkFlagsContract(cmd, cmdConfig)...)
+		violations = append(violations, cb.checkArgsContract(cmd, cmdConfig)...)
+		for _, name := range sortedCommandNames(cmdConfig.Commands) {
+			sub := cmdConfig.Commands[name]
+			subName, _, _ := strings.Cut(sub.Use, " ")
+			subCmd := findSubcommand(cmd, subName)
+			if subCmd == nil {
+				violations = append(violations, fmt.Errorf("command %q: not found", cmd.CommandPath()+" "+subName))
+				continue
+			}
+			check(subCmd, sub)
+		}
+	}
+	check(rootCmd, CommandConfig{
+		Use:       config.Root.Use,
+		Args:      config.Root.Args,
+		ValidArgs: config.Root.ValidArgs,
+		Flags:     config.Root.Flags,
+		Commands:  defaultCommands(config),
+	})
+	return violations
+}
+
+// checkFlagsContract compares the flags of cmd with the declared flags,
+// against flags built from the declaration by addFlags.
+func (cb *CommandBuilder) checkFlagsContract(cmd *cobra.Command, config CommandConfig) []error {
+	var violations []error
+	for _, flag := range config.Flags {
+		lookup := func(c *cobra.Command) *pflag.Flag {
+			if flag.Persistent {
+				return c.PersistentFlags().Lookup(flag.Name)
+			}
+			return c.Flags().Lookup(flag.Name)
+		}
+		got := lookup(cmd)
+		if got == nil {
+			violations = append(violations, fmt.Errorf("command %q: flag --%s is not defined", cmd.CommandPath(), flag.Name))
+			continue
+		}
+		if !slices.Contains(SupportedFlagTypes, flag.Type) {
+			continue
+		}
+
+		declared := &cobra.Command{Use: cmd.Use}
+		if err := cb.addFlags(declared, []FlagConfig{parseOnlyFlag(flag)}); err != nil {
+			violations = append(violations, fmt.Errorf("command %q: flag --%s: %w", cmd.CommandPath(), flag.Name, err))
+			continue
+		}
+		want := lookup(declared)
+		switch {
+		case got.Value.Type() != want.Value.Type():
+			violations = append(violations, fmt.Errorf("command %q: flag --%s has type %s, want %s", cmd.CommandPath(), flag.Name, got.Value.Type(), want.Value.Type()))
+		case got.DefValue != want.DefValue:
+			violations = append(violations, fmt.Errorf("command %q: flag --%s has default %q, want %q", cmd.CommandPath(), flag.Name, got.DefValue, want.DefValue))
+		}
+		if got.Shorthand != want.Shorthand {
+			violations = append(violations, fmt.Errorf("command %q: flag --%s has shorthand %q, want %q", cmd.CommandPath(), flag.Name, got.Shorthand, want.Shorthand))
+		}
+		if flagRequired(got) != flag.Required {
+			violations = append(violations, fmt.Errorf("command %q: flag --%s required is %t, want %t", cmd.CommandPath(), flag.Name, flagRequired(got), flag.Required))
+		}
+	}
+	return violations
+}
+
+// flagRequired reports whether flag is marked as required.
+func flagRequired(flag *pflag.Flag) bool {
+	return slices.Contains(flag.Annotations[cobra.BashCompOneRequiredFlag], "true")
+}
+
+// checkArgsContract compares which args the validator of cmd accepts with the
+// validator built from the declaration by setArgs, for every number of args up
+// to one more than the configured bounds and for an arg that is not valid.
+func (cb *CommandBuilder) checkArgsContract(cmd *cobra.Command, config CommandConfig) []error {
+	if config.DisableFlagParsing || config.DynamicCommandsFunc != "" {
+		return nil
+	}
+	declared := &cobra.Command{Use: cmd.Use, ValidArgs: config.ValidArgs}
+	cb.setArgs(declared, config.Args)
+
+	arg := "arg"
+	if len(config.ValidArgs) > 0 {
+		arg, _, _ = strings.Cut(config.ValidArgs[0], "\t")
+	}
+	limit := 2
+	if config.Args != nil {
+		limit = max(limit, config.Args.Count, config.Args.Min, config.Args.Max) + 1
+	}
+	var probes [][]string
+	for n := 0; n <= limit; n++ {
+		args := make([]string, n)
+		for i := range args {
+			args[i] = arg
+		}
+		probes = append(probes, args)
+	}
+	if len(config.ValidArgs) > 0 {
+		probes = append(probes, []string{"not-a-valid-arg"})
+	}
+
+	var violations []error
+	for _, args := range probes {
+		gotErr, wantErr := cmd.ValidateArgs(args), declared.ValidateArgs(args)
+		switch {
+		case gotErr == nil && wantErr != nil:
+			violations = append(violations, fmt.Errorf("command %q: args %q are accepted, want rejected: %v", cmd.CommandPath(), args, wantErr))
+		case gotErr != nil && wantErr == nil:
+			violations = append(violations, fmt.Errorf("command %q: args %q are rejected, want accepted: %v", cmd.CommandPath(), args, gotErr))
+		}
+	}
+	return violations
+}
+
+const contractTestTemplate = `{{.LicenseHeader}}// Code generated by cobrayaml. DO NOT EDIT.
+// cobrayaml schema version: {{.SchemaVersion}}
+
+package {{.PackageName}}
+
+import (
+	"testing"
+
+	"github.com/S-mishina/cobrayaml"
+)
+
+// TestContract checks that the commands built by newRootCommand still have the
+// commands, flags and args validators declared in commands.yaml.
+func TestContract(t *testing.T) {
+	rootCmd, err := newRootCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder, err := cobrayaml.NewCommandBuilderFromString(commandsYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+{{- if .Include}}
+	if err := builder.Include(includedCommands...); err != nil {
+		t.Fatal(err)
+	}
+{{- end}}
+
+	for _, violation := range cobrayaml.CheckContract(rootCmd, builder.GetConfig()) {
+		t.Error(violation)
+	}
+}
+`
+
+// GenerateContractTest generates contract_test.go, which checks the commands
+// built by newRootCommand of main.go with CheckContract against the commandsYAML
+// embedded in main.go.
+func (g *Generator) GenerateContractTest(packageName string) (string, error) {
+	tmpl, err := template.New("contract").Parse(contractTestTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse contract test template: %w", err)
+	}
+
+	data := struct {
+		LicenseHeader string
+		PackageName   string
+		SchemaVersion int
+		Include       bool
+	}{
+		LicenseHeader: g.licenseHeader(),
+		PackageName:   packageName,
+		SchemaVersion: SchemaVersion(),
+		Include:       len(g.include) > 0,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute contract test template: %w", err)
+	}
+
+	// Format the generated code
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		// Return unformatted if formatting fails
+		return buf.String(), nil
+	}
+
+	return string(formatted), nil
+}
+
+// GenerateContractTestToFile generates contract_test.go and writes to file
+func (g *Generator) GenerateContractTestToFile(packageName, outputPath string) error {
+	code, err := g.GenerateContractTest(packageName)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, []byte(code), 0644)
+}
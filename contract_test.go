@@ -0,0 +1,135 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const contractYAML = `
+name: contract
+root:
+  use: contract
+  short: Contract test
+  flags:
+    - name: verbose
+      shorthand: v
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+    args:
+      type: exact
+      count: 1
+      only_valid: true
+    valid_args: [dev, prod]
+    flags:
+      - name: replicas
+        shorthand: r
+        type: int
+        default: "2"
+        usage: Number of replicas
+      - name: timeout
+        type: duration
+        required: true
+        usage: Rollout timeout
+      - name: min-version
+        type: semver
+        usage: Custom type
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup [name]
+        short: Back up
+        run_func: runBackup
+        args:
+          type: max
+          max: 1
+`
+
+// buildContractRoot builds the root command of yamlContent with no-op handlers.
+func buildContractRoot(t *testing.T, yamlContent string) *cobra.Command {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	noop := func(cmd *cobra.Command, args []string) error { return nil }
+	cb.RegisterFunction("runDeploy", noop)
+	cb.RegisterFunction("runBackup", noop)
+	cb.RegisterFlagType("semver", func(FlagConfig) (pflag.Value, error) {
+		return &semverValue{}, nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	return rootCmd
+}
+
+func TestCheckContract(t *testing.T) {
+	gen, err := NewGeneratorFromString(contractYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	if violations := CheckContract(buildContractRoot(t, contractYAML), gen.config); len(violations) != 0 {
+		t.Errorf("CheckContract() = %v, want none", violations)
+	}
+
+	drifted := strings.NewReplacer(
+		"shorthand: r\n        type: int\n        default: \"2\"", "type: string\n        default: \"3\"",
+		"        required: true\n", "",
+		"count: 1", "count: 2",
+		"    run_func: runBackup\n        args:\n          type: max\n          max: 1\n", "    run_func: runBackup\n",
+		"  db:\n", "  database:\n",
+		"use: db\n", "use: database\n",
+	).Replace(contractYAML)
+	var got []string
+	for _, violation := range CheckContract(buildContractRoot(t, drifted), gen.config) {
+		got = append(got, violation.Error())
+	}
+	want := []string{
+		`command "contract db": not found`,
+		`command "contract deploy": flag --replicas has type string, want int`,
+		`command "contract deploy": flag --replicas has shorthand "", want "r"`,
+		`command "contract deploy": flag --timeout required is false, want true`,
+		`command "contract deploy": args ["dev"] are rejected, want accepted: accepts 2 arg(s), received 1`,
+		`command "contract deploy": args ["dev" "dev"] are accepted, want rejected: accepts 1 arg(s), received 2`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CheckContract() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestGenerator_GenerateContractTest(t *testing.T) {
+	gen, err := NewGeneratorFromString(contractYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateContractTest("main")
+	if err != nil {
+		t.Fatalf("GenerateContractTest() error = %v", err)
+	}
+	for _, want := range []string{
+		"// Code generated by cobrayaml. DO NOT EDIT.",
+		"package main",
+		"func TestContract(t *testing.T) {",
+		"rootCmd, err := newRootCommand()",
+		"builder, err := cobrayaml.NewCommandBuilderFromString(commandsYAML)",
+		"for _, violation := range cobrayaml.CheckContract(rootCmd, builder.GetConfig()) {",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("contract test should contain %q:\n%s", want, code)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagFactory creates the value of a flag with a custom type registered with
+// RegisterFlagType. It is called once per flag with the flag's YAML config; the
+// builder then sets the flag's default, if any, with the value's Set method.
+// Handlers read the parsed value with cmd.Flags().Lookup(name).Value and a type
+// assertion to the concrete type.
+type FlagFactory func(config FlagConfig) (pflag.Value, error)
+
+// RegisterFlagType registers a custom flag type that YAML flags can use as their
+// type, such as a UUID or semantic version. Built-in flag types cannot be replaced.
+func (cb *CommandBuilder) RegisterFlagType(name string, factory FlagFactory) {
+	if cb.flagTypes == nil {
+		cb.flagTypes = make(map[string]FlagFactory)
+	}
+	cb.flagTypes[name] = factory
+}
+
+// addCustomFlag adds a flag whose type was registered with RegisterFlagType.
+func (cb *CommandBuilder) addCustomFlag(flagSet *pflag.FlagSet, flag FlagConfig, usage string) error {
+	factory, exists := cb.flagTypes[flag.Type]
+	if !exists || slices.Contains(SupportedFlagTypes, flag.Type) {
+		return fmt.Errorf("unsupported flag type: %s", flag.Type)
+	}
+
+	value, err := factory(flag)
+	if err != nil {
+		return fmt.Errorf("failed to create flag %s: %w", flag.Name, err)
+	}
+	if flag.DefaultValue != "" {
+		if err := value.Set(flag.DefaultValue); err != nil {
+			return fmt.Errorf("invalid %s default value %q for flag %s: %w", flag.Type, flag.DefaultValue, flag.Name, err)
+		}
+	}
+	flagSet.VarP(value, flag.Name, flag.Shorthand, usage)
+	return nil
+}
@@ -0,0 +1,196 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// semverValue is a minimal major.minor.patch flag value for the tests.
+type semverValue struct {
+	major, minor, patch int
+}
+
+func (v *semverValue) Set(s string) error {
+	var major, minor, patch int
+	if n, err := fmt.Sscanf(s, "%d.%d.%d", &major, &minor, &patch); err != nil || n != 3 {
+		return fmt.Errorf("%q is not a semantic version", s)
+	}
+	v.major, v.minor, v.patch = major, minor, patch
+	return nil
+}
+
+func (v *semverValue) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+func (v *semverValue) Type() string {
+	return "semver"
+}
+
+const customFlagYAML = `
+name: custom-test
+root:
+  use: custom-test
+  short: Custom flag test
+commands:
+  release:
+    use: release
+    short: Release a version
+    run_func: runRelease
+    flags:
+      - name: version
+        shorthand: v
+        type: semver
+        default: 1.0.0
+        usage: Version to release
+`
+
+func TestCommandBuilder_RegisterFlagType(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "default",
+			args: []string{"release"},
+			want: "release 1.0.0",
+		},
+		{
+			name: "long flag",
+			args: []string{"release", "--version", "2.3.4"},
+			want: "release 2.3.4",
+		},
+		{
+			name: "shorthand",
+			args: []string{"release", "-v", "0.1.0"},
+			want: "release 0.1.0",
+		},
+		{
+			name:    "invalid value",
+			args:    []string{"release", "--version", "latest"},
+			wantErr: `"latest" is not a semantic version`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(customFlagYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			cb.RegisterFlagType("semver", func(config FlagConfig) (pflag.Value, error) {
+				return &semverValue{}, nil
+			})
+			var got string
+			cb.RegisterFunction("runRelease", func(cmd *cobra.Command, args []string) error {
+				version := cmd.Flags().Lookup("version").Value.(*semverValue)
+				got = "release " + version.String()
+				return nil
+			})
+
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SetOut(&bytes.Buffer{})
+			rootCmd.SetErr(&bytes.Buffer{})
+			rootCmd.SetArgs(tt.args)
+			err = rootCmd.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Execute() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("handler got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_RegisterFlagTypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		factory FlagFactory
+		wantErr string
+	}{
+		{
+			name:    "unregistered type",
+			yaml:    customFlagYAML,
+			wantErr: "unsupported flag type: semver",
+		},
+		{
+			name: "invalid default",
+			yaml: strings.Replace(customFlagYAML, "default: 1.0.0", "default: one", 1),
+			factory: func(config FlagConfig) (pflag.Value, error) {
+				return &semverValue{}, nil
+			},
+			wantErr: `invalid semver default value "one" for flag version`,
+		},
+		{
+			name: "factory error",
+			yaml: customFlagYAML,
+			factory: func(config FlagConfig) (pflag.Value, error) {
+				return nil, fmt.Errorf("no registry for %s", config.Name)
+			},
+			wantErr: "failed to create flag version: no registry for version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(tt.yaml)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			if tt.factory != nil {
+				cb.RegisterFlagType("semver", tt.factory)
+			}
+			cb.RegisterFunction("runRelease", func(cmd *cobra.Command, args []string) error { return nil })
+
+			_, err = cb.BuildRootCommand()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildRootCommand() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_CustomFlagType(t *testing.T) {
+	gen, err := NewGeneratorFromString(customFlagYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	for _, want := range []string{
+		`version := cmd.Flags().Lookup("version").Value // semver registered in configureBuilder`,
+		"func configureBuilder(builder *cobrayaml.CommandBuilder) error {",
+		"// TODO: Register the flag types semver with builder.RegisterFlagType",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("GenerateHandlers() does not contain %q:\n%s", want, code)
+		}
+	}
+	code, err = gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+	if !strings.Contains(code, "if err := configureBuilder(builder); err != nil {") {
+		t.Errorf("main.go does not call configureBuilder:\n%s", code)
+	}
+}
@@ -0,0 +1,128 @@
+package cobrayaml
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// DefaultsConfig represents the defaults a command sets for its subtree in
+// commands.yaml. The defaults replace the declared defaults of the flags of all
+// descendant commands when the YAML is loaded, so help, docs and the built
+// commands show the effective values. Defaults of a nested command take
+// precedence over those of its ancestors. Each flag must be declared by at
+// least one descendant; persistent flags inherited from above the command are
+// not affected.
+//
+// Fields:
+//   - Flags: Default values keyed by flag name
+//
+// Example YAML:
+//
+//	db:
+//	  use: db
+//	  short: Database commands
+//	  defaults:
+//	    flags:
+//	      timeout: "60"
+type DefaultsConfig struct {
+	Flags map[string]string `yaml:"flags,omitempty"`
+}
+
+// validateDefaults validates the defaults of the command at path for the
+// commands of its subtree, subcommands.
+func validateDefaults(defaults *DefaultsConfig, subcommands []map[string]CommandConfig, path string, ve *ValidationError) {
+	if defaults == nil {
+		return
+	}
+	for _, name := range sortedKeys(defaults.Flags) {
+		value := defaults.Flags[name]
+		var flags []FlagConfig
+		for _, cmds := range subcommands {
+			flags = append(flags, descendantFlags(cmds, name)...)
+		}
+		if len(flags) == 0 {
+			ve.addError("command %q: defaults flag %q is not declared by any subcommand", path, name)
+			continue
+		}
+		for _, flag := range flags {
+			if len(flag.AllowedValues) > 0 && !slices.Contains(flag.AllowedValues, value) {
+				ve.addError("command %q: defaults flag %q: %q is not one of the allowed values", path, name, value)
+				break
+			}
+			if flag.Type == FlagTypeUint || flag.Type == FlagTypeUint64 {
+				if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+					ve.addError("command %q: defaults flag %q: %q is not a valid %s", path, name, value, flag.Type)
+					break
+				}
+			}
+		}
+	}
+}
+
+// descendantFlags returns the flags named name declared by cmds and their
+// subcommands.
+func descendantFlags(cmds map[string]CommandConfig, name string) []FlagConfig {
+	var flags []FlagConfig
+	for _, cmdName := range sortedCommandNames(cmds) {
+		cmd := cmds[cmdName]
+		for _, flag := range cmd.Flags {
+			if flag.Name == name {
+				flags = append(flags, flag)
+			}
+		}
+		flags = append(flags, descendantFlags(cmd.Commands, name)...)
+	}
+	return flags
+}
+
+// resolveDefaults applies the defaults of each command of config to the flags
+// of its descendants. The defaults of the root command apply to the top-level
+// commands and the commands of every surface.
+func resolveDefaults(config *ToolConfig) {
+	inherited := rootDefaults(config)
+	config.Commands = applyDefaults(config.Commands, inherited)
+	for _, name := range sortedKeys(config.Surfaces) {
+		surface := config.Surfaces[name]
+		surface.Commands = applyDefaults(surface.Commands, inherited)
+		config.Surfaces[name] = surface
+	}
+}
+
+// rootDefaults returns the flag defaults the root command sets.
+func rootDefaults(config *ToolConfig) map[string]string {
+	if config.Root.Defaults == nil {
+		return nil
+	}
+	return config.Root.Defaults.Flags
+}
+
+// applyDefaults returns cmds with the flag defaults in inherited, and the
+// defaults each command sets, applied to their flags and subcommands.
+func applyDefaults(cmds map[string]CommandConfig, inherited map[string]string) map[string]CommandConfig {
+	if len(cmds) == 0 {
+		return cmds
+	}
+	resolved := make(map[string]CommandConfig, len(cmds))
+	for name, cmd := range cmds {
+		if len(inherited) > 0 {
+			cmd.Flags = slices.Clone(cmd.Flags)
+			for i, flag := range cmd.Flags {
+				if value, exists := inherited[flag.Name]; exists {
+					cmd.Flags[i].DefaultValue = value
+				}
+			}
+		}
+		defaults := inherited
+		if cmd.Defaults != nil && len(cmd.Defaults.Flags) > 0 {
+			defaults = maps.Clone(inherited)
+			if defaults == nil {
+				defaults = make(map[string]string)
+			}
+			maps.Copy(defaults, cmd.Defaults.Flags)
+		}
+		cmd.Commands = applyDefaults(cmd.Commands, defaults)
+		resolved[name] = cmd
+	}
+	return resolved
+}
@@ -0,0 +1,123 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultsYAML = `
+name: app
+root:
+  use: app
+  short: App
+  defaults:
+    flags:
+      output: json
+commands:
+  db:
+    use: db
+    short: Database
+    defaults:
+      flags:
+        timeout: "60"
+    commands:
+      backup:
+        use: backup
+        short: Back up
+        run_func: runNoop
+        flags:
+          - name: timeout
+            type: int
+            default: "30"
+            usage: Timeout in seconds
+          - name: output
+            type: string
+            default: text
+            usage: Output format
+      replica:
+        use: replica
+        short: Replicas
+        defaults:
+          flags:
+            timeout: "120"
+        commands:
+          sync:
+            use: sync
+            short: Sync a replica
+            run_func: runNoop
+            flags:
+              - name: timeout
+                type: int
+                default: "30"
+                usage: Timeout in seconds
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runNoop
+    flags:
+      - name: timeout
+        type: int
+        default: "30"
+        usage: Timeout in seconds
+`
+
+func TestCommandBuilder_Defaults(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(defaultsYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runNoop", func(cmd *cobra.Command, args []string) {})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	tests := []struct {
+		path  []string
+		flag  string
+		value string
+	}{
+		{[]string{"db", "backup"}, "timeout", "60"},
+		{[]string{"db", "backup"}, "output", "json"},
+		{[]string{"db", "replica", "sync"}, "timeout", "120"},
+		{[]string{"deploy"}, "timeout", "30"},
+	}
+	for _, tt := range tests {
+		cmd, _, err := rootCmd.Find(tt.path)
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", tt.path, err)
+		}
+		if got := cmd.Flags().Lookup(tt.flag).DefValue; got != tt.value {
+			t.Errorf("%s --%s default = %q, want %q", cmd.CommandPath(), tt.flag, got, tt.value)
+		}
+	}
+
+	if violations := CheckContract(rootCmd, cb.GetConfig()); len(violations) > 0 {
+		t.Errorf("CheckContract() = %v", violations)
+	}
+}
+
+func TestValidateConfig_Defaults(t *testing.T) {
+	yamlContent := strings.Replace(defaultsYAML, `        timeout: "60"`, `        timeout: "60"
+        retries: "3"`, 1)
+	yamlContent = strings.Replace(yamlContent, "      output: json", "      output: json\n      missing: x", 1)
+	yamlContent = strings.Replace(yamlContent, `            default: text
+`, `            default: text
+            allowed_values: [text, yaml]
+`, 1)
+	_, err := NewCommandBuilderFromString(yamlContent)
+	if err == nil {
+		t.Fatal("NewCommandBuilderFromString() error = nil, want defaults errors")
+	}
+	for _, want := range []string{
+		`command "db": defaults flag "retries" is not declared by any subcommand`,
+		`command "root": defaults flag "missing" is not declared by any subcommand`,
+		`command "root": defaults flag "output": "json" is not one of the allowed values`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
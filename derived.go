@@ -0,0 +1,124 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// DeriveFunc computes a derived value from the parsed values of its input flags.
+// inputs maps each flag name listed in "from" to its typed value
+// (for example string, bool, int, []string, net.IP or time.Duration).
+// Register derive functions with RegisterFunction.
+type DeriveFunc = func(inputs map[string]any) (any, error)
+
+// derivedKey is the context key under which derived values are stored.
+type derivedKey struct{}
+
+// GetDerived returns the derived value with the given name computed for cmd.
+// The second return value reports whether the value exists.
+func GetDerived(cmd *cobra.Command, name string) (any, bool) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		return nil, false
+	}
+	values, ok := ctx.Value(derivedKey{}).(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	value, ok := values[name]
+	return value, ok
+}
+
+// lookupDerive resolves a registered derive function by name.
+func (cb *CommandBuilder) lookupDerive(name string) (DeriveFunc, error) {
+	fn, exists := cb.funcMap[name]
+	if !exists {
+		return nil, fmt.Errorf("function %s not registered", name)
+	}
+	derive, ok := fn.(func(map[string]any) (any, error))
+	if !ok {
+		return nil, fmt.Errorf("function %s is not of type func(map[string]any) (any, error)", name)
+	}
+	return derive, nil
+}
+
+// computeDerived evaluates the derived values of a command in declaration order
+// and stores them in the command's context.
+func computeDerived(cmd *cobra.Command, derived []DerivedConfig, funcs []DeriveFunc) error {
+	if len(derived) == 0 {
+		return nil
+	}
+
+	values := make(map[string]any, len(derived))
+	for i, d := range derived {
+		inputs := make(map[string]any, len(d.From))
+		for _, name := range d.From {
+			value, err := typedFlagValue(cmd.Flags(), name)
+			if err != nil {
+				return fmt.Errorf("derived value %s: %w", d.Name, err)
+			}
+			inputs[name] = value
+		}
+
+		value, err := funcs[i](inputs)
+		if err != nil {
+			return fmt.Errorf("derived value %s: %w", d.Name, err)
+		}
+		values[d.Name] = value
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, derivedKey{}, values))
+	return nil
+}
+
+// typedFlagValue returns the parsed value of a flag as its Go type.
+// Flag types without a dedicated getter are returned as their string form.
+func typedFlagValue(fs *pflag.FlagSet, name string) (any, error) {
+	flag := fs.Lookup(name)
+	if flag == nil {
+		return nil, fmt.Errorf("flag --%s is not defined", name)
+	}
+
+	switch flag.Value.Type() {
+	case FlagTypeString:
+		return asAny(fs.GetString(name))
+	case FlagTypeBool:
+		return asAny(fs.GetBool(name))
+	case FlagTypeInt:
+		return asAny(fs.GetInt(name))
+	case FlagTypeUint:
+		return asAny(fs.GetUint(name))
+	case FlagTypeUint64:
+		return asAny(fs.GetUint64(name))
+	case FlagTypeStringSlice:
+		return asAny(fs.GetStringSlice(name))
+	case FlagTypeStringArray:
+		return asAny(fs.GetStringArray(name))
+	case FlagTypeIP:
+		return asAny(fs.GetIP(name))
+	case "ipNet":
+		return asAny(fs.GetIPNet(name))
+	case FlagTypeDuration:
+		return asAny(fs.GetDuration(name))
+	case FlagTypeURL:
+		return asAny(GetURL(fs, name))
+	case FlagTypeByteSize:
+		return asAny(GetByteSize(fs, name))
+	case FlagTypeTime:
+		return asAny(GetTime(fs, name))
+	default:
+		return flag.Value.String(), nil
+	}
+}
+
+// asAny widens a typed getter result to any.
+func asAny[T any](value T, err error) (any, error) {
+	return value, err
+}
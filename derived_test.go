@@ -0,0 +1,189 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func TestCommandBuilder_Derived(t *testing.T) {
+	yamlContent := `
+name: derived-test
+root:
+  use: derived-test
+  short: Derived test
+  flags:
+    - name: env
+      type: string
+      default: dev
+      usage: Environment
+      persistent: true
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    flags:
+      - name: region
+        type: string
+        usage: Region
+    derived:
+      - name: endpoint
+        from: [region, env]
+        func: computeEndpoint
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	cb.RegisterFunction("computeEndpoint", func(inputs map[string]any) (any, error) {
+		return fmt.Sprintf("https://%s.%s.example.com", inputs["region"], inputs["env"]), nil
+	})
+
+	var endpoint any
+	var found bool
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		endpoint, found = GetDerived(cmd, "endpoint")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	deployCmd, _, _ := rootCmd.Find([]string{"deploy"})
+	if deployCmd.Flags().Lookup("endpoint") != nil {
+		t.Error("derived value should not be exposed as a flag")
+	}
+
+	rootCmd.SetArgs([]string{"deploy", "--region", "eu"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if !found {
+		t.Fatal("derived value endpoint not found")
+	}
+	if endpoint != "https://eu.dev.example.com" {
+		t.Errorf("endpoint = %v, want %q", endpoint, "https://eu.dev.example.com")
+	}
+}
+
+func TestCommandBuilder_DerivedErrors(t *testing.T) {
+	yamlContent := `
+name: derived-test
+root:
+  use: derived-test
+  short: Derived test
+  run_func: runRoot
+  derived:
+    - name: value
+      from: [missing]
+      func: computeValue
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		return nil
+	})
+
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("BuildRootCommand() error = %v, want not registered error", err)
+	}
+
+	cb.RegisterFunction("computeValue", func(inputs map[string]any) (any, error) {
+		return nil, nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--missing") {
+		t.Errorf("Execute() error = %v, want undefined flag error", err)
+	}
+}
+
+func TestGetDerived_NoContext(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	if _, ok := GetDerived(cmd, "anything"); ok {
+		t.Error("GetDerived() should report missing value without a context")
+	}
+}
+
+func TestValidateConfig_Derived(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Flags: []FlagConfig{
+				{Name: "region", Type: FlagTypeString, Usage: "Region"},
+			},
+			Derived: []DerivedConfig{
+				{Name: "region", From: []string{"region"}, Func: "f"},
+				{Name: "noFunc", From: []string{"region"}},
+				{Name: "noFrom", Func: "f"},
+				{From: []string{"region"}, Func: "f"},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected errors")
+	}
+	for _, want := range []string{
+		`derived "region" conflicts`,
+		`derived "noFunc": func is required`,
+		`derived "noFrom": from must list at least one flag`,
+		"derived name is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+}
+
+func TestTypedFlagValue(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.String("name", "n", "")
+	fs.Bool("debug", true, "")
+	fs.Int("count", 3, "")
+	fs.StringSlice("tags", []string{"a", "b"}, "")
+	fs.Float64("ratio", 0.5, "")
+
+	tests := []struct {
+		name string
+		want any
+	}{
+		{"name", "n"},
+		{"debug", true},
+		{"count", 3},
+		{"tags", []string{"a", "b"}},
+		{"ratio", "0.5"},
+	}
+	for _, tt := range tests {
+		got, err := typedFlagValue(fs, tt.name)
+		if err != nil {
+			t.Fatalf("typedFlagValue(%q) error = %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("typedFlagValue(%q) = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := typedFlagValue(fs, "undefined"); err == nil {
+		t.Error("typedFlagValue() expected error for undefined flag")
+	}
+}
@@ -2,6 +2,7 @@ package cobrayaml
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
@@ -28,6 +29,9 @@ func (d *DocGenerator) GenerateYAMLReference() string {
 			ft, flagTypeGoType(ft), flagTypeExample(ft))
 	}
 	buf.WriteString("\n")
+	buf.WriteString("Applications can add their own types with `CommandBuilder.RegisterFlagType`. ")
+	buf.WriteString("The generated `handlers.go` of a tool using them has a `configureBuilder` stub to register them in, ")
+	buf.WriteString("called by the generated `main.go` before the commands are built.\n\n")
 
 	// Args Validation (from actual constants)
 	buf.WriteString("### Args Validation\n\n")
@@ -38,18 +42,23 @@ func (d *DocGenerator) GenerateYAMLReference() string {
 			at, argsTypeDescription(at), argsTypeConfig(at))
 	}
 	buf.WriteString("\n")
+	buf.WriteString("The other types can add `only_valid: true` to also reject arguments not listed in the command's `valid_args`.\n\n")
+
+	// Built-in transformers (from actual constants)
+	buf.WriteString("### Built-in Transformers\n\n")
+	buf.WriteString("| Name | Description |\n")
+	buf.WriteString("|------|-------------|\n")
+	for _, tf := range SupportedTransforms {
+		fmt.Fprintf(&buf, "| `%s` | %s |\n", tf, transformDescription(tf))
+	}
+	buf.WriteString("\n")
 
 	// ToolConfig (from reflection)
 	buf.WriteString("### ToolConfig (Root)\n\n")
 	buf.WriteString("| YAML Key | Type | Description |\n")
 	buf.WriteString("|----------|------|-------------|\n")
-	toolFields := extractFieldDocs(reflect.TypeOf(ToolConfig{}))
-	for _, f := range toolFields {
-		if f.YAMLKey == "functions" {
-			continue
-		}
-		desc := fieldDescription("ToolConfig", f.YAMLKey)
-		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.YAMLKey, f.GoType, desc)
+	for _, f := range catalogFields("ToolConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
 	}
 	buf.WriteString("\n")
 
@@ -57,10 +66,8 @@ func (d *DocGenerator) GenerateYAMLReference() string {
 	buf.WriteString("### CommandConfig\n\n")
 	buf.WriteString("| YAML Key | Type | Description |\n")
 	buf.WriteString("|----------|------|-------------|\n")
-	cmdFields := extractFieldDocs(reflect.TypeOf(CommandConfig{}))
-	for _, f := range cmdFields {
-		desc := fieldDescription("CommandConfig", f.YAMLKey)
-		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.YAMLKey, f.GoType, desc)
+	for _, f := range catalogFields("CommandConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
 	}
 	buf.WriteString("\n")
 
@@ -68,17 +75,449 @@ func (d *DocGenerator) GenerateYAMLReference() string {
 	buf.WriteString("### FlagConfig\n\n")
 	buf.WriteString("| YAML Key | Type | Required | Description |\n")
 	buf.WriteString("|----------|------|----------|-------------|\n")
-	flagFields := extractFieldDocs(reflect.TypeOf(FlagConfig{}))
-	for _, f := range flagFields {
+	for _, f := range catalogFields("FlagConfig") {
 		req := ""
 		if f.Required {
 			req = "Yes"
 		}
-		desc := fieldDescription("FlagConfig", f.YAMLKey)
-		fmt.Fprintf(&buf, "| `%s` | `%s` | %s | %s |\n", f.YAMLKey, f.GoType, req, desc)
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s | %s |\n", f.Key, f.Type, req, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// DerivedConfig (from reflection)
+	buf.WriteString("### DerivedConfig\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("DerivedConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
 	}
 	buf.WriteString("\n")
 
+	// EnvConfig (from reflection)
+	buf.WriteString("### EnvConfig\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("EnvConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// CacheConfig (from reflection)
+	buf.WriteString("### CacheConfig\n\n")
+	buf.WriteString("The output a handler writes to `cmd.OutOrStdout()` is cached in the user cache directory. ")
+	buf.WriteString("Cached commands get a `--no-cache` flag that runs the handler and refreshes the cache, ")
+	buf.WriteString("and the tool gets a `cache clear` command.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("CacheConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// ConcurrencyConfig (from reflection)
+	buf.WriteString("### ConcurrencyConfig\n\n")
+	buf.WriteString("The command gets an int flag, and `cobrayaml.Pool(cmd)` returns a worker pool of that size. ")
+	buf.WriteString("`pool.Go` runs a task once a slot is free, and `pool.Wait` returns the first error; after a failure, ")
+	buf.WriteString("the pool's context is canceled and no further tasks start.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("ConcurrencyConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// DefaultsConfig (from reflection)
+	buf.WriteString("### DefaultsConfig\n\n")
+	buf.WriteString("A command can override the flag defaults of its whole subtree, e.g. a longer timeout for all `db` commands:\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("db:\n")
+	buf.WriteString("  use: db\n")
+	buf.WriteString("  short: Database commands\n")
+	buf.WriteString("  defaults:\n")
+	buf.WriteString("    flags:\n")
+	buf.WriteString("      timeout: \"60\"\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("The defaults replace the declared defaults of the flags of all descendant commands when `commands.yaml` is loaded, ")
+	buf.WriteString("so help, generated docs and the tool manifest show the effective values. ")
+	buf.WriteString("Defaults of a nested command take precedence over those of its ancestors, and `defaults` of the root command apply to all commands. ")
+	buf.WriteString("Each flag must be declared by at least one descendant, and the value must fit its `allowed_values`.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("DefaultsConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// NotifyConfig (from reflection)
+	buf.WriteString("### NotifyConfig\n\n")
+	buf.WriteString("A notification is sent on the `command.succeeded` or `command.failed` event of the command, ")
+	buf.WriteString("so it also covers runs served from the result cache. A failing notifier prints a warning ")
+	buf.WriteString("and does not change the result of the command.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("NotifyConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// EventSinkConfig (from reflection)
+	buf.WriteString("### EventSinkConfig\n\n")
+	buf.WriteString("Every runnable command emits `command.started` before its handler runs and `command.succeeded` ")
+	buf.WriteString("or `command.failed` after it returns. Each event is sent to the sinks listed under `events` as JSON ")
+	buf.WriteString("with `type`, `time`, `tool`, `command`, `args`, `flags`, `error`, `duration`, `variant` and `identity`. ")
+	buf.WriteString("`flags` holds the `type`, `value` and `source` (`command_line` or `default`) of every flag, with the values of ")
+	buf.WriteString("sensitive flags redacted; middleware can build the same map with `cobrayaml.FlagValues(cmd)`. ")
+	buf.WriteString("`identity` is the caller the command ran for (see Caller Identity). ")
+	buf.WriteString("A failing sink prints a warning.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("EventSinkConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// ErrorConfig (from reflection)
+	buf.WriteString("### ErrorConfig\n\n")
+	buf.WriteString("Entries of the tool's `errors` catalog. A handler returns `cobrayaml.NewCatalogError(\"E1001\", host)`, ")
+	buf.WriteString("and the error is printed as `Error: E1001: <message>` followed by its `Hint:` and `See:` lines. ")
+	buf.WriteString("Generated READMEs list the catalog in an Error Reference appendix.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("ErrorConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// HelpVariantConfig (from reflection)
+	buf.WriteString("### HelpVariantConfig\n\n")
+	buf.WriteString("Entries of a command's `variants`, for experiments with help wording. Each user is shown either the ")
+	buf.WriteString("command's own descriptions (the variant named `default`) or one of its variants, chosen by a hash of the ")
+	buf.WriteString("tool, the user name and the command, so a user always sees the same wording. The user name is never stored ")
+	buf.WriteString("or sent. The shown variant is reported as `variant` in the command's events, in a `help.shown` event ")
+	buf.WriteString("whenever its help is shown, and by `cobrayaml.HelpVariant(cmd)` in handlers.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("HelpVariantConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// LicenseConfig (from reflection)
+	buf.WriteString("### LicenseConfig\n\n")
+	buf.WriteString("With a license, `cobrayaml gen` starts every generated Go file with a copyright and ")
+	buf.WriteString("`SPDX-License-Identifier` header and writes `THIRD_PARTY_NOTICES.md` next to `main.go`, listing the ")
+	buf.WriteString("version and license of each module the CLI depends on (cobrayaml, cobra and their dependencies).\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("LicenseConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// SurfaceConfig (from reflection)
+	buf.WriteString("### SurfaceConfig\n\n")
+	buf.WriteString("Entries of `surfaces`, keyed by API version. The root command gets `--api-version`; the surface is ")
+	buf.WriteString("selected by that flag, then by the `surface_env` variable, then by `default_surface`, and only its ")
+	buf.WriteString("commands are built next to the shared `commands`. A surface command must not share a name with a shared command. ")
+	buf.WriteString("`BuildRootCommand` reads the flag from the process arguments; to run other args, use `ExecuteArgs`, which builds the ")
+	buf.WriteString("commands for them. A command fails when its parsed `--api-version` differs from the surface it was built for.\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("default_surface: v1\n")
+	buf.WriteString("surface_env: MY_TOOL_API_VERSION\n")
+	buf.WriteString("surfaces:\n")
+	buf.WriteString("  v1:\n")
+	buf.WriteString("    commands:\n")
+	buf.WriteString("      get:\n")
+	buf.WriteString("        use: get <name>\n")
+	buf.WriteString("        run_func: runGetV1\n")
+	buf.WriteString("  v2:\n")
+	buf.WriteString("    commands:\n")
+	buf.WriteString("      get:\n")
+	buf.WriteString("        use: get <kind> <name>\n")
+	buf.WriteString("        run_func: runGetV2\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("SurfaceConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// BaseFlagsConfig (from reflection)
+	buf.WriteString("### BaseFlagsConfig\n\n")
+	buf.WriteString("`base_flags` is either `true` or a mapping of base flags. Each base flag is customized with ")
+	buf.WriteString("`name`, `shorthand`, `usage` and `disabled`.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("BaseFlagsConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// SettingConfig (from reflection)
+	buf.WriteString("### SettingConfig\n\n")
+	buf.WriteString("Entries of `settings_schema`. When settings are declared, the built CLI gets ")
+	buf.WriteString("`config init` (writes a commented config file with the defaults), ")
+	buf.WriteString("`config validate` (checks an existing config file) and `config where` (lists the config file cascade).\n\n")
+	buf.WriteString("Handlers read the effective value with `cobrayaml.GetSetting(cmd, key)`. ")
+	buf.WriteString("Values are resolved with the precedence **flag > env > config file > default**; ")
+	buf.WriteString("a flag only counts when it is set on the command line. ")
+	buf.WriteString("Run any command with `--debug-settings` to print each effective value and where it came from.\n\n")
+	buf.WriteString("| YAML Key | Type | Description |\n")
+	buf.WriteString("|----------|------|-------------|\n")
+	for _, f := range catalogFields("SettingConfig") {
+		fmt.Fprintf(&buf, "| `%s` | `%s` | %s |\n", f.Key, f.Type, f.Description)
+	}
+	buf.WriteString("\n")
+
+	// Per-user overrides
+	buf.WriteString("### User Overrides\n\n")
+	buf.WriteString("Each user can change flag defaults and hide commands in `overrides.yaml` in the tool's directory under ")
+	buf.WriteString("the user config directory (e.g. `~/.config/my-tool/overrides.yaml`). The file is merged when the commands ")
+	buf.WriteString("are built; commands and flags it names must exist, and only flags declared on the named command can be ")
+	buf.WriteString("given a new default.\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("root:\n")
+	buf.WriteString("  defaults:\n")
+	buf.WriteString("    output: json\n")
+	buf.WriteString("commands:\n")
+	buf.WriteString("  get:\n")
+	buf.WriteString("    defaults:\n")
+	buf.WriteString("      namespace: kube-system\n")
+	buf.WriteString("  db migrate:\n")
+	buf.WriteString("    hidden: true\n")
+	buf.WriteString("```\n\n")
+
+	// Frequently used commands
+	buf.WriteString("### Frequently Used Commands\n\n")
+	buf.WriteString("Call `CommandBuilder.ShowFrequentCommands(limit)` before `BuildRootCommand` to start the root help with a ")
+	buf.WriteString("\"Frequently used\" section listing the user's most used commands. It is off by default. Runs are only counted ")
+	buf.WriteString("while it is enabled, in `usage.json` in the tool's directory under the user cache directory, as a count per ")
+	buf.WriteString("command path without args or flag values; nothing leaves the machine. Setting `DO_NOT_TRACK=1` turns off both ")
+	buf.WriteString("counting and the section.\n\n")
+
+	// Command history
+	buf.WriteString("### Command History\n\n")
+	buf.WriteString("With `history: true`, every run of a command is recorded with its outcome in `history.json` in the tool's directory under the ")
+	buf.WriteString("user cache directory, keeping the last 100 runs. Each run has an ID that stays the same when older runs are dropped. ")
+	buf.WriteString("`history` lists them and `rerun <id>` runs an entry again. ")
+	buf.WriteString("Values of flags marked `sensitive: true` are stored as `<redacted>`, and entries containing them cannot be rerun.\n\n")
+	buf.WriteString("Commands with an `undo_func` can be reverted: `undo` calls the undo function of the last successful run not undone yet, ")
+	buf.WriteString("or of an entry with `undo <id>`, with the flags and args of that run. Failed runs cannot be undone, and each run can be undone once. ")
+	buf.WriteString("The undo function runs in place of the handler, so cleanups, events and middleware apply to it as to a run.\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("history: true\n")
+	buf.WriteString("commands:\n")
+	buf.WriteString("  login:\n")
+	buf.WriteString("    use: login\n")
+	buf.WriteString("    short: Log in\n")
+	buf.WriteString("    run_func: runLogin\n")
+	buf.WriteString("    undo_func: runLogout\n")
+	buf.WriteString("    flags:\n")
+	buf.WriteString("      - name: token\n")
+	buf.WriteString("        type: string\n")
+	buf.WriteString("        usage: API token\n")
+	buf.WriteString("        sensitive: true\n")
+	buf.WriteString("```\n\n")
+
+	// Transcripts
+	buf.WriteString("### Transcripts\n\n")
+	buf.WriteString("With `record: true`, running a command with `--record <file>` writes a transcript of the run to `file`, ")
+	buf.WriteString("e.g. to attach a reproduction to a bug report. It is a JSON document with the command line, start time, ")
+	buf.WriteString("duration, error, a summary of the environment (OS, architecture, Go and tool version, `TERM` and `SHELL`) and ")
+	buf.WriteString("the output as asciinema-style events `[seconds, stream, data]`, where stream is `o` for stdout and `e` for ")
+	buf.WriteString("stderr. Only output written to `cmd.OutOrStdout()` and `cmd.ErrOrStderr()` is captured, and values of ")
+	buf.WriteString("flags marked `sensitive: true` are redacted.\n\n")
+
+	// Accessible output
+	buf.WriteString("### Accessible Output\n\n")
+	buf.WriteString("With `accessibility: true`, the root command gets an `--accessible` flag; setting `ACCESSIBLE=1` has the ")
+	buf.WriteString("same effect in any tool. Built-in commands then write screen reader friendly output: tables such as ")
+	buf.WriteString("`jobs list` and `history` become one `HEADER: value` line per field, and lists lose their column padding. ")
+	buf.WriteString("Handlers check `cobrayaml.Accessible(cmd)` to replace their own spinners, animations and box-drawing ")
+	buf.WriteString("tables, e.g. with progress as explicit percentages.\n\n")
+
+	// Machine-readable help
+	buf.WriteString("### Machine-Readable Help\n\n")
+	buf.WriteString("Every built CLI has a hidden `--help-format` flag. `my-tool deploy --help --help-format=json` prints the help ")
+	buf.WriteString("of `deploy` and all its available subcommands as JSON, so wrapper tools and TUIs can build on any ")
+	buf.WriteString("cobrayaml CLI without parsing help text. Each command has `name`, `path`, `use`, `args`, `valid_args`, ")
+	buf.WriteString("`aliases`, `short`, `long`, `example`, `runnable`, `flags`, `inherited_flags` and `commands`; each visible ")
+	buf.WriteString("flag has `name`, `shorthand`, `type`, `default`, `usage` and `required`.\n\n")
+
+	buf.WriteString("### Asking for Commands\n\n")
+	buf.WriteString("With `ask_command: true`, `my-tool ask how do I back up the database` prints the three commands that ")
+	buf.WriteString("match the request best, with the args of their use line, and runs nothing. The built-in `keywords` ")
+	buf.WriteString("matcher scores the words of the request found in command names, descriptions and examples. ")
+	buf.WriteString("To match with a local embedding index or an external API instead, implement `CommandMatcher`, ")
+	buf.WriteString("register it with `cb.RegisterCommandMatcher(\"embeddings\", matcher)` and set `ask_matcher: embeddings`.\n\n")
+
+	buf.WriteString("### Menu Navigator\n\n")
+	buf.WriteString("With `tui: true`, `my-tool tui` shows the command tree as a numbered menu for users who prefer menus ")
+	buf.WriteString("over memorizing commands. Choosing a command asks for its args and flags, with their defaults and ")
+	buf.WriteString("allowed values, prints the equivalent command line and runs it; `b` goes back and `q` quits. ")
+	buf.WriteString("Values of sensitive flags are redacted from the printed command line.\n\n")
+
+	buf.WriteString("### Verifying Examples\n\n")
+	buf.WriteString("Keep example scripts next to `commands.yaml` and list them in the `examples` of their commands:\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("examples_dir: examples\n")
+	buf.WriteString("commands:\n")
+	buf.WriteString("  deploy:\n")
+	buf.WriteString("    use: deploy <env>\n")
+	buf.WriteString("    examples: [deploy.sh, rollback.sh]\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("`cobrayaml verify-examples commands.yaml` parses every invocation of the tool in those scripts against the ")
+	buf.WriteString("current commands, flags and args, without running any handler, and fails when a command was renamed, a flag ")
+	buf.WriteString("removed, a value does not match the flag type or a required flag or arg is missing. Pipelines, `&&` lists, ")
+	buf.WriteString("redirections and `VAR=value` prefixes are handled as in a shell; other commands of the scripts are ignored. ")
+	buf.WriteString("Invocations are parsed with the commands the tool builds, so flags and commands added by features such as ")
+	buf.WriteString("`--detach` or `history` are known, and with the surface their `--api-version` selects.\n\n")
+
+	buf.WriteString("### Side Effects\n\n")
+	buf.WriteString("Classify what each command does so people and automation know which commands are safe to run:\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("commands:\n")
+	buf.WriteString("  list:\n")
+	buf.WriteString("    use: list\n")
+	buf.WriteString("    side_effects: read-only\n")
+	buf.WriteString("  delete:\n")
+	buf.WriteString("    use: delete <name>\n")
+	buf.WriteString("    side_effects: destructive\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("Destructive commands get a `--yes` flag and ask `Continue? [y/N]` before the handler runs; when stdin is a pipe ")
+	buf.WriteString("or file rather than a terminal, as in CI, they fail at once unless `--yes` is set. Generated docs show the classification next to each command, ")
+	buf.WriteString("`--help-format json` includes it as `side_effects`, and the tool manifest marks MCP tools with `readOnlyHint` ")
+	buf.WriteString("and `destructiveHint`. With `require_side_effects: true`, `cobrayaml gen` warns about every ")
+	buf.WriteString("runnable command without `side_effects`, and fails with `--strict`.\n\n")
+
+	buf.WriteString("### Usage Quotas\n\n")
+	buf.WriteString("Commands that act on shared infrastructure can declare a quota, e.g. `quota: deployments-per-day`. ")
+	buf.WriteString("Before such a command runs, the checker set on the builder decides whether the run is within the quota:\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("builder.SetQuotaChecker(cobrayaml.QuotaCheckerFunc(func(ctx context.Context, req cobrayaml.QuotaRequest) (cobrayaml.QuotaDecision, error) {\n")
+	buf.WriteString("\treturn limiter.Allow(ctx, req.Quota) // e.g. a call to a rate limiting service\n")
+	buf.WriteString("}))\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("A denied run fails with a `*cobrayaml.QuotaExceededError` carrying the checker's reason and `RetryAfter`, ")
+	buf.WriteString("printed as e.g. `mytool deploy: quota \"deployments-per-day\" exceeded: 10 of 10 used (retry after 2h0m0s)`. ")
+	buf.WriteString("A command with a quota fails to run while no checker is set, so it never runs unchecked. ")
+	buf.WriteString("The generated `handlers.go` of a tool with quotas has a `configureBuilder` stub, called by the generated `main.go` ")
+	buf.WriteString("before the commands are built, to set the checker in.\n\n")
+
+	buf.WriteString("### Binding Flags to a Struct\n\n")
+	buf.WriteString("Instead of one `GetString`, `GetBool` or `GetInt` call per flag, a handler can read all its flags into a ")
+	buf.WriteString("struct with `flag` tags:\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("type DeployOpts struct {\n")
+	buf.WriteString("    Env      string        `flag:\"env\"`\n")
+	buf.WriteString("    Replicas int           `flag:\"replicas\"`\n")
+	buf.WriteString("    Timeout  time.Duration `flag:\"timeout\"`\n")
+	buf.WriteString("}\n\n")
+	buf.WriteString("func runDeploy(cmd *cobra.Command, args []string) error {\n")
+	buf.WriteString("    opts, err := cobrayaml.Bind[DeployOpts](cmd)\n")
+	buf.WriteString("    if err != nil {\n")
+	buf.WriteString("        return err\n")
+	buf.WriteString("    }\n")
+	buf.WriteString("    // use opts.Env, opts.Replicas and opts.Timeout\n")
+	buf.WriteString("    return nil\n")
+	buf.WriteString("}\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("Each field has the Go type of its flag (see Flag Types) or a type defined on it, such as `type Env string`. ")
+	buf.WriteString("`Bind` fails when a tagged flag is not defined on the command or its type does not fit the field.\n\n")
+
+	buf.WriteString("### Capturing Output\n\n")
+	buf.WriteString("Everything the built CLI prints goes through the writers of the root command: handler and built-in command ")
+	buf.WriteString("output to `cmd.OutOrStdout()`, and errors, warnings, hints and confirmation prompts to `cmd.ErrOrStderr()`. ")
+	buf.WriteString("Set them on the builder to capture it in a host application or test:\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("var out, errOut bytes.Buffer\n")
+	buf.WriteString("builder.SetOut(&out)\n")
+	buf.WriteString("builder.SetErr(&errOut)\n")
+	buf.WriteString("builder.SetIn(strings.NewReader(\"y\\n\")) // answers to prompts\n")
+	buf.WriteString("rootCmd, err := builder.BuildRootCommand()\n")
+	buf.WriteString("```\n\n")
+
+	buf.WriteString("### Middleware\n\n")
+	buf.WriteString("Middleware wraps the handler of every command with a `run_func`, for logging, authorization or panic recovery:\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("builder.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {\n")
+	buf.WriteString("\treturn func(cmd *cobra.Command, args []string) error {\n")
+	buf.WriteString("\t\tlog.Printf(\"running %s\", cmd.CommandPath())\n")
+	buf.WriteString("\t\treturn next(cmd, args)\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("})\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("Middleware runs in the order it was added, the first one outermost. ")
+	buf.WriteString("It runs outside the built-in behavior such as the result cache, notifications, events and background jobs, ")
+	buf.WriteString("so an authorization check also guards a command served from its result cache or started with `--detach`. ")
+	buf.WriteString("It also runs before the checks ahead of the handler, such as quotas, confirmation prompts, `validate_func` and `create_missing`, ")
+	buf.WriteString("so a refused run charges no quota, asks nothing and creates no paths. ")
+	buf.WriteString("Catalog errors it returns are still printed with their hint.\n\n")
+
+	buf.WriteString("### Caller Identity\n\n")
+	buf.WriteString("When a server runs commands in-process for REST or agent clients, it attaches the caller to the context ")
+	buf.WriteString("of each run, and handlers read it from `cmd.Context()`:\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("ctx := cobrayaml.WithIdentity(r.Context(), cobrayaml.Identity{Tenant: \"acme\", Subject: user})\n")
+	buf.WriteString("err := builder.ExecuteArgs(ctx, []string{\"deploy\", \"prod\"})\n")
+	buf.WriteString("\n")
+	buf.WriteString("// in a handler or middleware\n")
+	buf.WriteString("id, ok := cobrayaml.IdentityFromContext(cmd.Context())\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("`webui.BuilderRunner` runs commands with the context of the HTTP request, so an authenticating HTTP middleware in front of the web UI ")
+	buf.WriteString("can attach the identity the same way. Events record the identity as `identity`, and the quota checker gets it in ")
+	buf.WriteString("`QuotaRequest.Identity`, so audit logs, quotas and authorization middleware agree on the caller. ")
+	buf.WriteString("Results cached with `cache` are kept per identity, so one caller is never served another's output. ")
+	buf.WriteString("The identity does not cross process boundaries, such as background jobs or the runs of `cobrayaml ui`.\n\n")
+
+	buf.WriteString("### Restricted Editions\n\n")
+	buf.WriteString("To ship a restricted edition of a CLI from the same `commands.yaml`, generate it with only some of the commands:\n\n")
+	buf.WriteString("```bash\n")
+	buf.WriteString("cobrayaml gen commands.yaml --include 'db.*,user.list'\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("A pattern is a command path with the names joined by dots, and each name may be a glob such as `*`. ")
+	buf.WriteString("A selected command keeps its subcommands, and its parents are kept so it can be reached. ")
+	buf.WriteString("The generated handlers cover the selected commands only, and the generated `main.go` applies the patterns ")
+	buf.WriteString("with `builder.Include(...)`, which a host application can also call before `BuildRootCommand`. ")
+	buf.WriteString("A pattern that matches no command is an error. ")
+	buf.WriteString("The embedded `commands.yaml` still describes the other commands, so keep anything secret out of it.\n\n")
+
+	buf.WriteString("### Disabling Features\n\n")
+	buf.WriteString("A host application can disable optional features it does not want in its binary, e.g. running plugins from ")
+	buf.WriteString("`$PATH` or writing history files, before loading `commands.yaml`:\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("cobrayaml.DisableFeatures(cobrayaml.FeaturePlugins, cobrayaml.FeatureHistory)\n")
+	buf.WriteString("builder, err := cobrayaml.NewCommandBuilder(\"commands.yaml\")\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("Validation then fails for every key that needs a disabled feature, such as `history` or a command's `undo_func`, ")
+	buf.WriteString("instead of ignoring it. The features are ")
+	for i, feature := range SupportedFeatures {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString("`" + string(feature) + "`")
+	}
+	buf.WriteString("; with `completion` disabled the default `completion` command is left out as well.\n\n")
+
+	buf.WriteString("### Policies\n\n")
+	buf.WriteString("An organization can restrict what the `commands.yaml` files of its CLIs may use with a `policy.yaml`, ")
+	buf.WriteString("which the embedding application loads and sets before loading `commands.yaml`:\n\n")
+	buf.WriteString("```yaml\n")
+	buf.WriteString("forbid: [exec, webhooks, notify, record] # exec is discover_plugins; webhooks are notify via webhook and webhook event sinks\n")
+	buf.WriteString("require_confirmation: [\"delete\", \"db.*\"] # these commands must declare side_effects: destructive\n")
+	buf.WriteString("max_timeout: 10m # largest default of a duration flag\n")
+	buf.WriteString("max_cache_ttl: 1h\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("```go\n")
+	buf.WriteString("policy, err := cobrayaml.LoadPolicy(\"/etc/acme/policy.yaml\")\n")
+	buf.WriteString("if err != nil {\n\treturn err\n}\n")
+	buf.WriteString("cobrayaml.SetPolicy(policy)\n")
+	buf.WriteString("builder, err := cobrayaml.NewCommandBuilder(\"commands.yaml\")\n")
+	buf.WriteString("```\n\n")
+	buf.WriteString("Validation then fails with a `policy violation:` error for every key that breaks the policy. `forbid` also takes ")
+	buf.WriteString("the names of the optional features above, and `require_confirmation` takes command path patterns as `include` does.\n\n")
+
 	// Hidden Commands/Flags Example
 	buf.WriteString("### Hidden Commands/Flags\n\n")
 	buf.WriteString("```yaml\n")
@@ -98,6 +537,58 @@ func (d *DocGenerator) GenerateYAMLReference() string {
 	return buf.String()
 }
 
+// yamlReference is the JSON form of the YAML reference.
+type yamlReference struct {
+	SchemaVersion int              `json:"schema_version"`
+	FlagTypes     []referenceEntry `json:"flag_types"`
+	ArgsTypes     []referenceEntry `json:"args_types"`
+	Transforms    []referenceEntry `json:"transforms"`
+	Fields        []FieldInfo      `json:"fields"`
+}
+
+// referenceEntry describes a flag type, args type or built-in transformer.
+type referenceEntry struct {
+	Name        string `json:"name"`
+	GoType      string `json:"go_type,omitempty"`
+	Example     string `json:"example,omitempty"`
+	Description string `json:"description,omitempty"`
+	Config      string `json:"config,omitempty"`
+}
+
+// GenerateYAMLReferenceJSON generates the YAML reference as JSON, with the same
+// flag types, args types and transformers as GenerateYAMLReference and the config
+// fields of FieldCatalog.
+func (d *DocGenerator) GenerateYAMLReferenceJSON() (string, error) {
+	ref := yamlReference{SchemaVersion: SchemaVersion()}
+	for _, ft := range SupportedFlagTypes {
+		ref.FlagTypes = append(ref.FlagTypes, referenceEntry{
+			Name:    ft,
+			GoType:  flagTypeGoType(ft),
+			Example: flagTypeExample(ft),
+		})
+	}
+	for _, at := range SupportedArgsTypes {
+		ref.ArgsTypes = append(ref.ArgsTypes, referenceEntry{
+			Name:        at,
+			Description: argsTypeDescription(at),
+			Config:      argsTypeConfig(at),
+		})
+	}
+	for _, tf := range SupportedTransforms {
+		ref.Transforms = append(ref.Transforms, referenceEntry{
+			Name:        tf,
+			Description: transformDescription(tf),
+		})
+	}
+	ref.Fields = FieldCatalog()
+
+	data, err := json.MarshalIndent(ref, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal reference: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
 // GenerateQuickStart generates the quick start documentation
 func (d *DocGenerator) GenerateQuickStart() string {
 	var buf bytes.Buffer
@@ -169,12 +660,65 @@ commands:
 	buf.WriteString("# Create a new commands.yaml template\n")
 	buf.WriteString("cobrayaml init my-app\n")
 	buf.WriteString("\n")
+	buf.WriteString("# Also include commented-out examples of advanced fields\n")
+	buf.WriteString("cobrayaml init my-app --with-examples\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Record the license, so gen adds license headers and a third-party notice\n")
+	buf.WriteString("cobrayaml init my-app --license Apache-2.0 --copyright \"Example Corp.\"\n")
+	buf.WriteString("\n")
 	buf.WriteString("# Generate handler stubs\n")
 	buf.WriteString("cobrayaml gen commands.yaml\n")
 	buf.WriteString("\n")
 	buf.WriteString("# Specify output file and package name\n")
 	buf.WriteString("cobrayaml gen commands.yaml -o handlers.go -p main\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Also generate Go enum types for flags with allowed_values (enums.go)\n")
+	buf.WriteString("cobrayaml gen commands.yaml --enums\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Also generate a test checking the built commands against commands.yaml (contract_test.go)\n")
+	buf.WriteString("cobrayaml gen commands.yaml --contract-test\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Generate a restricted edition with only the db subcommands and user list\n")
+	buf.WriteString("cobrayaml gen commands.yaml --include 'db.*,user.list'\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Sort the functions registered in main.go so regenerating keeps diffs small\n")
+	buf.WriteString("cobrayaml gen commands.yaml --force --sort\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Fail on warnings such as runnable commands without a long description (for CI)\n")
+	buf.WriteString("cobrayaml gen commands.yaml --strict\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Check that functions in YAML and registered functions match\n")
+	buf.WriteString("cobrayaml check-handlers commands.yaml ./...\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Check that the example scripts of the commands still parse (for CI)\n")
+	buf.WriteString("cobrayaml verify-examples commands.yaml\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Run db backup of the built binary every night with cron or a systemd timer\n")
+	buf.WriteString("cobrayaml schedule commands.yaml db.backup --cron \"0 3 * * *\"\n")
+	buf.WriteString("cobrayaml schedule commands.yaml db.backup --cron @daily --format systemd -o /etc/systemd/system\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Serve a web form per command that runs the built binary and streams its output\n")
+	buf.WriteString("cobrayaml ui commands.yaml --serve :8080 --binary ./bin/my-app\n")
+	buf.WriteString("\n")
+	buf.WriteString("# Export the commands as tools for AI agents (MCP tools/list or OpenAI functions)\n")
+	buf.WriteString("cobrayaml manifest commands.yaml --format openai -o tools.json\n")
 	buf.WriteString("```\n\n")
+	buf.WriteString("The web UI has no authentication, so serve it on a trusted network. ")
+	buf.WriteString("It only answers requests whose `Host` is an IP address, `localhost` or the host of `--serve`, ")
+	buf.WriteString("so a page on another site cannot reach it through a rebound DNS name. ")
+	buf.WriteString("A tool can also serve the web UI itself with the `webui` package, running its registered handlers in-process; ")
+	buf.WriteString("pass the names it is served on, as in `webui.Handler(cb.GetConfig(), webui.BuilderRunner(cb), \"tools.internal\")`.\n\n")
+	buf.WriteString("The tool manifest describes each runnable command with a JSON Schema of its flags and an `args` array, ")
+	buf.WriteString("and leaves out sensitive flags so secrets are never asked from the model. ")
+	buf.WriteString("An MCP server turns a tool call back into CLI args with `gen.ToolCallArgs(name, params)`, ")
+	buf.WriteString("which rejects unknown parameters.\n\n")
+	buf.WriteString("The contract test builds the commands with `newRootCommand` of the generated `main.go` and checks them ")
+	buf.WriteString("with `cobrayaml.CheckContract` against the embedded `commands.yaml`: every declared command must exist, ")
+	buf.WriteString("every flag must have the declared type, shorthand, default and required setting, and the args validators ")
+	buf.WriteString("must accept and reject the same number and values of args as configured. `go test` then catches manual ")
+	buf.WriteString("edits of generated code that drift from the YAML.\n\n")
+	buf.WriteString("The generated `main.go` records the SHA-256 of `commands.yaml` and the cobrayaml version it was generated with. ")
+	buf.WriteString("Run the hidden `build-info` command of the built CLI to check which `commands.yaml` a binary was built from.\n\n")
 	buf.WriteString("### Generated Code Example\n\n")
 	buf.WriteString("From this YAML:\n\n")
 	buf.WriteString("```yaml\n")
@@ -254,8 +798,26 @@ func flagTypeGoType(flagType string) string {
 		return "bool"
 	case FlagTypeInt:
 		return "int"
-	case FlagTypeStringSlice:
+	case FlagTypeUint:
+		return "uint"
+	case FlagTypeUint64:
+		return "uint64"
+	case FlagTypeStringSlice, FlagTypeStringArray:
 		return "[]string"
+	case FlagTypeFile, FlagTypeDir:
+		return "string"
+	case FlagTypeURL:
+		return "*url.URL"
+	case FlagTypeIP:
+		return "net.IP"
+	case FlagTypeCIDR:
+		return "net.IPNet"
+	case FlagTypeDuration:
+		return "time.Duration"
+	case FlagTypeByteSize:
+		return "int64"
+	case FlagTypeTime:
+		return "time.Time"
 	default:
 		return "any"
 	}
@@ -269,8 +831,30 @@ func flagTypeExample(flagType string) string {
 		return "--debug"
 	case FlagTypeInt:
 		return "--count 10"
+	case FlagTypeUint:
+		return "--replicas 3"
+	case FlagTypeUint64:
+		return "--max-bytes 1048576"
 	case FlagTypeStringSlice:
 		return "--tags a,b,c"
+	case FlagTypeStringArray:
+		return `--header "Accept: a, b" --header "X-Id: 1"`
+	case FlagTypeFile:
+		return "--input data.yaml"
+	case FlagTypeDir:
+		return "--output-dir ./out"
+	case FlagTypeURL:
+		return "--endpoint https://api.example.com"
+	case FlagTypeIP:
+		return "--bind 127.0.0.1"
+	case FlagTypeCIDR:
+		return "--subnet 10.0.0.0/16"
+	case FlagTypeDuration:
+		return "--timeout 1m30s"
+	case FlagTypeByteSize:
+		return "--max-size 10MiB"
+	case FlagTypeTime:
+		return "--since 2024-01-02T15:04:05Z"
 	default:
 		return ""
 	}
@@ -290,6 +874,8 @@ func argsTypeDescription(argsType string) string {
 		return "Maximum number"
 	case ArgsTypeRange:
 		return "Range of arguments"
+	case ArgsTypeOnlyValidArgs:
+		return "Any number of arguments listed in `valid_args`"
 	default:
 		return ""
 	}
@@ -309,6 +895,25 @@ func argsTypeConfig(argsType string) string {
 		return "`type: max`, `max: N`"
 	case ArgsTypeRange:
 		return "`type: range`, `min: N`, `max: N`"
+	case ArgsTypeOnlyValidArgs:
+		return "`type: only_valid_args`, `valid_args: [...]`"
+	default:
+		return ""
+	}
+}
+
+func transformDescription(name string) string {
+	switch name {
+	case TransformTrimSpace:
+		return "Remove leading and trailing whitespace"
+	case TransformToLower:
+		return "Convert to lower case"
+	case TransformToUpper:
+		return "Convert to upper case"
+	case TransformExpandHome:
+		return "Expand a leading `~` to the user's home directory"
+	case TransformAbsPath:
+		return "Resolve to an absolute path"
 	default:
 		return ""
 	}
@@ -317,32 +922,174 @@ func argsTypeConfig(argsType string) string {
 func fieldDescription(structName, yamlKey string) string {
 	descriptions := map[string]map[string]string{
 		"ToolConfig": {
-			"name":        "Tool name",
-			"description": "Tool description",
-			"version":     "Tool version (shown with --version)",
-			"root":        "Root command configuration",
-			"commands":    "Top-level subcommands",
+			"schema_version":       "Schema version the file was written for; versions newer than `cobrayaml.SchemaVersion()` are rejected",
+			"name":                 "Tool name",
+			"description":          "Tool description",
+			"version":              "Tool version (shown with --version)",
+			"root":                 "Root command configuration",
+			"commands":             "Top-level subcommands; a key such as `db migrate up` nests the command and creates missing parent commands",
+			"config_file":          "Path of the tool's config file (a leading `~` is expanded)",
+			"config_files":         "Config file cascade, lowest precedence first (e.g., system, user, project); must include `config_file`",
+			"base_flags":           "Add the common `--config` flag that overrides the config file",
+			"discover_plugins":     "Expose executables named `<tool>-<sub>` in `$PATH` as subcommands (args and flags are passed through); on Windows, only files with a `%PATHEXT%` extension count. `$PATH` is only scanned when the args do not name a declared command",
+			"fuzzy_match":          "For an unknown command, offer the closest commands of the whole tree to run when on a terminal; `--no-interactive` or no terminal prints the usual suggestions",
+			"search_command":       "Add a `search <keyword>...` command listing the commands whose name, aliases or descriptions contain every keyword",
+			"ask_command":          "Add an `ask <request>...` command printing the commands that best match a request in natural language, without running them (see Asking for Commands)",
+			"ask_matcher":          "Name of the matcher registered with `RegisterCommandMatcher` that `ask` uses (default: `keywords`)",
+			"require_side_effects": "Warn about runnable commands without `side_effects`, so `cobrayaml gen --strict` fails until every command is classified",
+			"history":              "Record runs of commands for the added `history` and `rerun <id>` commands (see Command History)",
+			"record":               "Add a persistent `--record <file>` flag writing a transcript of the run (see Transcripts)",
+			"accessibility":        "Add a persistent `--accessible` flag for screen reader friendly output (see Accessible Output)",
+			"tui":                  "Add a `tui` command to browse the commands in a menu and run them (see Menu Navigator)",
+			"settings_schema":      "Runtime settings stored in the config file (see SettingConfig)",
+			"events":               "Sinks receiving command started, succeeded and failed events (see EventSinkConfig)",
+			"examples_dir":         "Directory of the example scripts listed in commands' `examples`, relative to commands.yaml",
+			"errors":               "Catalog of coded errors that handlers return with `NewCatalogError` (see ErrorConfig)",
+			"license":              "License of the generated CLI, written as headers into generated Go files and a third-party notice (see LicenseConfig)",
+			"surfaces":             "Versioned command sets keyed by API version; the selected one is built next to `commands` (see SurfaceConfig)",
+			"default_surface":      "Surface built when none is selected; required with `surfaces`",
+			"surface_env":          "Environment variable selecting the surface (e.g., `MY_TOOL_API_VERSION`); `--api-version` takes precedence",
+			"silence_usage":        "Do not print the usage when any command fails",
+			"silence_errors":       "Do not print the error when any command fails, e.g. because handlers report errors themselves",
+		},
+		"ArgsConfig": {
+			"type":       "Args validation type (see Args Validation)",
+			"count":      "Number of arguments for `exact`",
+			"min":        "Minimum number of arguments for `min` and `range`",
+			"max":        "Maximum number of arguments for `max` and `range`",
+			"only_valid": "Reject positional arguments not listed in the command's `valid_args`",
+		},
+		"BaseFlagsConfig": {
+			"config": "The flag that overrides the config file (default name `config`)",
+		},
+		"BaseFlagConfig": {
+			"name":      "Flag name",
+			"shorthand": "Short flag",
+			"usage":     "Description shown in help",
+			"disabled":  "Do not add the flag",
+		},
+		"SettingConfig": {
+			"key":         "Setting key; dots nest the setting in the config file (e.g., `server.port`)",
+			"type":        "Setting type: `string`, `bool`, `int`, `stringSlice` or `duration`",
+			"default":     "Default value written by `config init`",
+			"description": "Description written to the config file and generated docs",
+			"required":    "Fail `config validate` if the setting is missing",
+			"flag":        "Flag that overrides the setting when set on the command line",
+			"env":         "Environment variable that overrides the config file",
 		},
 		"CommandConfig": {
-			"use":      "Command name and argument pattern (e.g., `add <name>`)",
-			"aliases":  "Alternative command names",
-			"short":    "Brief description shown in help",
-			"long":     "Detailed description",
-			"args":     "Argument validation configuration",
-			"run_func": "Name of the handler function",
-			"flags":    "List of flag definitions",
-			"commands": "Nested subcommands",
-			"hidden":   "Hide command from help output",
+			"use":                   "Command name and argument pattern (e.g., `add <name>`)",
+			"aliases":               "Alternative command names",
+			"short":                 "Brief description shown in help",
+			"long":                  "Detailed description",
+			"args":                  "Argument validation configuration",
+			"valid_args":            "Values shells complete for positional arguments",
+			"completion_func":       "Name of a function completing positional arguments at runtime, registered with `RegisterCompletionFunc` (e.g. namespaces read from a cluster)",
+			"run_func":              "Name of the handler function, a `func(*cobra.Command, []string) error` or `func(*cobra.Command, []string)`",
+			"validate_func":         "Name of a function that validates flags and args together before the handler runs",
+			"undo_func":             "Name of a function reverting a run of the command; with `history`, `undo [id]` calls it with the flags and args of the run (see Command History)",
+			"flags":                 "List of flag definitions",
+			"commands":              "Nested subcommands (keys may also be multi-word paths)",
+			"hidden":                "Hide command from help output",
+			"renamed_from":          "Former command names kept as hidden, deprecated shims",
+			"derived":               "Values computed from other flags before the handler runs",
+			"env":                   "Environment variables the command reads",
+			"requires_config":       "Fail before the handler runs if the tool's config file does not exist",
+			"dynamic_commands_func": "Name of a function returning subcommands resolved at runtime (e.g. one per configured environment)",
+			"cache":                 "Serve the command's output from a cache within a TTL (see CacheConfig)",
+			"background":            "Set to `supported` to allow `--detach`, which runs the command as a background job managed with `jobs list`, `jobs logs` and `jobs kill`",
+			"silence_usage":         "Do not print the usage when the command fails",
+			"silence_errors":        "Do not print the error when the command fails",
+			"disable_flag_parsing":  "Pass all tokens, including flags and `--help`, to the handler as args, e.g. for wrappers that proxy to another binary",
+			"notify":                "Send a notification when the command succeeds or fails (see NotifyConfig)",
+			"required_together":     "Groups of the command's flags that must be set together, e.g. `[[user, password]]`",
+			"one_required":          "Groups of the command's flags of which at least one must be set, e.g. `[[file, url]]`",
+			"concurrency":           "Add a flag sizing the worker pool returned by `cobrayaml.Pool(cmd)` (see ConcurrencyConfig)",
+			"touches":               "Sensitive paths the command accesses (e.g. `~/.kube/config`); before it first runs, the user is asked to allow them and the answer is kept in the user config directory",
+			"annotations":           "Metadata for downstream tooling, passed through to the cobra command's `Annotations`",
+			"variants":              "Alternate help wordings keyed by name for help text experiments (see HelpVariantConfig)",
+			"deprecated":            "Deprecation message (e.g., `use 'deploy' instead`); the command is hidden from help and running it prints the message",
+			"side_effects":          "What running the command does: `read-only`, `mutating` or `destructive`; destructive commands ask for confirmation unless `--yes` is set (see Side Effects)",
+			"defaults":              "Flag defaults overridden for all descendant commands (see DefaultsConfig)",
+			"quota":                 "Name of the usage quota checked with the builder's quota checker before the command runs (see Usage Quotas)",
+			"examples":              "Example scripts in `examples_dir` whose invocations of the tool `cobrayaml verify-examples` checks (see Verifying Examples)",
+		},
+		"CacheConfig": {
+			"ttl": "How long a cached result is served (e.g., `5m`)",
+			"key": "Values the result depends on, as `flags.<name>` or `args.<index>` (default: all args and flags)",
+		},
+		"ConcurrencyConfig": {
+			"flag":      "Name of the flag (default: `concurrency`)",
+			"shorthand": "Short flag (e.g., `j`)",
+			"default":   "Default number of parallel tasks (default: the number of CPUs)",
+		},
+		"DefaultsConfig": {
+			"flags": "Default values keyed by flag name, replacing the declared defaults of the flags of all descendant commands",
+		},
+		"SurfaceConfig": {
+			"commands": "Commands of the surface, declared like the top-level `commands`",
+		},
+		"HelpVariantConfig": {
+			"short": "Brief description replacing the command's `short` (default: unchanged)",
+			"long":  "Detailed description replacing the command's `long` (default: unchanged)",
+		},
+		"LicenseConfig": {
+			"spdx":   "SPDX license identifier of the CLI (e.g., `Apache-2.0`)",
+			"holder": "Copyright holder (e.g., `Example Corp.`)",
+			"year":   "Copyright year",
+		},
+		"NotifyConfig": {
+			"on":      "Events to notify on: `success` and/or `failure` (default: both)",
+			"via":     "Notifier: `desktop`, `webhook` or a name registered with `RegisterNotifier`",
+			"url":     "URL the webhook notifier posts to; `${VAR}` references are expanded from the environment. Webhooks need `SetWebhookPoster(webhook.Post)`, which generated code sets",
+			"payload": "`text/template` rendering the webhook body from the notification (`.Command`, `.Status`, `.Error`, `.Duration`, `.Message`; `json` quotes a value); default: a JSON object",
+		},
+		"EventSinkConfig": {
+			"type":   "Sink: `stdout`, `file`, `webhook` or a name registered with `RegisterEventSink`",
+			"events": "Events sent to the sink: `command.started`, `command.succeeded`, `command.failed`, `help.shown` (default: all)",
+			"path":   "File the `file` sink appends JSON lines to (a leading `~` is expanded)",
+			"url":    "URL the `webhook` sink posts each event to; `${VAR}` references are expanded from the environment. Webhooks need `SetWebhookPoster(webhook.Post)`, which generated code sets",
+		},
+		"ErrorConfig": {
+			"code":    "Unique error code (e.g., `E1001`)",
+			"message": "Message, formatted with the args of `NewCatalogError` like `fmt.Sprintf`",
+			"hint":    "What the user can do about the error, printed after it",
+			"doc":     "Link to documentation about the error",
+		},
+		"EnvConfig": {
+			"name":        "Environment variable name",
+			"description": "Description shown in generated docs",
+			"required":    "Fail before the handler runs if the variable is not set",
+		},
+		"DerivedConfig": {
+			"name": "Name used to read the value with `cobrayaml.GetDerived`",
+			"from": "Flags passed to the derive function",
+			"func": "Name of the derive function",
 		},
 		"FlagConfig": {
-			"name":       "Flag name (e.g., `namespace` for --namespace)",
-			"shorthand":  "Short flag (e.g., `n` for -n)",
-			"type":       "Flag type (string, bool, int, stringSlice)",
-			"default":    "Default value",
-			"usage":      "Description shown in help",
-			"required":   "Mark flag as required",
-			"persistent": "Inherit flag to all subcommands",
-			"hidden":     "Hide flag from help output",
+			"name":                 "Flag name (e.g., `namespace` for --namespace)",
+			"shorthand":            "Short flag (e.g., `n` for -n)",
+			"type":                 "Flag type (see Flag Types)",
+			"default":              "Default value",
+			"usage":                "Description shown in help",
+			"required":             "Mark flag as required",
+			"persistent":           "Inherit flag to all subcommands",
+			"hidden":               "Hide flag from help output",
+			"transform_func":       "Transformer applied to the value before the handler runs (e.g., `trimSpace`, `expandHome`)",
+			"schema":               "JSON Schema (inline or file path, relative to commands.yaml) the JSON/YAML payload must match; a schema file is read when the flag is validated",
+			"must_exist":           "Set to `false` to accept a `file` or `dir` path that does not exist (default: `true`)",
+			"extensions":           "Allowed extensions for a `file` flag (e.g., `[.yaml, .json]`)",
+			"create_missing":       "Create the missing file or directory of a `file` or `dir` flag before the command runs",
+			"layout":               "Layout of a `time` flag: a Go layout or a name such as `RFC3339` (default) or `DateOnly`",
+			"relative":             "Also accept relative times such as `-24h`, `-7d` or `yesterday` for a `time` flag",
+			"allowed_values":       "Values accepted by a `string` flag (shown in help and shell completion)",
+			"example":              "Illustrative value shown in help and generated docs",
+			"deprecated":           "Deprecation message (e.g., `use --output instead`); the flag is hidden from help and using it prints the message",
+			"shorthand_deprecated": "Deprecation message of the shorthand; the shorthand is hidden from help and using it prints the message",
+			"sensitive":            "Store the value as `<redacted>` in the command history and background job list, and pass it to a job in its environment",
+			"completion":           "Values shells complete for the flag; unlike `allowed_values`, other values are accepted",
+			"completion_func":      "Name of a function completing the flag's values at runtime, registered with `RegisterCompletionFunc`",
+			"annotations":          "Metadata for downstream tooling, passed through to the flag's pflag annotations (each value as a one-element list)",
 		},
 	}
 
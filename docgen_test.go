@@ -1,6 +1,8 @@
 package cobrayaml
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -32,6 +34,32 @@ func TestDocGenerator_GenerateYAMLReference(t *testing.T) {
 	}
 }
 
+func TestDocGenerator_GenerateYAMLReferenceJSON(t *testing.T) {
+	gen := NewDocGenerator()
+	result, err := gen.GenerateYAMLReferenceJSON()
+	if err != nil {
+		t.Fatalf("GenerateYAMLReferenceJSON() error = %v", err)
+	}
+
+	var ref yamlReference
+	if err := json.Unmarshal([]byte(result), &ref); err != nil {
+		t.Fatalf("GenerateYAMLReferenceJSON() should return valid JSON, got error = %v", err)
+	}
+
+	if ref.SchemaVersion != SchemaVersion() {
+		t.Errorf("schema_version = %d, want %d", ref.SchemaVersion, SchemaVersion())
+	}
+	if len(ref.FlagTypes) != len(SupportedFlagTypes) {
+		t.Errorf("flag_types = %d entries, want %d", len(ref.FlagTypes), len(SupportedFlagTypes))
+	}
+	if len(ref.ArgsTypes) != len(SupportedArgsTypes) {
+		t.Errorf("args_types = %d entries, want %d", len(ref.ArgsTypes), len(SupportedArgsTypes))
+	}
+	if len(ref.Fields) != len(FieldCatalog()) {
+		t.Errorf("fields = %d entries, want %d", len(ref.Fields), len(FieldCatalog()))
+	}
+}
+
 func TestDocGenerator_GenerateQuickStart(t *testing.T) {
 	gen := NewDocGenerator()
 	result := gen.GenerateQuickStart()
@@ -101,13 +129,19 @@ func TestDocGenerator_AllSectionsCanBeGenerated(t *testing.T) {
 	}
 
 	// Verify GenerateInitTemplate works
-	initTemplate := GenerateInitTemplate("test-app")
+	initTemplate, err := GenerateInitTemplate("test-app")
+	if err != nil {
+		t.Errorf("Init template generation failed: %v", err)
+	}
 	if initTemplate == "" {
 		t.Error("Init template generation returned empty string")
 	}
 	if !strings.Contains(initTemplate, "test-app") {
 		t.Error("Init template should contain the app name")
 	}
+	if !strings.HasPrefix(initTemplate, fmt.Sprintf("schema_version: %d\n", SchemaVersion())) {
+		t.Error("Init template should start with the schema version")
+	}
 }
 
 func TestExtractFieldDocs(t *testing.T) {
@@ -156,4 +190,37 @@ func TestFieldDocumentation_MatchesStructFields(t *testing.T) {
 			t.Errorf("FlagConfig field %q has no description", f.YAMLKey)
 		}
 	}
+
+	derivedFields := extractFieldDocs(reflect.TypeOf(DerivedConfig{}))
+	for _, f := range derivedFields {
+		desc := fieldDescription("DerivedConfig", f.YAMLKey)
+		if desc == "" {
+			t.Errorf("DerivedConfig field %q has no description", f.YAMLKey)
+		}
+	}
+
+	envFields := extractFieldDocs(reflect.TypeOf(EnvConfig{}))
+	for _, f := range envFields {
+		desc := fieldDescription("EnvConfig", f.YAMLKey)
+		if desc == "" {
+			t.Errorf("EnvConfig field %q has no description", f.YAMLKey)
+		}
+	}
+
+	for _, typ := range []any{BaseFlagsConfig{}, BaseFlagConfig{}} {
+		name := reflect.TypeOf(typ).Name()
+		for _, f := range extractFieldDocs(reflect.TypeOf(typ)) {
+			if fieldDescription(name, f.YAMLKey) == "" {
+				t.Errorf("%s field %q has no description", name, f.YAMLKey)
+			}
+		}
+	}
+
+	settingFields := extractFieldDocs(reflect.TypeOf(SettingConfig{}))
+	for _, f := range settingFields {
+		desc := fieldDescription("SettingConfig", f.YAMLKey)
+		if desc == "" {
+			t.Errorf("SettingConfig field %q has no description", f.YAMLKey)
+		}
+	}
 }
@@ -0,0 +1,86 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// DynamicCommandsFunc returns child command definitions that depend on local state,
+// such as one subcommand per configured environment. It is called when the command
+// tree is built, so the returned commands show up in help and shell completion like
+// commands declared in YAML. Each returned command is validated like a YAML command
+// and may reference any registered function.
+// Register dynamic commands functions with RegisterFunction.
+type DynamicCommandsFunc = func() (map[string]CommandConfig, error)
+
+// lookupDynamicCommands resolves a registered dynamic commands function by name.
+func (cb *CommandBuilder) lookupDynamicCommands(name string) (DynamicCommandsFunc, error) {
+	fn, exists := cb.funcMap[name]
+	if !exists {
+		return nil, fmt.Errorf("function %s not registered", name)
+	}
+	dynamic, ok := fn.(func() (map[string]CommandConfig, error))
+	if !ok {
+		return nil, fmt.Errorf("function %s is not of type func() (map[string]cobrayaml.CommandConfig, error)", name)
+	}
+	return dynamic, nil
+}
+
+// addDynamicCommands adds the commands returned by the command's dynamic_commands_func.
+// A returned command whose name or alias is already used by a sibling is an error.
+func (cb *CommandBuilder) addDynamicCommands(cmd *cobra.Command, config CommandConfig) error {
+	if config.DynamicCommandsFunc == "" {
+		return nil
+	}
+
+	dynamic, err := cb.lookupDynamicCommands(config.DynamicCommandsFunc)
+	if err != nil {
+		return err
+	}
+	cmds, err := dynamic()
+	if err != nil {
+		return fmt.Errorf("dynamic commands of %s: %w", cmd.Name(), err)
+	}
+
+	names := make([]string, 0, len(cmds))
+	for name := range cmds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ve := &ValidationError{}
+	used := make(map[string]bool)
+	for _, sub := range cmd.Commands() {
+		for _, n := range append([]string{sub.Name()}, sub.Aliases...) {
+			used[n] = true
+		}
+	}
+	for _, name := range names {
+		subConfig := cmds[name]
+		cmdName := extractCommandName(subConfig.Use)
+		if cmdName == "" {
+			cmdName = name
+		}
+		for _, n := range append([]string{cmdName}, subConfig.Aliases...) {
+			if used[n] {
+				ve.addError("command %q: duplicate subcommand name %q", cmd.Name(), n)
+			}
+			used[n] = true
+		}
+		validateCommandRecursive(&subConfig, cmd.Name()+"/"+name, ve)
+	}
+	if ve.hasErrors() {
+		return fmt.Errorf("dynamic commands of %s: %w", cmd.Name(), ve)
+	}
+
+	for _, name := range names {
+		subCmd, err := cb.buildCommand(name, cmds[name])
+		if err != nil {
+			return fmt.Errorf("failed to build dynamic subcommand %s: %v", name, err)
+		}
+		cmd.AddCommand(subCmd)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const dynamicYAML = `
+name: dynamic-test
+root:
+  use: dynamic-test
+  short: Dynamic test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy to an environment
+    dynamic_commands_func: environments
+    commands:
+      list:
+        use: list
+        short: List environments
+`
+
+func TestCommandBuilder_DynamicCommands(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(dynamicYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var deployed string
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		deployed = cmd.Name()
+		return nil
+	})
+	cb.RegisterFunction("environments", func() (map[string]CommandConfig, error) {
+		envs := map[string]CommandConfig{}
+		for _, env := range []string{"staging", "production"} {
+			envs[env] = CommandConfig{
+				Use:     env,
+				Short:   "Deploy to " + env,
+				RunFunc: "runDeploy",
+				Args:    &ArgsConfig{Type: ArgsTypeNone},
+			}
+		}
+		return envs, nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"deploy", "staging"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if deployed != "staging" {
+		t.Errorf("deployed to %q, want staging", deployed)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{cobra.ShellCompRequestCmd, "deploy", "pro"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "production") {
+		t.Errorf("completion should offer production, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"deploy", "--help"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, want := range []string{"list", "production", "Deploy to staging"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("help should list %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCommandBuilder_DynamicCommandsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      any
+		wantErr string
+	}{
+		{
+			name:    "wrong type",
+			fn:      func() []string { return nil },
+			wantErr: "function environments is not of type func() (map[string]cobrayaml.CommandConfig, error)",
+		},
+		{
+			name: "function error",
+			fn: func() (map[string]CommandConfig, error) {
+				return nil, errors.New("no environments configured")
+			},
+			wantErr: "dynamic commands of deploy: no environments configured",
+		},
+		{
+			name: "conflicts with declared command",
+			fn: func() (map[string]CommandConfig, error) {
+				return map[string]CommandConfig{"list": {Use: "list", Short: "List"}}, nil
+			},
+			wantErr: `duplicate subcommand name "list"`,
+		},
+		{
+			name: "invalid command",
+			fn: func() (map[string]CommandConfig, error) {
+				return map[string]CommandConfig{"staging": {Use: "staging"}}, nil
+			},
+			wantErr: `command "deploy/staging": short description is required`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(dynamicYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			cb.RegisterFunction("environments", tt.fn)
+
+			_, err = cb.BuildRootCommand()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildRootCommand() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	cb, err := NewCommandBuilderFromString(dynamicYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "function environments not registered") {
+		t.Errorf("BuildRootCommand() error = %v, want not registered error", err)
+	}
+}
+
+func TestGenerator_GenerateHandlers_DynamicCommands(t *testing.T) {
+	gen, err := NewGeneratorFromString(dynamicYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	for _, want := range []string{
+		`// environments returns the subcommands of the "deploy" command resolved at runtime`,
+		"func environments() (map[string]cobrayaml.CommandConfig, error) {",
+		`"github.com/S-mishina/cobrayaml"`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code should contain %q, got:\n%s", want, code)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// EnumInfo holds information about a Go enum type generated for a flag with allowed_values.
+type EnumInfo struct {
+	TypeName string
+	FlagName string
+	Values   []EnumValue
+}
+
+// EnumValue holds a single constant of a generated enum type.
+type EnumValue struct {
+	ConstName string
+	Value     string
+}
+
+// goInitialisms lists words rendered in upper case in generated identifiers.
+var goInitialisms = map[string]bool{
+	"API": true, "CSV": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TSV": true, "UDP": true, "URL": true, "UUID": true, "XML": true,
+	"YAML": true,
+}
+
+// toPascalCase converts a flag name or value such as "output-format" or "json"
+// into an exported Go identifier such as "OutputFormat" or "JSON".
+func toPascalCase(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); goInitialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+		sb.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+
+	result := sb.String()
+	if result == "" || unicode.IsDigit(rune(result[0])) {
+		result = "V" + result
+	}
+	return result
+}
+
+// enumTypeName returns the generated enum type name for a flag, or an empty
+// string if enum types are disabled or the flag has no allowed values.
+func (g *Generator) enumTypeName(flag FlagConfig) string {
+	if !g.enumTypes || len(flag.AllowedValues) == 0 {
+		return ""
+	}
+	return toPascalCase(flag.Name)
+}
+
+// CollectEnums collects the enum types for all flags with allowed_values, sorted by type name.
+// Flags sharing a name across commands share one type, so their allowed values must match.
+// Commands are visited in name order, so the first flag of a shared type is always the same.
+func (g *Generator) CollectEnums() ([]EnumInfo, error) {
+	enums := make(map[string]EnumInfo)
+
+	var collect func(cmd CommandConfig) error
+	collect = func(cmd CommandConfig) error {
+		for _, flag := range cmd.Flags {
+			if len(flag.AllowedValues) == 0 {
+				continue
+			}
+			typeName := toPascalCase(flag.Name)
+			if existing, ok := enums[typeName]; ok {
+				if existing.FlagName != flag.Name {
+					return fmt.Errorf("flags --%s and --%s both map to enum type %s", existing.FlagName, flag.Name, typeName)
+				}
+				if !sameValues(existing.Values, flag.AllowedValues) {
+					return fmt.Errorf("flag --%s has conflicting allowed_values for enum type %s", flag.Name, typeName)
+				}
+				continue
+			}
+
+			info := EnumInfo{TypeName: typeName, FlagName: flag.Name}
+			for _, v := range flag.AllowedValues {
+				info.Values = append(info.Values, EnumValue{ConstName: typeName + toPascalCase(v), Value: v})
+			}
+			enums[typeName] = info
+		}
+		for _, name := range sortedCommandNames(cmd.Commands) {
+			if err := collect(cmd.Commands[name]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := collect(g.config.Root); err != nil {
+		return nil, err
+	}
+	for _, name := range sortedCommandNames(g.config.Commands) {
+		if err := collect(g.config.Commands[name]); err != nil {
+			return nil, err
+		}
+	}
+	for _, surface := range sortedKeys(g.config.Surfaces) {
+		cmds := g.config.Surfaces[surface].Commands
+		for _, name := range sortedCommandNames(cmds) {
+			if err := collect(cmds[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := make([]EnumInfo, 0, len(enums))
+	for _, info := range enums {
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TypeName < result[j].TypeName
+	})
+	if err := checkEnumIdentifiers(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkEnumIdentifiers fails when two generated identifiers collide, such as the
+// constants of the values "a-b" and "a_b", or the constant FormatJSON of
+// --format and the type of --format-json.
+func checkEnumIdentifiers(enums []EnumInfo) error {
+	owners := make(map[string]string)
+	for _, info := range enums {
+		owners[info.TypeName] = fmt.Sprintf("the enum type of --%s", info.FlagName)
+	}
+	for _, info := range enums {
+		for _, v := range info.Values {
+			owner := fmt.Sprintf("the value %q of --%s", v.Value, info.FlagName)
+			if other, exists := owners[v.ConstName]; exists {
+				return fmt.Errorf("%s and %s both map to the identifier %s", other, owner, v.ConstName)
+			}
+			owners[v.ConstName] = owner
+		}
+	}
+	return nil
+}
+
+// sameValues reports whether an enum's constants cover exactly the given values in order.
+func sameValues(values []EnumValue, allowed []string) bool {
+	if len(values) != len(allowed) {
+		return false
+	}
+	for i, v := range values {
+		if v.Value != allowed[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const enumsTemplate = `{{.LicenseHeader}}// Code generated by cobrayaml. DO NOT EDIT.
+// cobrayaml schema version: {{.SchemaVersion}}
+
+package {{.PackageName}}
+
+import (
+	"github.com/spf13/cobra"
+)
+{{range .Enums}}{{$enum := .}}
+// {{.TypeName}} is an allowed value of the --{{.FlagName}} flag.
+type {{.TypeName}} string
+
+// Allowed values of {{.TypeName}}.
+const (
+{{- range .Values}}
+	{{.ConstName}} {{$enum.TypeName}} = {{printf "%q" .Value}}
+{{- end}}
+)
+
+// get{{.TypeName}} returns the value of the --{{.FlagName}} flag as a {{.TypeName}}.
+func get{{.TypeName}}(cmd *cobra.Command) {{.TypeName}} {
+	v, _ := cmd.Flags().GetString({{printf "%q" .FlagName}})
+	return {{.TypeName}}(v)
+}
+{{end}}`
+
+// GenerateEnums generates Go enum types, constants and typed getters for
+// all flags with allowed_values.
+func (g *Generator) GenerateEnums(packageName string) (string, error) {
+	enums, err := g.CollectEnums()
+	if err != nil {
+		return "", err
+	}
+	if len(enums) == 0 {
+		return "", fmt.Errorf("no enums to generate (no allowed_values defined in YAML)")
+	}
+
+	tmpl, err := template.New("enums").Parse(enumsTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse enums template: %w", err)
+	}
+
+	data := struct {
+		LicenseHeader string
+		PackageName   string
+		SchemaVersion int
+		Enums         []EnumInfo
+	}{
+		LicenseHeader: g.licenseHeader(),
+		PackageName:   packageName,
+		SchemaVersion: SchemaVersion(),
+		Enums:         enums,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute enums template: %w", err)
+	}
+
+	// Format the generated code
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		// Return unformatted if formatting fails
+		return buf.String(), nil
+	}
+
+	return string(formatted), nil
+}
+
+// GenerateEnumsToFile generates enums and writes to file
+func (g *Generator) GenerateEnumsToFile(packageName, outputPath string) error {
+	code, err := g.GenerateEnums(packageName)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, []byte(code), 0644)
+}
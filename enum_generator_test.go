@@ -0,0 +1,249 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const enumTestYAML = `
+name: test
+root:
+  use: test
+  short: Test command
+  flags:
+    - name: output
+      type: string
+      usage: Output format
+      persistent: true
+      allowed_values: [json, yaml, table]
+commands:
+  get:
+    use: get
+    short: Get items
+    run_func: runGet
+    flags:
+      - name: output
+        type: string
+        usage: Output format
+        allowed_values: [json, yaml, table]
+      - name: log-level
+        type: string
+        usage: Log level
+        allowed_values: [debug, info, 2xx]
+`
+
+func TestToPascalCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"output", "Output"},
+		{"output-format", "OutputFormat"},
+		{"log_level", "LogLevel"},
+		{"json", "JSON"},
+		{"http-url", "HTTPURL"},
+		{"2xx", "V2xx"},
+		{"a.b", "AB"},
+		{"", "V"},
+	}
+	for _, tt := range tests {
+		if got := toPascalCase(tt.in); got != tt.want {
+			t.Errorf("toPascalCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGenerator_CollectEnums(t *testing.T) {
+	gen, err := NewGeneratorFromString(enumTestYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	enums, err := gen.CollectEnums()
+	if err != nil {
+		t.Fatalf("CollectEnums() error = %v", err)
+	}
+	if len(enums) != 2 {
+		t.Fatalf("expected 2 enums, got %d", len(enums))
+	}
+	if enums[0].TypeName != "LogLevel" || enums[1].TypeName != "Output" {
+		t.Errorf("enum types = %s, %s; want LogLevel, Output", enums[0].TypeName, enums[1].TypeName)
+	}
+	if got := enums[1].Values[0].ConstName; got != "OutputJSON" {
+		t.Errorf("const name = %q, want %q", got, "OutputJSON")
+	}
+}
+
+func TestGenerator_CollectEnums_Conflict(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  a:
+    use: a
+    short: A
+    flags:
+      - name: output
+        type: string
+        usage: Output
+        allowed_values: [json]
+  b:
+    use: b
+    short: B
+    flags:
+      - name: output
+        type: string
+        usage: Output
+        allowed_values: [yaml]
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	if _, err := gen.CollectEnums(); err == nil || !strings.Contains(err.Error(), "conflicting allowed_values") {
+		t.Errorf("CollectEnums() error = %v, want conflict error", err)
+	}
+}
+
+func TestGenerator_CollectEnums_Collision(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   string
+		wantErr string
+	}{
+		{
+			name: "values",
+			flags: `
+      - name: mode
+        type: string
+        usage: Mode
+        allowed_values: [a-b, a_b]`,
+			wantErr: `the value "a-b" of --mode and the value "a_b" of --mode both map to the identifier ModeAB`,
+		},
+		{
+			name: "flags",
+			flags: `
+      - name: out-format
+        type: string
+        usage: Output format
+        allowed_values: [json]
+      - name: out_format
+        type: string
+        usage: Output format
+        allowed_values: [json]`,
+			wantErr: "flags --out-format and --out_format both map to enum type OutFormat",
+		},
+		{
+			name: "type and value",
+			flags: `
+      - name: format
+        type: string
+        usage: Format
+        allowed_values: [json]
+      - name: format-json
+        type: string
+        usage: JSON format
+        allowed_values: [pretty]`,
+			wantErr: `the enum type of --format-json and the value "json" of --format both map to the identifier FormatJSON`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := NewGeneratorFromString(`
+name: test
+root:
+  use: test
+  short: Test command
+  flags:` + tt.flags + "\n")
+			if err != nil {
+				t.Fatalf("NewGeneratorFromString() error = %v", err)
+			}
+			if _, err := gen.CollectEnums(); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("CollectEnums() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateEnums(t *testing.T) {
+	gen, err := NewGeneratorFromString(enumTestYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	gen.SetEnumTypes(true)
+
+	code, err := gen.GenerateEnums("main")
+	if err != nil {
+		t.Fatalf("GenerateEnums() error = %v", err)
+	}
+
+	expected := []string{
+		"package main",
+		"type Output string",
+		`OutputJSON  Output = "json"`,
+		`OutputTable Output = "table"`,
+		`LogLevelV2xx  LogLevel = "2xx"`,
+		"func getOutput(cmd *cobra.Command) Output {",
+		`v, _ := cmd.Flags().GetString("output")`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(code, exp) {
+			t.Errorf("generated code should contain %q\nGot:\n%s", exp, code)
+		}
+	}
+
+	handlers, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	if !strings.Contains(handlers, "output := getOutput(cmd)") {
+		t.Errorf("handlers should use the typed getter\nGot:\n%s", handlers)
+	}
+
+	gen.SetEnumTypes(false)
+	handlers, err = gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	if !strings.Contains(handlers, `output, _ := cmd.Flags().GetString("output")`) {
+		t.Errorf("handlers should use GetString when enum types are disabled\nGot:\n%s", handlers)
+	}
+}
+
+func TestGenerator_GenerateEnums_NoEnums(t *testing.T) {
+	gen, err := NewGeneratorFromString(`
+name: test
+root:
+  use: test
+  short: Test command
+`)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	if _, err := gen.GenerateEnums("main"); err == nil {
+		t.Error("GenerateEnums() expected error when no allowed_values are defined")
+	}
+}
+
+func TestGenerator_GenerateEnumsToFile(t *testing.T) {
+	gen, err := NewGeneratorFromString(enumTestYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "enums.go")
+	if err := gen.GenerateEnumsToFile("main", outputPath); err != nil {
+		t.Fatalf("GenerateEnumsToFile() error = %v", err)
+	}
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read generated file: %v", err)
+	}
+	if !strings.Contains(string(content), "type LogLevel string") {
+		t.Error("generated file should contain LogLevel type")
+	}
+}
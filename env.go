@@ -0,0 +1,61 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// envKey is the context key under which declared environment variables are stored.
+type envKey struct{}
+
+// GetEnv returns the value of an environment variable declared in the command's
+// "env" list. The second return value reports whether the variable is declared
+// and set. Undeclared variables are never returned, so handlers cannot silently
+// depend on variables missing from the documented contract.
+func GetEnv(cmd *cobra.Command, name string) (string, bool) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		return "", false
+	}
+	values, ok := ctx.Value(envKey{}).(map[string]string)
+	if !ok {
+		return "", false
+	}
+	value, ok := values[name]
+	return value, ok
+}
+
+// loadEnv reads the declared environment variables of a command, fails if any
+// required one is unset, and stores the values in the command's context.
+func loadEnv(cmd *cobra.Command, env []EnvConfig) error {
+	if len(env) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(env))
+	var missing []string
+	for _, e := range env {
+		value, ok := os.LookupEnv(e.Name)
+		if !ok {
+			if e.Required {
+				missing = append(missing, e.Name)
+			}
+			continue
+		}
+		values[e.Name] = value
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required environment variable(s) not set: %s", strings.Join(missing, ", "))
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, envKey{}, values))
+	return nil
+}
@@ -0,0 +1,113 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandBuilder_Env(t *testing.T) {
+	yamlContent := `
+name: env-test
+root:
+  use: env-test
+  short: Env test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    env:
+      - name: ENV_TEST_TOKEN
+        description: API token
+        required: true
+      - name: ENV_TEST_REGION
+        description: Default region
+      - name: ENV_TEST_UNSET
+        description: Optional value
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	values := map[string]string{}
+	var unsetFound, undeclaredFound bool
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		values["ENV_TEST_TOKEN"], _ = GetEnv(cmd, "ENV_TEST_TOKEN")
+		values["ENV_TEST_REGION"], _ = GetEnv(cmd, "ENV_TEST_REGION")
+		_, unsetFound = GetEnv(cmd, "ENV_TEST_UNSET")
+		_, undeclaredFound = GetEnv(cmd, "HOME")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.SetArgs([]string{"deploy"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "ENV_TEST_TOKEN") {
+		t.Errorf("Execute() error = %v, want missing ENV_TEST_TOKEN error", err)
+	}
+
+	t.Setenv("ENV_TEST_TOKEN", "secret")
+	t.Setenv("ENV_TEST_REGION", "eu")
+	rootCmd.SetArgs([]string{"deploy"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if values["ENV_TEST_TOKEN"] != "secret" {
+		t.Errorf("GetEnv(ENV_TEST_TOKEN) = %q, want %q", values["ENV_TEST_TOKEN"], "secret")
+	}
+	if values["ENV_TEST_REGION"] != "eu" {
+		t.Errorf("GetEnv(ENV_TEST_REGION) = %q, want %q", values["ENV_TEST_REGION"], "eu")
+	}
+	if unsetFound {
+		t.Error("GetEnv() should report unset variables as missing")
+	}
+	if undeclaredFound {
+		t.Error("GetEnv() should not return undeclared variables")
+	}
+}
+
+func TestGetEnv_NoContext(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	if _, ok := GetEnv(cmd, "HOME"); ok {
+		t.Error("GetEnv() should report missing value without a context")
+	}
+}
+
+func TestValidateConfig_Env(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Env: []EnvConfig{
+				{Name: "TOKEN"},
+				{Name: "TOKEN"},
+				{Name: "BAD NAME"},
+				{Description: "no name"},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected errors")
+	}
+	for _, want := range []string{
+		`duplicate env "TOKEN"`,
+		`env "BAD NAME" is not a valid environment variable name`,
+		"env name is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+}
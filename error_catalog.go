@@ -0,0 +1,151 @@
+package cobrayaml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrorConfig represents an entry of the error catalog in commands.yaml.
+// Handlers return catalog errors with NewCatalogError, and they are printed
+// with their code, hint and doc link.
+//
+// Fields:
+//   - Code: Unique error code (e.g., "E1001")
+//   - Message: Message, formatted with the args of NewCatalogError like fmt.Sprintf
+//   - Hint: What the user can do about the error
+//   - Doc: Link to documentation about the error
+//
+// Example YAML:
+//
+//	errors:
+//	  - code: E1001
+//	    message: "cannot reach %s"
+//	    hint: Check your network connection and proxy settings.
+//	    doc: https://example.com/errors/E1001
+type ErrorConfig struct {
+	Code    string `yaml:"code"`
+	Message string `yaml:"message"`
+	Hint    string `yaml:"hint,omitempty"`
+	Doc     string `yaml:"doc,omitempty"`
+}
+
+// CatalogError is an error of the error catalog, created with NewCatalogError.
+type CatalogError struct {
+	Code    string // code of the catalog entry
+	Message string // message of the entry formatted with the args
+	Hint    string // hint of the entry
+	Doc     string // doc link of the entry
+
+	args []any // args of NewCatalogError, formatted with the entry's message
+}
+
+// NewCatalogError returns the error of the catalog entry code, its message
+// formatted with args like fmt.Sprintf. Return it from a handler, wrapped or not:
+//
+//	return cobrayaml.NewCatalogError("E1001", host)
+//
+// The entry is looked up in the errors section of the commands.yaml the
+// running command was built from, when the error is returned from the command;
+// until then, and for codes not in the catalog, the message is the args
+// separated by spaces.
+func NewCatalogError(code string, args ...any) error {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = fmt.Sprint(arg)
+	}
+	return &CatalogError{Code: code, Message: strings.Join(values, " "), args: args}
+}
+
+// Error returns the code and the message.
+func (e *CatalogError) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// catalogMessageError is an error wrapping a catalog error, with the message of
+// the wrapping error rewritten to show the resolved catalog message.
+type catalogMessageError struct {
+	msg string
+	err error
+}
+
+func (e *catalogMessageError) Error() string { return e.msg }
+func (e *catalogMessageError) Unwrap() error { return e.err }
+
+// resolveCatalogError fills in a catalog error in err from the catalog of the
+// builder. An error wrapping it, e.g. with fmt.Errorf, has had its message
+// formatted already, so its message is rewritten to show the resolved one.
+func (cb *CommandBuilder) resolveCatalogError(err error) error {
+	var catalogErr *CatalogError
+	if !errors.As(err, &catalogErr) {
+		return err
+	}
+	var entry *ErrorConfig
+	for i := range cb.config.Errors {
+		if cb.config.Errors[i].Code == catalogErr.Code {
+			entry = &cb.config.Errors[i]
+			break
+		}
+	}
+	if entry == nil {
+		return err
+	}
+
+	unresolved := catalogErr.Error()
+	catalogErr.Message = fmt.Sprintf(entry.Message, catalogErr.args...)
+	catalogErr.Hint = entry.Hint
+	catalogErr.Doc = entry.Doc
+	if err == error(catalogErr) {
+		return err
+	}
+	msg := err.Error()
+	if i := strings.LastIndex(msg, unresolved); i >= 0 {
+		msg = msg[:i] + catalogErr.Error() + msg[i+len(unresolved):]
+	}
+	return &catalogMessageError{msg: msg, err: err}
+}
+
+// addErrorCatalog resolves catalog errors returned by the pre-run and run of
+// cmd against the catalog of the builder, and prints them with their hint and
+// doc link instead of cobra's plain error line.
+func (cb *CommandBuilder) addErrorCatalog(cmd *cobra.Command) {
+	if len(cb.config.Errors) == 0 {
+		return
+	}
+	if preRunE := cmd.PreRunE; preRunE != nil {
+		cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+			return cb.printCatalogError(cmd, preRunE(cmd, args))
+		}
+	}
+	if runE := cmd.RunE; runE != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			return cb.printCatalogError(cmd, runE(cmd, args))
+		}
+	}
+}
+
+// printCatalogError resolves a catalog error in err and prints it with its
+// hint and doc link, unless errors are silenced.
+func (cb *CommandBuilder) printCatalogError(cmd *cobra.Command, err error) error {
+	err = cb.resolveCatalogError(err)
+	var catalogErr *CatalogError
+	if !errors.As(err, &catalogErr) || cmd.SilenceErrors || cmd.Root().SilenceErrors {
+		return err
+	}
+
+	w := cmd.ErrOrStderr()
+	fmt.Fprintln(w, cmd.ErrPrefix(), err.Error())
+	if catalogErr.Hint != "" {
+		fmt.Fprintln(w, "Hint:", catalogErr.Hint)
+	}
+	if catalogErr.Doc != "" {
+		fmt.Fprintln(w, "See:", catalogErr.Doc)
+	}
+	cmd.SilenceErrors = true
+	return err
+}
@@ -0,0 +1,144 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const errorCatalogYAML = `
+name: catalog-test
+root:
+  use: catalog-test
+  short: Catalog test
+errors:
+  - code: E1001
+    message: cannot reach %s on port %d
+    hint: Check your network connection.
+    doc: https://example.com/errors/E1001
+  - code: E1002
+    message: deployment failed
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+`
+
+func TestCommandBuilder_ErrorCatalog(t *testing.T) {
+	tests := []struct {
+		name       string
+		newErr     func() error
+		wantErr    string
+		wantStderr string
+	}{
+		{
+			name:    "catalog error",
+			newErr:  func() error { return NewCatalogError("E1001", "api.example.com", 443) },
+			wantErr: "E1001: cannot reach api.example.com on port 443",
+			wantStderr: "Error: E1001: cannot reach api.example.com on port 443\n" +
+				"Hint: Check your network connection.\n" +
+				"See: https://example.com/errors/E1001\n",
+		},
+		{
+			name:       "wrapped without hint",
+			newErr:     func() error { return fmt.Errorf("prod: %w", NewCatalogError("E1002")) },
+			wantErr:    "prod: E1002: deployment failed",
+			wantStderr: "Error: prod: E1002: deployment failed\n",
+		},
+		{
+			name:       "unknown code",
+			newErr:     func() error { return NewCatalogError("E9999", "x", 1) },
+			wantErr:    "E9999: x 1",
+			wantStderr: "Error: E9999: x 1\n",
+		},
+		{
+			name:       "other error",
+			newErr:     func() error { return errors.New("boom") },
+			wantErr:    "boom",
+			wantStderr: "Error: boom\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr, err := testTool{yaml: errorCatalogYAML, funcs: map[string]any{
+				"runDeploy": func(cmd *cobra.Command, args []string) error { return tt.newErr() },
+			}}.run(t, "deploy")
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want prefix %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_ErrorCatalog_SilenceErrors(t *testing.T) {
+	yamlContent := strings.Replace(errorCatalogYAML, "root:", "silence_errors: true\nroot:", 1)
+	stderr, err := testTool{yaml: yamlContent, funcs: map[string]any{
+		"runDeploy": func(cmd *cobra.Command, args []string) error { return NewCatalogError("E1001", "db", 5432) },
+	}}.run(t, "deploy")
+
+	var catalogErr *CatalogError
+	if !errors.As(err, &catalogErr) || catalogErr.Hint != "Check your network connection." {
+		t.Fatalf("Execute() error = %v, want the catalog error", err)
+	}
+	if strings.Contains(stderr, "E1001") {
+		t.Errorf("stderr = %q, want no error", stderr)
+	}
+}
+
+func TestCommandBuilder_ErrorCatalog_PerBuilder(t *testing.T) {
+	// Building another tool afterwards must not change the catalog of the first.
+	rootCmd := testTool{yaml: errorCatalogYAML, funcs: map[string]any{
+		"runDeploy": func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("prod: %w", NewCatalogError("E1001", "db", 5432))
+		},
+	}}.build(t)
+	other := strings.Replace(errorCatalogYAML, "cannot reach %s on port %d", "other tool %s %d", 1)
+	testTool{yaml: other, funcs: map[string]any{"runDeploy": noopRun}}.mustRun(t, "deploy")
+
+	var stderr bytes.Buffer
+	rootCmd.SetOut(&bytes.Buffer{})
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs([]string{"deploy"})
+	err := rootCmd.Execute()
+	if want := "prod: E1001: cannot reach db on port 5432"; err == nil || err.Error() != want {
+		t.Errorf("Execute() error = %v, want %q", err, want)
+	}
+	if !strings.Contains(stderr.String(), "Hint: Check your network connection.") {
+		t.Errorf("stderr = %q, want the hint", stderr.String())
+	}
+}
+
+func TestValidateConfig_ErrorCatalog(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		Errors: []ErrorConfig{
+			{Code: "E1", Message: "one"},
+			{Code: "E1", Message: "again"},
+			{Code: "E2"},
+			{Message: "no code"},
+		},
+	}
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() error = nil")
+	}
+	for _, want := range []string{
+		`errors[1]: duplicate code "E1"`,
+		`errors[2]: message is required for code "E2"`,
+		"errors[3]: code is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateConfig() error = %v, want %q", err, want)
+		}
+	}
+}
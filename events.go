@@ -0,0 +1,219 @@
+package cobrayaml
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Command lifecycle events.
+const (
+	// EventCommandStarted is emitted before the handler runs.
+	EventCommandStarted = "command.started"
+	// EventCommandSucceeded is emitted when the handler returns no error.
+	EventCommandSucceeded = "command.succeeded"
+	// EventCommandFailed is emitted when the handler returns an error.
+	EventCommandFailed = "command.failed"
+	// EventHelpShown is emitted when the help of a command with help variants is shown.
+	EventHelpShown = "help.shown"
+)
+
+// SupportedEvents lists all events accepted in an event sink's events filter.
+var SupportedEvents = []string{
+	EventCommandStarted,
+	EventCommandSucceeded,
+	EventCommandFailed,
+	EventHelpShown,
+}
+
+// Built-in event sinks.
+const (
+	// EventSinkStdout writes each event as a JSON line to the command's output.
+	EventSinkStdout = "stdout"
+	// EventSinkFile appends each event as a JSON line to the sink's path.
+	EventSinkFile = "file"
+	// EventSinkWebhook posts each event as JSON to the sink's url.
+	EventSinkWebhook = "webhook"
+)
+
+// Event describes a step in the lifecycle of a command run.
+type Event struct {
+	Type     string                `json:"type"`            // EventCommandStarted, EventCommandSucceeded, EventCommandFailed or EventHelpShown
+	Time     time.Time             `json:"time"`            // when the event occurred
+	Tool     string                `json:"tool"`            // name of the root command
+	Command  string                `json:"command"`         // full command path, e.g. "mytool db backup"
+	Args     []string              `json:"args"`            // positional args
+	Flags    map[string]TypedValue `json:"flags,omitempty"` // effective flag values, sensitive ones redacted (see FlagValues)
+	Error    string                `json:"error,omitempty"`
+	Duration string                `json:"duration,omitempty"` // how long the handler ran, for finished commands
+	Variant  string                `json:"variant,omitempty"`  // help variant shown to the user (see HelpVariantConfig)
+	Identity *Identity             `json:"identity,omitempty"` // caller the command ran for (see WithIdentity)
+}
+
+// EventSink receives the lifecycle events of commands.
+type EventSink interface {
+	Emit(ctx context.Context, e Event) error
+}
+
+// EventSinkFunc adapts a function to the EventSink interface.
+type EventSinkFunc func(ctx context.Context, e Event) error
+
+// Emit calls f(ctx, e).
+func (f EventSinkFunc) Emit(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// RegisterEventSink registers an event sink that an events entry can refer to by
+// type, replacing the built-in sink if name is stdout, file or webhook.
+func (cb *CommandBuilder) RegisterEventSink(name string, sink EventSink) {
+	if cb.eventSinks == nil {
+		cb.eventSinks = make(map[string]EventSink)
+	}
+	cb.eventSinks[name] = sink
+}
+
+// eventSink returns the sink registered as config.Type or the built-in one.
+// out is the writer of the stdout sink.
+func (cb *CommandBuilder) eventSink(config EventSinkConfig, out io.Writer) (EventSink, bool) {
+	if sink, exists := cb.eventSinks[config.Type]; exists {
+		return sink, true
+	}
+	switch config.Type {
+	case EventSinkStdout:
+		return EventSinkFunc(func(ctx context.Context, e Event) error {
+			return writeEventLine(out, e)
+		}), true
+	case EventSinkFile:
+		return EventSinkFunc(func(ctx context.Context, e Event) error {
+			return appendEvent(config.Path, e)
+		}), true
+	case EventSinkWebhook:
+		if cb.webhookPoster == nil {
+			return nil, false
+		}
+		return EventSinkFunc(func(ctx context.Context, e Event) error {
+			body, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			return cb.webhookPoster(ctx, os.ExpandEnv(config.URL), body)
+		}), true
+	}
+	return nil, false
+}
+
+// checkEventSinks reports event sinks whose type is neither built in nor registered.
+func (cb *CommandBuilder) checkEventSinks() error {
+	for _, config := range cb.config.Events {
+		if _, exists := cb.eventSink(config, io.Discard); !exists {
+			if config.Type == EventSinkWebhook {
+				return errNoWebhookPoster("the webhook event sink")
+			}
+			return fmt.Errorf("event sink %s not registered", config.Type)
+		}
+	}
+	return nil
+}
+
+// subscriber is a sink receiving the lifecycle events of a command, such as the
+// notifier of a command with notify.
+type subscriber struct {
+	name   string   // named in warnings, e.g. "webhook notification"
+	events []string // events the sink receives
+	sink   EventSink
+}
+
+// addEvents wraps the RunE of cmd so the configured sinks and the subscribers
+// of cmd receive its lifecycle events. A failing sink only prints a warning.
+func (cb *CommandBuilder) addEvents(cmd *cobra.Command, subscribers ...*subscriber) {
+	subscribers = slices.DeleteFunc(subscribers, func(s *subscriber) bool { return s == nil })
+	if len(cb.config.Events) == 0 && len(subscribers) == 0 || cmd.RunE == nil {
+		return
+	}
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		e := Event{
+			Type:    EventCommandStarted,
+			Time:    time.Now(),
+			Tool:    cmd.Root().Name(),
+			Command: cmd.CommandPath(),
+			Args:    args,
+			Flags:   FlagValues(cmd),
+			Variant: HelpVariant(cmd),
+		}
+		cb.emit(cmd, e, subscribers...)
+
+		start := e.Time
+		runErr := run(cmd, args)
+
+		e.Type = EventCommandSucceeded
+		e.Time = time.Now()
+		e.Duration = e.Time.Sub(start).Round(time.Millisecond).String()
+		if runErr != nil {
+			e.Type = EventCommandFailed
+			e.Error = runErr.Error()
+		}
+		cb.emit(cmd, e, subscribers...)
+		return runErr
+	}
+}
+
+// emit sends e, with the caller identity of cmd's context, to every sink and
+// subscriber whose events filter includes it.
+func (cb *CommandBuilder) emit(cmd *cobra.Command, e Event, subscribers ...*subscriber) {
+	e.Identity = contextIdentity(cmd.Context())
+	all := make([]*subscriber, 0, len(cb.config.Events)+len(subscribers))
+	for _, config := range cb.config.Events {
+		sink, _ := cb.eventSink(config, cmd.OutOrStdout()) // checked by checkEventSinks
+		all = append(all, &subscriber{name: config.Type + " event sink", events: config.Events, sink: sink})
+	}
+	for _, sub := range append(all, subscribers...) {
+		if len(sub.events) > 0 && !slices.Contains(sub.events, e.Type) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		if err := sub.sink.Emit(ctx, e); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s failed: %v\n", sub.name, err)
+		}
+		cancel()
+	}
+}
+
+// writeEventLine writes e to w as a single JSON line.
+func writeEventLine(w io.Writer, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// appendEvent appends e as a JSON line to the file at path, creating it and its
+// directory if needed. A leading ~ in path is expanded.
+func appendEvent(path string, e Event) error {
+	path, err := expandHome(path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := writeEventLine(f, e); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
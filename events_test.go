@@ -0,0 +1,216 @@
+package cobrayaml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/S-mishina/cobrayaml/webhook"
+)
+
+const eventsYAML = `
+name: events-test
+root:
+  use: events-test
+  short: Events test
+events:
+  - type: stdout
+  - type: file
+    path: %s
+  - type: audit
+    events: [command.failed]
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+`
+
+func TestCommandBuilder_Events(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "events.jsonl")
+	yamlContent := strings.Replace(eventsYAML, "%s", path, 1)
+
+	var audited []Event
+	tool := testTool{
+		yaml: yamlContent,
+		funcs: map[string]any{
+			"runDeploy": func(cmd *cobra.Command, args []string) error {
+				if args[0] == "prod" {
+					return errors.New("frozen")
+				}
+				cmd.Println("deployed")
+				return nil
+			},
+		},
+		setup: func(cb *CommandBuilder) {
+			cb.RegisterEventSink("audit", EventSinkFunc(func(ctx context.Context, e Event) error {
+				audited = append(audited, e)
+				return nil
+			}))
+		},
+	}
+	out, err := tool.run(t, "deploy", "staging")
+	if err != nil {
+		t.Fatalf("deploy staging error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || lines[1] != "deployed" {
+		t.Fatalf("output = %q, want started event, handler output and succeeded event", out)
+	}
+	var started, succeeded Event
+	if err := json.Unmarshal([]byte(lines[0]), &started); err != nil {
+		t.Fatalf("stdout event is not JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &succeeded); err != nil {
+		t.Fatalf("stdout event is not JSON: %v", err)
+	}
+	if started.Type != EventCommandStarted || started.Tool != "events-test" || started.Command != "events-test deploy" ||
+		!reflect.DeepEqual(started.Args, []string{"staging"}) || started.Duration != "" {
+		t.Errorf("started event = %+v", started)
+	}
+	if succeeded.Type != EventCommandSucceeded || succeeded.Duration == "" || succeeded.Error != "" {
+		t.Errorf("succeeded event = %+v", succeeded)
+	}
+	if len(audited) != 0 {
+		t.Errorf("audit sink should only receive failures, got %+v", audited)
+	}
+
+	if _, err := tool.run(t, "deploy", "prod"); err == nil || err.Error() != "frozen" {
+		t.Fatalf("deploy prod error = %v, want the handler error", err)
+	}
+	if len(audited) != 1 || audited[0].Type != EventCommandFailed || audited[0].Error != "frozen" {
+		t.Errorf("audit sink events = %+v, want one failure", audited)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("file sink should create %s: %v", path, err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0o600 {
+		t.Errorf("file sink mode = %v, want 0600", info.Mode().Perm())
+	}
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("file event is not JSON: %v", err)
+		}
+		types = append(types, e.Type)
+	}
+	want := []string{EventCommandStarted, EventCommandSucceeded, EventCommandStarted, EventCommandFailed}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("file sink events = %v, want %v", types, want)
+	}
+}
+
+func TestCommandBuilder_EventsWebhook(t *testing.T) {
+	var got []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			t.Errorf("webhook body is not an event: %v", err)
+		}
+		got = append(got, e)
+		if e.Type == EventCommandStarted {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("EVENTS_TEST_URL", server.URL)
+
+	yamlContent := `
+name: events-test
+root:
+  use: events-test
+  short: Events test
+  run_func: runRoot
+events:
+  - type: webhook
+    url: ${EVENTS_TEST_URL}/events
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runRoot", noopRun)
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "needs a webhook poster set with SetWebhookPoster") {
+		t.Fatalf("BuildRootCommand() without a poster error = %v, want a missing poster error", err)
+	}
+
+	tool := testTool{yaml: yamlContent, funcs: map[string]any{"runRoot": noopRun}}
+	tool.setup = func(cb *CommandBuilder) { cb.SetWebhookPoster(webhook.Post) }
+	stderr, err := tool.run(t)
+	if err != nil {
+		t.Fatalf("a failing sink should not fail the command, got error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].Type != EventCommandStarted || got[1].Type != EventCommandSucceeded {
+		t.Errorf("webhook events = %+v, want started and succeeded", got)
+	}
+	if want := "Warning: webhook event sink failed: webhook returned 503 Service Unavailable\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestCommandBuilder_EventsUnregistered(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(strings.Replace(eventsYAML, "%s", filepath.Join(t.TempDir(), "events.jsonl"), 1))
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error { return nil })
+
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "event sink audit not registered") {
+		t.Errorf("BuildRootCommand() error = %v, want event sink not registered", err)
+	}
+}
+
+func TestValidateConfig_Events(t *testing.T) {
+	tests := []struct {
+		name    string
+		sink    EventSinkConfig
+		wantErr string
+	}{
+		{name: "stdout", sink: EventSinkConfig{Type: EventSinkStdout, Events: []string{EventCommandFailed}}},
+		{name: "registered sink", sink: EventSinkConfig{Type: "otel"}},
+		{name: "missing type", sink: EventSinkConfig{}, wantErr: "events[0]: type is required"},
+		{
+			name:    "unknown event",
+			sink:    EventSinkConfig{Type: EventSinkStdout, Events: []string{"command.retried"}},
+			wantErr: `events[0]: event "command.retried" is not supported`,
+		},
+		{name: "file without path", sink: EventSinkConfig{Type: EventSinkFile}, wantErr: "events[0]: file sink requires path"},
+		{name: "webhook without url", sink: EventSinkConfig{Type: EventSinkWebhook}, wantErr: "events[0]: webhook sink requires url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name:   "test",
+				Root:   CommandConfig{Use: "test", Short: "Test"},
+				Events: []EventSinkConfig{tt.sink},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
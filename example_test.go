@@ -0,0 +1,184 @@
+package cobrayaml_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/S-mishina/cobrayaml"
+	"github.com/spf13/cobra"
+)
+
+const todoYAML = `
+name: todo
+description: Manage todo items
+root:
+  use: todo
+  short: Manage todo items
+commands:
+  add:
+    use: add <title>
+    short: Add a todo item
+    run_func: runAdd
+    args:
+      type: exact
+      count: 1
+    flags:
+      - name: priority
+        shorthand: p
+        type: string
+        default: normal
+        usage: Priority of the item
+`
+
+func ExampleNewCommandBuilderFromString() {
+	builder, err := cobrayaml.NewCommandBuilderFromString(todoYAML)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	builder.RegisterFunction("runAdd", func(cmd *cobra.Command, args []string) error {
+		priority, _ := cmd.Flags().GetString("priority")
+		fmt.Printf("Adding %q with %s priority\n", args[0], priority)
+		return nil
+	})
+
+	rootCmd, err := builder.BuildRootCommand()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	rootCmd.SetArgs([]string{"add", "Buy milk", "-p", "high"})
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+	}
+	// Output: Adding "Buy milk" with high priority
+}
+
+func ExampleCommandBuilder_AttachTo() {
+	builder, err := cobrayaml.NewCommandBuilderFromString(todoYAML)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	builder.RegisterFunction("runAdd", func(cmd *cobra.Command, args []string) error {
+		fmt.Println("Added", args[0])
+		return nil
+	})
+
+	// An existing root command defined in Go.
+	rootCmd := &cobra.Command{Use: "app"}
+	if err := builder.AttachTo(rootCmd); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	rootCmd.SetArgs([]string{"add", "Buy milk"})
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+	}
+	// Output: Added Buy milk
+}
+
+func ExampleGenerator_GenerateHandlers() {
+	gen, err := cobrayaml.NewGeneratorFromString(todoYAML)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Print only the generated handler.
+	fmt.Print(code[strings.Index(code, "// runAdd"):])
+	// Output:
+	// // runAdd handles the "add <title>" command
+	// func runAdd(cmd *cobra.Command, args []string) error {
+	// 	// Auto-generated flag/arg getters
+	// 	priority, _ := cmd.Flags().GetString("priority")
+	// 	arg0 := args[0]
+	//
+	// 	// TODO: Implement your logic here
+	// 	_ = priority
+	// 	_ = arg0
+	//
+	// 	return nil
+	// }
+}
+
+func ExampleGenerator_GenerateDocs() {
+	gen, err := cobrayaml.NewGeneratorFromString(todoYAML)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Print only the commands section.
+	fmt.Print(docs[strings.Index(docs, "## Commands"):])
+	// Output:
+	// ## Commands
+	//
+	// ### add
+	//
+	// Add a todo item
+	//
+	// ```bash
+	// todo add <title>
+	// ```
+	//
+	// **Arguments:** Exactly 1 argument(s) required
+	//
+	// **Flags:**
+	//
+	// | Flag | Shorthand | Type | Default | Description |
+	// |------|-----------|------|---------|-------------|
+	// | `--priority` | `-p` | string | `normal` | Priority of the item |
+}
+
+func ExampleFromCobra() {
+	rootCmd := &cobra.Command{Use: "legacy", Short: "Legacy CLI"}
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "sync",
+		Short: "Sync data",
+		Args:  cobra.NoArgs,
+		RunE:  func(cmd *cobra.Command, args []string) error { return nil },
+	})
+
+	config, err := cobrayaml.FromCobra(rootCmd)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	out, err := config.ToYAML()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Print(out)
+	// Output:
+	// schema_version: 1
+	// name: legacy
+	// description: Legacy CLI
+	// root:
+	//   use: legacy
+	//   short: Legacy CLI
+	// commands:
+	//   sync:
+	//     use: sync
+	//     short: Sync data
+	//     args:
+	//       type: none
+	//     run_func: runSync
+}
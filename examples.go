@@ -1,6 +1,11 @@
 package cobrayaml
 
-import "gopkg.in/yaml.v2"
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
 
 // ExampleCommandsYAML is the example YAML configuration used in documentation.
 // This is also used in tests to ensure the example stays valid.
@@ -49,6 +54,7 @@ const ExampleMainGo = `package main
 import (
     _ "embed"
     "fmt"
+    "os"
 
     "github.com/S-mishina/cobrayaml"
     "github.com/spf13/cobra"
@@ -64,8 +70,9 @@ func main() {
     builder.RegisterFunction("runAdd", runAdd)
     builder.RegisterFunction("runDelete", runDelete)
 
-    rootCmd, _ := builder.BuildRootCommand()
-    rootCmd.Execute()
+    if err := builder.Execute(); err != nil {
+        os.Exit(1)
+    }
 }
 
 func runList(cmd *cobra.Command, args []string) error {
@@ -86,10 +93,22 @@ func runDelete(cmd *cobra.Command, args []string) error {
 
 // GenerateInitTemplate generates a commands.yaml template for the given tool name.
 // This ensures the template always matches the current YAML schema.
-func GenerateInitTemplate(name string) string {
-	config := ToolConfig{
-		Name:    name,
-		Version: "0.1.0",
+func GenerateInitTemplate(name string) (string, error) {
+	return GenerateInitTemplateWithOptions(name, InitOptions{})
+}
+
+// InitOptions customizes the template generated by GenerateInitTemplateWithOptions.
+type InitOptions struct {
+	Examples bool           // append commented-out examples of advanced fields
+	License  *LicenseConfig // license of the tool, written as the license key
+}
+
+// initConfig returns the config of the init template for the given tool name.
+func initConfig(name string) ToolConfig {
+	return ToolConfig{
+		SchemaVersion: SchemaVersion(),
+		Name:          name,
+		Version:       "0.1.0",
 		Root: CommandConfig{
 			Use:   name,
 			Short: name + " CLI",
@@ -123,15 +142,218 @@ func GenerateInitTemplate(name string) string {
 			},
 		},
 	}
+}
 
-	return config.ToYAML()
+// initExamples are the advanced commands GenerateInitTemplateWithExamples appends
+// to the init template as comments, in order.
+var initExamples = []struct {
+	description string
+	name        string
+	command     CommandConfig
+}{
+	{
+		description: "Aliases and a minimum number of arguments",
+		name:        "remove",
+		command: CommandConfig{
+			Use:     "remove <name>...",
+			Aliases: []string{"rm"},
+			Short:   "Remove items",
+			Args:    &ArgsConfig{Type: ArgsTypeMin, Min: 1},
+			RunFunc: "runRemove",
+		},
+	},
+	{
+		description: "A hidden command that accepts any arguments",
+		name:        "debug",
+		command: CommandConfig{
+			Use:     "debug",
+			Short:   "Print debugging information",
+			Hidden:  true,
+			Args:    &ArgsConfig{Type: ArgsTypeAny},
+			RunFunc: "runDebug",
+		},
+	},
+	{
+		description: "Nested commands inheriting a persistent flag, with max and range arguments",
+		name:        "server",
+		command: CommandConfig{
+			Use:   "server",
+			Short: "Manage the server",
+			Flags: []FlagConfig{
+				{
+					Name:         "port",
+					Shorthand:    "p",
+					Type:         FlagTypeInt,
+					DefaultValue: "8080",
+					Usage:        "Port to listen on",
+					Persistent:   true,
+				},
+			},
+			Commands: map[string]CommandConfig{
+				"start": {
+					Use:     "start [profile]",
+					Short:   "Start the server",
+					Args:    &ArgsConfig{Type: ArgsTypeMax, Max: 1},
+					RunFunc: "runServerStart",
+				},
+				"scale": {
+					Use:     "scale <min> [max]",
+					Short:   "Scale the server",
+					Args:    &ArgsConfig{Type: ArgsTypeRange, Min: 1, Max: 2},
+					RunFunc: "runServerScale",
+				},
+			},
+		},
+	},
+}
+
+// GenerateInitTemplateWithExamples generates the same template as GenerateInitTemplate
+// followed by commented-out commands showing advanced fields (aliases, hidden commands,
+// persistent flags, nested commands and argument checks). The examples are marshaled
+// from the config types like the template, so they always match the current schema.
+func GenerateInitTemplateWithExamples(name string) (string, error) {
+	return GenerateInitTemplateWithOptions(name, InitOptions{Examples: true})
+}
+
+// GenerateInitTemplateWithOptions generates a commands.yaml template for the given
+// tool name like GenerateInitTemplate, customized by opts.
+func GenerateInitTemplateWithOptions(name string, opts InitOptions) (string, error) {
+	config := initConfig(name)
+	config.License = opts.License
+	template, err := config.ToYAML()
+	if err != nil {
+		return "", err
+	}
+	if !opts.Examples {
+		return template, nil
+	}
+
+	var buf strings.Builder
+	buf.WriteString(template)
+	buf.WriteString("\n  # Advanced examples: uncomment and adapt as needed.\n")
+	for _, example := range initExamples {
+		commands, err := commandsToYAML(map[string]CommandConfig{example.name: example.command})
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString("\n  # " + example.description + ".\n")
+		for _, line := range strings.Split(strings.TrimSuffix(commands, "\n"), "\n") {
+			buf.WriteString("  # " + line + "\n")
+		}
+	}
+	return buf.String(), nil
 }
 
-// ToYAML converts ToolConfig to YAML string
-func (c *ToolConfig) ToYAML() string {
+// commandKeyOrder is the order command keys are written in by ToYAML. Keys not
+// listed keep their struct order after flags, and commands always comes last so
+// nested commands follow the fields of their parent.
+var commandKeyOrder = []string{"use", "short", "long", "args", "flags"}
+
+// ToYAML converts ToolConfig to a YAML string. Command fields are written in the
+// order they are usually authored in (use, short, long, args, flags, ..., commands)
+// rather than struct order.
+func (c *ToolConfig) ToYAML() (string, error) {
 	data, err := yaml.Marshal(c)
 	if err != nil {
-		return ""
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("failed to reorder config: %w", err)
+	}
+	doc = orderKeys(doc, nil)
+	for i, item := range doc {
+		switch item.Key {
+		case "root":
+			doc[i].Value = orderCommand(item.Value)
+		case "commands":
+			doc[i].Value = orderCommands(item.Value)
+		}
+	}
+
+	data, err = yaml.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return string(data), nil
+}
+
+// commandsToYAML marshals a commands mapping with the key order used by ToYAML.
+func commandsToYAML(cmds map[string]CommandConfig) (string, error) {
+	data, err := yaml.Marshal(cmds)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal commands: %w", err)
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("failed to reorder commands: %w", err)
+	}
+
+	data, err = yaml.Marshal(orderCommands(doc))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal commands: %w", err)
+	}
+	return string(data), nil
+}
+
+// orderCommands applies orderCommand to every command of a commands mapping.
+func orderCommands(value any) any {
+	cmds, ok := value.(yaml.MapSlice)
+	if !ok {
+		return value
+	}
+	for i := range cmds {
+		cmds[i].Value = orderCommand(cmds[i].Value)
+	}
+	return cmds
+}
+
+// orderCommand reorders the keys of a command mapping by commandKeyOrder.
+func orderCommand(value any) any {
+	cmd, ok := value.(yaml.MapSlice)
+	if !ok {
+		return value
+	}
+	cmd = orderKeys(cmd, commandKeyOrder)
+	for i, item := range cmd {
+		if item.Key == "commands" {
+			cmd[i].Value = orderCommands(item.Value)
+		}
+	}
+	return cmd
+}
+
+// orderKeys returns m with the keys in first moved to the front in that order and
+// the commands key moved to the end. Other keys keep their relative order.
+func orderKeys(m yaml.MapSlice, first []string) yaml.MapSlice {
+	ordered := make(yaml.MapSlice, 0, len(m))
+	for _, key := range first {
+		for _, item := range m {
+			if item.Key == key {
+				ordered = append(ordered, item)
+			}
+		}
+	}
+	var commands []yaml.MapItem
+	for _, item := range m {
+		switch {
+		case item.Key == "commands":
+			commands = append(commands, item)
+		case !containsKey(first, item.Key):
+			ordered = append(ordered, item)
+		}
+	}
+	return append(ordered, commands...)
+}
+
+// containsKey reports whether key is one of keys.
+func containsKey(keys []string, key any) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
 	}
-	return string(data)
+	return false
 }
@@ -0,0 +1,148 @@
+package cobrayaml
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func TestGenerateInitTemplate_Golden(t *testing.T) {
+	got, err := GenerateInitTemplate("test-app")
+	if err != nil {
+		t.Fatalf("GenerateInitTemplate() error = %v", err)
+	}
+
+	golden := filepath.Join("testdata", "init_template.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("GenerateInitTemplate() mismatch (run go test -update to accept)\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := NewCommandBuilderFromString(got); err != nil {
+		t.Errorf("init template should be a valid config, got error = %v", err)
+	}
+}
+
+func TestToYAML_FieldOrder(t *testing.T) {
+	config := ToolConfig{
+		Name: "order",
+		Root: CommandConfig{Use: "order", Short: "Order test"},
+		Commands: map[string]CommandConfig{
+			"db": {
+				Use:     "db",
+				Aliases: []string{"database"},
+				Short:   "Database",
+				Hidden:  true,
+				Commands: map[string]CommandConfig{
+					"migrate": {
+						Use:     "migrate",
+						Short:   "Migrate",
+						Long:    "Apply migrations",
+						RunFunc: "runMigrate",
+						Args:    &ArgsConfig{Type: ArgsTypeNone},
+						Flags:   []FlagConfig{{Name: "dry-run", Type: FlagTypeBool, Usage: "Dry run"}},
+					},
+				},
+			},
+		},
+		Functions: map[string]string{"runMigrate": "Apply migrations"},
+	}
+
+	got, err := config.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML() error = %v", err)
+	}
+
+	want := `name: order
+root:
+  use: order
+  short: Order test
+functions:
+  runMigrate: Apply migrations
+commands:
+  db:
+    use: db
+    short: Database
+    aliases:
+    - database
+    hidden: true
+    commands:
+      migrate:
+        use: migrate
+        short: Migrate
+        long: Apply migrations
+        args:
+          type: none
+        flags:
+        - name: dry-run
+          type: bool
+          usage: Dry run
+        run_func: runMigrate
+`
+	if got != want {
+		t.Errorf("ToYAML() =\n%s\nwant:\n%s", got, want)
+	}
+
+	cb, err := NewCommandBuilderFromString(got)
+	if err != nil {
+		t.Fatalf("ToYAML() output should load, got error = %v", err)
+	}
+	if !strings.Contains(cb.config.Commands["db"].Commands["migrate"].Long, "Apply migrations") {
+		t.Error("ToYAML() output should round-trip nested commands")
+	}
+}
+
+func TestGenerateInitTemplateWithExamples(t *testing.T) {
+	base, err := GenerateInitTemplate("test-app")
+	if err != nil {
+		t.Fatalf("GenerateInitTemplate() error = %v", err)
+	}
+	got, err := GenerateInitTemplateWithExamples("test-app")
+	if err != nil {
+		t.Fatalf("GenerateInitTemplateWithExamples() error = %v", err)
+	}
+	if !strings.HasPrefix(got, base) {
+		t.Errorf("GenerateInitTemplateWithExamples() should start with the plain template, got:\n%s", got)
+	}
+
+	// Uncommenting the examples should give a valid config
+	var uncommented []string
+	for _, line := range strings.Split(got, "\n") {
+		yamlLine, commented := strings.CutPrefix(line, "  # ")
+		if !commented {
+			uncommented = append(uncommented, line)
+			continue
+		}
+		if strings.HasPrefix(yamlLine, " ") || strings.HasSuffix(yamlLine, ":") {
+			uncommented = append(uncommented, "  "+yamlLine)
+		}
+	}
+	cb, err := NewCommandBuilderFromString(strings.Join(uncommented, "\n"))
+	if err != nil {
+		t.Fatalf("uncommented examples should be a valid config, got error = %v\n%s", err, strings.Join(uncommented, "\n"))
+	}
+
+	for _, example := range initExamples {
+		if _, ok := cb.config.Commands[example.name]; !ok {
+			t.Errorf("uncommented examples should define %s", example.name)
+		}
+	}
+	if !cb.config.Commands["debug"].Hidden {
+		t.Error("debug example should be hidden")
+	}
+	if got := cb.config.Commands["server"].Commands["scale"].Args; got == nil || got.Type != ArgsTypeRange {
+		t.Errorf("server scale example args = %+v, want range", got)
+	}
+}
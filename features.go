@@ -0,0 +1,192 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// Feature is an optional subsystem of cobrayaml that a host application can
+// disable with DisableFeatures, e.g. to keep a binary from writing files in the
+// user's directories or running executables from $PATH.
+type Feature string
+
+// Optional features of cobrayaml.
+const (
+	// FeatureCompletion is shell completion: the completion command and the
+	// completion and completion_func keys.
+	FeatureCompletion Feature = "completion"
+
+	// FeatureSettings is the tool's config file: the config_file, config_files,
+	// settings_schema and base_flags keys and the config commands.
+	FeatureSettings Feature = "settings"
+
+	// FeaturePlugins is running tool-<sub> executables from $PATH (discover_plugins).
+	FeaturePlugins Feature = "plugins"
+
+	// FeatureHistory is recording runs for history, rerun and undo (history, undo_func).
+	FeatureHistory Feature = "history"
+
+	// FeatureRecord is writing transcripts of runs with --record (record).
+	FeatureRecord Feature = "record"
+
+	// FeatureTUI is the interactive menu of the tui command (tui).
+	FeatureTUI Feature = "tui"
+
+	// FeatureAsk is suggesting commands for a request with the ask command
+	// (ask_command, ask_matcher).
+	FeatureAsk Feature = "ask"
+
+	// FeatureCache is serving command output from the user cache directory (cache).
+	FeatureCache Feature = "cache"
+
+	// FeatureNotify is sending notifications when a command finishes (notify).
+	FeatureNotify Feature = "notify"
+
+	// FeatureEvents is sending command lifecycle events to sinks (events).
+	FeatureEvents Feature = "events"
+
+	// FeatureBackground is running commands as background jobs with --detach (background).
+	FeatureBackground Feature = "background"
+)
+
+// SupportedFeatures lists all optional features.
+var SupportedFeatures = []Feature{
+	FeatureCompletion,
+	FeatureSettings,
+	FeaturePlugins,
+	FeatureHistory,
+	FeatureRecord,
+	FeatureTUI,
+	FeatureAsk,
+	FeatureCache,
+	FeatureNotify,
+	FeatureEvents,
+	FeatureBackground,
+}
+
+// disabledFeatures holds the features disabled by the host application. It is
+// package-level because ValidateConfig runs before a CommandBuilder exists.
+var (
+	disabledFeaturesMu sync.RWMutex
+	disabledFeatures   = make(map[Feature]bool)
+)
+
+// DisableFeatures disables optional features in this binary. Call it before
+// loading commands.yaml, typically from main or an init function:
+//
+//	cobrayaml.DisableFeatures(cobrayaml.FeaturePlugins, cobrayaml.FeatureHistory)
+//
+// ValidateConfig then rejects a commands.yaml that uses a disabled feature
+// instead of silently ignoring the keys, and the builder leaves out what the
+// feature adds on its own, such as the completion command.
+func DisableFeatures(features ...Feature) {
+	disabledFeaturesMu.Lock()
+	defer disabledFeaturesMu.Unlock()
+	for _, feature := range features {
+		disabledFeatures[feature] = true
+	}
+}
+
+// EnableFeatures enables features disabled with DisableFeatures again.
+func EnableFeatures(features ...Feature) {
+	disabledFeaturesMu.Lock()
+	defer disabledFeaturesMu.Unlock()
+	for _, feature := range features {
+		delete(disabledFeatures, feature)
+	}
+}
+
+// FeatureEnabled reports whether feature is enabled in this binary.
+func FeatureEnabled(feature Feature) bool {
+	disabledFeaturesMu.RLock()
+	defer disabledFeaturesMu.RUnlock()
+	return !disabledFeatures[feature]
+}
+
+// toolFeatureKeys returns the tool-level keys of config that use a feature.
+func toolFeatureKeys(config *ToolConfig) map[Feature][]string {
+	keys := make(map[Feature][]string)
+	use := func(feature Feature, key string, used bool) {
+		if used {
+			keys[feature] = append(keys[feature], key)
+		}
+	}
+	use(FeatureSettings, "config_file", config.ConfigFile != "")
+	use(FeatureSettings, "config_files", len(config.ConfigFiles) > 0)
+	use(FeatureSettings, "settings_schema", len(config.SettingsSchema) > 0)
+	use(FeatureSettings, "base_flags", config.BaseFlags.Enabled)
+	use(FeaturePlugins, "discover_plugins", config.DiscoverPlugins)
+	use(FeatureHistory, "history", config.History)
+	use(FeatureRecord, "record", config.Record)
+	use(FeatureTUI, "tui", config.TUI)
+	use(FeatureAsk, "ask_command", config.AskCommand)
+	use(FeatureAsk, "ask_matcher", config.AskMatcher != "")
+	use(FeatureEvents, "events", len(config.Events) > 0)
+	return keys
+}
+
+// commandFeatureKeys returns the keys of a command and its flags that use a feature.
+func commandFeatureKeys(config CommandConfig) map[Feature][]string {
+	keys := make(map[Feature][]string)
+	use := func(feature Feature, key string, used bool) {
+		if used {
+			keys[feature] = append(keys[feature], key)
+		}
+	}
+	use(FeatureCompletion, "completion_func", config.CompletionFunc != "")
+	for _, flag := range config.Flags {
+		use(FeatureCompletion, fmt.Sprintf("flag %q: completion", flag.Name), len(flag.Completion) > 0)
+		use(FeatureCompletion, fmt.Sprintf("flag %q: completion_func", flag.Name), flag.CompletionFunc != "")
+	}
+	use(FeatureHistory, "undo_func", config.UndoFunc != "")
+	use(FeatureCache, "cache", config.Cache != nil)
+	use(FeatureNotify, "notify", config.Notify != nil)
+	use(FeatureBackground, "background", config.Background != "")
+	return keys
+}
+
+// validateFeatures reports the keys of config that use a feature disabled with
+// DisableFeatures.
+func validateFeatures(config *ToolConfig, ve *ValidationError) {
+	disabledFeaturesMu.RLock()
+	defer disabledFeaturesMu.RUnlock()
+	if len(disabledFeatures) == 0 {
+		return
+	}
+
+	report := func(prefix string, keys map[Feature][]string) {
+		for _, feature := range SupportedFeatures {
+			if !disabledFeatures[feature] {
+				continue
+			}
+			for _, key := range keys[feature] {
+				ve.addError("%s%s needs feature %q, which is disabled in this binary", prefix, key, feature)
+			}
+		}
+	}
+	report("tool config: ", toolFeatureKeys(config))
+
+	var walk func(cmds map[string]CommandConfig, prefix string)
+	walk = func(cmds map[string]CommandConfig, prefix string) {
+		for _, name := range sortedCommandNames(cmds) {
+			path := prefix + name
+			report(fmt.Sprintf("command %q: ", path), commandFeatureKeys(cmds[name]))
+			walk(cmds[name].Commands, path+"/")
+		}
+	}
+	report(`command "root": `, commandFeatureKeys(config.Root))
+	walk(config.Commands, "")
+	for _, surface := range sortedKeys(config.Surfaces) {
+		walk(config.Surfaces[surface].Commands, "surfaces/"+surface+"/")
+	}
+}
+
+// disableCompletionCommand leaves out cobra's default completion command when
+// shell completion is disabled.
+func disableCompletionCommand(rootCmd *cobra.Command) {
+	if !FeatureEnabled(FeatureCompletion) {
+		rootCmd.CompletionOptions.DisableDefaultCmd = true
+	}
+}
@@ -0,0 +1,86 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const featuresYAML = `
+name: features
+history: true
+discover_plugins: true
+root:
+  use: features
+  short: Features test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    undo_func: undoDeploy
+    cache:
+      ttl: 5m
+    flags:
+      - name: env
+        type: string
+        usage: Environment
+        completion: [dev, prod]
+`
+
+func TestValidateConfig_DisabledFeatures(t *testing.T) {
+	if _, err := NewCommandBuilderFromString(featuresYAML); err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	DisableFeatures(FeatureHistory, FeatureCompletion, FeatureTUI)
+	defer EnableFeatures(FeatureHistory, FeatureCompletion, FeatureTUI)
+	if FeatureEnabled(FeatureHistory) || !FeatureEnabled(FeaturePlugins) {
+		t.Errorf("FeatureEnabled() does not reflect DisableFeatures")
+	}
+
+	_, err := NewCommandBuilderFromString(featuresYAML)
+	if err == nil {
+		t.Fatal("NewCommandBuilderFromString() error = nil, want disabled features")
+	}
+	for _, want := range []string{
+		`tool config: history needs feature "history", which is disabled in this binary`,
+		`command "deploy": flag "env": completion needs feature "completion", which is disabled in this binary`,
+		`command "deploy": undo_func needs feature "history", which is disabled in this binary`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+	for _, unwanted := range []string{"plugins", "cache"} {
+		if strings.Contains(err.Error(), `feature "`+unwanted+`"`) {
+			t.Errorf("error = %v, want no error for %s", err, unwanted)
+		}
+	}
+}
+
+func TestBuildRootCommand_CompletionDisabled(t *testing.T) {
+	defer EnableFeatures(FeatureCompletion)
+	noop := func(cmd *cobra.Command, args []string) error { return nil }
+	for _, disabled := range []bool{false, true} {
+		if disabled {
+			DisableFeatures(FeatureCompletion)
+		}
+		cb, err := NewCommandBuilderFromString(strings.Replace(featuresYAML, "        completion: [dev, prod]\n", "", 1))
+		if err != nil {
+			t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+		}
+		cb.RegisterFunction("runDeploy", noop)
+		cb.RegisterFunction("undoDeploy", noop)
+		rootCmd, err := cb.BuildRootCommand()
+		if err != nil {
+			t.Fatalf("BuildRootCommand() error = %v", err)
+		}
+
+		rootCmd.InitDefaultCompletionCmd()
+		if found := findSubcommand(rootCmd, "completion") != nil; found == disabled {
+			t.Errorf("completion disabled = %t: completion command found = %t", disabled, found)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cobrayaml
+
+import "reflect"
+
+// FieldInfo describes one YAML field of a config struct.
+type FieldInfo struct {
+	// Struct is the Go name of the config struct, e.g. CommandConfig.
+	Struct string `json:"struct"`
+	// Key is the YAML key of the field.
+	Key string `json:"key"`
+	// Type is the Go type of the field, e.g. []FlagConfig.
+	Type string `json:"type"`
+	// Required reports whether the key must be present.
+	Required bool `json:"required"`
+	// Description is the one-line description used in the YAML reference.
+	Description string `json:"description"`
+	// Since is the schema version that introduced the field.
+	Since int `json:"since"`
+}
+
+// catalogStructs lists the config structs in the field catalog, in order.
+var catalogStructs = []reflect.Type{
+	reflect.TypeOf(ToolConfig{}),
+	reflect.TypeOf(CommandConfig{}),
+	reflect.TypeOf(ArgsConfig{}),
+	reflect.TypeOf(FlagConfig{}),
+	reflect.TypeOf(DerivedConfig{}),
+	reflect.TypeOf(EnvConfig{}),
+	reflect.TypeOf(CacheConfig{}),
+	reflect.TypeOf(ConcurrencyConfig{}),
+	reflect.TypeOf(DefaultsConfig{}),
+	reflect.TypeOf(NotifyConfig{}),
+	reflect.TypeOf(EventSinkConfig{}),
+	reflect.TypeOf(ErrorConfig{}),
+	reflect.TypeOf(HelpVariantConfig{}),
+	reflect.TypeOf(LicenseConfig{}),
+	reflect.TypeOf(SurfaceConfig{}),
+	reflect.TypeOf(BaseFlagsConfig{}),
+	reflect.TypeOf(BaseFlagConfig{}),
+	reflect.TypeOf(SettingConfig{}),
+}
+
+// fieldSince records the schema version of fields added after schema version 1,
+// keyed by "Struct.key". Add an entry when a field is introduced together with a
+// schema version bump.
+var fieldSince = map[string]int{}
+
+// FieldCatalog returns every YAML field of the config structs, in struct order.
+// It is the source of the YAML reference (`cobrayaml reference`) and is meant
+// for tooling such as editor plugins that complete or document commands.yaml.
+func FieldCatalog() []FieldInfo {
+	var fields []FieldInfo
+	for _, t := range catalogStructs {
+		for _, f := range extractFieldDocs(t) {
+			if t.Name() == "ToolConfig" && f.YAMLKey == "functions" {
+				continue // internal field
+			}
+			since, ok := fieldSince[t.Name()+"."+f.YAMLKey]
+			if !ok {
+				since = 1
+			}
+			fields = append(fields, FieldInfo{
+				Struct:      t.Name(),
+				Key:         f.YAMLKey,
+				Type:        f.GoType,
+				Required:    f.Required,
+				Description: fieldDescription(t.Name(), f.YAMLKey),
+				Since:       since,
+			})
+		}
+	}
+	return fields
+}
+
+// catalogFields returns the fields of the named struct from FieldCatalog.
+func catalogFields(structName string) []FieldInfo {
+	var fields []FieldInfo
+	for _, f := range FieldCatalog() {
+		if f.Struct == structName {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
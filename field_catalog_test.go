@@ -0,0 +1,57 @@
+package cobrayaml
+
+import "testing"
+
+func TestFieldCatalog(t *testing.T) {
+	fields := FieldCatalog()
+
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		id := f.Struct + "." + f.Key
+		if seen[id] {
+			t.Errorf("FieldCatalog() lists %s twice", id)
+		}
+		seen[id] = true
+
+		if f.Description == "" {
+			t.Errorf("%s has no description", id)
+		}
+		if f.Since < 1 || f.Since > SchemaVersion() {
+			t.Errorf("%s since = %d, want 1-%d", id, f.Since, SchemaVersion())
+		}
+	}
+
+	for _, s := range catalogStructs {
+		if len(catalogFields(s.Name())) == 0 {
+			t.Errorf("FieldCatalog() has no fields for %s", s.Name())
+		}
+	}
+	if seen["ToolConfig.functions"] {
+		t.Error("FieldCatalog() should not list the internal functions field")
+	}
+
+	for _, want := range []struct {
+		id       string
+		typ      string
+		required bool
+	}{
+		{"CommandConfig.use", "string", true},
+		{"CommandConfig.flags", "[]FlagConfig", false},
+		{"ArgsConfig.type", "string", true},
+		{"FlagConfig.name", "string", true},
+	} {
+		var got *FieldInfo
+		for i := range fields {
+			if fields[i].Struct+"."+fields[i].Key == want.id {
+				got = &fields[i]
+			}
+		}
+		if got == nil {
+			t.Errorf("FieldCatalog() is missing %s", want.id)
+			continue
+		}
+		if got.Type != want.typ || got.Required != want.required {
+			t.Errorf("%s = %+v, want type %s, required %v", want.id, *got, want.typ, want.required)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// pathValue is a pflag.Value for file and dir flags that validates the path when it is set.
+// A missing path is accepted when exists is false or the path is created by createMissing.
+type pathValue struct {
+	value         string
+	dir           bool
+	exists        bool
+	createMissing bool
+	extensions    []string
+}
+
+// newPathValue creates a pathValue from a file or dir flag configuration.
+func newPathValue(flag FlagConfig) *pathValue {
+	return &pathValue{
+		value:         flag.DefaultValue,
+		dir:           flag.Type == FlagTypeDir,
+		exists:        flag.MustExist == nil || *flag.MustExist,
+		createMissing: flag.CreateMissing,
+		extensions:    normalizeExtensions(flag.Extensions),
+	}
+}
+
+// String returns the current path.
+func (p *pathValue) String() string {
+	return p.value
+}
+
+// Set validates and stores a path.
+func (p *pathValue) Set(s string) error {
+	if !p.dir && len(p.extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(s))
+		if !slices.Contains(p.extensions, ext) {
+			return fmt.Errorf("file %q must have one of the extensions: %s", s, strings.Join(p.extensions, ", "))
+		}
+	}
+
+	info, err := os.Stat(s)
+	switch {
+	case err == nil:
+		if p.dir && !info.IsDir() {
+			return fmt.Errorf("%q is not a directory", s)
+		}
+		if !p.dir && info.IsDir() {
+			return fmt.Errorf("%q is a directory, not a file", s)
+		}
+	case os.IsNotExist(err) && p.createMissing:
+		// Created by createMissingPaths once the command runs
+	case os.IsNotExist(err) && p.exists:
+		if p.dir {
+			return fmt.Errorf("directory %q does not exist", s)
+		}
+		return fmt.Errorf("file %q does not exist", s)
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	p.value = s
+	return nil
+}
+
+// create creates a missing file or directory, including parent directories.
+func (p *pathValue) create(s string) error {
+	if p.dir {
+		return os.MkdirAll(s, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(s), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Type returns "string" so the value can be read with GetString.
+func (p *pathValue) Type() string {
+	return "string"
+}
+
+// createMissingPaths creates the missing files and directories of the
+// create_missing flags set on cmd. It runs in PreRunE rather than when the flag
+// is parsed, so completion and --help do not touch the file system.
+func createMissingPaths(cmd *cobra.Command) error {
+	var firstErr error
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		value, ok := flag.Value.(*pathValue)
+		if firstErr != nil || !ok || !value.createMissing || value.value == "" {
+			return
+		}
+		if _, err := os.Stat(value.value); !os.IsNotExist(err) {
+			return
+		}
+		if err := value.create(value.value); err != nil {
+			firstErr = fmt.Errorf("flag --%s: %w", flag.Name, err)
+		}
+	})
+	return firstErr
+}
+
+// normalizeExtensions lowercases extensions and ensures they start with a dot.
+func normalizeExtensions(extensions []string) []string {
+	normalized := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+	return normalized
+}
+
+// urlValue is a pflag.Value for url flags that only accepts absolute URLs.
+type urlValue struct {
+	url *url.URL
+}
+
+// String returns the current URL, or an empty string if unset.
+func (u *urlValue) String() string {
+	if u.url == nil {
+		return ""
+	}
+	return u.url.String()
+}
+
+// Set parses and stores an absolute URL.
+func (u *urlValue) Set(s string) error {
+	parsed, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", s)
+	}
+	u.url = parsed
+	return nil
+}
+
+// Type returns the flag type name shown in help.
+func (u *urlValue) Type() string {
+	return FlagTypeURL
+}
+
+// byteSizeValue is a pflag.Value for bytesize flags such as "512", "10MB" or "1.5GiB".
+type byteSizeValue int64
+
+// byteSizeUnits maps unit suffixes to their multipliers.
+// Decimal units (KB, MB, ...) use powers of 1000 and binary units (KiB, MiB, ...) powers of 1024.
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1e6,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1e9,
+	"gb":  1e9,
+	"gib": 1 << 30,
+	"t":   1e12,
+	"tb":  1e12,
+	"tib": 1 << 40,
+}
+
+// ParseByteSize parses a human readable size such as "512", "10MB" or "1.5GiB" into bytes.
+func ParseByteSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(trimmed)
+	}
+
+	number, unit := trimmed[:i], strings.ToLower(strings.TrimSpace(trimmed[i:]))
+	multiplier, ok := byteSizeUnits[unit]
+	if number == "" || !ok {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	size := n * multiplier
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(size), nil
+}
+
+// String returns the size in bytes.
+func (b *byteSizeValue) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+// Set parses and stores a size.
+func (b *byteSizeValue) Set(s string) error {
+	n, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = byteSizeValue(n)
+	return nil
+}
+
+// Type returns the flag type name shown in help.
+func (b *byteSizeValue) Type() string {
+	return FlagTypeByteSize
+}
+
+// GetURL returns the value of a url flag.
+// It returns nil without an error if the flag was not set and has no default.
+func GetURL(fs *pflag.FlagSet, name string) (*url.URL, error) {
+	flag := fs.Lookup(name)
+	if flag == nil {
+		return nil, fmt.Errorf("flag accessed but not defined: %s", name)
+	}
+	value, ok := flag.Value.(*urlValue)
+	if !ok {
+		return nil, fmt.Errorf("trying to get %s value of flag of type %s", FlagTypeURL, flag.Value.Type())
+	}
+	return value.url, nil
+}
+
+// GetByteSize returns the value of a bytesize flag in bytes.
+func GetByteSize(fs *pflag.FlagSet, name string) (int64, error) {
+	flag := fs.Lookup(name)
+	if flag == nil {
+		return 0, fmt.Errorf("flag accessed but not defined: %s", name)
+	}
+	value, ok := flag.Value.(*byteSizeValue)
+	if !ok {
+		return 0, fmt.Errorf("trying to get %s value of flag of type %s", FlagTypeByteSize, flag.Value.Type())
+	}
+	return int64(*value), nil
+}
+
+// timeLayouts maps named layouts accepted in the "layout" field to Go time layouts.
+// Any other value is used as a Go time layout as-is.
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// timeNow returns the current time; replaced in tests.
+var timeNow = time.Now
+
+// resolveTimeLayout returns the Go layout for a layout name, defaulting to RFC3339.
+func resolveTimeLayout(layout string) string {
+	if layout == "" {
+		return time.RFC3339
+	}
+	if named, ok := timeLayouts[layout]; ok {
+		return named
+	}
+	return layout
+}
+
+// timeValue is a pflag.Value for time flags.
+type timeValue struct {
+	time     time.Time
+	layout   string
+	relative bool
+}
+
+// newTimeValue creates a timeValue from a time flag configuration.
+func newTimeValue(flag FlagConfig) *timeValue {
+	return &timeValue{
+		layout:   resolveTimeLayout(flag.Layout),
+		relative: flag.Relative,
+	}
+}
+
+// String returns the time formatted with the flag's layout, or an empty string if unset.
+func (t *timeValue) String() string {
+	if t.time.IsZero() {
+		return ""
+	}
+	return t.time.Format(t.layout)
+}
+
+// Set parses a time with the flag's layout. When relative forms are enabled it also
+// accepts "now", "today", "yesterday", "tomorrow" and signed offsets such as "-24h" or "+7d".
+func (t *timeValue) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if t.relative {
+		if parsed, ok := parseRelativeTime(s, timeNow()); ok {
+			t.time = parsed
+			return nil
+		}
+	}
+
+	parsed, err := time.Parse(t.layout, s)
+	if err != nil {
+		return fmt.Errorf("invalid time %q (expected layout %q)", s, t.layout)
+	}
+	t.time = parsed
+	return nil
+}
+
+// Type returns the flag type name shown in help.
+func (t *timeValue) Type() string {
+	return FlagTypeTime
+}
+
+// parseRelativeTime parses relative time expressions relative to now.
+func parseRelativeTime(s string, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(s) {
+	case "now":
+		return now, true
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	}
+
+	if !strings.HasPrefix(s, "-") && !strings.HasPrefix(s, "+") {
+		return time.Time{}, false
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return now.AddDate(0, 0, n), true
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return now.Add(d), true
+}
+
+// GetTime returns the value of a time flag.
+// It returns the zero time without an error if the flag was not set and has no default.
+func GetTime(fs *pflag.FlagSet, name string) (time.Time, error) {
+	flag := fs.Lookup(name)
+	if flag == nil {
+		return time.Time{}, fmt.Errorf("flag accessed but not defined: %s", name)
+	}
+	value, ok := flag.Value.(*timeValue)
+	if !ok {
+		return time.Time{}, fmt.Errorf("trying to get %s value of flag of type %s", FlagTypeTime, flag.Value.Type())
+	}
+	return value.time, nil
+}
+
+// enumValue is a pflag.Value for string flags restricted to a set of allowed values.
+type enumValue struct {
+	value   string
+	allowed []string
+}
+
+// String returns the current value.
+func (e *enumValue) String() string {
+	return e.value
+}
+
+// Set stores a value if it is one of the allowed values.
+func (e *enumValue) Set(s string) error {
+	if !slices.Contains(e.allowed, s) {
+		return fmt.Errorf("must be one of: %s", strings.Join(e.allowed, ", "))
+	}
+	e.value = s
+	return nil
+}
+
+// Type returns "string" so the value can be read with GetString.
+func (e *enumValue) Type() string {
+	return "string"
+}
@@ -0,0 +1,665 @@
+package cobrayaml
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPathValue_Set(t *testing.T) {
+	tmpDir := t.TempDir()
+	existingFile := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(existingFile, []byte("a: 1"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	mustNotExist := false
+	tests := []struct {
+		name    string
+		flag    FlagConfig
+		value   string
+		wantErr string
+	}{
+		{
+			name:  "existing file",
+			flag:  FlagConfig{Type: FlagTypeFile, Extensions: []string{".yaml", "json"}},
+			value: existingFile,
+		},
+		{
+			name:    "missing file",
+			flag:    FlagConfig{Type: FlagTypeFile},
+			value:   filepath.Join(tmpDir, "missing.yaml"),
+			wantErr: "does not exist",
+		},
+		{
+			name:  "missing file allowed",
+			flag:  FlagConfig{Type: FlagTypeFile, MustExist: &mustNotExist},
+			value: filepath.Join(tmpDir, "missing.yaml"),
+		},
+		{
+			name:    "wrong extension",
+			flag:    FlagConfig{Type: FlagTypeFile, Extensions: []string{"json"}},
+			value:   existingFile,
+			wantErr: "must have one of the extensions: .json",
+		},
+		{
+			name:  "extension is case insensitive",
+			flag:  FlagConfig{Type: FlagTypeFile, Extensions: []string{".YAML"}},
+			value: existingFile,
+		},
+		{
+			name:    "file given a directory",
+			flag:    FlagConfig{Type: FlagTypeFile},
+			value:   tmpDir,
+			wantErr: "is a directory",
+		},
+		{
+			name:    "dir given a file",
+			flag:    FlagConfig{Type: FlagTypeDir},
+			value:   existingFile,
+			wantErr: "is not a directory",
+		},
+		{
+			name:    "missing dir",
+			flag:    FlagConfig{Type: FlagTypeDir},
+			value:   filepath.Join(tmpDir, "missing"),
+			wantErr: "directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newPathValue(tt.flag)
+			err := v.Set(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Set() error = %v", err)
+				}
+				if v.String() != tt.value {
+					t.Errorf("String() = %q, want %q", v.String(), tt.value)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Set() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_CreateMissing(t *testing.T) {
+	yamlContent := `
+name: path-test
+root:
+  use: path-test
+  short: Path test
+  run_func: runRoot
+  flags:
+    - name: out-dir
+      type: dir
+      usage: Output directory
+      create_missing: true
+    - name: log
+      type: file
+      usage: Log file
+      create_missing: true
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error { return nil })
+
+	tmpDir := t.TempDir()
+	dirPath := filepath.Join(tmpDir, "a", "b")
+	filePath := filepath.Join(tmpDir, "c", "out.log")
+	for _, tt := range []struct {
+		name       string
+		args       []string
+		wantCreate bool
+	}{
+		{name: "help", args: []string{"--out-dir", dirPath, "--log", filePath, "--help"}},
+		{name: "completion", args: []string{cobra.ShellCompRequestCmd, "--out-dir", dirPath, "--log", filePath, ""}},
+		{name: "run", args: []string{"--out-dir", dirPath, "--log", filePath}, wantCreate: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SetOut(io.Discard)
+			rootCmd.SetArgs(tt.args)
+			if err := rootCmd.Execute(); err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			dirInfo, dirErr := os.Stat(dirPath)
+			fileInfo, fileErr := os.Stat(filePath)
+			if created := dirErr == nil && dirInfo.IsDir(); created != tt.wantCreate {
+				t.Errorf("directory created = %v, want %v", created, tt.wantCreate)
+			}
+			if created := fileErr == nil && !fileInfo.IsDir(); created != tt.wantCreate {
+				t.Errorf("file created = %v, want %v", created, tt.wantCreate)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_PathFlags(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "input.json")
+	if err := os.WriteFile(input, []byte("{}"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	yamlContent := `
+name: path-test
+root:
+  use: path-test
+  short: Path test
+  run_func: runRoot
+  flags:
+    - name: input
+      shorthand: i
+      type: file
+      usage: Input file
+      extensions: [.json, .yaml]
+    - name: out-dir
+      type: dir
+      default: out
+      usage: Output directory
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var gotInput, gotOutDir string
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		gotInput, _ = cmd.Flags().GetString("input")
+		gotOutDir, _ = cmd.Flags().GetString("out-dir")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	inputFlag := rootCmd.Flags().Lookup("input")
+	if exts := inputFlag.Annotations[cobra.BashCompFilenameExt]; len(exts) != 2 || exts[0] != "json" {
+		t.Errorf("input completion extensions = %v, want [json yaml]", exts)
+	}
+	if _, ok := rootCmd.Flags().Lookup("out-dir").Annotations[cobra.BashCompSubdirsInDir]; !ok {
+		t.Error("out-dir should complete directory names")
+	}
+
+	rootCmd.SetArgs([]string{"-i", input})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if gotInput != input {
+		t.Errorf("input = %q, want %q", gotInput, input)
+	}
+	if gotOutDir != "out" {
+		t.Errorf("out-dir = %q, want default %q", gotOutDir, "out")
+	}
+
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{"-i", filepath.Join(tmpDir, "missing.json")})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Execute() error = %v, want does not exist error", err)
+	}
+}
+
+func TestValidateConfig_PathFlagOptions(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Flags: []FlagConfig{
+				{Name: "name", Type: FlagTypeString, Usage: "Name", MustExist: new(bool)},
+				{Name: "out", Type: FlagTypeDir, Usage: "Out", Extensions: []string{".txt"}},
+				{Name: "in", Type: FlagTypeFile, Usage: "In", Extensions: []string{" "}},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected errors")
+	}
+	for _, want := range []string{
+		`flag "name": must_exist and create_missing are only supported`,
+		`flag "out": extensions are only supported for file flags`,
+		`flag "in": extensions must not be empty`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "512", want: 512},
+		{in: "10B", want: 10},
+		{in: "1KB", want: 1000},
+		{in: "1KiB", want: 1024},
+		{in: "10MiB", want: 10 << 20},
+		{in: "1.5GB", want: 1500000000},
+		{in: "2 gib", want: 2 << 30},
+		{in: "1TiB", want: 1 << 40},
+		{in: "", wantErr: true},
+		{in: "MB", wantErr: true},
+		{in: "10XB", wantErr: true},
+		{in: "1.2.3MB", wantErr: true},
+		{in: "10000000TB", wantErr: true},
+		{in: "9999999999TiB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCommandBuilder_SemanticFlagTypes(t *testing.T) {
+	yamlContent := `
+name: semantic-test
+root:
+  use: semantic-test
+  short: Semantic test
+  run_func: runRoot
+  flags:
+    - name: endpoint
+      type: url
+      default: https://api.example.com
+      usage: API endpoint
+    - name: bind
+      type: ip
+      usage: Bind address
+    - name: subnet
+      type: cidr
+      default: 10.0.0.0/8
+      usage: Subnet
+    - name: timeout
+      type: duration
+      default: 30s
+      usage: Timeout
+    - name: max-size
+      type: bytesize
+      default: 1KiB
+      usage: Maximum size
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var (
+		endpoint string
+		bind     string
+		subnet   string
+		timeout  time.Duration
+		maxSize  int64
+	)
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		u, err := GetURL(cmd.Flags(), "endpoint")
+		if err != nil {
+			return err
+		}
+		endpoint = u.Host
+		ip, _ := cmd.Flags().GetIP("bind")
+		bind = ip.String()
+		n, _ := cmd.Flags().GetIPNet("subnet")
+		subnet = n.String()
+		timeout, _ = cmd.Flags().GetDuration("timeout")
+		maxSize, err = GetByteSize(cmd.Flags(), "max-size")
+		return err
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"--bind", "192.168.0.1", "--timeout", "2m", "--max-size", "10MB"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if endpoint != "api.example.com" {
+		t.Errorf("endpoint host = %q, want %q", endpoint, "api.example.com")
+	}
+	if bind != "192.168.0.1" {
+		t.Errorf("bind = %q, want %q", bind, "192.168.0.1")
+	}
+	if subnet != "10.0.0.0/8" {
+		t.Errorf("subnet = %q, want %q", subnet, "10.0.0.0/8")
+	}
+	if timeout != 2*time.Minute {
+		t.Errorf("timeout = %v, want %v", timeout, 2*time.Minute)
+	}
+	if maxSize != 10000000 {
+		t.Errorf("max-size = %d, want %d", maxSize, 10000000)
+	}
+
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	for _, args := range [][]string{
+		{"--endpoint", "not-a-url"},
+		{"--bind", "300.1.1.1"},
+		{"--subnet", "10.0.0.0"},
+		{"--timeout", "soon"},
+		{"--max-size", "huge"},
+	} {
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err == nil {
+			t.Errorf("Execute(%v) expected parse error", args)
+		}
+	}
+}
+
+func TestCommandBuilder_SemanticFlagTypesInvalidDefault(t *testing.T) {
+	for _, flagType := range []string{FlagTypeURL, FlagTypeIP, FlagTypeCIDR, FlagTypeDuration, FlagTypeByteSize, FlagTypeTime} {
+		t.Run(flagType, func(t *testing.T) {
+			yamlContent := `
+name: default-test
+root:
+  use: default-test
+  short: Default test
+  flags:
+    - name: value
+      type: ` + flagType + `
+      default: "???"
+      usage: Value
+`
+			cb, err := NewCommandBuilderFromString(yamlContent)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "invalid "+flagType+" default value") {
+				t.Errorf("BuildRootCommand() error = %v, want invalid default error", err)
+			}
+		})
+	}
+}
+
+func TestGetURL_WrongType(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("name", "", "")
+
+	if _, err := GetURL(cmd.Flags(), "name"); err == nil {
+		t.Error("GetURL() expected error for string flag")
+	}
+	if _, err := GetByteSize(cmd.Flags(), "name"); err == nil {
+		t.Error("GetByteSize() expected error for string flag")
+	}
+	if _, err := GetURL(cmd.Flags(), "missing"); err == nil {
+		t.Error("GetURL() expected error for undefined flag")
+	}
+}
+
+func TestTimeValue_Set(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	origNow := timeNow
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = origNow }()
+
+	tests := []struct {
+		name    string
+		flag    FlagConfig
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "default RFC3339",
+			flag: FlagConfig{Type: FlagTypeTime},
+			in:   "2024-01-02T03:04:05Z",
+			want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name: "named layout",
+			flag: FlagConfig{Type: FlagTypeTime, Layout: "DateOnly"},
+			in:   "2024-01-02",
+			want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "custom layout",
+			flag: FlagConfig{Type: FlagTypeTime, Layout: "02/01/2006"},
+			in:   "02/01/2024",
+			want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "wrong layout",
+			flag:    FlagConfig{Type: FlagTypeTime, Layout: "DateOnly"},
+			in:      "2024-01-02T03:04:05Z",
+			wantErr: true,
+		},
+		{
+			name:    "relative disabled",
+			flag:    FlagConfig{Type: FlagTypeTime},
+			in:      "yesterday",
+			wantErr: true,
+		},
+		{
+			name: "yesterday",
+			flag: FlagConfig{Type: FlagTypeTime, Relative: true},
+			in:   "yesterday",
+			want: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "negative duration",
+			flag: FlagConfig{Type: FlagTypeTime, Relative: true},
+			in:   "-24h",
+			want: now.Add(-24 * time.Hour),
+		},
+		{
+			name: "days",
+			flag: FlagConfig{Type: FlagTypeTime, Relative: true},
+			in:   "+7d",
+			want: now.AddDate(0, 0, 7),
+		},
+		{
+			name: "absolute still accepted",
+			flag: FlagConfig{Type: FlagTypeTime, Relative: true},
+			in:   "2024-01-02T03:04:05Z",
+			want: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTimeValue(tt.flag)
+			err := v.Set(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !v.time.Equal(tt.want) {
+				t.Errorf("Set(%q) = %v, want %v", tt.in, v.time, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_TimeFlag(t *testing.T) {
+	yamlContent := `
+name: time-test
+root:
+  use: time-test
+  short: Time test
+  run_func: runRoot
+  flags:
+    - name: since
+      type: time
+      layout: DateOnly
+      default: "2024-01-01"
+      usage: Start date
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var since time.Time
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		since, err = GetTime(cmd.Flags(), "since")
+		return err
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	if def := rootCmd.Flags().Lookup("since").DefValue; def != "2024-01-01" {
+		t.Errorf("DefValue = %q, want %q", def, "2024-01-01")
+	}
+
+	rootCmd.SetArgs([]string{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !since.Equal(want) {
+		t.Errorf("since = %v, want default %v", since, want)
+	}
+
+	rootCmd.SetArgs([]string{"--since", "2024-02-03"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if want := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC); !since.Equal(want) {
+		t.Errorf("since = %v, want %v", since, want)
+	}
+}
+
+func TestValidateConfig_TimeOptions(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Flags: []FlagConfig{
+				{Name: "name", Type: FlagTypeString, Usage: "Name", Layout: "DateOnly"},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "layout and relative are only supported for time flags") {
+		t.Errorf("ValidateConfig() error = %v, want layout error", err)
+	}
+}
+
+func TestCommandBuilder_AllowedValues(t *testing.T) {
+	yamlContent := `
+name: enum-test
+root:
+  use: enum-test
+  short: Enum test
+  run_func: runRoot
+  flags:
+    - name: output
+      shorthand: o
+      type: string
+      default: table
+      usage: Output format
+      allowed_values: [json, yaml, table]
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	var output string
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		output, _ = cmd.Flags().GetString("output")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	flag := rootCmd.Flags().Lookup("output")
+	if !strings.Contains(flag.Usage, "(one of: json, yaml, table)") {
+		t.Errorf("usage = %q, should list the allowed values", flag.Usage)
+	}
+	completion, ok := rootCmd.GetFlagCompletionFunc("output")
+	if !ok {
+		t.Fatal("output flag should have a completion function")
+	}
+	if values, _ := completion(rootCmd, nil, ""); len(values) != 3 {
+		t.Errorf("completion values = %v, want 3 values", values)
+	}
+
+	rootCmd.SetArgs([]string{})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if output != "table" {
+		t.Errorf("output = %q, want default %q", output, "table")
+	}
+
+	rootCmd.SetArgs([]string{"-o", "yaml"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if output != "yaml" {
+		t.Errorf("output = %q, want %q", output, "yaml")
+	}
+
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{"-o", "xml"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "must be one of: json, yaml, table") {
+		t.Errorf("Execute() error = %v, want allowed values error", err)
+	}
+}
+
+func TestValidateConfig_AllowedValues(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Flags: []FlagConfig{
+				{Name: "count", Type: FlagTypeInt, Usage: "Count", AllowedValues: []string{"1"}},
+				{Name: "output", Type: FlagTypeString, Usage: "Output", AllowedValues: []string{"json", "json", ""}, DefaultValue: "xml"},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected errors")
+	}
+	for _, want := range []string{
+		`flag "count": allowed_values are only supported for string flags`,
+		`flag "output": duplicate allowed value "json"`,
+		`flag "output": allowed_values must not be empty`,
+		`flag "output": default "xml" is not one of the allowed values`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package cobrayaml
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// FlagSource is where the effective value of a flag came from.
+type FlagSource string
+
+// Flag sources.
+const (
+	// FlagSourceCommandLine is a value set on the command line.
+	FlagSourceCommandLine FlagSource = "command_line"
+	// FlagSourceDefault is the default value of the flag.
+	FlagSourceDefault FlagSource = "default"
+)
+
+// TypedValue is the effective value of a flag, as returned by FlagValues.
+type TypedValue struct {
+	Type     string     `json:"type"`               // pflag type name, e.g. "string", "duration" or a custom type
+	Value    any        `json:"value"`              // value as the getter of its type returns it (see Bind); "<redacted>" for sensitive flags
+	Source   FlagSource `json:"source"`             // FlagSourceCommandLine or FlagSourceDefault
+	Redacted bool       `json:"redacted,omitempty"` // the flag is sensitive and Value does not hold its value
+}
+
+// FlagValues returns the effective values of the flags of cmd, keyed by flag
+// name: the declared flags, inherited persistent flags and flags added by
+// cobrayaml features such as --help-format, but not --help. Values of
+// sensitive flags are redacted, so middleware such as tracing, audit logs and
+// telemetry can record the result as it is.
+func FlagValues(cmd *cobra.Command) map[string]TypedValue {
+	values := make(map[string]TypedValue)
+	visit := func(flag *pflag.Flag) {
+		if flag.Name == "help" {
+			return
+		}
+		v := TypedValue{Type: flag.Value.Type(), Source: FlagSourceDefault}
+		if flag.Changed {
+			v.Source = FlagSourceCommandLine
+		}
+		if sensitive(flag) {
+			v.Value, v.Redacted = historyRedacted, true
+		} else if value, err := flagValue(cmd.Flags(), flag); err == nil {
+			v.Value = value
+		} else {
+			v.Value = flag.Value.String()
+		}
+		values[flag.Name] = v
+	}
+	cmd.LocalFlags().VisitAll(visit)
+	cmd.InheritedFlags().VisitAll(visit)
+	return values
+}
+
+// sensitive reports whether flag is marked sensitive, so its value must not be
+// recorded.
+func sensitive(flag *pflag.Flag) bool {
+	return len(flag.Annotations[sensitiveAnnotation]) > 0
+}
@@ -0,0 +1,68 @@
+package cobrayaml
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFlagValues(t *testing.T) {
+	yamlContent := `
+name: values
+root:
+  use: values
+  short: Values test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  login:
+    use: login
+    short: Log in
+    run_func: runLogin
+    flags:
+      - name: user
+        type: string
+        usage: User name
+      - name: token
+        type: string
+        sensitive: true
+        usage: API token
+      - name: timeout
+        type: duration
+        default: 30s
+        usage: Timeout
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var got map[string]TypedValue
+	cb.RegisterFunction("runLogin", func(cmd *cobra.Command, args []string) {
+		got = FlagValues(cmd)
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SetArgs([]string{"login", "--verbose", "--user", "gopher", "--token", "s3cret"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := map[string]TypedValue{
+		"verbose": {Type: "bool", Value: true, Source: FlagSourceCommandLine},
+		"user":    {Type: "string", Value: "gopher", Source: FlagSourceCommandLine},
+		"token":   {Type: "string", Value: "<redacted>", Source: FlagSourceCommandLine, Redacted: true},
+		"timeout": {Type: "duration", Value: 30 * time.Second, Source: FlagSourceDefault},
+		// Built-in flags are included as well
+		"help-format": {Type: "string", Value: "text", Source: FlagSourceDefault},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlagValues() = %+v, want %+v", got, want)
+	}
+}
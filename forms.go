@@ -0,0 +1,191 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Form is the form of a runnable command, shown by the web UI (see the webui
+// package) and the tui command.
+type Form struct {
+	Path  string // names of the command separated with spaces; empty for the root
+	Title string
+	Short string
+	Long  string
+	Flags []FormField
+	Args  []FormField
+
+	rawArgs bool // the command disables flag parsing and takes "--" as an arg
+}
+
+// FormField is an input of a command form.
+type FormField struct {
+	Name     string // form field name
+	Label    string
+	Input    string // text, password, number, checkbox, textarea or select
+	Usage    string
+	Default  string
+	Options  []string
+	Required bool
+	Multiple bool // a textarea of values, or args separated with spaces
+
+	flag string // flag name without dashes; empty for args
+}
+
+// Forms returns the forms of the runnable, visible commands of config sorted by
+// path, starting with the root command when it has a run_func.
+func Forms(config *ToolConfig) []Form {
+	var commands []Form
+	inherited := persistentFlags(nil, config.Root.Flags)
+	if config.Root.RunFunc != "" {
+		commands = append(commands, newForm(config.Name, nil, config.Root, nil))
+	}
+
+	var walk func(names []string, cmds map[string]CommandConfig, inherited []FlagConfig)
+	walk = func(names []string, cmds map[string]CommandConfig, inherited []FlagConfig) {
+		for _, name := range sortedKeys(cmds) {
+			cmd := cmds[name]
+			if cmd.Hidden {
+				continue
+			}
+			path := append(append([]string{}, names...), name)
+			if cmd.RunFunc != "" {
+				commands = append(commands, newForm(config.Name, path, cmd, inherited))
+			}
+			walk(path, cmd.Commands, persistentFlags(inherited, cmd.Flags))
+		}
+	}
+	walk(nil, config.Commands, inherited)
+	return commands
+}
+
+// newForm builds the form of the command at path with its own flags
+// followed by the persistent flags it inherits. A command that disables flag
+// parsing gets no flag inputs; flags go in its args.
+func newForm(tool string, path []string, config CommandConfig, inherited []FlagConfig) Form {
+	cmd := Form{
+		Path:  strings.Join(path, " "),
+		Title: strings.Join(append([]string{tool}, path...), " "),
+		Short: config.Short,
+		Long:  config.Long,
+
+		rawArgs: config.DisableFlagParsing,
+	}
+	for _, flag := range append(append([]FlagConfig{}, config.Flags...), inherited...) {
+		if flag.Hidden || flag.Deprecated != "" || config.DisableFlagParsing {
+			continue
+		}
+		cmd.Flags = append(cmd.Flags, newFormFlagField(flag))
+	}
+	if config.SideEffects == SideEffectsDestructive && !config.DisableFlagParsing {
+		cmd.Flags = append(cmd.Flags, newFormFlagField(yesFlagConfig))
+	}
+	cmd.Args = formArgFields(config)
+	return cmd
+}
+
+// newFormFlagField returns the input of a flag.
+func newFormFlagField(flag FlagConfig) FormField {
+	field := FormField{
+		Name:     "flag." + flag.Name,
+		Label:    "--" + flag.Name,
+		Input:    "text",
+		Usage:    flag.Usage,
+		Default:  flag.DefaultValue,
+		Required: flag.Required,
+		flag:     flag.Name,
+	}
+	switch {
+	case len(flag.AllowedValues) > 0:
+		field.Input = "select"
+		field.Options = flag.AllowedValues
+	case flag.Type == FlagTypeBool:
+		field.Input = "checkbox"
+		field.Required = false
+	case flag.Type == FlagTypeInt || flag.Type == FlagTypeUint || flag.Type == FlagTypeUint64:
+		field.Input = "number"
+	case flag.Type == FlagTypeStringSlice || flag.Type == FlagTypeStringArray:
+		field.Input = "textarea"
+		field.Multiple = true
+		field.Default = strings.Join(strings.Split(strings.Trim(flag.DefaultValue, "[]"), ","), "\n")
+	case flag.Sensitive:
+		field.Input = "password"
+		field.Default = ""
+	}
+	return field
+}
+
+// formArgFields returns a field per argument named in the use line of config, such
+// as <env> and [version...], or a single field of args separated with spaces
+// when the use line names none and the command takes args.
+func formArgFields(config CommandConfig) []FormField {
+	var fields []FormField
+	for i, token := range strings.Fields(config.Use)[1:] {
+		required := strings.HasPrefix(token, "<")
+		name := strings.Trim(token, "<>[]")
+		if name == "" || name == "flags" || strings.HasPrefix(token, "-") {
+			continue
+		}
+		multiple := strings.HasSuffix(name, "...") || strings.HasSuffix(token, "...")
+		name = strings.TrimSuffix(name, "...")
+		fields = append(fields, FormField{
+			Name:     fmt.Sprintf("arg.%d", i),
+			Label:    name,
+			Input:    "text",
+			Required: required,
+			Multiple: multiple,
+		})
+	}
+	if len(fields) > 0 || (config.Args != nil && config.Args.Type == ArgsTypeNone) {
+		return fields
+	}
+	if config.Args == nil && len(config.ValidArgs) == 0 && !config.DisableFlagParsing {
+		return nil
+	}
+	return []FormField{{Name: "args", Label: "args", Input: "text", Usage: "Arguments separated with spaces", Multiple: true}}
+}
+
+// Argv returns the names, flags and args of a run of cmd from the posted form.
+// Flags are passed as --name=value; args starting with "-" follow "--" unless
+// the command disables flag parsing.
+func (cmd Form) Argv(form url.Values) []string {
+	argv := strings.Fields(cmd.Path)
+	for _, field := range cmd.Flags {
+		values := form[field.Name]
+		switch {
+		case field.Input == "checkbox":
+			checked := len(values) > 0 && values[0] != ""
+			if on := field.Default == "true"; checked != on {
+				argv = append(argv, fmt.Sprintf("--%s=%t", field.flag, checked))
+			}
+		case field.Multiple:
+			for _, value := range strings.Split(form.Get(field.Name), "\n") {
+				if value = strings.TrimSpace(value); value != "" {
+					argv = append(argv, "--"+field.flag+"="+value)
+				}
+			}
+		default:
+			if value := form.Get(field.Name); value != "" {
+				argv = append(argv, "--"+field.flag+"="+value)
+			}
+		}
+	}
+
+	var args []string
+	for _, field := range cmd.Args {
+		value := form.Get(field.Name)
+		if field.Multiple {
+			args = append(args, strings.Fields(value)...)
+		} else if value != "" {
+			args = append(args, value)
+		}
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && !cmd.rawArgs {
+			argv = append(argv, "--")
+			break
+		}
+	}
+	return append(argv, args...)
+}
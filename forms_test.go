@@ -0,0 +1,81 @@
+package cobrayaml
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+const formsYAML = `
+name: ui-test
+root:
+  use: ui-test
+  short: UI test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  deploy:
+    use: deploy <env> [services...]
+    short: Deploy services
+    run_func: runDeploy
+    flags:
+      - name: strategy
+        type: string
+        default: rolling
+        allowed_values: [rolling, canary]
+        usage: Rollout strategy
+      - name: label
+        type: stringArray
+        usage: Labels to set
+      - name: wait
+        type: bool
+        default: "true"
+        usage: Wait for the rollout
+      - name: token
+        type: string
+        sensitive: true
+        usage: API token
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+        args:
+          type: none
+  secret:
+    use: secret
+    short: Hidden command
+    hidden: true
+    run_func: runBackup
+`
+
+func TestForms_Argv(t *testing.T) {
+	gen, err := NewGeneratorFromString(formsYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	commands := Forms(gen.config)
+
+	var paths []string
+	for _, cmd := range commands {
+		paths = append(paths, cmd.Path)
+	}
+	if want := []string{"db backup", "deploy"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	got := commands[1].Argv(url.Values{
+		"arg.0":      {"prod"},
+		"flag.wait":  {"true"},
+		"flag.token": {"s3cret"},
+	})
+	if want := []string{"deploy", "--token=s3cret", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("argv = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// renamedShimPrefix is the deprecation message prefix of the shims built for renamed_from.
+const renamedShimPrefix = "it has been renamed to "
+
+// FromCobra introspects an existing cobra command tree and returns the equivalent
+// ToolConfig, so existing CLIs can be moved to commands.yaml or mixed with it.
+//
+// Uses, descriptions, aliases, hidden state, flags and, where detectable, args are
+// exported. Runnable commands get a run_func named after their path (e.g.
+// "runDbMigrate") since Go function names cannot be recovered. Only cobra.NoArgs and
+// cobra.ArbitraryArgs are detected; other argument validators are omitted. The
+// help and completion commands that cobra adds are skipped.
+func FromCobra(cmd *cobra.Command) (*ToolConfig, error) {
+	root, err := commandConfigFromCobra(cmd, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ToolConfig{
+		SchemaVersion: SchemaVersion(),
+		Name:          cmd.Name(),
+		Description:   cmd.Short,
+		Version:       cmd.Version,
+		Commands:      root.Commands,
+	}
+	root.Commands = nil
+	config.Root = root
+
+	if err := ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// commandConfigFromCobra converts a command and its subcommands.
+// path holds the names of the parent commands, excluding the root.
+func commandConfigFromCobra(cmd *cobra.Command, path []string) (CommandConfig, error) {
+	config := CommandConfig{
+		Use:                cmd.Use,
+		Aliases:            cmd.Aliases,
+		Short:              cmd.Short,
+		Long:               cmd.Long,
+		Args:               argsConfigFromCobra(cmd.Args),
+		ValidArgs:          cmd.ValidArgs,
+		Hidden:             cmd.Hidden,
+		Deprecated:         cmd.Deprecated,
+		UndoFunc:           cmd.Annotations[undoAnnotation],
+		SilenceUsage:       cmd.SilenceUsage,
+		SilenceErrors:      cmd.SilenceErrors,
+		DisableFlagParsing: cmd.DisableFlagParsing,
+	}
+	for key, value := range cmd.Annotations {
+		// Annotations of the builder map to other command options
+		if strings.HasPrefix(key, "cobrayaml_") {
+			continue
+		}
+		if config.Annotations == nil {
+			config.Annotations = make(map[string]string)
+		}
+		config.Annotations[key] = value
+	}
+	if cmd.Runnable() {
+		name := cmd.Name()
+		if len(path) > 0 {
+			name = strings.Join(path, " ")
+		}
+		config.RunFunc = "run" + toPascalCase(name)
+	}
+
+	flags, err := flagConfigsFromCobra(cmd)
+	if err != nil {
+		return CommandConfig{}, err
+	}
+	config.Flags = flags
+
+	// Rename shims are folded back into renamed_from of the command they point to.
+	renamed := make(map[string][]string)
+	for _, sub := range cmd.Commands() {
+		if target, ok := strings.CutPrefix(sub.Deprecated, renamedShimPrefix); ok && sub.Hidden {
+			name := strings.Trim(target, `"`)
+			renamed[name] = append(renamed[name], sub.Name())
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		if isCobraDefaultCommand(sub) || (strings.HasPrefix(sub.Deprecated, renamedShimPrefix) && sub.Hidden) {
+			continue
+		}
+		subConfig, err := commandConfigFromCobra(sub, append(append([]string{}, path...), sub.Name()))
+		if err != nil {
+			return CommandConfig{}, err
+		}
+		subConfig.RenamedFrom = renamed[sub.Name()]
+		sort.Strings(subConfig.RenamedFrom)
+
+		if config.Commands == nil {
+			config.Commands = make(map[string]CommandConfig)
+		}
+		config.Commands[sub.Name()] = subConfig
+	}
+
+	return config, nil
+}
+
+// isCobraDefaultCommand reports whether cmd is the help or completion command added by cobra.
+func isCobraDefaultCommand(cmd *cobra.Command) bool {
+	return (cmd.Name() == "help" || cmd.Name() == "completion") && cmd.Parent() != nil && !cmd.Parent().HasParent()
+}
+
+// argsConfigFromCobra detects the argument validators that can be expressed in YAML.
+func argsConfigFromCobra(args cobra.PositionalArgs) *ArgsConfig {
+	if args == nil {
+		return nil
+	}
+	switch reflect.ValueOf(args).Pointer() {
+	case reflect.ValueOf(cobra.NoArgs).Pointer():
+		return &ArgsConfig{Type: ArgsTypeNone}
+	case reflect.ValueOf(cobra.ArbitraryArgs).Pointer():
+		return &ArgsConfig{Type: ArgsTypeAny}
+	case reflect.ValueOf(cobra.OnlyValidArgs).Pointer():
+		return &ArgsConfig{Type: ArgsTypeOnlyValidArgs}
+	default:
+		return nil
+	}
+}
+
+// flagConfigsFromCobra converts the flags defined on cmd itself, skipping inherited
+// flags and the help and version flags added by cobra.
+func flagConfigsFromCobra(cmd *cobra.Command) ([]FlagConfig, error) {
+	var flags []FlagConfig
+	var err error
+	convert := func(persistent bool) func(*pflag.Flag) {
+		return func(flag *pflag.Flag) {
+			if err != nil || flag.Name == "help" || (flag.Name == "version" && !cmd.HasParent() && cmd.Version != "") ||
+				(flag.Name == helpFormatFlag && !cmd.HasParent() && flag.Hidden) {
+				return
+			}
+			var config FlagConfig
+			if config, err = flagConfigFromPflag(flag); err != nil {
+				err = fmt.Errorf("command %q: %w", cmd.CommandPath(), err)
+				return
+			}
+			config.Persistent = persistent
+			flags = append(flags, config)
+		}
+	}
+
+	cmd.LocalNonPersistentFlags().VisitAll(convert(false))
+	cmd.PersistentFlags().VisitAll(convert(true))
+	return flags, err
+}
+
+// flagZeroDefaults holds the default value strings pflag reports for unset flags.
+var flagZeroDefaults = map[string]string{
+	FlagTypeString:      "",
+	FlagTypeBool:        "false",
+	FlagTypeInt:         "0",
+	FlagTypeUint:        "0",
+	FlagTypeUint64:      "0",
+	FlagTypeStringSlice: "[]",
+	FlagTypeStringArray: "[]",
+	FlagTypeIP:          "<nil>",
+	FlagTypeCIDR:        "<nil>",
+	FlagTypeDuration:    "0s",
+	FlagTypeURL:         "",
+	FlagTypeByteSize:    "0",
+	FlagTypeTime:        "",
+}
+
+// flagConfigFromPflag converts a single flag.
+func flagConfigFromPflag(flag *pflag.Flag) (FlagConfig, error) {
+	config := FlagConfig{
+		Name:      flag.Name,
+		Shorthand: flag.Shorthand,
+		Usage:     flag.Usage,
+		// MarkDeprecated also hides the flag
+		Hidden:              flag.Hidden && flag.Deprecated == "",
+		Required:            len(flag.Annotations[cobra.BashCompOneRequiredFlag]) > 0,
+		Deprecated:          flag.Deprecated,
+		ShorthandDeprecated: flag.ShorthandDeprecated,
+	}
+	for key, values := range flag.Annotations {
+		// Annotations of cobra and the builder map to other flag options, and
+		// FlagConfig annotations hold a single value.
+		if strings.HasPrefix(key, "cobra_annotation_") || strings.HasPrefix(key, "cobrayaml_") || len(values) != 1 {
+			continue
+		}
+		if config.Annotations == nil {
+			config.Annotations = make(map[string]string)
+		}
+		config.Annotations[key] = values[0]
+	}
+	if names := flag.Annotations[transformAnnotation]; len(names) > 0 {
+		config.TransformFunc = names[0]
+	}
+	if sources := flag.Annotations[schemaAnnotation]; len(sources) > 0 {
+		config.Schema = sources[0]
+	}
+	config.Sensitive = sensitive(flag)
+
+	switch value := flag.Value.(type) {
+	case *pathValue:
+		config.Type = FlagTypeFile
+		if value.dir {
+			config.Type = FlagTypeDir
+		}
+		if !value.exists {
+			mustExist := false
+			config.MustExist = &mustExist
+		}
+		config.CreateMissing = value.createMissing
+		if len(value.extensions) > 0 {
+			config.Extensions = value.extensions
+		}
+	case *enumValue:
+		config.Type = FlagTypeString
+		config.AllowedValues = value.allowed
+		config.Usage = strings.TrimSuffix(config.Usage, fmt.Sprintf(" (one of: %s)", strings.Join(value.allowed, ", ")))
+	case *timeValue:
+		config.Type = FlagTypeTime
+		config.Relative = value.relative
+		if value.layout != resolveTimeLayout("") {
+			config.Layout = value.layout
+		}
+	default:
+		switch flag.Value.Type() {
+		case "ipNet":
+			config.Type = FlagTypeCIDR
+		case FlagTypeString, FlagTypeBool, FlagTypeInt, FlagTypeUint, FlagTypeUint64, FlagTypeStringSlice,
+			FlagTypeStringArray, FlagTypeIP, FlagTypeDuration, FlagTypeURL, FlagTypeByteSize:
+			config.Type = flag.Value.Type()
+		default:
+			return FlagConfig{}, fmt.Errorf("flag --%s: unsupported flag type %s", flag.Name, flag.Value.Type())
+		}
+	}
+
+	if flag.DefValue != flagZeroDefaults[config.Type] {
+		config.DefaultValue = flag.DefValue
+		if config.Type == FlagTypeStringSlice || config.Type == FlagTypeStringArray {
+			config.DefaultValue = strings.Trim(flag.DefValue, "[]")
+		}
+	}
+	return config, nil
+}
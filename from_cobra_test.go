@@ -0,0 +1,197 @@
+package cobrayaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFromCobra(t *testing.T) {
+	root := &cobra.Command{Use: "legacy", Short: "Legacy CLI", Version: "1.2.3"}
+	root.PersistentFlags().StringP("namespace", "n", "default", "Namespace")
+
+	db := &cobra.Command{Use: "db", Short: "Database commands", Aliases: []string{"database"}}
+	migrate := &cobra.Command{
+		Use:   "migrate",
+		Short: "Run migrations",
+		Long:  "Run all pending migrations.",
+		Args:  cobra.NoArgs,
+		RunE:  func(cmd *cobra.Command, args []string) error { return nil },
+	}
+	migrate.Flags().Uint("batch", 100, "Batch size")
+	migrate.Flags().Bool("dry-run", false, "Show what would be migrated")
+	migrate.Flags().Int("steps", 1, "Number of steps")
+	migrate.Flags().Duration("timeout", 30*time.Second, "Timeout")
+	migrate.Flags().StringSlice("only", nil, "Only these migrations")
+	migrate.Flags().String("secret", "", "Secret")
+	_ = migrate.MarkFlagRequired("steps")
+	_ = migrate.Flags().MarkHidden("secret")
+
+	db.AddCommand(migrate)
+	root.AddCommand(db)
+	root.InitDefaultHelpCmd()
+	root.InitDefaultCompletionCmd()
+
+	config, err := FromCobra(root)
+	if err != nil {
+		t.Fatalf("FromCobra() error = %v", err)
+	}
+
+	if config.Name != "legacy" || config.Description != "Legacy CLI" || config.Version != "1.2.3" {
+		t.Errorf("tool = %q/%q/%q, want legacy/Legacy CLI/1.2.3", config.Name, config.Description, config.Version)
+	}
+	if config.Root.RunFunc != "" {
+		t.Errorf("root run_func = %q, want empty for a non-runnable root", config.Root.RunFunc)
+	}
+	wantRootFlags := []FlagConfig{
+		{Name: "namespace", Shorthand: "n", Type: FlagTypeString, DefaultValue: "default", Usage: "Namespace", Persistent: true},
+	}
+	if !reflect.DeepEqual(config.Root.Flags, wantRootFlags) {
+		t.Errorf("root flags = %+v, want %+v", config.Root.Flags, wantRootFlags)
+	}
+
+	if len(config.Commands) != 1 {
+		t.Fatalf("commands = %v, want only db (help and completion skipped)", config.Commands)
+	}
+	dbConfig := config.Commands["db"]
+	if !reflect.DeepEqual(dbConfig.Aliases, []string{"database"}) {
+		t.Errorf("db aliases = %v, want [database]", dbConfig.Aliases)
+	}
+
+	migrateConfig := dbConfig.Commands["migrate"]
+	if migrateConfig.RunFunc != "runDbMigrate" {
+		t.Errorf("migrate run_func = %q, want runDbMigrate", migrateConfig.RunFunc)
+	}
+	if migrateConfig.Args == nil || migrateConfig.Args.Type != ArgsTypeNone {
+		t.Errorf("migrate args = %+v, want none", migrateConfig.Args)
+	}
+	if migrateConfig.Long != "Run all pending migrations." {
+		t.Errorf("migrate long = %q", migrateConfig.Long)
+	}
+	wantFlags := []FlagConfig{
+		{Name: "batch", Type: FlagTypeUint, DefaultValue: "100", Usage: "Batch size"},
+		{Name: "dry-run", Type: FlagTypeBool, Usage: "Show what would be migrated"},
+		{Name: "only", Type: FlagTypeStringSlice, Usage: "Only these migrations"},
+		{Name: "secret", Type: FlagTypeString, Usage: "Secret", Hidden: true},
+		{Name: "steps", Type: FlagTypeInt, DefaultValue: "1", Usage: "Number of steps", Required: true},
+		{Name: "timeout", Type: FlagTypeDuration, DefaultValue: "30s", Usage: "Timeout"},
+	}
+	if !reflect.DeepEqual(migrateConfig.Flags, wantFlags) {
+		t.Errorf("migrate flags = %+v, want %+v", migrateConfig.Flags, wantFlags)
+	}
+}
+
+func TestFromCobra_RoundTrip(t *testing.T) {
+	yamlContent := `
+name: round-trip
+root:
+  use: round-trip
+  short: Round trip
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    renamed_from: [ship]
+    annotations:
+      docs.section: release
+    args:
+      type: any
+    flags:
+      - name: format
+        type: string
+        usage: Output format
+        allowed_values: [json, yaml]
+        annotations:
+          completion.group: output
+      - name: manifest
+        type: file
+        usage: Manifest
+        extensions: [.yaml]
+      - name: name
+        type: string
+        usage: Name
+        transform_func: trimSpace
+      - name: old-name
+        type: string
+        usage: Old name
+        deprecated: use --name instead
+      - name: out-dir
+        type: dir
+        usage: Output directory
+        must_exist: false
+      - name: quiet
+        shorthand: q
+        type: bool
+        usage: Quiet
+        shorthand_deprecated: use --quiet instead
+      - name: since
+        type: time
+        usage: Since
+        layout: DateOnly
+        relative: true
+  release:
+    use: release
+    short: Release
+    run_func: runRelease
+    deprecated: use 'deploy' instead
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error { return nil })
+	cb.RegisterFunction("runRelease", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	config, err := FromCobra(rootCmd)
+	if err != nil {
+		t.Fatalf("FromCobra() error = %v", err)
+	}
+
+	deploy, ok := config.Commands["deploy"]
+	if !ok || len(config.Commands) != 2 {
+		t.Fatalf("commands = %v, want deploy and release (rename shim folded)", config.Commands)
+	}
+	if got := config.Commands["release"].Deprecated; got != "use 'deploy' instead" {
+		t.Errorf("release deprecated = %q, want the deprecation message", got)
+	}
+	if !reflect.DeepEqual(deploy.RenamedFrom, []string{"ship"}) {
+		t.Errorf("renamed_from = %v, want [ship]", deploy.RenamedFrom)
+	}
+	if deploy.Args == nil || deploy.Args.Type != ArgsTypeAny {
+		t.Errorf("args = %+v, want any", deploy.Args)
+	}
+	if !reflect.DeepEqual(deploy.Annotations, map[string]string{"docs.section": "release"}) {
+		t.Errorf("annotations = %v, want docs.section", deploy.Annotations)
+	}
+
+	wantFlags := []FlagConfig{
+		{Name: "format", Type: FlagTypeString, Usage: "Output format", AllowedValues: []string{"json", "yaml"}, Annotations: map[string]string{"completion.group": "output"}},
+		{Name: "manifest", Type: FlagTypeFile, Usage: "Manifest", Extensions: []string{".yaml"}},
+		{Name: "name", Type: FlagTypeString, Usage: "Name", TransformFunc: "trimSpace"},
+		{Name: "old-name", Type: FlagTypeString, Usage: "Old name", Deprecated: "use --name instead"},
+		{Name: "out-dir", Type: FlagTypeDir, Usage: "Output directory", MustExist: new(bool)},
+		{Name: "quiet", Shorthand: "q", Type: FlagTypeBool, Usage: "Quiet", ShorthandDeprecated: "use --quiet instead"},
+		{Name: "since", Type: FlagTypeTime, Usage: "Since", Layout: time.DateOnly, Relative: true},
+	}
+	if !reflect.DeepEqual(deploy.Flags, wantFlags) {
+		t.Errorf("flags = %+v, want %+v", deploy.Flags, wantFlags)
+	}
+}
+
+func TestFromCobra_UnsupportedFlagType(t *testing.T) {
+	root := &cobra.Command{Use: "legacy", Short: "Legacy CLI"}
+	root.Flags().Float64("ratio", 0.5, "Ratio")
+
+	_, err := FromCobra(root)
+	if err == nil || !strings.Contains(err.Error(), "flag --ratio: unsupported flag type float64") {
+		t.Errorf("FromCobra() error = %v, want unsupported flag type error", err)
+	}
+}
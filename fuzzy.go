@@ -0,0 +1,194 @@
+package cobrayaml
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// noInteractiveFlag turns off the interactive prompt of fuzzy_match.
+	noInteractiveFlag = "no-interactive"
+	// fuzzyMatchLimit is the number of matches offered for an unknown command.
+	fuzzyMatchLimit = 3
+)
+
+// isInteractive reports whether cmd reads from a terminal. It is replaced in tests.
+var isInteractive = func(cmd *cobra.Command) bool {
+	in, ok := cmd.InOrStdin().(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := in.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// addFuzzyMatch makes an unknown command given to the root command offer the
+// closest commands of the whole tree to run when fuzzy_match is set. Without a
+// terminal, or with --no-interactive, it fails with cobra's usual suggestions.
+func (cb *CommandBuilder) addFuzzyMatch(rootCmd *cobra.Command) {
+	if !cb.config.FuzzyMatch || rootCmd.Runnable() || rootCmd.Flags().Lookup(noInteractiveFlag) != nil {
+		return
+	}
+
+	rootCmd.Flags().Bool(noInteractiveFlag, false, "Do not offer matching commands for an unknown command")
+	rootCmd.Args = cobra.ArbitraryArgs
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return cmd.Help()
+		}
+		noInteractive, _ := cmd.Flags().GetBool(noInteractiveFlag)
+		matches := fuzzyMatches(cmd, args[0])
+		if noInteractive || len(matches) == 0 || !isInteractive(cmd) {
+			cmd.SilenceUsage = true // cobra does not print usage for unknown commands either
+			return unknownCommandError(cmd, args[0])
+		}
+
+		chosen, err := chooseMatch(cmd, args[0], matches)
+		if err != nil || chosen == nil {
+			return err
+		}
+		return runMatch(cmd, append(strings.Fields(commandKey(chosen)), args[1:]...))
+	}
+}
+
+// unknownCommandError returns the error cobra reports for an unknown command,
+// including its suggestions.
+func unknownCommandError(cmd *cobra.Command, name string) error {
+	msg := fmt.Sprintf("unknown command %q for %q", name, cmd.CommandPath())
+	if cmd.DisableSuggestions {
+		return errors.New(msg)
+	}
+	if cmd.SuggestionsMinimumDistance <= 0 {
+		cmd.SuggestionsMinimumDistance = 2 // cobra's default
+	}
+	if suggestions := cmd.SuggestionsFor(name); len(suggestions) > 0 {
+		msg += "\n\nDid you mean this?\n"
+		for _, s := range suggestions {
+			msg += fmt.Sprintf("\t%v\n", s)
+		}
+	}
+	return errors.New(msg)
+}
+
+// fuzzyMatches returns the available commands anywhere below rootCmd whose name
+// or an alias is close to name, best match first. A name starting with the typed
+// word ranks above one a few edits away.
+func fuzzyMatches(rootCmd *cobra.Command, name string) []*cobra.Command {
+	name = strings.ToLower(name)
+	maxDistance := max(2, len(name)/3)
+
+	type match struct {
+		cmd   *cobra.Command
+		score int
+	}
+	var matches []match
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if !sub.IsAvailableCommand() {
+				continue
+			}
+			score := -1
+			for _, n := range append([]string{sub.Name()}, sub.Aliases...) {
+				n = strings.ToLower(n)
+				s := 2 * levenshtein(name, n)
+				if len(name) >= 2 && strings.HasPrefix(n, name) {
+					s = 1
+				}
+				if s <= 2*maxDistance && (score < 0 || s < score) {
+					score = s
+				}
+			}
+			if score >= 0 {
+				matches = append(matches, match{sub, score})
+			}
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.score != b.score {
+			return a.score - b.score
+		}
+		return strings.Compare(commandKey(a.cmd), commandKey(b.cmd))
+	})
+	var cmds []*cobra.Command
+	for _, m := range matches[:min(len(matches), fuzzyMatchLimit)] {
+		cmds = append(cmds, m.cmd)
+	}
+	return cmds
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// chooseMatch asks the user which of matches to run instead of the unknown
+// command name. It returns nil if the user cancels.
+func chooseMatch(cmd *cobra.Command, name string, matches []*cobra.Command) (*cobra.Command, error) {
+	width := 0
+	for _, m := range matches {
+		width = max(width, len(commandKey(m)))
+	}
+
+	errOut := cmd.ErrOrStderr()
+	fmt.Fprintf(errOut, "Unknown command %q. Did you mean:\n", name)
+	for i, m := range matches {
+		fmt.Fprintf(errOut, "  %d) %-*s  %s\n", i+1, width, commandKey(m), m.Short)
+	}
+	fmt.Fprintf(errOut, "Run which? [1-%d, empty to cancel]: ", len(matches))
+
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read answer: %w", err)
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(answer)
+	if err != nil || i < 1 || i > len(matches) {
+		return nil, fmt.Errorf("invalid choice %q", answer)
+	}
+	return matches[i-1], nil
+}
+
+// runMatch runs the root command again with args. The error is reported once,
+// by the outer run, and without the root command's usage.
+func runMatch(rootCmd *cobra.Command, args []string) error {
+	silenceErrors := rootCmd.SilenceErrors
+	rootCmd.SilenceErrors = true
+	rootCmd.SetArgs(args)
+	_, err := rootCmd.ExecuteC()
+	rootCmd.SilenceErrors = silenceErrors
+	if err != nil {
+		rootCmd.SilenceUsage = true
+	}
+	return err
+}
@@ -0,0 +1,144 @@
+package cobrayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const fuzzyToolYAML = `
+name: fuzzy-test
+fuzzy_match: true
+root:
+  use: fuzzy-test
+  short: Fuzzy test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy the application
+    run_func: runDeploy
+    aliases: [ship]
+  describe:
+    use: describe
+    short: Describe a resource
+    run_func: runNoop
+  db migrate:
+    use: migrate
+    short: Run migrations
+    run_func: runNoop
+`
+
+// setInteractive replaces the terminal detection of the fuzzy_match prompt.
+func setInteractive(t *testing.T, interactive bool) {
+	t.Helper()
+	original := isInteractive
+	isInteractive = func(cmd *cobra.Command) bool { return interactive }
+	t.Cleanup(func() { isInteractive = original })
+}
+
+func TestFuzzyMatches(t *testing.T) {
+	rootCmd := testTool{yaml: fuzzyToolYAML, funcs: map[string]any{"runDeploy": noopRun, "runNoop": noopRun}}.build(t)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "deplyo", want: "deploy"},
+		{name: "de", want: "deploy, describe, db"},
+		{name: "migrat", want: "db migrate"},
+		{name: "shp", want: "deploy"},
+		{name: "xyzzy", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, cmd := range fuzzyMatches(rootCmd, tt.name) {
+				got = append(got, commandKey(cmd))
+			}
+			if strings.Join(got, ", ") != tt.want {
+				t.Errorf("fuzzyMatches(%q) = %v, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_FuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		interactive bool
+		in          string
+		args        []string
+		wantRan     string
+		wantOut     []string
+		wantErr     string
+	}{
+		{
+			name:        "interactive choice",
+			interactive: true,
+			in:          "1\n",
+			args:        []string{"deplyo", "prod"},
+			wantRan:     "deploy prod",
+			wantOut:     []string{`Unknown command "deplyo". Did you mean:`, "1) deploy  Deploy the application"},
+		},
+		{name: "interactive cancel", interactive: true, in: "\n", args: []string{"deplyo"}},
+		{name: "invalid choice", interactive: true, in: "7\n", args: []string{"deplyo"}, wantErr: `invalid choice "7"`},
+		{
+			name:        "no-interactive",
+			interactive: true,
+			in:          "1\n",
+			args:        []string{"--no-interactive", "deplyo"},
+			wantErr:     "unknown command \"deplyo\" for \"fuzzy-test\"\n\nDid you mean this?\n\tdeploy\n",
+		},
+		{name: "no terminal", in: "1\n", args: []string{"deplyo"}, wantErr: `unknown command "deplyo"`},
+		{name: "known command", interactive: true, args: []string{"deploy"}, wantRan: "deploy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("HOME", t.TempDir())
+			setInteractive(t, tt.interactive)
+
+			var ran []string
+			out, err := testTool{
+				yaml: fuzzyToolYAML,
+				funcs: map[string]any{
+					"runDeploy": func(cmd *cobra.Command, args []string) error {
+						ran = append([]string{"deploy"}, args...)
+						return nil
+					},
+					"runNoop": noopRun,
+				},
+				in: tt.in,
+			}.run(t, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Execute() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := strings.Join(ran, " "); got != tt.wantRan {
+				t.Errorf("ran %q, want %q", got, tt.wantRan)
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out, want) {
+					t.Errorf("output should contain %q, got:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateConfig_FuzzyMatchRunnableRoot(t *testing.T) {
+	config := &ToolConfig{
+		Name:       "test",
+		FuzzyMatch: true,
+		Root:       CommandConfig{Use: "test", Short: "Test", RunFunc: "runRoot"},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "fuzzy_match needs a root command without run_func and args") {
+		t.Errorf("ValidateConfig() error = %v, want fuzzy_match error", err)
+	}
+}
@@ -5,18 +5,38 @@ import (
 	"fmt"
 	"go/format"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
 
 	"gopkg.in/yaml.v2"
 )
 
+// Kinds of functions collected from the YAML config.
+const (
+	// FuncKindRun is a command handler referenced by run_func.
+	FuncKindRun = "run"
+	// FuncKindValidate is an input validator referenced by validate_func.
+	FuncKindValidate = "validate"
+	// FuncKindDerive is a derive function referenced by a derived value.
+	FuncKindDerive = "derive"
+	// FuncKindDynamic is a dynamic commands function referenced by dynamic_commands_func.
+	FuncKindDynamic = "dynamic"
+	// FuncKindComplete is a completion function referenced by completion_func.
+	FuncKindComplete = "complete"
+	// FuncKindUndo is a function reverting a run, referenced by undo_func.
+	FuncKindUndo = "undo"
+)
+
 // FuncInfo holds information about a function to be generated
 type FuncInfo struct {
 	Name    string
+	Kind    string // FuncKindRun, FuncKindValidate, FuncKindDerive, FuncKindDynamic, FuncKindComplete or FuncKindUndo
 	Flags   []FlagConfig
 	Args    *ArgsConfig
-	CmdPath string // e.g., "root > add" for context
+	CmdPath string   // e.g., "root > add" for context
+	Inputs  []string // flag names passed to a derive function
 }
 
 // GeneratorConfig holds configuration for code generation
@@ -27,7 +47,11 @@ type GeneratorConfig struct {
 
 // Generator generates handler function stubs from YAML config
 type Generator struct {
-	config *ToolConfig
+	config            *ToolConfig
+	configHash        string
+	enumTypes         bool
+	sortRegistrations bool
+	include           []string
 }
 
 // NewGenerator creates a new generator from a YAML file
@@ -45,8 +69,9 @@ func NewGenerator(configPath string) (*Generator, error) {
 	if err := ValidateConfig(&config); err != nil {
 		return nil, err
 	}
+	resolveDefaults(&config)
 
-	return &Generator{config: &config}, nil
+	return &Generator{config: &config, configHash: ConfigHash(string(data))}, nil
 }
 
 // NewGeneratorFromString creates a new generator from YAML string
@@ -59,32 +84,190 @@ func NewGeneratorFromString(yamlContent string) (*Generator, error) {
 	if err := ValidateConfig(&config); err != nil {
 		return nil, err
 	}
+	resolveDefaults(&config)
 
-	return &Generator{config: &config}, nil
+	return &Generator{config: &config, configHash: ConfigHash(yamlContent)}, nil
 }
 
-// CollectFunctions collects all function info from the config
+// SetEnumTypes enables or disables typed enums for flags with allowed_values.
+// When enabled, GenerateEnums emits a Go string type with one constant per
+// allowed value, and the generated handlers read such flags through typed getters.
+func (g *Generator) SetEnumTypes(enabled bool) {
+	g.enumTypes = enabled
+}
+
+// SetSortRegistrations enables or disables sorting the functions registered in the
+// generated main.go by function name. By default they follow the command tree, so
+// adding a command can move existing lines; sorted registrations keep the diff of a
+// regenerated main.go to the added or removed lines.
+func (g *Generator) SetSortRegistrations(enabled bool) {
+	g.sortRegistrations = enabled
+}
+
+// Include restricts the generated code to the commands selected by patterns, as
+// CommandBuilder.Include does: handlers are generated for these commands only,
+// and the generated main.go and contract test apply the same patterns to the
+// embedded commands.yaml.
+func (g *Generator) Include(patterns ...string) error {
+	if err := includeCommands(g.config, patterns); err != nil {
+		return err
+	}
+	g.include = append(g.include, patterns...)
+	return nil
+}
+
+// CollectFunctions collects all function info from the config, including the
+// commands of every surface; their CmdPath starts with the surface name.
+// Commands are visited in name order, so the result is deterministic. A function
+// referenced by several commands is listed once per reference.
 func (g *Generator) CollectFunctions() []FuncInfo {
 	var funcs []FuncInfo
 
 	// Check root command
-	if g.config.Root.RunFunc != "" {
-		funcs = append(funcs, FuncInfo{
-			Name:    g.config.Root.RunFunc,
-			Flags:   g.config.Root.Flags,
-			Args:    g.config.Root.Args,
-			CmdPath: g.config.Root.Use,
-		})
-	}
+	funcs = append(funcs, commandFunctions(g.config.Root, g.config.Root.Use)...)
 
 	// Collect from all commands recursively
-	for _, cmdConfig := range g.config.Commands {
-		funcs = append(funcs, g.collectFromCommand(cmdConfig, "")...)
+	for _, name := range sortedCommandNames(g.config.Commands) {
+		funcs = append(funcs, g.collectFromCommand(g.config.Commands[name], "")...)
+	}
+
+	// Every surface can be selected at runtime, so all their functions are needed
+	for _, surface := range sortedKeys(g.config.Surfaces) {
+		cmds := g.config.Surfaces[surface].Commands
+		for _, name := range sortedCommandNames(cmds) {
+			funcs = append(funcs, g.collectFromCommand(cmds[name], surface)...)
+		}
 	}
 
 	return funcs
 }
 
+// visitCommands calls visit for the root command and every command below it,
+// including the commands of every surface.
+func (g *Generator) visitCommands(visit func(cmd CommandConfig)) {
+	var walk func(cmds map[string]CommandConfig)
+	walk = func(cmds map[string]CommandConfig) {
+		for _, name := range sortedCommandNames(cmds) {
+			visit(cmds[name])
+			walk(cmds[name].Commands)
+		}
+	}
+	visit(g.config.Root)
+	walk(g.config.Commands)
+	for _, surface := range sortedKeys(g.config.Surfaces) {
+		walk(g.config.Surfaces[surface].Commands)
+	}
+}
+
+// configuresBuilder reports whether the generated code has a configureBuilder
+// stub, for the custom flag types or quotas of the commands.
+func (g *Generator) configuresBuilder() bool {
+	return len(g.customFlagTypes()) > 0 || len(g.quotas()) > 0
+}
+
+// customFlagTypes returns the sorted flag types of the commands that are not
+// built in, which need to be registered in the generated configureBuilder.
+func (g *Generator) customFlagTypes() []string {
+	var types []string
+	g.visitCommands(func(cmd CommandConfig) {
+		for _, flag := range cmd.Flags {
+			if flag.Type != "" && !slices.Contains(SupportedFlagTypes, flag.Type) && !slices.Contains(types, flag.Type) {
+				types = append(types, flag.Type)
+			}
+		}
+	})
+	sort.Strings(types)
+	return types
+}
+
+// quotas returns the sorted quotas declared by the commands, which need a quota
+// checker set in the generated configureBuilder.
+func (g *Generator) quotas() []string {
+	var quotas []string
+	g.visitCommands(func(cmd CommandConfig) {
+		if cmd.Quota != "" && !slices.Contains(quotas, cmd.Quota) {
+			quotas = append(quotas, cmd.Quota)
+		}
+	})
+	sort.Strings(quotas)
+	return quotas
+}
+
+// usesWebhooks reports whether a command notifies or an event sink posts to a
+// webhook, which needs the generated main.go to set a webhook poster.
+func (g *Generator) usesWebhooks() bool {
+	for _, sink := range g.config.Events {
+		if sink.Type == EventSinkWebhook {
+			return true
+		}
+	}
+	uses := false
+	g.visitCommands(func(cmd CommandConfig) {
+		if cmd.Notify != nil && cmd.Notify.Via == NotifierWebhook {
+			uses = true
+		}
+	})
+	return uses
+}
+
+// uniqueFunctions returns funcs with only the first reference of each function name.
+func uniqueFunctions(funcs []FuncInfo) []FuncInfo {
+	seen := make(map[string]bool, len(funcs))
+	var unique []FuncInfo
+	for _, fn := range funcs {
+		if seen[fn.Name] {
+			continue
+		}
+		seen[fn.Name] = true
+		unique = append(unique, fn)
+	}
+	return unique
+}
+
+// ConfigWarnings returns the validation warnings of the loaded config
+// (see ValidateConfigWithWarnings).
+func (g *Generator) ConfigWarnings() []string {
+	return configWarnings(g.config)
+}
+
+// Warnings reports functions referenced by more than one command. The generated
+// stub of a shared function only reads the flags and args of its first command, and
+// a function used with different kinds (for example as run_func and as a derive
+// function) cannot be implemented by a single Go function.
+func (g *Generator) Warnings() []string {
+	refs := make(map[string][]FuncInfo)
+	var names []string
+	for _, fn := range g.CollectFunctions() {
+		if _, exists := refs[fn.Name]; !exists {
+			names = append(names, fn.Name)
+		}
+		refs[fn.Name] = append(refs[fn.Name], fn)
+	}
+
+	var warnings []string
+	for _, name := range names {
+		fns := refs[name]
+		if len(fns) < 2 {
+			continue
+		}
+		var paths, kinds []string
+		for _, fn := range fns {
+			paths = append(paths, fmt.Sprintf("%q", fn.CmdPath))
+			if !slices.Contains(kinds, fn.Kind) {
+				kinds = append(kinds, fn.Kind)
+			}
+		}
+		if len(kinds) > 1 {
+			warnings = append(warnings, fmt.Sprintf("function %s is used as %s function by %s; one Go function cannot have all these signatures",
+				name, strings.Join(kinds, " and "), strings.Join(paths, ", ")))
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("function %s is shared by %s; its stub only reads the flags and args of %s",
+			name, strings.Join(paths, ", "), paths[0]))
+	}
+	return warnings
+}
+
 func (g *Generator) collectFromCommand(cmd CommandConfig, parentPath string) []FuncInfo {
 	var funcs []FuncInfo
 
@@ -93,48 +276,161 @@ func (g *Generator) collectFromCommand(cmd CommandConfig, parentPath string) []F
 		cmdPath = parentPath + " > " + cmd.Use
 	}
 
+	funcs = append(funcs, commandFunctions(cmd, cmdPath)...)
+
+	// Recurse into subcommands
+	for _, name := range sortedCommandNames(cmd.Commands) {
+		funcs = append(funcs, g.collectFromCommand(cmd.Commands[name], cmdPath)...)
+	}
+
+	return funcs
+}
+
+// commandFunctions returns the functions referenced by a single command.
+// Derive functions and the validator come before the handler, matching the order they run in.
+func commandFunctions(cmd CommandConfig, cmdPath string) []FuncInfo {
+	var funcs []FuncInfo
+	for _, d := range cmd.Derived {
+		funcs = append(funcs, FuncInfo{
+			Name:    d.Func,
+			Kind:    FuncKindDerive,
+			CmdPath: cmdPath,
+			Inputs:  d.From,
+		})
+	}
+	if cmd.ValidateFunc != "" {
+		funcs = append(funcs, FuncInfo{
+			Name:    cmd.ValidateFunc,
+			Kind:    FuncKindValidate,
+			Flags:   cmd.Flags,
+			Args:    cmd.Args,
+			CmdPath: cmdPath,
+		})
+	}
 	if cmd.RunFunc != "" {
-		// Collect flags including parent persistent flags
 		funcs = append(funcs, FuncInfo{
 			Name:    cmd.RunFunc,
+			Kind:    FuncKindRun,
 			Flags:   cmd.Flags,
 			Args:    cmd.Args,
 			CmdPath: cmdPath,
 		})
 	}
-
-	// Recurse into subcommands
-	for _, subCmd := range cmd.Commands {
-		funcs = append(funcs, g.collectFromCommand(subCmd, cmdPath)...)
+	if cmd.UndoFunc != "" {
+		funcs = append(funcs, FuncInfo{
+			Name:    cmd.UndoFunc,
+			Kind:    FuncKindUndo,
+			Flags:   cmd.Flags,
+			Args:    cmd.Args,
+			CmdPath: cmdPath,
+		})
+	}
+	if cmd.DynamicCommandsFunc != "" {
+		funcs = append(funcs, FuncInfo{
+			Name:    cmd.DynamicCommandsFunc,
+			Kind:    FuncKindDynamic,
+			CmdPath: cmdPath,
+		})
+	}
+	if cmd.CompletionFunc != "" {
+		funcs = append(funcs, FuncInfo{
+			Name:    cmd.CompletionFunc,
+			Kind:    FuncKindComplete,
+			CmdPath: cmdPath,
+		})
+	}
+	for _, flag := range cmd.Flags {
+		if flag.CompletionFunc != "" {
+			funcs = append(funcs, FuncInfo{
+				Name:    flag.CompletionFunc,
+				Kind:    FuncKindComplete,
+				Flags:   []FlagConfig{flag},
+				CmdPath: cmdPath,
+			})
+		}
 	}
-
 	return funcs
 }
 
-const handlerTemplate = `// Code generated by cobrayaml. DO NOT EDIT.
+const handlerTemplate = `{{.LicenseHeader}}// Code generated by cobrayaml. DO NOT EDIT.
+// cobrayaml schema version: {{.SchemaVersion}}
 // You can customize the function bodies below.
 
 package {{.PackageName}}
 
 import (
-	"github.com/spf13/cobra"
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
 )
 
 {{range .Functions}}
+{{- if eq .Kind "derive"}}
+// {{.Name}} computes a derived value of the "{{.CmdPath}}" command
+func {{.Name}}(inputs map[string]any) (any, error) {
+	// Inputs: {{join .Inputs ", "}}
+	// TODO: Compute the derived value
+	return nil, nil
+}
+{{else if eq .Kind "dynamic"}}
+// {{.Name}} returns the subcommands of the "{{.CmdPath}}" command resolved at runtime
+func {{.Name}}() (map[string]cobrayaml.CommandConfig, error) {
+	// TODO: Return one command definition per subcommand, keyed by name
+	return nil, nil
+}
+{{else if eq .Kind "complete"}}
+{{- if .Flags}}
+// {{.Name}} completes the --{{(index .Flags 0).Name}} flag of the "{{.CmdPath}}" command
+{{- else}}
+// {{.Name}} completes the arguments of the "{{.CmdPath}}" command
+{{- end}}
+func {{.Name}}(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	// TODO: Return the values matching toComplete
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+{{else}}
+{{- if eq .Kind "validate"}}
+// {{.Name}} validates the flags and args of the "{{.CmdPath}}" command
+{{- else if eq .Kind "undo"}}
+// {{.Name}} reverts a run of the "{{.CmdPath}}" command with the same flags and args
+{{- else}}
 // {{.Name}} handles the "{{.CmdPath}}" command
+{{- end}}
 func {{.Name}}(cmd *cobra.Command, args []string) error {
 {{- if or .Flags .Args}}
 	// Auto-generated flag/arg getters
 {{- end}}
 {{- range .Flags}}
-{{- if eq .Type "string"}}
+{{- if enumType .}}
+	{{.Name | toCamelCase}} := get{{enumType .}}(cmd)
+{{- else if or (eq .Type "string") (eq .Type "file") (eq .Type "dir")}}
 	{{.Name | toCamelCase}}, _ := cmd.Flags().GetString("{{.Name}}")
 {{- else if eq .Type "bool"}}
 	{{.Name | toCamelCase}}, _ := cmd.Flags().GetBool("{{.Name}}")
 {{- else if eq .Type "int"}}
 	{{.Name | toCamelCase}}, _ := cmd.Flags().GetInt("{{.Name}}")
+{{- else if eq .Type "uint"}}
+	{{.Name | toCamelCase}}, _ := cmd.Flags().GetUint("{{.Name}}")
+{{- else if eq .Type "uint64"}}
+	{{.Name | toCamelCase}}, _ := cmd.Flags().GetUint64("{{.Name}}")
 {{- else if eq .Type "stringSlice"}}
 	{{.Name | toCamelCase}}, _ := cmd.Flags().GetStringSlice("{{.Name}}")
+{{- else if eq .Type "stringArray"}}
+	{{.Name | toCamelCase}}, _ := cmd.Flags().GetStringArray("{{.Name}}")
+{{- else if eq .Type "url"}}
+	{{.Name | toCamelCase}}, _ := cobrayaml.GetURL(cmd.Flags(), "{{.Name}}")
+{{- else if eq .Type "ip"}}
+	{{.Name | toCamelCase}}, _ := cmd.Flags().GetIP("{{.Name}}")
+{{- else if eq .Type "cidr"}}
+	{{.Name | toCamelCase}}, _ := cmd.Flags().GetIPNet("{{.Name}}")
+{{- else if eq .Type "duration"}}
+	{{.Name | toCamelCase}}, _ := cmd.Flags().GetDuration("{{.Name}}")
+{{- else if eq .Type "bytesize"}}
+	{{.Name | toCamelCase}}, _ := cobrayaml.GetByteSize(cmd.Flags(), "{{.Name}}")
+{{- else if eq .Type "time"}}
+	{{.Name | toCamelCase}}, _ := cobrayaml.GetTime(cmd.Flags(), "{{.Name}}")
+{{- else}}
+	{{.Name | toCamelCase}} := cmd.Flags().Lookup("{{.Name}}").Value // {{.Type}} registered in configureBuilder
 {{- end}}
 {{- end}}
 {{- if .Args}}
@@ -144,10 +440,18 @@ func {{.Name}}(cmd *cobra.Command, args []string) error {
 {{- end}}
 {{- else if or (eq .Args.Type "min") (eq .Args.Type "any") (eq .Args.Type "range")}}
 	// args contains {{if eq .Args.Type "min"}}at least {{.Args.Min}}{{else if eq .Args.Type "range"}}{{.Args.Min}} to {{.Args.Max}}{{else}}any number of{{end}} argument(s)
+{{- else if eq .Args.Type "only_valid_args"}}
+	// args contains values listed in valid_args
 {{- end}}
 {{- end}}
 
+{{- if eq .Kind "validate"}}
+
+	// TODO: Return an error if the combination of values is invalid
+{{- else}}
+
 	// TODO: Implement your logic here
+{{- end}}
 {{- range .Flags}}
 	_ = {{.Name | toCamelCase}}
 {{- end}}
@@ -162,19 +466,34 @@ func {{.Name}}(cmd *cobra.Command, args []string) error {
 	return nil
 }
 {{end}}
+{{- end}}
+{{- if or .FlagTypes .Quotas}}
+// configureBuilder configures the builder before the commands are built
+func configureBuilder(builder *cobrayaml.CommandBuilder) error {
+{{- if .FlagTypes}}
+	// TODO: Register the flag types {{join .FlagTypes ", "}} with builder.RegisterFlagType
+{{- end}}
+{{- if .Quotas}}
+	// TODO: Check the quotas {{join .Quotas ", "}} with builder.SetQuotaChecker
+{{- end}}
+	return nil
+}
+{{end}}
 `
 
 // GenerateHandlers generates handler function stubs
 func (g *Generator) GenerateHandlers(packageName string) (string, error) {
-	funcs := g.CollectFunctions()
+	funcs := uniqueFunctions(g.CollectFunctions())
 
 	if len(funcs) == 0 {
-		return "", fmt.Errorf("no functions to generate (no run_func defined in YAML)")
+		return "", fmt.Errorf("no functions to generate (no run_func or validate_func defined in YAML)")
 	}
 
 	funcMap := template.FuncMap{
 		"toCamelCase": toCamelCase,
 		"iterate":     iterate,
+		"join":        strings.Join,
+		"enumType":    g.enumTypeName,
 	}
 
 	tmpl, err := template.New("handlers").Funcs(funcMap).Parse(handlerTemplate)
@@ -183,11 +502,21 @@ func (g *Generator) GenerateHandlers(packageName string) (string, error) {
 	}
 
 	data := struct {
-		PackageName string
-		Functions   []FuncInfo
+		LicenseHeader string
+		PackageName   string
+		SchemaVersion int
+		Imports       []string
+		Functions     []FuncInfo
+		FlagTypes     []string
+		Quotas        []string
 	}{
-		PackageName: packageName,
-		Functions:   funcs,
+		LicenseHeader: g.licenseHeader(),
+		PackageName:   packageName,
+		SchemaVersion: SchemaVersion(),
+		Imports:       handlerImports(funcs, g.configuresBuilder()),
+		Functions:     funcs,
+		FlagTypes:     g.customFlagTypes(),
+		Quotas:        g.quotas(),
 	}
 
 	var buf bytes.Buffer
@@ -215,6 +544,36 @@ func (g *Generator) GenerateHandlersToFile(packageName, outputPath string) error
 	return os.WriteFile(outputPath, []byte(code), 0644)
 }
 
+// handlerImports returns the sorted import paths needed by the generated
+// handlers, and by the configureBuilder stub when configure is set.
+func handlerImports(funcs []FuncInfo, configure bool) []string {
+	needsCobra, needsCobrayaml := false, configure
+	for _, fn := range funcs {
+		if fn.Kind == FuncKindDerive {
+			continue
+		}
+		if fn.Kind == FuncKindDynamic {
+			needsCobrayaml = true
+			continue
+		}
+		needsCobra = true
+		for _, flag := range fn.Flags {
+			if flag.Type == FlagTypeURL || flag.Type == FlagTypeByteSize || flag.Type == FlagTypeTime {
+				needsCobrayaml = true
+			}
+		}
+	}
+
+	var imports []string
+	if needsCobrayaml {
+		imports = append(imports, "github.com/S-mishina/cobrayaml")
+	}
+	if needsCobra {
+		imports = append(imports, "github.com/spf13/cobra")
+	}
+	return imports
+}
+
 // toCamelCase converts kebab-case or snake_case to camelCase
 func toCamelCase(s string) string {
 	s = strings.ReplaceAll(s, "-", "_")
@@ -243,7 +602,8 @@ func iterate(n int) []int {
 	return result
 }
 
-const mainTemplate = `// Code generated by cobrayaml. DO NOT EDIT.
+const mainTemplate = `{{.LicenseHeader}}// Code generated by cobrayaml. DO NOT EDIT.
+// cobrayaml schema version: {{.SchemaVersion}}
 
 package {{.PackageName}}
 
@@ -252,33 +612,78 @@ import (
 	"os"
 
 	"github.com/S-mishina/cobrayaml"
+{{- if .Webhooks}}
+	"github.com/S-mishina/cobrayaml/webhook"
+{{- end}}
+	"github.com/spf13/cobra"
 )
 
 //go:embed {{.ConfigPath}}
 var commandsYAML string
 
+// Inputs this file was generated from, printed by the hidden build-info command.
+const (
+	commandsYAMLSHA256 = "{{.ConfigSHA256}}"
+	cobrayamlVersion   = "{{.Version}}"
+)
+{{if .Include}}
+// includedCommands selects the commands of this edition (cobrayaml gen --include).
+var includedCommands = []string{ {{- range $i, $p := .Include}}{{if $i}}, {{end}}{{printf "%q" $p}}{{end -}} }
+{{end}}
 func main() {
-	builder, err := cobrayaml.NewCommandBuilderFromString(commandsYAML)
+	builder, err := newBuilder()
 	if err != nil {
 		panic(err)
 	}
 
-{{range .Functions}}	builder.RegisterFunction("{{.Name}}", {{.Name}})
-{{end}}
-	rootCmd, err := builder.BuildRootCommand()
+	if err := builder.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// newRootCommand builds the CLI from commands.yaml with the handlers registered.
+func newRootCommand() (*cobra.Command, error) {
+	builder, err := newBuilder()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return builder.BuildRootCommand()
+}
 
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+// newBuilder loads commands.yaml and registers the handlers.
+func newBuilder() (*cobrayaml.CommandBuilder, error) {
+	builder, err := cobrayaml.NewCommandBuilderFromString(commandsYAML)
+	if err != nil {
+		return nil, err
 	}
+	builder.SetBuildInfo(cobrayaml.BuildInfo{
+		ConfigSHA256: commandsYAMLSHA256,
+		Version:      cobrayamlVersion,
+	})
+{{if .Webhooks}}	builder.SetWebhookPoster(webhook.Post)
+{{end}}{{if .Include}}	if err := builder.Include(includedCommands...); err != nil {
+		return nil, err
+	}
+{{end}}
+{{if .Functions}}	builder.RegisterFunctions(map[string]any{
+{{range .Functions}}		"{{.Name}}": {{.Name}},
+{{end}}	})
+
+{{end}}{{if .Configure}}	if err := configureBuilder(builder); err != nil {
+		return nil, err
+	}
+
+{{end}}	return builder, nil
 }
 `
 
-// GenerateMain generates main.go that wires up the CLI
+// GenerateMain generates main.go that wires up the CLI.
+// Each function referenced in YAML is registered once; see SetSortRegistrations for the order.
 func (g *Generator) GenerateMain(packageName, configPath string) (string, error) {
-	funcs := g.CollectFunctions()
+	funcs := uniqueFunctions(g.CollectFunctions())
+	if g.sortRegistrations {
+		sort.Slice(funcs, func(i, j int) bool { return funcs[i].Name < funcs[j].Name })
+	}
 
 	tmpl, err := template.New("main").Parse(mainTemplate)
 	if err != nil {
@@ -286,13 +691,27 @@ func (g *Generator) GenerateMain(packageName, configPath string) (string, error)
 	}
 
 	data := struct {
-		PackageName string
-		ConfigPath  string
-		Functions   []FuncInfo
+		LicenseHeader string
+		PackageName   string
+		SchemaVersion int
+		ConfigPath    string
+		ConfigSHA256  string
+		Version       string
+		Include       []string
+		Functions     []FuncInfo
+		Configure     bool
+		Webhooks      bool
 	}{
-		PackageName: packageName,
-		ConfigPath:  configPath,
-		Functions:   funcs,
+		LicenseHeader: g.licenseHeader(),
+		PackageName:   packageName,
+		SchemaVersion: SchemaVersion(),
+		ConfigPath:    configPath,
+		ConfigSHA256:  g.configHash,
+		Version:       moduleVersion(),
+		Include:       g.include,
+		Functions:     funcs,
+		Configure:     g.configuresBuilder(),
+		Webhooks:      g.usesWebhooks(),
 	}
 
 	var buf bytes.Buffer
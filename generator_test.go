@@ -1,12 +1,20 @@
 package cobrayaml
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 )
 
+// countRegistrations returns how often the generated main.go registers the function fn.
+func countRegistrations(code, fn string) int {
+	return len(regexp.MustCompile(`"`+fn+`":\s+`+fn+`,`).FindAllString(code, -1))
+}
+
 func TestNewGeneratorFromString(t *testing.T) {
 	yamlContent := `
 name: test-tool
@@ -156,6 +164,15 @@ commands:
       - name: tags
         type: stringSlice
         usage: Tags list
+      - name: header
+        type: stringArray
+        usage: Headers
+      - name: replicas
+        type: uint
+        usage: Replicas
+      - name: max-bytes
+        type: uint64
+        usage: Maximum size
     args:
       type: exact
       count: 2
@@ -175,6 +192,11 @@ commands:
 		t.Error("generated code should contain 'package main'")
 	}
 
+	// Check schema version header
+	if !strings.Contains(code, fmt.Sprintf("// cobrayaml schema version: %d\n", SchemaVersion())) {
+		t.Error("generated code should contain the schema version header")
+	}
+
 	// Check function signature
 	if !strings.Contains(code, "func runAdd(cmd *cobra.Command, args []string) error") {
 		t.Error("generated code should contain runAdd function")
@@ -197,6 +219,18 @@ commands:
 		t.Error("generated code should contain GetStringSlice for tags flag")
 	}
 
+	if !strings.Contains(code, `cmd.Flags().GetStringArray("header")`) {
+		t.Error("generated code should contain GetStringArray for header flag")
+	}
+
+	if !strings.Contains(code, `cmd.Flags().GetUint("replicas")`) {
+		t.Error("generated code should contain GetUint for replicas flag")
+	}
+
+	if !strings.Contains(code, `cmd.Flags().GetUint64("max-bytes")`) {
+		t.Error("generated code should contain GetUint64 for max-bytes flag")
+	}
+
 	// Check args extraction
 	if !strings.Contains(code, "arg0 := args[0]") {
 		t.Error("generated code should extract arg0")
@@ -413,6 +447,11 @@ commands:
 		t.Error("generated code should contain 'package main'")
 	}
 
+	// Check schema version header
+	if !strings.Contains(code, fmt.Sprintf("// cobrayaml schema version: %d\n", SchemaVersion())) {
+		t.Error("generated code should contain the schema version header")
+	}
+
 	// Check imports
 	if !strings.Contains(code, `"github.com/S-mishina/cobrayaml"`) {
 		t.Error("generated code should import cobrayaml")
@@ -429,10 +468,10 @@ commands:
 	}
 
 	// Check function registrations
-	if !strings.Contains(code, `RegisterFunction("runHello", runHello)`) {
+	if countRegistrations(code, "runHello") == 0 {
 		t.Error("generated code should register runHello")
 	}
-	if !strings.Contains(code, `RegisterFunction("runGoodbye", runGoodbye)`) {
+	if countRegistrations(code, "runGoodbye") == 0 {
 		t.Error("generated code should register runGoodbye")
 	}
 
@@ -442,7 +481,7 @@ commands:
 	}
 
 	// Check Execute call
-	if !strings.Contains(code, "rootCmd.Execute()") {
+	if !strings.Contains(code, "builder.Execute()") {
 		t.Error("generated code should call Execute")
 	}
 }
@@ -472,14 +511,54 @@ commands:
 	}
 
 	// Check both root and sub command functions are registered
-	if !strings.Contains(code, `RegisterFunction("runRoot", runRoot)`) {
+	if countRegistrations(code, "runRoot") == 0 {
 		t.Error("generated code should register runRoot")
 	}
-	if !strings.Contains(code, `RegisterFunction("runSub", runSub)`) {
+	if countRegistrations(code, "runSub") == 0 {
 		t.Error("generated code should register runSub")
 	}
 }
 
+func TestGenerator_GenerateMain_Webhooks(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  backup:
+    use: backup
+    short: Backup
+    run_func: runBackup
+%s`
+	tests := []struct {
+		name  string
+		extra string
+		want  bool
+	}{
+		{name: "no webhooks", want: false},
+		{name: "webhook notifier", extra: "    notify:\n      on: [failure]\n      via: webhook\n      url: https://example.com/hook\n", want: true},
+		{name: "webhook event sink", extra: "events:\n  - type: webhook\n    url: https://example.com/events\n", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen, err := NewGeneratorFromString(fmt.Sprintf(yamlContent, tt.extra))
+			if err != nil {
+				t.Fatalf("NewGeneratorFromString() error = %v", err)
+			}
+			code, err := gen.GenerateMain("main", "commands.yaml")
+			if err != nil {
+				t.Fatalf("GenerateMain() error = %v", err)
+			}
+			for _, want := range []string{`"github.com/S-mishina/cobrayaml/webhook"`, "builder.SetWebhookPoster(webhook.Post)"} {
+				if got := strings.Contains(code, want); got != tt.want {
+					t.Errorf("generated code contains %s = %v, want %v", want, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
 func TestGenerator_GenerateMainToFile(t *testing.T) {
 	yamlContent := `
 name: test
@@ -618,3 +697,466 @@ commands:
 		t.Error("expected error when writing to invalid path")
 	}
 }
+
+func TestGenerator_ValidateFunc(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  report:
+    use: report
+    short: Build a report
+    validate_func: validateReport
+    run_func: runReport
+    flags:
+      - name: start
+        type: string
+        usage: Start date
+      - name: end
+        type: string
+        usage: End date
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	funcs := gen.CollectFunctions()
+	if len(funcs) != 2 {
+		t.Fatalf("expected 2 functions, got %d", len(funcs))
+	}
+	if funcs[0].Name != "validateReport" || funcs[0].Kind != FuncKindValidate {
+		t.Errorf("funcs[0] = %s (%s), want validateReport (%s)", funcs[0].Name, funcs[0].Kind, FuncKindValidate)
+	}
+	if funcs[1].Name != "runReport" || funcs[1].Kind != FuncKindRun {
+		t.Errorf("funcs[1] = %s (%s), want runReport (%s)", funcs[1].Name, funcs[1].Kind, FuncKindRun)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+
+	expected := []string{
+		`// validateReport validates the flags and args of the "report" command`,
+		"func validateReport(cmd *cobra.Command, args []string) error {",
+		`start, _ := cmd.Flags().GetString("start")`,
+		`end, _ := cmd.Flags().GetString("end")`,
+		"// TODO: Return an error if the combination of values is invalid",
+		`// runReport handles the "report" command`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(code, exp) {
+			t.Errorf("generated code should contain %q\nGot:\n%s", exp, code)
+		}
+	}
+
+	mainCode, err := gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+	if countRegistrations(mainCode, "validateReport") == 0 {
+		t.Error("generated main should register validateReport")
+	}
+}
+
+func TestGenerator_CompletionFunc(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  logs:
+    use: logs <namespace>
+    short: Show logs
+    run_func: runLogs
+    completion_func: completeNamespaces
+    flags:
+      - name: container
+        type: string
+        usage: Container
+        completion_func: completeContainers
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	funcs := gen.CollectFunctions()
+	if len(funcs) != 3 || funcs[1].Name != "completeNamespaces" || funcs[2].Name != "completeContainers" || funcs[2].Kind != FuncKindComplete {
+		t.Fatalf("CollectFunctions() = %+v, want runLogs, completeNamespaces and completeContainers (%s)", funcs, FuncKindComplete)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	expected := []string{
+		`// completeNamespaces completes the arguments of the "logs <namespace>" command`,
+		"func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {",
+		"return nil, cobra.ShellCompDirectiveNoFileComp",
+		`// completeContainers completes the --container flag of the "logs <namespace>" command`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(code, exp) {
+			t.Errorf("generated code should contain %q\nGot:\n%s", exp, code)
+		}
+	}
+}
+
+func TestGenerator_UndoFunc(t *testing.T) {
+	yamlContent := `
+name: test
+history: true
+root:
+  use: test
+  short: Test command
+commands:
+  scale:
+    use: scale
+    short: Scale
+    run_func: runScale
+    undo_func: undoScale
+    flags:
+      - name: replicas
+        type: int
+        usage: Replicas
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	expected := []string{
+		`// undoScale reverts a run of the "scale" command with the same flags and args`,
+		"func undoScale(cmd *cobra.Command, args []string) error {",
+		`replicas, _ := cmd.Flags().GetInt("replicas")`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(code, exp) {
+			t.Errorf("generated code should contain %q\nGot:\n%s", exp, code)
+		}
+	}
+}
+
+func TestGenerator_DeriveFunc(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    flags:
+      - name: region
+        type: string
+        usage: Region
+    derived:
+      - name: endpoint
+        from: [region, env]
+        func: computeEndpoint
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+
+	expected := []string{
+		`// computeEndpoint computes a derived value of the "deploy" command`,
+		"func computeEndpoint(inputs map[string]any) (any, error) {",
+		"// Inputs: region, env",
+		"func runDeploy(cmd *cobra.Command, args []string) error {",
+	}
+	for _, exp := range expected {
+		if !strings.Contains(code, exp) {
+			t.Errorf("generated code should contain %q\nGot:\n%s", exp, code)
+		}
+	}
+}
+
+func TestGenerator_SemanticFlagGetters(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+  run_func: runRoot
+  flags:
+    - name: endpoint
+      type: url
+      usage: Endpoint
+    - name: bind
+      type: ip
+      usage: Bind
+    - name: subnet
+      type: cidr
+      usage: Subnet
+    - name: timeout
+      type: duration
+      usage: Timeout
+    - name: max-size
+      type: bytesize
+      usage: Max size
+    - name: since
+      type: time
+      usage: Since
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+
+	expected := []string{
+		`"github.com/S-mishina/cobrayaml"`,
+		`endpoint, _ := cobrayaml.GetURL(cmd.Flags(), "endpoint")`,
+		`bind, _ := cmd.Flags().GetIP("bind")`,
+		`subnet, _ := cmd.Flags().GetIPNet("subnet")`,
+		`timeout, _ := cmd.Flags().GetDuration("timeout")`,
+		`maxSize, _ := cobrayaml.GetByteSize(cmd.Flags(), "max-size")`,
+		`since, _ := cobrayaml.GetTime(cmd.Flags(), "since")`,
+	}
+	for _, exp := range expected {
+		if !strings.Contains(code, exp) {
+			t.Errorf("generated code should contain %q\nGot:\n%s", exp, code)
+		}
+	}
+}
+
+func TestHandlerImports(t *testing.T) {
+	tests := []struct {
+		name  string
+		funcs []FuncInfo
+		want  []string
+	}{
+		{
+			name:  "plain handler",
+			funcs: []FuncInfo{{Kind: FuncKindRun, Flags: []FlagConfig{{Type: FlagTypeString}}}},
+			want:  []string{"github.com/spf13/cobra"},
+		},
+		{
+			name:  "cobrayaml getter",
+			funcs: []FuncInfo{{Kind: FuncKindRun, Flags: []FlagConfig{{Type: FlagTypeByteSize}}}},
+			want:  []string{"github.com/S-mishina/cobrayaml", "github.com/spf13/cobra"},
+		},
+		{
+			name:  "derive only",
+			funcs: []FuncInfo{{Kind: FuncKindDerive}},
+			want:  nil,
+		},
+		{
+			name:  "dynamic commands only",
+			funcs: []FuncInfo{{Kind: FuncKindDynamic}},
+			want:  []string{"github.com/S-mishina/cobrayaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := handlerImports(tt.funcs, false)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handlerImports() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+const sharedFuncsYAML = `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  remove:
+    use: remove <name>
+    short: Remove item
+    run_func: runRemove
+    args:
+      type: exact
+      count: 1
+  delete:
+    use: delete
+    short: Delete item
+    run_func: runRemove
+  zap:
+    use: zap
+    short: Zap
+    run_func: runAdd
+  add:
+    use: add
+    short: Add item
+    run_func: runAdd
+    flags:
+      - name: name
+        type: string
+        usage: Name
+    derived:
+      - name: id
+        from: [name]
+        func: runRemove
+`
+
+func TestGenerator_SharedFunctions(t *testing.T) {
+	gen, err := NewGeneratorFromString(sharedFuncsYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	code, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	if n := strings.Count(code, "func runAdd("); n != 1 {
+		t.Errorf("runAdd should be generated once, got %d", n)
+	}
+
+	mainCode, err := gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+	if n := countRegistrations(mainCode, "runRemove"); n != 1 {
+		t.Errorf("runRemove should be registered once, got %d", n)
+	}
+
+	want := []string{
+		`function runRemove is used as derive and run function by "add", "delete", "remove <name>"; one Go function cannot have all these signatures`,
+		`function runAdd is shared by "add", "zap"; its stub only reads the flags and args of "add"`,
+	}
+	if got := gen.Warnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Warnings() = %q, want %q", got, want)
+	}
+}
+
+func TestGenerator_GenerateMain_SortRegistrations(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  alpha:
+    use: alpha
+    short: Alpha
+    run_func: runZulu
+  bravo:
+    use: bravo
+    short: Bravo
+    run_func: runAlpha
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	zuluFirst := func() bool {
+		code, err := gen.GenerateMain("main", "commands.yaml")
+		if err != nil {
+			t.Fatalf("GenerateMain() error = %v", err)
+		}
+		return strings.Index(code, `"runZulu"`) < strings.Index(code, `"runAlpha"`)
+	}
+
+	// By default registrations follow the command tree (alpha, then bravo).
+	if !zuluFirst() {
+		t.Error("runZulu (command alpha) should be registered before runAlpha (command bravo)")
+	}
+
+	gen.SetSortRegistrations(true)
+	if zuluFirst() {
+		t.Error("sorted registrations should put runAlpha before runZulu")
+	}
+}
+
+func TestGenerator_DeterministicOutput(t *testing.T) {
+	yamlContent := `
+name: test
+root:
+  use: test
+  short: Test command
+commands:
+  zulu:
+    use: zulu
+    short: Zulu
+    run_func: runZulu
+    flags:
+      - name: level
+        type: string
+        usage: Level
+        allowed_values: [low, high]
+  alpha:
+    use: alpha
+    short: Alpha
+    run_func: runAlpha
+    commands:
+      nested-b:
+        use: nested-b
+        short: Nested B
+        run_func: runNestedB
+      nested-a:
+        use: nested-a
+        short: Nested A
+        run_func: runNestedA
+        flags:
+          - name: mode
+            type: string
+            usage: Mode
+            allowed_values: [fast, slow]
+  mike:
+    use: mike
+    short: Mike
+    run_func: runMike
+  echo:
+    use: echo
+    short: Echo
+    validate_func: validateEcho
+    run_func: runEcho
+`
+	generate := func() map[string]string {
+		gen, err := NewGeneratorFromString(yamlContent)
+		if err != nil {
+			t.Fatalf("NewGeneratorFromString() error = %v", err)
+		}
+		gen.SetEnumTypes(true)
+
+		outputs := map[string]string{}
+		var errs [5]error
+		outputs["init template"], errs[4] = GenerateInitTemplate("test")
+		outputs["handlers"], errs[0] = gen.GenerateHandlers("main")
+		outputs["main"], errs[1] = gen.GenerateMain("main", "commands.yaml")
+		outputs["enums"], errs[2] = gen.GenerateEnums("main")
+		outputs["docs"], errs[3] = gen.GenerateDocs()
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("generation error = %v", err)
+			}
+		}
+		return outputs
+	}
+
+	first := generate()
+	for i := 0; i < 20; i++ {
+		for name, output := range generate() {
+			if output != first[name] {
+				t.Fatalf("%s output differs between runs:\n%s\n---\n%s", name, first[name], output)
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package cobrayaml
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HandlerCheck is the result of comparing the functions referenced in YAML with
+// the RegisterFunction calls found in Go source.
+type HandlerCheck struct {
+	// Unregistered lists functions referenced in YAML that are never passed to RegisterFunction.
+	Unregistered []FuncInfo
+	// Orphans lists names passed to RegisterFunction that no YAML entry references.
+	Orphans []string
+}
+
+// OK reports whether every referenced function is registered and every registration is referenced.
+func (c *HandlerCheck) OK() bool {
+	return len(c.Unregistered) == 0 && len(c.Orphans) == 0
+}
+
+// CheckHandlers parses the Go files in the given directories and compares their
+// RegisterFunction and RegisterFunctions calls with the functions referenced in YAML (run_func,
+// validate_func, undo_func, derive, dynamic commands and completion functions). A directory ending in
+// "/..." is searched recursively, like a Go package pattern. Only calls with a
+// string literal name are recognized; test files are ignored.
+func (g *Generator) CheckHandlers(dirs ...string) (*HandlerCheck, error) {
+	registered, err := findRegistrations(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &HandlerCheck{}
+	referenced := make(map[string]bool)
+	for _, fn := range g.CollectFunctions() {
+		if referenced[fn.Name] {
+			continue
+		}
+		referenced[fn.Name] = true
+		if !registered[fn.Name] {
+			check.Unregistered = append(check.Unregistered, fn)
+		}
+	}
+	for name := range registered {
+		if !referenced[name] {
+			check.Orphans = append(check.Orphans, name)
+		}
+	}
+	sort.Slice(check.Unregistered, func(i, j int) bool {
+		return check.Unregistered[i].Name < check.Unregistered[j].Name
+	})
+	sort.Strings(check.Orphans)
+	return check, nil
+}
+
+// findRegistrations returns the names passed to RegisterFunction,
+// RegisterFunctions and RegisterCompletionFunc in the Go files of dirs.
+func findRegistrations(dirs []string) (map[string]bool, error) {
+	registered := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, dir := range dirs {
+		files, err := goFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+			if err != nil {
+				return nil, err
+			}
+			ast.Inspect(file, func(node ast.Node) bool {
+				for _, name := range registeredNames(node) {
+					registered[name] = true
+				}
+				return true
+			})
+		}
+	}
+	return registered, nil
+}
+
+// registeredNames returns the names of a RegisterFunction("name", fn) or
+// RegisterCompletionFunc("name", fn) call, or the string literal keys of a
+// RegisterFunctions(map[string]any{"name": fn}) call.
+func registeredNames(node ast.Node) []string {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	switch {
+	case (sel.Sel.Name == "RegisterFunction" || sel.Sel.Name == "RegisterCompletionFunc") && len(call.Args) == 2:
+		if name, ok := stringLiteral(call.Args[0]); ok {
+			return []string{name}
+		}
+	case sel.Sel.Name == "RegisterFunctions" && len(call.Args) == 1:
+		lit, ok := call.Args[0].(*ast.CompositeLit)
+		if !ok {
+			return nil
+		}
+		var names []string
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if name, ok := stringLiteral(kv.Key); ok {
+					names = append(names, name)
+				}
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// stringLiteral returns the value of expr if it is a string literal.
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	name, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// goFiles lists the non-test Go files in dir, or below it when dir ends in "/...".
+// Hidden directories, vendor and testdata are skipped when searching recursively.
+func goFiles(dir string) ([]string, error) {
+	root, recursive := strings.CutSuffix(dir, "...")
+	if recursive {
+		root = filepath.Clean(root)
+	}
+
+	var files []string
+	if !recursive {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && isGoSource(entry.Name()) {
+				files = append(files, filepath.Join(dir, entry.Name()))
+			}
+		}
+		return files, nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isGoSource(entry.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// isGoSource reports whether name is a Go source file other than a test.
+func isGoSource(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go")
+}
@@ -0,0 +1,147 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGenerator_CheckHandlers(t *testing.T) {
+	yamlContent := `
+name: check-test
+root:
+  use: check-test
+  short: Check test
+commands:
+  add:
+    use: add
+    short: Add
+    run_func: runAdd
+    validate_func: validateAdd
+  remove:
+    use: remove
+    short: Remove
+    run_func: runRemove
+  delete:
+    use: delete
+    short: Delete
+    run_func: runRemove
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	writeGoFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeGoFile(filepath.Join(dir, "main.go"), `package main
+
+func main() {
+	builder, _ := cobrayaml.NewCommandBuilderFromString(commandsYAML)
+	builder.RegisterFunction("runAdd", runAdd)
+	builder.RegisterFunction("runOld", runOld)
+}
+`)
+	writeGoFile(filepath.Join(dir, "cmd", "remove.go"), `package cmd
+
+func register(builder *cobrayaml.CommandBuilder) {
+	builder.RegisterFunction("runRemove", func(cmd *cobra.Command, args []string) error { return nil })
+}
+`)
+	writeGoFile(filepath.Join(dir, "main_test.go"), `package main
+
+func init() { builder.RegisterFunction("validateAdd", nil) }
+`)
+	writeGoFile(filepath.Join(dir, "testdata", "fixture.go"), `package fixture
+
+func init() { builder.RegisterFunction("validateAdd", nil) }
+`)
+
+	tests := []struct {
+		name             string
+		dirs             []string
+		wantUnregistered []string
+		wantOrphans      []string
+	}{
+		{
+			name:             "single directory",
+			dirs:             []string{dir},
+			wantUnregistered: []string{"runRemove", "validateAdd"},
+			wantOrphans:      []string{"runOld"},
+		},
+		{
+			name:             "recursive",
+			dirs:             []string{filepath.Join(dir, "...")},
+			wantUnregistered: []string{"validateAdd"},
+			wantOrphans:      []string{"runOld"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := gen.CheckHandlers(tt.dirs...)
+			if err != nil {
+				t.Fatalf("CheckHandlers() error = %v", err)
+			}
+			var unregistered []string
+			for _, fn := range check.Unregistered {
+				unregistered = append(unregistered, fn.Name)
+			}
+			if !reflect.DeepEqual(unregistered, tt.wantUnregistered) {
+				t.Errorf("Unregistered = %v, want %v", unregistered, tt.wantUnregistered)
+			}
+			if !reflect.DeepEqual(check.Orphans, tt.wantOrphans) {
+				t.Errorf("Orphans = %v, want %v", check.Orphans, tt.wantOrphans)
+			}
+			if check.OK() {
+				t.Error("OK() = true, want false")
+			}
+		})
+	}
+}
+
+func TestGenerator_CheckHandlers_GeneratedMain(t *testing.T) {
+	gen, err := NewGeneratorFromString(ExampleCommandsYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	code, err := gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check, err := gen.CheckHandlers(dir)
+	if err != nil {
+		t.Fatalf("CheckHandlers() error = %v", err)
+	}
+	if !check.OK() {
+		t.Errorf("generated main.go should pass the check, got %+v", check)
+	}
+}
+
+func TestGenerator_CheckHandlers_ParseError(t *testing.T) {
+	gen, err := NewGeneratorFromString(ExampleCommandsYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\nfunc {"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gen.CheckHandlers(dir); err == nil {
+		t.Error("CheckHandlers() expected parse error")
+	}
+}
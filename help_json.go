@@ -0,0 +1,124 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// helpFormatFlag is the hidden root flag selecting the format of --help.
+	helpFormatFlag = "help-format"
+	// helpFormatText is cobra's usual help text.
+	helpFormatText = "text"
+	// helpFormatJSON is the help of a command and its subcommands as JSON.
+	helpFormatJSON = "json"
+)
+
+// helpDoc is the JSON help of a command.
+type helpDoc struct {
+	Name           string     `json:"name"`
+	Path           string     `json:"path"`
+	Use            string     `json:"use"`
+	Args           string     `json:"args,omitempty"`
+	ValidArgs      []string   `json:"valid_args,omitempty"`
+	Aliases        []string   `json:"aliases,omitempty"`
+	Short          string     `json:"short,omitempty"`
+	Long           string     `json:"long,omitempty"`
+	Example        string     `json:"example,omitempty"`
+	Runnable       bool       `json:"runnable"`
+	SideEffects    string     `json:"side_effects,omitempty"`
+	Flags          []helpFlag `json:"flags"`
+	InheritedFlags []helpFlag `json:"inherited_flags"`
+	Commands       []helpDoc  `json:"commands"`
+}
+
+// helpFlag is the JSON help of a flag.
+type helpFlag struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default,omitempty"`
+	Usage     string `json:"usage"`
+	Required  bool   `json:"required,omitempty"`
+}
+
+// addHelpFormat adds the hidden --help-format flag, so "--help --help-format=json"
+// prints the help of a command and all its available subcommands as JSON for
+// wrapper tools, instead of text meant to be read.
+func (cb *CommandBuilder) addHelpFormat(rootCmd *cobra.Command) {
+	if rootCmd.PersistentFlags().Lookup(helpFormatFlag) != nil {
+		return
+	}
+	rootCmd.PersistentFlags().String(helpFormatFlag, helpFormatText, "Format of --help: text or json")
+	_ = rootCmd.PersistentFlags().MarkHidden(helpFormatFlag)
+
+	help := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		switch format, _ := rootCmd.PersistentFlags().GetString(helpFormatFlag); format {
+		case helpFormatJSON:
+			data, err := json.MarshalIndent(commandHelp(cmd), "", "  ")
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: failed to encode help: %v\n", err)
+				return
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		case helpFormatText:
+			help(cmd, args)
+		default:
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: invalid help format %q (must be text or json)\n", format)
+		}
+	})
+}
+
+// commandHelp returns the help of cmd and its available subcommands.
+func commandHelp(cmd *cobra.Command) helpDoc {
+	doc := helpDoc{
+		Name:           cmd.Name(),
+		Path:           cmd.CommandPath(),
+		Use:            cmd.Use,
+		ValidArgs:      cmd.ValidArgs,
+		Aliases:        cmd.Aliases,
+		Short:          cmd.Short,
+		Long:           cmd.Long,
+		Example:        cmd.Example,
+		Runnable:       cmd.Runnable(),
+		SideEffects:    cmd.Annotations[sideEffectsAnnotation],
+		Flags:          flagsHelp(cmd.LocalFlags()),
+		InheritedFlags: flagsHelp(cmd.InheritedFlags()),
+		Commands:       []helpDoc{},
+	}
+	if _, args, found := strings.Cut(cmd.Use, " "); found {
+		doc.Args = strings.TrimSpace(args)
+	}
+	for _, sub := range cmd.Commands() {
+		if sub.IsAvailableCommand() {
+			doc.Commands = append(doc.Commands, commandHelp(sub))
+		}
+	}
+	return doc
+}
+
+// flagsHelp returns the help of the visible flags of fs, sorted by name. Deprecated
+// flags are hidden as well. The help flag added by cobra is left out, since it is
+// only added to the command that runs.
+func flagsHelp(fs *pflag.FlagSet) []helpFlag {
+	flags := []helpFlag{}
+	fs.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Name == "help" {
+			return
+		}
+		flags = append(flags, helpFlag{
+			Name:      flag.Name,
+			Shorthand: flag.Shorthand,
+			Type:      flag.Value.Type(),
+			Default:   flag.DefValue,
+			Usage:     flag.Usage,
+			Required:  len(flag.Annotations[cobra.BashCompOneRequiredFlag]) > 0,
+		})
+	})
+	return flags
+}
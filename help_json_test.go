@@ -0,0 +1,108 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const helpJSONToolYAML = `
+name: help-test
+root:
+  use: help-test
+  short: Help test
+  flags:
+    - name: verbose
+      type: bool
+      usage: Verbose output
+      persistent: true
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy the application
+    run_func: runNoop
+    valid_args: [dev, prod]
+    aliases: [ship]
+    flags:
+      - name: replicas
+        type: int
+        default: "2"
+        usage: Replicas
+        required: true
+      - name: debug
+        type: bool
+        usage: Debug
+        hidden: true
+  db:
+    use: db
+    short: Database commands
+    commands:
+      migrate:
+        use: migrate
+        short: Run migrations
+        run_func: runNoop
+  internal:
+    use: internal
+    short: Internal
+    run_func: runNoop
+    hidden: true
+`
+
+// helpJSONTool is the help-test tool.
+var helpJSONTool = testTool{yaml: helpJSONToolYAML, funcs: map[string]any{"runNoop": noopRun}}
+
+func TestCommandBuilder_HelpFormatJSON(t *testing.T) {
+	var root helpDoc
+	if err := json.Unmarshal([]byte(helpJSONTool.mustRun(t, "--help", "--help-format=json")), &root); err != nil {
+		t.Fatalf("help should be JSON: %v", err)
+	}
+
+	var names []string
+	for _, cmd := range root.Commands {
+		names = append(names, cmd.Name)
+	}
+	if strings.Join(names, " ") != "completion db deploy" {
+		t.Errorf("commands = %v, want the available commands", names)
+	}
+	if len(root.Flags) != 1 || root.Flags[0].Name != "verbose" {
+		t.Errorf("root flags = %+v, want verbose only", root.Flags)
+	}
+
+	deploy := root.Commands[2]
+	if deploy.Path != "help-test deploy" || deploy.Args != "<env>" || !deploy.Runnable ||
+		strings.Join(deploy.ValidArgs, ",") != "dev,prod" || strings.Join(deploy.Aliases, ",") != "ship" {
+		t.Errorf("deploy = %+v", deploy)
+	}
+	wantFlag := helpFlag{Name: "replicas", Type: "int", Default: "2", Usage: "Replicas", Required: true}
+	if len(deploy.Flags) != 1 || deploy.Flags[0] != wantFlag {
+		t.Errorf("deploy flags = %+v, want %+v", deploy.Flags, wantFlag)
+	}
+	if len(deploy.InheritedFlags) != 1 || deploy.InheritedFlags[0].Name != "verbose" {
+		t.Errorf("deploy inherited flags = %+v, want verbose", deploy.InheritedFlags)
+	}
+	if db := root.Commands[1]; db.Runnable || len(db.Commands) != 1 || db.Commands[0].Path != "help-test db migrate" {
+		t.Errorf("db = %+v, want migrate as subcommand", db)
+	}
+}
+
+func TestCommandBuilder_HelpFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		prefix  string
+		want    string
+		notWant string
+	}{
+		{name: "help command", args: []string{"help", "db", "--help-format", "json"}, prefix: "{\n  \"name\": \"db\","},
+		{name: "default text", args: []string{"--help"}, want: "Available Commands:", notWant: helpFormatFlag},
+		{name: "invalid format", args: []string{"--help", "--help-format=yaml"}, want: `Error: invalid help format "yaml" (must be text or json)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := helpJSONTool.mustRun(t, tt.args...)
+			if !strings.HasPrefix(out, tt.prefix) || !strings.Contains(out, tt.want) || (tt.notWant != "" && strings.Contains(out, tt.notWant)) {
+				t.Errorf("output should start with %q, contain %q and not %q, got:\n%s", tt.prefix, tt.want, tt.notWant, out)
+			}
+		})
+	}
+}
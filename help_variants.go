@@ -0,0 +1,109 @@
+package cobrayaml
+
+import (
+	"cmp"
+	"hash/fnv"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// helpVariantAnnotation is the command annotation holding the help variant
+// shown to the current user.
+const helpVariantAnnotation = "cobrayaml_help_variant"
+
+// HelpVariantDefault names the short and long descriptions of a command itself,
+// the control group of a command that declares variants.
+const HelpVariantDefault = "default"
+
+// HelpVariantConfig represents an alternate wording of a command's help in
+// commands.yaml, for experiments with help text. Each user is shown either the
+// command's own descriptions or one of its variants, always the same one.
+//
+// Fields:
+//   - Short: Brief description replacing the command's short (default: unchanged)
+//   - Long: Detailed description replacing the command's long (default: unchanged)
+//
+// Example YAML:
+//
+//	deploy:
+//	  use: deploy
+//	  short: Deploy the application
+//	  variants:
+//	    imperative:
+//	      short: Ship the current build to an environment
+//	    outcome:
+//	      short: Make the current build live in an environment
+type HelpVariantConfig struct {
+	Short string `yaml:"short,omitempty"`
+	Long  string `yaml:"long,omitempty"`
+}
+
+// currentUser returns the name identifying the user when selecting help
+// variants. It is replaced in tests.
+var currentUser = func() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// selectHelpVariant returns the name of the variant of config shown to the
+// current user: HelpVariantDefault or one of its variants, chosen by a hash of
+// the tool, the user and the command so a user keeps seeing the same wording.
+// The user name is only hashed; just the variant name is reported.
+func selectHelpVariant(tool string, config CommandConfig) string {
+	names := append([]string{HelpVariantDefault}, sortedKeys(config.Variants)...)
+	h := fnv.New32a()
+	h.Write([]byte(tool + "\x00" + currentUser() + "\x00" + config.Use))
+	return names[h.Sum32()%uint32(len(names))]
+}
+
+// applyHelpVariant replaces the descriptions of cmd with those of the variant
+// selected for the current user and records the variant in its annotations.
+func (cb *CommandBuilder) applyHelpVariant(cmd *cobra.Command, config CommandConfig) {
+	if len(config.Variants) == 0 {
+		return
+	}
+
+	name := selectHelpVariant(cb.config.Name, config)
+	if variant, exists := config.Variants[name]; exists {
+		cmd.Short = cmp.Or(variant.Short, cmd.Short)
+		cmd.Long = cmp.Or(variant.Long, cmd.Long)
+	}
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[helpVariantAnnotation] = name
+}
+
+// HelpVariant returns the name of the help variant shown for cmd, or "" if the
+// command declares no variants. Handlers can use it to report outcomes per variant;
+// events of the command carry it as well.
+func HelpVariant(cmd *cobra.Command) string {
+	return cmd.Annotations[helpVariantAnnotation]
+}
+
+// addHelpEvents wraps the help of the root command so the configured sinks
+// receive a help.shown event whenever the help of a command with variants is shown.
+func (cb *CommandBuilder) addHelpEvents(rootCmd *cobra.Command) {
+	if len(cb.config.Events) == 0 {
+		return
+	}
+
+	help := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if variant := HelpVariant(cmd); variant != "" {
+			cb.emit(cmd, Event{
+				Type:    EventHelpShown,
+				Time:    time.Now(),
+				Tool:    cmd.Root().Name(),
+				Command: cmd.CommandPath(),
+				Variant: variant,
+			})
+		}
+		help(cmd, args)
+	})
+}
@@ -0,0 +1,174 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const helpVariantsYAML = `
+name: variants-test
+events:
+  - type: recorder
+root:
+  use: variants-test
+  short: Variants test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy the application
+    run_func: runDeploy
+    variants:
+      imperative:
+        short: Ship the current build
+      outcome:
+        short: Make the current build live
+        long: Makes the current build live in the selected environment.
+`
+
+// setCurrentUser replaces the user name used to select help variants.
+func setCurrentUser(t *testing.T, name string) {
+	t.Helper()
+	original := currentUser
+	currentUser = func() string { return name }
+	t.Cleanup(func() { currentUser = original })
+}
+
+// buildHelpVariantsTool builds the variants-test tool and returns its root
+// command with the events it emits.
+func buildHelpVariantsTool(t *testing.T) (*cobra.Command, *[]Event) {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(helpVariantsYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var events []Event
+	cb.RegisterEventSink("recorder", EventSinkFunc(func(ctx context.Context, e Event) error {
+		events = append(events, e)
+		return nil
+	}))
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	return rootCmd, &events
+}
+
+func TestSelectHelpVariant(t *testing.T) {
+	config := CommandConfig{
+		Use: "deploy",
+		Variants: map[string]HelpVariantConfig{
+			"imperative": {Short: "Ship the current build"},
+			"outcome":    {Short: "Make the current build live"},
+		},
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi"} {
+		setCurrentUser(t, name)
+		variant := selectHelpVariant("variants-test", config)
+		if again := selectHelpVariant("variants-test", config); again != variant {
+			t.Errorf("user %s: selection should be deterministic, got %q then %q", name, variant, again)
+		}
+		seen[variant] = true
+	}
+	for _, want := range []string{HelpVariantDefault, "imperative", "outcome"} {
+		if !seen[want] {
+			t.Errorf("variant %q was not selected for any user, got %v", want, seen)
+		}
+	}
+}
+
+func TestCommandBuilder_HelpVariants(t *testing.T) {
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		setCurrentUser(t, name)
+		rootCmd, events := buildHelpVariantsTool(t)
+		deployCmd, _, _ := rootCmd.Find([]string{"deploy"})
+
+		variant := HelpVariant(deployCmd)
+		wantShort := map[string]string{
+			HelpVariantDefault: "Deploy the application",
+			"imperative":       "Ship the current build",
+			"outcome":          "Make the current build live",
+		}[variant]
+		if wantShort == "" || deployCmd.Short != wantShort {
+			t.Errorf("user %s: variant %q short = %q, want %q", name, variant, deployCmd.Short, wantShort)
+		}
+
+		rootCmd.SetArgs([]string{"deploy"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+
+		var out bytes.Buffer
+		rootCmd.SetOut(&out)
+		rootCmd.SetArgs([]string{"deploy", "--help"})
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if !strings.Contains(out.String(), wantShort) && !strings.Contains(out.String(), deployCmd.Long) {
+			t.Errorf("user %s: help should show the variant, got:\n%s", name, out.String())
+		}
+
+		var types []string
+		for _, e := range *events {
+			types = append(types, e.Type)
+			if e.Variant != variant {
+				t.Errorf("user %s: %s event variant = %q, want %q", name, e.Type, e.Variant, variant)
+			}
+		}
+		if got, want := strings.Join(types, ","), "command.started,command.succeeded,help.shown"; got != want {
+			t.Errorf("user %s: events = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestValidateConfig_HelpVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants map[string]HelpVariantConfig
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			variants: map[string]HelpVariantConfig{"concise": {Short: "Deploy"}},
+		},
+		{
+			name:     "reserved name",
+			variants: map[string]HelpVariantConfig{"default": {Short: "Deploy"}},
+			wantErr:  `command "deploy": variant name "default" is reserved`,
+		},
+		{
+			name:     "empty variant",
+			variants: map[string]HelpVariantConfig{"concise": {}},
+			wantErr:  `command "deploy": variant "concise" must set short or long`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name: "test",
+				Root: CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{
+					"deploy": {Use: "deploy", Short: "Deploy the application", Variants: tt.variants},
+				},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// historyLimit is the number of runs kept in the history.
+	historyLimit = 100
+	// historyRedacted replaces the value of a sensitive flag in the history.
+	historyRedacted = "<redacted>"
+	// sensitiveAnnotation is the pflag annotation key that marks a sensitive flag.
+	sensitiveAnnotation = "cobrayaml_sensitive"
+)
+
+// Outcomes of a run kept in the history.
+const (
+	historySucceeded = "succeeded"
+	historyFailed    = "failed"
+)
+
+// historyEntry is a run of a command kept in the history.
+type historyEntry struct {
+	ID     int       `json:"id"` // stable number of the run, also after older runs are dropped
+	Time   time.Time `json:"time"`
+	Args   []string  `json:"args"`             // command line without the root command name
+	Status string    `json:"status,omitempty"` // historySucceeded or historyFailed
+	Undone bool      `json:"undone,omitempty"`
+}
+
+// redacted reports whether the entry lacks values of sensitive flags.
+func (e historyEntry) redacted() bool {
+	return slices.ContainsFunc(e.Args, func(arg string) bool { return strings.HasSuffix(arg, "="+historyRedacted) })
+}
+
+// historyFile returns the file the history of tool is kept in.
+func historyFile(tool string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tool, "history.json"), nil
+}
+
+// readHistory returns the history in path, oldest run first. A missing or
+// unreadable file counts as no history. Entries written before runs had IDs
+// are numbered by their position.
+func readHistory(path string) []historyEntry {
+	var entries []historyEntry
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	_ = json.Unmarshal(data, &entries)
+	for i := range entries {
+		if entries[i].ID == 0 {
+			entries[i].ID = i + 1
+		}
+	}
+	return entries
+}
+
+// findHistoryEntry returns the index of the entry of entries with the ID given
+// as arg.
+func findHistoryEntry(entries []historyEntry, arg string) (int, error) {
+	id, err := strconv.Atoi(arg)
+	if err == nil {
+		for i, entry := range entries {
+			if entry.ID == id {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no history entry %s", arg)
+}
+
+// recordHistory appends entry to the history of tool with the next ID, keeping
+// the last historyLimit runs. Failures are ignored, since recording must never
+// affect the command.
+func recordHistory(tool string, entry historyEntry) {
+	path, err := historyFile(tool)
+	if err != nil {
+		return
+	}
+	entries := readHistory(path)
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	entries = append(entries, entry)
+	if len(entries) > historyLimit {
+		entries = entries[len(entries)-historyLimit:]
+	}
+	_ = writeHistory(path, entries)
+}
+
+// writeHistory replaces the history in path with entries.
+func writeHistory(path string, entries []historyEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// historyArgs returns the command line that runs cmd again with the flags set on
+// the command line and args. The values of sensitive flags are redacted.
+func historyArgs(cmd *cobra.Command, args []string) []string {
+	return append(strings.Fields(commandKey(cmd)), rerunArgs(cmd, args, false)...)
+}
+
+// rerunArgs returns the flags set on the command line of cmd, except those named
+// in skip, and args, as the args that run cmd again. The values of sensitive
+// flags are redacted, or the flags left out when omitSensitive is set. Args
+// starting with "-" follow "--", unless the command disables flag parsing and
+// takes them as they are.
+func rerunArgs(cmd *cobra.Command, args []string, omitSensitive bool, skip ...string) []string {
+	var line []string
+	flags := FlagValues(cmd)
+	for _, name := range sortedKeys(flags) {
+		v := flags[name]
+		if v.Source != FlagSourceCommandLine || slices.Contains(skip, name) || (v.Redacted && omitSensitive) {
+			continue
+		}
+		flag := cmd.Flags().Lookup(name)
+		values := []string{flag.Value.String()}
+		if v.Redacted {
+			values = []string{historyRedacted}
+		} else if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values = slice.GetSlice()
+		}
+		for _, value := range values {
+			line = append(line, "--"+name+"="+value)
+		}
+	}
+	if !cmd.DisableFlagParsing && slices.ContainsFunc(args, func(arg string) bool { return strings.HasPrefix(arg, "-") }) {
+		line = append(line, "--")
+	}
+	return append(line, args...)
+}
+
+// addHistory wraps the RunE of cmd so its runs are recorded with their outcome
+// when history is set. Runs of the undo command are not recorded; it marks the
+// entry it reverts instead.
+func (cb *CommandBuilder) addHistory(cmd *cobra.Command) {
+	if !cb.config.History || cmd.RunE == nil {
+		return
+	}
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if undoing(cmd) {
+			return run(cmd, args)
+		}
+		entry := historyEntry{Time: time.Now(), Args: historyArgs(cmd, args), Status: historySucceeded}
+		err := run(cmd, args)
+		if err != nil {
+			entry.Status = historyFailed
+		}
+		recordHistory(cmd.Root().Name(), entry)
+		return err
+	}
+}
+
+// addHistoryCommands adds the "history" and "rerun" commands when history is set,
+// unless the tool already has commands of those names.
+func (cb *CommandBuilder) addHistoryCommands(rootCmd *cobra.Command) {
+	if !cb.config.History {
+		return
+	}
+	if findSubcommand(rootCmd, "history") == nil {
+		rootCmd.AddCommand(buildHistoryCommand())
+	}
+	if findSubcommand(rootCmd, "rerun") == nil {
+		rootCmd.AddCommand(buildRerunCommand())
+	}
+}
+
+// buildHistoryCommand builds the "history" command that lists previous runs.
+func buildHistoryCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "history",
+		Short: "List previous runs of commands",
+		Long: fmt.Sprintf("List the last %d runs of commands, oldest first. Values of sensitive flags "+
+			"are shown as %s. Run an entry again with rerun <id>.", historyLimit, historyRedacted),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := historyFile(cmd.Root().Name())
+			if err != nil {
+				return err
+			}
+			entries := readHistory(path)
+			if len(entries) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No history")
+				return nil
+			}
+
+			table := newOutputTable(cmd, "ID", "TIME", "COMMAND")
+			for _, entry := range entries {
+				line := shellJoin(append([]string{cmd.Root().Name()}, entry.Args...))
+				switch {
+				case entry.Undone:
+					line += " (undone)"
+				case entry.Status == historyFailed:
+					line += " (failed)"
+				}
+				table.Row(strconv.Itoa(entry.ID), entry.Time.Format(time.DateTime), line)
+			}
+			return table.Flush()
+		},
+	}
+}
+
+// buildRerunCommand builds the "rerun" command that runs a history entry again.
+func buildRerunCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rerun <id>",
+		Short: "Run a command from the history again",
+		Long: "Run the history entry with the given ID again. Entries with redacted values of sensitive " +
+			"flags cannot be run again and have to be typed out.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := historyFile(cmd.Root().Name())
+			if err != nil {
+				return err
+			}
+			entries := readHistory(path)
+			i, err := findHistoryEntry(entries, args[0])
+			if err != nil {
+				return err
+			}
+			entry := entries[i]
+			if entry.redacted() {
+				return fmt.Errorf("history entry %d has redacted flag values; run it again by hand", entry.ID)
+			}
+
+			fmt.Fprintln(cmd.ErrOrStderr(), shellJoin(append([]string{cmd.Root().Name()}, entry.Args...)))
+			return runMatch(cmd.Root(), entry.Args)
+		},
+	}
+}
+
+// shellJoin joins args into a command line that a POSIX shell runs as args.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,140 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const historyToolYAML = `
+name: history-test
+history: true
+root:
+  use: history-test
+  short: History test
+  flags:
+    - name: verbose
+      type: bool
+      usage: Verbose output
+      persistent: true
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+    flags:
+      - name: tag
+        type: stringSlice
+        usage: Tags
+      - name: token
+        type: string
+        usage: API token
+        sensitive: true
+`
+
+func TestCommandBuilder_History(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var ran []string
+	tool := testTool{yaml: historyToolYAML, funcs: map[string]any{
+		"runDeploy": func(cmd *cobra.Command, args []string) error {
+			tags, _ := cmd.Flags().GetStringSlice("tag")
+			ran = append(args, tags...)
+			return nil
+		},
+	}}
+
+	if out, err := tool.run(t, "history"); err != nil || out != "No history\n" {
+		t.Fatalf("history = %q, error = %v, want No history", out, err)
+	}
+
+	tool.mustRun(t, "deploy", "prod", "--tag", "a,b", "--verbose")
+	tool.mustRun(t, "deploy", "--token", "s3cret", "--", "-x y")
+
+	data, err := os.ReadFile(filepath.Join(cacheDir, "history-test", "history.json"))
+	if err != nil {
+		t.Fatalf("failed to read history: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("history should not contain sensitive values:\n%s", data)
+	}
+
+	out := tool.mustRun(t, "history")
+	timestamp := regexp.MustCompile(`\d{4}-\d\d-\d\d \d\d:\d\d:\d\d`)
+	want := "ID  TIME                 COMMAND\n" +
+		"1   2006-01-02 15:04:05  history-test deploy --tag=a --tag=b --verbose=true prod\n" +
+		"2   2006-01-02 15:04:05  history-test deploy '--token=<redacted>' -- '-x y'\n"
+	if got := timestamp.ReplaceAllString(out, "2006-01-02 15:04:05"); got != want {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+
+	out = tool.mustRun(t, "rerun", "1")
+	if strings.Join(ran, " ") != "prod a b" {
+		t.Errorf("deploy ran with %v, want [prod a b]", ran)
+	}
+	if !strings.Contains(out, "history-test deploy --tag=a --tag=b --verbose=true prod\n") {
+		t.Errorf("rerun should print the command line, got %q", out)
+	}
+
+	if _, err := tool.run(t, "rerun", "2"); err == nil || !strings.Contains(err.Error(), "history entry 2 has redacted flag values") {
+		t.Errorf("rerun 2 error = %v, want redacted error", err)
+	}
+	if _, err := tool.run(t, "rerun", "9"); err == nil || !strings.Contains(err.Error(), "no history entry 9") {
+		t.Errorf("rerun 9 error = %v, want no history entry", err)
+	}
+}
+
+func TestCommandBuilder_HistoryDisabled(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	tool := testTool{yaml: strings.Replace(historyToolYAML, "history: true\n", "", 1), funcs: map[string]any{"runDeploy": noopRun}}
+	if rootCmd := tool.build(t); findSubcommand(rootCmd, "history") != nil || findSubcommand(rootCmd, "rerun") != nil {
+		t.Error("history and rerun should only be added with history")
+	}
+
+	tool.mustRun(t, "deploy", "prod")
+	if _, err := os.Stat(filepath.Join(cacheDir, "history-test", "history.json")); !os.IsNotExist(err) {
+		t.Errorf("runs should not be recorded unless enabled, stat error = %v", err)
+	}
+}
+
+func TestRecordHistory_IDs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	for i := 0; i <= historyLimit; i++ {
+		recordHistory("history-test", historyEntry{Args: []string{"deploy"}, Status: historySucceeded})
+	}
+	path, err := historyFile("history-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readHistory(path)
+	if len(entries) != historyLimit || entries[0].ID != 2 || entries[historyLimit-1].ID != historyLimit+1 {
+		t.Fatalf("history has %d entries with IDs %d to %d, want %d with IDs 2 to %d",
+			len(entries), entries[0].ID, entries[len(entries)-1].ID, historyLimit, historyLimit+1)
+	}
+
+	// IDs stay with their runs after the oldest run is dropped
+	if _, err := findHistoryEntry(entries, "1"); err == nil {
+		t.Error("findHistoryEntry() found the dropped entry 1")
+	}
+	if i, err := findHistoryEntry(entries, "101"); err != nil || i != historyLimit-1 {
+		t.Errorf("findHistoryEntry(101) = %d, %v, want the last entry", i, err)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	args := []string{"deploy", "--msg=$(rm -rf ~)", "`id`", `a\b`, "it's", "", "plain-arg"}
+	want := `deploy '--msg=$(rm -rf ~)' '` + "`id`" + `' 'a\b' 'it'\''s' '' plain-arg`
+	if got := shellJoin(args); got != want {
+		t.Errorf("shellJoin() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,49 @@
+package cobrayaml
+
+import "context"
+
+// Identity is the caller a command runs on behalf of, when commands are
+// executed for others, e.g. by a server running them in-process for REST or
+// agent clients.
+type Identity struct {
+	Tenant  string            `json:"tenant,omitempty"` // tenant or organization of the caller
+	Subject string            `json:"subject"`          // caller, e.g. a user ID or service account
+	Claims  map[string]string `json:"claims,omitempty"` // further attributes, e.g. roles or scopes
+}
+
+// identityKey is the context key under which the caller identity is stored.
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id. Run a command with the result,
+// e.g. with CommandBuilder.ExecuteArgs or from an HTTP middleware in front of
+// webui.Handler, and its handler reads the caller with IdentityFromContext:
+//
+//	ctx := cobrayaml.WithIdentity(r.Context(), cobrayaml.Identity{Tenant: "acme", Subject: user})
+//	err := builder.ExecuteArgs(ctx, []string{"deploy", "prod"})
+//
+// The same identity is recorded in events, passed to the quota checker,
+// available to middleware and part of the result cache key, so audit logs,
+// quotas, authorization and cached output agree on the caller. It does not
+// cross process boundaries, such as background jobs or the runs of
+// webui.BinaryRunner.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the caller identity carried by ctx, typically
+// cmd.Context() in a handler or middleware, and whether there is one.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	if ctx == nil {
+		return Identity{}, false
+	}
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// contextIdentity returns the caller identity carried by ctx, or nil.
+func contextIdentity(ctx context.Context) *Identity {
+	if id, ok := IdentityFromContext(ctx); ok {
+		return &id
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const identityYAML = `
+name: tenants
+events:
+  - type: audit
+root:
+  use: tenants
+  short: Multi-tenant tool
+commands:
+  deploy:
+    use: deploy
+    short: Deploy
+    run_func: runDeploy
+    quota: deployments-per-day
+`
+
+func TestIdentity(t *testing.T) {
+	alice := Identity{Tenant: "acme", Subject: "alice", Claims: map[string]string{"role": "admin"}}
+
+	cb, err := NewCommandBuilderFromString(identityYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	seen := make(map[string]*Identity)
+	record := func(name string, ctx context.Context) {
+		seen[name] = contextIdentity(ctx)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) {
+		record("handler", cmd.Context())
+	})
+	cb.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			record("middleware", cmd.Context())
+			return next(cmd, args)
+		}
+	})
+	cb.RegisterEventSink("audit", EventSinkFunc(func(ctx context.Context, e Event) error {
+		if e.Type == EventCommandSucceeded {
+			seen["event"] = e.Identity
+		}
+		return nil
+	}))
+	cb.SetQuotaChecker(QuotaCheckerFunc(func(ctx context.Context, req QuotaRequest) (QuotaDecision, error) {
+		seen["quota"] = req.Identity
+		return QuotaDecision{Allowed: true}, nil
+	}))
+
+	setCommandLine(t, "deploy")
+	if err := cb.ExecuteContext(WithIdentity(context.Background(), alice)); err != nil {
+		t.Fatalf("ExecuteContext() error = %v", err)
+	}
+	for _, name := range []string{"handler", "middleware", "event", "quota"} {
+		if got := seen[name]; got == nil || !reflect.DeepEqual(*got, alice) {
+			t.Errorf("%s identity = %v, want %v", name, got, alice)
+		}
+	}
+
+	// Without an identity, nothing is made up.
+	clear(seen)
+	if err := cb.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	for _, name := range []string{"handler", "middleware", "event", "quota"} {
+		if got, ok := seen[name]; !ok || got != nil {
+			t.Errorf("%s identity = %v, want none", name, got)
+		}
+	}
+
+}
+
+func TestIdentityFromContext(t *testing.T) {
+	if _, ok := IdentityFromContext(context.Background()); ok {
+		t.Error("IdentityFromContext() ok = true for a context without identity")
+	}
+	// A command that never ran has no context.
+	if _, ok := IdentityFromContext(nil); ok {
+		t.Error("IdentityFromContext(nil) ok = true")
+	}
+	id, ok := IdentityFromContext(WithIdentity(context.Background(), Identity{Tenant: "acme", Subject: "bob"}))
+	if !ok || id.Tenant != "acme" || id.Subject != "bob" {
+		t.Errorf("IdentityFromContext() = %v, %v", id, ok)
+	}
+}
+
+func TestIdentity_Cache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cb, err := NewCommandBuilderFromString(cacheYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runPods", func(cmd *cobra.Command, args []string) {
+		id, _ := IdentityFromContext(cmd.Context())
+		cmd.Printf("pods of %s\n", id.Subject)
+	})
+	var out bytes.Buffer
+	cb.SetOut(&out)
+	for _, subject := range []string{"alice", "bob", "alice"} {
+		out.Reset()
+		ctx := WithIdentity(context.Background(), Identity{Tenant: "acme", Subject: subject})
+		if err := cb.ExecuteArgs(ctx, []string{"pods"}); err != nil {
+			t.Fatalf("ExecuteArgs() error = %v", err)
+		}
+		if want := "pods of " + subject + "\n"; out.String() != want {
+			t.Errorf("output for %s = %q, want %q", subject, out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// includeCommands removes the commands of config that no pattern selects, so a
+// restricted edition of a CLI is built from the same commands.yaml. A pattern
+// is a command path with the names joined by dots, e.g. "user.list", and each
+// name may be a glob as in path.Match, e.g. "db.*". A selected command keeps
+// all its subcommands, and the parents of a selected command are kept so it
+// can be reached; the root command is always kept. Surface commands are
+// pruned like top-level commands. A pattern that selects no command is an
+// error, so a typo does not silently drop commands.
+func includeCommands(config *ToolConfig, patterns []string) error {
+	for _, pattern := range patterns {
+		if err := checkCommandPattern(pattern); err != nil {
+			return fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		}
+	}
+
+	matched := make(map[string]bool)
+	config.Commands = pruneCommands(config.Commands, nil, patterns, matched)
+	for _, name := range sortedKeys(config.Surfaces) {
+		surface := config.Surfaces[name]
+		surface.Commands = pruneCommands(surface.Commands, nil, patterns, matched)
+		config.Surfaces[name] = surface
+	}
+
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			return fmt.Errorf("include pattern %q matches no command", pattern)
+		}
+	}
+	return nil
+}
+
+// pruneCommands returns the commands of cmds, below the command path parent,
+// that patterns select or that have a selected subcommand. Patterns that select
+// a command are recorded in matched.
+func pruneCommands(cmds map[string]CommandConfig, parent []string, patterns []string, matched map[string]bool) map[string]CommandConfig {
+	if len(cmds) == 0 {
+		return cmds
+	}
+	pruned := make(map[string]CommandConfig)
+	for _, name := range sortedCommandNames(cmds) {
+		cmd := cmds[name]
+		names := append(parent[:len(parent):len(parent)], name)
+		selected := false
+		for _, pattern := range patterns {
+			if matchCommandPath(pattern, names) {
+				matched[pattern] = true
+				selected = true
+			}
+		}
+		subcommands := pruneCommands(cmd.Commands, names, patterns, matched)
+		if selected {
+			pruned[name] = cmd
+		} else if len(subcommands) > 0 {
+			cmd.Commands = subcommands
+			pruned[name] = cmd
+		}
+	}
+	if len(pruned) == 0 {
+		return nil
+	}
+	return pruned
+}
+
+// checkCommandPattern reports whether pattern is a well-formed command path
+// pattern, as matched by matchCommandPath.
+func checkCommandPattern(pattern string) error {
+	for _, name := range strings.Split(pattern, ".") {
+		if name == "" {
+			return fmt.Errorf("empty command name")
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchCommandPath reports whether the command path names matches pattern.
+func matchCommandPath(pattern string, names []string) bool {
+	parts := strings.Split(pattern, ".")
+	if len(parts) != len(names) {
+		return false
+	}
+	for i, part := range parts {
+		if ok, _ := path.Match(part, names[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package cobrayaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const includeYAML = `
+name: app
+root:
+  use: app
+  short: App
+commands:
+  db:
+    use: db
+    short: Database
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+        commands:
+          verify:
+            use: verify
+            short: Verify a backup
+            run_func: runVerify
+      restore:
+        use: restore
+        short: Restore the database
+        run_func: runRestore
+  user:
+    use: user
+    short: Users
+    commands:
+      list:
+        use: list
+        short: List users
+        run_func: runUserList
+      delete:
+        use: delete
+        short: Delete a user
+        run_func: runUserDelete
+  version:
+    use: version
+    short: Print the version
+    run_func: runVersion
+`
+
+// commandPaths returns the dotted paths of all commands below cmd.
+func commandPaths(cmd *cobra.Command, parent string) []string {
+	var paths []string
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == "help" || sub.Name() == "completion" {
+			continue
+		}
+		path := parent + sub.Name()
+		paths = append(paths, path)
+		paths = append(paths, commandPaths(sub, path+".")...)
+	}
+	return paths
+}
+
+func TestCommandBuilder_Include(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		funcs    []string
+		want     []string
+	}{
+		{
+			name:     "globs and paths",
+			patterns: []string{"db.*", "user.list"},
+			funcs:    []string{"runBackup", "runVerify", "runRestore", "runUserList"},
+			want:     []string{"db", "db.backup", "db.backup.verify", "db.restore", "user", "user.list"},
+		},
+		{
+			name:     "selected command keeps subcommands",
+			patterns: []string{"user"},
+			funcs:    []string{"runUserList", "runUserDelete"},
+			want:     []string{"user", "user.delete", "user.list"},
+		},
+		{
+			name:     "glob within a name",
+			patterns: []string{"*.re*"},
+			funcs:    []string{"runRestore"},
+			want:     []string{"db", "db.restore"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(includeYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			if err := cb.Include(tt.patterns...); err != nil {
+				t.Fatalf("Include() error = %v", err)
+			}
+			// Only the handlers of the included commands are registered.
+			for _, fn := range tt.funcs {
+				cb.RegisterFunction(fn, func(cmd *cobra.Command, args []string) {})
+			}
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			if got := commandPaths(rootCmd, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("commands = %v, want %v", got, tt.want)
+			}
+			if violations := CheckContract(rootCmd, cb.GetConfig()); len(violations) > 0 {
+				t.Errorf("CheckContract() = %v", violations)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_Include_Errors(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		wantErr  string
+	}{
+		{[]string{"db.*", "user.lst"}, `include pattern "user.lst" matches no command`},
+		{[]string{"db..backup"}, `invalid include pattern "db..backup": empty command name`},
+		{[]string{"db.[a"}, `invalid include pattern "db.[a": syntax error in pattern`},
+	}
+	for _, tt := range tests {
+		cb, err := NewCommandBuilderFromString(includeYAML)
+		if err != nil {
+			t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+		}
+		if err := cb.Include(tt.patterns...); err == nil || err.Error() != tt.wantErr {
+			t.Errorf("Include(%q) error = %v, want %q", tt.patterns, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerator_Include(t *testing.T) {
+	gen, err := NewGeneratorFromString(includeYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	if err := gen.Include("db.backup", "user.list"); err != nil {
+		t.Fatalf("Include() error = %v", err)
+	}
+
+	var funcs []string
+	for _, fn := range gen.CollectFunctions() {
+		funcs = append(funcs, fn.Name)
+	}
+	if want := []string{"runBackup", "runVerify", "runUserList"}; !reflect.DeepEqual(funcs, want) {
+		t.Errorf("CollectFunctions() = %v, want %v", funcs, want)
+	}
+
+	code, err := gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+	for _, want := range []string{
+		`var includedCommands = []string{"db.backup", "user.list"}`,
+		`if err := builder.Include(includedCommands...); err != nil {`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("main.go does not contain %q:\n%s", want, code)
+		}
+	}
+
+	code, err = gen.GenerateContractTest("main")
+	if err != nil {
+		t.Fatalf("GenerateContractTest() error = %v", err)
+	}
+	if !strings.Contains(code, "builder.Include(includedCommands...)") {
+		t.Errorf("contract test does not apply the include patterns:\n%s", code)
+	}
+}
@@ -0,0 +1,423 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// BackgroundSupported is the background mode that lets a command run as a job with --detach.
+const BackgroundSupported = "supported"
+
+const (
+	// detachFlag is the flag added to commands that can run in the background.
+	detachFlag = "detach"
+	// jobIDEnv tells a command it runs as the background job with this ID.
+	jobIDEnv = "COBRAYAML_JOB_ID"
+	// jobSecretsEnv passes the values of the sensitive flags of a job to its
+	// process, keeping them off its command line and out of its state file.
+	jobSecretsEnv = "COBRAYAML_JOB_SECRETS"
+)
+
+// jobState is the state file written when a job starts.
+type jobState struct {
+	ID      int       `json:"id"`
+	PID     int       `json:"pid"`
+	Command []string  `json:"command"`
+	Started time.Time `json:"started"`
+	// ProcessStart is when the process with PID started (see processStart), to
+	// tell the job from a later process that reuses its PID.
+	ProcessStart string `json:"process_start,omitempty"`
+}
+
+// jobSecrets is the value of jobSecretsEnv: the sensitive flags set on the
+// command line of the command run as the job.
+type jobSecrets struct {
+	Command []string            `json:"command"` // command path below the root
+	Flags   map[string][]string `json:"flags"`   // values of the sensitive flags
+}
+
+// jobExit is the file a job writes when its handler returns.
+type jobExit struct {
+	Error    string    `json:"error,omitempty"`
+	Finished time.Time `json:"finished"`
+}
+
+// jobCommand returns the command that runs the tool itself with args. Tests replace it.
+var jobCommand = func(args []string) (*exec.Cmd, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(exe, args...), nil
+}
+
+// jobsDir returns the directory the state and logs of tool's jobs are kept in.
+func jobsDir(tool string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tool, "jobs"), nil
+}
+
+// addBackground adds the --detach flag to a command that supports running in
+// the background. With --detach, the tool runs itself again as a detached job with
+// the same command, flags and args, and its output goes to the job's log file.
+func (cb *CommandBuilder) addBackground(cmd *cobra.Command, background string) {
+	if background == "" || cmd.RunE == nil {
+		return
+	}
+	cmd.Flags().Bool(detachFlag, false, "Run in the background as a job (see the jobs command)")
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if detach, _ := cmd.Flags().GetBool(detachFlag); detach {
+			return startJob(cmd, args)
+		}
+		id := os.Getenv(jobIDEnv)
+		if id == "" {
+			return run(cmd, args)
+		}
+
+		// Running as a job: record how the handler finished for "jobs list"
+		os.Unsetenv(jobIDEnv)
+		err := run(cmd, args)
+		if dir, dirErr := jobsDir(cmd.Root().Name()); dirErr == nil {
+			exit := jobExit{Finished: time.Now()}
+			if err != nil {
+				exit.Error = err.Error()
+			}
+			if data, jsonErr := json.Marshal(exit); jsonErr == nil {
+				_ = os.WriteFile(filepath.Join(dir, id+".exit"), data, 0o600)
+			}
+		}
+		return err
+	}
+}
+
+// jobArgs returns the args that run cmd again with the flags set on the command
+// line (except --detach and the sensitive flags, see sensitiveFlags) and the
+// positional args, confirming a destructive command that was already confirmed.
+// With redact set, the sensitive flags are included with redacted values
+// instead, to show the command.
+func jobArgs(cmd *cobra.Command, args []string, redact bool) []string {
+	jobArgs := strings.Fields(commandKey(cmd))
+	// A destructive command was confirmed before it was detached
+	if flag := cmd.Flags().Lookup(yesFlag); flag != nil && !flag.Changed && cmd.Annotations[sideEffectsAnnotation] == SideEffectsDestructive {
+		jobArgs = append(jobArgs, "--"+yesFlag)
+	}
+	return append(jobArgs, rerunArgs(cmd, args, !redact, detachFlag)...)
+}
+
+// sensitiveFlags returns the values of the sensitive flags set on the command
+// line of cmd, passed to its job in the environment.
+func sensitiveFlags(cmd *cobra.Command) map[string][]string {
+	secrets := make(map[string][]string)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if !sensitive(flag) {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			secrets[flag.Name] = slice.GetSlice()
+			return
+		}
+		secrets[flag.Name] = []string{flag.Value.String()}
+	})
+	return secrets
+}
+
+// applyJobSecrets sets the sensitive flags passed by startJob when the tool runs
+// as a job. They are set on the command of the job before its command line is
+// parsed, so required sensitive flags count as set.
+func applyJobSecrets(rootCmd *cobra.Command) error {
+	data, ok := os.LookupEnv(jobSecretsEnv)
+	if !ok || os.Getenv(jobIDEnv) == "" {
+		return nil
+	}
+	os.Unsetenv(jobSecretsEnv)
+
+	var secrets jobSecrets
+	if err := json.Unmarshal([]byte(data), &secrets); err != nil {
+		return fmt.Errorf("invalid %s: %w", jobSecretsEnv, err)
+	}
+	cmd, _, err := rootCmd.Find(secrets.Command)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(secrets.Flags) {
+		for _, value := range secrets.Flags[name] {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				return fmt.Errorf("failed to set flag %s of the job: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// startJob starts cmd as a detached job and prints its ID.
+func startJob(cmd *cobra.Command, args []string) error {
+	dir, err := jobsDir(cmd.Root().Name())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+	jobs, err := readJobs(dir)
+	if err != nil {
+		return err
+	}
+	id := 1
+	if len(jobs) > 0 {
+		id = jobs[len(jobs)-1].ID + 1
+	}
+
+	// Creating the log reserves the ID; a job started at the same time that
+	// took the ID first leaves its log, so try the next one
+	var log *os.File
+	for {
+		log, err = os.OpenFile(filepath.Join(dir, strconv.Itoa(id)+".log"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, os.ErrExist) {
+			break
+		}
+		id++
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create job log: %w", err)
+	}
+	defer log.Close()
+
+	state := jobState{ID: id, Command: jobArgs(cmd, args, true), Started: time.Now()}
+	job, err := jobCommand(jobArgs(cmd, args, false))
+	if err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+	job.Stdout = log
+	job.Stderr = log
+	job.Env = append(job.Environ(), jobIDEnv+"="+strconv.Itoa(id))
+	if secrets := sensitiveFlags(cmd); len(secrets) > 0 {
+		data, err := json.Marshal(jobSecrets{Command: strings.Fields(commandKey(cmd)), Flags: secrets})
+		if err != nil {
+			return err
+		}
+		job.Env = append(job.Env, jobSecretsEnv+"="+string(data))
+	}
+	job.SysProcAttr = detachedProcAttr()
+	if err := job.Start(); err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+	state.PID = job.Process.Pid
+	state.ProcessStart, _ = processStart(state.PID)
+	_ = job.Process.Release()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(id)+".json"), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write job state: %w", err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Started job %d (pid %d); see its output with: %s jobs logs %d\n", id, state.PID, cmd.Root().Name(), id)
+	return nil
+}
+
+// readJobs returns the jobs in dir ordered by ID.
+func readJobs(dir string) ([]jobState, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var jobs []jobState
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var job jobState
+		if err := json.Unmarshal(data, &job); err != nil {
+			return nil, fmt.Errorf("invalid job state %s: %w", entry.Name(), err)
+		}
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs, nil
+}
+
+// jobStatus returns "done" or "failed" for a job that recorded how its handler
+// finished, "running" while its process is alive, "unknown" when a process with
+// its PID is alive but cannot be told apart from a later process reusing the
+// PID, and "exited" otherwise.
+func jobStatus(dir string, job jobState) string {
+	data, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(job.ID)+".exit"))
+	if err == nil {
+		var exit jobExit
+		if json.Unmarshal(data, &exit) == nil && exit.Error == "" {
+			return "done"
+		}
+		return "failed"
+	}
+	if !processAlive(job.PID) {
+		return "exited"
+	}
+	if job.ProcessStart == "" {
+		return "unknown"
+	}
+	if start, err := processStart(job.PID); err != nil || start != job.ProcessStart {
+		return "exited"
+	}
+	return "running"
+}
+
+// addJobsCommands adds the "jobs list", "jobs logs" and "jobs kill" commands when
+// any of the built commands can run in the background.
+func (cb *CommandBuilder) addJobsCommands(rootCmd *cobra.Command, commands map[string]CommandConfig) {
+	if !hasBackground(commands) {
+		return
+	}
+
+	jobsCmd := findSubcommand(rootCmd, "jobs")
+	if jobsCmd == nil {
+		jobsCmd = &cobra.Command{
+			Use:   "jobs",
+			Short: "Manage commands running in the background",
+		}
+		rootCmd.AddCommand(jobsCmd)
+	}
+	if findSubcommand(jobsCmd, "list") == nil {
+		jobsCmd.AddCommand(buildJobsListCommand())
+	}
+	if findSubcommand(jobsCmd, "logs") == nil {
+		jobsCmd.AddCommand(buildJobsLogsCommand())
+	}
+	if findSubcommand(jobsCmd, "kill") == nil {
+		jobsCmd.AddCommand(buildJobsKillCommand())
+	}
+}
+
+// hasBackground reports whether any of cmds or their subcommands can run in the background.
+func hasBackground(cmds map[string]CommandConfig) bool {
+	for _, cmd := range cmds {
+		if cmd.Background != "" || hasBackground(cmd.Commands) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildJobsListCommand builds the "jobs list" command.
+func buildJobsListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List background jobs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := jobsDir(cmd.Root().Name())
+			if err != nil {
+				return err
+			}
+			jobs, err := readJobs(dir)
+			if err != nil {
+				return err
+			}
+			if len(jobs) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No jobs")
+				return nil
+			}
+
+			table := newOutputTable(cmd, "ID", "STATUS", "PID", "STARTED", "COMMAND")
+			for _, job := range jobs {
+				table.Row(strconv.Itoa(job.ID), jobStatus(dir, job), strconv.Itoa(job.PID),
+					job.Started.Format(time.DateTime), strings.Join(job.Command, " "))
+			}
+			return table.Flush()
+		},
+	}
+}
+
+// buildJobsLogsCommand builds the "jobs logs" command that prints the output of a job.
+func buildJobsLogsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logs <id>",
+		Short: "Print the output of a background job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, job, err := findJob(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			log, err := os.Open(filepath.Join(dir, strconv.Itoa(job.ID)+".log"))
+			if err != nil {
+				return fmt.Errorf("failed to read job log: %w", err)
+			}
+			defer log.Close()
+			_, err = io.Copy(cmd.OutOrStdout(), log)
+			return err
+		},
+	}
+}
+
+// buildJobsKillCommand builds the "jobs kill" command that stops a running job.
+func buildJobsKillCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "kill <id>",
+		Short: "Stop a running background job",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, job, err := findJob(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			// Only signal the PID while it is still the job's process
+			if status := jobStatus(dir, job); status != "running" {
+				return fmt.Errorf("job %d is not running (%s)", job.ID, status)
+			}
+			if err := terminateProcess(job.PID); err != nil {
+				return fmt.Errorf("failed to stop job %d: %w", job.ID, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Stopped job %d\n", job.ID)
+			return nil
+		},
+	}
+}
+
+// findJob returns the jobs directory and the job with the given ID.
+func findJob(cmd *cobra.Command, id string) (string, jobState, error) {
+	dir, err := jobsDir(cmd.Root().Name())
+	if err != nil {
+		return "", jobState{}, err
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return "", jobState{}, fmt.Errorf("invalid job ID %q", id)
+	}
+	jobs, err := readJobs(dir)
+	if err != nil {
+		return "", jobState{}, err
+	}
+	for _, job := range jobs {
+		if job.ID == n {
+			return dir, job, nil
+		}
+	}
+	return "", jobState{}, fmt.Errorf("job %d not found", n)
+}
@@ -0,0 +1,254 @@
+package cobrayaml
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const jobsYAML = `
+name: jobs-test
+root:
+  use: jobs-test
+  short: Jobs test
+commands:
+  sync:
+    use: sync <source>
+    short: Sync a source
+    run_func: runSync
+    background: supported
+    args:
+      type: exact
+      count: 1
+    flags:
+      - name: tag
+        type: stringSlice
+        usage: Tags
+      - name: wait
+        type: duration
+        usage: Time to wait before syncing
+      - name: token
+        type: string
+        usage: Access token
+        sensitive: true
+`
+
+// jobsTool is the jobs test CLI.
+var jobsTool = testTool{yaml: jobsYAML, funcs: map[string]any{
+	"runSync": func(cmd *cobra.Command, args []string) error {
+		wait, _ := cmd.Flags().GetDuration("wait")
+		time.Sleep(wait)
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		token, _ := cmd.Flags().GetString("token")
+		cmd.Printf("synced %s %v %s\n", args[0], tags, token)
+		return nil
+	},
+}}
+
+// TestJobHelperProcess is not a real test; startJob runs it as the detached job.
+func TestJobHelperProcess(t *testing.T) {
+	if os.Getenv("COBRAYAML_TEST_JOB_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	rootCmd := jobsTool.build(t)
+	rootCmd.SetArgs(args)
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestCommandBuilder_BackgroundJobs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var started [][]string
+	orig := jobCommand
+	jobCommand = func(args []string) (*exec.Cmd, error) {
+		started = append(started, args)
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=TestJobHelperProcess", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "COBRAYAML_TEST_JOB_HELPER=1")
+		return cmd, nil
+	}
+	defer func() { jobCommand = orig }()
+
+	out, err := jobsTool.run(t, "sync", "--detach", "--tag", "a,b", "--tag=c", "--token", "s3cr3t", "db")
+	if err != nil {
+		t.Fatalf("sync --detach error = %v", err)
+	}
+	if !strings.HasPrefix(out, "Started job 1 (pid ") || !strings.Contains(out, "jobs-test jobs logs 1") {
+		t.Errorf("sync --detach output = %q", out)
+	}
+	// The token is passed in the environment, not on the command line
+	want := []string{"sync", "--tag=a", "--tag=b", "--tag=c", "db"}
+	if len(started) != 1 || !reflect.DeepEqual(started[0], want) {
+		t.Fatalf("job args = %q, want %q", started, want)
+	}
+
+	dir, err := jobsDir("jobs-test")
+	if err != nil {
+		t.Fatalf("jobsDir() error = %v", err)
+	}
+	waitForFile(t, filepath.Join(dir, "1.exit"))
+
+	out, err = jobsTool.run(t, "jobs", "logs", "1")
+	if err != nil || out != "synced db [a b c] s3cr3t\n" {
+		t.Errorf("jobs logs 1 = %q, %v, want the job output", out, err)
+	}
+	jobs, err := readJobs(dir)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("readJobs() = %v, %v, want one job", jobs, err)
+	}
+	if want := []string{"sync", "--tag=a", "--tag=b", "--tag=c", "--token=<redacted>", "db"}; !reflect.DeepEqual(jobs[0].Command, want) {
+		t.Errorf("job command = %q, want %q", jobs[0].Command, want)
+	}
+	for _, name := range []string{"1.json", "1.exit"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && info.Mode().Perm() != 0o600 {
+			t.Errorf("%s mode = %v, want 0600", name, info.Mode().Perm())
+		}
+	}
+
+	if _, err := jobsTool.run(t, "sync", "--detach", "--wait", "1m", "slow"); err != nil {
+		t.Fatalf("sync --detach error = %v", err)
+	}
+	out, err = jobsTool.run(t, "jobs", "list")
+	if err != nil {
+		t.Fatalf("jobs list error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("jobs list = %q, want a header and two jobs", out)
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "1" || fields[1] != "done" {
+		t.Errorf("jobs list job 1 = %q, want done", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "2" || fields[1] != "running" || !strings.HasSuffix(lines[2], "sync --wait=1m0s slow") {
+		t.Errorf("jobs list job 2 = %q, want running", lines[2])
+	}
+
+	if out, err := jobsTool.run(t, "jobs", "kill", "2"); err != nil || out != "Stopped job 2\n" {
+		t.Errorf("jobs kill 2 = %q, %v", out, err)
+	}
+	if _, err := jobsTool.run(t, "jobs", "kill", "1"); err == nil || !strings.Contains(err.Error(), "job 1 is not running (done)") {
+		t.Errorf("jobs kill 1 error = %v, want not running", err)
+	}
+	if _, err := jobsTool.run(t, "jobs", "logs", "9"); err == nil || !strings.Contains(err.Error(), "job 9 not found") {
+		t.Errorf("jobs logs 9 error = %v, want not found", err)
+	}
+
+	// A job that has taken ID 3 but not written its state yet keeps it
+	if err := os.WriteFile(filepath.Join(dir, "3.log"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := jobsTool.run(t, "sync", "--detach", "db"); err != nil || !strings.HasPrefix(out, "Started job 4 ") {
+		t.Errorf("sync --detach = %q, %v, want job 4", out, err)
+	}
+	waitForFile(t, filepath.Join(dir, "4.exit"))
+}
+
+func TestJobStatus_ReusedPID(t *testing.T) {
+	dir := t.TempDir()
+	start, err := processStart(os.Getpid())
+	if err != nil {
+		t.Skipf("processStart() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		start string
+		want  string
+	}{
+		{name: "same process", start: start, want: "running"},
+		{name: "PID reused", start: start + "0", want: "exited"},
+		{name: "no start time", want: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := jobState{ID: 1, PID: os.Getpid(), ProcessStart: tt.start}
+			if got := jobStatus(dir, job); got != tt.want {
+				t.Errorf("jobStatus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// waitForFile waits until path exists.
+func waitForFile(t *testing.T, path string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", path)
+}
+
+func TestValidateConfig_Background(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "supported",
+			command: CommandConfig{RunFunc: "runSync", Background: BackgroundSupported},
+		},
+		{
+			name:    "unknown mode",
+			command: CommandConfig{RunFunc: "runSync", Background: "always"},
+			wantErr: `background "always" is not supported`,
+		},
+		{
+			name:    "no run_func",
+			command: CommandConfig{Background: BackgroundSupported},
+			wantErr: "background requires run_func",
+		},
+		{
+			name: "detach flag",
+			command: CommandConfig{
+				RunFunc:    "runSync",
+				Background: BackgroundSupported,
+				Flags:      []FlagConfig{{Name: "detach", Type: FlagTypeBool, Usage: "Detach"}},
+			},
+			wantErr: "conflicts with the flag added by background",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.command.Use = "sync"
+			tt.command.Short = "Sync"
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"sync": tt.command},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
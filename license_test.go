@@ -0,0 +1,178 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const licenseYAML = `
+name: test-cli
+license:
+  spdx: Apache-2.0
+  holder: Example Corp.
+  year: 2026
+root:
+  use: test-cli
+  short: Test CLI
+commands:
+  get:
+    use: get
+    short: Get items
+    run_func: runGet
+    flags:
+      - name: output
+        type: string
+        usage: Output format
+        allowed_values: [json, yaml]
+`
+
+func TestGenerator_LicenseHeader(t *testing.T) {
+	gen, err := NewGeneratorFromString(licenseYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	header := "// Copyright 2026 Example Corp.\n// SPDX-License-Identifier: Apache-2.0\n\n// Code generated by cobrayaml. DO NOT EDIT.\n"
+	generators := map[string]func() (string, error){
+		"handlers": func() (string, error) { return gen.GenerateHandlers("main") },
+		"main":     func() (string, error) { return gen.GenerateMain("main", "commands.yaml") },
+		"enums":    func() (string, error) { return gen.GenerateEnums("main") },
+	}
+	for name, generate := range generators {
+		code, err := generate()
+		if err != nil {
+			t.Fatalf("generating %s: error = %v", name, err)
+		}
+		if !strings.HasPrefix(code, header) {
+			t.Errorf("%s should start with the license header, got:\n%s", name, code)
+		}
+	}
+}
+
+func TestGenerator_LicenseHeader_Variants(t *testing.T) {
+	tests := []struct {
+		name    string
+		license *LicenseConfig
+		want    string
+	}{
+		{
+			name: "no license",
+		},
+		{
+			name:    "spdx only",
+			license: &LicenseConfig{SPDX: "MIT"},
+			want:    "// SPDX-License-Identifier: MIT\n\n",
+		},
+		{
+			name:    "holder without year",
+			license: &LicenseConfig{SPDX: "MIT", Holder: "Jane Doe"},
+			want:    "// Copyright Jane Doe\n// SPDX-License-Identifier: MIT\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &Generator{config: &ToolConfig{Name: "test", License: tt.license}}
+			if got := gen.licenseHeader(); got != tt.want {
+				t.Errorf("licenseHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateNotice(t *testing.T) {
+	gen, err := NewGeneratorFromString(licenseYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), NoticeFile)
+	if err := gen.GenerateNoticeToFile(path); err != nil {
+		t.Fatalf("GenerateNoticeToFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	notice := string(data)
+
+	for _, want := range []string{
+		"test-cli is licensed under Apache-2.0. Copyright 2026 Example Corp.",
+		"| github.com/S-mishina/cobrayaml | ",
+		"| github.com/spf13/cobra | v",
+		"| github.com/spf13/pflag | v",
+		" | BSD-3-Clause |",
+		"| gopkg.in/yaml.v2 | v",
+	} {
+		if !strings.Contains(notice, want) {
+			t.Errorf("notice should contain %q, got:\n%s", want, notice)
+		}
+	}
+}
+
+func TestGenerateInitTemplateWithOptions_License(t *testing.T) {
+	license := &LicenseConfig{SPDX: "Apache-2.0", Holder: "Example Corp.", Year: 2026}
+	template, err := GenerateInitTemplateWithOptions("test-app", InitOptions{Examples: true, License: license})
+	if err != nil {
+		t.Fatalf("GenerateInitTemplateWithOptions() error = %v", err)
+	}
+
+	gen, err := NewGeneratorFromString(template)
+	if err != nil {
+		t.Fatalf("template should load, got error = %v", err)
+	}
+	if got := gen.License(); got == nil || *got != *license {
+		t.Errorf("License() = %+v, want %+v", got, license)
+	}
+}
+
+func TestValidateConfig_License(t *testing.T) {
+	tests := []struct {
+		name    string
+		license *LicenseConfig
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			license: &LicenseConfig{SPDX: "Apache-2.0", Holder: "Example Corp.", Year: 2026},
+		},
+		{
+			name:    "missing spdx",
+			license: &LicenseConfig{Holder: "Example Corp."},
+			wantErr: "license: spdx is required",
+		},
+		{
+			name:    "multi-line holder",
+			license: &LicenseConfig{SPDX: "MIT", Holder: "Example\npackage evil"},
+			wantErr: "license: spdx and holder must be a single line",
+		},
+		{
+			name:    "negative year",
+			license: &LicenseConfig{SPDX: "MIT", Year: -1},
+			wantErr: "license: year must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name:    "test",
+				Root:    CommandConfig{Use: "test", Short: "Test"},
+				License: tt.license,
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Output formats of GenerateToolManifest.
+const (
+	// ManifestFormatMCP generates the result of an MCP tools/list request.
+	ManifestFormatMCP = "mcp"
+	// ManifestFormatOpenAI generates a list of OpenAI function tools.
+	ManifestFormatOpenAI = "openai"
+)
+
+// SupportedManifestFormats lists all output formats of GenerateToolManifest.
+var SupportedManifestFormats = []string{
+	ManifestFormatMCP,
+	ManifestFormatOpenAI,
+}
+
+// manifestArgs is the parameter holding the positional args of a command.
+const manifestArgs = "args"
+
+// manifestSchema is the JSON Schema of the parameters of a tool.
+type manifestSchema struct {
+	Type                 string                     `json:"type"`
+	Description          string                     `json:"description,omitempty"`
+	Properties           map[string]*manifestSchema `json:"properties,omitempty"`
+	Required             []string                   `json:"required,omitempty"`
+	AdditionalProperties *bool                      `json:"additionalProperties,omitempty"`
+	Items                *manifestSchema            `json:"items,omitempty"`
+	Enum                 []string                   `json:"enum,omitempty"`
+	Default              any                        `json:"default,omitempty"`
+	Minimum              *int                       `json:"minimum,omitempty"`
+	MinItems             *int                       `json:"minItems,omitempty"`
+	MaxItems             *int                       `json:"maxItems,omitempty"`
+}
+
+// manifestTool is a runnable command described as a tool for AI agents.
+type manifestTool struct {
+	name        string
+	path        []string
+	description string
+	flags       map[string]FlagConfig
+	rawArgs     bool
+	sideEffects string
+	schema      *manifestSchema
+}
+
+// invalidToolName matches the characters not allowed in MCP and OpenAI tool names.
+var invalidToolName = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// GenerateToolManifest describes the runnable, visible commands as tools for
+// AI agents and MCP servers: a name such as mytool_db_backup, the short and long
+// description, and a JSON Schema of the parameters with a property per flag and
+// an "args" array of the positional args. Sensitive flags are left out, so
+// secrets are never asked from the model; pass them through the environment or
+// config file instead. Use ToolCallArgs to turn a call of a tool into the args
+// of the CLI.
+//
+// Commands with side_effects are marked: MCP tools get readOnlyHint and
+// destructiveHint annotations, and OpenAI tools of destructive commands a
+// warning in the description. Destructive commands ask for confirmation, so
+// confirm a call with the user and run it with --yes.
+func (g *Generator) GenerateToolManifest(format string) (string, error) {
+	tools := manifestTools(g.config)
+
+	var manifest any
+	switch format {
+	case "", ManifestFormatMCP:
+		type mcpAnnotations struct {
+			ReadOnlyHint    bool `json:"readOnlyHint"`
+			DestructiveHint bool `json:"destructiveHint"`
+		}
+		type mcpTool struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			InputSchema *manifestSchema `json:"inputSchema"`
+			Annotations *mcpAnnotations `json:"annotations,omitempty"`
+		}
+		list := []mcpTool{}
+		for _, tool := range tools {
+			var annotations *mcpAnnotations
+			if tool.sideEffects != "" {
+				annotations = &mcpAnnotations{
+					ReadOnlyHint:    tool.sideEffects == SideEffectsReadOnly,
+					DestructiveHint: tool.sideEffects == SideEffectsDestructive,
+				}
+			}
+			list = append(list, mcpTool{tool.name, tool.description, tool.schema, annotations})
+		}
+		manifest = map[string]any{"tools": list}
+	case ManifestFormatOpenAI:
+		type function struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			Parameters  *manifestSchema `json:"parameters"`
+		}
+		type openAITool struct {
+			Type     string   `json:"type"`
+			Function function `json:"function"`
+		}
+		list := []openAITool{}
+		for _, tool := range tools {
+			description := tool.description
+			if tool.sideEffects == SideEffectsDestructive {
+				// Function tools have no annotations, so warn the model in the description
+				description += "\n\nThis command is destructive: confirm with the user before calling it."
+			}
+			list = append(list, openAITool{"function", function{tool.name, description, tool.schema}})
+		}
+		manifest = list
+	default:
+		return "", fmt.Errorf("unsupported manifest format %q (supported: %s)", format, strings.Join(SupportedManifestFormats, ", "))
+	}
+
+	// Keep descriptions such as "<env>" readable
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ToolCallArgs returns the args that run the command of the tool named name,
+// from GenerateToolManifest, with the parameters of a call decoded from JSON.
+// The args start with the names of the command, without the tool name. It
+// rejects parameters the tool does not have, such as sensitive flags, and calls
+// missing required parameters.
+func (g *Generator) ToolCallArgs(name string, params map[string]any) ([]string, error) {
+	var tool *manifestTool
+	for _, t := range manifestTools(g.config) {
+		if t.name == name {
+			tool = &t
+			break
+		}
+	}
+	if tool == nil {
+		return nil, fmt.Errorf("unknown tool %q", name)
+	}
+	for _, required := range tool.schema.Required {
+		if _, exists := params[required]; !exists {
+			return nil, fmt.Errorf("tool %s: missing required parameter %q", name, required)
+		}
+	}
+
+	argv := append([]string{}, tool.path...)
+	var args []string
+	for _, key := range sortedKeys(params) {
+		value := params[key]
+		if key == manifestArgs && tool.schema.Properties[manifestArgs] != nil {
+			values, err := manifestStrings(value)
+			if err != nil {
+				return nil, fmt.Errorf("tool %s: parameter %q: %w", name, key, err)
+			}
+			args = values
+			continue
+		}
+		flag, exists := tool.flags[key]
+		if !exists {
+			return nil, fmt.Errorf("tool %s: unknown parameter %q", name, key)
+		}
+		values, err := manifestStrings(value)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: parameter %q: %w", name, key, err)
+		}
+		for _, v := range values {
+			argv = append(argv, "--"+flag.Name+"="+v)
+		}
+	}
+
+	if !tool.rawArgs && slices.ContainsFunc(args, func(arg string) bool { return strings.HasPrefix(arg, "-") }) {
+		argv = append(argv, "--")
+	}
+	return append(argv, args...), nil
+}
+
+// manifestStrings converts a parameter value decoded from JSON into flag or
+// arg values.
+func manifestStrings(value any) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}, nil
+	case json.Number:
+		return []string{v.String()}, nil
+	case []any:
+		var values []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("array items must be strings")
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	case []string:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %T", value)
+	}
+}
+
+// manifestTools returns the tools of the runnable, visible commands of config
+// sorted by path, starting with the root command when it has a run_func.
+func manifestTools(config *ToolConfig) []manifestTool {
+	var tools []manifestTool
+	if config.Root.RunFunc != "" {
+		tools = append(tools, newManifestTool(config.Name, nil, config.Root, nil))
+	}
+
+	var walk func(names []string, cmds map[string]CommandConfig, inherited []FlagConfig)
+	walk = func(names []string, cmds map[string]CommandConfig, inherited []FlagConfig) {
+		for _, name := range sortedKeys(cmds) {
+			cmd := cmds[name]
+			if cmd.Hidden || cmd.Deprecated != "" {
+				continue
+			}
+			path := append(append([]string{}, names...), name)
+			if cmd.RunFunc != "" {
+				tools = append(tools, newManifestTool(config.Name, path, cmd, inherited))
+			}
+			walk(path, cmd.Commands, persistentFlags(inherited, cmd.Flags))
+		}
+	}
+	walk(nil, config.Commands, persistentFlags(nil, config.Root.Flags))
+	return tools
+}
+
+// newManifestTool describes the command at path with its own flags and the
+// persistent flags it inherits.
+func newManifestTool(toolName string, path []string, config CommandConfig, inherited []FlagConfig) manifestTool {
+	description := config.Short
+	if config.Long != "" {
+		description = strings.TrimSpace(description + "\n\n" + config.Long)
+	}
+	noExtra := false
+	tool := manifestTool{
+		name:        invalidToolName.ReplaceAllString(strings.Join(append([]string{toolName}, path...), "_"), "_"),
+		path:        path,
+		description: description,
+		flags:       make(map[string]FlagConfig),
+		rawArgs:     config.DisableFlagParsing,
+		sideEffects: config.SideEffects,
+		schema: &manifestSchema{
+			Type:                 "object",
+			Properties:           make(map[string]*manifestSchema),
+			AdditionalProperties: &noExtra,
+		},
+	}
+
+	if !config.DisableFlagParsing {
+		for _, flag := range append(append([]FlagConfig{}, config.Flags...), inherited...) {
+			if flag.Hidden || flag.Deprecated != "" || flag.Sensitive || flag.Name == manifestArgs {
+				continue
+			}
+			tool.flags[flag.Name] = flag
+			tool.schema.Properties[flag.Name] = flagSchema(flag)
+			if flag.Required {
+				tool.schema.Required = append(tool.schema.Required, flag.Name)
+			}
+		}
+	}
+
+	if args := argsSchema(config); args != nil {
+		tool.schema.Properties[manifestArgs] = args
+		if args.MinItems != nil && *args.MinItems > 0 {
+			tool.schema.Required = append(tool.schema.Required, manifestArgs)
+		}
+	}
+	return tool
+}
+
+// flagSchema returns the JSON Schema of the value of a flag.
+func flagSchema(flag FlagConfig) *manifestSchema {
+	schema := &manifestSchema{Type: "string", Description: flag.Usage, Enum: flag.AllowedValues}
+	switch flag.Type {
+	case FlagTypeBool:
+		schema.Type = "boolean"
+		if value, err := strconv.ParseBool(flag.DefaultValue); err == nil && value {
+			schema.Default = true
+		}
+		return schema
+	case FlagTypeInt, FlagTypeUint, FlagTypeUint64:
+		schema.Type = "integer"
+		if flag.Type != FlagTypeInt {
+			zero := 0
+			schema.Minimum = &zero
+		}
+		if value, err := strconv.Atoi(flag.DefaultValue); err == nil && value != 0 {
+			schema.Default = value
+		}
+		return schema
+	case FlagTypeStringSlice, FlagTypeStringArray:
+		schema = &manifestSchema{Type: "array", Description: flag.Usage, Items: &manifestSchema{Type: "string", Enum: flag.AllowedValues}}
+		if def := strings.Trim(flag.DefaultValue, "[]"); def != "" {
+			schema.Default = strings.Split(def, ",")
+		}
+		return schema
+	case FlagTypeDuration:
+		schema.Description = strings.TrimSpace(schema.Description + ` (a duration such as "30s" or "5m")`)
+	case FlagTypeByteSize:
+		schema.Description = strings.TrimSpace(schema.Description + ` (a size such as "512KB" or "1GiB")`)
+	}
+	if flag.DefaultValue != "" {
+		schema.Default = flag.DefaultValue
+	}
+	return schema
+}
+
+// argsSchema returns the JSON Schema of the positional args of a command, or
+// nil if it takes none.
+func argsSchema(config CommandConfig) *manifestSchema {
+	schema := &manifestSchema{
+		Type:  "array",
+		Items: &manifestSchema{Type: "string"},
+	}
+	names := slices.DeleteFunc(strings.Fields(config.Use)[1:], func(token string) bool { return token == "[flags]" })
+	if len(names) > 0 {
+		schema.Description = "Positional args: " + strings.Join(names, " ")
+	}
+	if len(config.ValidArgs) > 0 && (config.Args == nil || config.Args.OnlyValid || config.Args.Type == ArgsTypeOnlyValidArgs) {
+		schema.Items.Enum = config.ValidArgs
+	}
+	if config.DisableFlagParsing {
+		schema.Description = "All args, including flags, passed as they are"
+	}
+
+	bound := func(n int) *int { return &n }
+	if config.Args != nil {
+		switch config.Args.Type {
+		case ArgsTypeNone:
+			return nil
+		case ArgsTypeExact:
+			schema.MinItems, schema.MaxItems = bound(config.Args.Count), bound(config.Args.Count)
+		case ArgsTypeMin:
+			schema.MinItems = bound(config.Args.Min)
+		case ArgsTypeMax:
+			schema.MaxItems = bound(config.Args.Max)
+		case ArgsTypeRange:
+			schema.MinItems, schema.MaxItems = bound(config.Args.Min), bound(config.Args.Max)
+		}
+	} else if schema.Description == "" && len(config.ValidArgs) == 0 {
+		// Commands that name no args in use and do not validate them take none
+		return nil
+	}
+	return schema
+}
+
+// persistentFlags returns inherited followed by the persistent flags of flags.
+func persistentFlags(inherited, flags []FlagConfig) []FlagConfig {
+	result := append([]FlagConfig{}, inherited...)
+	for _, flag := range flags {
+		if flag.Persistent {
+			result = append(result, flag)
+		}
+	}
+	return result
+}
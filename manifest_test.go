@@ -0,0 +1,251 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const manifestYAML = `
+name: my.tool
+root:
+  use: my.tool
+  short: Manifest test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy the app
+    long: Deploy the app to an environment.
+    run_func: runDeploy
+    args:
+      type: exact
+      count: 1
+      only_valid: true
+    valid_args: [dev, prod]
+    flags:
+      - name: replicas
+        type: uint
+        default: "2"
+        usage: Number of replicas
+      - name: strategy
+        type: string
+        default: rolling
+        allowed_values: [rolling, canary]
+        usage: Rollout strategy
+      - name: label
+        type: stringSlice
+        usage: Labels to set
+      - name: timeout
+        type: duration
+        required: true
+        usage: Rollout timeout
+      - name: token
+        type: string
+        sensitive: true
+        usage: API token
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+      restore:
+        use: restore
+        short: Restore the database
+        run_func: runRestore
+        deprecated: use backup restore
+  kubectl:
+    use: kubectl [args...]
+    short: Run kubectl
+    run_func: runKubectl
+    disable_flag_parsing: true
+`
+
+func TestGenerator_GenerateToolManifest(t *testing.T) {
+	gen, err := NewGeneratorFromString(manifestYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	out, err := gen.GenerateToolManifest(ManifestFormatMCP)
+	if err != nil {
+		t.Fatalf("GenerateToolManifest() error = %v", err)
+	}
+
+	var manifest struct {
+		Tools []struct {
+			Name        string         `json:"name"`
+			Description string         `json:"description"`
+			InputSchema map[string]any `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(out), &manifest); err != nil {
+		t.Fatalf("manifest is not JSON: %v\n%s", err, out)
+	}
+	var names []string
+	for _, tool := range manifest.Tools {
+		names = append(names, tool.Name)
+	}
+	if want := []string{"my_tool_db_backup", "my_tool_deploy", "my_tool_kubectl"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("tools = %v, want %v", names, want)
+	}
+
+	deploy := manifest.Tools[1]
+	if deploy.Description != "Deploy the app\n\nDeploy the app to an environment." {
+		t.Errorf("description = %q", deploy.Description)
+	}
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"args": map[string]any{
+				"type":        "array",
+				"description": "Positional args: <env>",
+				"items":       map[string]any{"type": "string", "enum": []any{"dev", "prod"}},
+				"minItems":    float64(1),
+				"maxItems":    float64(1),
+			},
+			"replicas": map[string]any{"type": "integer", "description": "Number of replicas", "default": float64(2), "minimum": float64(0)},
+			"strategy": map[string]any{"type": "string", "description": "Rollout strategy", "enum": []any{"rolling", "canary"}, "default": "rolling"},
+			"label":    map[string]any{"type": "array", "description": "Labels to set", "items": map[string]any{"type": "string"}},
+			"timeout":  map[string]any{"type": "string", "description": `Rollout timeout (a duration such as "30s" or "5m")`},
+			"verbose":  map[string]any{"type": "boolean", "description": "Verbose output"},
+		},
+		"required":             []any{"timeout", "args"},
+		"additionalProperties": false,
+	}
+	if !reflect.DeepEqual(deploy.InputSchema, want) {
+		got, _ := json.MarshalIndent(deploy.InputSchema, "", "  ")
+		t.Errorf("inputSchema =\n%s", got)
+	}
+
+	backup := manifest.Tools[0].InputSchema["properties"].(map[string]any)
+	if _, exists := backup["args"]; exists {
+		t.Error("backup should take no args")
+	}
+	kubectl := manifest.Tools[2].InputSchema["properties"].(map[string]any)
+	if len(kubectl) != 1 || kubectl["args"] == nil {
+		t.Errorf("kubectl properties = %v, want only args", kubectl)
+	}
+}
+
+func TestGenerator_GenerateToolManifest_OpenAI(t *testing.T) {
+	gen, err := NewGeneratorFromString(manifestYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	out, err := gen.GenerateToolManifest(ManifestFormatOpenAI)
+	if err != nil {
+		t.Fatalf("GenerateToolManifest() error = %v", err)
+	}
+
+	var tools []struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name       string         `json:"name"`
+			Parameters map[string]any `json:"parameters"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal([]byte(out), &tools); err != nil {
+		t.Fatalf("manifest is not JSON: %v\n%s", err, out)
+	}
+	if len(tools) != 3 || tools[0].Type != "function" || tools[0].Function.Name != "my_tool_db_backup" {
+		t.Errorf("tools = %+v", tools)
+	}
+
+	if _, err := gen.GenerateToolManifest("yaml"); err == nil || !strings.Contains(err.Error(), `unsupported manifest format "yaml"`) {
+		t.Errorf("GenerateToolManifest(yaml) error = %v", err)
+	}
+}
+
+func TestGenerator_ToolCallArgs(t *testing.T) {
+	gen, err := NewGeneratorFromString(manifestYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		tool    string
+		params  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "flags and args",
+			tool:   "my_tool_deploy",
+			params: `{"args": ["prod"], "replicas": 3, "label": ["a=1", "b=2"], "timeout": "5m", "verbose": true}`,
+			want:   []string{"deploy", "--label=a=1", "--label=b=2", "--replicas=3", "--timeout=5m", "--verbose=true", "prod"},
+		},
+		{
+			name:   "nested command",
+			tool:   "my_tool_db_backup",
+			params: `{}`,
+			want:   []string{"db", "backup"},
+		},
+		{
+			name:   "raw args",
+			tool:   "my_tool_kubectl",
+			params: `{"args": ["get", "-n", "kube-system"]}`,
+			want:   []string{"kubectl", "get", "-n", "kube-system"},
+		},
+		{
+			name:    "unknown tool",
+			tool:    "my_tool_db_restore",
+			params:  `{}`,
+			wantErr: `unknown tool "my_tool_db_restore"`,
+		},
+		{
+			name:    "sensitive flag",
+			tool:    "my_tool_deploy",
+			params:  `{"args": ["prod"], "timeout": "5m", "token": "s3cret"}`,
+			wantErr: `tool my_tool_deploy: unknown parameter "token"`,
+		},
+		{
+			name:    "missing required",
+			tool:    "my_tool_deploy",
+			params:  `{"args": ["prod"]}`,
+			wantErr: `tool my_tool_deploy: missing required parameter "timeout"`,
+		},
+		{
+			name:    "args of a command without args",
+			tool:    "my_tool_db_backup",
+			params:  `{"args": ["x"]}`,
+			wantErr: `tool my_tool_db_backup: unknown parameter "args"`,
+		},
+		{
+			name:    "invalid value",
+			tool:    "my_tool_deploy",
+			params:  `{"args": [1], "timeout": "5m"}`,
+			wantErr: `tool my_tool_deploy: parameter "args": array items must be strings`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var params map[string]any
+			if err := json.Unmarshal([]byte(tt.params), &params); err != nil {
+				t.Fatal(err)
+			}
+			got, err := gen.ToolCallArgs(tt.tool, params)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ToolCallArgs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToolCallArgs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ToolCallArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
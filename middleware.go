@@ -0,0 +1,60 @@
+package cobrayaml
+
+import "github.com/spf13/cobra"
+
+// Middleware wraps the run function of a command, e.g. to log runs, check
+// authorization or recover from panics:
+//
+//	builder.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+//		return func(cmd *cobra.Command, args []string) error {
+//			start := time.Now()
+//			err := next(cmd, args)
+//			log.Printf("%s took %s", cmd.CommandPath(), time.Since(start))
+//			return err
+//		}
+//	})
+type Middleware func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error
+
+// Use adds middleware that wraps the run function of every command built from
+// YAML with a run_func, including the root command and dynamic commands.
+// Middleware runs in the order it was added: the first wraps all others, and
+// the last wraps the run. The chain sits outside the wrappers of cobrayaml
+// features such as the result cache, notifications, events and background
+// jobs, so it also runs when a cached result is served or a job is started,
+// and outside the checks before the run, such as quotas, confirmation prompts,
+// validate_func and create_missing, so middleware that refuses a run does so
+// before any of them takes effect. Only the error catalog sits outside it, so
+// catalog errors it returns are printed with their hint. Use must be called before BuildRootCommand or
+// AttachTo.
+func (cb *CommandBuilder) Use(mw ...Middleware) {
+	cb.middleware = append(cb.middleware, mw...)
+}
+
+// addMiddleware wraps the run function of cmd in the middleware added with Use.
+// The PreRunE hook moves into the wrapped run, so middleware that refuses a run
+// does so before quotas are charged, prompts are shown or paths are created.
+func (cb *CommandBuilder) addMiddleware(cmd *cobra.Command) {
+	if cmd.RunE == nil || len(cb.middleware) == 0 {
+		return
+	}
+	run := cmd.RunE
+	if preRun := cmd.PreRunE; preRun != nil {
+		cmd.PreRunE = nil
+		runE := run
+		run = func(cmd *cobra.Command, args []string) error {
+			if err := preRun(cmd, args); err != nil {
+				return err
+			}
+			return runE(cmd, args)
+		}
+	}
+	cmd.RunE = cb.applyMiddleware(run)
+}
+
+// applyMiddleware wraps run in the middleware added with Use.
+func (cb *CommandBuilder) applyMiddleware(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	for i := len(cb.middleware) - 1; i >= 0; i-- {
+		run = cb.middleware[i](run)
+	}
+	return run
+}
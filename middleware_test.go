@@ -0,0 +1,189 @@
+package cobrayaml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const middlewareYAML = `
+name: mw
+root:
+  use: mw
+  short: Middleware test
+  run_func: runRoot
+commands:
+  db:
+    use: db
+    short: Database
+    commands:
+      backup:
+        use: backup
+        short: Back up
+        run_func: runBackup
+  crash:
+    use: crash
+    short: Panic
+    run_func: runCrash
+`
+
+func TestCommandBuilder_Use(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+			return func(cmd *cobra.Command, args []string) error {
+				calls = append(calls, name+" before "+cmd.Name())
+				err := next(cmd, args)
+				calls = append(calls, name+" after "+cmd.Name())
+				return err
+			}
+		}
+	}
+	recovery := func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+		return func(cmd *cobra.Command, args []string) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("recovered: %v", r)
+				}
+			}()
+			return next(cmd, args)
+		}
+	}
+
+	run := func(cmd *cobra.Command, args []string) {
+		calls = append(calls, "run "+cmd.Name())
+	}
+	rootCmd := testTool{
+		yaml: middlewareYAML,
+		funcs: map[string]any{
+			"runRoot":   run,
+			"runBackup": run,
+			"runCrash":  func(cmd *cobra.Command, args []string) { panic("boom") },
+		},
+		setup: func(cb *CommandBuilder) {
+			cb.Use(recovery, trace("outer"))
+			cb.Use(trace("inner"))
+		},
+	}.build(t)
+
+	for _, args := range [][]string{{}, {"db", "backup"}} {
+		calls = nil
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Fatalf("Execute(%v) error = %v", args, err)
+		}
+		name := "mw"
+		if len(args) > 0 {
+			name = args[len(args)-1]
+		}
+		want := []string{
+			"outer before " + name,
+			"inner before " + name,
+			"run " + name,
+			"inner after " + name,
+			"outer after " + name,
+		}
+		if !reflect.DeepEqual(calls, want) {
+			t.Errorf("Execute(%v) calls = %q, want %q", args, calls, want)
+		}
+	}
+
+	rootCmd.SetArgs([]string{"crash"})
+	rootCmd.SilenceErrors = true
+	if err := rootCmd.Execute(); err == nil || err.Error() != "recovered: boom" {
+		t.Errorf("Execute(crash) error = %v, want recovered panic", err)
+	}
+}
+
+func TestCommandBuilder_Use_ShortCircuit(t *testing.T) {
+	ran := false
+	run := func(cmd *cobra.Command, args []string) { ran = true }
+	errDenied := errors.New("permission denied")
+	_, err := testTool{
+		yaml:  middlewareYAML,
+		funcs: map[string]any{"runRoot": run, "runBackup": run, "runCrash": run},
+		setup: func(cb *CommandBuilder) {
+			cb.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+				return func(cmd *cobra.Command, args []string) error {
+					if cmd.Name() == "backup" {
+						return errDenied
+					}
+					return next(cmd, args)
+				}
+			})
+		},
+	}.run(t, "db", "backup")
+	if !errors.Is(err, errDenied) {
+		t.Errorf("Execute() error = %v, want %v", err, errDenied)
+	}
+	if ran {
+		t.Error("handler ran although the middleware denied it")
+	}
+}
+
+func TestCommandBuilder_Use_BeforePreRun(t *testing.T) {
+	yamlContent := `
+name: mw
+root:
+  use: mw
+  short: Middleware test
+commands:
+  restore:
+    use: restore
+    short: Restore
+    run_func: runRestore
+    flags:
+      - name: out
+        type: dir
+        create_missing: true
+        usage: Backup directory
+`
+	out := filepath.Join(t.TempDir(), "backups")
+	errDenied := errors.New("permission denied")
+	_, err := testTool{
+		yaml:  yamlContent,
+		funcs: map[string]any{"runRestore": noopRun},
+		setup: func(cb *CommandBuilder) {
+			cb.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+				return func(cmd *cobra.Command, args []string) error { return errDenied }
+			})
+		},
+	}.run(t, "restore", "--out", out)
+	if !errors.Is(err, errDenied) {
+		t.Errorf("Execute() error = %v, want %v", err, errDenied)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("create_missing ran although the middleware denied the run, stat error = %v", err)
+	}
+}
+
+func TestCommandBuilder_Use_CacheHit(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var calls []string
+	for i := 0; i < 2; i++ {
+		testTool{
+			yaml: cacheYAML,
+			funcs: map[string]any{
+				"runPods": func(cmd *cobra.Command, args []string) { calls = append(calls, "run") },
+			},
+			setup: func(cb *CommandBuilder) {
+				cb.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+					return func(cmd *cobra.Command, args []string) error {
+						calls = append(calls, "middleware")
+						return next(cmd, args)
+					}
+				})
+			},
+		}.mustRun(t, "pods")
+	}
+	if want := []string{"middleware", "run", "middleware"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q (the middleware must run on a cache hit)", calls, want)
+	}
+}
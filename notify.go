@@ -0,0 +1,240 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Events a command can notify on.
+const (
+	// NotifyOnSuccess notifies when the handler returns no error.
+	NotifyOnSuccess = "success"
+	// NotifyOnFailure notifies when the handler returns an error.
+	NotifyOnFailure = "failure"
+)
+
+// SupportedNotifyEvents lists all events accepted in notify.on.
+var SupportedNotifyEvents = []string{
+	NotifyOnSuccess,
+	NotifyOnFailure,
+}
+
+// Built-in notifiers.
+const (
+	// NotifierDesktop shows a desktop notification (notify-send on Linux, osascript on macOS).
+	NotifierDesktop = "desktop"
+	// NotifierWebhook posts the notification to notify.url.
+	NotifierWebhook = "webhook"
+)
+
+// notifyTimeout bounds how long a notifier or event sink may delay the exit of a command.
+const notifyTimeout = 10 * time.Second
+
+// Notification describes a finished command passed to a Notifier.
+type Notification struct {
+	Tool     string        // name of the root command
+	Command  string        // full command path, e.g. "mytool db backup"
+	Args     []string      // positional args
+	Status   string        // NotifyOnSuccess or NotifyOnFailure
+	Error    string        // error returned by the handler, if any
+	Duration time.Duration // how long the handler ran
+	Config   NotifyConfig  // notify config of the command
+}
+
+// Message returns a one-line summary such as "mytool db backup failed after 3s: timeout".
+func (n Notification) Message() string {
+	verb := "succeeded"
+	if n.Status == NotifyOnFailure {
+		verb = "failed"
+	}
+	msg := fmt.Sprintf("%s %s after %s", n.Command, verb, n.Duration.Round(time.Millisecond))
+	if n.Error != "" {
+		msg += ": " + n.Error
+	}
+	return msg
+}
+
+// Notifier sends a notification when a command with notify finishes.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface.
+type NotifierFunc func(ctx context.Context, n Notification) error
+
+// Notify calls f(ctx, n).
+func (f NotifierFunc) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// RegisterNotifier registers a notifier that notify.via can refer to by name,
+// replacing the built-in desktop or webhook notifier if name is one of them.
+func (cb *CommandBuilder) RegisterNotifier(name string, notifier Notifier) {
+	if cb.notifiers == nil {
+		cb.notifiers = make(map[string]Notifier)
+	}
+	cb.notifiers[name] = notifier
+}
+
+// notifier returns the notifier registered as name or the built-in one.
+func (cb *CommandBuilder) notifier(name string) (Notifier, bool) {
+	if notifier, exists := cb.notifiers[name]; exists {
+		return notifier, true
+	}
+	switch name {
+	case NotifierDesktop:
+		return NotifierFunc(notifyDesktop), true
+	case NotifierWebhook:
+		if cb.webhookPoster != nil {
+			return NotifierFunc(cb.notifyWebhook), true
+		}
+	}
+	return nil, false
+}
+
+// notifySubscriber returns the subscriber that sends the notification of a
+// command when its command.succeeded or command.failed event matches one of the
+// configured events, or nil without notify.
+func (cb *CommandBuilder) notifySubscriber(notify *NotifyConfig) (*subscriber, error) {
+	if notify == nil {
+		return nil, nil
+	}
+	notifier, exists := cb.notifier(notify.Via)
+	switch {
+	case !exists && notify.Via == NotifierWebhook:
+		return nil, errNoWebhookPoster("notify via webhook")
+	case !exists:
+		return nil, fmt.Errorf("notifier %s not registered", notify.Via)
+	}
+
+	on := notify.On
+	if len(on) == 0 {
+		on = SupportedNotifyEvents
+	}
+	sub := &subscriber{name: notify.Via + " notification"}
+	for _, event := range on {
+		sub.events = append(sub.events, notifyEvents[event])
+	}
+	sub.sink = EventSinkFunc(func(ctx context.Context, e Event) error {
+		duration, _ := time.ParseDuration(e.Duration)
+		n := Notification{
+			Tool:     e.Tool,
+			Command:  e.Command,
+			Args:     e.Args,
+			Status:   NotifyOnSuccess,
+			Error:    e.Error,
+			Duration: duration,
+			Config:   *notify,
+		}
+		if e.Type == EventCommandFailed {
+			n.Status = NotifyOnFailure
+		}
+		return notifier.Notify(ctx, n)
+	})
+	return sub, nil
+}
+
+// notifyEvents maps the events of notify.on to the lifecycle events they notify on.
+var notifyEvents = map[string]string{
+	NotifyOnSuccess: EventCommandSucceeded,
+	NotifyOnFailure: EventCommandFailed,
+}
+
+// desktopCommand returns the command that shows a desktop notification. It is a
+// variable so tests can replace it.
+var desktopCommand = func(ctx context.Context, title, message string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.CommandContext(ctx, "notify-send", title, message), nil
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		return exec.CommandContext(ctx, "osascript", "-e", script), nil
+	default:
+		return nil, fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// notifyDesktop shows n as a desktop notification titled with the tool name.
+func notifyDesktop(ctx context.Context, n Notification) error {
+	cmd, err := desktopCommand(ctx, n.Tool, n.Message())
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// webhookFuncs are the functions available in notify.payload templates.
+var webhookFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// webhookPayload renders the request body for n: notify.payload executed as a
+// text/template with the Notification, or a JSON object by default.
+func webhookPayload(n Notification) ([]byte, error) {
+	if n.Config.Payload == "" {
+		return json.Marshal(map[string]any{
+			"tool":     n.Tool,
+			"command":  n.Command,
+			"args":     n.Args,
+			"status":   n.Status,
+			"error":    n.Error,
+			"duration": n.Duration.Round(time.Millisecond).String(),
+			"message":  n.Message(),
+		})
+	}
+
+	tmpl, err := template.New("payload").Funcs(webhookFuncs).Parse(n.Config.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, n); err != nil {
+		return nil, fmt.Errorf("failed to render payload: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WebhookPoster posts a JSON body to a URL. It delivers the webhook notifier
+// and the webhook event sink (see SetWebhookPoster).
+type WebhookPoster func(ctx context.Context, url string, body []byte) error
+
+// SetWebhookPoster sets the poster of the built-in webhook notifier and event
+// sink. They are unavailable while no poster is set, so a CLI without webhooks
+// does not link an HTTP client; webhook.Post from the webhook subpackage posts
+// with net/http.
+func (cb *CommandBuilder) SetWebhookPoster(post WebhookPoster) {
+	cb.webhookPoster = post
+}
+
+// errNoWebhookPoster is returned when a webhook is configured without a poster.
+func errNoWebhookPoster(what string) error {
+	return fmt.Errorf("%s needs a webhook poster set with SetWebhookPoster, e.g. webhook.Post from github.com/S-mishina/cobrayaml/webhook", what)
+}
+
+// notifyWebhook posts the payload of n to notify.url.
+func (cb *CommandBuilder) notifyWebhook(ctx context.Context, n Notification) error {
+	body, err := webhookPayload(n)
+	if err != nil {
+		return err
+	}
+	return cb.webhookPoster(ctx, os.ExpandEnv(n.Config.URL), body)
+}
@@ -0,0 +1,286 @@
+package cobrayaml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/S-mishina/cobrayaml/webhook"
+)
+
+const notifyYAML = `
+name: notify-test
+root:
+  use: notify-test
+  short: Notify test
+commands:
+  backup:
+    use: backup <target>
+    short: Back up a database
+    run_func: runBackup
+    notify:
+      on: [failure]
+      via: pager
+  restore:
+    use: restore
+    short: Restore a database
+    run_func: runRestore
+    notify:
+      via: pager
+`
+
+// notifyFuncs are the handlers of the notify test CLI; backup fails for the
+// target "broken".
+var notifyFuncs = map[string]any{
+	"runBackup": func(cmd *cobra.Command, args []string) error {
+		if args[0] == "broken" {
+			return errors.New("disk full")
+		}
+		return nil
+	},
+	"runRestore": noopRun,
+}
+
+func TestCommandBuilder_Notify(t *testing.T) {
+	var got []Notification
+	tool := testTool{yaml: notifyYAML, funcs: notifyFuncs, setup: func(cb *CommandBuilder) {
+		cb.RegisterNotifier("pager", NotifierFunc(func(ctx context.Context, n Notification) error {
+			got = append(got, n)
+			return nil
+		}))
+	}}
+
+	if _, err := tool.run(t, "backup", "db1"); err != nil {
+		t.Fatalf("backup db1 error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("a successful run should not notify on failure only, got %+v", got)
+	}
+
+	if _, err := tool.run(t, "backup", "broken"); err == nil || err.Error() != "disk full" {
+		t.Fatalf("backup broken error = %v, want the handler error", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("a failed run should notify once, got %+v", got)
+	}
+	n := got[0]
+	if n.Tool != "notify-test" || n.Command != "notify-test backup" || !reflect.DeepEqual(n.Args, []string{"broken"}) ||
+		n.Status != NotifyOnFailure || n.Error != "disk full" || n.Config.Via != "pager" {
+		t.Errorf("notification = %+v", n)
+	}
+	if msg := n.Message(); !strings.HasPrefix(msg, "notify-test backup failed after ") || !strings.HasSuffix(msg, ": disk full") {
+		t.Errorf("Message() = %q", msg)
+	}
+
+	got = nil
+	if _, err := tool.run(t, "restore"); err != nil {
+		t.Fatalf("restore error = %v", err)
+	}
+	if len(got) != 1 || got[0].Status != NotifyOnSuccess {
+		t.Errorf("without on, a successful run should notify, got %+v", got)
+	}
+}
+
+func TestCommandBuilder_NotifyFailureIsWarning(t *testing.T) {
+	tool := testTool{yaml: notifyYAML, funcs: notifyFuncs, setup: func(cb *CommandBuilder) {
+		cb.RegisterNotifier("pager", NotifierFunc(func(ctx context.Context, n Notification) error {
+			return errors.New("pager unreachable")
+		}))
+	}}
+
+	stderr, err := tool.run(t, "restore")
+	if err != nil {
+		t.Errorf("a failing notifier should not fail the command, got error = %v", err)
+	}
+	if want := "Warning: pager notification failed: pager unreachable\n"; stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestCommandBuilder_NotifyWithEvents(t *testing.T) {
+	var events []string
+	var notified []string
+	tool := testTool{
+		yaml: notifyYAML + `events:
+  - type: audit
+    events: [command.failed]
+`,
+		funcs: notifyFuncs,
+		setup: func(cb *CommandBuilder) {
+			cb.RegisterEventSink("audit", EventSinkFunc(func(ctx context.Context, e Event) error {
+				events = append(events, e.Type+" "+e.Command)
+				return nil
+			}))
+			cb.RegisterNotifier("pager", NotifierFunc(func(ctx context.Context, n Notification) error {
+				notified = append(notified, n.Status+" "+n.Command)
+				return nil
+			}))
+		},
+	}
+
+	for _, args := range [][]string{{"backup", "broken"}, {"restore"}} {
+		_, _ = tool.run(t, args...)
+	}
+
+	if want := []string{"command.failed notify-test backup"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %q, want %q", events, want)
+	}
+	if want := []string{"failure notify-test backup", "success notify-test restore"}; !reflect.DeepEqual(notified, want) {
+		t.Errorf("notifications = %q, want %q", notified, want)
+	}
+}
+
+func TestCommandBuilder_NotifyUnregistered(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(notifyYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunctions(notifyFuncs)
+
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "notifier pager not registered") {
+		t.Errorf("BuildRootCommand() error = %v, want notifier not registered", err)
+	}
+}
+
+func TestNotifyWebhook(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("NOTIFY_TEST_URL", server.URL)
+
+	n := Notification{
+		Tool:    "mytool",
+		Command: "mytool backup",
+		Args:    []string{"db1"},
+		Status:  NotifyOnFailure,
+		Error:   `disk "data" full`,
+		Config:  NotifyConfig{Via: NotifierWebhook, URL: "${NOTIFY_TEST_URL}/hook"},
+	}
+	cb := &CommandBuilder{}
+	cb.SetWebhookPoster(webhook.Post)
+	if err := cb.notifyWebhook(context.Background(), n); err != nil {
+		t.Fatalf("notifyWebhook() error = %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(bodies[0]), &payload); err != nil {
+		t.Fatalf("default payload is not JSON: %v\n%s", err, bodies[0])
+	}
+	if payload["command"] != "mytool backup" || payload["status"] != "failure" || payload["error"] != `disk "data" full` {
+		t.Errorf("default payload = %v", payload)
+	}
+
+	n.Config.Payload = `{"text": {{json .Message}}}`
+	if err := cb.notifyWebhook(context.Background(), n); err != nil {
+		t.Fatalf("notifyWebhook() error = %v", err)
+	}
+	if want := `{"text": "mytool backup failed after 0s: disk \"data\" full"}`; bodies[1] != want {
+		t.Errorf("templated payload = %s, want %s", bodies[1], want)
+	}
+
+	n.Config.URL = server.URL + "/broken"
+	if err := cb.notifyWebhook(context.Background(), n); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("notifyWebhook() error = %v, want the response status", err)
+	}
+}
+
+func TestNotifyDesktop(t *testing.T) {
+	var gotTitle, gotMessage string
+	orig := desktopCommand
+	desktopCommand = func(ctx context.Context, title, message string) (*exec.Cmd, error) {
+		gotTitle, gotMessage = title, message
+		return exec.CommandContext(ctx, os.Args[0], "-test.run=^$"), nil
+	}
+	defer func() { desktopCommand = orig }()
+
+	n := Notification{Tool: "mytool", Command: "mytool backup", Status: NotifyOnSuccess}
+	if err := notifyDesktop(context.Background(), n); err != nil {
+		t.Fatalf("notifyDesktop() error = %v", err)
+	}
+	if gotTitle != "mytool" || gotMessage != "mytool backup succeeded after 0s" {
+		t.Errorf("desktop notification = %q, %q", gotTitle, gotMessage)
+	}
+}
+
+func TestValidateConfig_Notify(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "webhook",
+			command: CommandConfig{RunFunc: "runBackup", Notify: &NotifyConfig{On: []string{NotifyOnFailure}, Via: NotifierWebhook, URL: "https://example.com"}},
+		},
+		{
+			name:    "registered notifier",
+			command: CommandConfig{RunFunc: "runBackup", Notify: &NotifyConfig{Via: "pager"}},
+		},
+		{
+			name:    "unknown event",
+			command: CommandConfig{RunFunc: "runBackup", Notify: &NotifyConfig{On: []string{"start"}, Via: NotifierDesktop}},
+			wantErr: `notify event "start" is not supported`,
+		},
+		{
+			name:    "missing via",
+			command: CommandConfig{RunFunc: "runBackup", Notify: &NotifyConfig{}},
+			wantErr: "notify via is required",
+		},
+		{
+			name:    "webhook without url",
+			command: CommandConfig{RunFunc: "runBackup", Notify: &NotifyConfig{Via: NotifierWebhook}},
+			wantErr: "notify via webhook requires url",
+		},
+		{
+			name:    "invalid payload",
+			command: CommandConfig{RunFunc: "runBackup", Notify: &NotifyConfig{Via: NotifierWebhook, URL: "https://example.com", Payload: "{{.Message"}},
+			wantErr: "notify payload is not a valid template",
+		},
+		{
+			name:    "no run_func",
+			command: CommandConfig{Notify: &NotifyConfig{Via: NotifierDesktop}},
+			wantErr: "notify requires run_func",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.command.Use = "backup"
+			tt.command.Short = "Back up"
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"backup": tt.command},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
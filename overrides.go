@@ -0,0 +1,167 @@
+package cobrayaml
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// OverridesConfig represents the per-user overrides file of a tool, read from
+// overrides.yaml in the tool's directory under the user config directory (e.g.
+// ~/.config/mytool/overrides.yaml). It changes flag defaults and hides commands
+// for that user only and is merged when the commands are built.
+//
+// Fields:
+//   - Root: Overrides of the root command (its flags only; it cannot be hidden)
+//   - Commands: Overrides keyed by command path (e.g., "db migrate")
+//
+// Example YAML:
+//
+//	root:
+//	  defaults:
+//	    output: json
+//	commands:
+//	  get:
+//	    defaults:
+//	      namespace: kube-system
+//	  db migrate:
+//	    hidden: true
+type OverridesConfig struct {
+	Root     CommandOverride            `yaml:"root,omitempty"`
+	Commands map[string]CommandOverride `yaml:"commands,omitempty"`
+}
+
+// CommandOverride represents the overrides of a single command.
+//
+// Fields:
+//   - Hidden: Hide the command from help; it can still be run
+//   - Defaults: New default values keyed by flag name, for flags declared on the command
+type CommandOverride struct {
+	Hidden   bool              `yaml:"hidden,omitempty"`
+	Defaults map[string]string `yaml:"defaults,omitempty"`
+}
+
+// overridesFile returns the per-user overrides file of tool.
+func overridesFile(tool string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tool, "overrides.yaml"), nil
+}
+
+// loadOverrides returns config with the overrides file of tool applied, or
+// config itself when the user has no overrides file.
+func (cb *CommandBuilder) loadOverrides(tool string, config *ToolConfig) (*ToolConfig, error) {
+	path, err := overridesFile(tool)
+	if err != nil || cb.parseOnly {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overrides file: %w", err)
+	}
+
+	var overrides OverridesConfig
+	if err := yaml.UnmarshalStrict(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse overrides file %s: %w", path, err)
+	}
+	result, err := cb.applyOverrides(config, &overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid overrides file %s: %w", path, err)
+	}
+	return result, nil
+}
+
+// applyOverrides returns a copy of config with overrides applied. Every command
+// and flag the overrides refer to must exist, and the new defaults must be valid
+// for their flags. config itself is not modified.
+func (cb *CommandBuilder) applyOverrides(config *ToolConfig, overrides *OverridesConfig) (*ToolConfig, error) {
+	ve := &ValidationError{}
+	result := *config
+
+	if overrides.Root.Hidden {
+		ve.addError("root: the root command cannot be hidden")
+	}
+	result.Root.Flags = cb.overrideDefaults(config.Root.Flags, overrides.Root.Defaults, "root", ve)
+
+	result.Commands = maps.Clone(config.Commands)
+	for _, path := range sortedKeys(overrides.Commands) {
+		names := strings.Fields(path)
+		if len(names) == 0 {
+			ve.addError("commands: command path must not be empty")
+			continue
+		}
+		cb.overrideCommand(result.Commands, names, path, overrides.Commands[path], ve)
+	}
+
+	if len(ve.Errors) > 0 {
+		return nil, ve
+	}
+	// Catch defaults that do not fit their flag, e.g. outside allowed_values
+	if err := ValidateConfig(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// overrideCommand applies o to the command at names below cmds, cloning the
+// commands maps it descends into so the original config is left untouched.
+func (cb *CommandBuilder) overrideCommand(cmds map[string]CommandConfig, names []string, path string, o CommandOverride, ve *ValidationError) {
+	cmd, exists := cmds[names[0]]
+	if !exists {
+		ve.addError("commands: command %q does not exist", path)
+		return
+	}
+	if len(names) > 1 {
+		cmd.Commands = maps.Clone(cmd.Commands)
+		cb.overrideCommand(cmd.Commands, names[1:], path, o, ve)
+	} else {
+		cmd.Hidden = cmd.Hidden || o.Hidden
+		cmd.Flags = cb.overrideDefaults(cmd.Flags, o.Defaults, path, ve)
+	}
+	cmds[names[0]] = cmd
+}
+
+// overrideDefaults returns a copy of flags with the defaults replaced. Only flags
+// declared on the command itself can be overridden, since an inherited persistent
+// flag is shared with its other subcommands.
+func (cb *CommandBuilder) overrideDefaults(flags []FlagConfig, defaults map[string]string, path string, ve *ValidationError) []FlagConfig {
+	if len(defaults) == 0 {
+		return flags
+	}
+	flags = slices.Clone(flags)
+	for _, name := range sortedKeys(defaults) {
+		i := slices.IndexFunc(flags, func(f FlagConfig) bool { return f.Name == name })
+		if i < 0 {
+			ve.addError("command %q: flag %q does not exist", path, name)
+			continue
+		}
+		flags[i].DefaultValue = defaults[name]
+		// Adding the flag to a scratch command parses the new default
+		if err := cb.addFlags(&cobra.Command{}, flags[i:i+1]); err != nil {
+			ve.addError("command %q: %v", path, err)
+		}
+	}
+	return flags
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,185 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const overridesToolYAML = `
+name: overrides-test
+root:
+  use: overrides-test
+  short: Overrides test
+  flags:
+    - name: output
+      type: string
+      default: text
+      usage: Output format
+      allowed_values: [text, json]
+      persistent: true
+commands:
+  get:
+    use: get
+    short: Get items
+    run_func: runGet
+    flags:
+      - name: namespace
+        type: string
+        default: default
+        usage: Namespace
+      - name: limit
+        type: int
+        default: "10"
+        usage: Maximum number of items
+  db migrate:
+    use: migrate
+    short: Run migrations
+    run_func: runMigrate
+`
+
+// writeOverrides writes the overrides file of the overrides-test tool in a
+// temporary config directory.
+func writeOverrides(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(dir, "overrides-test", "overrides.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+}
+
+func buildOverridesTool(t *testing.T) (*CommandBuilder, *cobra.Command, error) {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(overridesToolYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runGet", func(cmd *cobra.Command, args []string) error { return nil })
+	cb.RegisterFunction("runMigrate", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	return cb, rootCmd, err
+}
+
+func TestCommandBuilder_Overrides(t *testing.T) {
+	writeOverrides(t, `
+root:
+  defaults:
+    output: json
+commands:
+  get:
+    defaults:
+      namespace: kube-system
+      limit: "50"
+  db migrate:
+    hidden: true
+`)
+
+	cb, rootCmd, err := buildOverridesTool(t)
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	if got := rootCmd.PersistentFlags().Lookup("output").DefValue; got != "json" {
+		t.Errorf("root --output default = %q, want json", got)
+	}
+	getCmd, _, _ := rootCmd.Find([]string{"get"})
+	if got := getCmd.Flags().Lookup("namespace").DefValue; got != "kube-system" {
+		t.Errorf("get --namespace default = %q, want kube-system", got)
+	}
+	if got, _ := getCmd.Flags().GetInt("limit"); got != 50 {
+		t.Errorf("get --limit = %d, want 50", got)
+	}
+	migrateCmd, _, _ := rootCmd.Find([]string{"db", "migrate"})
+	if migrateCmd.Name() != "migrate" || !migrateCmd.Hidden {
+		t.Errorf("db migrate should be hidden, got %s hidden = %v", migrateCmd.CommandPath(), migrateCmd.Hidden)
+	}
+
+	// The overrides apply to the built commands only
+	if got := cb.GetConfig().Commands["get"].Flags[0].DefaultValue; got != "default" {
+		t.Errorf("config --namespace default = %q, want the YAML default", got)
+	}
+	if cb.GetConfig().Commands["db"].Commands["migrate"].Hidden {
+		t.Error("overrides should not change the config")
+	}
+}
+
+func TestCommandBuilder_OverridesNotPresent(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	_, rootCmd, err := buildOverridesTool(t)
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	getCmd, _, _ := rootCmd.Find([]string{"get"})
+	if got := getCmd.Flags().Lookup("namespace").DefValue; got != "default" {
+		t.Errorf("get --namespace default = %q, want default", got)
+	}
+}
+
+func TestCommandBuilder_InvalidOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		wantErr   string
+	}{
+		{
+			name:      "unknown command",
+			overrides: "commands:\n  deploy:\n    hidden: true\n",
+			wantErr:   `command "deploy" does not exist`,
+		},
+		{
+			name:      "unknown nested command",
+			overrides: "commands:\n  db rollback:\n    hidden: true\n",
+			wantErr:   `command "db rollback" does not exist`,
+		},
+		{
+			name:      "unknown flag",
+			overrides: "commands:\n  get:\n    defaults:\n      output: json\n",
+			wantErr:   `command "get": flag "output" does not exist`,
+		},
+		{
+			name:      "hidden root",
+			overrides: "root:\n  hidden: true\n",
+			wantErr:   "the root command cannot be hidden",
+		},
+		{
+			name:      "default outside allowed values",
+			overrides: "root:\n  defaults:\n    output: xml\n",
+			wantErr:   `default "xml" is not one of the allowed values`,
+		},
+		{
+			name:      "invalid default",
+			overrides: "commands:\n  get:\n    defaults:\n      limit: many\n",
+			wantErr:   "many",
+		},
+		{
+			name:      "unknown key",
+			overrides: "commands:\n  get:\n    hide: true\n",
+			wantErr:   "failed to parse overrides file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeOverrides(t, tt.overrides)
+
+			_, _, err := buildOverridesTool(t)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BuildRootCommand() error = %v, want containing %q", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "overrides.yaml") {
+				t.Errorf("error should name the overrides file, got %v", err)
+			}
+		})
+	}
+}
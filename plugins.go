@@ -0,0 +1,170 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginGroupID is the help group plugin commands are listed under.
+const pluginGroupID = "plugins"
+
+// discoverPlugins scans the directories in $PATH for executables named
+// "<tool>-<sub>" and returns the path of each plugin keyed by <sub>. When several
+// directories contain the same plugin, the first one in $PATH wins, like the shell.
+func discoverPlugins(tool string) map[string]string {
+	prefix := tool + "-"
+	plugins := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if runtime.GOOS == "windows" {
+				if !hasPathExt(name, os.Getenv("PATHEXT")) {
+					continue
+				}
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			sub, ok := strings.CutPrefix(name, prefix)
+			if !ok || sub == "" {
+				continue
+			}
+			if _, exists := plugins[sub]; exists {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			plugins[sub] = path
+		}
+	}
+	return plugins
+}
+
+// isExecutable reports whether path is a regular file that can be executed: on
+// Windows one with an extension listed in %PATHEXT%, elsewhere one with an
+// execute bit set.
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return hasPathExt(path, os.Getenv("PATHEXT"))
+	}
+	return info.Mode().Perm()&0111 != 0
+}
+
+// hasPathExt reports whether name has one of the executable extensions listed in
+// pathext, a %PATHEXT% value, ignoring case. An empty pathext stands for the
+// Windows default.
+func hasPathExt(name, pathext string) bool {
+	if pathext == "" {
+		pathext = ".com;.exe;.bat;.cmd"
+	}
+	ext := filepath.Ext(name)
+	for _, want := range strings.Split(pathext, ";") {
+		if want != "" && strings.EqualFold(ext, "."+strings.TrimPrefix(want, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPlugins returns the plugins to add for running args. Running a declared
+// command needs none, and a command that is not declared is first looked up as
+// a single plugin, so $PATH is only scanned in full for help, completion and
+// args that name no command.
+func findPlugins(rootCmd *cobra.Command, args []string) map[string]string {
+	if cmd, _, err := rootCmd.Find(args); err == nil && cmd != rootCmd {
+		return nil
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if strings.ContainsAny(arg, `/\`) {
+			break
+		}
+		if path, err := exec.LookPath(rootCmd.Name() + "-" + arg); err == nil {
+			return map[string]string{arg: path}
+		}
+		break
+	}
+	return discoverPlugins(rootCmd.Name())
+}
+
+// addPluginCommands adds a command for each plugin in $PATH that running args
+// may need when discover_plugins is enabled. Plugins never replace commands
+// declared in YAML or added by the builder.
+func (cb *CommandBuilder) addPluginCommands(rootCmd *cobra.Command, args []string) {
+	if !cb.config.DiscoverPlugins {
+		return
+	}
+
+	plugins := findPlugins(rootCmd, args)
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if isReservedCommandName(rootCmd, name) {
+			continue
+		}
+		if !rootCmd.ContainsGroup(pluginGroupID) {
+			rootCmd.AddGroup(&cobra.Group{ID: pluginGroupID, Title: "Plugin Commands:"})
+		}
+		rootCmd.AddCommand(buildPluginCommand(name, plugins[name]))
+	}
+}
+
+// isReservedCommandName reports whether name is already taken on rootCmd, either by a
+// command or alias or by the help and completion commands cobra adds when executing.
+func isReservedCommandName(rootCmd *cobra.Command, name string) bool {
+	if name == "help" || name == "completion" || strings.HasPrefix(name, "__") {
+		return true
+	}
+	for _, sub := range rootCmd.Commands() {
+		if sub.Name() == name || sub.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPluginCommand builds a command that runs the plugin at path, passing all
+// args and flags through unparsed.
+func buildPluginCommand(name, path string) *cobra.Command {
+	return &cobra.Command{
+		Use:                name,
+		Short:              fmt.Sprintf("Run the %s plugin", filepath.Base(path)),
+		GroupID:            pluginGroupID,
+		DisableFlagParsing: true,
+		SilenceUsage:       true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plugin := exec.Command(path, args...)
+			plugin.Stdin = cmd.InOrStdin()
+			plugin.Stdout = cmd.OutOrStdout()
+			plugin.Stderr = cmd.ErrOrStderr()
+			if err := plugin.Run(); err != nil {
+				return fmt.Errorf("plugin %s: %w", name, err)
+			}
+			return nil
+		},
+	}
+}
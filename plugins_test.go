@@ -0,0 +1,204 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const pluginsYAML = `
+name: plugin-test
+discover_plugins: true
+root:
+  use: plugin-test
+  short: Plugin test
+commands:
+  list:
+    use: list
+    short: List items
+`
+
+// writePlugin writes an executable shell script to dir.
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "plugin-test-hello", "echo first")
+	writePlugin(t, second, "plugin-test-hello", "echo second")
+	writePlugin(t, second, "plugin-test-lint", "echo lint")
+	writePlugin(t, second, "other-tool-hello", "echo other")
+	if err := os.WriteFile(filepath.Join(second, "plugin-test-notes"), []byte("not executable"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	got := discoverPlugins("plugin-test")
+	want := map[string]string{
+		"hello": filepath.Join(first, "plugin-test-hello"),
+		"lint":  filepath.Join(second, "plugin-test-lint"),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("discoverPlugins() = %v, want %v", got, want)
+	}
+	for name, path := range want {
+		if got[name] != path {
+			t.Errorf("discoverPlugins()[%q] = %q, want %q", name, got[name], path)
+		}
+	}
+}
+
+func TestCommandBuilder_Plugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "plugin-test-hello", `echo "hello $*"`)
+	writePlugin(t, dir, "plugin-test-list", "echo plugin list")
+	writePlugin(t, dir, "plugin-test-fail", "exit 3")
+	t.Setenv("PATH", dir)
+
+	cb, err := NewCommandBuilderFromString(pluginsYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+
+	if list := findSubcommand(rootCmd, "list"); list == nil || list.GroupID == pluginGroupID {
+		t.Error("declared list command should not be replaced by a plugin")
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetArgs([]string{"hello", "world", "--loud", "-n", "2"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out.String() != "hello world --loud -n 2\n" {
+		t.Errorf("plugin output = %q, want args and flags passed through", out.String())
+	}
+
+	rootCmd.SetArgs([]string{"fail"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "plugin fail: exit status 3") {
+		t.Errorf("Execute() error = %v, want plugin exit status", err)
+	}
+
+	out.Reset()
+	rootCmd.SetErr(&bytes.Buffer{})
+	rootCmd.SetArgs([]string{cobra.ShellCompRequestCmd, "he"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "hello") {
+		t.Errorf("completion should offer the hello plugin, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	rootCmd.SetArgs([]string{"--help"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	help := out.String()
+	if !strings.Contains(help, "Plugin Commands:") || !strings.Contains(help, "Run the plugin-test-hello plugin") {
+		t.Errorf("help should list plugins in their own group, got:\n%s", help)
+	}
+}
+
+func TestCommandBuilder_PluginsDisabled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "plugin-test-hello", "echo hello")
+	t.Setenv("PATH", dir)
+
+	cb, err := NewCommandBuilderFromString(strings.Replace(pluginsYAML, "discover_plugins: true\n", "", 1))
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	if findSubcommand(rootCmd, "hello") != nil {
+		t.Error("plugins should only be discovered when discover_plugins is set")
+	}
+}
+
+func TestCommandBuilder_PluginsForArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+	dir := t.TempDir()
+	writePlugin(t, dir, "plugin-test-hello", "echo hello")
+	writePlugin(t, dir, "plugin-test-lint", "echo lint")
+	t.Setenv("PATH", dir)
+
+	cb, err := NewCommandBuilderFromString(pluginsYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"list"}},
+		{args: []string{"hello", "--loud"}, want: []string{"hello"}},
+		{args: []string{"nope"}, want: []string{"hello", "lint"}},
+		{args: []string{"--help"}, want: []string{"hello", "lint"}},
+		{args: []string{cobra.ShellCompRequestCmd, "he"}, want: []string{"hello", "lint"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			rootCmd, err := cb.BuildRootCommandFor(tt.args)
+			if err != nil {
+				t.Fatalf("BuildRootCommandFor() error = %v", err)
+			}
+			var got []string
+			for _, cmd := range rootCmd.Commands() {
+				if cmd.GroupID == pluginGroupID {
+					got = append(got, cmd.Name())
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("plugin commands = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasPathExt(t *testing.T) {
+	tests := []struct {
+		name    string
+		pathext string
+		want    bool
+	}{
+		{name: "tool-hello.exe", want: true},
+		{name: "tool-hello.CMD", want: true},
+		{name: "tool-hello.txt"},
+		{name: "tool-hello"},
+		{name: "tool-hello.ps1", pathext: ".COM;.EXE;.PS1", want: true},
+		{name: "tool-hello.bat", pathext: ".COM;.EXE;.PS1"},
+	}
+	for _, tt := range tests {
+		if got := hasPathExt(tt.name, tt.pathext); got != tt.want {
+			t.Errorf("hasPathExt(%q, %q) = %v, want %v", tt.name, tt.pathext, got, tt.want)
+		}
+	}
+}
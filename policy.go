@@ -0,0 +1,202 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Names a policy can forbid besides the optional features.
+const (
+	// policyExec is running executables other than the tool itself, which a
+	// config does with discover_plugins.
+	policyExec Feature = "exec"
+
+	// policyWebhooks is sending data to webhooks: notify via webhook and the
+	// webhook event sink.
+	policyWebhooks Feature = "webhooks"
+)
+
+// Policy is an organization policy that an embedding application enforces on
+// the commands.yaml it loads, e.g. to keep CLIs from posting to webhooks. Read
+// it from a policy.yaml with LoadPolicy and set it with SetPolicy; ValidateConfig
+// then rejects configs that break it with policy violation errors.
+//
+// Fields:
+//   - Forbid: What configs must not use: "exec" (discover_plugins), "webhooks"
+//     (notify via webhook and webhook event sinks) or an optional feature (see
+//     SupportedFeatures), e.g. notify or record
+//   - RequireConfirmation: Patterns of the commands that must ask for
+//     confirmation with side_effects: destructive; a pattern is a command path
+//     with the names joined by dots, each of which may be a glob as in
+//     path.Match (e.g., "db.*")
+//   - MaxTimeout: Largest default of a duration flag, such as --timeout
+//   - MaxCacheTTL: Longest ttl of a command's cache
+//
+// Example YAML:
+//
+//	forbid: [exec, webhooks, notify, record]
+//	require_confirmation: ["delete", "db.*"]
+//	max_timeout: 10m
+//	max_cache_ttl: 1h
+type Policy struct {
+	Forbid              []Feature `yaml:"forbid,omitempty"`
+	RequireConfirmation []string  `yaml:"require_confirmation,omitempty"`
+	MaxTimeout          string    `yaml:"max_timeout,omitempty"`
+	MaxCacheTTL         string    `yaml:"max_cache_ttl,omitempty"`
+}
+
+// policy holds the policy set by the host application. It is package-level
+// because ValidateConfig runs before a CommandBuilder exists.
+var (
+	policyMu sync.RWMutex
+	policy   *Policy
+)
+
+// LoadPolicy reads a policy from the policy.yaml at path and checks that it is
+// well-formed.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %v", err)
+	}
+	var p Policy
+	if err := yaml.UnmarshalStrict(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal policy: %v", err)
+	}
+	if err := p.check(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// check reports the first malformed entry of p.
+func (p *Policy) check() error {
+	for _, name := range p.Forbid {
+		if name != policyExec && name != policyWebhooks && !slices.Contains(SupportedFeatures, name) {
+			return fmt.Errorf("forbid: unknown name %q", name)
+		}
+	}
+	for _, pattern := range p.RequireConfirmation {
+		if err := checkCommandPattern(pattern); err != nil {
+			return fmt.Errorf("require_confirmation: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if _, err := parsePolicyDuration(p.MaxTimeout); err != nil {
+		return fmt.Errorf("max_timeout: %v", err)
+	}
+	if _, err := parsePolicyDuration(p.MaxCacheTTL); err != nil {
+		return fmt.Errorf("max_cache_ttl: %v", err)
+	}
+	return nil
+}
+
+// parsePolicyDuration parses a duration limit of a policy; an empty limit is 0,
+// for no limit.
+func parsePolicyDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// SetPolicy sets the policy enforced on configs loaded afterwards, or removes it
+// when p is nil. Like DisableFeatures, call it before loading commands.yaml:
+//
+//	policy, err := cobrayaml.LoadPolicy("/etc/acme/policy.yaml")
+//	if err != nil {
+//		return err
+//	}
+//	cobrayaml.SetPolicy(policy)
+func SetPolicy(p *Policy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	policy = p
+}
+
+// policyKeys returns the keys of a command that use a name a policy can forbid,
+// which are those of commandFeatureKeys and the webhook notifications.
+func policyKeys(config CommandConfig) map[Feature][]string {
+	keys := commandFeatureKeys(config)
+	if config.Notify != nil && config.Notify.Via == "webhook" {
+		keys[policyWebhooks] = append(keys[policyWebhooks], "notify: via webhook")
+	}
+	return keys
+}
+
+// validatePolicy reports the keys of config that break the policy set with
+// SetPolicy.
+func validatePolicy(config *ToolConfig, ve *ValidationError) {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	if policy == nil {
+		return
+	}
+	if err := policy.check(); err != nil {
+		ve.addError("invalid policy: %v", err)
+		return
+	}
+	maxTimeout, _ := parsePolicyDuration(policy.MaxTimeout)
+	maxCacheTTL, _ := parsePolicyDuration(policy.MaxCacheTTL)
+
+	forbid := func(prefix string, keys map[Feature][]string) {
+		for _, name := range policy.Forbid {
+			for _, key := range keys[name] {
+				ve.addError("policy violation: %s%s uses %q, which the policy forbids", prefix, key, name)
+			}
+		}
+	}
+	toolKeys := toolFeatureKeys(config)
+	if config.DiscoverPlugins {
+		toolKeys[policyExec] = append(toolKeys[policyExec], "discover_plugins")
+	}
+	for i, sink := range config.Events {
+		if sink.Type == EventSinkWebhook {
+			toolKeys[policyWebhooks] = append(toolKeys[policyWebhooks], fmt.Sprintf("events[%d]: webhook sink", i))
+		}
+	}
+	forbid("tool config: ", toolKeys)
+
+	check := func(path string, names []string, cmd CommandConfig) {
+		prefix := fmt.Sprintf("command %q: ", path)
+		forbid(prefix, policyKeys(cmd))
+		needsConfirmation := slices.ContainsFunc(policy.RequireConfirmation, func(pattern string) bool {
+			return matchCommandPath(pattern, names)
+		})
+		if needsConfirmation && cmd.RunFunc != "" && cmd.SideEffects != SideEffectsDestructive {
+			ve.addError("policy violation: %smust ask for confirmation with side_effects: %s", prefix, SideEffectsDestructive)
+		}
+		for _, flag := range cmd.Flags {
+			if flag.Type != "duration" || flag.DefaultValue == "" || maxTimeout == 0 {
+				continue
+			}
+			if d, err := time.ParseDuration(flag.DefaultValue); err == nil && d > maxTimeout {
+				ve.addError("policy violation: %sflag %q: default %s exceeds the policy's max_timeout %s", prefix, flag.Name, flag.DefaultValue, policy.MaxTimeout)
+			}
+		}
+		if cmd.Cache != nil && maxCacheTTL > 0 {
+			if d, err := time.ParseDuration(cmd.Cache.TTL); err == nil && d > maxCacheTTL {
+				ve.addError("policy violation: %scache ttl %s exceeds the policy's max_cache_ttl %s", prefix, cmd.Cache.TTL, policy.MaxCacheTTL)
+			}
+		}
+	}
+
+	var walk func(cmds map[string]CommandConfig, prefix string, parent []string)
+	walk = func(cmds map[string]CommandConfig, prefix string, parent []string) {
+		for _, name := range sortedCommandNames(cmds) {
+			path := prefix + name
+			names := append(parent[:len(parent):len(parent)], name)
+			check(path, names, cmds[name])
+			walk(cmds[name].Commands, path+"/", names)
+		}
+	}
+	check("root", nil, config.Root)
+	walk(config.Commands, "", nil)
+	for _, surface := range sortedKeys(config.Surfaces) {
+		walk(config.Surfaces[surface].Commands, "surfaces/"+surface+"/", nil)
+	}
+}
@@ -0,0 +1,119 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const policyYAML = `
+name: policy-test
+discover_plugins: true
+record: true
+events:
+  - type: webhook
+    url: https://example.com/events
+root:
+  use: policy-test
+  short: Policy test
+commands:
+  db:
+    use: db
+    short: Database commands
+    commands:
+      drop:
+        use: drop
+        short: Drop a database
+        run_func: runDrop
+      backup:
+        use: backup
+        short: Back up a database
+        run_func: runBackup
+        side_effects: destructive
+  status:
+    use: status
+    short: Show the status
+    run_func: runStatus
+    cache:
+      ttl: 24h
+    notify:
+      via: webhook
+      url: https://example.com/notify
+    flags:
+      - name: timeout
+        type: duration
+        default: 1h
+        usage: Time to wait
+`
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{name: "valid", policy: "forbid: [exec, webhooks, notify, record]\nrequire_confirmation: [db.*]\nmax_timeout: 10m\nmax_cache_ttl: 1h\n"},
+		{name: "unknown name", policy: "forbid: [network]\n", wantErr: `forbid: unknown name "network"`},
+		{name: "bad pattern", policy: "require_confirmation: [\"db.[\"]\n", wantErr: `require_confirmation: invalid pattern "db.["`},
+		{name: "bad duration", policy: "max_timeout: soon\n", wantErr: "max_timeout: "},
+		{name: "unknown key", policy: "forbidden: [exec]\n", wantErr: "failed to unmarshal policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".yaml")
+			if err := os.WriteFile(path, []byte(tt.policy), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadPolicy(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadPolicy() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadPolicy() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_Policy(t *testing.T) {
+	if _, err := NewCommandBuilderFromString(policyYAML); err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	SetPolicy(&Policy{
+		Forbid:              []Feature{policyExec, policyWebhooks, FeatureNotify, FeatureRecord},
+		RequireConfirmation: []string{"db.*"},
+		MaxTimeout:          "10m",
+		MaxCacheTTL:         "1h",
+	})
+	defer SetPolicy(nil)
+
+	_, err := NewCommandBuilderFromString(policyYAML)
+	if err == nil {
+		t.Fatal("NewCommandBuilderFromString() error = nil, want policy violations")
+	}
+	for _, want := range []string{
+		`policy violation: tool config: discover_plugins uses "exec", which the policy forbids`,
+		`policy violation: tool config: events[0]: webhook sink uses "webhooks", which the policy forbids`,
+		`policy violation: tool config: record uses "record", which the policy forbids`,
+		`policy violation: command "db/drop": must ask for confirmation with side_effects: destructive`,
+		`policy violation: command "status": notify uses "notify", which the policy forbids`,
+		`policy violation: command "status": notify: via webhook uses "webhooks", which the policy forbids`,
+		`policy violation: command "status": flag "timeout": default 1h exceeds the policy's max_timeout 10m`,
+		`policy violation: command "status": cache ttl 24h exceeds the policy's max_cache_ttl 1h`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), `"db/backup"`) {
+		t.Errorf("error = %v, want no violation for the destructive db backup", err)
+	}
+
+	SetPolicy(&Policy{Forbid: []Feature{"network"}})
+	if _, err := NewCommandBuilderFromString(policyYAML); err == nil || !strings.Contains(err.Error(), `invalid policy: forbid: unknown name "network"`) {
+		t.Errorf("NewCommandBuilderFromString() error = %v, want an invalid policy", err)
+	}
+}
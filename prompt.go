@@ -0,0 +1,54 @@
+package cobrayaml
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// errNoAnswer is returned by confirm when nobody can answer the prompt.
+var errNoAnswer = errors.New("no answer")
+
+// canPrompt reports whether the input of cmd can answer a prompt: a terminal, or
+// a reader the host set with SetIn, e.g. with scripted answers. A pipe or file as
+// stdin, as in CI, may never send an answer, so prompts fail instead of waiting.
+func canPrompt(cmd *cobra.Command) bool {
+	if _, ok := cmd.InOrStdin().(*os.File); ok {
+		return isInteractive(cmd)
+	}
+	return true
+}
+
+// confirm asks question with a [y/N] prompt on the error output of cmd and
+// reports whether the answer is yes. It returns errNoAnswer when the input
+// cannot answer or ends before an answer. Prompts share one buffered reader set
+// as the input of the root command, so input after an answer, such as the
+// answer to the next prompt, is not lost.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	if !canPrompt(cmd) {
+		return false, errNoAnswer
+	}
+	in, ok := cmd.InOrStdin().(*bufio.Reader)
+	if !ok {
+		in = bufio.NewReader(cmd.InOrStdin())
+		cmd.Root().SetIn(in)
+	}
+
+	errOut := cmd.ErrOrStderr()
+	fmt.Fprintf(errOut, "%s [y/N]: ", question)
+	answer, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read answer: %w", err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" && errors.Is(err, io.EOF) {
+		fmt.Fprintln(errOut)
+		return false, errNoAnswer
+	}
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,116 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// QuotaRequest is a run of a command checked against the command's quota.
+type QuotaRequest struct {
+	Quota    string    // quota of the command, e.g. "deployments-per-day"
+	Tool     string    // name of the root command
+	Command  string    // full command path, e.g. "mytool deploy"
+	Args     []string  // positional args of the run
+	Identity *Identity // caller the command runs for, if any (see WithIdentity)
+}
+
+// QuotaDecision is the answer of a QuotaChecker to a QuotaRequest.
+type QuotaDecision struct {
+	Allowed    bool          // the run may proceed
+	Reason     string        // why the run was denied, e.g. "10 of 10 deployments used today"
+	RetryAfter time.Duration // how long until the run may be retried; 0 when unknown
+}
+
+// QuotaChecker decides whether a command may run within its quota, e.g. by
+// asking a rate limiting service shared by all users of the infrastructure the
+// command acts on. Whether a check also consumes the quota is up to the checker.
+type QuotaChecker interface {
+	CheckQuota(ctx context.Context, req QuotaRequest) (QuotaDecision, error)
+}
+
+// QuotaCheckerFunc adapts a function to the QuotaChecker interface.
+type QuotaCheckerFunc func(ctx context.Context, req QuotaRequest) (QuotaDecision, error)
+
+// CheckQuota calls f(ctx, req).
+func (f QuotaCheckerFunc) CheckQuota(ctx context.Context, req QuotaRequest) (QuotaDecision, error) {
+	return f(ctx, req)
+}
+
+// SetQuotaChecker sets the checker consulted before running a command with a
+// quota. A command with a quota fails to run while no checker is set.
+func (cb *CommandBuilder) SetQuotaChecker(checker QuotaChecker) {
+	cb.quotaChecker = checker
+}
+
+// QuotaExceededError is returned instead of running a command whose quota
+// checker denied the run. Handlers and wrappers can test for it with errors.As
+// and read RetryAfter.
+type QuotaExceededError struct {
+	Quota      string        // quota of the command
+	Command    string        // full command path
+	Reason     string        // reason given by the checker
+	RetryAfter time.Duration // how long until the run may be retried; 0 when unknown
+}
+
+func (e *QuotaExceededError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: quota %q exceeded", e.Command, e.Quota)
+	if e.Reason != "" {
+		b.WriteString(": " + e.Reason)
+	}
+	if e.RetryAfter > 0 {
+		fmt.Fprintf(&b, " (retry after %s)", e.RetryAfter.Round(time.Second))
+	}
+	return b.String()
+}
+
+// validateQuota validates the quota of a command.
+func validateQuota(config *CommandConfig, path string, ve *ValidationError) {
+	if config.Quota == "" {
+		return
+	}
+	if config.RunFunc == "" {
+		ve.addError("command %q: quota requires run_func", path)
+	}
+	if strings.ContainsFunc(config.Quota, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' }) {
+		ve.addError("command %q: quota %q must not contain whitespace", path, config.Quota)
+	}
+}
+
+// quotaCheck returns the check of quota run before the handler, or nil for a
+// command without a quota. The check fails if no quota checker is set, so a
+// command with a quota never runs unchecked.
+func (cb *CommandBuilder) quotaCheck(quota string) func(*cobra.Command, []string) error {
+	if quota == "" {
+		return nil
+	}
+	return func(cmd *cobra.Command, args []string) error {
+		checker := cb.quotaChecker
+		if checker == nil {
+			return fmt.Errorf("quota %s needs a quota checker set with SetQuotaChecker", quota)
+		}
+		decision, err := checker.CheckQuota(cmd.Context(), QuotaRequest{
+			Quota:    quota,
+			Tool:     cmd.Root().Name(),
+			Command:  cmd.CommandPath(),
+			Args:     args,
+			Identity: contextIdentity(cmd.Context()),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to check quota %s: %w", quota, err)
+		}
+		if !decision.Allowed {
+			return &QuotaExceededError{
+				Quota:      quota,
+				Command:    cmd.CommandPath(),
+				Reason:     decision.Reason,
+				RetryAfter: decision.RetryAfter,
+			}
+		}
+		return nil
+	}
+}
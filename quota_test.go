@@ -0,0 +1,164 @@
+package cobrayaml
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const quotaYAML = `
+name: infra
+root:
+  use: infra
+  short: Infra tool
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+    quota: deployments-per-day
+  status:
+    use: status
+    short: Status
+    run_func: runStatus
+`
+
+func TestCommandBuilder_Quota(t *testing.T) {
+	var requests []QuotaRequest
+	allowed := true
+	checker := QuotaCheckerFunc(func(ctx context.Context, req QuotaRequest) (QuotaDecision, error) {
+		requests = append(requests, req)
+		if !allowed {
+			return QuotaDecision{Reason: "10 of 10 used", RetryAfter: 2 * time.Hour}, nil
+		}
+		return QuotaDecision{Allowed: true}, nil
+	})
+
+	cb, err := NewCommandBuilderFromString(quotaYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var runs int
+	run := func(cmd *cobra.Command, args []string) { runs++ }
+	cb.RegisterFunctions(map[string]any{"runDeploy": run, "runStatus": run})
+	cb.SetQuotaChecker(checker)
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+
+	rootCmd.SetArgs([]string{"deploy", "prod"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	rootCmd.SetArgs([]string{"status"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	want := []QuotaRequest{{Quota: "deployments-per-day", Tool: "infra", Command: "infra deploy", Args: []string{"prod"}}}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %+v, want %+v", requests, want)
+	}
+
+	allowed = false
+	rootCmd.SetArgs([]string{"deploy", "prod"})
+	err = rootCmd.Execute()
+	var quotaErr *QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("Execute() error = %v, want QuotaExceededError", err)
+	}
+	if quotaErr.RetryAfter != 2*time.Hour {
+		t.Errorf("RetryAfter = %v, want 2h", quotaErr.RetryAfter)
+	}
+	if want := `infra deploy: quota "deployments-per-day" exceeded: 10 of 10 used (retry after 2h0m0s)`; err.Error() != want {
+		t.Errorf("Execute() error = %q, want %q", err, want)
+	}
+	if runs != 2 {
+		t.Errorf("runs = %d, want 2 (the denied run must not call the handler)", runs)
+	}
+}
+
+func TestCommandBuilder_Quota_Errors(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(quotaYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	run := func(cmd *cobra.Command, args []string) {}
+	cb.RegisterFunctions(map[string]any{"runDeploy": run, "runStatus": run})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SetArgs([]string{"status"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Execute(status) error = %v, want commands without a quota to run without a checker", err)
+	}
+	rootCmd.SetArgs([]string{"deploy", "prod"})
+	if err := rootCmd.Execute(); err == nil || err.Error() != "quota deployments-per-day needs a quota checker set with SetQuotaChecker" {
+		t.Errorf("Execute(deploy) error = %v, want missing quota checker", err)
+	}
+
+	cb.SetQuotaChecker(QuotaCheckerFunc(func(ctx context.Context, req QuotaRequest) (QuotaDecision, error) {
+		return QuotaDecision{}, errors.New("service unavailable")
+	}))
+	rootCmd.SetArgs([]string{"deploy", "prod"})
+	if err := rootCmd.Execute(); err == nil || err.Error() != "failed to check quota deployments-per-day: service unavailable" {
+		t.Errorf("Execute() error = %v, want the checker error", err)
+	}
+
+	yamlContent := strings.Replace(quotaYAML, "    run_func: runStatus\n", "    quota: status checks\n", 1)
+	_, err = NewCommandBuilderFromString(yamlContent)
+	for _, want := range []string{
+		`command "status": quota requires run_func`,
+		`command "status": quota "status checks" must not contain whitespace`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestGenerator_Quota(t *testing.T) {
+	gen, err := NewGeneratorFromString(quotaYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	handlers, err := gen.GenerateHandlers("main")
+	if err != nil {
+		t.Fatalf("GenerateHandlers() error = %v", err)
+	}
+	for _, want := range []string{
+		`"github.com/S-mishina/cobrayaml"`,
+		"func configureBuilder(builder *cobrayaml.CommandBuilder) error {",
+		"// TODO: Check the quotas deployments-per-day with builder.SetQuotaChecker",
+	} {
+		if !strings.Contains(handlers, want) {
+			t.Errorf("handlers.go does not contain %q:\n%s", want, handlers)
+		}
+	}
+	main, err := gen.GenerateMain("main", "commands.yaml")
+	if err != nil {
+		t.Fatalf("GenerateMain() error = %v", err)
+	}
+	if !strings.Contains(main, "if err := configureBuilder(builder); err != nil {") {
+		t.Errorf("main.go does not call configureBuilder:\n%s", main)
+	}
+
+	// Without quotas there is nothing to configure.
+	gen, err = NewGeneratorFromString(strings.Replace(quotaYAML, "    quota: deployments-per-day\n", "", 1))
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	handlers, _ = gen.GenerateHandlers("main")
+	main, _ = gen.GenerateMain("main", "commands.yaml")
+	if strings.Contains(handlers, "configureBuilder") || strings.Contains(main, "configureBuilder") {
+		t.Errorf("generated code has configureBuilder without quotas:\n%s\n%s", handlers, main)
+	}
+}
@@ -2,8 +2,10 @@ package cobrayaml
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 	"text/template"
@@ -18,7 +20,12 @@ type CommandDoc struct {
 	FullPath    string
 	Aliases     []string
 	Flags       []FlagConfig
+	Env         []EnvConfig
 	Args        *ArgsConfig
+	ValidArgs   []string
+	Touches     []string
+	Deprecated  string
+	SideEffects string
 	Subcommands []CommandDoc
 	Depth       int
 }
@@ -28,8 +35,20 @@ type DocsConfig struct {
 	ToolName        string
 	ToolDescription string
 	Version         string
+	ConfigFile      string
+	ConfigFiles     []string
+	Settings        []SettingConfig
 	RootCommand     CommandDoc
 	Commands        []CommandDoc
+	Surfaces        []SurfaceDoc
+	Errors          []ErrorConfig
+}
+
+// SurfaceDoc holds documentation for the commands of a single surface
+type SurfaceDoc struct {
+	Name     string
+	Default  bool
+	Commands []CommandDoc
 }
 
 const docsTemplate = `# {{ .ToolName }}
@@ -52,21 +71,56 @@ go install github.com/your-username/{{ .ToolName }}@latest
 
 {{ if .RootCommand.Long }}{{ .RootCommand.Long }}{{ end }}
 
+{{ if .RootCommand.Touches }}**Accesses:** {{ range $i, $p := .RootCommand.Touches }}{{ if $i }}, {{ end }}` + "`" + `{{ $p }}` + "`" + `{{ end }} (asks for permission on first run){{ end }}
+
 {{ if .RootCommand.Flags }}### Global Flags
 
 | Flag | Shorthand | Type | Default | Description |
 |------|-----------|------|---------|-------------|
-{{ range .RootCommand.Flags }}| ` + "`" + `--{{ .Name }}` + "`" + ` | {{ if .Shorthand }}` + "`" + `-{{ .Shorthand }}` + "`" + `{{ end }} | {{ .Type }} | {{ if .DefaultValue }}` + "`" + `{{ .DefaultValue }}` + "`" + `{{ end }} | {{ .Usage }}{{ if .Required }} **(required)**{{ end }} |
-{{ end }}{{ end }}
+{{ range .RootCommand.Flags }}| ` + "`" + `--{{ .Name }}` + "`" + ` | {{ if .Shorthand }}` + "`" + `-{{ .Shorthand }}` + "`" + `{{ end }} | {{ .Type }} | {{ if .DefaultValue }}` + "`" + `{{ .DefaultValue }}` + "`" + `{{ end }} | {{ .Usage }}{{ if .Example }} (e.g. ` + "`" + `--{{ .Name }} {{ .Example }}` + "`" + `){{ end }}{{ if .Required }} **(required)**{{ end }}{{ if .Deprecated }} **(deprecated: {{ .Deprecated }})**{{ end }}{{ if .ShorthandDeprecated }} **(` + "`" + `-{{ .Shorthand }}` + "`" + ` deprecated: {{ .ShorthandDeprecated }})**{{ end }} |
+{{ end }}{{ end }}{{ if .RootCommand.Env }}
+### Environment Variables
+
+| Variable | Required | Description |
+|----------|----------|-------------|
+{{ range .RootCommand.Env }}| ` + "`" + `{{ .Name }}` + "`" + ` | {{ if .Required }}Yes{{ end }} | {{ .Description }} |
+{{ end }}{{ end }}{{ if .Settings }}
+## Configuration
+
+Settings are read from ` + "`" + `{{ .ConfigFile }}` + "`" + `. Run ` + "`" + `{{ .RootCommand.Name }} config init` + "`" + ` to create it with the defaults below and ` + "`" + `{{ .RootCommand.Name }} config validate` + "`" + ` to check it.
+
+{{ if .ConfigFiles }}Config files are merged in this order, later files overriding earlier ones (` + "`" + `{{ .RootCommand.Name }} config where` + "`" + ` shows which exist):
+
+{{ range .ConfigFiles }}- ` + "`" + `{{ . }}` + "`" + `
+{{ end }}
+{{ end }}| Key | Type | Default | Description |
+|-----|------|---------|-------------|
+{{ range .Settings }}| ` + "`" + `{{ .Key }}` + "`" + ` | {{ .Type }} | {{ if .Default }}` + "`" + `{{ .Default }}` + "`" + `{{ end }} | {{ .Description }}{{ if .Flag }} (flag ` + "`" + `--{{ .Flag }}` + "`" + `){{ end }}{{ if .Env }} (env ` + "`" + `{{ .Env }}` + "`" + `){{ end }}{{ if .Required }} **(required)**{{ end }} |
+{{ end }}
+Values are resolved with the precedence flag > env > config file > default. Use ` + "`" + `--debug-settings` + "`" + ` to print where each value came from.
+{{ end }}
 
 ## Commands
 
-{{ range .Commands }}{{ template "command" . }}{{ end }}
+{{ range .Commands }}{{ template "command" . }}{{ end }}{{ range .Surfaces }}
+## Commands ({{ .Name }})
+
+Available with ` + "`" + `--api-version {{ .Name }}` + "`" + `{{ if .Default }} (the default){{ end }}.
+
+{{ range .Commands }}{{ template "command" . }}{{ end }}{{ end }}{{ if .Errors }}
+## Error Reference
+
+| Code | Message | Hint | Docs |
+|------|---------|------|------|
+{{ range .Errors }}| ` + "`" + `{{ .Code }}` + "`" + ` | {{ .Message }} | {{ .Hint }} | {{ if .Doc }}[{{ .Code }}]({{ .Doc }}){{ end }} |
+{{ end }}{{ end }}
 `
 
-const commandTemplate = `{{ $heading := repeat "#" (add .Depth 3) }}{{ $heading }} {{ .Name }}
+const commandTemplate = `{{ $heading := repeat "#" (add .Depth 3) }}{{ $heading }} {{ .Name }}{{ if .Deprecated }} (deprecated){{ end }}{{ if .SideEffects }} ` + "`" + `{{ .SideEffects }}` + "`" + `{{ end }}
+
+{{ if .Deprecated }}**Deprecated:** {{ .Deprecated }}
 
-{{ .Short }}
+{{ end }}{{ .Short }}
 
 ` + "```" + `bash
 {{ .FullPath }}
@@ -76,13 +130,25 @@ const commandTemplate = `{{ $heading := repeat "#" (add .Depth 3) }}{{ $heading
 
 {{ end }}{{ if .Aliases }}**Aliases:** {{ join .Aliases ", " }}
 
-{{ end }}{{ if .Args }}**Arguments:** {{ argsDescription .Args }}
+{{ end }}{{ with argsDescription .Args }}**Arguments:** {{ . }}
+
+{{ end }}{{ if .ValidArgs }}**Valid arguments:** {{ range $i, $a := .ValidArgs }}{{ if $i }}, {{ end }}` + "`" + `{{ $a }}` + "`" + `{{ end }}{{ if and $.Args (or $.Args.OnlyValid (eq $.Args.Type "only_valid_args")) }} (others are rejected){{ end }}
+
+{{ end }}{{ if .Touches }}**Accesses:** {{ range $i, $p := .Touches }}{{ if $i }}, {{ end }}` + "`" + `{{ $p }}` + "`" + `{{ end }} (asks for permission on first run)
+
+{{ end }}{{ if eq .SideEffects "destructive" }}**Destructive:** asks for confirmation before running; pass ` + "`" + `--yes` + "`" + ` to skip it
 
 {{ end }}{{ if .Flags }}**Flags:**
 
 | Flag | Shorthand | Type | Default | Description |
 |------|-----------|------|---------|-------------|
-{{ range .Flags }}| ` + "`" + `--{{ .Name }}` + "`" + ` | {{ if .Shorthand }}` + "`" + `-{{ .Shorthand }}` + "`" + `{{ end }} | {{ .Type }} | {{ if .DefaultValue }}` + "`" + `{{ .DefaultValue }}` + "`" + `{{ end }} | {{ .Usage }}{{ if .Required }} **(required)**{{ end }} |
+{{ range .Flags }}| ` + "`" + `--{{ .Name }}` + "`" + ` | {{ if .Shorthand }}` + "`" + `-{{ .Shorthand }}` + "`" + `{{ end }} | {{ .Type }} | {{ if .DefaultValue }}` + "`" + `{{ .DefaultValue }}` + "`" + `{{ end }} | {{ .Usage }}{{ if .Example }} (e.g. ` + "`" + `--{{ .Name }} {{ .Example }}` + "`" + `){{ end }}{{ if .Required }} **(required)**{{ end }}{{ if .Deprecated }} **(deprecated: {{ .Deprecated }})**{{ end }}{{ if .ShorthandDeprecated }} **(` + "`" + `-{{ .Shorthand }}` + "`" + ` deprecated: {{ .ShorthandDeprecated }})**{{ end }} |
+{{ end }}{{ end }}{{ if .Env }}
+**Environment Variables:**
+
+| Variable | Required | Description |
+|----------|----------|-------------|
+{{ range .Env }}| ` + "`" + `{{ .Name }}` + "`" + ` | {{ if .Required }}Yes{{ end }} | {{ .Description }} |
 {{ end }}{{ end }}{{ if .Subcommands }}
 {{ range .Subcommands }}{{ template "command" . }}{{ end }}{{ end }}`
 
@@ -107,6 +173,10 @@ func (g *Generator) collectDocsConfig() *DocsConfig {
 		ToolName:        g.config.Name,
 		ToolDescription: g.config.Description,
 		Version:         g.config.Version,
+		ConfigFile:      g.config.ConfigFile,
+		ConfigFiles:     g.config.ConfigFiles,
+		Settings:        g.config.SettingsSchema,
+		Errors:          g.config.Errors,
 	}
 
 	// Collect root command documentation
@@ -116,11 +186,55 @@ func (g *Generator) collectDocsConfig() *DocsConfig {
 		Short:   g.config.Root.Short,
 		Long:    g.config.Root.Long,
 		Flags:   filterVisibleFlags(g.config.Root.Flags),
+		Env:     g.config.Root.Env,
 		Args:    g.config.Root.Args,
+		Touches: g.config.Root.Touches,
 		Aliases: g.config.Root.Aliases,
 		Depth:   0,
 	}
 
+	if base := g.config.BaseFlags; base.Enabled && !base.Config.Disabled {
+		name := configFlagName(base)
+		if !slices.ContainsFunc(g.config.Root.Flags, func(f FlagConfig) bool { return f.Name == name }) {
+			config.RootCommand.Flags = append(config.RootCommand.Flags, FlagConfig{
+				Name:         name,
+				Shorthand:    base.Config.Shorthand,
+				Type:         FlagTypeString,
+				DefaultValue: g.config.ConfigFile,
+				Usage:        cmp.Or(base.Config.Usage, "Config file"),
+			})
+		}
+	}
+	if len(g.config.Surfaces) > 0 {
+		config.RootCommand.Flags = append(config.RootCommand.Flags, FlagConfig{
+			Name:         apiVersionFlag,
+			Type:         FlagTypeString,
+			DefaultValue: g.config.DefaultSurface,
+			Usage:        surfaceFlagUsage(g.config),
+		})
+	}
+	if g.config.Accessibility {
+		config.RootCommand.Flags = append(config.RootCommand.Flags, FlagConfig{
+			Name:  accessibleFlag,
+			Type:  FlagTypeBool,
+			Usage: accessibleFlagUsage,
+		})
+	}
+	if g.config.Record {
+		config.RootCommand.Flags = append(config.RootCommand.Flags, FlagConfig{
+			Name:  recordFlag,
+			Type:  FlagTypeString,
+			Usage: recordFlagUsage,
+		})
+	}
+	if g.config.FuzzyMatch {
+		config.RootCommand.Flags = append(config.RootCommand.Flags, FlagConfig{
+			Name:  noInteractiveFlag,
+			Type:  FlagTypeBool,
+			Usage: "Do not offer matching commands for an unknown command",
+		})
+	}
+
 	// Collect all commands
 	var commands []CommandDoc
 
@@ -139,6 +253,17 @@ func (g *Generator) collectDocsConfig() *DocsConfig {
 	}
 
 	config.Commands = commands
+
+	for _, surface := range sortedKeys(g.config.Surfaces) {
+		doc := SurfaceDoc{Name: surface, Default: surface == g.config.DefaultSurface}
+		cmds := g.config.Surfaces[surface].Commands
+		for _, name := range sortedCommandNames(cmds) {
+			if !cmds[name].Hidden {
+				doc.Commands = append(doc.Commands, g.collectCommandDoc(cmds[name], name, 0))
+			}
+		}
+		config.Surfaces = append(config.Surfaces, doc)
+	}
 	return config
 }
 
@@ -151,15 +276,20 @@ func (g *Generator) collectCommandDoc(cmd CommandConfig, name string, depth int)
 	}
 
 	doc := CommandDoc{
-		Name:     cmdName,
-		Use:      cmd.Use,
-		Short:    cmd.Short,
-		Long:     cmd.Long,
-		FullPath: g.config.Root.Use + " " + cmd.Use,
-		Flags:    filterVisibleFlags(cmd.Flags),
-		Args:     cmd.Args,
-		Aliases:  cmd.Aliases,
-		Depth:    depth,
+		Name:        cmdName,
+		Use:         cmd.Use,
+		Short:       cmd.Short,
+		Long:        cmd.Long,
+		FullPath:    g.config.Root.Use + " " + cmd.Use,
+		Flags:       filterVisibleFlags(cmd.Flags),
+		Env:         cmd.Env,
+		Args:        cmd.Args,
+		ValidArgs:   cmd.ValidArgs,
+		Touches:     cmd.Touches,
+		Deprecated:  cmd.Deprecated,
+		SideEffects: cmd.SideEffects,
+		Aliases:     cmd.Aliases,
+		Depth:       depth,
 	}
 
 	// Collect subcommands
@@ -227,6 +357,8 @@ func renderDocsTemplate(config *DocsConfig) (string, error) {
 				return fmt.Sprintf("At most %d argument(s) allowed", args.Max)
 			case ArgsTypeRange:
 				return fmt.Sprintf("%d to %d argument(s)", args.Min, args.Max)
+			case ArgsTypeOnlyValidArgs:
+				return "Any number of the valid arguments"
 			default:
 				return ""
 			}
@@ -363,6 +363,277 @@ commands:
 	}
 }
 
+func TestGenerator_GenerateDocs_FlagExample(t *testing.T) {
+	yamlContent := `
+name: test-tool
+root:
+  use: test-tool
+  short: Test tool
+commands:
+  get:
+    use: get
+    short: Get command
+    run_func: runGet
+    flags:
+      - name: namespace
+        type: string
+        usage: Target namespace
+        example: kube-system
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	if !strings.Contains(docs, "Target namespace (e.g. `--namespace kube-system`)") {
+		t.Error("docs should contain the flag example")
+	}
+}
+
+func TestGenerator_GenerateDocs_DeprecatedFlag(t *testing.T) {
+	yamlContent := `
+name: test-tool
+root:
+  use: test-tool
+  short: Test tool
+commands:
+  get:
+    use: get
+    short: Get command
+    run_func: runGet
+    flags:
+      - name: output
+        type: string
+        usage: Output format
+      - name: format
+        type: string
+        usage: Output format
+        deprecated: use --output instead
+      - name: quiet
+        shorthand: q
+        type: bool
+        usage: Quiet output
+        shorthand_deprecated: use --quiet instead
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	if !strings.Contains(docs, "| `--format` |  | string |  | Output format **(deprecated: use --output instead)** |") {
+		t.Errorf("docs should annotate the deprecated flag, got:\n%s", docs)
+	}
+	if !strings.Contains(docs, "| `--quiet` | `-q` | bool |  | Quiet output **(`-q` deprecated: use --quiet instead)** |") {
+		t.Errorf("docs should annotate the deprecated shorthand, got:\n%s", docs)
+	}
+	if strings.Contains(docs, "`--output` |  | string |  | Output format **(deprecated") {
+		t.Error("docs should not annotate flags that are not deprecated")
+	}
+}
+
+func TestGenerator_GenerateDocs_Env(t *testing.T) {
+	yamlContent := `
+name: test-tool
+root:
+  use: test-tool
+  short: Test tool
+commands:
+  deploy:
+    use: deploy
+    short: Deploy command
+    run_func: runDeploy
+    env:
+      - name: API_TOKEN
+        description: Token used to authenticate
+        required: true
+      - name: API_REGION
+        description: Default region
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"**Environment Variables:**",
+		"| `API_TOKEN` | Yes | Token used to authenticate |",
+		"| `API_REGION` |  | Default region |",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("docs should contain %q", want)
+		}
+	}
+}
+
+func TestGenerator_GenerateDocs_Errors(t *testing.T) {
+	yamlContent := `
+name: test-tool
+root:
+  use: test-tool
+  short: Test tool
+errors:
+  - code: E1001
+    message: cannot reach %s
+    hint: Check your network connection.
+    doc: https://example.com/errors/E1001
+  - code: E1002
+    message: deployment failed
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"## Error Reference",
+		"| `E1001` | cannot reach %s | Check your network connection. | [E1001](https://example.com/errors/E1001) |",
+		"| `E1002` | deployment failed |  |  |",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("docs should contain %q", want)
+		}
+	}
+}
+
+func TestGenerator_GenerateDocs_Touches(t *testing.T) {
+	yamlContent := `
+name: test-tool
+root:
+  use: test-tool
+  short: Test tool
+commands:
+  deploy:
+    use: deploy
+    short: Deploy command
+    run_func: runDeploy
+    touches: [~/.kube/config, /etc/hosts]
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	if !strings.Contains(docs, "**Accesses:** `~/.kube/config`, `/etc/hosts` (asks for permission on first run)") {
+		t.Errorf("docs should list the touched paths, got:\n%s", docs)
+	}
+}
+
+func TestGenerator_GenerateDocs_Deprecated(t *testing.T) {
+	yamlContent := `
+name: test-tool
+root:
+  use: test-tool
+  short: Test tool
+commands:
+  release:
+    use: release
+    short: Release command
+    run_func: runRelease
+    deprecated: use 'deploy' instead
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	if !strings.Contains(docs, "### release (deprecated)\n\n**Deprecated:** use 'deploy' instead\n\nRelease command") {
+		t.Errorf("docs should flag the deprecated command, got:\n%s", docs)
+	}
+}
+
+func TestGenerator_GenerateDocs_Settings(t *testing.T) {
+	yamlContent := `
+name: test-tool
+config_file: ~/.test-tool.yaml
+root:
+  use: test-tool
+  short: Test tool
+settings_schema:
+  - key: server.port
+    type: int
+    default: "8080"
+    description: Port the server listens on
+  - key: token
+    type: string
+    description: API token
+    required: true
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"## Configuration",
+		"Settings are read from `~/.test-tool.yaml`. Run `test-tool config init`",
+		"| `server.port` | int | `8080` | Port the server listens on |",
+		"| `token` | string |  | API token **(required)** |",
+	} {
+		if !strings.Contains(docs, want) {
+			t.Errorf("docs should contain %q", want)
+		}
+	}
+}
+
+func TestGenerator_GenerateDocs_BaseFlags(t *testing.T) {
+	yamlContent := `
+name: test-tool
+config_file: ~/.test-tool.yaml
+base_flags: true
+root:
+  use: test-tool
+  short: Test tool
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+
+	if !strings.Contains(docs, "| `--config` |  | string | `~/.test-tool.yaml` | Config file |") {
+		t.Error("docs should list the --config base flag as a global flag")
+	}
+}
+
 func TestGenerator_GenerateDocs_Aliases(t *testing.T) {
 	yamlContent := `
 name: test-tool
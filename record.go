@@ -0,0 +1,155 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// recordFlag is the root flag naming the transcript file of a run.
+	recordFlag = "record"
+	// recordFlagUsage is the help text of --record.
+	recordFlagUsage = "Write a transcript of the run with its output and timing to this file"
+	// transcriptVersion is the version of the transcript format.
+	transcriptVersion = 1
+)
+
+// transcript is a recorded run of a command. Events follow asciinema: each is
+// [seconds since start, stream, data], with stream "o" for stdout and "e" for stderr.
+type transcript struct {
+	Version  int               `json:"version"`
+	Command  []string          `json:"command"`
+	Started  time.Time         `json:"started"`
+	Duration float64           `json:"duration"`
+	Error    string            `json:"error,omitempty"`
+	Env      map[string]string `json:"env"`
+	Events   []transcriptEvent `json:"events"`
+}
+
+// transcriptEvent is output written at a point in time of a recorded run.
+type transcriptEvent struct {
+	Time   float64
+	Stream string
+	Data   string
+}
+
+// MarshalJSON encodes the event as an asciinema event array.
+func (e transcriptEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Stream, e.Data})
+}
+
+// recorder collects the output of a run as transcript events.
+type recorder struct {
+	mu      sync.Mutex
+	started time.Time
+	events  []transcriptEvent
+}
+
+// recordWriter passes writes through to w and records them on stream.
+type recordWriter struct {
+	rec    *recorder
+	stream string
+	w      io.Writer
+}
+
+func (rw recordWriter) Write(p []byte) (int, error) {
+	rw.rec.mu.Lock()
+	rw.rec.events = append(rw.rec.events, transcriptEvent{
+		Time:   time.Since(rw.rec.started).Seconds(),
+		Stream: rw.stream,
+		Data:   string(p),
+	})
+	rw.rec.mu.Unlock()
+	return rw.w.Write(p)
+}
+
+// transcriptEnv returns the environment summary of a transcript. It leaves out
+// environment variables other than the terminal and shell, which may hold secrets.
+func transcriptEnv(cmd *cobra.Command) map[string]string {
+	env := map[string]string{
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+		"go":   runtime.Version(),
+	}
+	if version := cmd.Root().Version; version != "" {
+		env["version"] = version
+	}
+	for key, name := range map[string]string{"term": "TERM", "shell": "SHELL"} {
+		if value := os.Getenv(name); value != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// addRecordFlag adds --record to the root command when record is set.
+func (cb *CommandBuilder) addRecordFlag(rootCmd *cobra.Command) {
+	if !cb.config.Record || rootCmd.PersistentFlags().Lookup(recordFlag) != nil {
+		return
+	}
+	rootCmd.PersistentFlags().String(recordFlag, "", recordFlagUsage)
+}
+
+// addRecord wraps the RunE of cmd so a run with --record writes a transcript of
+// the output the handler writes to cmd.OutOrStdout and cmd.ErrOrStderr.
+func (cb *CommandBuilder) addRecord(cmd *cobra.Command) {
+	if !cb.config.Record || cmd.RunE == nil {
+		return
+	}
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString(recordFlag)
+		if path == "" {
+			return run(cmd, args)
+		}
+
+		rec := &recorder{started: time.Now()}
+		out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+		cmd.SetOut(recordWriter{rec: rec, stream: "o", w: out})
+		cmd.SetErr(recordWriter{rec: rec, stream: "e", w: errOut})
+		runErr := run(cmd, args)
+		cmd.SetOut(out)
+		cmd.SetErr(errOut)
+
+		t := transcript{
+			Version:  transcriptVersion,
+			Command:  append([]string{cmd.Root().Name()}, historyArgs(cmd, args)...),
+			Started:  rec.started,
+			Duration: time.Since(rec.started).Seconds(),
+			Env:      transcriptEnv(cmd),
+			Events:   rec.events,
+		}
+		if runErr != nil {
+			t.Error = runErr.Error()
+		}
+		if err := writeTranscript(path, t); err != nil {
+			fmt.Fprintf(errOut, "Warning: %v\n", err)
+		} else {
+			fmt.Fprintf(errOut, "Transcript written to %s\n", path)
+		}
+		return runErr
+	}
+}
+
+// writeTranscript writes t to path as indented JSON.
+func writeTranscript(path string, t transcript) error {
+	if t.Events == nil {
+		t.Events = []transcriptEvent{}
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const recordToolYAML = `
+name: record-test
+record: true
+version: 1.2.3
+root:
+  use: record-test
+  short: Record test
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+    flags:
+      - name: token
+        type: string
+        usage: API token
+        sensitive: true
+`
+
+func TestCommandBuilder_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	tool := testTool{yaml: recordToolYAML, funcs: map[string]any{
+		"runDeploy": func(cmd *cobra.Command, args []string) error {
+			fmt.Fprintf(cmd.OutOrStdout(), "deploying %s\n", args[0])
+			fmt.Fprintln(cmd.ErrOrStderr(), "slow network")
+			return errors.New("deploy failed")
+		},
+	}}
+	out, err := tool.run(t, "deploy", "prod", "--token", "s3cret", "--record", path)
+	if err == nil {
+		t.Fatal("Execute() should return the handler's error")
+	}
+	if !strings.Contains(out, "deploying prod\nslow network\nTranscript written to "+path+"\n") {
+		t.Errorf("output should pass through, got %q", out)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read transcript: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("transcript should not contain sensitive values:\n%s", data)
+	}
+	var got struct {
+		Version int               `json:"version"`
+		Command []string          `json:"command"`
+		Error   string            `json:"error"`
+		Env     map[string]string `json:"env"`
+		Events  [][]any           `json:"events"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("failed to decode transcript: %v", err)
+	}
+
+	wantCommand := "record-test deploy --record=" + path + " --token=<redacted> prod"
+	if got.Version != 1 || strings.Join(got.Command, " ") != wantCommand || got.Error != "deploy failed" {
+		t.Errorf("transcript = version %d, command %q, error %q", got.Version, got.Command, got.Error)
+	}
+	if got.Env["version"] != "1.2.3" || got.Env["os"] == "" {
+		t.Errorf("env = %v, want version and os", got.Env)
+	}
+	if len(got.Events) != 2 || got.Events[0][1] != "o" || got.Events[0][2] != "deploying prod\n" || got.Events[1][1] != "e" {
+		t.Errorf("events = %v, want stdout then stderr", got.Events)
+	}
+}
+
+func TestCommandBuilder_RecordNotRequested(t *testing.T) {
+	tool := testTool{yaml: recordToolYAML, funcs: map[string]any{
+		"runDeploy": func(cmd *cobra.Command, args []string) error {
+			fmt.Fprintln(cmd.OutOrStdout(), "deployed")
+			return nil
+		},
+	}}
+	out, err := tool.run(t, "deploy", "prod")
+	if err != nil || out != "deployed\n" {
+		t.Errorf("output = %q, error = %v, want only the handler's output", out, err)
+	}
+}
@@ -0,0 +1,290 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Output formats of GenerateSchedule.
+const (
+	// ScheduleFormatCrontab generates a crontab line.
+	ScheduleFormatCrontab = "crontab"
+	// ScheduleFormatSystemd generates a systemd service unit and a timer unit.
+	ScheduleFormatSystemd = "systemd"
+)
+
+// SupportedScheduleFormats lists all output formats of GenerateSchedule.
+var SupportedScheduleFormats = []string{
+	ScheduleFormatCrontab,
+	ScheduleFormatSystemd,
+}
+
+// ScheduleOptions configures GenerateSchedule.
+type ScheduleOptions struct {
+	Cron   string   // five-field cron expression or a macro such as @daily
+	Format string   // ScheduleFormatCrontab (default) or ScheduleFormatSystemd
+	Binary string   // binary to run (default: the tool name)
+	Args   []string // args and flags passed to the command
+}
+
+// ScheduleFile is a file generated by GenerateSchedule.
+type ScheduleFile struct {
+	Name    string
+	Content string
+}
+
+// cronMacros maps the cron macros to systemd calendar shorthands.
+var cronMacros = map[string]string{
+	"@hourly":   "hourly",
+	"@daily":    "daily",
+	"@midnight": "daily",
+	"@weekly":   "weekly",
+	"@monthly":  "monthly",
+	"@yearly":   "yearly",
+	"@annually": "yearly",
+}
+
+// cronFields describes the five fields of a cron expression in order.
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// weekdays are the systemd names of the cron days of week 0-7.
+var weekdays = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// GenerateSchedule generates the files that run the command at cmdPath on the
+// schedule opts.Cron. cmdPath names the command by its names separated with dots
+// or spaces, such as "db.backup". The crontab format returns a single crontab line;
+// the systemd format returns a oneshot service unit and a timer unit named after
+// the tool and the command, such as mytool-db-backup.service.
+func (g *Generator) GenerateSchedule(cmdPath string, opts ScheduleOptions) ([]ScheduleFile, error) {
+	names := strings.FieldsFunc(cmdPath, func(r rune) bool { return r == '.' || r == ' ' })
+	if len(names) == 0 {
+		return nil, fmt.Errorf("command path must not be empty")
+	}
+	cmd, err := findCommandConfig(g.config.Commands, names)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.RunFunc == "" {
+		return nil, fmt.Errorf("command %q has no run_func to schedule", strings.Join(names, " "))
+	}
+
+	binary := opts.Binary
+	if binary == "" {
+		binary = g.config.Name
+	}
+	unit := g.config.Name + "-" + strings.Join(names, "-")
+	description := fmt.Sprintf("%s %s", g.config.Name, strings.Join(names, " "))
+	if cmd.Short != "" {
+		description += ": " + cmd.Short
+	}
+	argv := append(append([]string{binary}, names...), opts.Args...)
+
+	switch opts.Format {
+	case "", ScheduleFormatCrontab:
+		if err := validateCron(opts.Cron); err != nil {
+			return nil, err
+		}
+		quoted := make([]string, len(argv))
+		for i, arg := range argv {
+			// cron turns unescaped % into newlines
+			quoted[i] = strings.ReplaceAll(shellQuote(arg), "%", `\%`)
+		}
+		content := fmt.Sprintf("# %s\n%s %s\n", description, opts.Cron, strings.Join(quoted, " "))
+		return []ScheduleFile{{Name: unit + ".cron", Content: content}}, nil
+
+	case ScheduleFormatSystemd:
+		calendar, err := cronToOnCalendar(opts.Cron)
+		if err != nil {
+			return nil, err
+		}
+		quoted := make([]string, len(argv))
+		for i, arg := range argv {
+			quoted[i] = systemdQuote(arg)
+		}
+
+		var service strings.Builder
+		service.WriteString("[Unit]\n")
+		fmt.Fprintf(&service, "Description=%s\n\n", description)
+		service.WriteString("[Service]\n")
+		service.WriteString("Type=oneshot\n")
+		fmt.Fprintf(&service, "ExecStart=%s\n", strings.Join(quoted, " "))
+
+		var timer strings.Builder
+		timer.WriteString("[Unit]\n")
+		fmt.Fprintf(&timer, "Description=Run %s on a schedule\n\n", unit)
+		timer.WriteString("[Timer]\n")
+		fmt.Fprintf(&timer, "# cron: %s\n", opts.Cron)
+		fmt.Fprintf(&timer, "OnCalendar=%s\n", calendar)
+		timer.WriteString("Persistent=true\n\n")
+		timer.WriteString("[Install]\n")
+		timer.WriteString("WantedBy=timers.target\n")
+
+		return []ScheduleFile{
+			{Name: unit + ".service", Content: service.String()},
+			{Name: unit + ".timer", Content: timer.String()},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported schedule format %q (supported: %s)", opts.Format, strings.Join(SupportedScheduleFormats, ", "))
+	}
+}
+
+// findCommandConfig returns the command at the path names below cmds.
+func findCommandConfig(cmds map[string]CommandConfig, names []string) (CommandConfig, error) {
+	var cmd CommandConfig
+	for i, name := range names {
+		next, exists := cmds[name]
+		if !exists {
+			return CommandConfig{}, fmt.Errorf("command %q not found", strings.Join(names[:i+1], " "))
+		}
+		cmd = next
+		cmds = cmd.Commands
+	}
+	return cmd, nil
+}
+
+// validateCron checks that expr is a cron macro or a five-field cron expression.
+func validateCron(expr string) error {
+	_, err := cronToOnCalendar(expr)
+	return err
+}
+
+// cronToOnCalendar converts a cron expression into a systemd OnCalendar value.
+// Fields support *, numbers, lists, ranges and steps; names such as MON are not
+// supported.
+func cronToOnCalendar(expr string) (string, error) {
+	if expr == "" {
+		return "", fmt.Errorf("cron expression is required")
+	}
+	if calendar, ok := cronMacros[expr]; ok {
+		return calendar, nil
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return "", fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week)", expr)
+	}
+
+	converted := make([]string, len(fields))
+	for i, field := range fields {
+		c, err := convertCronField(field, i)
+		if err != nil {
+			return "", fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		converted[i] = c
+	}
+
+	minute, hour, dom, month, dow := converted[0], converted[1], converted[2], converted[3], converted[4]
+	if dom != "*" && dow != "*" {
+		// cron runs when either field matches, systemd only when both do
+		return "", fmt.Errorf("cron expression %q restricts both day of month and day of week, which systemd cannot express", expr)
+	}
+	calendar := fmt.Sprintf("*-%s-%s %s:%s:00", month, dom, hour, minute)
+	if dow != "*" {
+		calendar = dow + " " + calendar
+	}
+	return calendar, nil
+}
+
+// convertCronField converts the cron field at index into its systemd form: ranges
+// use "..", steps start from the first value and days of week use names.
+func convertCronField(field string, index int) (string, error) {
+	spec := cronFields[index]
+	var parts []string
+	for _, part := range strings.Split(field, ",") {
+		base, step, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			n, err := strconv.Atoi(step)
+			if err != nil || n <= 0 {
+				return "", fmt.Errorf("invalid %s step %q", spec.name, part)
+			}
+			if index == 4 {
+				return "", fmt.Errorf("steps are not supported for day of week: %q", part)
+			}
+		}
+
+		if base == "*" {
+			if !hasStep {
+				parts = append(parts, "*")
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s/%s", formatCronValue(spec.min, index), step))
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(base, "-")
+		from, err := parseCronValue(lo, index)
+		if err != nil {
+			return "", err
+		}
+		if !isRange {
+			if hasStep {
+				parts = append(parts, fmt.Sprintf("%s/%s", formatCronValue(from, index), step))
+			} else {
+				parts = append(parts, formatCronValue(from, index))
+			}
+			continue
+		}
+		to, err := parseCronValue(hi, index)
+		if err != nil {
+			return "", err
+		}
+		if to < from {
+			return "", fmt.Errorf("invalid %s range %q", spec.name, base)
+		}
+		if hasStep {
+			return "", fmt.Errorf("steps over ranges are not supported: %q", part)
+		}
+		parts = append(parts, formatCronValue(from, index)+".."+formatCronValue(to, index))
+	}
+	return strings.Join(slices.Compact(parts), ","), nil
+}
+
+// parseCronValue parses a number of the cron field at index and checks its bounds.
+func parseCronValue(s string, index int) (int, error) {
+	spec := cronFields[index]
+	n, err := strconv.Atoi(s)
+	if err != nil || n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("invalid %s %q (want %d-%d)", spec.name, s, spec.min, spec.max)
+	}
+	return n, nil
+}
+
+// formatCronValue formats n for the systemd form of the cron field at index.
+func formatCronValue(n, index int) string {
+	if index == 4 {
+		return weekdays[n]
+	}
+	return fmt.Sprintf("%02d", n)
+}
+
+// shellQuote quotes s for a POSIX shell if it contains special characters.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@+%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// systemdQuote quotes s for an ExecStart= command line if needed. Specifiers (%)
+// and variable expansion ($) are escaped so args are passed literally.
+func systemdQuote(s string) string {
+	escaped := strings.NewReplacer("%", "%%", "$", "$$").Replace(s)
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\;") {
+		return escaped
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(escaped) + `"`
+}
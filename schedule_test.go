@@ -0,0 +1,164 @@
+package cobrayaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const scheduleYAML = `
+name: mytool
+root:
+  use: mytool
+  short: My tool
+commands:
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+`
+
+func TestGenerator_GenerateSchedule(t *testing.T) {
+	gen, err := NewGeneratorFromString(scheduleYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cmdPath string
+		opts    ScheduleOptions
+		want    []ScheduleFile
+		wantErr string
+	}{
+		{
+			name:    "crontab",
+			cmdPath: "db.backup",
+			opts:    ScheduleOptions{Cron: "0 3 * * *", Args: []string{"--label", "nightly 100%"}},
+			want: []ScheduleFile{{
+				Name:    "mytool-db-backup.cron",
+				Content: "# mytool db backup: Back up the database\n0 3 * * * mytool db backup --label 'nightly 100\\%'\n",
+			}},
+		},
+		{
+			name:    "systemd",
+			cmdPath: "db backup",
+			opts: ScheduleOptions{
+				Cron:   "*/15 9-17 * * 1-5",
+				Format: ScheduleFormatSystemd,
+				Binary: "/usr/local/bin/mytool",
+				Args:   []string{"--label", "nightly 100%"},
+			},
+			want: []ScheduleFile{
+				{
+					Name: "mytool-db-backup.service",
+					Content: `[Unit]
+Description=mytool db backup: Back up the database
+
+[Service]
+Type=oneshot
+ExecStart=/usr/local/bin/mytool db backup --label "nightly 100%%"
+`,
+				},
+				{
+					Name: "mytool-db-backup.timer",
+					Content: `[Unit]
+Description=Run mytool-db-backup on a schedule
+
+[Timer]
+# cron: */15 9-17 * * 1-5
+OnCalendar=Mon..Fri *-*-* 09..17:00/15:00
+Persistent=true
+
+[Install]
+WantedBy=timers.target
+`,
+				},
+			},
+		},
+		{
+			name:    "unknown command",
+			cmdPath: "db.restore",
+			opts:    ScheduleOptions{Cron: "@daily"},
+			wantErr: `command "db restore" not found`,
+		},
+		{
+			name:    "no run_func",
+			cmdPath: "db",
+			opts:    ScheduleOptions{Cron: "@daily"},
+			wantErr: `command "db" has no run_func to schedule`,
+		},
+		{
+			name:    "unsupported format",
+			cmdPath: "db.backup",
+			opts:    ScheduleOptions{Cron: "@daily", Format: "launchd"},
+			wantErr: `unsupported schedule format "launchd"`,
+		},
+		{
+			name:    "invalid cron",
+			cmdPath: "db.backup",
+			opts:    ScheduleOptions{Cron: "0 25 * * *"},
+			wantErr: `invalid hour "25"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := gen.GenerateSchedule(tt.cmdPath, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GenerateSchedule() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateSchedule() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GenerateSchedule() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronToOnCalendar(t *testing.T) {
+	tests := []struct {
+		cron    string
+		want    string
+		wantErr string
+	}{
+		{cron: "@weekly", want: "weekly"},
+		{cron: "0 3 * * *", want: "*-*-* 03:00:00"},
+		{cron: "30 2 1,15 */3 *", want: "*-01/3-01,15 02:30:00"},
+		{cron: "0 0 * * 0,7", want: "Sun *-*-* 00:00:00"},
+		{cron: "", wantErr: "cron expression is required"},
+		{cron: "0 3 * *", wantErr: "must have 5 fields"},
+		{cron: "0 3 1 * 1", wantErr: "restricts both day of month and day of week"},
+		{cron: "0 3 * * */2", wantErr: "steps are not supported for day of week"},
+		{cron: "0 17-9 * * *", wantErr: `invalid hour range "17-9"`},
+		{cron: "*/0 * * * *", wantErr: `invalid minute step "*/0"`},
+		{cron: "0 0 * JAN *", wantErr: `invalid month "JAN"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cron, func(t *testing.T) {
+			got, err := cronToOnCalendar(tt.cron)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("cronToOnCalendar() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("cronToOnCalendar() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("cronToOnCalendar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
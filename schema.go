@@ -0,0 +1,404 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+)
+
+// schemaAnnotation is the pflag annotation key that carries the payload schema source.
+const schemaAnnotation = "cobrayaml_schema"
+
+// PayloadSchema is a compiled JSON Schema used to validate JSON/YAML payload flags.
+//
+// The following subset of JSON Schema is supported: type, enum, const, required,
+// properties, additionalProperties (boolean), items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum and maximum.
+type PayloadSchema struct {
+	Type                 []string
+	Enum                 []any
+	Const                any
+	HasConst             bool
+	Required             []string
+	Properties           map[string]*PayloadSchema
+	AdditionalProperties *bool
+	Items                *PayloadSchema
+	MinItems             *int
+	MaxItems             *int
+	MinLength            *int
+	MaxLength            *int
+	Pattern              *regexp.Regexp
+	Minimum              *float64
+	Maximum              *float64
+}
+
+// SchemaViolation describes a single place where a payload does not match its schema.
+type SchemaViolation struct {
+	Path    string // JSON path of the offending value, e.g. "$.spec.replicas"
+	Message string
+}
+
+// String returns the violation formatted as "path: message".
+func (v SchemaViolation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// SchemaError is returned when a payload flag does not match its schema.
+type SchemaError struct {
+	Flag       string
+	Violations []SchemaViolation
+}
+
+// Error returns the formatted error message with all schema violations.
+func (e *SchemaError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "flag --%s: payload does not match schema (%d violation(s)):\n", e.Flag, len(e.Violations))
+	for _, v := range e.Violations {
+		sb.WriteString("  - ")
+		sb.WriteString(v.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// LoadPayloadSchema compiles a schema from its source. The source is either an
+// inline JSON/YAML schema document (starting with "{" or spanning several lines)
+// or the path of a file containing one.
+func LoadPayloadSchema(source string) (*PayloadSchema, error) {
+	data, err := readInlineOrFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	return compileSchema(normalizeYAML(raw), "$")
+}
+
+// Validate validates a decoded payload against the schema and returns all violations.
+func (s *PayloadSchema) Validate(value any) []SchemaViolation {
+	var violations []SchemaViolation
+	s.validate(normalizeYAML(value), "$", &violations)
+	return violations
+}
+
+// readInlineOrFile returns inline content as-is, or reads the file it names.
+func readInlineOrFile(source string) ([]byte, error) {
+	if isInline(source) {
+		return []byte(source), nil
+	}
+	return os.ReadFile(strings.TrimSpace(source))
+}
+
+// isInline reports whether source is inline content rather than a file path.
+func isInline(source string) bool {
+	trimmed := strings.TrimSpace(source)
+	return strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") || strings.Contains(trimmed, "\n")
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values produced by
+// yaml.v2 into map[string]any so payloads look like decoded JSON.
+func normalizeYAML(value any) any {
+	switch v := value.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, val := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(val)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, val := range v {
+			m[key] = normalizeYAML(val)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, val := range v {
+			s[i] = normalizeYAML(val)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// compileSchema compiles a decoded schema document.
+func compileSchema(raw any, path string) (*PayloadSchema, error) {
+	doc, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema %s: must be an object", path)
+	}
+
+	s := &PayloadSchema{}
+	switch t := doc["type"].(type) {
+	case nil:
+	case string:
+		s.Type = []string{t}
+	case []any:
+		for _, item := range t {
+			s.Type = append(s.Type, fmt.Sprint(item))
+		}
+	default:
+		return nil, fmt.Errorf("schema %s: type must be a string or a list of strings", path)
+	}
+
+	if enum, ok := doc["enum"].([]any); ok {
+		s.Enum = enum
+	}
+	if c, ok := doc["const"]; ok {
+		s.Const, s.HasConst = c, true
+	}
+	if required, ok := doc["required"].([]any); ok {
+		for _, r := range required {
+			s.Required = append(s.Required, fmt.Sprint(r))
+		}
+	}
+	if props, ok := doc["properties"].(map[string]any); ok {
+		s.Properties = make(map[string]*PayloadSchema, len(props))
+		for name, prop := range props {
+			compiled, err := compileSchema(prop, path+"."+name)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = compiled
+		}
+	}
+	if ap, ok := doc["additionalProperties"].(bool); ok {
+		s.AdditionalProperties = &ap
+	}
+	if items, ok := doc["items"]; ok {
+		compiled, err := compileSchema(items, path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		s.Items = compiled
+	}
+	if pattern, ok := doc["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: invalid pattern: %w", path, err)
+		}
+		s.Pattern = re
+	}
+
+	s.MinItems = schemaInt(doc, "minItems")
+	s.MaxItems = schemaInt(doc, "maxItems")
+	s.MinLength = schemaInt(doc, "minLength")
+	s.MaxLength = schemaInt(doc, "maxLength")
+	s.Minimum = schemaNumber(doc, "minimum")
+	s.Maximum = schemaNumber(doc, "maximum")
+
+	return s, nil
+}
+
+// schemaInt reads an integer keyword from a schema document.
+func schemaInt(doc map[string]any, key string) *int {
+	if n := schemaNumber(doc, key); n != nil {
+		i := int(*n)
+		return &i
+	}
+	return nil
+}
+
+// schemaNumber reads a numeric keyword from a schema document.
+func schemaNumber(doc map[string]any, key string) *float64 {
+	if n, ok := toFloat(doc[key]); ok {
+		return &n
+	}
+	return nil
+}
+
+// toFloat converts decoded numeric values to float64.
+func toFloat(value any) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// jsonType returns the JSON Schema type name of a decoded value.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		if f, ok := toFloat(v); ok {
+			if f == math.Trunc(f) {
+				return "integer"
+			}
+			return "number"
+		}
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// matchesType reports whether value satisfies one of the allowed types.
+func matchesType(value any, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PayloadSchema) validate(value any, path string, violations *[]SchemaViolation) {
+	add := func(format string, args ...any) {
+		*violations = append(*violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !matchesType(value, s.Type) {
+		add("expected %s, got %s", strings.Join(s.Type, " or "), jsonType(value))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		add("value %v is not one of %v", value, s.Enum)
+	}
+	if s.HasConst && fmt.Sprint(s.Const) != fmt.Sprint(value) {
+		add("value %v must equal %v", value, s.Const)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				add("missing required property %q", name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := s.Properties[key]; ok {
+				prop.validate(v[key], path+"."+key, violations)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				add("unknown property %q", key)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			add("expected at least %d item(s), got %d", *s.MinItems, len(v))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			add("expected at most %d item(s), got %d", *s.MaxItems, len(v))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			add("expected at least %d character(s), got %d", *s.MinLength, len(v))
+		}
+		if s.MaxLength != nil && len(v) > *s.MaxLength {
+			add("expected at most %d character(s), got %d", *s.MaxLength, len(v))
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			add("value %q does not match pattern %q", v, s.Pattern.String())
+		}
+	default:
+		if n, ok := toFloat(v); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				add("value %v is less than minimum %v", v, *s.Minimum)
+			}
+			if s.Maximum != nil && n > *s.Maximum {
+				add("value %v is greater than maximum %v", v, *s.Maximum)
+			}
+		}
+	}
+}
+
+// loadSchema compiles and caches the schema for a flag.
+func (cb *CommandBuilder) loadSchema(source string) (*PayloadSchema, error) {
+	cb.schemasMu.Lock()
+	defer cb.schemasMu.Unlock()
+	if schema, ok := cb.schemas[source]; ok {
+		return schema, nil
+	}
+	schema, err := LoadPayloadSchema(source)
+	if err != nil {
+		return nil, err
+	}
+	cb.schemas[source] = schema
+	return schema, nil
+}
+
+// schemaSource returns the source of the schema of a flag: an inline schema as
+// it is, and a relative schema file resolved against the directory of
+// commands.yaml when it was loaded from a file.
+func (cb *CommandBuilder) schemaSource(schema string) string {
+	if isInline(schema) || cb.configDir == "" || filepath.IsAbs(strings.TrimSpace(schema)) {
+		return schema
+	}
+	return filepath.Join(cb.configDir, strings.TrimSpace(schema))
+}
+
+// validatePayloads validates every payload flag visible to cmd against its schema.
+// A payload is given inline (starting with "{" or "[") or as the path of a JSON/YAML file.
+// Flags left empty are not validated. Schema files are loaded on first use, so a
+// missing schema file only fails the flags that use it.
+func (cb *CommandBuilder) validatePayloads(cmd *cobra.Command) error {
+	var firstErr error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		sources := flag.Annotations[schemaAnnotation]
+		if firstErr != nil || len(sources) == 0 || flag.Value.String() == "" {
+			return
+		}
+
+		schema, err := cb.loadSchema(sources[0])
+		if err != nil {
+			firstErr = fmt.Errorf("flag --%s: invalid schema: %w", flag.Name, err)
+			return
+		}
+
+		data, err := readInlineOrFile(flag.Value.String())
+		if err != nil {
+			firstErr = fmt.Errorf("flag --%s: failed to read payload: %w", flag.Name, err)
+			return
+		}
+
+		var payload any
+		if err := yaml.Unmarshal(data, &payload); err != nil {
+			firstErr = fmt.Errorf("flag --%s: failed to parse payload: %w", flag.Name, err)
+			return
+		}
+
+		if violations := schema.Validate(payload); len(violations) > 0 {
+			firstErr = &SchemaError{Flag: flag.Name, Violations: violations}
+		}
+	})
+	return firstErr
+}
@@ -0,0 +1,310 @@
+package cobrayaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const testResourceSchema = `{
+  "type": "object",
+  "required": ["name", "spec"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string", "pattern": "^[a-z-]+$"},
+    "spec": {
+      "type": "object",
+      "properties": {
+        "replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+        "tier": {"enum": ["web", "worker"]},
+        "ports": {"type": "array", "minItems": 1, "items": {"type": "integer"}}
+      }
+    }
+  }
+}`
+
+func TestPayloadSchema_Validate(t *testing.T) {
+	schema, err := LoadPayloadSchema(testResourceSchema)
+	if err != nil {
+		t.Fatalf("LoadPayloadSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{
+			name:    "valid",
+			payload: "name: api\nspec:\n  replicas: 3\n  tier: web\n  ports: [80, 443]\n",
+		},
+		{
+			name:    "missing required",
+			payload: `{"name": "api"}`,
+			want:    []string{`$: missing required property "spec"`},
+		},
+		{
+			name:    "nested violations",
+			payload: "name: API\nextra: 1\nspec:\n  replicas: 20\n  tier: db\n  ports: [web]\n",
+			want: []string{
+				`$.name: value "API" does not match pattern`,
+				`$: unknown property "extra"`,
+				"$.spec.replicas: value 20 is greater than maximum 10",
+				"$.spec.tier: value db is not one of [web worker]",
+				"$.spec.ports[0]: expected integer, got string",
+			},
+		},
+		{
+			name:    "wrong root type",
+			payload: "[1, 2]",
+			want:    []string{"$: expected object, got array"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload any
+			data, _ := readInlineOrFile(tt.payload)
+			if err := yaml.Unmarshal(data, &payload); err != nil {
+				t.Fatalf("failed to parse payload: %v", err)
+			}
+
+			violations := schema.Validate(payload)
+			if len(violations) != len(tt.want) {
+				t.Fatalf("got %d violation(s) %v, want %d", len(violations), violations, len(tt.want))
+			}
+			var all []string
+			for _, v := range violations {
+				all = append(all, v.String())
+			}
+			joined := strings.Join(all, "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(joined, want) {
+					t.Errorf("violations should contain %q, got:\n%s", want, joined)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPayloadSchema_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"missing file", "/nonexistent/schema.json"},
+		{"not an object", "[1, 2]"},
+		{"bad pattern", `{"type": "string", "pattern": "("}`},
+		{"bad type", `{"type": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadPayloadSchema(tt.source); err == nil {
+				t.Error("LoadPayloadSchema() expected error")
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_SchemaFlag(t *testing.T) {
+	tmpDir := t.TempDir()
+	schemaPath := filepath.Join(tmpDir, "schema.json")
+	if err := os.WriteFile(schemaPath, []byte(testResourceSchema), 0644); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+	validPath := filepath.Join(tmpDir, "valid.yaml")
+	if err := os.WriteFile(validPath, []byte("name: api\nspec:\n  replicas: 2\n"), 0644); err != nil {
+		t.Fatalf("failed to write payload: %v", err)
+	}
+	invalidPath := filepath.Join(tmpDir, "invalid.yaml")
+	if err := os.WriteFile(invalidPath, []byte("name: api\nspec:\n  replicas: 0\n"), 0644); err != nil {
+		t.Fatalf("failed to write payload: %v", err)
+	}
+
+	yamlContent := `
+name: schema-test
+root:
+  use: schema-test
+  short: Schema test
+commands:
+  create:
+    use: create
+    short: Create a resource
+    run_func: runCreate
+    flags:
+      - name: filename
+        shorthand: f
+        type: string
+        usage: Resource file
+        schema: ` + schemaPath + `
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	ran := 0
+	cb.RegisterFunction("runCreate", func(cmd *cobra.Command, args []string) error {
+		ran++
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.SetArgs([]string{"create", "-f", validPath})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() with valid payload error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"create", "-f", invalidPath})
+	err = rootCmd.Execute()
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("Execute() error = %v, want *SchemaError", err)
+	}
+	if schemaErr.Flag != "filename" {
+		t.Errorf("SchemaError.Flag = %q, want %q", schemaErr.Flag, "filename")
+	}
+	if !strings.Contains(err.Error(), "$.spec.replicas") {
+		t.Errorf("error should contain the violation path, got: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"create", "-f", `{"name": "inline", "spec": {}}`})
+	if err := rootCmd.Execute(); err != nil {
+		t.Errorf("Execute() with inline payload error = %v", err)
+	}
+
+	if ran != 2 {
+		t.Errorf("handler ran %d time(s), want 2", ran)
+	}
+}
+
+func TestCommandBuilder_SchemaFlagInvalidSchema(t *testing.T) {
+	yamlContent := `
+name: schema-test
+root:
+  use: schema-test
+  short: Schema test
+commands:
+  create:
+    use: create
+    short: Create a resource
+    run_func: runCreate
+    flags:
+      - name: filename
+        type: string
+        usage: Resource file
+        schema: /nonexistent/schema.json
+  list:
+    use: list
+    short: List resources
+    run_func: runList
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	run := func(cmd *cobra.Command, args []string) error { return nil }
+	cb.RegisterFunctions(map[string]any{"runCreate": run, "runList": run})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	for _, args := range [][]string{{"list"}, {"create"}} {
+		rootCmd.SetArgs(args)
+		if err := rootCmd.Execute(); err != nil {
+			t.Errorf("Execute(%v) error = %v", args, err)
+		}
+	}
+
+	rootCmd.SetArgs([]string{"create", "--filename", `{"name": "api"}`})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "flag --filename: invalid schema") {
+		t.Errorf("Execute() error = %v, want invalid schema error for --filename", err)
+	}
+
+	inline := strings.Replace(yamlContent, "/nonexistent/schema.json", `'{"type": 1}'`, 1)
+	cb, err = NewCommandBuilderFromString(inline)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunctions(map[string]any{"runCreate": run, "runList": run})
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "invalid schema for flag filename") {
+		t.Errorf("BuildRootCommand() error = %v, want invalid inline schema error", err)
+	}
+}
+
+func TestCommandBuilder_SchemaFlagRelativePath(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "schema.json"), []byte(testResourceSchema), 0644); err != nil {
+		t.Fatalf("failed to write schema: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "commands.yaml")
+	if err := os.WriteFile(configPath, []byte(`
+name: schema-test
+root:
+  use: schema-test
+  short: Schema test
+commands:
+  create:
+    use: create
+    short: Create a resource
+    run_func: runCreate
+    flags:
+      - name: filename
+        type: string
+        usage: Resource file
+        schema: schema.json
+`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cb, err := NewCommandBuilder(configPath)
+	if err != nil {
+		t.Fatalf("NewCommandBuilder() error = %v", err)
+	}
+	cb.RegisterFunction("runCreate", func(cmd *cobra.Command, args []string) error { return nil })
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	rootCmd.SetArgs([]string{"create", "--filename", `{"name": "api"}`})
+	var schemaErr *SchemaError
+	if err := rootCmd.Execute(); !errors.As(err, &schemaErr) {
+		t.Errorf("Execute() error = %v, want *SchemaError from schema.json next to commands.yaml", err)
+	}
+}
+
+func TestValidateConfig_SchemaUnsupportedType(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Flags: []FlagConfig{
+				{Name: "count", Type: FlagTypeInt, Usage: "Count", Schema: "{}"},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "schema is only supported") {
+		t.Errorf("ValidateConfig() error = %v, want schema error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// addSearchCommand adds the "search" command when search_command is set, unless
+// the tool already has a command of that name.
+func (cb *CommandBuilder) addSearchCommand(rootCmd *cobra.Command) {
+	if !cb.config.SearchCommand || findSubcommand(rootCmd, "search") != nil {
+		return
+	}
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "search <keyword>...",
+		Short: "Search commands by name, alias and description",
+		Long: "Search commands by name, alias and description. A command matches when it contains " +
+			"every keyword, ignoring case; commands matching by name or alias are listed first.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			matches := searchCommands(cmd.Root(), args)
+			out := cmd.OutOrStdout()
+			if len(matches) == 0 {
+				fmt.Fprintf(out, "No commands match %q\n", strings.Join(args, " "))
+				return nil
+			}
+
+			if Accessible(cmd) {
+				for _, m := range matches {
+					fmt.Fprintf(out, "%s: %s\n", m.CommandPath(), m.Short)
+				}
+				return nil
+			}
+			width := 0
+			for _, m := range matches {
+				width = max(width, len(m.CommandPath()))
+			}
+			for _, m := range matches {
+				fmt.Fprintf(out, "%-*s  %s\n", width, m.CommandPath(), m.Short)
+			}
+			return nil
+		},
+	})
+}
+
+// searchCommands returns the available commands below rootCmd whose name,
+// aliases, short and long descriptions together contain every keyword, ignoring
+// case. Commands whose name or an alias contains a keyword come first; each group
+// is sorted by command path.
+func searchCommands(rootCmd *cobra.Command, keywords []string) []*cobra.Command {
+	var byName, byText []*cobra.Command
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if !sub.IsAvailableCommand() {
+				continue
+			}
+			names := strings.ToLower(strings.Join(append([]string{sub.Name()}, sub.Aliases...), " "))
+			text := names + " " + strings.ToLower(sub.Short+" "+sub.Long)
+
+			matchesAll, matchesName := true, false
+			for _, keyword := range keywords {
+				keyword = strings.ToLower(keyword)
+				matchesAll = matchesAll && strings.Contains(text, keyword)
+				matchesName = matchesName || strings.Contains(names, keyword)
+			}
+			switch {
+			case matchesAll && matchesName:
+				byName = append(byName, sub)
+			case matchesAll:
+				byText = append(byText, sub)
+			}
+			walk(sub)
+		}
+	}
+	walk(rootCmd)
+
+	byPath := func(a, b *cobra.Command) int { return strings.Compare(a.CommandPath(), b.CommandPath()) }
+	slices.SortFunc(byName, byPath)
+	slices.SortFunc(byText, byPath)
+	return append(byName, byText...)
+}
@@ -0,0 +1,86 @@
+package cobrayaml
+
+import "testing"
+
+const searchToolYAML = `
+name: search-test
+search_command: true
+root:
+  use: search-test
+  short: Search test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy the application
+    run_func: runNoop
+    aliases: [ship]
+  db migrate:
+    use: migrate
+    short: Run migrations
+    long: Applies pending schema changes before a deploy.
+    run_func: runNoop
+  secret:
+    use: secret
+    short: Deploy secrets
+    run_func: runNoop
+    hidden: true
+`
+
+func TestCommandBuilder_SearchCommand(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	tool := testTool{yaml: searchToolYAML, funcs: map[string]any{"runNoop": noopRun}}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "name before description",
+			args: []string{"search", "DEPLOY"},
+			want: "search-test deploy      Deploy the application\nsearch-test db migrate  Run migrations\n",
+		},
+		{
+			name: "alias",
+			args: []string{"search", "ship"},
+			want: "search-test deploy  Deploy the application\n",
+		},
+		{
+			name: "every keyword",
+			args: []string{"search", "schema", "migrat"},
+			want: "search-test db migrate  Run migrations\n",
+		},
+		{
+			name: "no match",
+			args: []string{"search", "rollback"},
+			want: "No commands match \"rollback\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tool.mustRun(t, tt.args...); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_SearchCommandDisabled(t *testing.T) {
+	yamlContent := `
+name: search-test
+root:
+  use: search-test
+  short: Search test
+commands:
+  deploy:
+    use: deploy
+    short: Deploy the application
+    run_func: runNoop
+`
+	rootCmd := testTool{yaml: yamlContent, funcs: map[string]any{"runNoop": noopRun}}.build(t)
+	if findSubcommand(rootCmd, "search") != nil {
+		t.Error("search should only be added with search_command")
+	}
+}
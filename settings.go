@@ -0,0 +1,413 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// SupportedSettingTypes lists the types a setting in settings_schema can have.
+var SupportedSettingTypes = []string{
+	FlagTypeString,
+	FlagTypeBool,
+	FlagTypeInt,
+	FlagTypeStringSlice,
+	FlagTypeDuration,
+}
+
+// parseSettingDefault converts the string default of a setting to its typed value.
+// Durations are kept in their string form so they can be written back to YAML.
+func parseSettingDefault(setting SettingConfig) (any, error) {
+	value, err := parseSettingValue(setting.Type, setting.Default)
+	if err != nil {
+		return nil, err
+	}
+	if setting.Type == FlagTypeDuration {
+		return setting.Default, nil
+	}
+	return value, nil
+}
+
+// parseSettingValue parses a string (from a default, an environment variable or
+// a flag) into the Go type of a setting.
+func parseSettingValue(settingType, s string) (any, error) {
+	switch settingType {
+	case FlagTypeBool:
+		return strconv.ParseBool(s)
+	case FlagTypeInt:
+		return strconv.Atoi(s)
+	case FlagTypeStringSlice:
+		values := []string{}
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+	case FlagTypeDuration:
+		return time.ParseDuration(s)
+	default:
+		return s, nil
+	}
+}
+
+// checkSettingValue reports why a decoded config value does not match the setting type.
+func checkSettingValue(setting SettingConfig, value any) error {
+	switch setting.Type {
+	case FlagTypeBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected bool, got %s", jsonType(value))
+		}
+	case FlagTypeInt:
+		if jsonType(value) != "integer" {
+			return fmt.Errorf("expected int, got %s", jsonType(value))
+		}
+	case FlagTypeStringSlice:
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("expected list of strings, got %s", jsonType(value))
+		}
+		for i, item := range items {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("item %d: expected string, got %s", i, jsonType(item))
+			}
+		}
+	case FlagTypeDuration:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected duration, got %s", jsonType(value))
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+	default:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected string, got %s", jsonType(value))
+		}
+	}
+	return nil
+}
+
+// lookupSetting returns the value at a dotted key in a decoded config document.
+func lookupSetting(doc map[string]any, key string) (any, bool) {
+	parts := strings.Split(key, ".")
+	var current any = doc
+	for _, part := range parts {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// settingKeys returns the dotted keys of every leaf value in a decoded config document.
+func settingKeys(doc map[string]any, prefix string) []string {
+	var keys []string
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			keys = append(keys, settingKeys(nested, key)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// decodeSettingsDocument decodes a config file into a mapping of settings.
+func decodeSettingsDocument(data []byte) (map[string]any, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if raw == nil {
+		return map[string]any{}, nil
+	}
+	doc, ok := normalizeYAML(raw).(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("config file must be a mapping of settings")
+	}
+	return doc, nil
+}
+
+// validateSettings checks a config file against the settings schema.
+// It reports unknown keys, values of the wrong type and missing required settings.
+func validateSettings(settings []SettingConfig, data []byte) error {
+	doc, err := decodeSettingsDocument(data)
+	if err != nil {
+		return err
+	}
+
+	ve := &ValidationError{}
+	checkSettingsDocument(settings, doc, ve)
+	checkRequiredSettings(settings, []map[string]any{doc}, ve)
+	if ve.hasErrors() {
+		return ve
+	}
+	return nil
+}
+
+// checkSettingsDocument reports unknown keys and values of the wrong type in a config document.
+func checkSettingsDocument(settings []SettingConfig, doc map[string]any, ve *ValidationError) {
+	known := make(map[string]SettingConfig, len(settings))
+	for _, s := range settings {
+		known[s.Key] = s
+	}
+	for _, key := range settingKeys(doc, "") {
+		if _, ok := known[key]; ok {
+			continue
+		}
+		// A parent mapping whose settings are all commented out decodes as null.
+		if value, _ := lookupSetting(doc, key); value == nil && slices.ContainsFunc(settings, func(s SettingConfig) bool {
+			return strings.HasPrefix(s.Key, key+".")
+		}) {
+			continue
+		}
+		ve.addError("setting %q: unknown setting", key)
+	}
+	for _, s := range settings {
+		if value, ok := lookupSetting(doc, s.Key); ok && value != nil {
+			if err := checkSettingValue(s, value); err != nil {
+				ve.addError("setting %q: %v", s.Key, err)
+			}
+		}
+	}
+}
+
+// checkRequiredSettings reports required settings missing from every config document.
+func checkRequiredSettings(settings []SettingConfig, docs []map[string]any, ve *ValidationError) {
+	for _, s := range settings {
+		if !s.Required {
+			continue
+		}
+		found := slices.ContainsFunc(docs, func(doc map[string]any) bool {
+			value, ok := lookupSetting(doc, s.Key)
+			return ok && value != nil
+		})
+		if !found {
+			ve.addError("setting %q: required setting is missing", s.Key)
+		}
+	}
+}
+
+// renderDefaultSettings renders a commented config file holding the default
+// value of every setting. Settings without a default are written commented out.
+func renderDefaultSettings(config *ToolConfig) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Configuration for %s\n", config.Name)
+	fmt.Fprintf(&sb, "# Generated by `%s config init`.\n", extractCommandName(config.Root.Use))
+
+	var written []string
+	for _, s := range config.SettingsSchema {
+		parts := strings.Split(s.Key, ".")
+		sb.WriteString("\n")
+
+		// Open parent mappings that were not written by a previous setting.
+		for depth := range parts[:len(parts)-1] {
+			parent := strings.Join(parts[:depth+1], ".")
+			if !slices.Contains(written, parent) {
+				fmt.Fprintf(&sb, "%s%s:\n", strings.Repeat("  ", depth), parts[depth])
+				written = append(written, parent)
+			}
+		}
+
+		indent := strings.Repeat("  ", len(parts)-1)
+		if s.Description != "" {
+			fmt.Fprintf(&sb, "%s# %s\n", indent, s.Description)
+		}
+		typeNote := s.Type
+		if s.Required {
+			typeNote += ", required"
+		}
+		fmt.Fprintf(&sb, "%s# Type: %s\n", indent, typeNote)
+
+		name := parts[len(parts)-1]
+		if s.Default == "" && s.Type != FlagTypeString {
+			fmt.Fprintf(&sb, "%s# %s:\n", indent, name)
+			continue
+		}
+		value, _ := parseSettingDefault(s)
+		fmt.Fprintf(&sb, "%s%s: %s\n", indent, name, yamlScalar(value))
+	}
+	return sb.String()
+}
+
+// yamlScalar formats a default value as an inline YAML value.
+func yamlScalar(value any) string {
+	if values, ok := value.([]string); ok {
+		items := make([]string, len(values))
+		for i, v := range values {
+			items[i] = yamlScalar(v)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// addConfigCommands adds "config init", "config validate" and "config where" to the root command
+// when the tool declares a settings schema. An existing "config" command declared
+// in commands.yaml is reused, and subcommands it already defines are kept.
+func (cb *CommandBuilder) addConfigCommands(rootCmd *cobra.Command) {
+	if len(cb.config.SettingsSchema) == 0 {
+		return
+	}
+
+	configCmd := findSubcommand(rootCmd, "config")
+	if configCmd == nil {
+		configCmd = &cobra.Command{
+			Use:   "config",
+			Short: "Manage the configuration file",
+		}
+		rootCmd.AddCommand(configCmd)
+	}
+
+	if findSubcommand(configCmd, "init") == nil {
+		configCmd.AddCommand(cb.buildConfigInitCommand())
+	}
+	if findSubcommand(configCmd, "validate") == nil {
+		configCmd.AddCommand(cb.buildConfigValidateCommand())
+	}
+	if findSubcommand(configCmd, "where") == nil {
+		configCmd.AddCommand(cb.buildConfigWhereCommand())
+	}
+}
+
+// findSubcommand returns the direct subcommand of cmd with the given name, if any.
+func findSubcommand(cmd *cobra.Command, name string) *cobra.Command {
+	for _, sub := range cmd.Commands() {
+		if sub.Name() == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// buildConfigInitCommand builds the "config init" command that writes the default config file.
+func (cb *CommandBuilder) buildConfigInitCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Write a config file with the default settings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := cb.ConfigFilePath()
+			if err != nil {
+				return fmt.Errorf("failed to resolve config file: %w", err)
+			}
+			force, _ := cmd.Flags().GetBool("force")
+			if _, err := os.Stat(path); err == nil && !force {
+				return fmt.Errorf("config file %s already exists (use --force to overwrite)", path)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("failed to create config directory: %w", err)
+			}
+			if err := os.WriteFile(path, []byte(renderDefaultSettings(cb.config)), 0644); err != nil {
+				return fmt.Errorf("failed to write config file: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote config file to %s\n", path)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("force", false, "Overwrite an existing config file")
+	return cmd
+}
+
+// buildConfigValidateCommand builds the "config validate" command that checks a
+// config file, or every existing file of the config cascade when no path is given.
+func (cb *CommandBuilder) buildConfigValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate [path]",
+		Short: "Check config files against the settings schema",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				data, err := os.ReadFile(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to read config file: %w", err)
+				}
+				if err := validateSettings(cb.config.SettingsSchema, data); err != nil {
+					return fmt.Errorf("%s: %w", args[0], err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", args[0])
+				return nil
+			}
+
+			layers, err := cb.readConfigLayers()
+			if err != nil {
+				return err
+			}
+			var docs []map[string]any
+			for _, layer := range layers {
+				if !layer.exists {
+					continue
+				}
+				ve := &ValidationError{}
+				if checkSettingsDocument(cb.config.SettingsSchema, layer.doc, ve); ve.hasErrors() {
+					return fmt.Errorf("%s: %w", layer.path, ve)
+				}
+				docs = append(docs, layer.doc)
+			}
+			if len(docs) == 0 {
+				return cb.checkConfigFile(cmd)
+			}
+
+			ve := &ValidationError{}
+			if checkRequiredSettings(cb.config.SettingsSchema, docs, ve); ve.hasErrors() {
+				return ve
+			}
+			for _, layer := range layers {
+				if layer.exists {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", layer.path)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// buildConfigWhereCommand builds the "config where" command that lists the
+// config cascade in precedence order.
+func (cb *CommandBuilder) buildConfigWhereCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "where",
+		Short: "Show the config files that are read, in precedence order",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := cb.ConfigFilePaths()
+			if err != nil {
+				return fmt.Errorf("failed to resolve config file: %w", err)
+			}
+
+			w := cmd.OutOrStdout()
+			for i, path := range paths {
+				status := "not found"
+				if info, err := os.Stat(path); err == nil && !info.IsDir() {
+					status = "loaded"
+				}
+				fmt.Fprintf(w, "%d. %s (%s)\n", i+1, path, status)
+			}
+			if len(paths) > 1 {
+				fmt.Fprintln(w, "Settings in later files override earlier ones.")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,226 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// SettingSource tells where the effective value of a setting came from.
+type SettingSource string
+
+// Setting sources, from highest to lowest precedence.
+const (
+	SettingSourceFlag    SettingSource = "flag"
+	SettingSourceEnv     SettingSource = "env"
+	SettingSourceConfig  SettingSource = "config"
+	SettingSourceDefault SettingSource = "default"
+)
+
+// debugSettingsFlag is the persistent flag that prints where each setting came from.
+const debugSettingsFlag = "debug-settings"
+
+// resolvedSetting is the effective value of a setting and its origin.
+type resolvedSetting struct {
+	value  any
+	source SettingSource
+	origin string // flag, variable or file the value was read from
+}
+
+// settingsKey is the context key under which resolved settings are stored.
+type settingsKey struct{}
+
+// GetSetting returns the effective value of a setting declared in settings_schema.
+// The value has the Go type of the setting: string, bool, int, []string or time.Duration.
+// The second return value reports whether the setting has a value from any source.
+func GetSetting(cmd *cobra.Command, key string) (any, bool) {
+	setting, ok := lookupResolvedSetting(cmd, key)
+	return setting.value, ok
+}
+
+// GetSettingSource returns where the effective value of a setting came from.
+func GetSettingSource(cmd *cobra.Command, key string) (SettingSource, bool) {
+	setting, ok := lookupResolvedSetting(cmd, key)
+	return setting.source, ok
+}
+
+// lookupResolvedSetting returns a resolved setting stored in the command's context.
+func lookupResolvedSetting(cmd *cobra.Command, key string) (resolvedSetting, bool) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		return resolvedSetting{}, false
+	}
+	settings, ok := ctx.Value(settingsKey{}).(map[string]resolvedSetting)
+	if !ok {
+		return resolvedSetting{}, false
+	}
+	setting, ok := settings[key]
+	return setting, ok
+}
+
+// resolveSettings computes the effective value of every setting with the precedence
+// CLI flag > environment variable > config file > default, and stores the result
+// in the command's context. A flag only counts when it was set on the command line.
+func (cb *CommandBuilder) resolveSettings(cmd *cobra.Command) error {
+	if len(cb.config.SettingsSchema) == 0 {
+		return nil
+	}
+
+	layers, err := cb.readConfigLayers()
+	if err != nil {
+		return err
+	}
+
+	resolved := make(map[string]resolvedSetting, len(cb.config.SettingsSchema))
+	for _, s := range cb.config.SettingsSchema {
+		setting, ok, err := resolveSetting(cmd.Flags(), s, layers)
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", s.Key, err)
+		}
+		if ok {
+			resolved[s.Key] = setting
+		}
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd.SetContext(context.WithValue(ctx, settingsKey{}, resolved))
+
+	if debug, _ := cmd.Flags().GetBool(debugSettingsFlag); debug {
+		printSettings(cmd, cb.config.SettingsSchema, resolved)
+	}
+	return nil
+}
+
+// resolveSetting returns the effective value of a single setting.
+// Config layers are searched from highest to lowest precedence.
+func resolveSetting(fs *pflag.FlagSet, s SettingConfig, layers []configLayer) (resolvedSetting, bool, error) {
+	if s.Flag != "" {
+		if flag := fs.Lookup(s.Flag); flag != nil && flag.Changed {
+			value, err := settingFromFlag(s, flag)
+			return resolvedSetting{value, SettingSourceFlag, "--" + s.Flag}, true, err
+		}
+	}
+
+	if s.Env != "" {
+		if env, ok := os.LookupEnv(s.Env); ok {
+			value, err := parseSettingValue(s.Type, env)
+			if err != nil {
+				return resolvedSetting{}, false, fmt.Errorf("invalid value %q in $%s", env, s.Env)
+			}
+			return resolvedSetting{value, SettingSourceEnv, "$" + s.Env}, true, nil
+		}
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		value, ok := lookupSetting(layers[i].doc, s.Key)
+		if !ok || value == nil {
+			continue
+		}
+		if err := checkSettingValue(s, value); err != nil {
+			return resolvedSetting{}, false, fmt.Errorf("%s: %w", layers[i].path, err)
+		}
+		return resolvedSetting{settingFromConfig(s, value), SettingSourceConfig, layers[i].path}, true, nil
+	}
+
+	if s.Default != "" {
+		value, err := parseSettingValue(s.Type, s.Default)
+		return resolvedSetting{value, SettingSourceDefault, ""}, true, err
+	}
+	return resolvedSetting{}, false, nil
+}
+
+// settingFromFlag converts the value of a flag to the Go type of a setting.
+func settingFromFlag(s SettingConfig, flag *pflag.Flag) (any, error) {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		if s.Type == FlagTypeStringSlice {
+			return slice.GetSlice(), nil
+		}
+		return parseSettingValue(s.Type, strings.Join(slice.GetSlice(), ","))
+	}
+	value, err := parseSettingValue(s.Type, flag.Value.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q in --%s", flag.Value.String(), flag.Name)
+	}
+	return value, nil
+}
+
+// settingFromConfig converts a decoded config file value, already checked with
+// checkSettingValue, to the Go type of a setting.
+func settingFromConfig(s SettingConfig, value any) any {
+	switch s.Type {
+	case FlagTypeInt:
+		n, _ := toFloat(value)
+		return int(n)
+	case FlagTypeStringSlice:
+		items := value.([]any)
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = item.(string)
+		}
+		return values
+	case FlagTypeDuration:
+		d, _ := time.ParseDuration(value.(string))
+		return d
+	default:
+		return value
+	}
+}
+
+// configLayer is a decoded file of the config cascade.
+type configLayer struct {
+	path   string
+	doc    map[string]any
+	exists bool
+}
+
+// readConfigLayers reads and decodes every file of the config cascade, from
+// lowest to highest precedence. Missing files yield empty layers.
+func (cb *CommandBuilder) readConfigLayers() ([]configLayer, error) {
+	paths, err := cb.ConfigFilePaths()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config file: %w", err)
+	}
+
+	layers := make([]configLayer, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			layers = append(layers, configLayer{path: path, doc: map[string]any{}})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+
+		doc, err := decodeSettingsDocument(data)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+		layers = append(layers, configLayer{path: path, doc: doc, exists: true})
+	}
+	return layers, nil
+}
+
+// printSettings writes the effective value and source of every setting to stderr.
+func printSettings(cmd *cobra.Command, settings []SettingConfig, resolved map[string]resolvedSetting) {
+	w := cmd.ErrOrStderr()
+	for _, s := range settings {
+		setting, ok := resolved[s.Key]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "%s: (unset)\n", s.Key)
+		case setting.origin != "":
+			fmt.Fprintf(w, "%s = %v (%s %s)\n", s.Key, setting.value, setting.source, setting.origin)
+		default:
+			fmt.Fprintf(w, "%s = %v (%s)\n", s.Key, setting.value, setting.source)
+		}
+	}
+}
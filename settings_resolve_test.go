@@ -0,0 +1,249 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const precedenceYAML = `
+name: precedence-test
+config_file: %s
+root:
+  use: precedence-test
+  short: Precedence test
+commands:
+  serve:
+    use: serve
+    short: Serve
+    run_func: runServe
+    flags:
+      - name: port
+        type: int
+        usage: Port
+      - name: tags
+        type: stringSlice
+        usage: Tags
+settings_schema:
+  - key: server.port
+    type: int
+    default: "8080"
+    flag: port
+    env: PRECEDENCE_TEST_PORT
+  - key: tags
+    type: stringSlice
+    flag: tags
+  - key: timeout
+    type: duration
+    default: 30s
+    env: PRECEDENCE_TEST_TIMEOUT
+  - key: token
+    type: string
+`
+
+// runPrecedence builds the precedence test CLI, runs serve with args and returns
+// the resolved settings with their sources.
+func runPrecedence(t *testing.T, configPath string, args ...string) (map[string]any, map[string]SettingSource, string) {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(strings.Replace(precedenceYAML, "%s", configPath, 1))
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	values := map[string]any{}
+	sources := map[string]SettingSource{}
+	cb.RegisterFunction("runServe", func(cmd *cobra.Command, args []string) error {
+		for _, key := range []string{"server.port", "tags", "timeout", "token"} {
+			if value, ok := GetSetting(cmd, key); ok {
+				values[key] = value
+			}
+			if source, ok := GetSettingSource(cmd, key); ok {
+				sources[key] = source
+			}
+		}
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	var stderr bytes.Buffer
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs(append([]string{"serve"}, args...))
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	return values, sources, stderr.String()
+}
+
+func TestResolveSettings_Precedence(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	// Defaults only: no config file, no env, no flags.
+	values, sources, _ := runPrecedence(t, configPath)
+	if values["server.port"] != 8080 || sources["server.port"] != SettingSourceDefault {
+		t.Errorf("server.port = %v (%s), want 8080 (default)", values["server.port"], sources["server.port"])
+	}
+	if values["timeout"] != 30*time.Second {
+		t.Errorf("timeout = %v, want 30s", values["timeout"])
+	}
+	if _, ok := values["token"]; ok {
+		t.Error("token without a value from any source should be unset")
+	}
+
+	// Config file overrides defaults.
+	config := "server:\n  port: 9000\ntags: [a, b]\ntimeout: 1m\ntoken: abc\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	values, sources, _ = runPrecedence(t, configPath)
+	if values["server.port"] != 9000 || sources["server.port"] != SettingSourceConfig {
+		t.Errorf("server.port = %v (%s), want 9000 (config)", values["server.port"], sources["server.port"])
+	}
+	if !reflect.DeepEqual(values["tags"], []string{"a", "b"}) {
+		t.Errorf("tags = %#v, want [a b]", values["tags"])
+	}
+	if values["timeout"] != time.Minute || values["token"] != "abc" {
+		t.Errorf("timeout = %v, token = %v, want 1m0s and abc", values["timeout"], values["token"])
+	}
+
+	// Environment overrides the config file.
+	t.Setenv("PRECEDENCE_TEST_PORT", "9100")
+	values, sources, _ = runPrecedence(t, configPath)
+	if values["server.port"] != 9100 || sources["server.port"] != SettingSourceEnv {
+		t.Errorf("server.port = %v (%s), want 9100 (env)", values["server.port"], sources["server.port"])
+	}
+
+	// A flag set on the command line overrides everything.
+	values, sources, _ = runPrecedence(t, configPath, "--port", "9200", "--tags", "x,y")
+	if values["server.port"] != 9200 || sources["server.port"] != SettingSourceFlag {
+		t.Errorf("server.port = %v (%s), want 9200 (flag)", values["server.port"], sources["server.port"])
+	}
+	if !reflect.DeepEqual(values["tags"], []string{"x", "y"}) || sources["tags"] != SettingSourceFlag {
+		t.Errorf("tags = %#v (%s), want [x y] (flag)", values["tags"], sources["tags"])
+	}
+}
+
+func TestResolveSettings_DebugFlag(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("tags: [a]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PRECEDENCE_TEST_TIMEOUT", "5s")
+
+	_, _, stderr := runPrecedence(t, configPath, "--port", "1234", "--debug-settings")
+	for _, want := range []string{
+		"server.port = 1234 (flag --port)",
+		"tags = [a] (config " + configPath + ")",
+		"timeout = 5s (env $PRECEDENCE_TEST_TIMEOUT)",
+		"token: (unset)",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("debug output should contain %q, got:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestResolveSettings_InvalidValues(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	cb, err := NewCommandBuilderFromString(strings.Replace(precedenceYAML, "%s", configPath, 1))
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runServe", func(cmd *cobra.Command, args []string) error {
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+
+	if err := os.WriteFile(configPath, []byte("server:\n  port: high\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"serve"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "setting server.port") {
+		t.Errorf("Execute() error = %v, want invalid config value error", err)
+	}
+
+	if err := os.Remove(configPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PRECEDENCE_TEST_TIMEOUT", "soon")
+	rootCmd.SetArgs([]string{"serve"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "$PRECEDENCE_TEST_TIMEOUT") {
+		t.Errorf("Execute() error = %v, want invalid env value error", err)
+	}
+}
+
+func TestGetSetting_NoContext(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	if _, ok := GetSetting(cmd, "anything"); ok {
+		t.Error("GetSetting() should report missing value without a context")
+	}
+	if _, ok := GetSettingSource(cmd, "anything"); ok {
+		t.Error("GetSettingSource() should report missing source without a context")
+	}
+}
+
+func TestResolveSettings_Cascade(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.yaml")
+	user := filepath.Join(dir, "user.yaml")
+
+	cb, err := NewCommandBuilderFromString(`
+name: cascade-test
+config_file: ` + user + `
+config_files: [` + system + `, ` + user + `]
+root:
+  use: cascade-test
+  short: Cascade test
+  run_func: runRoot
+settings_schema:
+  - key: region
+    type: string
+  - key: retries
+    type: int
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	if err := os.WriteFile(system, []byte("region: us\nretries: 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(user, []byte("region: eu\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stderr bytes.Buffer
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		return nil
+	})
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SetErr(&stderr)
+	rootCmd.SetArgs([]string{"--debug-settings"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"region = eu (config " + user + ")",
+		"retries = 3 (config " + system + ")",
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("debug output should contain %q, got:\n%s", want, stderr.String())
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const settingsYAML = `
+name: settings-test
+config_file: %s
+root:
+  use: settings-test
+  short: Settings test
+settings_schema:
+  - key: server.host
+    type: string
+    default: localhost
+    description: Host the server binds to
+  - key: server.port
+    type: int
+    default: "8080"
+    description: Port the server listens on
+  - key: timeout
+    type: duration
+    default: 30s
+  - key: tags
+    type: stringSlice
+    default: a,b
+  - key: token
+    type: string
+    required: true
+  - key: debug
+    type: bool
+`
+
+func newSettingsBuilder(t *testing.T, configPath string) *CommandBuilder {
+	t.Helper()
+	cb, err := NewCommandBuilderFromString(strings.Replace(settingsYAML, "%s", configPath, 1))
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	return cb
+}
+
+func TestRenderDefaultSettings(t *testing.T) {
+	cb := newSettingsBuilder(t, "config.yaml")
+	got := renderDefaultSettings(cb.config)
+
+	for _, want := range []string{
+		"# Generated by `settings-test config init`.",
+		"server:\n  # Host the server binds to\n  # Type: string\n  host: localhost\n",
+		"\n  # Port the server listens on\n  # Type: int\n  port: 8080\n",
+		"timeout: 30s\n",
+		"tags: [a, b]\n",
+		"# Type: string, required\ntoken: \"\"\n",
+		"# Type: bool\n# debug:\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("renderDefaultSettings() should contain %q, got:\n%s", want, got)
+		}
+	}
+
+	if err := validateSettings(cb.config.SettingsSchema, []byte(got)); err != nil {
+		t.Errorf("validateSettings() on default config error = %v", err)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	cb := newSettingsBuilder(t, "config.yaml")
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr []string
+	}{
+		{
+			name:    "valid",
+			content: "token: abc\nserver:\n  port: 9000\ntags: [x]\ntimeout: 1m\n",
+		},
+		{
+			name:    "missing required",
+			content: "server:\n  port: 9000\n",
+			wantErr: []string{`setting "token": required setting is missing`},
+		},
+		{
+			name:    "wrong types",
+			content: "token: abc\nserver:\n  port: high\ndebug: yes-please\ntags: [1]\ntimeout: soon\n",
+			wantErr: []string{
+				`setting "server.port": expected int, got string`,
+				`setting "debug": expected bool, got string`,
+				`setting "tags": item 0: expected string, got integer`,
+				`setting "timeout": invalid duration "soon"`,
+			},
+		},
+		{
+			name:    "unknown key",
+			content: "token: abc\nserver:\n  address: 0.0.0.0\n",
+			wantErr: []string{`setting "server.address": unknown setting`},
+		},
+		{
+			name:    "commented out parent",
+			content: "token: abc\nserver:\n",
+		},
+		{
+			name:    "not a mapping",
+			content: "- a\n- b\n",
+			wantErr: []string{"must be a mapping"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSettings(cb.config.SettingsSchema, []byte(tt.content))
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("validateSettings() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("validateSettings() expected error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("validateSettings() error should contain %q, got: %s", want, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_ConfigCommands(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "nested", "config.yaml")
+	cb := newSettingsBuilder(t, configPath)
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+
+	rootCmd.SetArgs([]string{"config", "init"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config init error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Wrote config file to "+configPath) {
+		t.Errorf("config init output = %q", out.String())
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		t.Fatalf("config file not written: %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"config", "init"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("config init error = %v, want already exists error", err)
+	}
+
+	if err := os.WriteFile(configPath, []byte("token: abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	rootCmd.SetArgs([]string{"config", "validate"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config validate error = %v", err)
+	}
+	if !strings.Contains(out.String(), configPath+" is valid") {
+		t.Errorf("config validate output = %q", out.String())
+	}
+
+	badPath := filepath.Join(t.TempDir(), "bad.yaml")
+	if err := os.WriteFile(badPath, []byte("port: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"config", "validate", badPath})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), `setting "port": unknown setting`) {
+		t.Errorf("config validate error = %v, want unknown setting error", err)
+	}
+}
+
+func TestCommandBuilder_ConfigCommandsKeepExisting(t *testing.T) {
+	cb, err := NewCommandBuilderFromString(`
+name: settings-test
+config_file: config.yaml
+root:
+  use: settings-test
+  short: Settings test
+commands:
+  config:
+    use: config
+    short: Custom config command
+    commands:
+      init:
+        use: init
+        short: Custom init
+        run_func: runInit
+settings_schema:
+  - key: name
+    type: string
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runInit", func(cmd *cobra.Command, args []string) error {
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	configCmd := findSubcommand(rootCmd, "config")
+	if configCmd == nil || configCmd.Short != "Custom config command" {
+		t.Fatal("declared config command should be reused")
+	}
+	if initCmd := findSubcommand(configCmd, "init"); initCmd == nil || initCmd.Short != "Custom init" {
+		t.Error("declared config init command should be kept")
+	}
+	if findSubcommand(configCmd, "validate") == nil {
+		t.Error("config validate should be added to the declared config command")
+	}
+}
+
+func TestValidateConfig_SettingsSchema(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		SettingsSchema: []SettingConfig{
+			{Key: "server", Type: FlagTypeString},
+			{Key: "server.port", Type: FlagTypeInt, Default: "high"},
+			{Key: "server.port", Type: FlagTypeInt},
+			{Key: "ratio", Type: "float"},
+			{Key: "a..b", Type: FlagTypeString},
+			{Type: FlagTypeString},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected errors")
+	}
+	for _, want := range []string{
+		"settings_schema is set but the tool has no config_file",
+		`setting "server.port": invalid int default value "high"`,
+		`setting "server.port": duplicate key`,
+		`setting "ratio": unsupported type "float"`,
+		`setting "a..b": key must not have empty segments`,
+		`setting "server": conflicts with nested setting "server.port"`,
+		"settings_schema: key is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %s", want, err.Error())
+		}
+	}
+}
+
+func TestValidateConfig_SettingsSchemaConflictOrder(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		SettingsSchema: []SettingConfig{
+			{Key: "server.tls", Type: FlagTypeBool},
+			{Key: "db.host", Type: FlagTypeString},
+			{Key: "server", Type: FlagTypeString},
+			{Key: "server.port", Type: FlagTypeInt},
+			{Key: "db", Type: FlagTypeString},
+		},
+	}
+
+	want := strings.Join([]string{
+		`setting "db": conflicts with nested setting "db.host"`,
+		`setting "server": conflicts with nested setting "server.port"`,
+		`setting "server": conflicts with nested setting "server.tls"`,
+	}, "\n  - ")
+	for i := 0; i < 5; i++ {
+		err := ValidateConfig(config)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("ValidateConfig() error = %v, want conflicts in key order:\n%s", err, want)
+		}
+	}
+}
+
+func TestCommandBuilder_ConfigCascade(t *testing.T) {
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.yaml")
+	user := filepath.Join(dir, "user.yaml")
+	project := filepath.Join(dir, "project.yaml")
+
+	cb, err := NewCommandBuilderFromString(`
+name: cascade-test
+config_file: ` + user + `
+config_files:
+  - ` + system + `
+  - ` + user + `
+  - ` + project + `
+root:
+  use: cascade-test
+  short: Cascade test
+settings_schema:
+  - key: region
+    type: string
+  - key: token
+    type: string
+    required: true
+`)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	if err := os.WriteFile(system, []byte("region: us\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(project, []byte("token: abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+
+	rootCmd.SetArgs([]string{"config", "where"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config where error = %v", err)
+	}
+	want := "1. " + system + " (loaded)\n2. " + user + " (not found)\n3. " + project + " (loaded)\nSettings in later files override earlier ones.\n"
+	if out.String() != want {
+		t.Errorf("config where output = %q, want %q", out.String(), want)
+	}
+
+	// The required token is only set in the project file, which is enough.
+	out.Reset()
+	rootCmd.SetArgs([]string{"config", "validate"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("config validate error = %v", err)
+	}
+	if !strings.Contains(out.String(), system+" is valid") || !strings.Contains(out.String(), project+" is valid") {
+		t.Errorf("config validate output = %q", out.String())
+	}
+
+	if err := os.WriteFile(user, []byte("unknown: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rootCmd.SetArgs([]string{"config", "validate"})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), user) || !strings.Contains(err.Error(), `"unknown": unknown setting`) {
+		t.Errorf("config validate error = %v, want unknown setting in %s", err, user)
+	}
+}
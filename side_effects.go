@@ -0,0 +1,93 @@
+package cobrayaml
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Side effects classifications of a command.
+const (
+	// SideEffectsReadOnly is a command that only reads state.
+	SideEffectsReadOnly = "read-only"
+	// SideEffectsMutating is a command that changes state in a way that can be undone or repeated safely.
+	SideEffectsMutating = "mutating"
+	// SideEffectsDestructive is a command that deletes or overwrites state; it asks for confirmation.
+	SideEffectsDestructive = "destructive"
+)
+
+// SupportedSideEffects lists all side effects classifications.
+var SupportedSideEffects = []string{
+	SideEffectsReadOnly,
+	SideEffectsMutating,
+	SideEffectsDestructive,
+}
+
+// yesFlag is the flag that confirms a destructive command without asking.
+const yesFlag = "yes"
+
+// yesFlagConfig is the flag added to destructive commands.
+var yesFlagConfig = FlagConfig{
+	Name:  yesFlag,
+	Type:  FlagTypeBool,
+	Usage: "Run this destructive command without asking for confirmation",
+}
+
+// validateSideEffects validates the side_effects of a command.
+func validateSideEffects(config *CommandConfig, path string, ve *ValidationError) {
+	if config.SideEffects == "" {
+		return
+	}
+	if !slices.Contains(SupportedSideEffects, config.SideEffects) {
+		ve.addError("command %q: invalid side_effects %q (must be one of: %s)", path, config.SideEffects, strings.Join(SupportedSideEffects, ", "))
+	}
+	if config.SideEffects == SideEffectsDestructive && slices.ContainsFunc(config.Flags, func(f FlagConfig) bool { return f.Name == yesFlag }) {
+		ve.addError("command %q, flag %q: reserved for confirming destructive commands", path, yesFlag)
+	}
+}
+
+// sideEffectsAnnotation is the command annotation key holding the side effects
+// classification, for machine-readable help.
+const sideEffectsAnnotation = "cobrayaml_side_effects"
+
+// addSideEffects records the side effects classification of cmd and adds the
+// --yes flag to a destructive command.
+func (cb *CommandBuilder) addSideEffects(cmd *cobra.Command, sideEffects string) error {
+	if sideEffects == "" {
+		return nil
+	}
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[sideEffectsAnnotation] = sideEffects
+	if sideEffects != SideEffectsDestructive || cmd.Flags().Lookup(yesFlag) != nil {
+		return nil
+	}
+	return cb.addFlags(cmd, []FlagConfig{yesFlagConfig})
+}
+
+// confirmDestructive asks the user to confirm running a destructive command,
+// unless --yes is set. Without a terminal to answer, it fails and points at --yes.
+func confirmDestructive(cmd *cobra.Command, sideEffects string) error {
+	if sideEffects != SideEffectsDestructive {
+		return nil
+	}
+	if yes, _ := cmd.Flags().GetBool(yesFlag); yes {
+		return nil
+	}
+
+	key := cmd.CommandPath()
+	ok, err := confirm(cmd, key+" is destructive. Continue?")
+	switch {
+	case errors.Is(err, errNoAnswer):
+		return fmt.Errorf("%s is destructive; pass --%s to run it non-interactively", key, yesFlag)
+	case err != nil:
+		return err
+	case !ok:
+		return fmt.Errorf("%s not confirmed", key)
+	}
+	return nil
+}
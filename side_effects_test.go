@@ -0,0 +1,228 @@
+package cobrayaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+const sideEffectsYAML = `
+name: ops
+root:
+  use: ops
+  short: Ops tool
+commands:
+  status:
+    use: status
+    short: Show status
+    run_func: runStatus
+    side_effects: read-only
+  drop:
+    use: drop
+    short: Drop the database
+    run_func: runDrop
+    side_effects: destructive
+  sync:
+    use: sync
+    short: Sync state
+    run_func: runSync
+`
+
+func TestCommandBuilder_ConfirmDestructive(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		wantRun bool
+		wantErr string
+	}{
+		{name: "confirmed", args: []string{"drop"}, stdin: "y\n", wantRun: true},
+		{name: "confirmed with yes", args: []string{"drop"}, stdin: "YES\n", wantRun: true},
+		{name: "declined", args: []string{"drop"}, stdin: "n\n", wantErr: "ops drop not confirmed"},
+		{name: "no answer", args: []string{"drop"}, stdin: "", wantErr: "ops drop is destructive; pass --yes to run it non-interactively"},
+		{name: "yes flag", args: []string{"drop", "--yes"}, wantRun: true},
+		{name: "read-only", args: []string{"status"}, wantRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := NewCommandBuilderFromString(sideEffectsYAML)
+			if err != nil {
+				t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+			}
+			ran := false
+			run := func(cmd *cobra.Command, args []string) error {
+				ran = true
+				return nil
+			}
+			cb.RegisterFunctions(map[string]any{"runStatus": run, "runDrop": run, "runSync": run})
+			var errOut bytes.Buffer
+			cb.SetIn(strings.NewReader(tt.stdin))
+			cb.SetErr(&errOut)
+			rootCmd, err := cb.BuildRootCommand()
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			rootCmd.SetArgs(tt.args)
+			err = rootCmd.Execute()
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Fatalf("Execute() error = %v, want %q", err, tt.wantErr)
+			}
+			if ran != tt.wantRun {
+				t.Errorf("ran = %v, want %v", ran, tt.wantRun)
+			}
+			prompted := strings.Contains(errOut.String(), "ops drop is destructive. Continue? [y/N]: ")
+			if want := tt.args[0] == "drop" && len(tt.args) == 1; prompted != want {
+				t.Errorf("prompted = %v, want %v (stderr %q)", prompted, want, errOut.String())
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_ConfirmDestructive_Pipe(t *testing.T) {
+	// An open pipe that never sends an answer, as stdin in CI
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	rootCmd := testTool{yaml: sideEffectsYAML, funcs: map[string]any{"runStatus": noopRun, "runDrop": noopRun, "runSync": noopRun}}.build(t)
+	var out bytes.Buffer
+	rootCmd.SetIn(r)
+	rootCmd.SetErr(&out)
+	rootCmd.SetArgs([]string{"drop"})
+	done := make(chan error, 1)
+	go func() { done <- rootCmd.Execute() }()
+	select {
+	case err := <-done:
+		if want := "ops drop is destructive; pass --yes to run it non-interactively"; err == nil || err.Error() != want {
+			t.Errorf("Execute() error = %v, want %q", err, want)
+		}
+		if strings.Contains(out.String(), "Continue?") {
+			t.Errorf("prompted without a terminal: %q", out.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the prompt waited for an answer from a pipe")
+	}
+}
+
+func TestCommandBuilder_ConfirmDestructive_InputAfterAnswer(t *testing.T) {
+	var got string
+	testTool{
+		yaml: sideEffectsYAML,
+		funcs: map[string]any{"runStatus": noopRun, "runSync": noopRun, "runDrop": func(cmd *cobra.Command, args []string) error {
+			data, err := io.ReadAll(cmd.InOrStdin())
+			got = string(data)
+			return err
+		}},
+		in: "y\nrows to drop\n",
+	}.mustRun(t, "drop")
+	if got != "rows to drop\n" {
+		t.Errorf("handler read %q, want the input after the answer", got)
+	}
+}
+
+func TestValidateConfig_SideEffects(t *testing.T) {
+	yamlContent := strings.Replace(sideEffectsYAML, "side_effects: read-only", "side_effects: readonly", 1)
+	yamlContent = strings.Replace(yamlContent, "    side_effects: destructive\n", `    side_effects: destructive
+    flags:
+      - name: yes
+        type: bool
+        usage: Skip the prompt
+`, 1)
+	_, err := NewCommandBuilderFromString(yamlContent)
+	if err == nil {
+		t.Fatal("NewCommandBuilderFromString() error = nil, want side_effects errors")
+	}
+	for _, want := range []string{
+		`command "status": invalid side_effects "readonly" (must be one of: read-only, mutating, destructive)`,
+		`command "drop", flag "yes": reserved for confirming destructive commands`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestValidateConfigWithWarnings_SideEffects(t *testing.T) {
+	const unclassified = `command "sync": no side_effects classification`
+	for _, tt := range []struct {
+		name     string
+		yaml     string
+		wantWarn bool
+	}{
+		{name: "classified commands", yaml: sideEffectsYAML},
+		{name: "require_side_effects", yaml: "require_side_effects: true\n" + sideEffectsYAML, wantWarn: true},
+		{name: "no classification", yaml: strings.NewReplacer("    side_effects: read-only\n", "", "    side_effects: destructive\n", "").Replace(sideEffectsYAML)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var config ToolConfig
+			if err := yaml.Unmarshal([]byte(tt.yaml), &config); err != nil {
+				t.Fatalf("yaml.Unmarshal() error = %v", err)
+			}
+			warnings, err := ValidateConfigWithWarnings(&config)
+			if err != nil {
+				t.Fatalf("ValidateConfigWithWarnings() error = %v", err)
+			}
+			warned := strings.Contains(strings.Join(warnings, "\n"), unclassified)
+			if warned != tt.wantWarn {
+				t.Errorf("warnings = %q, want warning %v", warnings, tt.wantWarn)
+			}
+			if strings.Contains(strings.Join(warnings, "\n"), `command "status": no side_effects`) {
+				t.Errorf("warnings = %q, want no warning for a classified command", warnings)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateToolManifest_SideEffects(t *testing.T) {
+	gen, err := NewGeneratorFromString(sideEffectsYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	out, err := gen.GenerateToolManifest(ManifestFormatMCP)
+	if err != nil {
+		t.Fatalf("GenerateToolManifest() error = %v", err)
+	}
+	var manifest struct {
+		Tools []struct {
+			Name        string          `json:"name"`
+			Annotations map[string]bool `json:"annotations"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal([]byte(out), &manifest); err != nil {
+		t.Fatalf("manifest is not JSON: %v\n%s", err, out)
+	}
+	annotations := make(map[string]map[string]bool)
+	for _, tool := range manifest.Tools {
+		annotations[tool.Name] = tool.Annotations
+	}
+	if got := annotations["ops_drop"]; !got["destructiveHint"] || got["readOnlyHint"] {
+		t.Errorf("drop annotations = %v, want destructiveHint", got)
+	}
+	if got := annotations["ops_status"]; !got["readOnlyHint"] || got["destructiveHint"] {
+		t.Errorf("status annotations = %v, want readOnlyHint", got)
+	}
+	if got := annotations["ops_sync"]; got != nil {
+		t.Errorf("sync annotations = %v, want none", got)
+	}
+
+	out, err = gen.GenerateToolManifest(ManifestFormatOpenAI)
+	if err != nil {
+		t.Fatalf("GenerateToolManifest() error = %v", err)
+	}
+	if !strings.Contains(out, "This command is destructive: confirm with the user before calling it.") {
+		t.Errorf("OpenAI manifest does not flag drop as destructive:\n%s", out)
+	}
+}
@@ -0,0 +1,142 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// apiVersionFlag is the root flag that selects the command surface.
+const apiVersionFlag = "api-version"
+
+// surfaceAnnotation records on the root command the surface its commands were
+// built for.
+const surfaceAnnotation = "cobrayaml_surface"
+
+// SurfaceConfig represents a versioned set of commands (see ToolConfig.Surfaces).
+// Only the commands of the selected surface are built, next to the shared
+// top-level commands, so a transitional CLI can expose old and new command
+// shapes from one binary.
+//
+// Example YAML:
+//
+//	default_surface: v1
+//	surface_env: MY_TOOL_API_VERSION
+//	surfaces:
+//	  v1:
+//	    commands:
+//	      get:
+//	        use: get <name>
+//	        run_func: runGetV1
+//	  v2:
+//	    commands:
+//	      get:
+//	        use: get <kind> <name>
+//	        run_func: runGetV2
+type SurfaceConfig struct {
+	Commands map[string]CommandConfig `yaml:"commands"`
+}
+
+// commandLineArgs returns the command line arguments. It is replaced in tests.
+var commandLineArgs = func() []string { return os.Args[1:] }
+
+// selectedSurface returns the name of the surface to build: the value of
+// --api-version in args, the command line the commands will run, else the
+// surface_env environment variable, else default_surface. It returns "" when
+// the config declares no surfaces. The command line is scanned before cobra
+// parses it, since the surface decides which commands exist.
+func (cb *CommandBuilder) selectedSurface(args []string) (string, error) {
+	config := cb.config
+	if len(config.Surfaces) == 0 {
+		return "", nil
+	}
+
+	name, source := config.DefaultSurface, "default_surface"
+	if config.SurfaceEnv != "" {
+		if value := os.Getenv(config.SurfaceEnv); value != "" {
+			name, source = value, "$"+config.SurfaceEnv
+		}
+	}
+	if value, ok := apiVersionArg(args); ok {
+		name, source = value, "--"+apiVersionFlag
+	}
+
+	if _, exists := config.Surfaces[name]; !exists {
+		return "", fmt.Errorf("%s: unknown API version %q (available: %s)", source, name, strings.Join(sortedKeys(config.Surfaces), ", "))
+	}
+	return name, nil
+}
+
+// apiVersionArg returns the value of --api-version in args, which may be given
+// as "--api-version v2" or "--api-version=v2". Arguments after "--" are not flags.
+func apiVersionArg(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+apiVersionFlag+"="); ok {
+			return value, true
+		}
+		if arg == "--"+apiVersionFlag && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// surfaceConfig returns the config with the commands of the surface selected
+// for args added to the shared top-level commands, and the name of that
+// surface. The result declares no surfaces itself, so it validates like a
+// config without them. The config of cb is not modified.
+func (cb *CommandBuilder) surfaceConfig(args []string) (*ToolConfig, string, error) {
+	surface, err := cb.selectedSurface(args)
+	if err != nil || surface == "" {
+		return cb.config, surface, err
+	}
+
+	result := *cb.config
+	result.Commands = make(map[string]CommandConfig, len(cb.config.Commands)+len(cb.config.Surfaces[surface].Commands))
+	maps.Copy(result.Commands, cb.config.Commands)
+	maps.Copy(result.Commands, cb.config.Surfaces[surface].Commands)
+	result.Surfaces, result.DefaultSurface, result.SurfaceEnv = nil, "", ""
+	return &result, surface, nil
+}
+
+// addSurfaceFlag adds --api-version to the root command when surfaces are
+// declared. Its default is the surface the commands were built for, which is
+// recorded on the root for checkSurface.
+func (cb *CommandBuilder) addSurfaceFlag(rootCmd *cobra.Command, surface string) {
+	if len(cb.config.Surfaces) == 0 || rootCmd.PersistentFlags().Lookup(apiVersionFlag) != nil {
+		return
+	}
+	if rootCmd.Annotations == nil {
+		rootCmd.Annotations = make(map[string]string)
+	}
+	rootCmd.Annotations[surfaceAnnotation] = surface
+	rootCmd.PersistentFlags().String(apiVersionFlag, surface, surfaceFlagUsage(cb.config))
+	_ = rootCmd.RegisterFlagCompletionFunc(apiVersionFlag, cobra.FixedCompletions(sortedKeys(cb.config.Surfaces), cobra.ShellCompDirectiveNoFileComp))
+}
+
+// surfaceFlagUsage returns the help text of --api-version.
+func surfaceFlagUsage(config *ToolConfig) string {
+	usage := fmt.Sprintf("API version of the commands (%s)", strings.Join(sortedKeys(config.Surfaces), ", "))
+	if config.SurfaceEnv != "" {
+		usage += fmt.Sprintf("; also read from $%s", config.SurfaceEnv)
+	}
+	return usage
+}
+
+// checkSurface fails when the parsed --api-version differs from the surface the
+// commands of cmd were built for, e.g. when a root command built for the
+// process arguments runs other args set with SetArgs.
+func checkSurface(cmd *cobra.Command) error {
+	built, ok := cmd.Root().Annotations[surfaceAnnotation]
+	flag := cmd.Flags().Lookup(apiVersionFlag)
+	if !ok || flag == nil || flag.Value.String() == built {
+		return nil
+	}
+	return fmt.Errorf("--%s %s does not match the commands built for %s; build them for the args they run, e.g. with ExecuteArgs", apiVersionFlag, flag.Value.String(), built)
+}
@@ -0,0 +1,281 @@
+package cobrayaml
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const surfacesToolYAML = `
+name: surfaces-test
+default_surface: v1
+surface_env: SURFACES_TEST_API_VERSION
+root:
+  use: surfaces-test
+  short: Surfaces test
+commands:
+  version:
+    use: version
+    short: Print the version
+    run_func: runVersion
+surfaces:
+  v1:
+    commands:
+      get:
+        use: get <name>
+        short: Get an item
+        run_func: runGetV1
+  v2:
+    commands:
+      get:
+        use: get <kind> <name>
+        short: Get an item of a kind
+        run_func: runGetV2
+      db migrate:
+        use: migrate
+        short: Run migrations
+        run_func: runMigrate
+`
+
+// setCommandLine replaces the command line scanned for --api-version.
+func setCommandLine(t *testing.T, args ...string) {
+	t.Helper()
+	original := commandLineArgs
+	commandLineArgs = func() []string { return args }
+	t.Cleanup(func() { commandLineArgs = original })
+}
+
+func buildSurfacesTool(t *testing.T) (*cobra.Command, error) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	cb, err := NewCommandBuilderFromString(surfacesToolYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	for _, name := range []string{"runVersion", "runGetV1", "runGetV2", "runMigrate"} {
+		cb.RegisterFunction(name, func(cmd *cobra.Command, args []string) error { return nil })
+	}
+	return cb.BuildRootCommand()
+}
+
+func TestCommandBuilder_Surfaces(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         string
+		wantUse     string
+		wantMigrate bool
+	}{
+		{name: "default", wantUse: "get <name>"},
+		{name: "env", env: "v2", wantUse: "get <kind> <name>", wantMigrate: true},
+		{name: "flag", args: []string{"--api-version", "v2", "get"}, wantUse: "get <kind> <name>", wantMigrate: true},
+		{name: "flag with value", args: []string{"get", "--api-version=v2"}, wantUse: "get <kind> <name>", wantMigrate: true},
+		{name: "flag over env", args: []string{"--api-version=v1"}, env: "v2", wantUse: "get <name>"},
+		{name: "after --", args: []string{"get", "--", "--api-version=v2"}, wantUse: "get <name>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCommandLine(t, tt.args...)
+			t.Setenv("SURFACES_TEST_API_VERSION", tt.env)
+
+			rootCmd, err := buildSurfacesTool(t)
+			if err != nil {
+				t.Fatalf("BuildRootCommand() error = %v", err)
+			}
+			getCmd, _, err := rootCmd.Find([]string{"get"})
+			if err != nil || getCmd.Use != tt.wantUse {
+				t.Errorf("get use = %q (error %v), want %q", getCmd.Use, err, tt.wantUse)
+			}
+			if versionCmd, _, _ := rootCmd.Find([]string{"version"}); versionCmd.Name() != "version" {
+				t.Error("shared command version should be built for every surface")
+			}
+			migrateCmd, _, _ := rootCmd.Find([]string{"db", "migrate"})
+			if got := migrateCmd.Name() == "migrate"; got != tt.wantMigrate {
+				t.Errorf("db migrate built = %v, want %v", got, tt.wantMigrate)
+			}
+			if flag := rootCmd.PersistentFlags().Lookup(apiVersionFlag); flag == nil {
+				t.Errorf("root should have --%s", apiVersionFlag)
+			}
+		})
+	}
+}
+
+func TestCommandBuilder_UnknownSurface(t *testing.T) {
+	setCommandLine(t, "--api-version", "v3")
+
+	_, err := buildSurfacesTool(t)
+	if err == nil || !strings.Contains(err.Error(), `--api-version: unknown API version "v3" (available: v1, v2)`) {
+		t.Errorf("BuildRootCommand() error = %v, want unknown API version", err)
+	}
+}
+
+func TestCommandBuilder_SurfaceArgs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	setCommandLine(t)
+	cb, err := NewCommandBuilderFromString(surfacesToolYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var ran string
+	for _, name := range []string{"runVersion", "runGetV1", "runGetV2", "runMigrate"} {
+		cb.RegisterFunction(name, func(cmd *cobra.Command, args []string) error {
+			ran = name
+			return nil
+		})
+	}
+
+	// ExecuteArgs builds the commands of the surface its args select.
+	if err := cb.ExecuteArgs(context.Background(), []string{"--api-version=v2", "get", "pod", "web"}); err != nil {
+		t.Fatalf("ExecuteArgs() error = %v", err)
+	}
+	if ran != "runGetV2" {
+		t.Errorf("ExecuteArgs() ran %s, want runGetV2", ran)
+	}
+
+	// A root built for v1 refuses to run args selecting v2.
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	if got := rootCmd.Annotations[surfaceAnnotation]; got != "v1" {
+		t.Errorf("surface annotation = %q, want v1", got)
+	}
+	ran = ""
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{"get", "--api-version=v2", "web"})
+	want := "--api-version v2 does not match the commands built for v1; build them for the args they run, e.g. with ExecuteArgs"
+	if err := rootCmd.Execute(); err == nil || err.Error() != want {
+		t.Errorf("Execute() error = %v, want %q", err, want)
+	}
+	if ran != "" {
+		t.Errorf("Execute() ran %s, want no handler", ran)
+	}
+}
+
+func TestGenerator_Surfaces(t *testing.T) {
+	gen, err := NewGeneratorFromString(surfacesToolYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+
+	var names []string
+	for _, fn := range gen.CollectFunctions() {
+		names = append(names, fn.Name+" ("+fn.CmdPath+")")
+	}
+	want := "runVersion (version), runGetV1 (v1 > get <name>), runMigrate (v2 > db > migrate), runGetV2 (v2 > get <kind> <name>)"
+	if got := strings.Join(names, ", "); got != want {
+		t.Errorf("CollectFunctions() = %s, want %s", got, want)
+	}
+
+	docs, err := gen.GenerateDocs()
+	if err != nil {
+		t.Fatalf("GenerateDocs() error = %v", err)
+	}
+	for _, s := range []string{
+		"| `--api-version` |  | string | `v1` | API version of the commands (v1, v2); also read from $SURFACES_TEST_API_VERSION |",
+		"## Commands (v1)\n\nAvailable with `--api-version v1` (the default).",
+		"## Commands (v2)\n\nAvailable with `--api-version v2`.",
+		"surfaces-test get <kind> <name>",
+	} {
+		if !strings.Contains(docs, s) {
+			t.Errorf("docs should contain %q, got:\n%s", s, docs)
+		}
+	}
+}
+
+func TestValidateConfig_Surfaces(t *testing.T) {
+	get := CommandConfig{Use: "get", Short: "Get items", RunFunc: "runGet"}
+	tests := []struct {
+		name    string
+		config  ToolConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			config: ToolConfig{
+				Surfaces:       map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"get": get}}},
+				DefaultSurface: "v1",
+			},
+		},
+		{
+			name:    "missing default",
+			config:  ToolConfig{Surfaces: map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"get": get}}}},
+			wantErr: "surfaces: default_surface is required",
+		},
+		{
+			name: "unknown default",
+			config: ToolConfig{
+				Surfaces:       map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"get": get}}},
+				DefaultSurface: "v2",
+			},
+			wantErr: `surfaces: default_surface "v2" is not a surface`,
+		},
+		{
+			name:    "default without surfaces",
+			config:  ToolConfig{DefaultSurface: "v1"},
+			wantErr: "surfaces: default_surface and surface_env require surfaces",
+		},
+		{
+			name: "clash with shared command",
+			config: ToolConfig{
+				Commands:       map[string]CommandConfig{"get": get},
+				Surfaces:       map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"fetch": {Use: "get", Short: "Get"}}}},
+				DefaultSurface: "v1",
+			},
+			wantErr: `duplicate command name "get" at root level`,
+		},
+		{
+			name: "invalid surface command",
+			config: ToolConfig{
+				Surfaces:       map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"get": {Short: "Get"}}}},
+				DefaultSurface: "v1",
+			},
+			wantErr: `command "surfaces/v1/get": use is required`,
+		},
+		{
+			name: "reserved flag",
+			config: ToolConfig{
+				Root:           CommandConfig{Use: "test", Short: "Test", Flags: []FlagConfig{{Name: "api-version", Type: FlagTypeString}}},
+				Surfaces:       map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"get": get}}},
+				DefaultSurface: "v1",
+			},
+			wantErr: `root, flag "api-version": reserved for selecting the surface`,
+		},
+		{
+			name: "invalid env",
+			config: ToolConfig{
+				Surfaces:       map[string]SurfaceConfig{"v1": {Commands: map[string]CommandConfig{"get": get}}},
+				DefaultSurface: "v1",
+				SurfaceEnv:     "API VERSION",
+			},
+			wantErr: `surfaces: surface_env "API VERSION" is not a valid environment variable name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			config.Name = "test"
+			if config.Root.Use == "" {
+				config.Root = CommandConfig{Use: "test", Short: "Test"}
+			}
+
+			err := ValidateConfig(&config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
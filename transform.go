@@ -0,0 +1,131 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// TransformFunc normalizes a parsed flag value before the handler runs.
+// Register custom transformers with RegisterFunction and reference them
+// from the "transform_func" field of a flag definition.
+type TransformFunc = func(string) (string, error)
+
+// Built-in transformers available to every flag without registration.
+// A function registered with RegisterFunction under the same name takes precedence.
+const (
+	TransformTrimSpace  = "trimSpace"
+	TransformToLower    = "toLower"
+	TransformToUpper    = "toUpper"
+	TransformExpandHome = "expandHome"
+	TransformAbsPath    = "absPath"
+)
+
+// SupportedTransforms lists all built-in transformer names.
+var SupportedTransforms = []string{
+	TransformTrimSpace,
+	TransformToLower,
+	TransformToUpper,
+	TransformExpandHome,
+	TransformAbsPath,
+}
+
+// builtinTransforms maps built-in transformer names to their implementations.
+var builtinTransforms = map[string]TransformFunc{
+	TransformTrimSpace: func(s string) (string, error) {
+		return strings.TrimSpace(s), nil
+	},
+	TransformToLower: func(s string) (string, error) {
+		return strings.ToLower(s), nil
+	},
+	TransformToUpper: func(s string) (string, error) {
+		return strings.ToUpper(s), nil
+	},
+	TransformExpandHome: expandHome,
+	TransformAbsPath:    filepath.Abs,
+}
+
+// transformAnnotation is the pflag annotation key that carries the transformer name.
+const transformAnnotation = "cobrayaml_transform_func"
+
+// expandHome replaces a leading "~" with the current user's home directory.
+func expandHome(s string) (string, error) {
+	if s != "~" && !strings.HasPrefix(s, "~/") {
+		return s, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(s, "~")), nil
+}
+
+// lookupTransform resolves a transformer by name, preferring registered functions
+// over the built-in ones.
+func (cb *CommandBuilder) lookupTransform(name string) (TransformFunc, error) {
+	if fn, exists := cb.funcMap[name]; exists {
+		transform, ok := fn.(func(string) (string, error))
+		if !ok {
+			return nil, fmt.Errorf("function %s is not of type func(string) (string, error)", name)
+		}
+		return transform, nil
+	}
+	if transform, ok := builtinTransforms[name]; ok {
+		return transform, nil
+	}
+	return nil, fmt.Errorf("function %s not registered", name)
+}
+
+// applyTransforms runs the declared transformers over every flag visible to cmd,
+// including persistent flags inherited from parent commands.
+// Empty values are passed through unchanged.
+func (cb *CommandBuilder) applyTransforms(cmd *cobra.Command) error {
+	var firstErr error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		names := flag.Annotations[transformAnnotation]
+		if firstErr != nil || len(names) == 0 {
+			return
+		}
+		transform, err := cb.lookupTransform(names[0])
+		if err != nil {
+			firstErr = err
+			return
+		}
+		if err := transformFlagValue(flag, transform); err != nil {
+			firstErr = fmt.Errorf("invalid value for flag --%s: %w", flag.Name, err)
+		}
+	})
+	return firstErr
+}
+
+// transformFlagValue applies transform to a string or string slice flag value in place.
+func transformFlagValue(flag *pflag.Flag, transform TransformFunc) error {
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		values := slice.GetSlice()
+		for i, v := range values {
+			if v == "" {
+				continue
+			}
+			out, err := transform(v)
+			if err != nil {
+				return err
+			}
+			values[i] = out
+		}
+		return slice.Replace(values)
+	}
+
+	value := flag.Value.String()
+	if value == "" {
+		return nil
+	}
+	out, err := transform(value)
+	if err != nil {
+		return err
+	}
+	return flag.Value.Set(out)
+}
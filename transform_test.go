@@ -0,0 +1,190 @@
+package cobrayaml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandBuilder_TransformFunc(t *testing.T) {
+	yamlContent := `
+name: transform-test
+root:
+  use: transform-test
+  short: Transform test
+  flags:
+    - name: output
+      type: string
+      usage: Output path
+      persistent: true
+      transform_func: expandHome
+commands:
+  set:
+    use: set
+    short: Set value
+    run_func: runSet
+    flags:
+      - name: env
+        type: string
+        usage: Environment
+        transform_func: normalizeEnv
+      - name: tags
+        type: stringSlice
+        usage: Tags
+        transform_func: toUpper
+      - name: empty
+        type: string
+        usage: Left empty
+        transform_func: absPath
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	cb.RegisterFunction("normalizeEnv", func(s string) (string, error) {
+		return strings.ToLower(strings.TrimSpace(s)), nil
+	})
+
+	var env, output, empty string
+	var tags []string
+	cb.RegisterFunction("runSet", func(cmd *cobra.Command, args []string) error {
+		env, _ = cmd.Flags().GetString("env")
+		output, _ = cmd.Flags().GetString("output")
+		empty, _ = cmd.Flags().GetString("empty")
+		tags, _ = cmd.Flags().GetStringSlice("tags")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+
+	rootCmd.SetArgs([]string{"set", "--env", "  PROD ", "--output", "~/out", "--tags", "a,b"})
+	if err := rootCmd.Execute(); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if env != "prod" {
+		t.Errorf("env = %q, want %q", env, "prod")
+	}
+	home, _ := os.UserHomeDir()
+	if want := filepath.Join(home, "out"); output != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+	if !reflect.DeepEqual(tags, []string{"A", "B"}) {
+		t.Errorf("tags = %v, want [A B]", tags)
+	}
+	if empty != "" {
+		t.Errorf("empty = %q, want empty value to pass through", empty)
+	}
+}
+
+func TestCommandBuilder_TransformFuncError(t *testing.T) {
+	yamlContent := `
+name: transform-test
+root:
+  use: transform-test
+  short: Transform test
+  run_func: runRoot
+  flags:
+    - name: name
+      type: string
+      usage: Name
+      transform_func: failing
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("failing", func(s string) (string, error) {
+		return "", errors.New("boom")
+	})
+	cb.RegisterFunction("runRoot", func(cmd *cobra.Command, args []string) error {
+		t.Error("handler should not run when a transform fails")
+		return nil
+	})
+
+	rootCmd, err := cb.BuildRootCommand()
+	if err != nil {
+		t.Fatalf("BuildRootCommand() error = %v", err)
+	}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetArgs([]string{"--name", "x"})
+	err = rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Errorf("Execute() error = %v, want error mentioning --name", err)
+	}
+}
+
+func TestCommandBuilder_TransformFuncNotRegistered(t *testing.T) {
+	yamlContent := `
+name: transform-test
+root:
+  use: transform-test
+  short: Transform test
+  flags:
+    - name: name
+      type: string
+      usage: Name
+      transform_func: missing
+`
+	cb, err := NewCommandBuilderFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	if _, err := cb.BuildRootCommand(); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("BuildRootCommand() error = %v, want not registered error", err)
+	}
+}
+
+func TestValidateConfig_TransformFuncUnsupportedType(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test",
+			Flags: []FlagConfig{
+				{Name: "count", Type: FlagTypeInt, Usage: "Count", TransformFunc: TransformTrimSpace},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), "transform_func is only supported") {
+		t.Errorf("ValidateConfig() error = %v, want transform_func error", err)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"~", home},
+		{"~/a/b", filepath.Join(home, "a", "b")},
+		{"/abs/path", "/abs/path"},
+		{"~user/x", "~user/x"},
+	}
+	for _, tt := range tests {
+		got, err := expandHome(tt.in)
+		if err != nil {
+			t.Fatalf("expandHome(%q) error = %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("expandHome(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
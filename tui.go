@@ -0,0 +1,277 @@
+package cobrayaml
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// tuiNode is an entry of the tui menu: a command with its subcommands, and the
+// form to run it with when it is runnable.
+type tuiNode struct {
+	name     string
+	short    string
+	form     *Form
+	children []*tuiNode
+}
+
+// tuiTree returns the menu of the visible commands of config, built like the
+// forms of the web UI.
+func tuiTree(config *ToolConfig) *tuiNode {
+	root := &tuiNode{name: config.Name, short: config.Root.Short}
+	if config.Root.RunFunc != "" {
+		form := newForm(config.Name, nil, config.Root, nil)
+		root.form = &form
+	}
+
+	var walk func(node *tuiNode, names []string, cmds map[string]CommandConfig, inherited []FlagConfig)
+	walk = func(node *tuiNode, names []string, cmds map[string]CommandConfig, inherited []FlagConfig) {
+		for _, name := range sortedKeys(cmds) {
+			cmd := cmds[name]
+			if cmd.Hidden {
+				continue
+			}
+			path := append(append([]string{}, names...), name)
+			child := &tuiNode{name: name, short: cmd.Short}
+			if cmd.RunFunc != "" {
+				form := newForm(config.Name, path, cmd, inherited)
+				child.form = &form
+			}
+			walk(child, path, cmd.Commands, persistentFlags(inherited, cmd.Flags))
+			if child.form != nil || len(child.children) > 0 {
+				node.children = append(node.children, child)
+			}
+		}
+	}
+	walk(root, nil, config.Commands, persistentFlags(nil, config.Root.Flags))
+	return root
+}
+
+// addTUICommand adds the "tui" command when tui is set, unless the tool already
+// has a command of that name.
+func (cb *CommandBuilder) addTUICommand(rootCmd *cobra.Command) {
+	if !cb.config.TUI || findSubcommand(rootCmd, "tui") != nil {
+		return
+	}
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "tui",
+		Short: "Browse the commands in a menu and run them",
+		Long: "Browse the commands in a menu, fill in the args and flags of a command " +
+			"when asked, and run it. Enter the number of an entry, b to go back or q to quit.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !isInteractive(cmd) {
+				return fmt.Errorf("tui needs a terminal")
+			}
+			t := &tui{cb: cb, cmd: cmd, in: bufio.NewReader(cmd.InOrStdin()), out: cmd.ErrOrStderr()}
+			err := t.browse(tuiTree(cb.config))
+			if errors.Is(err, io.EOF) || errors.Is(err, errQuit) {
+				return nil
+			}
+			return err
+		},
+	})
+}
+
+// tui is a session of the tui command. Menus and prompts are written to out;
+// the commands run write to the usual output of the tool.
+type tui struct {
+	cb  *CommandBuilder
+	cmd *cobra.Command
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// errQuit ends the tui session.
+var errQuit = errors.New("quit")
+
+// browse shows the menu of node until the user goes back. It returns errQuit
+// when the user quits and io.EOF when the input ends.
+func (t *tui) browse(node *tuiNode) error {
+	for {
+		width := 0
+		for _, child := range node.children {
+			width = max(width, len(child.name))
+		}
+		fmt.Fprintf(t.out, "\n%s\n", node.name)
+		for i, child := range node.children {
+			marker := ""
+			if len(child.children) > 0 {
+				marker = " >"
+			}
+			fmt.Fprintf(t.out, "  %d) %-*s  %s%s\n", i+1, width, child.name, child.short, marker)
+		}
+		if node.form != nil {
+			fmt.Fprintf(t.out, "  r) run %s\n", node.form.Title)
+		}
+		fmt.Fprint(t.out, "  b) back  q) quit\nChoose: ")
+
+		answer, err := t.readLine()
+		if err != nil {
+			return err
+		}
+		switch answer {
+		case "":
+			continue
+		case "b":
+			return nil
+		case "q":
+			return errQuit
+		case "r":
+			if node.form != nil {
+				if err := t.run(*node.form); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		i, err := strconv.Atoi(answer)
+		if err != nil || i < 1 || i > len(node.children) {
+			fmt.Fprintf(t.out, "Invalid choice %q\n", answer)
+			continue
+		}
+
+		child := node.children[i-1]
+		if len(child.children) == 0 {
+			if err := t.run(*child.form); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := t.browse(child); err != nil {
+			return err
+		}
+	}
+}
+
+// run asks for the args and flags of form and runs the command. It returns
+// only errors reading the answers; errors of the run are reported by the
+// command and the session goes on.
+func (t *tui) run(form Form) error {
+	values, err := t.fill(form)
+	if err != nil {
+		return err
+	}
+	argv := form.Argv(values)
+
+	// Show the command line to run next time, without secrets
+	shown := url.Values{}
+	for name, value := range values {
+		shown[name] = value
+	}
+	for _, field := range form.Flags {
+		if field.Input == "password" && shown.Get(field.Name) != "" {
+			shown.Set(field.Name, historyRedacted)
+		}
+	}
+	fmt.Fprintf(t.out, "Running: %s\n", shellJoin(append([]string{t.cmd.Root().Name()}, form.Argv(shown)...)))
+
+	rootCmd, err := t.cb.BuildRootCommand()
+	if err != nil {
+		fmt.Fprintf(t.out, "Error: %v\n", err)
+		return nil
+	}
+	rootCmd.SetArgs(argv)
+	rootCmd.SetIn(t.cmd.InOrStdin())
+	rootCmd.SetOut(t.cmd.OutOrStdout())
+	rootCmd.SetErr(t.cmd.ErrOrStderr())
+	rootCmd.SilenceUsage = true
+	_ = rootCmd.ExecuteContext(t.cmd.Context())
+	return nil
+}
+
+// fill asks for every field of form and returns the answers as the values of
+// the form. An empty answer keeps the default.
+func (t *tui) fill(form Form) (url.Values, error) {
+	values := url.Values{}
+	for _, field := range append(append([]FormField{}, form.Args...), form.Flags...) {
+		prompt := field.Label
+		switch {
+		case field.Input == "checkbox":
+			def := "y/N"
+			if field.Default == "true" {
+				def = "Y/n"
+			}
+			fmt.Fprintf(t.out, "%s [%s]%s: ", prompt, def, usageHint(field.Usage))
+			answer, err := t.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if on := strings.ToLower(answer); on == "y" || on == "yes" || (on == "" && field.Default == "true") {
+				values.Set(field.Name, "true")
+			}
+			continue
+		case field.Input == "textarea":
+			fmt.Fprintf(t.out, "%s%s, one value per line, empty line to finish:\n", prompt, usageHint(field.Usage))
+			var lines []string
+			for {
+				answer, err := t.readLine()
+				if err != nil {
+					return nil, err
+				}
+				if answer == "" {
+					break
+				}
+				lines = append(lines, answer)
+			}
+			if len(lines) == 0 {
+				lines = []string{field.Default}
+			}
+			values.Set(field.Name, strings.Join(lines, "\n"))
+			continue
+		case field.Multiple:
+			prompt += "... (separated with spaces)"
+		case len(field.Options) > 0:
+			prompt += " (" + strings.Join(field.Options, ", ") + ")"
+		}
+		if field.Default != "" {
+			prompt += " [" + field.Default + "]"
+		}
+
+		for {
+			fmt.Fprintf(t.out, "%s%s: ", prompt, usageHint(field.Usage))
+			answer, err := t.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if answer == "" {
+				answer = field.Default
+			}
+			if answer == "" && field.Required {
+				fmt.Fprintf(t.out, "%s is required\n", field.Label)
+				continue
+			}
+			values.Set(field.Name, answer)
+			break
+		}
+	}
+	return values, nil
+}
+
+// usageHint returns the usage of a field to show after its prompt.
+func usageHint(usage string) string {
+	if usage == "" {
+		return ""
+	}
+	return " - " + usage
+}
+
+// readLine reads an answer without surrounding spaces. It returns io.EOF when
+// the input ends before a line.
+func (t *tui) readLine() (string, error) {
+	line, err := t.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			return "", io.EOF
+		}
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
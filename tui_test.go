@@ -0,0 +1,113 @@
+package cobrayaml
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// tuiTool is the ui-test tool with a tui command, whose deploy appends its
+// flags and arguments to runs.
+func tuiTool(runs *[]string, input string) testTool {
+	return testTool{
+		yaml: "tui: true\n" + formsYAML,
+		funcs: map[string]any{
+			"runDeploy": func(cmd *cobra.Command, args []string) error {
+				strategy, _ := cmd.Flags().GetString("strategy")
+				labels, _ := cmd.Flags().GetStringArray("label")
+				wait, _ := cmd.Flags().GetBool("wait")
+				token, _ := cmd.Flags().GetString("token")
+				verbose, _ := cmd.Flags().GetBool("verbose")
+				*runs = append(*runs, strings.Join([]string{
+					strings.Join(args, ","), strategy, strings.Join(labels, ","),
+					strconv.FormatBool(wait), token, strconv.FormatBool(verbose),
+				}, " "))
+				return nil
+			},
+			"runBackup": func(cmd *cobra.Command, args []string) error { return errors.New("disk full") },
+		},
+		in: input,
+	}
+}
+
+func TestTUI(t *testing.T) {
+	setInteractive(t, true)
+
+	input := strings.Join([]string{
+		"2",           // deploy
+		"",            // env is required
+		"prod",        // env
+		"api web",     // services
+		"canary",      // --strategy
+		"team=core",   // --label
+		"owner=ops",   // --label
+		"",            // end of labels
+		"n",           // --wait
+		"s3cret",      // --token
+		"y",           // --verbose
+		"2",           // deploy again with the defaults
+		"dev", "", "", // env, services, --strategy
+		"", "", "", "", // --label, --wait, --token, --verbose
+		"1", // db
+		"1", // backup
+		"b", // back to the top
+		"7", // invalid
+		"q",
+	}, "\n") + "\n"
+	var runs []string
+	stderr, err := tuiTool(&runs, input).run(t, "tui")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	want := []string{
+		"prod,api,web canary team=core,owner=ops false s3cret true",
+		"dev rolling  true  false",
+	}
+	if strings.Join(runs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("runs = %q, want %q", runs, want)
+	}
+	for _, s := range []string{
+		"  1) db      Database commands >\n  2) deploy  Deploy services\n",
+		"env is required",
+		"--strategy (rolling, canary) [rolling] - Rollout strategy: ",
+		"--wait [Y/n] - Wait for the rollout: ",
+		`Running: ui-test deploy --strategy=canary --label=team=core --label=owner=ops --wait=false '--token=<redacted>' --verbose=true prod api web`,
+		"Running: ui-test deploy --strategy=rolling dev",
+		"Running: ui-test db backup\nError: disk full\n",
+		"\ndb\n  1) backup  Back up the database\n",
+		`Invalid choice "7"`,
+	} {
+		if !strings.Contains(stderr, s) {
+			t.Errorf("stderr does not contain %q:\n%s", s, stderr)
+		}
+	}
+	if strings.Contains(stderr, "s3cret") {
+		t.Error("stderr shows the sensitive flag value")
+	}
+}
+
+func TestTUI_EndOfInput(t *testing.T) {
+	setInteractive(t, true)
+
+	var runs []string
+	_, err := tuiTool(&runs, "2\nprod").run(t, "tui")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(runs) != 0 {
+		t.Errorf("runs = %q, want none", runs)
+	}
+}
+
+func TestTUI_NotInteractive(t *testing.T) {
+	setInteractive(t, false)
+
+	_, err := tuiTool(new([]string), "q\n").run(t, "tui")
+	if err == nil || err.Error() != "tui needs a terminal" {
+		t.Errorf("Execute() error = %v, want tui needs a terminal", err)
+	}
+}
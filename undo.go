@@ -0,0 +1,147 @@
+package cobrayaml
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// undoAnnotation is the command annotation holding the name of the command's undo_func.
+const undoAnnotation = "cobrayaml_undo_func"
+
+// undoingKey is the context key marking a run that reverts a history entry.
+type undoingKey struct{}
+
+// undoing reports whether the run of cmd reverts a history entry.
+func undoing(cmd *cobra.Command) bool {
+	ctx := cmd.Context()
+	return ctx != nil && ctx.Value(undoingKey{}) != nil
+}
+
+// setUndo checks that the undo_func of a command is registered, records its
+// name on cmd for the undo command and wraps the handler set as RunE so a run
+// of the undo command calls the undo_func instead. The wrappers added after it,
+// such as cleanups, concurrency, events and middleware, apply to the undo too.
+func (cb *CommandBuilder) setUndo(cmd *cobra.Command, config CommandConfig) error {
+	if config.UndoFunc == "" {
+		return nil
+	}
+	undo, err := cb.lookupUndo(config.UndoFunc)
+	if err != nil {
+		return err
+	}
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[undoAnnotation] = config.UndoFunc
+
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			if undoing(cmd) {
+				return undo(cmd, args)
+			}
+			return run(cmd, args)
+		}
+	}
+	return nil
+}
+
+// lookupUndo resolves a registered undo function by name.
+func (cb *CommandBuilder) lookupUndo(name string) (func(*cobra.Command, []string) error, error) {
+	fn, exists := cb.funcMap[name]
+	if !exists {
+		return nil, fmt.Errorf("function %s not registered", name)
+	}
+	undo, ok := fn.(func(*cobra.Command, []string) error)
+	if !ok {
+		return nil, fmt.Errorf("function %s is not of type func(*cobra.Command, []string) error", name)
+	}
+	return undo, nil
+}
+
+// hasUndo reports whether any of cmds or their subcommands has an undo_func.
+func hasUndo(cmds map[string]CommandConfig) bool {
+	for _, cmd := range cmds {
+		if cmd.UndoFunc != "" || hasUndo(cmd.Commands) {
+			return true
+		}
+	}
+	return false
+}
+
+// addUndoCommand adds the "undo" command when a command has an undo_func, unless
+// the tool already has a command of that name.
+func (cb *CommandBuilder) addUndoCommand(rootCmd *cobra.Command, commands map[string]CommandConfig) {
+	if !cb.config.History || !hasUndo(commands) || findSubcommand(rootCmd, "undo") != nil {
+		return
+	}
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "undo [id]",
+		Short: "Revert the last run of a command that can be undone",
+		Long: "Revert the history entry with the given ID, or the last successful run of a command " +
+			"that can be undone and was not undone yet. The command's undo function runs with the " +
+			"args and flags of the original run. List entries with history.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := historyFile(cmd.Root().Name())
+			if err != nil {
+				return err
+			}
+			entries := readHistory(path)
+
+			i := -1
+			if len(args) == 1 {
+				if i, err = findHistoryEntry(entries, args[0]); err != nil {
+					return err
+				}
+			} else {
+				for j := len(entries) - 1; j >= 0; j-- {
+					entry := entries[j]
+					if undoTarget(cmd.Root(), entry) != nil && entry.Status == historySucceeded && !entry.Undone {
+						i = j
+						break
+					}
+				}
+				if i < 0 {
+					return fmt.Errorf("no run in the history can be undone")
+				}
+			}
+
+			entry := entries[i]
+			target := undoTarget(cmd.Root(), entry)
+			switch {
+			case target == nil:
+				return fmt.Errorf("history entry %d cannot be undone", entry.ID)
+			case entry.Status != historySucceeded:
+				return fmt.Errorf("history entry %d did not succeed and cannot be undone", entry.ID)
+			case entry.Undone:
+				return fmt.Errorf("history entry %d was undone already", entry.ID)
+			case entry.redacted():
+				return fmt.Errorf("history entry %d has redacted flag values and cannot be undone", entry.ID)
+			}
+
+			// Run the command of the entry, whose handler calls the undo_func in
+			// a run marked as undoing
+			fmt.Fprintf(cmd.ErrOrStderr(), "Undoing: %s\n", shellJoin(append([]string{cmd.Root().Name()}, entry.Args...)))
+			target.SetContext(context.WithValue(cmd.Context(), undoingKey{}, true))
+			if err := runMatch(cmd.Root(), entry.Args); err != nil {
+				return err
+			}
+
+			entries[i].Undone = true
+			return writeHistory(path, entries)
+		},
+	})
+}
+
+// undoTarget returns the command run by entry, or nil if the command no longer
+// exists or cannot be undone.
+func undoTarget(rootCmd *cobra.Command, entry historyEntry) *cobra.Command {
+	target, _, err := rootCmd.Find(entry.Args)
+	if err != nil || target == rootCmd || target.Annotations[undoAnnotation] == "" {
+		return nil
+	}
+	return target
+}
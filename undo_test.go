@@ -0,0 +1,156 @@
+package cobrayaml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const undoToolYAML = `
+name: undo-test
+history: true
+root:
+  use: undo-test
+  short: Undo test
+commands:
+  scale:
+    use: scale <app>
+    short: Scale an app
+    run_func: runScale
+    undo_func: undoScale
+    args:
+      type: exact
+      count: 1
+    flags:
+      - name: replicas
+        type: int
+        usage: Replicas
+  status:
+    use: status
+    short: Show status
+    run_func: runStatus
+`
+
+func TestCommandBuilder_Undo(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// The handlers and the middleware record their calls; scaling to more than
+	// 5 replicas fails
+	var calls []string
+	record := func(name string) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			replicas, _ := cmd.Flags().GetInt("replicas")
+			calls = append(calls, strings.Join(append([]string{name}, args...), " ")+" "+strings.Repeat("+", replicas))
+			if replicas > 5 {
+				return errors.New("quota exceeded")
+			}
+			return nil
+		}
+	}
+	tool := testTool{
+		yaml: undoToolYAML,
+		funcs: map[string]any{
+			"runScale":  record("scale"),
+			"undoScale": record("undo"),
+			"runStatus": record("status"),
+		},
+		setup: func(cb *CommandBuilder) {
+			cb.Use(func(next func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+				return func(cmd *cobra.Command, args []string) error {
+					calls = append(calls, "middleware "+cmd.Name())
+					return next(cmd, args)
+				}
+			})
+		},
+	}
+
+	if _, err := tool.run(t, "undo"); err == nil || !strings.Contains(err.Error(), "no run in the history can be undone") {
+		t.Fatalf("undo with empty history error = %v", err)
+	}
+
+	tool.mustRun(t, "scale", "web", "--replicas", "2")
+	tool.mustRun(t, "scale", "api", "--replicas", "3")
+	tool.mustRun(t, "status")
+	if _, err := tool.run(t, "scale", "db", "--replicas", "9"); err == nil {
+		t.Fatal("scale db error = nil, want quota exceeded")
+	}
+
+	// The undo runs through the middleware of the command, and skips the failed run
+	calls = nil
+	out := tool.mustRun(t, "undo")
+	if want := "middleware scale; undo api +++"; strings.Join(calls, "; ") != want {
+		t.Errorf("undo called %v, want %s", calls, want)
+	}
+	if !strings.Contains(out, "Undoing: undo-test scale --replicas=3 api\n") {
+		t.Errorf("undo should print the run it reverts, got %q", out)
+	}
+
+	calls = nil
+	if _, err := tool.run(t, "undo"); err != nil || strings.Join(calls, "; ") != "middleware scale; undo web ++" {
+		t.Errorf("second undo called %v, error = %v, want the undo of scale web", calls, err)
+	}
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"undo", "1"}, wantErr: "history entry 1 was undone already"},
+		{args: []string{"undo", "3"}, wantErr: "history entry 3 cannot be undone"},
+		{args: []string{"undo", "4"}, wantErr: "history entry 4 did not succeed and cannot be undone"},
+		{args: []string{"undo", "9"}, wantErr: "no history entry 9"},
+	}
+	for _, tt := range tests {
+		if _, err := tool.run(t, tt.args...); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%v error = %v, want %q", tt.args, err, tt.wantErr)
+		}
+	}
+
+	out = tool.mustRun(t, "history")
+	if !strings.Contains(out, "undo-test scale --replicas=2 web (undone)\n") || !strings.Contains(out, "undo-test scale --replicas=9 db (failed)\n") {
+		t.Errorf("history should mark undone and failed runs, got:\n%s", out)
+	}
+	if strings.Count(out, "\n") != 5 {
+		t.Errorf("undo runs should not be recorded, got:\n%s", out)
+	}
+}
+
+func TestValidateConfig_UndoFunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ToolConfig
+		wantErr string
+	}{
+		{
+			name: "without history",
+			config: ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"scale": {Use: "scale", Short: "Scale", RunFunc: "runScale", UndoFunc: "undoScale"}},
+			},
+			wantErr: "tool config: undo_func needs history to be enabled",
+		},
+		{
+			name: "without run_func",
+			config: ToolConfig{
+				Name:     "test",
+				History:  true,
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"scale": {Use: "scale", Short: "Scale", UndoFunc: "undoScale", Commands: map[string]CommandConfig{"up": {Use: "up", Short: "Up", RunFunc: "runUp"}}}},
+			},
+			wantErr: `command "scale": undo_func needs a run_func to revert`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
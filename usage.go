@@ -0,0 +1,155 @@
+package cobrayaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultFrequentCommands is the number of commands listed under "Frequently
+// used" when ShowFrequentCommands is given no positive limit.
+const defaultFrequentCommands = 5
+
+// ShowFrequentCommands lists the limit most used commands in a "Frequently used"
+// section at the top of the root command's help. It must be called before
+// BuildRootCommand. A limit below 1 selects the default of 5.
+//
+// Usage is counted only while this is enabled and stays on the machine: the
+// number of runs per command path is kept in usage.json in the tool's directory
+// under the user cache directory, without args, flag values or timestamps.
+// Setting DO_NOT_TRACK to a non-empty value other than 0 turns both counting and
+// the section off.
+func (cb *CommandBuilder) ShowFrequentCommands(limit int) {
+	if limit < 1 {
+		limit = defaultFrequentCommands
+	}
+	cb.frequentCommands = limit
+}
+
+// usageFile returns the file the command usage counts of tool are kept in.
+func usageFile(tool string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tool, "usage.json"), nil
+}
+
+// doNotTrack reports whether the user opted out of usage counting with DO_NOT_TRACK.
+func doNotTrack() bool {
+	value := os.Getenv("DO_NOT_TRACK")
+	return value != "" && value != "0"
+}
+
+// readUsage returns the usage counts in path keyed by command path, e.g.
+// "db migrate". A missing or unreadable file counts as no usage.
+func readUsage(path string) map[string]int {
+	counts := make(map[string]int)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return counts
+	}
+	_ = json.Unmarshal(data, &counts)
+	return counts
+}
+
+// recordUsage adds a run of the command at cmdPath to the usage counts of tool.
+// Failures are ignored, since counting must never affect the command.
+func recordUsage(tool, cmdPath string) {
+	path, err := usageFile(tool)
+	if err != nil {
+		return
+	}
+	counts := readUsage(path)
+	counts[cmdPath]++
+	data, err := json.MarshalIndent(counts, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// commandKey returns the path of cmd below the root command, e.g. "db migrate".
+func commandKey(cmd *cobra.Command) string {
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
+
+// addUsage wraps the RunE of cmd so its runs are counted for ShowFrequentCommands.
+func (cb *CommandBuilder) addUsage(cmd *cobra.Command) {
+	if cb.frequentCommands == 0 || cmd.RunE == nil {
+		return
+	}
+
+	run := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !doNotTrack() {
+			recordUsage(cmd.Root().Name(), commandKey(cmd))
+		}
+		return run(cmd, args)
+	}
+}
+
+// frequentCommands returns the limit most used commands of rootCmd that are still
+// available, most used first.
+func frequentCommands(rootCmd *cobra.Command, limit int) []*cobra.Command {
+	path, err := usageFile(rootCmd.Name())
+	if err != nil {
+		return nil
+	}
+	counts := readUsage(path)
+
+	var cmds []*cobra.Command
+	for _, key := range sortedKeys(counts) {
+		cmd, _, err := rootCmd.Find(strings.Fields(key))
+		if err != nil || cmd == rootCmd || commandKey(cmd) != key || !cmd.IsAvailableCommand() {
+			continue
+		}
+		cmds = append(cmds, cmd)
+	}
+	slices.SortStableFunc(cmds, func(a, b *cobra.Command) int {
+		return counts[commandKey(b)] - counts[commandKey(a)]
+	})
+	if len(cmds) > limit {
+		cmds = cmds[:limit]
+	}
+	return cmds
+}
+
+// addFrequentHelp wraps the help of the root command so it starts with the most
+// used commands.
+func (cb *CommandBuilder) addFrequentHelp(rootCmd *cobra.Command) {
+	if cb.frequentCommands == 0 {
+		return
+	}
+
+	help := rootCmd.HelpFunc()
+	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		if cmd == rootCmd && !doNotTrack() {
+			if cmds := frequentCommands(rootCmd, cb.frequentCommands); len(cmds) > 0 {
+				width := 0
+				for _, c := range cmds {
+					width = max(width, len(commandKey(c)))
+				}
+				out := cmd.OutOrStdout()
+				fmt.Fprintln(out, "Frequently used:")
+				for _, c := range cmds {
+					if Accessible(cmd) {
+						fmt.Fprintf(out, "  %s: %s\n", commandKey(c), c.Short)
+					} else {
+						fmt.Fprintf(out, "  %-*s  %s\n", width, commandKey(c), c.Short)
+					}
+				}
+				fmt.Fprintln(out)
+			}
+		}
+		help(cmd, args)
+	})
+}
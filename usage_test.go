@@ -0,0 +1,94 @@
+package cobrayaml
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const usageToolYAML = `
+name: usage-test
+root:
+  use: usage-test
+  short: Usage test
+commands:
+  get:
+    use: get
+    short: Get items
+    run_func: runNoop
+  list:
+    use: list
+    short: List items
+    run_func: runNoop
+  internal:
+    use: internal
+    short: Internal command
+    run_func: runNoop
+    hidden: true
+  db migrate:
+    use: migrate
+    short: Run migrations
+    run_func: runNoop
+`
+
+// usageTool is the usage-test tool listing its 2 most frequently used commands.
+var usageTool = testTool{
+	yaml:  usageToolYAML,
+	funcs: map[string]any{"runNoop": noopRun},
+	setup: func(cb *CommandBuilder) { cb.ShowFrequentCommands(2) },
+}
+
+// setUsageDirs isolates the user cache directory and clears DO_NOT_TRACK.
+func setUsageDirs(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DO_NOT_TRACK", "")
+	return filepath.Join(dir, "usage-test", "usage.json")
+}
+
+func TestCommandBuilder_FrequentCommands(t *testing.T) {
+	setUsageDirs(t)
+
+	for _, args := range [][]string{{"db", "migrate"}, {"list"}, {"db", "migrate"}, {"get"}, {"internal"}, {"internal"}, {"internal"}} {
+		usageTool.mustRun(t, args...)
+	}
+
+	help := usageTool.mustRun(t, "--help")
+	want := "Frequently used:\n  db migrate  Run migrations\n  get         Get items\n\n"
+	if !strings.HasPrefix(help, want) {
+		t.Errorf("root help should start with %q, got:\n%s", want, help)
+	}
+
+	sub := usageTool.mustRun(t, "get", "--help")
+	if strings.Contains(sub, "Frequently used") {
+		t.Errorf("only the root help should list frequently used commands, got:\n%s", sub)
+	}
+}
+
+func TestCommandBuilder_FrequentCommandsDisabled(t *testing.T) {
+	path := setUsageDirs(t)
+	tool := usageTool
+	tool.setup = nil
+
+	tool.mustRun(t, "get")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("usage should not be recorded unless enabled, stat error = %v", err)
+	}
+	if help := tool.mustRun(t, "--help"); strings.Contains(help, "Frequently used") {
+		t.Errorf("help should not list frequently used commands unless enabled, got:\n%s", help)
+	}
+}
+
+func TestCommandBuilder_FrequentCommandsDoNotTrack(t *testing.T) {
+	path := setUsageDirs(t)
+	t.Setenv("DO_NOT_TRACK", "1")
+
+	usageTool.mustRun(t, "get")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("usage should not be recorded with DO_NOT_TRACK, stat error = %v", err)
+	}
+}
@@ -2,8 +2,13 @@ package cobrayaml
 
 import (
 	"fmt"
+	"maps"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 )
 
 // ValidationError represents multiple validation errors collected during config validation.
@@ -51,30 +56,195 @@ func ValidateConfig(config *ToolConfig) error {
 	validateFlags(config.Root.Flags, "root", ve)
 	validateFlagDuplicates(config.Root.Flags, "root", ve)
 
-	// Collect all command names at root level for duplicate check
-	commandNames := make(map[string]bool)
-
 	// Validate all top-level commands
-	for name, cmdConfig := range config.Commands {
+	commandNames := validateTopLevelCommands(config.Commands, "", nil, ve)
+
+	// Validate flags redeclaring required persistent flags
+	inherited := requiredPersistentFlags(config.Root.Flags, "root", nil)
+	for _, name := range sortedCommandNames(config.Commands) {
+		validateInheritedRequired(config.Commands[name], name, inherited, ve)
+	}
+
+	// Validate surfaces and their commands
+	validateSurfaces(config, commandNames, inherited, ve)
+
+	// Validate the defaults of the root command for all commands
+	topLevel := []map[string]CommandConfig{config.Commands}
+	for _, surface := range sortedKeys(config.Surfaces) {
+		topLevel = append(topLevel, config.Surfaces[surface].Commands)
+	}
+	validateDefaults(config.Root.Defaults, topLevel, "root", ve)
+
+	// Validate keys using features the binary disabled
+	validateFeatures(config, ve)
+
+	// Validate against the policy of the host application
+	validatePolicy(config, ve)
+
+	if ve.hasErrors() {
+		return ve
+	}
+	return nil
+}
+
+// validateTopLevelCommands validates commands declared next to the root command
+// and returns the names they take at root level, including renamed_from shims,
+// added to a copy of taken. Error paths start with prefix.
+func validateTopLevelCommands(cmds map[string]CommandConfig, prefix string, taken map[string]bool, ve *ValidationError) map[string]bool {
+	commandNames := maps.Clone(taken)
+	if commandNames == nil {
+		commandNames = make(map[string]bool)
+	}
+	for _, name := range sortedCommandNames(cmds) {
+		cmdConfig := cmds[name]
 		cmdName := extractCommandName(cmdConfig.Use)
 		if cmdName == "" {
 			cmdName = name
 		}
 
-		// Check for duplicate command names
-		if commandNames[cmdName] {
-			ve.addError("duplicate command name %q at root level", cmdName)
+		// Check for duplicate command names, including renamed_from shims
+		for _, n := range append([]string{cmdName}, cmdConfig.RenamedFrom...) {
+			if commandNames[n] {
+				ve.addError("duplicate command name %q at root level", n)
+			}
+			commandNames[n] = true
 		}
-		commandNames[cmdName] = true
 
 		// Validate this command and its subcommands recursively
-		validateCommandRecursive(&cmdConfig, name, ve)
+		validateCommandRecursive(&cmdConfig, prefix+name, ve)
+	}
+	return commandNames
+}
+
+// validateSurfaces validates the surfaces of the tool. The commands of each
+// surface are built next to the shared commands, so their names must not clash
+// with them. shared holds the names of the shared commands and inherited the
+// required persistent flags of the root command.
+func validateSurfaces(config *ToolConfig, shared map[string]bool, inherited map[string]string, ve *ValidationError) {
+	if len(config.Surfaces) == 0 {
+		if config.DefaultSurface != "" || config.SurfaceEnv != "" {
+			ve.addError("surfaces: default_surface and surface_env require surfaces")
+		}
+		return
 	}
 
-	if ve.hasErrors() {
-		return ve
+	switch _, exists := config.Surfaces[config.DefaultSurface]; {
+	case config.DefaultSurface == "":
+		ve.addError("surfaces: default_surface is required")
+	case !exists:
+		ve.addError("surfaces: default_surface %q is not a surface", config.DefaultSurface)
+	}
+	if strings.ContainsAny(config.SurfaceEnv, "= \t\r\n") {
+		ve.addError("surfaces: surface_env %q is not a valid environment variable name", config.SurfaceEnv)
+	}
+	if slices.ContainsFunc(config.Root.Flags, func(f FlagConfig) bool { return f.Name == apiVersionFlag }) {
+		ve.addError("root, flag %q: reserved for selecting the surface", apiVersionFlag)
+	}
+
+	for _, surface := range sortedKeys(config.Surfaces) {
+		if strings.TrimSpace(surface) == "" || strings.HasPrefix(surface, "-") {
+			ve.addError("surfaces: invalid surface name %q", surface)
+		}
+		cmds := config.Surfaces[surface].Commands
+		if len(cmds) == 0 {
+			ve.addError("surfaces: surface %q has no commands", surface)
+		}
+		prefix := "surfaces/" + surface + "/"
+		validateTopLevelCommands(cmds, prefix, shared, ve)
+		for _, name := range sortedCommandNames(cmds) {
+			validateInheritedRequired(cmds[name], prefix+name, inherited, ve)
+			if config.ConfigFile == "" {
+				validateRequiresConfig(cmds[name], prefix+name, ve)
+			}
+		}
+	}
+}
+
+// requiredPersistentFlags returns inherited extended with the required persistent
+// flags among flags, mapped to the path of the command declaring them.
+func requiredPersistentFlags(flags []FlagConfig, path string, inherited map[string]string) map[string]string {
+	result := maps.Clone(inherited)
+	for _, flag := range flags {
+		if flag.Required && flag.Persistent {
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[flag.Name] = path
+		}
+	}
+	return result
+}
+
+// validateInheritedRequired reports flags of a command and its subcommands that
+// redeclare a required persistent flag of an ancestor. The local flag would
+// replace the inherited one, so the flag would no longer be required there.
+func validateInheritedRequired(config CommandConfig, path string, inherited map[string]string, ve *ValidationError) {
+	for _, flag := range config.Flags {
+		if owner, exists := inherited[flag.Name]; exists {
+			ve.addError("command %q, flag %q: redeclares the required persistent flag of command %q", path, flag.Name, owner)
+		}
+	}
+	inherited = requiredPersistentFlags(config.Flags, path, inherited)
+	for _, name := range sortedCommandNames(config.Commands) {
+		validateInheritedRequired(config.Commands[name], path+"/"+name, inherited, ve)
+	}
+}
+
+// ValidateConfigWithWarnings validates config like ValidateConfig and also returns
+// warnings: non-fatal findings such as runnable commands without a long description
+// or required flags hidden from help. Warnings are returned even if validation fails.
+func ValidateConfigWithWarnings(config *ToolConfig) ([]string, error) {
+	return configWarnings(config), ValidateConfig(config)
+}
+
+// configWarnings returns the warnings of the root command and all subcommands.
+func configWarnings(config *ToolConfig) []string {
+	var warnings []string
+	// With require_side_effects, every runnable command must be classified
+	classify := config.RequireSideEffects
+	// Top-level commands are declared next to root rather than under it
+	commandWarnings(config.Root, "root", len(config.Commands) > 0 || len(config.Surfaces) > 0, classify, &warnings)
+	for _, name := range sortedCommandNames(config.Commands) {
+		commandWarnings(config.Commands[name], name, false, classify, &warnings)
+	}
+	for _, surface := range sortedKeys(config.Surfaces) {
+		cmds := config.Surfaces[surface].Commands
+		for _, name := range sortedCommandNames(cmds) {
+			commandWarnings(cmds[name], "surfaces/"+surface+"/"+name, false, classify, &warnings)
+		}
+	}
+	return warnings
+}
+
+// commandWarnings appends the warnings of a command and its subcommands.
+// hasCommands reports whether the command has subcommands declared elsewhere,
+// and classify whether runnable commands must declare side_effects.
+func commandWarnings(config CommandConfig, path string, hasCommands, classify bool, warnings *[]string) {
+	warn := func(format string, args ...any) {
+		*warnings = append(*warnings, fmt.Sprintf(format, args...))
+	}
+
+	if config.RunFunc != "" && config.Long == "" {
+		warn("command %q: no long description", path)
+	}
+	if classify && config.RunFunc != "" && config.SideEffects == "" {
+		warn("command %q: no side_effects classification", path)
+	}
+	if config.RunFunc == "" && len(config.Commands) == 0 && config.DynamicCommandsFunc == "" && !hasCommands {
+		warn("command %q: no run_func or subcommands, so it only prints help", path)
+	}
+	for _, flag := range config.Flags {
+		if flag.Required && flag.Hidden {
+			warn("command %q, flag %q: required flag is hidden from help", path, flag.Name)
+		}
+		if flag.Required && flag.Deprecated != "" {
+			warn("command %q, flag %q: required flag is deprecated", path, flag.Name)
+		}
+	}
+
+	for _, name := range sortedCommandNames(config.Commands) {
+		commandWarnings(config.Commands[name], path+"/"+name, false, classify, warnings)
 	}
-	return nil
 }
 
 // validateToolConfig validates the ToolConfig required fields.
@@ -82,6 +252,165 @@ func validateToolConfig(config *ToolConfig, ve *ValidationError) {
 	if config.Name == "" {
 		ve.addError("tool config: name is required")
 	}
+	if config.SchemaVersion < 0 || config.SchemaVersion > SchemaVersion() {
+		ve.addError("tool config: schema_version %d is not supported (supported: 1-%d)", config.SchemaVersion, SchemaVersion())
+	}
+	validateConfigFiles(config, ve)
+	validateSettingsSchema(config, ve)
+	validateEvents(config, ve)
+	validateErrorCatalog(config, ve)
+	validateLicense(config, ve)
+	if config.FuzzyMatch && (config.Root.RunFunc != "" || config.Root.Args != nil) {
+		ve.addError("tool config: fuzzy_match needs a root command without run_func and args")
+	}
+	if config.Accessibility && slices.ContainsFunc(config.Root.Flags, func(f FlagConfig) bool { return f.Name == accessibleFlag }) {
+		ve.addError("root, flag %q: reserved for accessible output", accessibleFlag)
+	}
+	if config.Record && slices.ContainsFunc(config.Root.Flags, func(f FlagConfig) bool { return f.Name == recordFlag }) {
+		ve.addError("root, flag %q: reserved for recording transcripts", recordFlag)
+	}
+	if !config.History && (hasUndo(config.Commands) || slices.ContainsFunc(sortedKeys(config.Surfaces), func(name string) bool {
+		return hasUndo(config.Surfaces[name].Commands)
+	})) {
+		ve.addError("tool config: undo_func needs history to be enabled")
+	}
+	if config.ExamplesDir == "" && len(exampleFiles(config)) > 0 {
+		ve.addError("tool config: examples need examples_dir to be set")
+	}
+	if config.AskMatcher != "" && !config.AskCommand {
+		ve.addError("tool config: ask_matcher needs ask_command to be enabled")
+	}
+	if config.ConfigFile == "" {
+		validateRequiresConfig(config.Root, "root", ve)
+		for _, name := range sortedCommandNames(config.Commands) {
+			validateRequiresConfig(config.Commands[name], name, ve)
+		}
+	}
+}
+
+// validateConfigFiles validates the config file cascade of the tool.
+func validateConfigFiles(config *ToolConfig, ve *ValidationError) {
+	for _, file := range config.ConfigFiles {
+		if strings.TrimSpace(file) == "" {
+			ve.addError("tool config: config_files entries must not be empty")
+		}
+	}
+	if len(config.ConfigFiles) > 0 && config.ConfigFile != "" && !slices.Contains(config.ConfigFiles, config.ConfigFile) {
+		ve.addError("tool config: config_file %q must be one of config_files", config.ConfigFile)
+	}
+}
+
+// validateLicense validates the license of the tool. Its values are written into
+// comments of generated Go files, so they must fit on one line.
+func validateLicense(config *ToolConfig, ve *ValidationError) {
+	license := config.License
+	if license == nil {
+		return
+	}
+	if strings.TrimSpace(license.SPDX) == "" {
+		ve.addError("license: spdx is required")
+	}
+	if strings.ContainsAny(license.SPDX, "\r\n") || strings.ContainsAny(license.Holder, "\r\n") {
+		ve.addError("license: spdx and holder must be a single line")
+	}
+	if license.Year < 0 {
+		ve.addError("license: year must not be negative, got %d", license.Year)
+	}
+}
+
+// validateEvents validates the event sinks of the tool. Types other than the
+// built-in sinks are checked when the commands are built, since they are
+// registered with RegisterEventSink.
+func validateEvents(config *ToolConfig, ve *ValidationError) {
+	for i, sink := range config.Events {
+		if sink.Type == "" {
+			ve.addError("events[%d]: type is required", i)
+		}
+		for _, event := range sink.Events {
+			if !slices.Contains(SupportedEvents, event) {
+				ve.addError("events[%d]: event %q is not supported (supported: %s)", i, event, strings.Join(SupportedEvents, ", "))
+			}
+		}
+		if sink.Type == EventSinkFile && sink.Path == "" {
+			ve.addError("events[%d]: file sink requires path", i)
+		}
+		if sink.Type == EventSinkWebhook && sink.URL == "" {
+			ve.addError("events[%d]: webhook sink requires url", i)
+		}
+	}
+}
+
+// validateErrorCatalog validates the error catalog of the tool.
+func validateErrorCatalog(config *ToolConfig, ve *ValidationError) {
+	codes := make(map[string]bool)
+	for i, entry := range config.Errors {
+		if entry.Code == "" {
+			ve.addError("errors[%d]: code is required", i)
+			continue
+		}
+		if codes[entry.Code] {
+			ve.addError("errors[%d]: duplicate code %q", i, entry.Code)
+		}
+		codes[entry.Code] = true
+		if entry.Message == "" {
+			ve.addError("errors[%d]: message is required for code %q", i, entry.Code)
+		}
+	}
+}
+
+// validateSettingsSchema validates the settings_schema section of the tool.
+func validateSettingsSchema(config *ToolConfig, ve *ValidationError) {
+	if len(config.SettingsSchema) > 0 && config.ConfigFile == "" {
+		ve.addError("tool config: settings_schema is set but the tool has no config_file")
+	}
+
+	keys := make(map[string]bool)
+	for _, s := range config.SettingsSchema {
+		if s.Key == "" {
+			ve.addError("settings_schema: key is required")
+			continue
+		}
+		if slices.Contains(strings.Split(s.Key, "."), "") {
+			ve.addError("setting %q: key must not have empty segments", s.Key)
+		}
+		if keys[s.Key] {
+			ve.addError("setting %q: duplicate key", s.Key)
+		}
+		keys[s.Key] = true
+		if !slices.Contains(SupportedSettingTypes, s.Type) {
+			ve.addError("setting %q: unsupported type %q (supported: %s)", s.Key, s.Type, strings.Join(SupportedSettingTypes, ", "))
+			continue
+		}
+		if strings.ContainsAny(s.Env, "= \t") {
+			ve.addError("setting %q: env %q is not a valid environment variable name", s.Key, s.Env)
+		}
+		if s.Default != "" {
+			if _, err := parseSettingDefault(s); err != nil {
+				ve.addError("setting %q: invalid %s default value %q", s.Key, s.Type, s.Default)
+			}
+		}
+	}
+
+	// A key cannot hold both a value and nested settings.
+	sorted := sortedKeys(keys)
+	for _, key := range sorted {
+		for _, other := range sorted {
+			if strings.HasPrefix(other, key+".") {
+				ve.addError("setting %q: conflicts with nested setting %q", key, other)
+			}
+		}
+	}
+}
+
+// validateRequiresConfig reports commands that require a config file when the
+// tool does not declare one.
+func validateRequiresConfig(config CommandConfig, path string, ve *ValidationError) {
+	if config.RequiresConfig {
+		ve.addError("command %q: requires_config is set but the tool has no config_file", path)
+	}
+	for _, name := range sortedCommandNames(config.Commands) {
+		validateRequiresConfig(config.Commands[name], path+"/"+name, ve)
+	}
 }
 
 // validateCommandConfig validates a CommandConfig's required fields.
@@ -95,6 +424,366 @@ func validateCommandConfig(config *CommandConfig, path string, ve *ValidationErr
 
 	// Validate args config
 	validateArgsConfig(config.Args, path, ve)
+
+	// Validate renamed_from entries
+	validateRenamedFrom(config, path, ve)
+
+	// Validate derived values
+	validateDerived(config, path, ve)
+
+	// Validate environment variables
+	validateEnv(config, path, ve)
+
+	// Validate the result cache
+	validateCache(config, path, ve)
+
+	// Validate background support
+	validateBackground(config, path, ve)
+
+	// Validate notifications
+	validateNotify(config, path, ve)
+
+	// Validate disabled flag parsing
+	validateDisableFlagParsing(config, path, ve)
+
+	// Validate the concurrency flag
+	validateConcurrency(config, path, ve)
+
+	// Validate flag groups
+	validateFlagGroups(config, "required_together", config.RequiredTogether, path, ve)
+	validateFlagGroups(config, "one_required", config.OneRequired, path, ve)
+
+	// Validate touched paths
+	validateTouches(config, path, ve)
+
+	// Validate annotations
+	validateAnnotations(config.Annotations, fmt.Sprintf("command %q", path), ve)
+
+	// Validate help variants
+	validateHelpVariants(config, path, ve)
+
+	// Validate deprecation
+	if path == "root" && config.Deprecated != "" {
+		ve.addError("command %q: the root command cannot be deprecated", path)
+	}
+
+	// Validate valid args
+	validateValidArgs(config, path, ve)
+
+	// Validate the usage quota
+	validateQuota(config, path, ve)
+
+	// Validate the defaults of the subtree; those of the root command apply to
+	// the top-level commands and are validated in ValidateConfig
+	if path != "root" {
+		validateDefaults(config.Defaults, []map[string]CommandConfig{config.Commands}, path, ve)
+	}
+
+	// Validate example scripts
+	for _, file := range config.Examples {
+		if !filepath.IsLocal(file) {
+			ve.addError("command %q: example %q must be a relative path inside examples_dir", path, file)
+		}
+	}
+
+	// Validate the undo function
+	if config.UndoFunc != "" {
+		if path == "root" {
+			ve.addError("command %q: the root command cannot have an undo_func", path)
+		} else if config.RunFunc == "" {
+			ve.addError("command %q: undo_func needs a run_func to revert", path)
+		}
+	}
+
+	// Validate dynamic completion
+	if config.CompletionFunc != "" && len(config.ValidArgs) > 0 {
+		ve.addError("command %q: completion_func and valid_args cannot be combined", path)
+	}
+
+	// Validate the side effects classification
+	validateSideEffects(config, path, ve)
+}
+
+// validateValidArgs validates the values completed for positional arguments.
+func validateValidArgs(config *CommandConfig, path string, ve *ValidationError) {
+	seen := make(map[string]bool, len(config.ValidArgs))
+	for i, arg := range config.ValidArgs {
+		if strings.TrimSpace(arg) == "" {
+			ve.addError("command %q: valid_args[%d] must not be empty", path, i)
+			continue
+		}
+		if seen[arg] {
+			ve.addError("command %q: duplicate value %q in valid_args", path, arg)
+		}
+		seen[arg] = true
+	}
+	if config.Args != nil && len(config.ValidArgs) == 0 {
+		if config.Args.Type == ArgsTypeOnlyValidArgs {
+			ve.addError("command %q: args type 'only_valid_args' requires valid_args", path)
+		} else if config.Args.OnlyValid {
+			ve.addError("command %q: args.only_valid needs valid_args", path)
+		}
+	}
+}
+
+// validateHelpVariants validates the help variants of a command.
+func validateHelpVariants(config *CommandConfig, path string, ve *ValidationError) {
+	for _, name := range sortedKeys(config.Variants) {
+		switch variant := config.Variants[name]; {
+		case strings.TrimSpace(name) == "":
+			ve.addError("command %q: variant names must not be empty", path)
+		case name == HelpVariantDefault:
+			ve.addError("command %q: variant name %q is reserved for the command's own descriptions", path, name)
+		case variant.Short == "" && variant.Long == "":
+			ve.addError("command %q: variant %q must set short or long", path, name)
+		}
+	}
+}
+
+// validateTouches validates the sensitive paths a command declares.
+func validateTouches(config *CommandConfig, path string, ve *ValidationError) {
+	seen := make(map[string]bool, len(config.Touches))
+	for i, touched := range config.Touches {
+		if strings.TrimSpace(touched) == "" {
+			ve.addError("command %q: touches[%d] must not be empty", path, i)
+			continue
+		}
+		if seen[touched] {
+			ve.addError("command %q: duplicate path %q in touches", path, touched)
+		}
+		seen[touched] = true
+	}
+}
+
+// validateConcurrency validates the concurrency flag of a command.
+func validateConcurrency(config *CommandConfig, path string, ve *ValidationError) {
+	concurrency := config.Concurrency
+	if concurrency == nil {
+		return
+	}
+	if path == "root" {
+		ve.addError("command %q: concurrency is not supported on the root command", path)
+		return
+	}
+	if concurrency.Default < 0 {
+		ve.addError("command %q: concurrency default must not be negative, got %d", path, concurrency.Default)
+	}
+	if len(concurrency.Shorthand) > 1 {
+		ve.addError("command %q: concurrency shorthand %q must be a single character", path, concurrency.Shorthand)
+	}
+	name := concurrency.Flag
+	if name == "" {
+		name = defaultConcurrencyFlag
+	}
+	for _, flag := range config.Flags {
+		if flag.Name == name {
+			ve.addError("command %q: flag %q conflicts with the flag added by concurrency", path, flag.Name)
+		}
+		if concurrency.Shorthand != "" && flag.Shorthand == concurrency.Shorthand {
+			ve.addError("command %q: flag %q shorthand %q conflicts with the flag added by concurrency", path, flag.Name, flag.Shorthand)
+		}
+	}
+}
+
+// validateFlagGroups validates the flag groups under key: each group names at
+// least two distinct flags defined on the command itself, since inherited flags
+// are not known when the groups are marked.
+func validateFlagGroups(config *CommandConfig, key string, groups [][]string, path string, ve *ValidationError) {
+	names := make(map[string]bool, len(config.Flags))
+	for _, flag := range config.Flags {
+		names[flag.Name] = true
+	}
+
+	for _, group := range groups {
+		if len(group) < 2 {
+			ve.addError("command %q: %s group %v must list at least two flags", path, key, group)
+		}
+		seen := make(map[string]bool, len(group))
+		for _, name := range group {
+			if seen[name] {
+				ve.addError("command %q: %s group %v lists flag %q more than once", path, key, group, name)
+			}
+			seen[name] = true
+			if !names[name] {
+				ve.addError("command %q: %s flag %q is not a flag of the command", path, key, name)
+			}
+		}
+	}
+}
+
+// validateDisableFlagParsing checks that a command passing all tokens to its
+// handler defines nothing that needs its flags parsed.
+func validateDisableFlagParsing(config *CommandConfig, path string, ve *ValidationError) {
+	if !config.DisableFlagParsing {
+		return
+	}
+	if len(config.Flags) > 0 {
+		ve.addError("command %q: disable_flag_parsing cannot be combined with flags", path)
+	}
+	if config.Background != "" {
+		ve.addError("command %q: disable_flag_parsing cannot be combined with background", path)
+	}
+	if config.Concurrency != nil {
+		ve.addError("command %q: disable_flag_parsing cannot be combined with concurrency", path)
+	}
+}
+
+// validateBackground validates the background mode of a command.
+func validateBackground(config *CommandConfig, path string, ve *ValidationError) {
+	if config.Background == "" {
+		return
+	}
+	if config.Background != BackgroundSupported {
+		ve.addError("command %q: background %q is not supported (supported: %s)", path, config.Background, BackgroundSupported)
+	}
+	if path == "root" {
+		ve.addError("command %q: background is not supported on the root command", path)
+		return
+	}
+	if config.RunFunc == "" {
+		ve.addError("command %q: background requires run_func", path)
+	}
+	for _, flag := range config.Flags {
+		if flag.Name == detachFlag {
+			ve.addError("command %q: flag %q conflicts with the flag added by background", path, flag.Name)
+		}
+	}
+}
+
+// validateNotify validates the notification settings of a command. Names other
+// than the built-in notifiers are checked when the command is built, since they
+// are registered with RegisterNotifier.
+func validateNotify(config *CommandConfig, path string, ve *ValidationError) {
+	notify := config.Notify
+	if notify == nil {
+		return
+	}
+	if path == "root" {
+		ve.addError("command %q: notify is not supported on the root command", path)
+		return
+	}
+	if config.RunFunc == "" {
+		ve.addError("command %q: notify requires run_func", path)
+	}
+	for _, event := range notify.On {
+		if !slices.Contains(SupportedNotifyEvents, event) {
+			ve.addError("command %q: notify event %q is not supported (supported: %s)", path, event, strings.Join(SupportedNotifyEvents, ", "))
+		}
+	}
+	if notify.Via == "" {
+		ve.addError("command %q: notify via is required", path)
+	}
+	if notify.Via == NotifierWebhook && notify.URL == "" {
+		ve.addError("command %q: notify via webhook requires url", path)
+	}
+	if notify.Payload != "" {
+		if _, err := template.New("payload").Funcs(webhookFuncs).Parse(notify.Payload); err != nil {
+			ve.addError("command %q: notify payload is not a valid template: %v", path, err)
+		}
+	}
+}
+
+// isCacheKey reports whether key is flags.<name> or args.<index>.
+func isCacheKey(key string) bool {
+	if name, ok := strings.CutPrefix(key, "flags."); ok {
+		return name != ""
+	}
+	if index, ok := strings.CutPrefix(key, "args."); ok {
+		i, err := strconv.Atoi(index)
+		return err == nil && i >= 0
+	}
+	return false
+}
+
+// validateCache validates the result cache of a command.
+func validateCache(config *CommandConfig, path string, ve *ValidationError) {
+	cache := config.Cache
+	if cache == nil {
+		return
+	}
+	if path == "root" {
+		ve.addError("command %q: cache is not supported on the root command", path)
+		return
+	}
+	if config.RunFunc == "" {
+		ve.addError("command %q: cache requires run_func", path)
+	}
+	if cache.TTL == "" {
+		ve.addError("command %q: cache ttl is required", path)
+	} else if ttl, err := time.ParseDuration(cache.TTL); err != nil || ttl <= 0 {
+		ve.addError("command %q: cache ttl %q is not a positive duration", path, cache.TTL)
+	}
+	for _, key := range cache.Key {
+		if !isCacheKey(key) {
+			ve.addError("command %q: cache key %q must be flags.<name> or args.<index>", path, key)
+		}
+	}
+	for _, flag := range config.Flags {
+		if flag.Name == noCacheFlag {
+			ve.addError("command %q: flag %q conflicts with the flag added by cache", path, flag.Name)
+		}
+	}
+}
+
+// validateEnv validates environment variable declarations of a command.
+func validateEnv(config *CommandConfig, path string, ve *ValidationError) {
+	seen := make(map[string]bool)
+	for _, e := range config.Env {
+		if e.Name == "" {
+			ve.addError("command %q: env name is required", path)
+			continue
+		}
+		if strings.ContainsAny(e.Name, "= \t") {
+			ve.addError("command %q: env %q is not a valid environment variable name", path, e.Name)
+		}
+		if seen[e.Name] {
+			ve.addError("command %q: duplicate env %q", path, e.Name)
+		}
+		seen[e.Name] = true
+	}
+}
+
+// validateDerived validates derived value definitions of a command.
+func validateDerived(config *CommandConfig, path string, ve *ValidationError) {
+	names := make(map[string]bool)
+	for _, flag := range config.Flags {
+		names[flag.Name] = true
+	}
+
+	for _, d := range config.Derived {
+		if d.Name == "" {
+			ve.addError("command %q: derived name is required", path)
+			continue
+		}
+		if names[d.Name] {
+			ve.addError("command %q: derived %q conflicts with another flag or derived value", path, d.Name)
+		}
+		names[d.Name] = true
+		if d.Func == "" {
+			ve.addError("command %q, derived %q: func is required", path, d.Name)
+		}
+		if len(d.From) == 0 {
+			ve.addError("command %q, derived %q: from must list at least one flag", path, d.Name)
+		}
+	}
+}
+
+// validateRenamedFrom validates that renamed_from entries are single command names
+// that differ from the command's current name.
+func validateRenamedFrom(config *CommandConfig, path string, ve *ValidationError) {
+	for _, oldName := range config.RenamedFrom {
+		if strings.TrimSpace(oldName) == "" {
+			ve.addError("command %q: renamed_from entries must not be empty", path)
+			continue
+		}
+		if len(strings.Fields(oldName)) != 1 {
+			ve.addError("command %q: renamed_from entry %q must be a single command name", path, oldName)
+			continue
+		}
+		if oldName == extractCommandName(config.Use) {
+			ve.addError("command %q: renamed_from entry %q matches the current command name", path, oldName)
+		}
+	}
 }
 
 // validateCommandRecursive validates a command and all its subcommands recursively.
@@ -112,7 +801,8 @@ func validateCommandRecursive(config *CommandConfig, path string, ve *Validation
 	subCommandNames := make(map[string]bool)
 
 	// Validate subcommands recursively
-	for name, subConfig := range config.Commands {
+	for _, name := range sortedCommandNames(config.Commands) {
+		subConfig := config.Commands[name]
 		subPath := path + "/" + name
 
 		cmdName := extractCommandName(subConfig.Use)
@@ -120,11 +810,13 @@ func validateCommandRecursive(config *CommandConfig, path string, ve *Validation
 			cmdName = name
 		}
 
-		// Check for duplicate command names at this level
-		if subCommandNames[cmdName] {
-			ve.addError("command %q: duplicate subcommand name %q", path, cmdName)
+		// Check for duplicate command names at this level, including renamed_from shims
+		for _, n := range append([]string{cmdName}, subConfig.RenamedFrom...) {
+			if subCommandNames[n] {
+				ve.addError("command %q: duplicate subcommand name %q", path, n)
+			}
+			subCommandNames[n] = true
 		}
-		subCommandNames[cmdName] = true
 
 		validateCommandRecursive(&subConfig, subPath, ve)
 	}
@@ -150,6 +842,119 @@ func validateFlags(flags []FlagConfig, cmdPath string, ve *ValidationError) {
 				ve.addError("command %q: flag usage is required", cmdPath)
 			}
 		}
+		if flag.TransformFunc != "" && flag.Type != FlagTypeString && flag.Type != FlagTypeStringSlice && flag.Type != FlagTypeStringArray {
+			ve.addError("command %q, flag %q: transform_func is only supported for string, stringSlice and stringArray flags", cmdPath, flag.Name)
+		}
+		if flag.Schema != "" && flag.Type != FlagTypeString && flag.Type != FlagTypeFile {
+			ve.addError("command %q, flag %q: schema is only supported for string and file flags", cmdPath, flag.Name)
+		}
+		validatePathFlag(flag, cmdPath, ve)
+		validateAllowedValues(flag, cmdPath, ve)
+		validateFlagCompletion(flag, cmdPath, ve)
+		validateUintDefault(flag, cmdPath, ve)
+		if flag.Type != FlagTypeTime && (flag.Layout != "" || flag.Relative) {
+			ve.addError("command %q, flag %q: layout and relative are only supported for time flags", cmdPath, flag.Name)
+		}
+		if flag.ShorthandDeprecated != "" && flag.Shorthand == "" {
+			ve.addError("command %q, flag %q: shorthand_deprecated requires a shorthand", cmdPath, flag.Name)
+		}
+		validateAnnotations(flag.Annotations, fmt.Sprintf("command %q, flag %q", cmdPath, flag.Name), ve)
+	}
+}
+
+// validateAnnotations validates annotation keys. subject names the command or
+// flag in errors. Keys with the cobrayaml_ prefix are reserved for the builder.
+func validateAnnotations(annotations map[string]string, subject string, ve *ValidationError) {
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		switch {
+		case strings.TrimSpace(key) == "":
+			ve.addError("%s: annotation keys must not be empty", subject)
+		case strings.HasPrefix(key, "cobrayaml_"):
+			ve.addError("%s: annotation %q uses the reserved cobrayaml_ prefix", subject, key)
+		}
+	}
+}
+
+// validateUintDefault validates that the default of a uint or uint64 flag is a
+// non-negative integer.
+func validateUintDefault(flag FlagConfig, cmdPath string, ve *ValidationError) {
+	if flag.DefaultValue == "" || (flag.Type != FlagTypeUint && flag.Type != FlagTypeUint64) {
+		return
+	}
+	bitSize := 64
+	if flag.Type == FlagTypeUint {
+		bitSize = strconv.IntSize
+	}
+	if _, err := strconv.ParseUint(flag.DefaultValue, 10, bitSize); err != nil {
+		ve.addError("command %q, flag %q: default %q is not a valid %s", cmdPath, flag.Name, flag.DefaultValue, flag.Type)
+	}
+}
+
+// validateAllowedValues validates the allowed values of a string flag.
+func validateAllowedValues(flag FlagConfig, cmdPath string, ve *ValidationError) {
+	if len(flag.AllowedValues) == 0 {
+		return
+	}
+	if flag.Type != FlagTypeString {
+		ve.addError("command %q, flag %q: allowed_values are only supported for string flags", cmdPath, flag.Name)
+		return
+	}
+	seen := make(map[string]bool)
+	for _, v := range flag.AllowedValues {
+		if v == "" {
+			ve.addError("command %q, flag %q: allowed_values must not be empty", cmdPath, flag.Name)
+		} else if seen[v] {
+			ve.addError("command %q, flag %q: duplicate allowed value %q", cmdPath, flag.Name, v)
+		}
+		seen[v] = true
+	}
+	if flag.DefaultValue != "" && !seen[flag.DefaultValue] {
+		ve.addError("command %q, flag %q: default %q is not one of the allowed values", cmdPath, flag.Name, flag.DefaultValue)
+	}
+}
+
+// validateFlagCompletion validates the completion of a flag's values.
+func validateFlagCompletion(flag FlagConfig, cmdPath string, ve *ValidationError) {
+	if len(flag.Completion) == 0 && flag.CompletionFunc == "" {
+		return
+	}
+	switch {
+	case len(flag.Completion) > 0 && flag.CompletionFunc != "":
+		ve.addError("command %q, flag %q: completion and completion_func cannot be combined", cmdPath, flag.Name)
+	case len(flag.AllowedValues) > 0:
+		ve.addError("command %q, flag %q: allowed_values are completed already and cannot be combined with completion or completion_func", cmdPath, flag.Name)
+	case flag.Type == FlagTypeBool:
+		ve.addError("command %q, flag %q: completion and completion_func are not supported for bool flags", cmdPath, flag.Name)
+	}
+	seen := make(map[string]bool)
+	for _, v := range flag.Completion {
+		if strings.TrimSpace(v) == "" {
+			ve.addError("command %q, flag %q: completion values must not be empty", cmdPath, flag.Name)
+		} else if seen[v] {
+			ve.addError("command %q, flag %q: duplicate completion value %q", cmdPath, flag.Name, v)
+		}
+		seen[v] = true
+	}
+}
+
+// validatePathFlag validates the options of file and dir flags.
+func validatePathFlag(flag FlagConfig, cmdPath string, ve *ValidationError) {
+	isPath := flag.Type == FlagTypeFile || flag.Type == FlagTypeDir
+	if !isPath && (flag.MustExist != nil || flag.CreateMissing) {
+		ve.addError("command %q, flag %q: must_exist and create_missing are only supported for file and dir flags", cmdPath, flag.Name)
+	}
+	if flag.Type != FlagTypeFile && len(flag.Extensions) > 0 {
+		ve.addError("command %q, flag %q: extensions are only supported for file flags", cmdPath, flag.Name)
+	}
+	for _, ext := range flag.Extensions {
+		if strings.TrimSpace(ext) == "" {
+			ve.addError("command %q, flag %q: extensions must not be empty", cmdPath, flag.Name)
+		}
 	}
 }
 
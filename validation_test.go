@@ -1,6 +1,7 @@
 package cobrayaml
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -194,6 +195,94 @@ func TestValidateConfig_MissingFlagUsage(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_ShorthandDeprecatedWithoutShorthand(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test command",
+			Flags: []FlagConfig{
+				{Name: "force", Type: "bool", Usage: "Force", ShorthandDeprecated: "use --force instead"},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), `flag "force": shorthand_deprecated requires a shorthand`) {
+		t.Errorf("ValidateConfig() error = %v, want shorthand_deprecated error", err)
+	}
+}
+
+func TestValidateConfig_Annotations(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:         "test",
+			Short:       "Test command",
+			Annotations: map[string]string{"": "empty", "docs.section": "tools"},
+			Flags: []FlagConfig{
+				{Name: "name", Type: "string", Usage: "Name", Annotations: map[string]string{"cobrayaml_schema": "x"}},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil {
+		t.Fatal("ValidateConfig() expected error")
+	}
+	for _, want := range []string{
+		`command "root": annotation keys must not be empty`,
+		`command "root", flag "name": annotation "cobrayaml_schema" uses the reserved cobrayaml_ prefix`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateConfig() error = %v, want containing %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "docs.section") {
+		t.Errorf("ValidateConfig() should accept docs.section, got %v", err)
+	}
+}
+
+func TestValidateConfig_RedeclaredRequiredPersistentFlag(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{
+			Use:   "test",
+			Short: "Test command",
+			Flags: []FlagConfig{
+				{Name: "token", Type: "string", Usage: "Token", Required: true, Persistent: true},
+				{Name: "verbose", Type: "bool", Usage: "Verbose", Persistent: true},
+			},
+		},
+		Commands: map[string]CommandConfig{
+			"db": {
+				Use:   "db",
+				Short: "Database",
+				Commands: map[string]CommandConfig{
+					"migrate": {
+						Use:     "migrate",
+						Short:   "Migrate",
+						RunFunc: "runMigrate",
+						Flags: []FlagConfig{
+							{Name: "token", Type: "string", Usage: "Token"},
+							{Name: "verbose", Type: "bool", Usage: "Verbose"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := ValidateConfig(config)
+	want := `command "db/migrate", flag "token": redeclares the required persistent flag of command "root"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("ValidateConfig() error = %v, want containing %q", err, want)
+	}
+	if strings.Contains(err.Error(), `flag "verbose"`) {
+		t.Errorf("ValidateConfig() should allow redeclaring optional persistent flags, got %v", err)
+	}
+}
+
 func TestValidateConfig_DuplicateFlagNameInRootCommand(t *testing.T) {
 	config := &ToolConfig{
 		Name: "test",
@@ -910,3 +999,384 @@ func TestExtractCommandName(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateConfig_RenamedFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmds    map[string]CommandConfig
+		wantErr string
+	}{
+		{
+			name: "valid",
+			cmds: map[string]CommandConfig{
+				"remove": {Use: "remove", Short: "Remove", RenamedFrom: []string{"delete"}},
+			},
+		},
+		{
+			name: "empty entry",
+			cmds: map[string]CommandConfig{
+				"remove": {Use: "remove", Short: "Remove", RenamedFrom: []string{""}},
+			},
+			wantErr: "renamed_from entries must not be empty",
+		},
+		{
+			name: "multiple words",
+			cmds: map[string]CommandConfig{
+				"remove": {Use: "remove", Short: "Remove", RenamedFrom: []string{"delete item"}},
+			},
+			wantErr: "must be a single command name",
+		},
+		{
+			name: "same as current name",
+			cmds: map[string]CommandConfig{
+				"remove": {Use: "remove <name>", Short: "Remove", RenamedFrom: []string{"remove"}},
+			},
+			wantErr: "matches the current command name",
+		},
+		{
+			name: "collides with sibling",
+			cmds: map[string]CommandConfig{
+				"remove": {Use: "remove", Short: "Remove", RenamedFrom: []string{"list"}},
+				"list":   {Use: "list", Short: "List"},
+			},
+			wantErr: `duplicate command name "list"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: tt.cmds,
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_SchemaVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		wantErr bool
+	}{
+		{name: "unset", version: 0},
+		{name: "current", version: SchemaVersion()},
+		{name: "newer", version: SchemaVersion() + 1, wantErr: true},
+		{name: "negative", version: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				SchemaVersion: tt.version,
+				Name:          "test",
+				Root:          CommandConfig{Use: "test", Short: "Test"},
+			}
+
+			err := ValidateConfig(config)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "schema_version") {
+				t.Errorf("ValidateConfig() error = %v, want schema_version error", err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_UintDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		flagType string
+		value    string
+		wantErr  bool
+	}{
+		{name: "uint", flagType: FlagTypeUint, value: "3"},
+		{name: "uint64 max", flagType: FlagTypeUint64, value: "18446744073709551615"},
+		{name: "negative uint", flagType: FlagTypeUint, value: "-1", wantErr: true},
+		{name: "negative uint64", flagType: FlagTypeUint64, value: "-1", wantErr: true},
+		{name: "not a number", flagType: FlagTypeUint, value: "three", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name: "test",
+				Root: CommandConfig{
+					Use:   "test",
+					Short: "Test",
+					Flags: []FlagConfig{{Name: "replicas", Type: tt.flagType, DefaultValue: tt.value, Usage: "Replicas"}},
+				},
+			}
+
+			err := ValidateConfig(config)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "is not a valid "+tt.flagType) {
+				t.Errorf("ValidateConfig() error = %v, want invalid default error", err)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_FlagGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			command: CommandConfig{RequiredTogether: [][]string{{"user", "password"}}},
+		},
+		{
+			name:    "single flag",
+			command: CommandConfig{RequiredTogether: [][]string{{"user"}}},
+			wantErr: "required_together group [user] must list at least two flags",
+		},
+		{
+			name:    "duplicate flag",
+			command: CommandConfig{RequiredTogether: [][]string{{"user", "user"}}},
+			wantErr: `required_together group [user user] lists flag "user" more than once`,
+		},
+		{
+			name:    "unknown flag",
+			command: CommandConfig{RequiredTogether: [][]string{{"user", "token"}}},
+			wantErr: `required_together flag "token" is not a flag of the command`,
+		},
+		{
+			name:    "one required",
+			command: CommandConfig{OneRequired: [][]string{{"user", "password"}}},
+		},
+		{
+			name:    "one required single flag",
+			command: CommandConfig{OneRequired: [][]string{{"password"}}},
+			wantErr: "one_required group [password] must list at least two flags",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.command.Use = "login"
+			tt.command.Short = "Log in"
+			tt.command.Flags = []FlagConfig{
+				{Name: "user", Type: FlagTypeString, Usage: "User name"},
+				{Name: "password", Type: FlagTypeString, Usage: "Password"},
+			}
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"login": tt.command},
+			}
+
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfigWithWarnings(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		Commands: map[string]CommandConfig{
+			"deploy": {
+				Use:     "deploy",
+				Short:   "Deploy",
+				Long:    "Deploy the application.",
+				RunFunc: "runDeploy",
+				Flags: []FlagConfig{
+					{Name: "token", Type: FlagTypeString, Usage: "Token", Required: true, Hidden: true},
+					{Name: "key", Type: FlagTypeString, Usage: "Key", Required: true, Deprecated: "use --token instead"},
+				},
+			},
+			"db": {
+				Use:   "db",
+				Short: "Database",
+				Commands: map[string]CommandConfig{
+					"migrate": {Use: "migrate", Short: "Migrate", RunFunc: "runMigrate"},
+					"seed":    {Use: "seed", Short: "Seed"},
+				},
+			},
+		},
+	}
+
+	warnings, err := ValidateConfigWithWarnings(config)
+	if err != nil {
+		t.Fatalf("ValidateConfigWithWarnings() error = %v", err)
+	}
+	want := []string{
+		`command "db/migrate": no long description`,
+		`command "db/seed": no run_func or subcommands, so it only prints help`,
+		`command "deploy", flag "token": required flag is hidden from help`,
+		`command "deploy", flag "key": required flag is deprecated`,
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("ValidateConfigWithWarnings() warnings = %q, want %q", warnings, want)
+	}
+
+	config.Name = ""
+	warnings, err = ValidateConfigWithWarnings(config)
+	if err == nil {
+		t.Error("ValidateConfigWithWarnings() expected error for missing name")
+	}
+	if len(warnings) != len(want) {
+		t.Errorf("warnings should be returned alongside errors, got %q", warnings)
+	}
+}
+
+func TestValidateConfig_DeterministicErrors(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		Commands: map[string]CommandConfig{
+			"charlie": {Use: "charlie"},
+			"alpha":   {Use: "alpha"},
+			"bravo": {Use: "bravo", Short: "Bravo", Commands: map[string]CommandConfig{
+				"two": {Use: "two"},
+				"one": {Use: "one"},
+			}},
+		},
+	}
+
+	want := ValidateConfig(config).Error()
+	for i := 0; i < 20; i++ {
+		if got := ValidateConfig(config).Error(); got != want {
+			t.Fatalf("ValidateConfig() errors differ between runs:\n%s\n---\n%s", want, got)
+		}
+	}
+	if strings.Index(want, `"alpha"`) > strings.Index(want, `"charlie"`) {
+		t.Errorf("errors should be reported in command name order, got:\n%s", want)
+	}
+}
+
+func TestValidateConfig_DeprecatedRoot(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test", Deprecated: "use other-tool instead"},
+	}
+
+	err := ValidateConfig(config)
+	if err == nil || !strings.Contains(err.Error(), `command "root": the root command cannot be deprecated`) {
+		t.Errorf("ValidateConfig() error = %v, want root deprecation error", err)
+	}
+}
+
+func TestValidateConfig_ValidArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "empty value",
+			command: CommandConfig{Use: "deploy", Short: "Deploy", ValidArgs: []string{"dev", " "}},
+			wantErr: `command "deploy": valid_args[1] must not be empty`,
+		},
+		{
+			name:    "duplicate value",
+			command: CommandConfig{Use: "deploy", Short: "Deploy", ValidArgs: []string{"dev", "dev"}},
+			wantErr: `command "deploy": duplicate value "dev" in valid_args`,
+		},
+		{
+			name:    "only_valid without valid_args",
+			command: CommandConfig{Use: "deploy", Short: "Deploy", Args: &ArgsConfig{OnlyValid: true}},
+			wantErr: `command "deploy": args.only_valid needs valid_args`,
+		},
+		{
+			name:    "only_valid_args without valid_args",
+			command: CommandConfig{Use: "deploy", Short: "Deploy", Args: &ArgsConfig{Type: ArgsTypeOnlyValidArgs}},
+			wantErr: `command "deploy": args type 'only_valid_args' requires valid_args`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"deploy": tt.command},
+			}
+			err := ValidateConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_DisableFlagParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		command CommandConfig
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			command: CommandConfig{Use: "kubectl", Short: "Proxy", RunFunc: "runProxy", DisableFlagParsing: true},
+		},
+		{
+			name: "flags",
+			command: CommandConfig{Use: "kubectl", Short: "Proxy", RunFunc: "runProxy", DisableFlagParsing: true,
+				Flags: []FlagConfig{{Name: "context", Type: "string", Usage: "Context"}}},
+			wantErr: `command "kubectl": disable_flag_parsing cannot be combined with flags`,
+		},
+		{
+			name:    "background",
+			command: CommandConfig{Use: "kubectl", Short: "Proxy", RunFunc: "runProxy", DisableFlagParsing: true, Background: BackgroundSupported},
+			wantErr: `command "kubectl": disable_flag_parsing cannot be combined with background`,
+		},
+		{
+			name:    "concurrency",
+			command: CommandConfig{Use: "kubectl", Short: "Proxy", RunFunc: "runProxy", DisableFlagParsing: true, Concurrency: &ConcurrencyConfig{}},
+			wantErr: `command "kubectl": disable_flag_parsing cannot be combined with concurrency`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ToolConfig{
+				Name:     "test",
+				Root:     CommandConfig{Use: "test", Short: "Test"},
+				Commands: map[string]CommandConfig{"kubectl": tt.command},
+			}
+			err := ValidateConfig(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
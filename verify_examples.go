@@ -0,0 +1,355 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// ExampleInvocation is an invocation of the tool found in an example script.
+type ExampleInvocation struct {
+	File string   // example file, relative to examples_dir
+	Line int      // line the invocation starts on; 0 for a file without invocations
+	Args []string // args after the tool name
+	Err  error    // why the invocation does not parse, or nil
+}
+
+// String returns the invocation as a command line.
+func (i ExampleInvocation) String() string {
+	return shellJoin(i.Args)
+}
+
+// VerifyExamples parses every invocation of the tool in the example scripts of
+// the commands with the command tree BuildRootCommand builds, including the
+// flags and commands added by cobrayaml features, without running any handler.
+// baseDir is the directory examples_dir is relative to, usually the directory
+// of commands.yaml.
+//
+// Invocations are the simple commands of a script, after any VAR=value
+// assignments, that start with the tool name; pipelines, lists and redirections
+// are split as in a shell, and other commands are ignored. A file without any
+// invocation is reported as a failure. Each invocation is parsed with the
+// commands of the surface its --api-version selects, else default_surface.
+// Flags are parsed by their type, but paths need not exist and transform,
+// schema and completion functions are not run; the args of a command with
+// dynamic subcommands are not checked.
+func (g *Generator) VerifyExamples(baseDir string) ([]ExampleInvocation, error) {
+	files := exampleFiles(g.config)
+	if len(files) == 0 {
+		return nil, nil
+	}
+	dir := filepath.Join(baseDir, g.config.ExamplesDir)
+	toolName, _, _ := strings.Cut(g.config.Root.Use, " ")
+
+	var invocations []ExampleInvocation
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read example: %w", err)
+		}
+		found := false
+		for _, cmdLine := range scriptCommands(string(data)) {
+			if len(cmdLine.words) == 0 || cmdLine.words[0] != toolName {
+				continue
+			}
+			found = true
+			invocation := ExampleInvocation{File: file, Line: cmdLine.line, Args: cmdLine.words[1:]}
+			invocation.Err = g.parseExample(invocation.Args)
+			invocations = append(invocations, invocation)
+		}
+		if !found {
+			invocations = append(invocations, ExampleInvocation{
+				File: file,
+				Err:  fmt.Errorf("no invocation of %s", toolName),
+			})
+		}
+	}
+	return invocations, nil
+}
+
+// exampleFiles returns the example files of the root command, the commands and
+// the commands of every surface, in order and without duplicates.
+func exampleFiles(config *ToolConfig) []string {
+	var files []string
+	seen := make(map[string]bool)
+	var walk func(cmd CommandConfig)
+	walk = func(cmd CommandConfig) {
+		for _, file := range cmd.Examples {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+		for _, name := range sortedCommandNames(cmd.Commands) {
+			walk(cmd.Commands[name])
+		}
+	}
+	walk(CommandConfig{Examples: config.Root.Examples, Commands: defaultCommands(config)})
+	for _, surface := range sortedKeys(config.Surfaces) {
+		walk(CommandConfig{Commands: config.Surfaces[surface].Commands})
+	}
+	return files
+}
+
+// defaultCommands returns the commands built when no surface is selected: those
+// of the config and of the default surface.
+func defaultCommands(config *ToolConfig) map[string]CommandConfig {
+	surface := config.Surfaces[config.DefaultSurface].Commands
+	if len(surface) == 0 {
+		return config.Commands
+	}
+	cmds := make(map[string]CommandConfig, len(config.Commands)+len(surface))
+	for name, cmd := range config.Commands {
+		cmds[name] = cmd
+	}
+	for name, cmd := range surface {
+		cmds[name] = cmd
+	}
+	return cmds
+}
+
+// parseExample parses args as cobra does before running a command, with the
+// command tree built for args by exampleBuilder, and fails if they do not
+// select a runnable command with valid flags and args.
+func (g *Generator) parseExample(args []string) error {
+	rootCmd, err := g.exampleBuilder().buildRootCommand(args)
+	if err != nil {
+		return err
+	}
+	rootCmd.InitDefaultHelpCmd()
+	rootCmd.InitDefaultCompletionCmd()
+
+	cmd, flags, err := rootCmd.Find(args)
+	if err != nil {
+		return err
+	}
+	cmd.InitDefaultHelpFlag()
+	cmd.InitDefaultVersionFlag()
+	if err := cmd.ParseFlags(flags); err != nil {
+		return cmd.FlagErrorFunc()(cmd, err)
+	}
+	help, _ := cmd.Flags().GetBool("help")
+	version, _ := cmd.Flags().GetBool("version")
+	if help || version {
+		return nil
+	}
+	if !cmd.Runnable() {
+		if rest := cmd.Flags().Args(); len(rest) > 0 {
+			return fmt.Errorf("unknown command %q for %q", rest[0], cmd.CommandPath())
+		}
+		return fmt.Errorf("%q is not runnable", cmd.CommandPath())
+	}
+
+	positional := cmd.Flags().Args()
+	if cmd.DisableFlagParsing {
+		positional = flags
+	}
+	if err := cmd.ValidateArgs(positional); err != nil {
+		return err
+	}
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		return err
+	}
+	return cmd.ValidateFlagGroups()
+}
+
+// exampleBuilder returns a builder of the tool's commands for parseExample.
+// Its handlers do nothing, its flags are parse-only (see parseOnlyFlag), and
+// the functions, notifiers, event sinks and plugins that only matter when a
+// command runs are left out. The user's overrides file and the surface_env
+// variable do not apply, so examples verify the same everywhere.
+func (g *Generator) exampleBuilder() *CommandBuilder {
+	handlers := make(map[string]bool)
+	config := *g.config
+	config.Root = exampleCommand(config.Root, handlers)
+	config.Commands = exampleCommand(CommandConfig{Commands: config.Commands}, handlers).Commands
+	config.Surfaces = make(map[string]SurfaceConfig, len(g.config.Surfaces))
+	for name, surface := range g.config.Surfaces {
+		config.Surfaces[name] = SurfaceConfig{Commands: exampleCommand(CommandConfig{Commands: surface.Commands}, handlers).Commands}
+	}
+	config.SurfaceEnv = ""
+	config.Events = nil
+	config.DiscoverPlugins = false
+
+	cb := &CommandBuilder{
+		config:    &config,
+		funcMap:   make(map[string]any),
+		schemas:   make(map[string]*PayloadSchema),
+		parseOnly: true,
+	}
+	noop := func(*cobra.Command, []string) error { return nil }
+	for name := range handlers {
+		cb.RegisterFunction(name, noop)
+	}
+	return cb
+}
+
+// exampleCommand returns config and its subcommands for exampleBuilder, adding
+// the names of their handlers to handlers. A command with dynamic subcommands
+// becomes a handler taking any args, since its subcommands are only known when
+// the tool runs.
+func exampleCommand(config CommandConfig, handlers map[string]bool) CommandConfig {
+	config.ValidateFunc = ""
+	config.CompletionFunc = ""
+	config.Derived = nil
+	config.Notify = nil
+	if config.DynamicCommandsFunc != "" {
+		config.RunFunc = config.DynamicCommandsFunc
+		config.DynamicCommandsFunc = ""
+		config.DisableFlagParsing = true
+		config.Args = nil
+	}
+	for _, name := range []string{config.RunFunc, config.UndoFunc} {
+		if name != "" {
+			handlers[name] = true
+		}
+	}
+
+	commands := make(map[string]CommandConfig, len(config.Commands))
+	for name, sub := range config.Commands {
+		commands[name] = exampleCommand(sub, handlers)
+	}
+	config.Commands = commands
+	return config
+}
+
+// parseOnlyFlag returns flag without the parts that need registered functions or
+// the files of the user: custom types become strings, paths need not exist, and
+// transform, schema and completion functions are dropped.
+func parseOnlyFlag(flag FlagConfig) FlagConfig {
+	if !slices.Contains(SupportedFlagTypes, flag.Type) {
+		flag.Type = FlagTypeString
+	}
+	mustExist := false
+	flag.MustExist = &mustExist
+	flag.CreateMissing = false
+	flag.TransformFunc = ""
+	flag.Schema = ""
+	flag.CompletionFunc = ""
+	return flag
+}
+
+// scriptCommand is a simple command of a shell script.
+type scriptCommand struct {
+	line  int
+	words []string
+}
+
+// scriptCommands splits a shell script into simple commands, with quotes
+// removed, line continuations joined, comments and redirections dropped, and
+// leading VAR=value assignments and "$ " prompts skipped. Expansions such as
+// $VAR are kept as they are.
+func scriptCommands(script string) []scriptCommand {
+	var cmds []scriptCommand
+	current := scriptCommand{line: 1}
+	var word strings.Builder
+	inWord, redirect := false, false
+	line := 1
+
+	endWord := func() {
+		if !inWord {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		inWord = false
+		switch {
+		case redirect:
+			redirect = false
+		case len(current.words) == 0 && (w == "$" || isAssignment(w)):
+		default:
+			current.words = append(current.words, w)
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(current.words) > 0 {
+			cmds = append(cmds, current)
+		}
+		current = scriptCommand{line: line}
+		redirect = false
+	}
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if !inWord && len(current.words) == 0 {
+			current.line = line
+		}
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] == '\n' {
+				line++
+				endWord()
+				continue
+			}
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == '\'':
+			inWord = true
+			for i++; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\n' {
+					line++
+				}
+				word.WriteRune(runes[i])
+			}
+		case r == '"':
+			inWord = true
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune(`"\$`+"`", runes[i+1]) {
+					i++
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				word.WriteRune(runes[i])
+			}
+		case r == '#' && !inWord:
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			i--
+		case r == '\n':
+			endCommand()
+			line++
+			current.line = line
+		case r == ';' || r == '|' || r == '&':
+			endCommand()
+		case r == '>' || r == '<':
+			// Drop the redirection and its target, including forms such as 2>&1
+			if w := word.String(); inWord && strings.Trim(w, "0123456789") == "" {
+				word.Reset()
+				inWord = false
+			}
+			endWord()
+			for i+1 < len(runes) && (runes[i+1] == '>' || runes[i+1] == '&') {
+				i++
+			}
+			redirect = true
+		case r == ' ' || r == '\t' || r == '\r':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+	return cmds
+}
+
+// isAssignment reports whether word is a VAR=value assignment.
+func isAssignment(word string) bool {
+	name, _, found := strings.Cut(word, "=")
+	if !found || name == "" || unicode.IsDigit(rune(name[0])) {
+		return false
+	}
+	return !strings.ContainsFunc(name, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
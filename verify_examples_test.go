@@ -0,0 +1,254 @@
+package cobrayaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const verifyExamplesYAML = `
+name: ex
+version: 1.0.0
+examples_dir: examples
+root:
+  use: ex
+  short: Example test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  deploy:
+    use: deploy <env>
+    short: Deploy
+    run_func: runDeploy
+    examples: [deploy.sh]
+    args:
+      type: exact
+      count: 1
+      only_valid: true
+    valid_args: [dev, prod]
+    flags:
+      - name: replicas
+        type: int
+        usage: Number of replicas
+      - name: timeout
+        type: duration
+        required: true
+        usage: Rollout timeout
+      - name: manifest
+        type: file
+        usage: Manifest file
+  db:
+    use: db
+    short: Database commands
+    examples: [db.sh]
+    commands:
+      backup:
+        use: backup
+        short: Back up
+        run_func: runBackup
+        examples: [deploy.sh]
+`
+
+// writeExamples writes files into the examples directory of a temporary
+// commands.yaml directory and returns that directory.
+func writeExamples(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "examples"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, "examples", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGenerator_VerifyExamples(t *testing.T) {
+	gen, err := NewGeneratorFromString(verifyExamplesYAML)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	dir := writeExamples(t, map[string]string{
+		"deploy.sh": `#!/bin/sh
+# Deploy to production
+ex deploy prod --timeout 5m --manifest ./does-not-exist.yaml
+
+ex deploy dev --replicas=three --timeout 1m
+ex deploy staging --timeout 1m
+ex deploy prod
+ex deploy prod --colour red --timeout 1m
+VERBOSE=1 ex --verbose deploy \
+    prod --timeout 30s | tee out.log && echo done
+$ ex db backup 2>&1 > backup.log
+ex db bakup
+ex db
+ex db --help
+ex --version
+`,
+		"db.sh": "echo nothing to see\n",
+	})
+
+	invocations, err := gen.VerifyExamples(dir)
+	if err != nil {
+		t.Fatalf("VerifyExamples() error = %v", err)
+	}
+
+	var got []string
+	for _, i := range invocations {
+		got = append(got, fmt.Sprintf("%s:%d: %s: %v", i.File, i.Line, i, i.Err))
+	}
+	want := []string{
+		"db.sh:0: : no invocation of ex",
+		"deploy.sh:3: deploy prod --timeout 5m --manifest ./does-not-exist.yaml: <nil>",
+		`deploy.sh:5: deploy dev --replicas=three --timeout 1m: invalid argument "three" for "--replicas" flag: strconv.ParseInt: parsing "three": invalid syntax`,
+		`deploy.sh:6: deploy staging --timeout 1m: invalid argument "staging" for "ex deploy"`,
+		`deploy.sh:7: deploy prod: required flag(s) "timeout" not set`,
+		"deploy.sh:8: deploy prod --colour red --timeout 1m: unknown flag: --colour",
+		"deploy.sh:9: --verbose deploy prod --timeout 30s: <nil>",
+		"deploy.sh:11: db backup: <nil>",
+		`deploy.sh:12: db bakup: unknown command "bakup" for "ex db"`,
+		`deploy.sh:13: db: "ex db" is not runnable`,
+		"deploy.sh:14: db --help: <nil>",
+		"deploy.sh:15: --version: <nil>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VerifyExamples() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := gen.VerifyExamples(t.TempDir()); err == nil || !strings.Contains(err.Error(), "failed to read example") {
+		t.Errorf("VerifyExamples() error = %v, want a read error", err)
+	}
+}
+
+func TestGenerator_VerifyExamples_BuiltTree(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	yamlContent := `
+name: ops
+examples_dir: examples
+history: true
+default_surface: v1
+surfaces:
+  v1:
+    commands:
+      get:
+        use: get <name>
+        short: Get
+        run_func: runGetV1
+        args:
+          type: exact
+          count: 1
+  v2:
+    commands:
+      get:
+        use: get <kind> <name>
+        short: Get
+        run_func: runGetV2
+        examples: [ops.sh]
+root:
+  use: ops
+  short: Ops
+commands:
+  sync:
+    use: sync
+    short: Sync
+    run_func: runSync
+    background: supported
+    cache:
+      ttl: 5m
+  drop:
+    use: drop
+    short: Drop
+    run_func: runDrop
+    side_effects: destructive
+  envs:
+    use: envs
+    short: Environments
+    dynamic_commands_func: envCommands
+`
+	gen, err := NewGeneratorFromString(yamlContent)
+	if err != nil {
+		t.Fatalf("NewGeneratorFromString() error = %v", err)
+	}
+	dir := writeExamples(t, map[string]string{"ops.sh": `ops sync --detach --no-cache
+ops drop --yes
+ops history
+ops jobs list
+ops get web
+ops --api-version v2 get pod web
+ops get pod web
+ops envs prod deploy --force
+ops help sync
+`})
+
+	invocations, err := gen.VerifyExamples(dir)
+	if err != nil {
+		t.Fatalf("VerifyExamples() error = %v", err)
+	}
+	var got []string
+	for _, i := range invocations {
+		got = append(got, fmt.Sprintf("%d: %v", i.Line, i.Err))
+	}
+	want := []string{
+		"1: <nil>",
+		"2: <nil>",
+		"3: <nil>",
+		"4: <nil>",
+		"5: <nil>",
+		"6: <nil>",
+		`7: accepts 1 arg(s), received 2`,
+		"8: <nil>",
+		"9: <nil>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VerifyExamples() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestScriptCommands(t *testing.T) {
+	script := `A=1 B="x y" tool run 'single quoted' "double \"quoted\"" # comment
+tool a\ b; tool c || tool d &
+tool e \
+  --flag=1 2>/dev/null
+echo "multi
+line"; tool f`
+	want := []scriptCommand{
+		{line: 1, words: []string{"tool", "run", "single quoted", `double "quoted"`}},
+		{line: 2, words: []string{"tool", "a b"}},
+		{line: 2, words: []string{"tool", "c"}},
+		{line: 2, words: []string{"tool", "d"}},
+		{line: 3, words: []string{"tool", "e", "--flag=1"}},
+		{line: 5, words: []string{"echo", "multi\nline"}},
+		{line: 6, words: []string{"tool", "f"}},
+	}
+	if got := scriptCommands(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("scriptCommands() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateConfig_Examples(t *testing.T) {
+	config := &ToolConfig{
+		Name: "test",
+		Root: CommandConfig{Use: "test", Short: "Test"},
+		Commands: map[string]CommandConfig{
+			"deploy": {Use: "deploy", Short: "Deploy", RunFunc: "runDeploy", Examples: []string{"../deploy.sh"}},
+		},
+	}
+	err := ValidateConfig(config)
+	for _, want := range []string{
+		"tool config: examples need examples_dir to be set",
+		`command "deploy": example "../deploy.sh" must be a relative path inside examples_dir`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateConfig() error = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Package webhook delivers the webhook notifications and events of a
+// cobrayaml CLI over HTTP. It is a separate package so CLIs that never post a
+// webhook do not link net/http:
+//
+//	builder.SetWebhookPoster(webhook.Post)
+//
+// Generated main.go files set it when commands.yaml uses a webhook.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Post posts body to rawURL as JSON. A response status of 300 or above is an
+// error. Errors do not include the URL, since it may hold a token.
+func Post(ctx context.Context, rawURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
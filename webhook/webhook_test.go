@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPost(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request = %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	if err := Post(context.Background(), server.URL+"/hook", []byte(`{"ok":true}`)); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if got != `{"ok":true}` {
+		t.Errorf("body = %s", got)
+	}
+
+	if err := Post(context.Background(), server.URL+"/broken", nil); err == nil || err.Error() != "webhook returned 502 Bad Gateway" {
+		t.Errorf("Post() error = %v, want the response status", err)
+	}
+
+	// The URL may carry a token, so a failed request does not echo it.
+	err := Post(context.Background(), "http://127.0.0.1:1/hook?token=secret", nil)
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Post() error = %v, want an error without the URL", err)
+	}
+}
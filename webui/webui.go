@@ -0,0 +1,237 @@
+// Package webui serves a web UI for a cobrayaml tool: a page with a form per
+// runnable command that runs it and streams its output back. It is a separate
+// package so CLIs that do not serve it do not link net/http and html/template.
+package webui
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/S-mishina/cobrayaml"
+)
+
+// Runner runs a command of a tool for the web UI. argv holds the names of the
+// command followed by its flags and args, without the tool name. The output of
+// the run is written to w as it happens.
+type Runner func(ctx context.Context, argv []string, w io.Writer) error
+
+// BuilderRunner returns a Runner that runs commands in-process with the
+// handlers registered on cb. Runs are serialized, and only output written to
+// cmd.OutOrStdout and cmd.ErrOrStderr is streamed. Commands run with the
+// context of the HTTP request, so an HTTP middleware in front of the handler
+// can attach the caller with cobrayaml.WithIdentity.
+func BuilderRunner(cb *cobrayaml.CommandBuilder) Runner {
+	var mu sync.Mutex
+	return func(ctx context.Context, argv []string, w io.Writer) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rootCmd, err := cb.BuildRootCommandFor(argv)
+		if err != nil {
+			return err
+		}
+		rootCmd.SetArgs(argv)
+		rootCmd.SetIn(strings.NewReader("")) // destructive commands are confirmed with --yes
+		rootCmd.SetOut(w)
+		rootCmd.SetErr(w)
+		// The form stands in for the usage, and the error follows the output
+		rootCmd.SilenceUsage = true
+		rootCmd.SilenceErrors = true
+		return rootCmd.ExecuteContext(ctx)
+	}
+}
+
+// BinaryRunner returns a Runner that runs commands by executing binary, the
+// built tool, and streams its stdout and stderr back.
+func BinaryRunner(binary string) Runner {
+	return func(ctx context.Context, argv []string, w io.Writer) error {
+		cmd := exec.CommandContext(ctx, binary, argv...)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		return cmd.Run()
+	}
+}
+
+// Handler returns a web UI for the tool of config: a page with a form per
+// runnable command, with an input per flag and a field per argument, that runs
+// the command with run and streams its output back. The handler has no
+// authentication; serve it on a trusted network.
+//
+// Requests are refused unless their Host is an IP address, localhost or one of
+// allowedHosts, such as the name the UI is served on, so a page on another site
+// cannot reach it through a rebound DNS name.
+func Handler(config *cobrayaml.ToolConfig, run Runner, allowedHosts ...string) http.Handler {
+	commands := cobrayaml.Forms(config)
+	byPath := make(map[string]cobrayaml.Form, len(commands))
+	for _, cmd := range commands {
+		byPath[cmd.Path] = cmd
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := page.Execute(w, map[string]any{"Tool": config, "Commands": commands}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	mux.HandleFunc("/run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// Refuse runs posted from other sites
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+				http.Error(w, "cross-origin run refused", http.StatusForbidden)
+				return
+			}
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cmd, exists := byPath[r.PostForm.Get("command")]
+		if !exists {
+			http.Error(w, fmt.Sprintf("unknown command %q", r.PostForm.Get("command")), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		out := &flushWriter{w: w}
+		out.flusher, _ = w.(http.Flusher)
+		if err := run(r.Context(), cmd.Argv(r.PostForm), out); err != nil {
+			fmt.Fprintf(out, "\nFailed: %v\n", err)
+		}
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hostAllowed(r.Host, allowedHosts) {
+			http.Error(w, fmt.Sprintf("host %q not allowed", r.Host), http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// hostAllowed reports whether host, the Host of a request with an optional
+// port, is an IP address, localhost or one of allowed.
+func hostAllowed(host string, allowed []string) bool {
+	name := strings.ToLower(hostName(host))
+	if net.ParseIP(name) != nil || name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return true
+	}
+	for _, a := range allowed {
+		if a != "" && strings.EqualFold(hostName(a), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostName returns host without its port and the brackets of an IPv6 address.
+func hostName(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+}
+
+// flushWriter flushes every write to the client, so output is streamed. The
+// mutex orders writes to stdout and stderr of the same run.
+type flushWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	n, err := fw.w.Write(p)
+	if fw.flusher != nil {
+		fw.flusher.Flush()
+	}
+	return n, err
+}
+
+// page is the page of the web UI. Each form posts to /run and shows the
+// streamed output below it.
+var page = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Tool.Name}}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; }
+section { border: 1px solid #ccc; border-radius: 4px; padding: 1em; margin-bottom: 1.5em; }
+label { display: block; margin-top: 0.6em; font-family: monospace; }
+small { display: block; color: #555; }
+pre { background: #111; color: #eee; padding: 0.8em; white-space: pre-wrap; min-height: 1em; }
+pre:empty { display: none; }
+</style>
+</head>
+<body>
+<h1>{{.Tool.Name}}</h1>
+{{with .Tool.Description}}<p>{{.}}</p>{{end}}
+{{range .Commands}}
+<section>
+<h2>{{.Title}}</h2>
+{{with .Short}}<p>{{.}}</p>{{end}}
+<form>
+<input type="hidden" name="command" value="{{.Path}}">
+{{range .Args}}<label>{{.Label}}{{if .Multiple}}...{{end}}
+<input type="text" name="{{.Name}}"{{if .Required}} required{{end}}>{{with .Usage}}<small>{{.}}</small>{{end}}</label>
+{{end}}{{range .Flags}}<label>{{if eq .Input "checkbox"}}<input type="checkbox" name="{{.Name}}" value="true"{{if eq .Default "true"}} checked{{end}}> {{.Label}}
+{{else}}{{.Label}}
+{{if eq .Input "select"}}<select name="{{.Name}}"{{if .Required}} required{{end}}>{{if not .Required}}<option value=""></option>{{end}}{{$default := .Default}}{{range .Options}}<option{{if eq . $default}} selected{{end}}>{{.}}</option>{{end}}</select>
+{{else if eq .Input "textarea"}}<textarea name="{{.Name}}" rows="2" placeholder="one value per line">{{.Default}}</textarea>
+{{else}}<input type="{{.Input}}" name="{{.Name}}" value="{{.Default}}"{{if .Required}} required{{end}}>
+{{end}}{{end}}{{with .Usage}}<small>{{.}}</small>{{end}}</label>
+{{end}}<p><button type="submit">Run</button></p>
+<pre></pre>
+</form>
+</section>
+{{else}}
+<p>No runnable commands.</p>
+{{end}}
+<script>
+for (const form of document.querySelectorAll("form")) {
+  form.addEventListener("submit", async (event) => {
+    event.preventDefault();
+    const out = form.querySelector("pre");
+    const button = form.querySelector("button");
+    out.textContent = "";
+    button.disabled = true;
+    try {
+      const resp = await fetch("run", { method: "POST", body: new URLSearchParams(new FormData(form)) });
+      const reader = resp.body.getReader();
+      const decoder = new TextDecoder();
+      for (;;) {
+        const { done, value } = await reader.read();
+        if (done) break;
+        out.textContent += decoder.decode(value, { stream: true });
+      }
+    } catch (err) {
+      out.textContent += "\nFailed: " + err;
+    } finally {
+      button.disabled = false;
+    }
+  });
+}
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,269 @@
+package webui
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/S-mishina/cobrayaml"
+)
+
+const uiYAML = `
+name: ui-test
+root:
+  use: ui-test
+  short: UI test
+  flags:
+    - name: verbose
+      type: bool
+      persistent: true
+      usage: Verbose output
+commands:
+  deploy:
+    use: deploy <env> [services...]
+    short: Deploy services
+    run_func: runDeploy
+    flags:
+      - name: strategy
+        type: string
+        default: rolling
+        allowed_values: [rolling, canary]
+        usage: Rollout strategy
+      - name: label
+        type: stringArray
+        usage: Labels to set
+      - name: wait
+        type: bool
+        default: "true"
+        usage: Wait for the rollout
+      - name: token
+        type: string
+        sensitive: true
+        usage: API token
+  db:
+    use: db
+    short: Database commands
+    commands:
+      backup:
+        use: backup
+        short: Back up the database
+        run_func: runBackup
+        args:
+          type: none
+  secret:
+    use: secret
+    short: Hidden command
+    hidden: true
+    run_func: runBackup
+`
+
+func newUITestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	cb, err := cobrayaml.NewCommandBuilderFromString(uiYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		strategy, _ := cmd.Flags().GetString("strategy")
+		labels, _ := cmd.Flags().GetStringArray("label")
+		wait, _ := cmd.Flags().GetBool("wait")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		cmd.Printf("deploy %v strategy=%s labels=%v wait=%t verbose=%t\n", args, strategy, labels, wait, verbose)
+		return nil
+	})
+	cb.RegisterFunction("runBackup", func(cmd *cobra.Command, args []string) error {
+		cmd.PrintErrln("backup started")
+		return errors.New("disk full")
+	})
+	return Handler(cb.GetConfig(), BuilderRunner(cb))
+}
+
+func postRun(t *testing.T, handler http.Handler, form url.Values, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(form.Encode()))
+	req.Host = "localhost:8080"
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_Page(t *testing.T) {
+	handler := newUITestHandler(t)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Host = "localhost:8080"
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	page := rec.Body.String()
+	for _, want := range []string{
+		"<h2>ui-test deploy</h2>",
+		"<h2>ui-test db backup</h2>",
+		`name="arg.0" required`,
+		`name="arg.1"`,
+		"<option selected>rolling</option>",
+		`<textarea name="flag.label"`,
+		`<input type="checkbox" name="flag.wait" value="true" checked>`,
+		`<input type="password" name="flag.token" value="">`,
+		`name="flag.verbose"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if strings.Contains(page, "ui-test secret") {
+		t.Error("page should not show hidden commands")
+	}
+	if strings.Contains(page, "<h2>ui-test db</h2>") {
+		t.Error("page should not show commands without run_func")
+	}
+}
+
+func TestHandler_Run(t *testing.T) {
+	handler := newUITestHandler(t)
+
+	rec := postRun(t, handler, url.Values{
+		"command":        {"deploy"},
+		"arg.0":          {"prod"},
+		"arg.1":          {"api -web"},
+		"flag.strategy":  {"canary"},
+		"flag.label":     {"team=core\n\nowner=ops"},
+		"flag.verbose":   {"true"},
+		"flag.undefined": {"x"},
+	}, "http://localhost:8080")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	want := "deploy [prod api -web] strategy=canary labels=[team=core owner=ops] wait=false verbose=true\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestHandler_RunFailure(t *testing.T) {
+	handler := newUITestHandler(t)
+
+	rec := postRun(t, handler, url.Values{"command": {"db backup"}}, "")
+	if want := "backup started\n\nFailed: disk full\n"; rec.Body.String() != want {
+		t.Errorf("output = %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestHandler_Refused(t *testing.T) {
+	handler := newUITestHandler(t)
+
+	tests := []struct {
+		name   string
+		method string
+		form   url.Values
+		path   string
+		host   string
+		origin string
+		want   int
+	}{
+		{name: "get", method: http.MethodGet, want: http.StatusMethodNotAllowed},
+		{name: "cross-origin", method: http.MethodPost, form: url.Values{"command": {"db backup"}}, origin: "http://evil.test", want: http.StatusForbidden},
+		{name: "rebound host", method: http.MethodPost, form: url.Values{"command": {"db backup"}}, host: "evil.test:8080", origin: "http://evil.test:8080", want: http.StatusForbidden},
+		{name: "rebound host page", method: http.MethodGet, path: "/", host: "evil.test", want: http.StatusForbidden},
+		{name: "unknown command", method: http.MethodPost, form: url.Values{"command": {"rm"}}, want: http.StatusNotFound},
+		{name: "hidden command", method: http.MethodPost, form: url.Values{"command": {"secret"}}, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, host := "/run", "localhost:8080"
+			if tt.path != "" {
+				path = tt.path
+			}
+			if tt.host != "" {
+				host = tt.host
+			}
+			req := httptest.NewRequest(tt.method, path, strings.NewReader(tt.form.Encode()))
+			req.Host = host
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"tools.example.com:8080"}
+	for _, tt := range []struct {
+		host string
+		want bool
+	}{
+		{host: "localhost:8080", want: true},
+		{host: "app.localhost", want: true},
+		{host: "127.0.0.1:8080", want: true},
+		{host: "[::1]:8080", want: true},
+		{host: "Tools.Example.com", want: true},
+		{host: "evil.test:8080"},
+		{host: "example.com"},
+	} {
+		if got := hostAllowed(tt.host, allowed); got != tt.want {
+			t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryRunner(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo not found")
+	}
+	cb, err := cobrayaml.NewCommandBuilderFromString(uiYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+
+	rec := postRun(t, Handler(cb.GetConfig(), BinaryRunner(echo)), url.Values{"command": {"db backup"}}, "")
+	if got := rec.Body.String(); got != "db backup\n" {
+		t.Errorf("output = %q, want %q", got, "db backup\n")
+	}
+}
+
+func TestBuilderRunner_Identity(t *testing.T) {
+	cb, err := cobrayaml.NewCommandBuilderFromString(uiYAML)
+	if err != nil {
+		t.Fatalf("NewCommandBuilderFromString() error = %v", err)
+	}
+	var got cobrayaml.Identity
+	cb.RegisterFunction("runDeploy", func(cmd *cobra.Command, args []string) error {
+		got, _ = cobrayaml.IdentityFromContext(cmd.Context())
+		return nil
+	})
+	cb.RegisterFunction("runBackup", func(cmd *cobra.Command, args []string) error { return nil })
+
+	// An HTTP middleware in front of the handler attaches the identity of the request.
+	ui := Handler(cb.GetConfig(), BuilderRunner(cb))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ui.ServeHTTP(w, r.WithContext(cobrayaml.WithIdentity(r.Context(), cobrayaml.Identity{Tenant: "acme", Subject: "bob"})))
+	})
+	postRun(t, handler, url.Values{"command": {"deploy"}, "arg.0": {"prod"}}, "")
+	if got.Subject != "bob" {
+		t.Errorf("handler identity = %+v, want the identity attached to the request", got)
+	}
+}